@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,8 +16,10 @@ import (
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/config"
 	router "github.com/Constantin-E-T/lunasentri/apps/api-go/internal/http"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/mail"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/notifications"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/silences"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/system"
 )
@@ -50,10 +54,10 @@ func main() {
 		log.Fatalf("Failed to bootstrap admin user: %v", err)
 	}
 
-	// Get JWT secret from environment variable (required)
-	jwtSecret := os.Getenv("AUTH_JWT_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("AUTH_JWT_SECRET environment variable is required")
+	// Get JWT keys from environment variables: AUTH_JWT_KEYS, AUTH_JWT_SECRET or both (required)
+	jwtKeys, err := auth.ParseKeySet(os.Getenv("AUTH_JWT_KEYS"), os.Getenv("AUTH_JWT_SECRET"))
+	if err != nil {
+		log.Fatalf("Invalid JWT keys (set AUTH_JWT_KEYS or AUTH_JWT_SECRET): %v", err)
 	}
 
 	// Get access token TTL from environment variable, default to 15 minutes
@@ -76,13 +80,104 @@ func main() {
 		}
 	}
 
+	// Get refresh token TTL from environment variable, default to 30 days
+	refreshTTL := auth.DefaultRefreshTTL
+	if ttlStr := os.Getenv("REFRESH_TOKEN_TTL"); ttlStr != "" {
+		if parsedTTL, err := time.ParseDuration(ttlStr); err == nil {
+			refreshTTL = parsedTTL
+		} else {
+			log.Printf("Warning: Invalid REFRESH_TOKEN_TTL value '%s', using default 720h", ttlStr)
+		}
+	}
+
 	// Initialize auth service
-	authService, err := auth.NewService(store, jwtSecret, accessTTL)
+	authService, err := auth.NewServiceWithKeys(store, jwtKeys, accessTTL)
 	if err != nil {
 		log.Fatalf("Failed to initialize auth service: %v", err)
 	}
+	if err := authService.SetRefreshTTL(refreshTTL); err != nil {
+		log.Fatalf("Invalid REFRESH_TOKEN_TTL: %v", err)
+	}
+
+	// Lock accounts out after repeated failed logins, default 10 failures for 15 minutes
+	lockoutAttempts := auth.AccountLockoutPolicy.LockoutAfter
+	if attemptsStr := os.Getenv("LOGIN_LOCKOUT_ATTEMPTS"); attemptsStr != "" {
+		if parsed, err := strconv.Atoi(attemptsStr); err == nil {
+			lockoutAttempts = parsed
+		} else {
+			log.Printf("Warning: Invalid LOGIN_LOCKOUT_ATTEMPTS value '%s', using default %d", attemptsStr, lockoutAttempts)
+		}
+	}
+	lockoutDuration := auth.AccountLockoutPolicy.LockoutDuration
+	if durationStr := os.Getenv("LOGIN_LOCKOUT_DURATION"); durationStr != "" {
+		if parsed, err := time.ParseDuration(durationStr); err == nil {
+			lockoutDuration = parsed
+		} else {
+			log.Printf("Warning: Invalid LOGIN_LOCKOUT_DURATION value '%s', using default %v", durationStr, lockoutDuration)
+		}
+	}
+	if err := authService.Throttle().SetLockout(lockoutAttempts, lockoutDuration); err != nil {
+		log.Fatalf("Invalid login lockout settings: %v", err)
+	}
+
+	// Only believe the client IP reported by these reverse proxies
+	if err := auth.SetTrustedProxies(strings.Split(os.Getenv("TRUSTED_PROXIES"), ",")); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
+	log.Printf("JWT signing key: %s (%s), %d verification-only keys", jwtKeys.Primary().ID, jwtKeys.Primary().Algorithm, len(jwtKeys.Keys())-1)
+	log.Printf("Auth service initialized (access token TTL: %v, refresh token TTL: %v, password reset TTL: %v, lockout: %d failures for %v)", accessTTL, refreshTTL, passwordResetTTL, lockoutAttempts, lockoutDuration)
 
-	log.Printf("Auth service initialized (access token TTL: %v, password reset TTL: %v)", accessTTL, passwordResetTTL)
+	// Email password reset links when a mail server is configured
+	appBaseURL := strings.TrimSuffix(os.Getenv("APP_BASE_URL"), "/")
+	if appBaseURL == "" {
+		appBaseURL = "http://localhost:3000"
+	}
+	mailConfig, err := config.LoadMailConfig()
+	if err != nil {
+		log.Println("Password reset emails disabled:", err)
+	} else {
+		mailer, err := mail.NewSMTPMailer(mailConfig)
+		if err != nil {
+			log.Fatalf("Failed to initialize mailer: %v", err)
+		}
+		if err := authService.SetPasswordResetMailer(mailer, appBaseURL+"/reset-password"); err != nil {
+			log.Fatalf("Invalid APP_BASE_URL: %v", err)
+		}
+		log.Printf("Password reset emails enabled (SMTP server: %s:%d)", mailConfig.SMTPHost, mailConfig.SMTPPort)
+	}
+
+	// Organization invitation links point at the web app, and are emailed with the same mailer
+	if err := authService.SetInvitationURL(appBaseURL + "/invitations/accept"); err != nil {
+		log.Fatalf("Invalid APP_BASE_URL: %v", err)
+	}
+
+	// Single sign-on with an OpenID Connect provider, when one is configured
+	oidcConfig, err := config.LoadOIDCConfig()
+	if err != nil {
+		log.Println("Single sign-on disabled:", err)
+	} else {
+		oidcProvider, err := auth.NewOIDCProvider(oidcConfig)
+		if err != nil {
+			log.Fatalf("Invalid OIDC configuration: %v", err)
+		}
+		authService.SetOIDCProvider(oidcProvider)
+		log.Printf("Single sign-on enabled (issuer: %s, automatic provisioning: %v, mapped groups: %d)",
+			oidcConfig.IssuerURL, oidcConfig.AutoProvision, len(oidcConfig.RoleMapping))
+	}
+	if os.Getenv("PASSWORD_LOGIN_DISABLED") == "true" {
+		if err := authService.SetPasswordLogin(false); err != nil {
+			log.Fatalf("Invalid PASSWORD_LOGIN_DISABLED: %v", err)
+		}
+		log.Println("Password login disabled - users sign in with single sign-on")
+	}
+
+	// Returning reset tokens from the API lets anyone reset any account; development only
+	passwordResetDev := os.Getenv("PASSWORD_RESET_DEV_MODE") == "true"
+	if passwordResetDev {
+		log.Println("Warning: PASSWORD_RESET_DEV_MODE enabled - reset tokens are logged and returned by /auth/forgot-password")
+		log.Println("         Never enable this in production.")
+	}
 
 	// Get secure cookie setting from environment variable, default to true for production
 	secureCookie := true
@@ -123,24 +218,88 @@ func main() {
 		log.Println("Telegram notifications enabled")
 	}
 
+	// Parse server-wide notification delivery policy (endpoints may override individually)
+	deliveryPolicy := notifications.DefaultDeliveryPolicy()
+	if intervalStr := os.Getenv("NOTIFY_MIN_ATTEMPT_INTERVAL"); intervalStr != "" {
+		if parsedInterval, err := time.ParseDuration(intervalStr); err == nil {
+			deliveryPolicy.MinAttemptInterval = parsedInterval
+		} else {
+			log.Printf("Warning: Invalid NOTIFY_MIN_ATTEMPT_INTERVAL value '%s', using default %v", intervalStr, notifications.MinAttemptInterval)
+		}
+	}
+	if thresholdStr := os.Getenv("NOTIFY_FAILURE_THRESHOLD"); thresholdStr != "" {
+		if parsedThreshold, err := strconv.Atoi(thresholdStr); err == nil {
+			deliveryPolicy.FailureThreshold = parsedThreshold
+		} else {
+			log.Printf("Warning: Invalid NOTIFY_FAILURE_THRESHOLD value '%s', using default %d", thresholdStr, notifications.FailureThreshold)
+		}
+	}
+	if windowStr := os.Getenv("NOTIFY_FAILURE_WINDOW"); windowStr != "" {
+		if parsedWindow, err := time.ParseDuration(windowStr); err == nil {
+			deliveryPolicy.FailureWindow = parsedWindow
+		} else {
+			log.Printf("Warning: Invalid NOTIFY_FAILURE_WINDOW value '%s', using default %v", windowStr, notifications.FailureWindow)
+		}
+	}
+	if cooldownStr := os.Getenv("NOTIFY_COOLDOWN_DURATION"); cooldownStr != "" {
+		if parsedCooldown, err := time.ParseDuration(cooldownStr); err == nil {
+			deliveryPolicy.CooldownDuration = parsedCooldown
+		} else {
+			log.Printf("Warning: Invalid NOTIFY_COOLDOWN_DURATION value '%s', using default %v", cooldownStr, notifications.CooldownDuration)
+		}
+	}
+	if err := deliveryPolicy.Validate(); err != nil {
+		log.Printf("Warning: Invalid notification delivery policy (%v), using built-in defaults", err)
+		deliveryPolicy = notifications.DefaultDeliveryPolicy()
+	}
+
+	// Telegram shares the circuit breaker but has its own rate limit, off by default
+	telegramDeliveryPolicy := deliveryPolicy
+	telegramDeliveryPolicy.MinAttemptInterval = notifications.TelegramMinAttemptInterval
+	if intervalStr := os.Getenv("NOTIFY_TELEGRAM_MIN_ATTEMPT_INTERVAL"); intervalStr != "" {
+		if parsedInterval, err := time.ParseDuration(intervalStr); err == nil && parsedInterval >= 0 {
+			telegramDeliveryPolicy.MinAttemptInterval = parsedInterval
+		} else {
+			log.Printf("Warning: Invalid NOTIFY_TELEGRAM_MIN_ATTEMPT_INTERVAL value '%s', using default %v", intervalStr, notifications.TelegramMinAttemptInterval)
+		}
+	}
+
 	// Initialize webhook notifier
 	webhookNotifier := notifications.NewNotifier(store, log.Default())
+	webhookNotifier.SetDeliveryDefaults(deliveryPolicy)
 
 	// Initialize Telegram notifier
 	var telegramNotifier *notifications.TelegramNotifier
 	if telegramConfig != nil && telegramConfig.IsEnabled() {
 		telegramNotifier = notifications.NewTelegramNotifier(store, telegramConfig, log.Default())
+		telegramNotifier.SetDeliveryDefaults(telegramDeliveryPolicy)
 	}
 
 	// Create composite notifier that fans out to all channels
 	compositeNotifier := notifications.NewCompositeNotifier(log.Default(), webhookNotifier, telegramNotifier)
 
-	// Initialize alert service with composite notifier
-	alertService := alerts.NewService(store, compositeNotifier)
+	// Apply per-user quiet hours and digests in front of the channels
+	policyNotifier := notifications.NewPolicyNotifier(store, compositeNotifier, log.Default())
+
+	// Initialize alert service with the policy notifier
+	alertService := alerts.NewService(store, policyNotifier)
+
+	// Page escalation steps for alerts nobody acknowledges
+	escalationScheduler := notifications.NewEscalationScheduler(store, compositeNotifier, log.Default())
+
+	// Initialize two-way Telegram bot
+	var telegramBot *notifications.TelegramBot
+	if telegramNotifier != nil && telegramConfig.BotEnabled() {
+		telegramBot = notifications.NewTelegramBot(store, telegramNotifier, alertService, log.Default())
+		log.Printf("Telegram bot enabled (mode: %s)", telegramConfig.BotMode)
+	}
 
 	// Initialize machine service for agent management
 	machineService := machines.NewService(store)
 
+	// Initialize silence service for maintenance windows
+	silenceService := silences.NewService(store)
+
 	// Parse heartbeat configuration from environment
 	heartbeatCheckInterval := 30 * time.Second
 	if intervalStr := os.Getenv("MACHINE_HEARTBEAT_CHECK_INTERVAL"); intervalStr != "" {
@@ -175,10 +334,30 @@ func main() {
 			OfflineThreshold: machineOfflineThreshold,
 		},
 	)
+	heartbeatMonitor.SetSilencer(silenceService)
 
 	// Start heartbeat monitor in background
 	heartbeatMonitor.Start(ctx)
 
+	// Start delivering held-back digests in background
+	policyNotifier.Start(ctx)
+
+	// Start escalating unacknowledged alerts in background
+	escalationScheduler.Start(ctx)
+
+	// Start polling for Telegram bot updates in background
+	if telegramBot != nil && telegramConfig.BotMode == config.TelegramBotModePolling {
+		telegramBot.Start(ctx)
+	}
+
+	// In webhook mode the router receives updates instead
+	var webhookBot *notifications.TelegramBot
+	var telegramWebhookSecret string
+	if telegramBot != nil && telegramConfig.BotMode == config.TelegramBotModeWebhook {
+		webhookBot = telegramBot
+		telegramWebhookSecret = telegramConfig.WebhookSecret
+	}
+
 	// Create HTTP router with all dependencies
 	routerCfg := &router.RouterConfig{
 		Collector:        metricsCollector,
@@ -187,13 +366,18 @@ func main() {
 		AlertService:     alertService,
 		SystemService:    systemService,
 		MachineService:   machineService,
+		SilenceService:   silenceService,
 		Store:            store,
 		WebhookNotifier:  webhookNotifier,
 		TelegramNotifier: telegramNotifier,
+		TelegramBot:      webhookBot,
+		TelegramSecret:   telegramWebhookSecret,
 		AccessTTL:        accessTTL,
 		PasswordResetTTL: passwordResetTTL,
+		PasswordResetDev: passwordResetDev,
 		SecureCookie:     secureCookie,
 		LocalHostMetrics: localHostMetrics,
+		AppBaseURL:       appBaseURL,
 	}
 	mux := router.NewRouter(routerCfg)
 
@@ -229,6 +413,17 @@ func main() {
 	// Stop heartbeat monitor
 	heartbeatMonitor.Stop()
 
+	// Stop the policy notifier, sending any pending digests
+	policyNotifier.Stop()
+
+	// Stop escalating alerts
+	escalationScheduler.Stop()
+
+	// Stop Telegram bot polling
+	if telegramBot != nil && telegramConfig.BotMode == config.TelegramBotModePolling {
+		telegramBot.Stop()
+	}
+
 	// Create context with timeout for graceful shutdown
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
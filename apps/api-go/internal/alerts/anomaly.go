@@ -0,0 +1,161 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// MaxAnomalyDeviation is the largest number of standard deviations an anomaly rule can require
+	MaxAnomalyDeviation = 10.0
+
+	// minBaselineSamples is how many samples a baseline slot learns before anomaly rules fire on it
+	minBaselineSamples = 30
+	// maxBaselineSamples caps the weight of past samples, so beyond it the baseline becomes an
+	// exponentially weighted average that keeps following slow changes in the metric
+	maxBaselineSamples = 2000
+	// minBaselineStddev is the smallest spread, in percentage points, deviations are measured
+	// against, so a nearly constant metric does not fire on every small wobble
+	minBaselineStddev = 1.0
+)
+
+// baselineKey identifies the baseline slot a sample is compared against
+type baselineKey struct {
+	metric      string
+	seasonality string
+	slot        int
+}
+
+// expectedRange is the range of values an anomaly rule considered normal
+type expectedRange struct {
+	low, high float64
+}
+
+// isAnomalyRule reports whether a rule compares samples against the machine's learned baseline
+func isAnomalyRule(rule *storage.AlertRule) bool {
+	return rule.Type == storage.RuleTypeAnomaly
+}
+
+// ruleSeasonality returns the baseline seasonality of an anomaly rule, daily unless set
+func ruleSeasonality(rule *storage.AlertRule) string {
+	if rule.Seasonality == "" {
+		return storage.SeasonalityDaily
+	}
+	return rule.Seasonality
+}
+
+// seasonSlot returns the baseline slot a time falls in: the UTC hour of the day or of the week
+func seasonSlot(seasonality string, at time.Time) int {
+	at = at.UTC()
+	switch seasonality {
+	case storage.SeasonalityDaily:
+		return at.Hour()
+	case storage.SeasonalityWeekly:
+		return int(at.Weekday())*24 + at.Hour()
+	default:
+		return 0
+	}
+}
+
+// anomalyKey returns the baseline slot an anomaly rule compares a sample taken at the given time against
+func anomalyKey(rule *storage.AlertRule, at time.Time) baselineKey {
+	seasonality := ruleSeasonality(rule)
+	return baselineKey{metric: rule.Metric, seasonality: seasonality, slot: seasonSlot(seasonality, at)}
+}
+
+// learnBaseline folds a sample into a baseline's running mean and variance.
+// Up to maxBaselineSamples every sample has equal weight; after that older samples fade out.
+func learnBaseline(b *storage.MetricBaseline, value float64) {
+	if b.Samples < maxBaselineSamples {
+		b.Samples++
+	}
+
+	alpha := 1 / float64(b.Samples)
+	delta := value - b.Mean
+	b.Mean += alpha * delta
+	b.Variance = (1 - alpha) * (b.Variance + alpha*delta*delta)
+}
+
+// baselineStddev returns the spread deviations from a baseline are measured against
+func baselineStddev(b *storage.MetricBaseline) float64 {
+	return math.Max(math.Sqrt(b.Variance), minBaselineStddev)
+}
+
+// deviation returns how many standard deviations a value lies above (positive) or below
+// (negative) a baseline's mean
+func deviation(b *storage.MetricBaseline, value float64) float64 {
+	return (value - b.Mean) / baselineStddev(b)
+}
+
+// expectedWithin returns the values within k standard deviations of a baseline's mean,
+// clamped to the 0-100% range metrics can take
+func expectedWithin(b *storage.MetricBaseline, k float64) expectedRange {
+	spread := k * baselineStddev(b)
+	return expectedRange{
+		low:  math.Max(b.Mean-spread, 0),
+		high: math.Min(b.Mean+spread, 100),
+	}
+}
+
+// loadBaselines loads the baseline slots the anomaly rules compare the machine's sample against.
+// Slots that have learned nothing yet start empty; slots that fail to load are left out, so
+// their rules skip the sample rather than learn over a baseline that could not be read.
+func (s *Service) loadBaselines(ctx context.Context, machineID int, now time.Time) map[baselineKey]*storage.MetricBaseline {
+	var baselines map[baselineKey]*storage.MetricBaseline
+	failed := make(map[baselineKey]bool)
+
+	for i := range s.rulesCache {
+		rule := &s.rulesCache[i]
+		if !isAnomalyRule(rule) {
+			continue
+		}
+		key := anomalyKey(rule, now)
+		if _, ok := baselines[key]; ok || failed[key] {
+			continue
+		}
+
+		baseline, err := s.store.GetMetricBaseline(ctx, machineID, key.metric, key.seasonality, key.slot)
+		if errors.Is(err, storage.ErrMetricBaselineNotFound) {
+			baseline = &storage.MetricBaseline{MachineID: machineID, Metric: key.metric, Seasonality: key.seasonality, Slot: key.slot}
+		} else if err != nil {
+			log.Printf("[ALERT] Failed to load %s baseline for machine %d: %v", key.metric, machineID, err)
+			failed[key] = true
+			continue
+		}
+
+		if baselines == nil {
+			baselines = make(map[baselineKey]*storage.MetricBaseline)
+		}
+		baselines[key] = baseline
+	}
+
+	return baselines
+}
+
+// learnBaselines folds the sample into each loaded baseline slot and stores them
+func (s *Service) learnBaselines(ctx context.Context, baselines map[baselineKey]*storage.MetricBaseline, sample metrics.Metrics) {
+	for key, baseline := range baselines {
+		learnBaseline(baseline, s.getMetricValue(sample, key.metric))
+		if err := s.store.UpsertMetricBaseline(ctx, *baseline); err != nil {
+			log.Printf("[ALERT] Failed to store %s baseline for machine %d: %v", key.metric, baseline.MachineID, err)
+		}
+	}
+}
+
+// anomalyValue computes the value an anomaly rule is checked against: the sample's deviation
+// from the baseline in standard deviations, along with the range the rule expected.
+// It returns false while the baseline is still learning.
+func (s *Service) anomalyValue(rule *storage.AlertRule, baselines map[baselineKey]*storage.MetricBaseline, value float64, now time.Time) (float64, expectedRange, bool) {
+	baseline, ok := baselines[anomalyKey(rule, now)]
+	if !ok || baseline.Samples < minBaselineSamples {
+		return 0, expectedRange{}, false
+	}
+
+	return deviation(baseline, value), expectedWithin(baseline, rule.ThresholdPct), true
+}
@@ -0,0 +1,192 @@
+package alerts
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestLearnBaseline(t *testing.T) {
+	series := []float64{12, 18, 15, 11, 19, 14, 16, 15}
+
+	var b storage.MetricBaseline
+	var sum float64
+	for _, v := range series {
+		learnBaseline(&b, v)
+		sum += v
+	}
+
+	mean := sum / float64(len(series))
+	var variance float64
+	for _, v := range series {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(series))
+
+	if b.Samples != len(series) {
+		t.Errorf("Expected %d samples, got %d", len(series), b.Samples)
+	}
+	if math.Abs(b.Mean-mean) > 1e-9 || math.Abs(b.Variance-variance) > 1e-9 {
+		t.Errorf("Expected mean %.3f and variance %.3f, got %.3f and %.3f", mean, variance, b.Mean, b.Variance)
+	}
+
+	// Past the cap the baseline keeps following the metric
+	for i := 0; i < 5*maxBaselineSamples; i++ {
+		learnBaseline(&b, 60)
+	}
+	if b.Samples != maxBaselineSamples {
+		t.Errorf("Expected samples to be capped at %d, got %d", maxBaselineSamples, b.Samples)
+	}
+	if math.Abs(b.Mean-60) > 0.1 {
+		t.Errorf("Expected the baseline to move to 60, got %.3f", b.Mean)
+	}
+
+	// A flat metric is measured against the minimum spread
+	flat := storage.MetricBaseline{Samples: minBaselineSamples, Mean: 50}
+	if d := deviation(&flat, 53); d != 3 {
+		t.Errorf("Expected a deviation of 3, got %.3f", d)
+	}
+	if r := expectedWithin(&flat, 60); r.low != 0 || r.high != 100 {
+		t.Errorf("Expected the range to be clamped to 0-100%%, got %.1f-%.1f", r.low, r.high)
+	}
+}
+
+func TestSeasonSlot(t *testing.T) {
+	// Wednesday 14:30 UTC
+	at := time.Date(2025, 1, 1, 15, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		seasonality string
+		want        int
+	}{
+		{storage.SeasonalityNone, 0},
+		{storage.SeasonalityDaily, 14},
+		{storage.SeasonalityWeekly, 3*24 + 14},
+	}
+	for _, tt := range tests {
+		if got := seasonSlot(tt.seasonality, at); got != tt.want {
+			t.Errorf("seasonSlot(%q) = %d, want %d", tt.seasonality, got, tt.want)
+		}
+	}
+}
+
+func TestAlertService_EvaluateMachine_AnomalyRule(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, err := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+
+	// CPU more than 3 standard deviations above the usual for the hour, twice in a row
+	rule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "CPU spike", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 3,
+		TriggerAfter: 2, Type: storage.RuleTypeAnomaly})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	if rule.Seasonality != storage.SeasonalityDaily || rule.WindowSeconds != 0 {
+		t.Errorf("Expected a daily baseline and no window, got %q and %d", rule.Seasonality, rule.WindowSeconds)
+	}
+
+	start := time.Date(2025, 1, 1, 14, 0, 0, 0, time.UTC)
+	evaluate := func(svc *Service, at time.Time, cpu float64) []storage.AlertEvent {
+		svc.now = func() time.Time { return at }
+		if err := svc.EvaluateMachine(ctx, machine.ID, metrics.Metrics{CPUPct: cpu}); err != nil {
+			t.Fatalf("Failed to evaluate: %v", err)
+		}
+		events, _ := store.ListAlertEvents(ctx, 10)
+		return events
+	}
+
+	// CPU wobbles around 20% between 14:00 and 14:40; a spike while the baseline is learning stays quiet
+	for i := 0; i < 40; i++ {
+		cpu := 20 + 2*math.Sin(float64(i))
+		if i == 10 || i == 11 {
+			cpu = 45
+		}
+		if events := evaluate(service, start.Add(time.Duration(i)*time.Minute), cpu); len(events) != 0 {
+			t.Fatalf("Expected no alert while the baseline learns, got %d at sample %d", len(events), i)
+		}
+	}
+
+	// The same load at 15:00 has no baseline yet
+	evaluate(service, start.Add(time.Hour), 80)
+	if events := evaluate(service, start.Add(time.Hour+time.Minute), 80); len(events) != 0 {
+		t.Fatalf("Expected no alert in an hour without a baseline, got %d", len(events))
+	}
+
+	// Two samples far above the 14:00 baseline fire
+	evaluate(service, start.Add(41*time.Minute), 80)
+	events := evaluate(service, start.Add(42*time.Minute), 80)
+	if len(events) != 1 {
+		t.Fatalf("Expected the anomaly to fire, got %d events", len(events))
+	}
+	event := events[0]
+	if event.Value != 80 {
+		t.Errorf("Expected the event to record the metric value, got %.1f", event.Value)
+	}
+	if event.ExpectedLow == nil || event.ExpectedHigh == nil {
+		t.Fatal("Expected the event to record the expected range")
+	}
+	if *event.ExpectedLow >= 20 || *event.ExpectedHigh <= 20 || *event.ExpectedHigh >= 80 {
+		t.Errorf("Expected a range around 20%%, got %.1f-%.1f", *event.ExpectedLow, *event.ExpectedHigh)
+	}
+
+	// Baselines survive a restart: a fresh service fires on the next day's 14:00 spike straight away
+	restarted := NewService(store, nil)
+	evaluate(restarted, start.Add(24*time.Hour), 20)
+	evaluate(restarted, start.Add(24*time.Hour+time.Minute), 90)
+	if events := evaluate(restarted, start.Add(24*time.Hour+2*time.Minute), 90); len(events) != 2 {
+		t.Errorf("Expected the stored baseline to fire after a restart, got %d events", len(events))
+	}
+}
+
+func TestAlertService_EvaluateMachine_AnomalyRuleBelow(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "worker-1", "worker-1", "", "hash")
+
+	// Memory dropping well below normal, e.g. a crashed worker
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Memory drop", Metric: "mem_used_pct", Comparison: "below", ThresholdPct: 4,
+		TriggerAfter: 1, Type: storage.RuleTypeAnomaly, Seasonality: storage.SeasonalityNone})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	evaluate := func(at time.Time, mem float64) []storage.AlertEvent {
+		service.now = func() time.Time { return at }
+		if err := service.EvaluateMachine(ctx, machine.ID, metrics.Metrics{MemUsedPct: mem}); err != nil {
+			t.Fatalf("Failed to evaluate: %v", err)
+		}
+		events, _ := store.ListAlertEvents(ctx, 10)
+		return events
+	}
+
+	// Without seasonality samples from every hour share one baseline
+	for i := 0; i < 60; i++ {
+		evaluate(start.Add(time.Duration(i)*10*time.Minute), 70+3*math.Cos(float64(i)))
+	}
+
+	// A rise is not what the rule looks for
+	if events := evaluate(start.Add(10*time.Hour), 95); len(events) != 0 {
+		t.Fatalf("Expected a 'below' rule to ignore a rise, got %d events", len(events))
+	}
+
+	events := evaluate(start.Add(10*time.Hour+10*time.Minute), 20)
+	if len(events) != 1 {
+		t.Fatalf("Expected the drop to fire, got %d events", len(events))
+	}
+	if *events[0].ExpectedLow <= 20 {
+		t.Errorf("Expected the value to be below the expected range, got %.1f-%.1f",
+			*events[0].ExpectedLow, *events[0].ExpectedHigh)
+	}
+}
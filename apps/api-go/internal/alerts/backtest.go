@@ -0,0 +1,146 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// MaxBacktestRange is the longest time range a backtest can replay
+	MaxBacktestRange = 30 * 24 * time.Hour
+	// maxBacktestSamples caps the history one backtest loads
+	maxBacktestSamples = 200000
+)
+
+// ErrBacktestTooManySamples is returned when a backtest's time range holds too much history
+var ErrBacktestTooManySamples = fmt.Errorf("time range has more than %d samples, choose a shorter one", maxBacktestSamples)
+
+// BacktestEvent is an alert a rule would have fired during a backtest
+type BacktestEvent struct {
+	TriggeredAt  time.Time  `json:"triggered_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"` // nil if still firing at the end of the range
+	Value        float64    `json:"value"`
+	ExpectedLow  *float64   `json:"expected_low,omitempty"` // range an anomaly rule expected
+	ExpectedHigh *float64   `json:"expected_high,omitempty"`
+}
+
+// BacktestResult is the outcome of replaying a machine's history through a rule
+type BacktestResult struct {
+	From               time.Time       `json:"from"`
+	To                 time.Time       `json:"to"`
+	Samples            int             `json:"samples"` // samples in the range the rule was checked against
+	Events             []BacktestEvent `json:"events"`
+	TimeInAlertSeconds int             `json:"time_in_alert_seconds"`
+}
+
+// Backtest replays a machine's metrics history between from and to through a rule definition,
+// using the same evaluation as live samples, and reports the alerts it would have fired.
+// Nothing is stored or sent: the rule need not be saved, silences are not applied and anomaly
+// rules learn a fresh baseline from the replayed history instead of using the stored one.
+func (s *Service) Backtest(ctx context.Context, rule storage.AlertRule, machine *storage.Machine, from, to time.Time) (*BacktestResult, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+	if to.Sub(from) > MaxBacktestRange {
+		return nil, fmt.Errorf("time range must not be longer than %s", MaxBacktestRange)
+	}
+
+	expressions := map[int]*Expression{}
+	if isExpressionRule(&rule) {
+		expr, err := ParseExpression(rule.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression: %w", err)
+		}
+		expressions[rule.ID] = expr
+	}
+
+	// Trend rules also need the window of history before the first sample
+	lookback := time.Duration(0)
+	if isTrendRule(&rule) {
+		lookback = trendWindow(&rule)
+	}
+	history, err := s.store.GetMetricsHistory(ctx, machine.ID, from.Add(-lookback), to, maxBacktestSamples+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics history: %w", err)
+	}
+	if len(history) > maxBacktestSamples {
+		return nil, ErrBacktestTooManySamples
+	}
+
+	result := &BacktestResult{From: from, To: to, Events: []BacktestEvent{}}
+	state := &RuleState{}
+	baselines := map[baselineKey]*storage.MetricBaseline{}
+	var open *BacktestEvent
+
+	// history is newest first; replay it oldest first
+	for i := len(history) - 1; i >= 0; i-- {
+		h := history[i]
+		if h.Timestamp.Before(from) {
+			continue
+		}
+
+		in := &sampleInputs{sample: historySample(h), now: h.Timestamp, expressions: expressions}
+		var baseline *storage.MetricBaseline
+		switch {
+		case isTrendRule(&rule):
+			in.history = historyWindow(history[i:], h.Timestamp.Add(-trendWindow(&rule)))
+		case isAnomalyRule(&rule):
+			key := anomalyKey(&rule, h.Timestamp)
+			if baseline = baselines[key]; baseline == nil {
+				baseline = &storage.MetricBaseline{MachineID: machine.ID, Metric: key.metric, Seasonality: key.seasonality, Slot: key.slot}
+				baselines[key] = baseline
+			}
+			in.baselines = baselines
+		case isExpressionRule(&rule):
+			in.vars = sampleVars(in.sample, float64(machine.CPUCores))
+		}
+
+		result.Samples++
+		reading, ok := s.measure(&rule, in)
+		if ok {
+			switch s.observe(state, &rule, reading.score, h.Timestamp) {
+			case transitionFire:
+				open = &BacktestEvent{TriggeredAt: h.Timestamp, Value: reading.value}
+				if reading.expected != nil {
+					open.ExpectedLow, open.ExpectedHigh = &reading.expected.low, &reading.expected.high
+				}
+			case transitionResolve:
+				if open != nil {
+					resolvedAt := h.Timestamp
+					open.ResolvedAt = &resolvedAt
+					result.Events = append(result.Events, *open)
+					open = nil
+				}
+			}
+		}
+
+		if baseline != nil {
+			learnBaseline(baseline, s.getMetricValue(in.sample, baseline.Metric))
+		}
+	}
+
+	if open != nil {
+		result.Events = append(result.Events, *open)
+	}
+	for _, event := range result.Events {
+		end := to
+		if event.ResolvedAt != nil {
+			end = *event.ResolvedAt
+		}
+		result.TimeInAlertSeconds += int(end.Sub(event.TriggeredAt) / time.Second)
+	}
+
+	return result, nil
+}
+
+// historyWindow returns the leading samples of newest-first history that are not older than since
+func historyWindow(history []storage.MetricsHistory, since time.Time) []storage.MetricsHistory {
+	n := sort.Search(len(history), func(i int) bool {
+		return history[i].Timestamp.Before(since)
+	})
+	return history[:n]
+}
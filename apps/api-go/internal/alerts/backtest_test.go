@@ -0,0 +1,113 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestAlertService_Backtest(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+
+	// Two CPU bursts a minute apart per sample: 5 minutes at 95% and 2 minutes at 92%
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	series := []float64{20, 95, 95, 95, 95, 95, 20, 20, 92, 92, 30, 30}
+	for i, cpu := range series {
+		if err := store.InsertMetrics(ctx, machine.ID, cpu, 40, 50, 0, 0, nil, start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("Failed to insert metrics: %v", err)
+		}
+	}
+
+	rule := storage.AlertRule{Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 2, ClearAfter: 1}
+	result, err := service.Backtest(ctx, rule, machine, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Backtest failed: %v", err)
+	}
+
+	if result.Samples != len(series) {
+		t.Errorf("Expected %d samples, got %d", len(series), result.Samples)
+	}
+	if len(result.Events) != 2 {
+		t.Fatalf("Expected two would-be alerts, got %d", len(result.Events))
+	}
+	first := result.Events[0]
+	if !first.TriggeredAt.Equal(start.Add(2*time.Minute)) || first.ResolvedAt == nil || !first.ResolvedAt.Equal(start.Add(6*time.Minute)) {
+		t.Errorf("Expected the first alert from 00:02 to 00:06, got %+v", first)
+	}
+	if first.Value != 95 {
+		t.Errorf("Expected the alert to record 95%%, got %.1f", first.Value)
+	}
+	// 4 minutes for the first burst and 1 for the second
+	if result.TimeInAlertSeconds != 5*60 {
+		t.Errorf("Expected 300s in alert, got %d", result.TimeInAlertSeconds)
+	}
+
+	// A shorter range only replays part of it, and an alert still firing runs to the end
+	result, _ = service.Backtest(ctx, rule, machine, start, start.Add(4*time.Minute))
+	if len(result.Events) != 1 || result.Events[0].ResolvedAt != nil || result.TimeInAlertSeconds != 2*60 {
+		t.Errorf("Expected one alert open until the end of the range, got %+v", result)
+	}
+
+	// Backtests have no side effects
+	if events, _ := store.ListAlertEvents(ctx, 10); len(events) != 0 {
+		t.Errorf("Expected no stored events, got %d", len(events))
+	}
+	if rules, _ := store.ListAlertRules(ctx, user.ID); len(rules) != 0 {
+		t.Errorf("Expected no stored rules, got %d", len(rules))
+	}
+
+	if _, err := service.Backtest(ctx, rule, machine, start, start.Add(MaxBacktestRange+time.Hour)); err == nil {
+		t.Error("Expected an error for a range longer than the maximum")
+	}
+}
+
+func TestAlertService_Backtest_MatchesEvaluation(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "db-1", "db-1", "", "hash")
+
+	rule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Memory pressure", Metric: "mem_used_pct", Comparison: "above", ThresholdPct: 80,
+		TriggerAfter: 2, ClearAfter: 2, Type: storage.RuleTypeExpression, Expression: "mem_used_pct > 80 && cpu_pct > 50"})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// Replay the same samples live and through a backtest
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mem := []float64{60, 85, 90, 75, 90, 88, 60, 60, 85, 85, 85, 40, 40}
+	cpu := []float64{70, 70, 70, 70, 70, 40, 70, 70, 70, 70, 70, 70, 70}
+	for i := range mem {
+		at := start.Add(time.Duration(i) * time.Minute)
+		store.InsertMetrics(ctx, machine.ID, cpu[i], mem[i], 50, 0, 0, nil, at)
+		service.now = func() time.Time { return at }
+		if err := service.EvaluateMachine(ctx, machine.ID, metrics.Metrics{CPUPct: cpu[i], MemUsedPct: mem[i]}); err != nil {
+			t.Fatalf("Failed to evaluate: %v", err)
+		}
+	}
+
+	result, err := service.Backtest(ctx, *rule, machine, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Backtest failed: %v", err)
+	}
+
+	live, _ := store.ListAlertEvents(ctx, 10)
+	if len(live) == 0 || len(live) != len(result.Events) {
+		t.Fatalf("Expected the backtest to fire like live evaluation, got %d and %d events", len(result.Events), len(live))
+	}
+	for i, event := range result.Events {
+		// Live events are listed newest first
+		want := live[len(live)-1-i]
+		if event.Value != want.Value || (event.ResolvedAt == nil) != (want.ResolvedAt == nil) {
+			t.Errorf("Backtest event %d = %+v, live event = %+v", i, event, want)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// maxExpressionLength caps the source length of an expression rule
+	maxExpressionLength = 512
+	// maxExpressionDepth caps how deeply an expression can nest
+	maxExpressionDepth = 32
+)
+
+// ExpressionVariables are the names an expression can refer to: the sample's metrics and the
+// machine's CPU core count
+var ExpressionVariables = []string{"cpu_pct", "mem_used_pct", "disk_used_pct", "uptime_s", "cores"}
+
+// Expression is a parsed boolean expression over a metrics sample, such as
+// "cpu_pct > 90 && mem_used_pct > 80".
+//
+// The language has numbers, the ExpressionVariables, arithmetic (+ - * /), comparisons
+// (> >= < <= == !=), boolean operators (&& || !) and parentheses. Operands are type checked when
+// parsing: comparisons take numbers and boolean operators take comparisons, and the whole
+// expression must be a comparison. Division by zero gives NaN, which no comparison matches.
+type Expression struct {
+	source    string
+	root      *exprNode
+	variables []string
+}
+
+// ParseExpression parses and type checks an expression
+func ParseExpression(source string) (*Expression, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+
+	tokens, err := tokenizeExpression(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+	}
+	if !root.boolean {
+		return nil, fmt.Errorf("expression must be a comparison, e.g. cpu_pct > 90")
+	}
+
+	return &Expression{source: source, root: root, variables: p.variables}, nil
+}
+
+// String returns the expression's source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Variables returns the variables the expression refers to, in order of first use
+func (e *Expression) Variables() []string {
+	return e.variables
+}
+
+// Eval evaluates the expression. Variables missing from vars are 0.
+func (e *Expression) Eval(vars map[string]float64) bool {
+	return e.root.eval(vars) != 0
+}
+
+// exprNode is a node of a parsed expression. Boolean nodes evaluate to 1 or 0.
+type exprNode struct {
+	op          string // operator, "num" or "var"
+	value       float64
+	name        string
+	left, right *exprNode
+	boolean     bool
+}
+
+func (n *exprNode) eval(vars map[string]float64) float64 {
+	switch n.op {
+	case "num":
+		return n.value
+	case "var":
+		return vars[n.name]
+	case "neg":
+		return -n.left.eval(vars)
+	case "!":
+		return boolValue(n.left.eval(vars) == 0)
+	case "&&":
+		return boolValue(n.left.eval(vars) != 0 && n.right.eval(vars) != 0)
+	case "||":
+		return boolValue(n.left.eval(vars) != 0 || n.right.eval(vars) != 0)
+	}
+
+	l, r := n.left.eval(vars), n.right.eval(vars)
+	switch n.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return math.NaN()
+		}
+		return l / r
+	case ">":
+		return boolValue(l > r)
+	case ">=":
+		return boolValue(l >= r)
+	case "<":
+		return boolValue(l < r)
+	case "<=":
+		return boolValue(l <= r)
+	case "==":
+		return boolValue(l == r)
+	case "!=":
+		return boolValue(l != r)
+	default:
+		return 0
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOp
+)
+
+type exprToken struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// exprOperators lists the operators, two-character ones first so they match before their prefixes
+var exprOperators = []string{"&&", "||", ">=", "<=", "==", "!=", ">", "<", "!", "+", "-", "*", "/", "(", ")"}
+
+func tokenizeExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: source[start:i], pos: start})
+
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_':
+			start := i
+			for i < len(source) && (source[i] >= 'a' && source[i] <= 'z' || source[i] >= 'A' && source[i] <= 'Z' ||
+				source[i] >= '0' && source[i] <= '9' || source[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: source[start:i], pos: start})
+
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokenOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+		}
+	}
+
+	return append(tokens, exprToken{kind: tokenEOF, text: "end of expression", pos: len(source)}), nil
+}
+
+// exprParser is a recursive descent parser over the tokens of an expression. From lowest to
+// highest precedence: ||, &&, !, comparisons (which do not chain), + -, * /, unary minus.
+type exprParser struct {
+	tokens    []exprToken
+	pos       int
+	variables []string
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the operators
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr(depth int) (*exprNode, error) {
+	if depth > maxExpressionDepth {
+		return nil, fmt.Errorf("expression is nested more than %d levels deep", maxExpressionDepth)
+	}
+
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = booleanNode(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseAnd(depth int) (*exprNode, error) {
+	left, err := p.parseNot(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseNot(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = booleanNode(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseNot(depth int) (*exprNode, error) {
+	if _, ok := p.accept("!"); ok {
+		if depth >= maxExpressionDepth {
+			return nil, fmt.Errorf("expression is nested more than %d levels deep", maxExpressionDepth)
+		}
+		operand, err := p.parseNot(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if !operand.boolean {
+			return nil, fmt.Errorf("! needs a comparison")
+		}
+		return &exprNode{op: "!", left: operand, boolean: true}, nil
+	}
+	return p.parseComparison(depth)
+}
+
+func (p *exprParser) parseComparison(depth int) (*exprNode, error) {
+	left, err := p.parseSum(depth)
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept(">=", "<=", "==", "!=", ">", "<")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum(depth)
+	if err != nil {
+		return nil, err
+	}
+	if left.boolean || right.boolean {
+		return nil, fmt.Errorf("%s compares numbers, not comparisons", op)
+	}
+	if _, chained := p.accept(">=", "<=", "==", "!=", ">", "<"); chained {
+		return nil, fmt.Errorf("comparisons cannot be chained, combine them with &&")
+	}
+	return &exprNode{op: op, left: left, right: right, boolean: true}, nil
+}
+
+func (p *exprParser) parseSum(depth int) (*exprNode, error) {
+	left, err := p.parseProduct(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseProduct(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = numericNode(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseProduct(depth int) (*exprNode, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		if left, err = numericNode(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *exprParser) parseUnary(depth int) (*exprNode, error) {
+	if _, ok := p.accept("-"); ok {
+		if depth >= maxExpressionDepth {
+			return nil, fmt.Errorf("expression is nested more than %d levels deep", maxExpressionDepth)
+		}
+		operand, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if operand.boolean {
+			return nil, fmt.Errorf("- needs a number")
+		}
+		return &exprNode{op: "neg", left: operand}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *exprParser) parsePrimary(depth int) (*exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos+1)
+		}
+		return &exprNode{op: "num", value: value}, nil
+
+	case tokenIdent:
+		if !isExpressionVariable(tok.text) {
+			return nil, fmt.Errorf("unknown variable %q at position %d (known: %s)",
+				tok.text, tok.pos+1, strings.Join(ExpressionVariables, ", "))
+		}
+		p.addVariable(tok.text)
+		return &exprNode{op: "var", name: tok.text}, nil
+
+	case tokenOp:
+		if tok.text == "(" {
+			inner, err := p.parseOr(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.accept(")"); !ok {
+				next := p.peek()
+				return nil, fmt.Errorf("expected ) at position %d, got %q", next.pos+1, next.text)
+			}
+			return inner, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+}
+
+func (p *exprParser) addVariable(name string) {
+	for _, v := range p.variables {
+		if v == name {
+			return
+		}
+	}
+	p.variables = append(p.variables, name)
+}
+
+// booleanNode combines two comparisons with && or ||
+func booleanNode(op string, left, right *exprNode) (*exprNode, error) {
+	if !left.boolean || !right.boolean {
+		return nil, fmt.Errorf("%s combines comparisons, not numbers", op)
+	}
+	return &exprNode{op: op, left: left, right: right, boolean: true}, nil
+}
+
+// numericNode combines two numbers with an arithmetic operator
+func numericNode(op string, left, right *exprNode) (*exprNode, error) {
+	if left.boolean || right.boolean {
+		return nil, fmt.Errorf("%s needs numbers, not comparisons", op)
+	}
+	return &exprNode{op: op, left: left, right: right}, nil
+}
+
+func isExpressionVariable(name string) bool {
+	for _, v := range ExpressionVariables {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isExpressionRule reports whether a rule fires on a boolean expression rather than one metric
+func isExpressionRule(rule *storage.AlertRule) bool {
+	return rule.Type == storage.RuleTypeExpression
+}
+
+// expressionVars returns the values expression rules can refer to for a sample. cores is the
+// machine's core count from its system info (0 if it never reported one), or this host's.
+func (s *Service) expressionVars(ctx context.Context, machineID *int, sample metrics.Metrics) map[string]float64 {
+	cores := runtime.NumCPU()
+	if machineID != nil {
+		cores = 0
+		machine, err := s.store.GetMachineByID(ctx, *machineID)
+		if err != nil {
+			log.Printf("[ALERT] Failed to load machine %d for expression rules: %v", *machineID, err)
+		} else {
+			cores = machine.CPUCores
+		}
+	}
+
+	return sampleVars(sample, float64(cores))
+}
+
+// sampleVars returns the values of the ExpressionVariables for a sample
+func sampleVars(sample metrics.Metrics, cores float64) map[string]float64 {
+	return map[string]float64{
+		"cpu_pct":       sample.CPUPct,
+		"mem_used_pct":  sample.MemUsedPct,
+		"disk_used_pct": sample.DiskUsedPct,
+		"uptime_s":      sample.UptimeS,
+		"cores":         cores,
+	}
+}
@@ -0,0 +1,141 @@
+package alerts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestParseExpression(t *testing.T) {
+	vars := map[string]float64{"cpu_pct": 95, "mem_used_pct": 60, "disk_used_pct": 40, "uptime_s": 120, "cores": 4}
+
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"cpu_pct > 90", true},
+		{"cpu_pct > 90 && mem_used_pct > 80", false},
+		{"mem_used_pct > 95 || cpu_pct >= 95", true},
+		{"!(cpu_pct > 90)", false},
+		{"cpu_pct > cores * 20 + 10", true},
+		{"cpu_pct / cores > 24", false},
+		{"(cpu_pct - mem_used_pct) * 2 == 70", true},
+		{"-disk_used_pct < -30", true},
+		{"cpu_pct > 90 && (mem_used_pct > 80 || uptime_s < 300)", true},
+		{"cpu_pct > 90 || mem_used_pct > 80 && disk_used_pct > 90", true},
+		{"cpu_pct != 95", false},
+		{"cpu_pct / 0 > 1", false},
+		{"cpu_pct / 0 <= 1", false},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseExpression(tt.source)
+		if err != nil {
+			t.Errorf("ParseExpression(%q) failed: %v", tt.source, err)
+			continue
+		}
+		if got := expr.Eval(vars); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+
+	expr, _ := ParseExpression("  mem_used_pct > 95 || cpu_pct > mem_used_pct ")
+	if got := strings.Join(expr.Variables(), ","); got != "mem_used_pct,cpu_pct" {
+		t.Errorf("Expected variables in order of use, got %s", got)
+	}
+	if expr.String() != "mem_used_pct > 95 || cpu_pct > mem_used_pct" {
+		t.Errorf("Expected the trimmed source, got %q", expr.String())
+	}
+}
+
+func TestParseExpression_Errors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", "empty"},
+		{"cpu_pct", "must be a comparison"},
+		{"cpu_pct + 1", "must be a comparison"},
+		{"load1 > 2", `unknown variable "load1"`},
+		{"cpu_pct > 90 &&", "unexpected"},
+		{"cpu_pct > 90 mem_used_pct", "unexpected"},
+		{"(cpu_pct > 90", "expected )"},
+		{"cpu_pct > 90 && 5", "combines comparisons"},
+		{"(cpu_pct > 90) + 1 > 2", "needs numbers"},
+		{"!cpu_pct", "needs a comparison"},
+		{"1 < cpu_pct < 90", "cannot be chained"},
+		{"cpu_pct > 1.2.3", "invalid number"},
+		{"cpu_pct > 90 & mem_used_pct > 1", "unexpected character"},
+		{strings.Repeat("(", 40) + "cpu_pct > 1" + strings.Repeat(")", 40), "nested"},
+		{strings.Repeat("!", 40) + "(cpu_pct > 1)", "nested"},
+		{"cpu_pct > " + strings.Repeat("1", maxExpressionLength), "longer than"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseExpression(tt.source)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseExpression(%.40q) error = %v, want it to mention %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+func TestAlertService_EvaluateMachine_ExpressionRule(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	cores := 4
+	if err := store.UpdateMachineSystemInfo(ctx, machine.ID, storage.MachineSystemInfoUpdate{CPUCores: &cores}); err != nil {
+		t.Fatalf("Failed to update system info: %v", err)
+	}
+
+	// CPU alone is not enough: memory has to be high too, or CPU above 20% per core
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Overloaded", Metric: "cpu_pct", Comparison: "above", TriggerAfter: 2,
+		Type: storage.RuleTypeExpression, Expression: "cpu_pct > 90 && (mem_used_pct > 80 || cpu_pct > cores * 20)"})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	step := 0
+	evaluate := func(sample metrics.Metrics) []storage.AlertEvent {
+		service.now = func() time.Time { return start.Add(time.Duration(step) * time.Minute) }
+		step++
+		if err := service.EvaluateMachine(ctx, machine.ID, sample); err != nil {
+			t.Fatalf("Failed to evaluate: %v", err)
+		}
+		events, _ := store.ListAlertEvents(ctx, 10)
+		return events
+	}
+
+	// CPU spikes with memory low and CPU under 80% (4 cores x 20%) do nothing
+	for i := 0; i < 3; i++ {
+		if events := evaluate(metrics.Metrics{CPUPct: 70, MemUsedPct: 30}); len(events) != 0 {
+			t.Fatalf("Expected a single signal not to fire, got %d events", len(events))
+		}
+	}
+
+	evaluate(metrics.Metrics{CPUPct: 95, MemUsedPct: 85})
+	events := evaluate(metrics.Metrics{CPUPct: 93, MemUsedPct: 88})
+	if len(events) != 1 {
+		t.Fatalf("Expected the expression to fire after two samples, got %d events", len(events))
+	}
+	if events[0].Value != 93 {
+		t.Errorf("Expected the event to record the rule's metric, got %.1f", events[0].Value)
+	}
+
+	// It resolves as soon as the expression no longer holds
+	events = evaluate(metrics.Metrics{CPUPct: 93, MemUsedPct: 50})
+	if events[0].ResolvedAt != nil {
+		t.Error("Expected the alert to stay open while CPU is above 20% per core")
+	}
+	events = evaluate(metrics.Metrics{CPUPct: 40, MemUsedPct: 50})
+	if events[0].ResolvedAt == nil {
+		t.Error("Expected the alert to resolve once the expression is false")
+	}
+}
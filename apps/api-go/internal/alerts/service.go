@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/silences"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
@@ -17,31 +20,59 @@ type AlertNotifier interface {
 	Notify(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error
 }
 
+// ResolutionNotifier is implemented by notifiers that also announce resolved alerts
+type ResolutionNotifier interface {
+	// NotifyResolved sends notifications for an alert event that has cleared
+	NotifyResolved(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error
+}
+
 // Service handles alert rule evaluation and event management
 type Service struct {
 	store       storage.Store
 	notifier    AlertNotifier
+	silencer    *silences.Service
 	mu          sync.RWMutex
-	ruleStates  map[int]*RuleState // rule ID -> current state
+	ruleStates  map[StateKey]*RuleState // rule and target -> current state
+	statesReady bool                    // whether persisted states have been restored
 	rulesCache  []storage.AlertRule
+	expressions map[int]*Expression // rule ID -> parsed condition of expression rules
 	lastRefresh time.Time
 	refreshTTL  time.Duration
+	now         func() time.Time
 }
 
-// RuleState tracks the consecutive breach count for a rule
+// RuleState tracks the consecutive breach and clear counts of a rule against one target.
+// States are persisted whenever they progress and restored on the first evaluation after a restart.
 type RuleState struct {
 	ConsecutiveBreaches int
+	ConsecutiveClears   int       // clear samples since the rule fired
+	BreachStartedAt     time.Time // first sample of the current run of breaches
+	ClearStartedAt      time.Time // first sample of the current run of clear samples
+	Firing              bool
 	LastValue           float64
 	LastEvaluated       time.Time
+	OpenEventID         int // ID of the fired event awaiting resolution, 0 if none
 }
 
+// transition is the change in a rule's state caused by a sample
+type transition int
+
+const (
+	noTransition      transition = iota
+	transitionRecover            // breaches ended before the rule fired
+	transitionFire
+	transitionResolve
+)
+
 // NewService creates a new alert service
 func NewService(store storage.Store, notifier AlertNotifier) *Service {
 	return &Service{
 		store:      store,
 		notifier:   notifier,
-		ruleStates: make(map[int]*RuleState),
+		silencer:   silences.NewService(store),
+		ruleStates: make(map[StateKey]*RuleState),
 		refreshTTL: 30 * time.Second, // Refresh rules every 30 seconds
+		now:        time.Now,
 	}
 }
 
@@ -49,88 +80,290 @@ func NewService(store storage.Store, notifier AlertNotifier) *Service {
 func (s *Service) refreshRulesIfNeeded(ctx context.Context) error {
 	s.mu.RLock()
 	needsRefresh := time.Since(s.lastRefresh) > s.refreshTTL
+	statesReady := s.statesReady
 	s.mu.RUnlock()
 
 	if !needsRefresh {
 		return nil
 	}
 
-	rules, err := s.store.ListAlertRules(ctx)
+	rules, err := s.store.ListAllAlertRules(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to refresh alert rules: %w", err)
 	}
 
+	// Restore persisted states before the first evaluation, so a restart neither
+	// resets pending breaches nor fires firing rules again
+	var restored map[StateKey]*RuleState
+	if !statesReady {
+		if restored, err = s.loadStates(ctx, rules); err != nil {
+			return fmt.Errorf("failed to restore alert rule states: %w", err)
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if restored != nil && !s.statesReady {
+		s.ruleStates = restored
+		s.statesReady = true
+	}
 	s.rulesCache = rules
 	s.lastRefresh = time.Now()
 
+	// Parse expression rules once per refresh; rules that no longer parse are skipped
+	s.expressions = make(map[int]*Expression)
+	for _, rule := range rules {
+		if !isExpressionRule(&rule) {
+			continue
+		}
+		expr, err := ParseExpression(rule.Expression)
+		if err != nil {
+			log.Printf("[ALERT] Rule '%s' has an invalid expression %q: %v", rule.Name, rule.Expression, err)
+			continue
+		}
+		s.expressions[rule.ID] = expr
+	}
+
 	// Clean up state for deleted rules
 	activeRuleIDs := make(map[int]bool)
 	for _, rule := range rules {
 		activeRuleIDs[rule.ID] = true
 	}
 
-	for ruleID := range s.ruleStates {
-		if !activeRuleIDs[ruleID] {
-			delete(s.ruleStates, ruleID)
+	for key := range s.ruleStates {
+		if !activeRuleIDs[key.RuleID] {
+			delete(s.ruleStates, key)
 		}
 	}
 
 	return nil
 }
 
-// Evaluate evaluates all alert rules against the given metrics sample
+// Evaluate evaluates the alert rules of every organization against a metrics sample from the local
+// host. Events fired by the sample belong to the organization of the rule that fired them.
 func (s *Service) Evaluate(ctx context.Context, sample metrics.Metrics) error {
+	return s.evaluate(ctx, nil, sample)
+}
+
+// EvaluateMachine evaluates the alert rules of a machine's organization against a metrics sample it
+// reported. Events fired by the sample record the machine so they can be routed by its tags.
+func (s *Service) EvaluateMachine(ctx context.Context, machineID int, sample metrics.Metrics) error {
+	return s.evaluate(ctx, &machineID, sample)
+}
+
+// evaluate evaluates alert rules against a sample from the given machine (nil for the local host)
+func (s *Service) evaluate(ctx context.Context, machineID *int, sample metrics.Metrics) error {
 	if err := s.refreshRulesIfNeeded(ctx); err != nil {
 		log.Printf("[ALERT] Failed to refresh rules: %v", err)
 		return err
 	}
 
+	orgID := 0 // every organization's rules apply to the local host
+	if machineID != nil {
+		machine, err := s.store.GetMachineByID(ctx, *machineID)
+		if err != nil {
+			return fmt.Errorf("failed to get machine %d: %w", *machineID, err)
+		}
+		orgID = machine.OrgID
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	now := time.Now()
+	in := s.loadInputs(ctx, machineID, sample, s.now())
 
 	for _, rule := range s.rulesCache {
-		value := s.getMetricValue(sample, rule.Metric)
+		if orgID != 0 && rule.OrgID != orgID {
+			continue
+		}
+
+		reading, ok := s.measure(&rule, in)
+		if !ok {
+			continue
+		}
 
 		// Get or create rule state
-		state, exists := s.ruleStates[rule.ID]
+		key := stateKey(rule.ID, machineID)
+		state, exists := s.ruleStates[key]
 		if !exists {
 			state = &RuleState{}
-			s.ruleStates[rule.ID] = state
+			s.ruleStates[key] = state
 		}
 
-		state.LastValue = value
-		state.LastEvaluated = now
-
-		// Check if threshold is breached
-		breached := s.isThresholdBreached(value, rule.ThresholdPct, rule.Comparison)
-
-		if breached {
-			state.ConsecutiveBreaches++
+		progress := state.progress()
+		state.LastValue = reading.value
+		state.LastEvaluated = in.now
+
+		breaches, clears := state.ConsecutiveBreaches, state.ConsecutiveClears
+		switch s.observe(state, &rule, reading.score, in.now) {
+		case transitionRecover:
+			log.Printf("[ALERT] Rule '%s' recovered: %s=%.1f (was breached %d times)",
+				rule.Name, rule.Metric, reading.score, breaches)
+
+		case transitionFire:
+			eventID, err := s.fireAlert(ctx, &rule, machineID, reading.value, reading.expected)
+			if err != nil {
+				log.Printf("[ALERT] Failed to fire alert for rule '%s': %v", rule.Name, err)
+			} else {
+				state.OpenEventID = eventID
+			}
 
-			// Fire alert if we've reached the trigger threshold
-			if state.ConsecutiveBreaches == rule.TriggerAfter {
-				if err := s.fireAlert(ctx, &rule, value); err != nil {
-					log.Printf("[ALERT] Failed to fire alert for rule '%s': %v", rule.Name, err)
+		case transitionResolve:
+			log.Printf("[ALERT] Rule '%s' cleared: %s=%.1f (after %d clear samples)",
+				rule.Name, rule.Metric, reading.score, clears+1)
+			if state.OpenEventID != 0 {
+				if err := s.resolveAlert(ctx, &rule, state.OpenEventID); err != nil {
+					log.Printf("[ALERT] Failed to resolve alert for rule '%s': %v", rule.Name, err)
 				}
+				state.OpenEventID = 0
 			}
-		} else {
-			// Reset consecutive breaches when metric recovers
-			if state.ConsecutiveBreaches > 0 {
-				log.Printf("[ALERT] Rule '%s' recovered: %s=%.1f (was breached %d times)",
-					rule.Name, rule.Metric, value, state.ConsecutiveBreaches)
+		}
+
+		if state.progress() != progress {
+			if err := s.saveState(ctx, key, state); err != nil {
+				log.Printf("[ALERT] %v", err)
 			}
-			state.ConsecutiveBreaches = 0
 		}
 	}
 
+	// Baselines learn from the sample after it has been checked against them
+	s.learnBaselines(ctx, in.baselines, sample)
+
 	return nil
 }
 
+// sampleInputs is everything rules are checked against for one sample. It is loaded before the
+// rules are evaluated so that evaluating them is deterministic and has no side effects, which
+// lets backtests replay history through the same code.
+type sampleInputs struct {
+	sample      metrics.Metrics
+	now         time.Time
+	history     []storage.MetricsHistory                // machine history, newest first, for rate and forecast rules
+	baselines   map[baselineKey]*storage.MetricBaseline // baseline slots for anomaly rules
+	vars        map[string]float64                      // values for expression rules
+	expressions map[int]*Expression                     // rule ID -> parsed expression
+}
+
+// reading is a rule's measurement of a sample
+type reading struct {
+	value    float64        // metric value events record
+	score    float64        // what the rule's threshold is checked against
+	expected *expectedRange // range anomaly rules expected, nil for other rules
+}
+
+// loadInputs loads what the cached rules need to evaluate a sample from the given machine.
+// Rate, forecast and anomaly rules are computed from the machine's history and baselines;
+// the local host keeps neither.
+func (s *Service) loadInputs(ctx context.Context, machineID *int, sample metrics.Metrics, now time.Time) *sampleInputs {
+	in := &sampleInputs{sample: sample, now: now, expressions: s.expressions}
+
+	if machineID != nil {
+		var err error
+		if in.history, err = s.loadTrendHistory(ctx, *machineID, now); err != nil {
+			log.Printf("[ALERT] Failed to load metrics history for machine %d: %v", *machineID, err)
+		}
+		in.baselines = s.loadBaselines(ctx, *machineID, now)
+	}
+	if len(s.expressions) > 0 {
+		in.vars = s.expressionVars(ctx, machineID, sample)
+	}
+
+	return in
+}
+
+// measure computes what a rule checks in a sample. The score is what the rule's threshold is
+// checked against: the metric itself, its rate or forecast for trend rules, the deviation from
+// the baseline for anomaly rules and 1 when the expression holds for expression rules.
+// It returns false when the rule cannot tell yet, such as a trend rule without enough history
+// or an anomaly rule whose baseline is still learning.
+func (s *Service) measure(rule *storage.AlertRule, in *sampleInputs) (reading, bool) {
+	value := s.getMetricValue(in.sample, rule.Metric)
+	r := reading{value: value, score: value}
+
+	switch {
+	case isTrendRule(rule):
+		trendValue, ok := s.trendValue(rule, in.history, in.now)
+		if !ok {
+			return reading{}, false
+		}
+		r.value, r.score = trendValue, trendValue
+
+	case isAnomalyRule(rule):
+		score, expected, ok := s.anomalyValue(rule, in.baselines, value, in.now)
+		if !ok {
+			return reading{}, false
+		}
+		r.score, r.expected = score, &expected
+
+	case isExpressionRule(rule):
+		expr, ok := in.expressions[rule.ID]
+		if !ok {
+			return reading{}, false
+		}
+		r.score = boolValue(expr.Eval(in.vars))
+	}
+
+	return r, true
+}
+
+// observe feeds a sample to a rule's state and reports whether the rule fires or resolves.
+// It only changes the state, so evaluation and backtests share it.
+//
+// A rule fires once the threshold is breached for trigger_after consecutive samples spanning at
+// least for_seconds, and resolves once the metric is past the clear threshold for clear_after
+// consecutive samples spanning at least clear_for_seconds. Samples between the threshold and the
+// clear threshold leave the state unchanged, so a metric hovering around the threshold neither
+// resets a pending alert nor flaps a firing one.
+func (s *Service) observe(state *RuleState, rule *storage.AlertRule, value float64, now time.Time) transition {
+	breached, cleared := s.classify(rule, value)
+
+	switch {
+	case breached:
+		state.ConsecutiveClears = 0
+		if state.ConsecutiveBreaches == 0 {
+			state.BreachStartedAt = now
+		}
+		state.ConsecutiveBreaches++
+
+		if state.Firing || state.ConsecutiveBreaches < rule.TriggerAfter ||
+			now.Sub(state.BreachStartedAt) < time.Duration(rule.ForSeconds)*time.Second {
+			return noTransition
+		}
+		state.Firing = true
+		return transitionFire
+
+	case cleared:
+		if !state.Firing {
+			// Reset consecutive breaches when metric recovers before the rule fires
+			if state.ConsecutiveBreaches == 0 {
+				return noTransition
+			}
+			state.ConsecutiveBreaches = 0
+			return transitionRecover
+		}
+
+		if state.ConsecutiveClears == 0 {
+			state.ClearStartedAt = now
+		}
+		state.ConsecutiveClears++
+
+		if state.ConsecutiveClears < rule.ClearAfter ||
+			now.Sub(state.ClearStartedAt) < time.Duration(rule.ClearForSeconds)*time.Second {
+			return noTransition
+		}
+
+		state.ConsecutiveBreaches = 0
+		state.ConsecutiveClears = 0
+		state.Firing = false
+		return transitionResolve
+
+	default:
+		// Between the threshold and the clear threshold
+		return noTransition
+	}
+}
+
 // getMetricValue extracts the specific metric value from the sample
 func (s *Service) getMetricValue(sample metrics.Metrics, metricName string) float64 {
 	switch metricName {
@@ -157,15 +390,75 @@ func (s *Service) isThresholdBreached(value, threshold float64, comparison strin
 	}
 }
 
-// fireAlert creates an alert event and logs it
-func (s *Service) fireAlert(ctx context.Context, rule *storage.AlertRule, value float64) error {
-	event, err := s.store.CreateAlertEvent(ctx, rule.ID, value)
+// classify reports whether a value breaches the rule or is clear of it. For forecast rules the
+// value is the hours until the threshold is reached, which breaches within the horizon. For
+// anomaly rules it is the deviation from the baseline, which breaches beyond threshold_pct
+// standard deviations above the mean, or below it for "below" rules. For expression rules it is
+// 1 while the expression holds.
+func (s *Service) classify(rule *storage.AlertRule, value float64) (breached, cleared bool) {
+	if isExpressionRule(rule) {
+		return value != 0, value == 0
+	}
+
+	if isAnomalyRule(rule) {
+		if rule.Comparison == "below" {
+			value = -value
+		}
+		clearDeviation := rule.ThresholdPct
+		if rule.ClearThresholdPct != nil {
+			clearDeviation = *rule.ClearThresholdPct
+		}
+		return value > rule.ThresholdPct, value <= clearDeviation
+	}
+
+	if rule.Type == storage.RuleTypeForecast {
+		horizon := float64(rule.HorizonSeconds) / 3600
+		if rule.HorizonSeconds <= 0 {
+			horizon = DefaultForecastHorizon.Hours()
+		}
+		return value <= horizon, value > horizon
+	}
+
+	return s.isThresholdBreached(value, rule.ThresholdPct, rule.Comparison), s.isCleared(value, rule)
+}
+
+// isCleared checks if the value is back past the rule's clear threshold, which defaults to the threshold
+func (s *Service) isCleared(value float64, rule *storage.AlertRule) bool {
+	clearThreshold := rule.ThresholdPct
+	if rule.ClearThresholdPct != nil {
+		clearThreshold = *rule.ClearThresholdPct
+	}
+
+	switch rule.Comparison {
+	case "above":
+		return value <= clearThreshold
+	case "below":
+		return value >= clearThreshold
+	default:
+		return true
+	}
+}
+
+// fireAlert creates an alert event, logs it and returns the new event ID.
+// expected is the range an anomaly rule considered normal, nil for other rules.
+func (s *Service) fireAlert(ctx context.Context, rule *storage.AlertRule, machineID *int, value float64, expected *expectedRange) (int, error) {
+	event, err := s.store.CreateAlertEvent(ctx, rule.ID, machineID, value)
 	if err != nil {
-		return fmt.Errorf("failed to create alert event: %w", err)
+		return 0, fmt.Errorf("failed to create alert event: %w", err)
+	}
+	if expected != nil {
+		if event, err = s.store.SetAlertEventExpectedRange(ctx, event.ID, expected.low, expected.high); err != nil {
+			return 0, fmt.Errorf("failed to record expected range: %w", err)
+		}
 	}
 
-	log.Printf("[ALERT] %s %s %.1f%% for %d samples (value=%.1f) - Event ID: %d",
-		rule.Name, rule.Comparison, rule.ThresholdPct, rule.TriggerAfter, value, event.ID)
+	log.Printf("[ALERT] [%s] %s %s %.1f%% for %d samples (value=%.1f) - Event ID: %d",
+		event.Severity, rule.Name, rule.Comparison, rule.ThresholdPct, rule.TriggerAfter, value, event.ID)
+
+	if silence := s.silencer.AlertSilenced(ctx, *rule, machineID, time.Now()); silence != nil {
+		log.Printf("[ALERT] %s is silenced by silence %d, skipping notifications for event %d", rule.Name, silence.ID, event.ID)
+		return event.ID, nil
+	}
 
 	// Send notifications asynchronously if notifier is available
 	if s.notifier != nil {
@@ -180,56 +473,127 @@ func (s *Service) fireAlert(ctx context.Context, rule *storage.AlertRule, value
 		}()
 	}
 
+	return event.ID, nil
+}
+
+// resolveAlert marks an open alert event as resolved and sends resolution notifications
+func (s *Service) resolveAlert(ctx context.Context, rule *storage.AlertRule, eventID int) error {
+	event, err := s.store.ResolveAlertEvent(ctx, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to resolve alert event: %w", err)
+	}
+
+	log.Printf("[ALERT] %s resolved - Event ID: %d", rule.Name, event.ID)
+
+	resolver, ok := s.notifier.(ResolutionNotifier)
+	if !ok || s.silencer.AlertSilenced(ctx, *rule, event.MachineID, time.Now()) != nil {
+		return nil
+	}
+
+	go func() {
+		notifyCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := resolver.NotifyResolved(notifyCtx, *rule, event); err != nil {
+			log.Printf("[ALERT] Failed to send resolution notifications for event %d: %v", event.ID, err)
+		}
+	}()
+
 	return nil
 }
 
-// ListRules returns all alert rules
-func (s *Service) ListRules(ctx context.Context) ([]storage.AlertRule, error) {
-	return s.store.ListAlertRules(ctx)
+// ListRules returns an organization's alert rules
+func (s *Service) ListRules(ctx context.Context, orgID int) ([]storage.AlertRule, error) {
+	return s.store.ListAlertRules(ctx, orgID)
 }
 
-// UpsertRule creates or updates an alert rule
-func (s *Service) UpsertRule(ctx context.Context, id int, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
-	if id == 0 {
-		// Create new rule
-		rule, err := s.store.CreateAlertRule(ctx, name, metric, comparison, thresholdPct, triggerAfter)
+// GetRule returns an organization's alert rule
+func (s *Service) GetRule(ctx context.Context, id, orgID int) (*storage.AlertRule, error) {
+	return s.store.GetAlertRule(ctx, id, orgID)
+}
+
+// SaveRule creates an alert rule in the rule's organization when its ID is 0 and otherwise replaces
+// every setting of the organization's existing rule, in a single write recorded as one audit entry.
+// Empty or zero settings use the
+// defaults: warning severity, a threshold rule clearing after one sample, and the default window,
+// horizon and seasonality of the rule's type.
+func (s *Service) SaveRule(ctx context.Context, rule storage.AlertRule) (*storage.AlertRule, error) {
+	applyRuleDefaults(&rule)
+
+	if rule.ID == 0 {
+		saved, err := s.store.SaveAlertRule(ctx, rule)
 		if err != nil {
 			return nil, err
 		}
+		s.audit(ctx, "alert_rule.create", saved.OrgID, saved.ID, nil, saved)
 
 		// Reset rule state cache to pick up new rule
 		s.mu.Lock()
 		s.lastRefresh = time.Time{}
 		s.mu.Unlock()
 
-		return rule, nil
-	} else {
-		// Update existing rule
-		rule, err := s.store.UpdateAlertRule(ctx, id, name, metric, comparison, thresholdPct, triggerAfter)
-		if err != nil {
-			return nil, err
-		}
+		return saved, nil
+	}
 
-		// Reset state for updated rule
-		s.mu.Lock()
-		delete(s.ruleStates, id)
-		s.lastRefresh = time.Time{}
-		s.mu.Unlock()
+	before := s.findRule(ctx, rule.ID, rule.OrgID)
+	saved, err := s.store.SaveAlertRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, "alert_rule.update", rule.OrgID, rule.ID, before, saved)
 
-		return rule, nil
+	// Reset state for updated rule
+	s.mu.Lock()
+	s.forgetRule(rule.ID)
+	s.lastRefresh = time.Time{}
+	s.mu.Unlock()
+	if err := s.store.DeleteAlertRuleStates(ctx, rule.ID); err != nil {
+		log.Printf("[ALERT] Failed to reset state of rule %d: %v", rule.ID, err)
 	}
+
+	return saved, nil
 }
 
-// DeleteRule deletes an alert rule
-func (s *Service) DeleteRule(ctx context.Context, id int) error {
-	err := s.store.DeleteAlertRule(ctx, id)
+// applyRuleDefaults fills in the settings a rule leaves empty
+func applyRuleDefaults(rule *storage.AlertRule) {
+	if rule.Severity == "" {
+		rule.Severity = storage.SeverityWarning
+	}
+	if rule.ClearAfter <= 0 {
+		rule.ClearAfter = 1
+	}
+	if rule.Type == "" {
+		rule.Type = storage.RuleTypeThreshold
+	}
+	if (rule.Type == storage.RuleTypeRate || rule.Type == storage.RuleTypeForecast) && rule.WindowSeconds <= 0 {
+		rule.WindowSeconds = int(DefaultTrendWindow / time.Second)
+	}
+	if rule.Type == storage.RuleTypeForecast && rule.HorizonSeconds <= 0 {
+		rule.HorizonSeconds = int(DefaultForecastHorizon / time.Second)
+	}
+	if rule.Type == storage.RuleTypeAnomaly && rule.Seasonality == "" {
+		rule.Seasonality = storage.SeasonalityDaily
+	}
+}
+
+// CheckEscalationPolicy verifies that an escalation policy exists and belongs to the organization
+func (s *Service) CheckEscalationPolicy(ctx context.Context, policyID, orgID int) error {
+	_, err := s.store.GetEscalationPolicy(ctx, policyID, orgID)
+	return err
+}
+
+// DeleteRule deletes an organization's alert rule
+func (s *Service) DeleteRule(ctx context.Context, id, orgID int) error {
+	before := s.findRule(ctx, id, orgID)
+	err := s.store.DeleteAlertRule(ctx, id, orgID)
 	if err != nil {
 		return err
 	}
+	s.audit(ctx, "alert_rule.delete", orgID, id, before, nil)
 
-	// Clean up rule state
+	// Clean up rule state; persisted states are deleted with the rule
 	s.mu.Lock()
-	delete(s.ruleStates, id)
+	s.forgetRule(id)
 	s.lastRefresh = time.Time{}
 	s.mu.Unlock()
 
@@ -244,19 +608,59 @@ func (s *Service) ListActiveEvents(ctx context.Context, limit int) ([]storage.Al
 	return s.store.ListAlertEvents(ctx, limit)
 }
 
-// AcknowledgeEvent acknowledges an alert event
-func (s *Service) AcknowledgeEvent(ctx context.Context, eventID int) error {
-	return s.store.AckAlertEvent(ctx, eventID)
+// ListEvents returns a page of alert events matching the filter (newest first) and the
+// cursor of the next page, nil on the last page. The page size defaults to 50. Set the filter's
+// OrgID to list only what the organization can see.
+func (s *Service) ListEvents(ctx context.Context, filter storage.AlertEventFilter) ([]storage.AlertEvent, *storage.AlertEventCursor, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+	return s.store.QueryAlertEvents(ctx, filter)
+}
+
+// AcknowledgeEvent acknowledges an alert event on the organization's behalf. Events fired by another
+// organization's rules, on its machines or on the local host, are reported as not found.
+func (s *Service) AcknowledgeEvent(ctx context.Context, orgID, eventID int) error {
+	event, err := s.store.GetAlertEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.store.GetAlertRule(ctx, event.RuleID, orgID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return fmt.Errorf("alert event with id %d not found", eventID)
+		}
+		return err
+	}
+
+	if err := s.store.AckAlertEvent(ctx, eventID); err != nil {
+		return err
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: orgID, Action: "alert_event.ack", TargetType: "alert_event", TargetID: eventID})
+	return nil
+}
+
+// findRule returns an organization's rule for the audit log, nil if it cannot be found
+func (s *Service) findRule(ctx context.Context, id, orgID int) *storage.AlertRule {
+	rule, err := s.store.GetAlertRule(ctx, id, orgID)
+	if err != nil {
+		return nil
+	}
+	return rule
+}
+
+// audit records a change to an organization's alert rule in the audit log
+func (s *Service) audit(ctx context.Context, action string, orgID, ruleID int, before, after *storage.AlertRule) {
+	audit.Record(ctx, s.store, audit.Event{OrgID: orgID, Action: action, TargetType: "alert_rule", TargetID: ruleID, Before: before, After: after})
 }
 
-// GetRuleStates returns the current state of all rules (for debugging/monitoring)
-func (s *Service) GetRuleStates() map[int]RuleState {
+// GetRuleStates returns the current state of all rules and targets (for debugging/monitoring)
+func (s *Service) GetRuleStates() map[StateKey]RuleState {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	states := make(map[int]RuleState)
-	for id, state := range s.ruleStates {
-		states[id] = *state
+	states := make(map[StateKey]RuleState)
+	for key, state := range s.ruleStates {
+		states[key] = *state
 	}
 	return states
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
@@ -49,9 +51,10 @@ func (n *noOpNotifier) Notify(ctx context.Context, rule storage.AlertRule, event
 func TestAlertService_Evaluate_AboveThreshold(t *testing.T) {
 	service, store := setupTestAlertService(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create a rule: CPU above 80% for 3 consecutive samples
-	_, err := store.CreateAlertRule(ctx, "High CPU", "cpu_pct", "above", 80.0, 3)
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0, TriggerAfter: 3})
 	if err != nil {
 		t.Fatalf("Failed to create alert rule: %v", err)
 	}
@@ -106,9 +109,10 @@ func TestAlertService_Evaluate_AboveThreshold(t *testing.T) {
 func TestAlertService_Evaluate_BelowThreshold(t *testing.T) {
 	service, store := setupTestAlertService(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create a rule: Memory below 20% for 2 consecutive samples
-	_, err := store.CreateAlertRule(ctx, "Low Memory", "mem_used_pct", "below", 20.0, 2)
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Low Memory", Metric: "mem_used_pct", Comparison: "below", ThresholdPct: 20.0, TriggerAfter: 2})
 	if err != nil {
 		t.Fatalf("Failed to create alert rule: %v", err)
 	}
@@ -145,9 +149,10 @@ func TestAlertService_Evaluate_BelowThreshold(t *testing.T) {
 func TestAlertService_Evaluate_Recovery(t *testing.T) {
 	service, store := setupTestAlertService(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create a rule: CPU above 70% for 2 consecutive samples
-	_, err := store.CreateAlertRule(ctx, "CPU Alert", "cpu_pct", "above", 70.0, 2)
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "CPU Alert", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 70.0, TriggerAfter: 2})
 	if err != nil {
 		t.Fatalf("Failed to create alert rule: %v", err)
 	}
@@ -185,14 +190,15 @@ func TestAlertService_Evaluate_Recovery(t *testing.T) {
 func TestAlertService_Evaluate_MultipleRules(t *testing.T) {
 	service, store := setupTestAlertService(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create multiple rules
-	cpuRule, err := store.CreateAlertRule(ctx, "High CPU", "cpu_pct", "above", 80.0, 2)
+	cpuRule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0, TriggerAfter: 2})
 	if err != nil {
 		t.Fatalf("Failed to create CPU rule: %v", err)
 	}
 
-	memRule, err := store.CreateAlertRule(ctx, "High Memory", "mem_used_pct", "above", 90.0, 1)
+	memRule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High Memory", Metric: "mem_used_pct", Comparison: "above", ThresholdPct: 90.0, TriggerAfter: 1})
 	if err != nil {
 		t.Fatalf("Failed to create memory rule: %v", err)
 	}
@@ -254,12 +260,16 @@ func TestAlertService_Evaluate_MultipleRules(t *testing.T) {
 	}
 }
 
-func TestAlertService_UpsertRule(t *testing.T) {
-	service, _ := setupTestAlertService(t)
+func TestAlertService_SaveRule(t *testing.T) {
+	service, store := setupTestAlertService(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Test creating a new rule
-	rule, err := service.UpsertRule(ctx, 0, "Test Rule", "cpu_pct", "above", 75.0, 2)
+	rule, err := service.SaveRule(ctx, storage.AlertRule{
+		OrgID: user.ID, Name: "Test Rule", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 75.0, TriggerAfter: 2,
+		Severity: storage.SeverityCritical, Type: storage.RuleTypeRate,
+	})
 	if err != nil {
 		t.Fatalf("Failed to create rule: %v", err)
 	}
@@ -270,9 +280,17 @@ func TestAlertService_UpsertRule(t *testing.T) {
 	if rule.Name != "Test Rule" {
 		t.Errorf("Expected name 'Test Rule', got '%s'", rule.Name)
 	}
+	if rule.Severity != storage.SeverityCritical {
+		t.Errorf("Expected severity 'critical', got '%s'", rule.Severity)
+	}
+	if rule.ClearAfter != 1 || rule.WindowSeconds != int(DefaultTrendWindow/time.Second) {
+		t.Errorf("Expected defaults for clear_after and window_seconds, got %d and %d", rule.ClearAfter, rule.WindowSeconds)
+	}
 
 	// Test updating the rule
-	updatedRule, err := service.UpsertRule(ctx, rule.ID, "Updated Rule", "mem_used_pct", "below", 25.0, 3)
+	updatedRule, err := service.SaveRule(ctx, storage.AlertRule{
+		ID: rule.ID, OrgID: user.ID, Name: "Updated Rule", Metric: "mem_used_pct", Comparison: "below", ThresholdPct: 25.0, TriggerAfter: 3,
+	})
 	if err != nil {
 		t.Fatalf("Failed to update rule: %v", err)
 	}
@@ -286,26 +304,43 @@ func TestAlertService_UpsertRule(t *testing.T) {
 	if updatedRule.Metric != "mem_used_pct" {
 		t.Errorf("Expected metric 'mem_used_pct', got '%s'", updatedRule.Metric)
 	}
+	if updatedRule.Severity != storage.SeverityWarning {
+		t.Errorf("Expected severity to default to 'warning', got '%s'", updatedRule.Severity)
+	}
+	if updatedRule.Type != storage.RuleTypeThreshold || updatedRule.WindowSeconds != 0 {
+		t.Errorf("Expected a threshold rule without a window, got %s with %d", updatedRule.Type, updatedRule.WindowSeconds)
+	}
+
+	if _, err := service.SaveRule(ctx, storage.AlertRule{ID: 9999, OrgID: user.ID, Name: "Missing", Metric: "cpu_pct", Comparison: "above", TriggerAfter: 1}); err == nil {
+		t.Error("Expected an error updating a missing rule")
+	}
+
+	// Rules can only be updated in their own organization
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	if _, err := service.SaveRule(ctx, storage.AlertRule{ID: rule.ID, OrgID: other.ID, Name: "Stolen", Metric: "cpu_pct", Comparison: "above", TriggerAfter: 1}); err == nil {
+		t.Error("Expected an error updating another organization's rule")
+	}
 }
 
 func TestAlertService_DeleteRule(t *testing.T) {
-	service, _ := setupTestAlertService(t)
+	service, store := setupTestAlertService(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create a rule
-	rule, err := service.UpsertRule(ctx, 0, "Test Rule", "cpu_pct", "above", 80.0, 1)
+	rule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Test Rule", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0, TriggerAfter: 1})
 	if err != nil {
 		t.Fatalf("Failed to create rule: %v", err)
 	}
 
 	// Delete the rule
-	err = service.DeleteRule(ctx, rule.ID)
+	err = service.DeleteRule(ctx, rule.ID, user.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete rule: %v", err)
 	}
 
 	// Verify rule is deleted
-	rules, err := service.ListRules(ctx)
+	rules, err := service.ListRules(ctx, user.ID)
 	if err != nil {
 		t.Fatalf("Failed to list rules: %v", err)
 	}
@@ -323,18 +358,20 @@ func TestAlertService_DeleteRule(t *testing.T) {
 	}
 
 	// Verify rule state is cleaned up
-	states := service.GetRuleStates()
-	if _, exists := states[rule.ID]; exists {
-		t.Error("Expected rule state to be cleaned up")
+	for key := range service.GetRuleStates() {
+		if key.RuleID == rule.ID {
+			t.Error("Expected rule state to be cleaned up")
+		}
 	}
 }
 
 func TestAlertService_AcknowledgeEvent(t *testing.T) {
-	service, _ := setupTestAlertService(t)
+	service, store := setupTestAlertService(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create a rule and trigger an event
-	_, err := service.UpsertRule(ctx, 0, "Test Rule", "cpu_pct", "above", 80.0, 1)
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Test Rule", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0, TriggerAfter: 1})
 	if err != nil {
 		t.Fatalf("Failed to create rule: %v", err)
 	}
@@ -360,7 +397,7 @@ func TestAlertService_AcknowledgeEvent(t *testing.T) {
 	}
 
 	// Acknowledge the event
-	err = service.AcknowledgeEvent(ctx, event.ID)
+	err = service.AcknowledgeEvent(ctx, user.ID, event.ID)
 	if err != nil {
 		t.Fatalf("Failed to acknowledge event: %v", err)
 	}
@@ -379,6 +416,81 @@ func TestAlertService_AcknowledgeEvent(t *testing.T) {
 	}
 }
 
+func TestAlertService_AcknowledgeEventOfAnotherOrganization(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, owner.ID, "web-1", "web-1", "", "hash")
+	rule, _ := service.SaveRule(ctx, storage.AlertRule{OrgID: owner.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1})
+	event, _ := store.CreateAlertEvent(ctx, rule.ID, &machine.ID, 90)
+	local, _ := store.CreateAlertEvent(ctx, rule.ID, nil, 90)
+
+	if err := service.AcknowledgeEvent(ctx, other.ID, event.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected another organization's event to be not found, got %v", err)
+	}
+	// Events on the local host belong to the rule's organization too
+	if err := service.AcknowledgeEvent(ctx, other.ID, local.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected another organization's local host event to be not found, got %v", err)
+	}
+	if err := service.AcknowledgeEvent(ctx, owner.ID, event.ID); err != nil {
+		t.Fatalf("Failed to acknowledge own event: %v", err)
+	}
+	if err := service.AcknowledgeEvent(ctx, owner.ID, local.ID); err != nil {
+		t.Fatalf("Failed to acknowledge own local host event: %v", err)
+	}
+}
+
+func TestAlertService_EvaluateMachine_OrganizationRules(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	own, _ := store.CreateMachine(ctx, owner.ID, "web-1", "web-1", "", "hash-1")
+	foreign, _ := store.CreateMachine(ctx, other.ID, "web-2", "web-2", "", "hash-2")
+	rule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: owner.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// Another organization's machine is not evaluated against the rule
+	if err := service.EvaluateMachine(ctx, foreign.ID, metrics.Metrics{CPUPct: 95}); err != nil {
+		t.Fatalf("Failed to evaluate: %v", err)
+	}
+	if events, _ := store.ListAlertEvents(ctx, 10); len(events) != 0 {
+		t.Fatalf("Expected no events on another organization's machine, got %d", len(events))
+	}
+
+	if err := service.EvaluateMachine(ctx, own.ID, metrics.Metrics{CPUPct: 95}); err != nil {
+		t.Fatalf("Failed to evaluate: %v", err)
+	}
+	if err := service.Evaluate(ctx, metrics.Metrics{CPUPct: 95}); err != nil {
+		t.Fatalf("Failed to evaluate: %v", err)
+	}
+
+	// Events on the organization's machine and on the local host belong to the rule's organization
+	for _, orgID := range []int{owner.ID, other.ID} {
+		events, _, err := service.ListEvents(ctx, storage.AlertEventFilter{OrgID: orgID})
+		if err != nil {
+			t.Fatalf("Failed to list events: %v", err)
+		}
+		want := 0
+		if orgID == owner.ID {
+			want = 2
+		}
+		if len(events) != want {
+			t.Errorf("Expected organization %d to see %d events, got %d", orgID, want, len(events))
+		}
+		for _, event := range events {
+			if event.RuleID != rule.ID {
+				t.Errorf("Expected events of rule %d, got %d", rule.ID, event.RuleID)
+			}
+		}
+	}
+}
+
 func TestAlertService_GetMetricValue(t *testing.T) {
 	service, _ := setupTestAlertService(t)
 
@@ -432,3 +544,214 @@ func TestAlertService_IsThresholdBreached(t *testing.T) {
 		}
 	}
 }
+
+// resolutionRecorder records resolution notifications sent by the service
+type resolutionRecorder struct {
+	resolved chan storage.AlertEvent
+}
+
+func (r *resolutionRecorder) Notify(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	return nil
+}
+
+func (r *resolutionRecorder) NotifyResolved(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	r.resolved <- *event
+	return nil
+}
+
+func TestAlertService_Evaluate_ResolvesOpenEvent(t *testing.T) {
+	_, store := setupTestAlertService(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	recorder := &resolutionRecorder{resolved: make(chan storage.AlertEvent, 1)}
+	service := NewService(store, recorder)
+
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0, TriggerAfter: 2})
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+
+	samples := []metrics.Metrics{
+		{CPUPct: 85.0}, // 1st breach
+		{CPUPct: 90.0}, // 2nd breach - fires
+		{CPUPct: 40.0}, // recovery - resolves
+	}
+	for i, sample := range samples {
+		if err := service.Evaluate(ctx, sample); err != nil {
+			t.Fatalf("Failed to evaluate sample %d: %v", i+1, err)
+		}
+	}
+
+	events, err := store.ListAlertEvents(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to list alert events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 alert event, got %d", len(events))
+	}
+	if events[0].ResolvedAt == nil {
+		t.Error("Expected alert event to be resolved after recovery")
+	}
+
+	select {
+	case event := <-recorder.resolved:
+		if event.ID != events[0].ID {
+			t.Errorf("Expected resolution for event %d, got %d", events[0].ID, event.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for resolution notification")
+	}
+
+	for _, state := range service.GetRuleStates() {
+		if state.OpenEventID != 0 {
+			t.Errorf("Expected no open event after resolution, got %d", state.OpenEventID)
+		}
+	}
+}
+
+// firingRecorder records fired alert notifications
+type firingRecorder struct {
+	fired chan storage.AlertEvent
+}
+
+func (r *firingRecorder) Notify(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	r.fired <- *event
+	return nil
+}
+
+func TestAlertService_Evaluate_SilencedRule(t *testing.T) {
+	_, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	recorder := &firingRecorder{fired: make(chan storage.AlertEvent, 1)}
+	service := NewService(store, recorder)
+
+	user, err := store.CreateUser(ctx, "oncall@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	rule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0, TriggerAfter: 1})
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+	end := time.Now().Add(time.Hour)
+	silence := storage.Silence{OrgID: user.ID, RuleID: &rule.ID, CreatedBy: user.ID, Reason: "maintenance", StartsAt: time.Now().Add(-time.Minute), EndsAt: &end}
+	if _, err := store.CreateSilence(ctx, silence); err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+
+	if err := service.Evaluate(ctx, metrics.Metrics{CPUPct: 95.0}); err != nil {
+		t.Fatalf("Failed to evaluate sample: %v", err)
+	}
+
+	// The event is still recorded, only the notification is suppressed
+	events, err := store.ListAlertEvents(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to list alert events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 alert event, got %d", len(events))
+	}
+
+	select {
+	case event := <-recorder.fired:
+		t.Errorf("Expected no notification for silenced rule, got event %d", event.ID)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestAlertService_Evaluate_Hysteresis(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	// Fire above 80%, clear only once back below 70% for two samples
+	clearPct := 70.0
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0,
+		TriggerAfter: 2, ClearThresholdPct: &clearPct, ClearAfter: 2})
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+
+	steps := []struct {
+		cpu      float64
+		open     bool // event firing and unresolved after the sample
+		resolved int  // resolved events after the sample
+	}{
+		{85, false, 0},
+		{78, false, 0}, // between thresholds: keeps the pending breach
+		{84, true, 0},  // second breach fires
+		{79, true, 0},  // oscillating around the threshold does not flap
+		{82, true, 0},
+		{65, true, 0}, // first clear sample
+		{75, true, 0}, // between thresholds: keeps the clear count
+		{60, false, 1},
+	}
+
+	for i, step := range steps {
+		if err := service.Evaluate(ctx, metrics.Metrics{CPUPct: step.cpu}); err != nil {
+			t.Fatalf("Failed to evaluate sample %d: %v", i+1, err)
+		}
+
+		events, _ := store.ListAlertEvents(ctx, 10)
+		open, resolved := 0, 0
+		for _, event := range events {
+			if event.ResolvedAt == nil {
+				open++
+			} else {
+				resolved++
+			}
+		}
+		if (open == 1) != step.open || resolved != step.resolved || len(events) > 1 {
+			t.Fatalf("After sample %d (cpu=%.0f): expected open=%v resolved=%d, got open=%d resolved=%d",
+				i+1, step.cpu, step.open, step.resolved, open, resolved)
+		}
+	}
+}
+
+func TestAlertService_Evaluate_ForDuration(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	// Fire once CPU has been above 80% for two minutes, however often samples arrive
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80.0,
+		TriggerAfter: 1, ForSeconds: 120, ClearForSeconds: 60})
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		after time.Duration
+		cpu   float64
+		open  bool
+	}{
+		{0, 90, false},
+		{90 * time.Second, 95, false},
+		{2 * time.Minute, 92, true},
+		{3 * time.Minute, 50, true}, // must stay clear for a minute
+		{3*time.Minute + 30*time.Second, 90, true},
+		{4 * time.Minute, 50, true},
+		{5 * time.Minute, 50, false},
+	}
+
+	for i, step := range steps {
+		service.now = func() time.Time { return start.Add(step.after) }
+		if err := service.Evaluate(ctx, metrics.Metrics{CPUPct: step.cpu}); err != nil {
+			t.Fatalf("Failed to evaluate sample %d: %v", i+1, err)
+		}
+
+		open := false
+		events, _ := store.ListAlertEvents(ctx, 10)
+		for _, event := range events {
+			if event.ResolvedAt == nil {
+				open = true
+			}
+		}
+		if open != step.open {
+			t.Fatalf("After %v (cpu=%.0f): expected open=%v, got %v", step.after, step.cpu, step.open, open)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// StateKey identifies the state of a rule evaluated against one target
+type StateKey struct {
+	RuleID    int
+	MachineID int // 0 for the local host
+}
+
+// stateKey returns the key of a rule's state for the given machine (nil for the local host)
+func stateKey(ruleID int, machineID *int) StateKey {
+	key := StateKey{RuleID: ruleID}
+	if machineID != nil {
+		key.MachineID = *machineID
+	}
+	return key
+}
+
+// progress returns the state without the last sample's value and time, which change with every
+// sample. States are only persisted when their progress changes.
+func (st RuleState) progress() RuleState {
+	st.LastValue, st.LastEvaluated = 0, time.Time{}
+	return st
+}
+
+// MinStateWindow is the shortest gap without samples after which a persisted breach or clear run
+// no longer counts as consecutive
+const MinStateWindow = 5 * time.Minute
+
+// stateWindow returns how long a rule's breach or clear run may go without samples before it is
+// stale: the longest span the rule evaluates, and at least MinStateWindow
+func stateWindow(rule storage.AlertRule) time.Duration {
+	window := MinStateWindow
+	for _, seconds := range []int{rule.ForSeconds, rule.ClearForSeconds, rule.WindowSeconds} {
+		if d := time.Duration(seconds) * time.Second; d > window {
+			window = d
+		}
+	}
+	return window
+}
+
+// lastSample returns when a persisted state last progressed
+func lastSample(p storage.AlertRuleState) time.Time {
+	last := p.LastEvaluated
+	for _, t := range []*time.Time{p.BreachStartedAt, p.ClearStartedAt} {
+		if t != nil && t.After(last) {
+			last = *t
+		}
+	}
+	return last
+}
+
+// loadStates restores the persisted states of the given rules. A state still waiting on an event
+// that has been resolved or deleted meanwhile starts over, so the rule fires again if its
+// condition still holds instead of later resolving an event that is already closed.
+//
+// Runs of breaches or clear samples older than the rule's window are not consecutive with the
+// samples after the restart: a pending rule starts over and a firing rule restarts its clear run.
+func (s *Service) loadStates(ctx context.Context, rules []storage.AlertRule) (map[StateKey]*RuleState, error) {
+	persisted, err := s.store.ListAlertRuleStates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	openEvents, err := s.store.ListOpenAlertEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[int]storage.AlertRule, len(rules))
+	for _, rule := range rules {
+		active[rule.ID] = rule
+	}
+	open := make(map[int]bool, len(openEvents))
+	for _, event := range openEvents {
+		open[event.ID] = true
+	}
+
+	now := s.now()
+	states := make(map[StateKey]*RuleState, len(persisted))
+	for _, p := range persisted {
+		rule, ok := active[p.RuleID]
+		if !ok {
+			continue
+		}
+
+		state := &RuleState{
+			ConsecutiveBreaches: p.ConsecutiveBreaches,
+			ConsecutiveClears:   p.ConsecutiveClears,
+			Firing:              p.Firing,
+			LastValue:           p.LastValue,
+			LastEvaluated:       p.LastEvaluated,
+		}
+		if p.BreachStartedAt != nil {
+			state.BreachStartedAt = *p.BreachStartedAt
+		}
+		if p.ClearStartedAt != nil {
+			state.ClearStartedAt = *p.ClearStartedAt
+		}
+		if p.OpenEventID != nil {
+			state.OpenEventID = *p.OpenEventID
+			if !open[state.OpenEventID] {
+				log.Printf("[ALERT] Event %d of rule %d is no longer open, restarting the rule's evaluation", state.OpenEventID, p.RuleID)
+				state = &RuleState{LastValue: p.LastValue, LastEvaluated: p.LastEvaluated}
+			}
+		}
+
+		if now.Sub(lastSample(p)) > stateWindow(rule) {
+			if state.Firing {
+				state.ConsecutiveClears, state.ClearStartedAt = 0, time.Time{}
+			} else if state.ConsecutiveBreaches > 0 {
+				log.Printf("[ALERT] State of rule %d is older than the rule's window, restarting the rule's evaluation", p.RuleID)
+				state = &RuleState{LastValue: p.LastValue, LastEvaluated: p.LastEvaluated}
+			}
+		}
+
+		states[stateKey(p.RuleID, p.MachineID)] = state
+	}
+
+	return states, nil
+}
+
+// saveState persists the state of a rule against a target
+func (s *Service) saveState(ctx context.Context, key StateKey, state *RuleState) error {
+	p := storage.AlertRuleState{
+		RuleID:              key.RuleID,
+		ConsecutiveBreaches: state.ConsecutiveBreaches,
+		ConsecutiveClears:   state.ConsecutiveClears,
+		Firing:              state.Firing,
+		LastValue:           state.LastValue,
+		LastEvaluated:       state.LastEvaluated,
+	}
+	if key.MachineID != 0 {
+		p.MachineID = &key.MachineID
+	}
+	if !state.BreachStartedAt.IsZero() {
+		p.BreachStartedAt = &state.BreachStartedAt
+	}
+	if !state.ClearStartedAt.IsZero() {
+		p.ClearStartedAt = &state.ClearStartedAt
+	}
+	if state.OpenEventID != 0 {
+		p.OpenEventID = &state.OpenEventID
+	}
+
+	if err := s.store.UpsertAlertRuleState(ctx, p); err != nil {
+		return fmt.Errorf("failed to save state of rule %d: %w", key.RuleID, err)
+	}
+	return nil
+}
+
+// forgetRule drops the in-memory states of a rule for every target. The caller must hold s.mu.
+func (s *Service) forgetRule(ruleID int) {
+	for key := range s.ruleStates {
+		if key.RuleID == ruleID {
+			delete(s.ruleStates, key)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestAlertService_RestartContinuesPendingBreach(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	if _, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 3}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := service.Evaluate(ctx, metrics.Metrics{CPUPct: 90}); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+	}
+
+	// A restarted service picks up the two breaches and fires on the third
+	restarted := NewService(store, &noOpNotifier{})
+	if err := restarted.Evaluate(ctx, metrics.Metrics{CPUPct: 90}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	events, _ := store.ListAlertEvents(ctx, 10)
+	if len(events) != 1 {
+		t.Fatalf("Expected the third breach after the restart to fire, got %d events", len(events))
+	}
+}
+
+func TestAlertService_RestartWhileFiring(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	if _, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	if err := service.EvaluateMachine(ctx, machine.ID, metrics.Metrics{CPUPct: 90}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	// The condition still holds after a restart: no second event
+	restarted := NewService(store, &noOpNotifier{})
+	if err := restarted.EvaluateMachine(ctx, machine.ID, metrics.Metrics{CPUPct: 95}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	events, _ := store.ListAlertEvents(ctx, 10)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event after the restart, got %d", len(events))
+	}
+
+	// ...and clearing resolves the event fired before the restart
+	if err := restarted.EvaluateMachine(ctx, machine.ID, metrics.Metrics{CPUPct: 50}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	open, _ := store.ListOpenAlertEvents(ctx)
+	if len(open) != 0 {
+		t.Errorf("Expected the event to be resolved, %d still open", len(open))
+	}
+}
+
+func TestAlertService_RestartAfterEventResolved(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	if _, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 2}); err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := service.Evaluate(ctx, metrics.Metrics{CPUPct: 90}); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+	}
+	events, _ := store.ListAlertEvents(ctx, 10)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	// The event is closed while the service is down, so the restarted service starts the rule over
+	if _, err := store.ResolveAlertEvent(ctx, events[0].ID); err != nil {
+		t.Fatalf("Failed to resolve event: %v", err)
+	}
+	restarted := NewService(store, &noOpNotifier{})
+	for i := 0; i < 2; i++ {
+		if err := restarted.Evaluate(ctx, metrics.Metrics{CPUPct: 90}); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+	}
+
+	open, _ := store.ListOpenAlertEvents(ctx)
+	if len(open) != 1 || open[0].ID == events[0].ID {
+		t.Errorf("Expected a new open event, got %+v", open)
+	}
+}
+
+func TestAlertService_StatesArePerTarget(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	rule, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 2})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// One breach from each target does not add up to two
+	if err := service.Evaluate(ctx, metrics.Metrics{CPUPct: 90}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if err := service.EvaluateMachine(ctx, machine.ID, metrics.Metrics{CPUPct: 90}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	events, _ := store.ListAlertEvents(ctx, 10)
+	if len(events) != 0 {
+		t.Errorf("Expected no events, got %d", len(events))
+	}
+	states := service.GetRuleStates()
+	for _, key := range []StateKey{{RuleID: rule.ID}, {RuleID: rule.ID, MachineID: machine.ID}} {
+		if states[key].ConsecutiveBreaches != 1 {
+			t.Errorf("Expected 1 breach for %+v, got %+v", key, states[key])
+		}
+	}
+}
+
+func TestAlertService_RestartDropsStaleRuns(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	pending, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 3})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	firing, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High memory", Metric: "mem_used_pct", Comparison: "above", ThresholdPct: 80,
+		TriggerAfter: 1, ClearAfter: 2})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// Two CPU breaches, and the memory rule fires and sees one clear sample
+	for _, sample := range []metrics.Metrics{{CPUPct: 90, MemUsedPct: 90}, {CPUPct: 90, MemUsedPct: 50}} {
+		if err := service.Evaluate(ctx, sample); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+	}
+
+	// The API comes back an hour later: neither run continues
+	restarted := NewService(store, &noOpNotifier{})
+	later := time.Now().Add(time.Hour)
+	restarted.now = func() time.Time { return later }
+	if err := restarted.Evaluate(ctx, metrics.Metrics{CPUPct: 90, MemUsedPct: 50}); err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+
+	states := restarted.GetRuleStates()
+	if got := states[StateKey{RuleID: pending.ID}]; got.ConsecutiveBreaches != 1 || got.Firing {
+		t.Errorf("Expected the stale breaches to start over, got %+v", got)
+	}
+	if got := states[StateKey{RuleID: firing.ID}]; !got.Firing || got.ConsecutiveClears != 1 {
+		t.Errorf("Expected the firing rule to restart its clear run, got %+v", got)
+	}
+
+	events, _ := store.ListAlertEvents(ctx, 10)
+	if len(events) != 1 || events[0].RuleID != firing.ID {
+		t.Errorf("Expected only the memory rule's event, got %+v", events)
+	}
+	open, _ := store.ListOpenAlertEvents(ctx)
+	if len(open) != 1 {
+		t.Errorf("Expected the memory event to stay open, got %d open", len(open))
+	}
+}
@@ -0,0 +1,170 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// MaxEventStatsRange is the longest period event statistics can cover
+	MaxEventStatsRange = 90 * 24 * time.Hour
+	// eventStatsPageSize is how many events statistics read at a time
+	eventStatsPageSize = 1000
+)
+
+// EventStats summarises alert events. MTTA is the mean time from firing to acknowledgement over
+// acknowledged events, MTTR the mean time from firing to resolution over resolved events; both
+// are omitted when no event qualifies.
+type EventStats struct {
+	Events       int      `json:"events"`
+	Open         int      `json:"open"`
+	Acknowledged int      `json:"acknowledged"`
+	Resolved     int      `json:"resolved"`
+	MTTASeconds  *float64 `json:"mtta_seconds,omitempty"`
+	MTTRSeconds  *float64 `json:"mttr_seconds,omitempty"`
+
+	ackTotal     time.Duration
+	resolveTotal time.Duration
+}
+
+// RuleEventStats summarises the events of one rule
+type RuleEventStats struct {
+	RuleID   int    `json:"rule_id"`
+	RuleName string `json:"rule_name"`
+	EventStats
+}
+
+// MachineEventStats summarises the events fired by one machine's metrics
+type MachineEventStats struct {
+	MachineID *int `json:"machine_id"` // nil for the local host
+	EventStats
+}
+
+// EventStatsReport summarises the events triggered in a period, overall, per rule and per machine
+type EventStatsReport struct {
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Total    EventStats          `json:"total"`
+	Rules    []RuleEventStats    `json:"rules"`
+	Machines []MachineEventStats `json:"machines"`
+}
+
+// add counts an event in the statistics
+func (st *EventStats) add(event *storage.AlertEvent) {
+	st.Events++
+	if event.Acknowledged {
+		st.Acknowledged++
+		if event.AcknowledgedAt != nil {
+			st.ackTotal += event.AcknowledgedAt.Sub(event.TriggeredAt)
+		}
+	}
+	if event.ResolvedAt != nil {
+		st.Resolved++
+		st.resolveTotal += event.ResolvedAt.Sub(event.TriggeredAt)
+	} else {
+		st.Open++
+	}
+}
+
+// finish computes the means once every event has been added
+func (st *EventStats) finish() {
+	if st.Acknowledged > 0 {
+		mtta := st.ackTotal.Seconds() / float64(st.Acknowledged)
+		st.MTTASeconds = &mtta
+	}
+	if st.Resolved > 0 {
+		mttr := st.resolveTotal.Seconds() / float64(st.Resolved)
+		st.MTTRSeconds = &mttr
+	}
+}
+
+// EventStats summarises the alert events of an organization's rules that were triggered between
+// from and to, on its machines and on the local host
+func (s *Service) EventStats(ctx context.Context, orgID int, from, to time.Time) (*EventStatsReport, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("to must be after from")
+	}
+	if to.Sub(from) > MaxEventStatsRange {
+		return nil, fmt.Errorf("time range must not be longer than %s", MaxEventStatsRange)
+	}
+
+	rules, err := s.store.ListAlertRules(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %w", err)
+	}
+	ruleNames := make(map[int]string, len(rules))
+	for _, rule := range rules {
+		ruleNames[rule.ID] = rule.Name
+	}
+
+	report := &EventStatsReport{From: from, To: to, Rules: []RuleEventStats{}, Machines: []MachineEventStats{}}
+	byRule := map[int]*RuleEventStats{}
+	byMachine := map[int]*MachineEventStats{} // 0 for the local host
+
+	filter := storage.AlertEventFilter{OrgID: orgID, From: &from, To: &to, Limit: eventStatsPageSize}
+	for {
+		events, next, err := s.store.QueryAlertEvents(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range events {
+			event := &events[i]
+			report.Total.add(event)
+
+			rule := byRule[event.RuleID]
+			if rule == nil {
+				rule = &RuleEventStats{RuleID: event.RuleID, RuleName: ruleNames[event.RuleID]}
+				byRule[event.RuleID] = rule
+			}
+			rule.add(event)
+
+			machineKey := 0
+			if event.MachineID != nil {
+				machineKey = *event.MachineID
+			}
+			machine := byMachine[machineKey]
+			if machine == nil {
+				machine = &MachineEventStats{MachineID: event.MachineID}
+				byMachine[machineKey] = machine
+			}
+			machine.add(event)
+		}
+
+		if next == nil {
+			break
+		}
+		filter.After = next
+	}
+
+	report.Total.finish()
+	for _, rule := range byRule {
+		rule.finish()
+		report.Rules = append(report.Rules, *rule)
+	}
+	for _, machine := range byMachine {
+		machine.finish()
+		report.Machines = append(report.Machines, *machine)
+	}
+
+	// Busiest first
+	sort.Slice(report.Rules, func(i, j int) bool {
+		if report.Rules[i].Events != report.Rules[j].Events {
+			return report.Rules[i].Events > report.Rules[j].Events
+		}
+		return report.Rules[i].RuleID < report.Rules[j].RuleID
+	})
+	sort.Slice(report.Machines, func(i, j int) bool {
+		a, b := report.Machines[i], report.Machines[j]
+		if a.Events != b.Events {
+			return a.Events > b.Events
+		}
+		return a.MachineID == nil || (b.MachineID != nil && *a.MachineID < *b.MachineID)
+	})
+
+	return report, nil
+}
@@ -0,0 +1,75 @@
+package alerts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestAlertService_EventStats(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	cpu, _ := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1})
+	mem, _ := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High memory", Metric: "mem_used_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1})
+
+	first, _ := store.CreateAlertEvent(ctx, cpu.ID, &machine.ID, 90)
+	store.CreateAlertEvent(ctx, cpu.ID, &machine.ID, 95)
+	second, _ := store.CreateAlertEvent(ctx, mem.ID, nil, 85)
+	store.AckAlertEvent(ctx, first.ID)
+	store.ResolveAlertEvent(ctx, first.ID)
+	store.ResolveAlertEvent(ctx, second.ID)
+
+	now := time.Now()
+	report, err := service.EventStats(ctx, user.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("EventStats failed: %v", err)
+	}
+
+	total := report.Total
+	if total.Events != 3 || total.Open != 1 || total.Acknowledged != 1 || total.Resolved != 2 {
+		t.Errorf("Unexpected totals: %+v", total)
+	}
+	if total.MTTASeconds == nil || *total.MTTASeconds < 0 || total.MTTRSeconds == nil || *total.MTTRSeconds < 0 {
+		t.Errorf("Expected MTTA and MTTR, got %v and %v", total.MTTASeconds, total.MTTRSeconds)
+	}
+
+	// Busiest rule and machine first
+	if len(report.Rules) != 2 || report.Rules[0].RuleID != cpu.ID || report.Rules[0].RuleName != "High CPU" || report.Rules[0].Events != 2 {
+		t.Errorf("Unexpected rule stats: %+v", report.Rules)
+	}
+	if report.Rules[1].MTTASeconds != nil {
+		t.Errorf("Expected no MTTA for a rule without acknowledged events, got %v", *report.Rules[1].MTTASeconds)
+	}
+	if len(report.Machines) != 2 || report.Machines[0].MachineID == nil || *report.Machines[0].MachineID != machine.ID ||
+		report.Machines[1].MachineID != nil || report.Machines[1].Events != 1 {
+		t.Errorf("Unexpected machine stats: %+v", report.Machines)
+	}
+
+	// Another organization sees none of the events, not even those on the local host
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	report, err = service.EventStats(ctx, other.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("EventStats failed: %v", err)
+	}
+	if report.Total.Events != 0 || len(report.Machines) != 0 {
+		t.Errorf("Expected no events for another organization, got %+v", report.Total)
+	}
+
+	// Events outside the period are not counted
+	report, err = service.EventStats(ctx, user.ID, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("EventStats failed: %v", err)
+	}
+	if report.Total.Events != 0 || len(report.Rules) != 0 {
+		t.Errorf("Expected no events in an earlier period, got %+v", report)
+	}
+
+	if _, err := service.EventStats(ctx, user.ID, now, now.Add(-time.Hour)); err == nil {
+		t.Error("Expected an error for an empty period")
+	}
+}
@@ -0,0 +1,161 @@
+package alerts
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// DefaultTrendWindow is the history a rate or forecast rule uses when window_seconds is not set
+	DefaultTrendWindow = time.Hour
+	// MinTrendWindow is the shortest history window a rule can use
+	MinTrendWindow = 5 * time.Minute
+	// MaxTrendWindow is the longest history window a rule can use
+	MaxTrendWindow = 7 * 24 * time.Hour
+
+	// DefaultForecastHorizon is how far ahead a forecast rule looks when horizon_seconds is not set
+	DefaultForecastHorizon = 24 * time.Hour
+	// MaxForecastHorizon is the furthest ahead a forecast rule can look
+	MaxForecastHorizon = 30 * 24 * time.Hour
+
+	// minTrendSamples is the fewest history samples a trend is fitted to
+	minTrendSamples = 3
+	// maxTrendSamples caps the history loaded for one evaluation
+	maxTrendSamples = 10000
+)
+
+// trend is a least-squares line fitted to a metric's recent history
+type trend struct {
+	slope float64   // change per second
+	level float64   // fitted value at the latest sample
+	at    time.Time // time of the latest sample
+}
+
+// fitTrend fits a line to the metric over the history (newest first, as returned by the store).
+// It returns false when there are too few samples, or they cover less than half the window,
+// to fit a meaningful line.
+func (s *Service) fitTrend(history []storage.MetricsHistory, metric string, window time.Duration) (trend, bool) {
+	if len(history) < minTrendSamples {
+		return trend{}, false
+	}
+
+	latest := history[0].Timestamp
+	oldest := latest
+	var n, sumX, sumY, sumXY, sumXX float64
+	for _, h := range history {
+		if latest.Sub(h.Timestamp) > window {
+			continue
+		}
+		// x is seconds before the latest sample, so the intercept is the fitted latest value
+		x := -latest.Sub(h.Timestamp).Seconds()
+		y := s.getMetricValue(historySample(h), metric)
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+		if h.Timestamp.Before(oldest) {
+			oldest = h.Timestamp
+		}
+	}
+
+	if n < minTrendSamples || latest.Sub(oldest) < window/2 {
+		return trend{}, false
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return trend{}, false
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	intercept := (sumY - slope*sumX) / n
+
+	return trend{slope: slope, level: intercept, at: latest}, true
+}
+
+// ratePerHour returns the trend's rate of change in percentage points per hour
+func (t trend) ratePerHour() float64 {
+	return t.slope * 3600
+}
+
+// hoursUntil returns how many hours from now the trend reaches the threshold, moving in the
+// rule's direction ("above": rising to it, "below": falling to it). A threshold that is already
+// reached is 0 hours away and one the metric is moving away from is never reached (+Inf).
+func (t trend) hoursUntil(threshold float64, comparison string, now time.Time) float64 {
+	remaining := threshold - t.level
+	if comparison == "below" {
+		remaining = -remaining
+	}
+	if remaining <= 0 {
+		return 0
+	}
+
+	rising := t.slope
+	if comparison == "below" {
+		rising = -rising
+	}
+	if rising <= 0 {
+		return math.Inf(1)
+	}
+
+	seconds := remaining/rising - now.Sub(t.at).Seconds()
+	return math.Max(seconds, 0) / 3600
+}
+
+// historySample converts a stored history row into a metrics sample
+func historySample(h storage.MetricsHistory) metrics.Metrics {
+	return metrics.Metrics{
+		CPUPct:      h.CPUPct,
+		MemUsedPct:  h.MemUsedPct,
+		DiskUsedPct: h.DiskUsedPct,
+		UptimeS:     h.UptimeSeconds,
+	}
+}
+
+// trendWindow returns the history window of a rate or forecast rule
+func trendWindow(rule *storage.AlertRule) time.Duration {
+	if rule.WindowSeconds <= 0 {
+		return DefaultTrendWindow
+	}
+	return time.Duration(rule.WindowSeconds) * time.Second
+}
+
+// isTrendRule reports whether a rule is computed from metrics history rather than the latest sample
+func isTrendRule(rule *storage.AlertRule) bool {
+	return rule.Type == storage.RuleTypeRate || rule.Type == storage.RuleTypeForecast
+}
+
+// loadTrendHistory loads the machine's history covering the longest window of the trend rules,
+// or nil when no rule needs it
+func (s *Service) loadTrendHistory(ctx context.Context, machineID int, now time.Time) ([]storage.MetricsHistory, error) {
+	var window time.Duration
+	for i := range s.rulesCache {
+		if rule := &s.rulesCache[i]; isTrendRule(rule) && trendWindow(rule) > window {
+			window = trendWindow(rule)
+		}
+	}
+	if window == 0 {
+		return nil, nil
+	}
+
+	return s.store.GetMetricsHistory(ctx, machineID, now.Add(-window), now, maxTrendSamples)
+}
+
+// trendValue computes the value a rate or forecast rule is checked against: the rate of change
+// in percentage points per hour, or the hours until the threshold is reached.
+// It returns false when the history is too short to tell.
+func (s *Service) trendValue(rule *storage.AlertRule, history []storage.MetricsHistory, now time.Time) (float64, bool) {
+	t, ok := s.fitTrend(history, rule.Metric, trendWindow(rule))
+	if !ok {
+		return 0, false
+	}
+
+	if rule.Type == storage.RuleTypeRate {
+		return t.ratePerHour(), true
+	}
+	return t.hoursUntil(rule.ThresholdPct, rule.Comparison, now), true
+}
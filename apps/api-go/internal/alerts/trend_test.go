@@ -0,0 +1,158 @@
+package alerts
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// diskHistory builds history with disk usage growing linearly, newest first like the store returns it
+func diskHistory(start time.Time, samples int, interval time.Duration, startPct, perHour float64) []storage.MetricsHistory {
+	history := make([]storage.MetricsHistory, samples)
+	for i := 0; i < samples; i++ {
+		at := start.Add(time.Duration(i) * interval)
+		history[samples-1-i] = storage.MetricsHistory{
+			DiskUsedPct: startPct + perHour*at.Sub(start).Hours(),
+			Timestamp:   at,
+		}
+	}
+	return history
+}
+
+func TestFitTrend(t *testing.T) {
+	service, _ := setupTestAlertService(t)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	history := diskHistory(start, 13, 5*time.Minute, 50, 2)
+	trend, ok := service.fitTrend(history, "disk_used_pct", time.Hour)
+	if !ok {
+		t.Fatal("Expected a trend for an hour of history")
+	}
+	if math.Abs(trend.ratePerHour()-2) > 0.001 || math.Abs(trend.level-52) > 0.001 {
+		t.Errorf("Expected 2%%/h from 52%%, got %.3f%%/h from %.3f%%", trend.ratePerHour(), trend.level)
+	}
+
+	// 52% growing 2%/h reaches 90% in 19 hours
+	if hours := trend.hoursUntil(90, "above", trend.at); math.Abs(hours-19) > 0.001 {
+		t.Errorf("Expected 19 hours to 90%%, got %.3f", hours)
+	}
+	if hours := trend.hoursUntil(90, "above", trend.at.Add(4*time.Hour)); math.Abs(hours-15) > 0.001 {
+		t.Errorf("Expected 15 hours to 90%% four hours later, got %.3f", hours)
+	}
+	if hours := trend.hoursUntil(40, "below", trend.at); !math.IsInf(hours, 1) {
+		t.Errorf("Expected a rising metric never to fall to 40%%, got %.3f", hours)
+	}
+	if hours := trend.hoursUntil(50, "above", trend.at); hours != 0 {
+		t.Errorf("Expected a reached threshold to be 0 hours away, got %.3f", hours)
+	}
+
+	// Too little history to tell
+	if _, ok := service.fitTrend(history[:2], "disk_used_pct", time.Hour); ok {
+		t.Error("Expected no trend from two samples")
+	}
+	if _, ok := service.fitTrend(history[:5], "disk_used_pct", time.Hour); ok {
+		t.Error("Expected no trend from history covering less than half the window")
+	}
+}
+
+func TestAlertService_EvaluateMachine_ForecastRule(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, err := store.CreateMachine(ctx, user.ID, "db-1", "db-1", "", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+
+	// Disk full (95%) within 24 hours, judged over the last 6 hours
+	_, err = service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Disk filling up", Metric: "disk_used_pct", Comparison: "above", ThresholdPct: 95,
+		TriggerAfter: 1, Type: storage.RuleTypeForecast, WindowSeconds: 6 * 3600})
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+
+	// Growing 1%/h from 60%: 95% is 29 hours away after 6 hours of history
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(from, to time.Duration, pct func(time.Duration) float64) time.Time {
+		var at time.Time
+		for d := from; d <= to; d += 10 * time.Minute {
+			at = start.Add(d)
+			if err := store.InsertMetrics(ctx, machine.ID, 10, 40, pct(d), 0, 0, nil, at); err != nil {
+				t.Fatalf("Failed to insert metrics: %v", err)
+			}
+		}
+		return at
+	}
+	evaluate := func(at time.Time) []storage.AlertEvent {
+		service.now = func() time.Time { return at }
+		if err := service.EvaluateMachine(ctx, machine.ID, metrics.Metrics{}); err != nil {
+			t.Fatalf("Failed to evaluate: %v", err)
+		}
+		events, _ := store.ListAlertEvents(ctx, 10)
+		return events
+	}
+
+	latest := record(0, 6*time.Hour, func(d time.Duration) float64 { return 60 + d.Hours() })
+	if events := evaluate(latest); len(events) != 0 {
+		t.Fatalf("Expected no alert 29 hours from full, got %d", len(events))
+	}
+
+	// The fill speeds up to 4%/h: 95% is a few hours away
+	latest = record(6*time.Hour+10*time.Minute, 12*time.Hour, func(d time.Duration) float64 { return 66 + 4*(d.Hours()-6) })
+	events := evaluate(latest)
+	if len(events) != 1 {
+		t.Fatalf("Expected the forecast to fire, got %d events", len(events))
+	}
+	if events[0].Value <= 0 || events[0].Value > 24 {
+		t.Errorf("Expected the event value to be the hours until full, got %.2f", events[0].Value)
+	}
+	if events[0].MachineID == nil || *events[0].MachineID != machine.ID {
+		t.Errorf("Expected the event to record machine %d", machine.ID)
+	}
+
+	// Local host samples have no history, so the rule is skipped
+	service.now = func() time.Time { return latest }
+	if err := service.Evaluate(ctx, metrics.Metrics{DiskUsedPct: 99}); err != nil {
+		t.Fatalf("Failed to evaluate: %v", err)
+	}
+	if events, _ := store.ListAlertEvents(ctx, 10); len(events) != 1 {
+		t.Errorf("Expected forecast rules to ignore the local host, got %d events", len(events))
+	}
+}
+
+func TestAlertService_EvaluateMachine_RateRule(t *testing.T) {
+	service, store := setupTestAlertService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "app-1", "app-1", "", "hash")
+
+	// Memory growing more than 10%/hour
+	_, err := service.SaveRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "Memory leak", Metric: "mem_used_pct", Comparison: "above", ThresholdPct: 10,
+		TriggerAfter: 1, Type: storage.RuleTypeRate})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var latest time.Time
+	for d := time.Duration(0); d <= time.Hour; d += 5 * time.Minute {
+		latest = start.Add(d)
+		store.InsertMetrics(ctx, machine.ID, 10, 30+12*d.Hours(), 50, 0, 0, nil, latest)
+	}
+
+	service.now = func() time.Time { return latest }
+	if err := service.EvaluateMachine(ctx, machine.ID, metrics.Metrics{MemUsedPct: 42}); err != nil {
+		t.Fatalf("Failed to evaluate: %v", err)
+	}
+
+	events, _ := store.ListAlertEvents(ctx, 10)
+	if len(events) != 1 || math.Abs(events[0].Value-12) > 0.01 {
+		t.Fatalf("Expected one event at 12%%/h, got %+v", events)
+	}
+}
@@ -0,0 +1,206 @@
+// Package audit records who changed what in the append-only audit log.
+//
+// Services call Record after each security-relevant or configuration change. The actor comes
+// from the context, where the auth middleware puts the signed-in user, and the agent middleware
+// the machine.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// Store is where audit entries are written
+type Store interface {
+	CreateAuditEntry(ctx context.Context, entry storage.AuditEntry) (*storage.AuditEntry, error)
+}
+
+// Actor is who takes the actions recorded with a context
+type Actor struct {
+	Type       string // storage.ActorUser, ActorMachine, ActorAnonymous or ActorSystem
+	ID         int    // user or machine ID, 0 if none
+	Email      string
+	APITokenID int // API token the user acts with, 0 for a browser session
+	IPAddress  string
+}
+
+type contextKey struct{}
+
+// WithActor returns a copy of ctx in which actions are taken by the actor. An actor without an IP
+// address keeps that of the context's current actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	if current, ok := ActorFromContext(ctx); ok && actor.IPAddress == "" {
+		actor.IPAddress = current.IPAddress
+	}
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// ActorFromContext returns the actor of the context, if any
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(contextKey{}).(Actor)
+	return actor, ok
+}
+
+// UserActor returns the actor for a user
+func UserActor(user *storage.User, ipAddress string) Actor {
+	return Actor{Type: storage.ActorUser, ID: user.ID, Email: user.Email, IPAddress: ipAddress}
+}
+
+// Event is an action to record
+type Event struct {
+	OrgID      int    // organization owning the target, 0 for system-wide targets
+	Action     string // e.g. "machine.delete"
+	TargetType string // e.g. "machine"
+	TargetID   any
+	Before     any // the target before the action, nil (or a nil pointer) when it was created
+	After      any // the target after the action, nil (or a nil pointer) when it was deleted
+}
+
+// Record appends the event, taken by the context's actor, to the audit log. Updates that change
+// nothing are skipped. Failures are logged rather than failing the action, which has already
+// happened.
+func Record(ctx context.Context, store Store, event Event) {
+	changes, err := Diff(event.Before, event.After)
+	if err != nil {
+		log.Printf("Failed to audit %s of %s %v: %v", event.Action, event.TargetType, event.TargetID, err)
+		return
+	}
+	if changes == nil && present(event.Before) && present(event.After) {
+		return
+	}
+
+	entry := storage.AuditEntry{
+		ActorType:  storage.ActorSystem,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		Changes:    changes,
+	}
+	if event.TargetID != nil {
+		entry.TargetID = fmt.Sprint(event.TargetID)
+	}
+	if event.OrgID != 0 {
+		entry.OrgID = &event.OrgID
+	}
+	if actor, ok := ActorFromContext(ctx); ok {
+		entry.ActorType = actor.Type
+		entry.ActorEmail = actor.Email
+		entry.IPAddress = actor.IPAddress
+		if actor.ID != 0 {
+			entry.ActorID = &actor.ID
+		}
+		if actor.APITokenID != 0 {
+			entry.APITokenID = &actor.APITokenID
+		}
+	}
+
+	// Record even when the request was cancelled right after the action
+	if _, err := store.CreateAuditEntry(context.WithoutCancel(ctx), entry); err != nil {
+		log.Printf("Failed to audit %s of %s %s: %v", entry.Action, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+// Change is a field's value before and after an action
+type Change struct {
+	Before any `json:"before,omitempty"`
+	After  any `json:"after,omitempty"`
+}
+
+// Redacted replaces the values of secrets, so the log shows that they changed but not to what
+const Redacted = "[redacted]"
+
+// ignoredFields change on their own and would only clutter diffs
+var ignoredFields = map[string]bool{"updated_at": true, "last_seen": true, "last_used_at": true}
+
+// Diff returns the fields of before and after, as they are encoded to JSON, that differ, nil if
+// none do. Secrets are redacted.
+func Diff(before, after any) (json.RawMessage, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]Change{}
+	for name, value := range beforeFields {
+		if !ignoredFields[name] {
+			changes[name] = Change{Before: value}
+		}
+	}
+	for name, value := range afterFields {
+		if ignoredFields[name] {
+			continue
+		}
+		change, ok := changes[name]
+		if ok && string(change.Before.(json.RawMessage)) == string(value) {
+			delete(changes, name)
+			continue
+		}
+		change.After = value
+		changes[name] = change
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	for name, change := range changes {
+		if isSecret(name) {
+			changes[name] = redact(change)
+		}
+	}
+	return json.Marshal(changes)
+}
+
+// fields returns the JSON encoding of each field of v, or of v itself as "value" when it does not
+// encode to an object
+func fields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %w", v, err)
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return map[string]json.RawMessage{"value": data}, nil
+	}
+	return fields, nil
+}
+
+// present reports whether v is a value rather than nil, including a nil pointer
+func present(v any) bool {
+	fields, err := fields(v)
+	return err == nil && fields != nil
+}
+
+// isSecret reports whether a field holds a secret or a hash of one
+func isSecret(name string) bool {
+	for _, part := range []string{"hash", "secret", "password", "api_key"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact replaces the values of a change to a secret
+func redact(change Change) Change {
+	if change.Before != nil {
+		change.Before = Redacted
+	}
+	if change.After != nil {
+		change.After = Redacted
+	}
+	return change
+}
@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+type recordingStore struct {
+	entries []storage.AuditEntry
+}
+
+func (s *recordingStore) CreateAuditEntry(ctx context.Context, entry storage.AuditEntry) (*storage.AuditEntry, error) {
+	s.entries = append(s.entries, entry)
+	return &entry, nil
+}
+
+type target struct {
+	Name       string `json:"name"`
+	Enabled    bool   `json:"enabled"`
+	SecretHash string `json:"secret_hash"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+func TestDiff(t *testing.T) {
+	before := target{Name: "web-1", Enabled: true, SecretHash: "aaa", UpdatedAt: "yesterday"}
+
+	tests := []struct {
+		name          string
+		before, after any
+		want          string
+	}{
+		{"unchanged", before, target{Name: "web-1", Enabled: true, SecretHash: "aaa", UpdatedAt: "today"}, ""},
+		{"changed", before, target{Name: "web-2", Enabled: false, SecretHash: "bbb"},
+			`{"enabled":{"before":true,"after":false},"name":{"before":"web-1","after":"web-2"},"secret_hash":{"before":"[redacted]","after":"[redacted]"}}`},
+		{"created", nil, target{Name: "web-1"},
+			`{"enabled":{"after":false},"name":{"after":"web-1"},"secret_hash":{"after":"[redacted]"}}`},
+		{"deleted", before, nil,
+			`{"enabled":{"before":true},"name":{"before":"web-1"},"secret_hash":{"before":"[redacted]"}}`},
+		{"values", "optional", "all", `{"value":{"before":"optional","after":"all"}}`},
+		{"nothing", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		got, err := Diff(tt.before, tt.after)
+		if err != nil {
+			t.Fatalf("%s: Diff failed: %v", tt.name, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	store := &recordingStore{}
+	ctx := WithActor(context.Background(), Actor{Type: storage.ActorAnonymous, IPAddress: "203.0.113.7"})
+	ctx = WithActor(ctx, UserActor(&storage.User{ID: 3, Email: "alice@example.com"}, ""))
+
+	Record(ctx, store, Event{OrgID: 2, Action: "machine.update", TargetType: "machine", TargetID: 5,
+		Before: target{Name: "web-1"}, After: target{Name: "web-2"}})
+	Record(ctx, store, Event{Action: "machine.update", TargetType: "machine", TargetID: 5,
+		Before: target{Name: "web-2"}, After: target{Name: "web-2"}})
+	Record(context.Background(), store, Event{Action: "machine.rotate_key", TargetType: "machine", TargetID: 5})
+	var missing *target
+	Record(ctx, store, Event{Action: "machine.delete", TargetType: "machine", TargetID: 5, Before: missing, After: missing})
+
+	if len(store.entries) != 3 {
+		t.Fatalf("Expected updates without changes to be skipped, got %d entries", len(store.entries))
+	}
+	entry := store.entries[0]
+	if entry.ActorType != storage.ActorUser || entry.ActorID == nil || *entry.ActorID != 3 || entry.ActorEmail != "alice@example.com" {
+		t.Errorf("Unexpected actor %+v", entry)
+	}
+	if entry.IPAddress != "203.0.113.7" {
+		t.Errorf("Expected the IP address of the previous actor, got %q", entry.IPAddress)
+	}
+	if entry.OrgID == nil || *entry.OrgID != 2 || entry.TargetID != "5" {
+		t.Errorf("Unexpected target %+v", entry)
+	}
+	var changes map[string]Change
+	if err := json.Unmarshal(entry.Changes, &changes); err != nil || changes["name"].After != "web-2" {
+		t.Errorf("Unexpected changes %s", entry.Changes)
+	}
+
+	if system := store.entries[1]; system.ActorType != storage.ActorSystem || system.ActorID != nil || system.Changes != nil {
+		t.Errorf("Expected a system entry without changes, got %+v", system)
+	}
+}
@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// API token scopes, which double as role permissions. Every token can read the areas its write
+// scopes cover; ScopeRead grants read access to everything available to tokens except users.
+const (
+	ScopeRead               = "read"
+	ScopeAlertsWrite        = "alerts:write"        // alert rules, events, silences and backtests
+	ScopeMachinesAdmin      = "machines:admin"      // registering, changing and deleting machines
+	ScopeNotificationsWrite = "notifications:write" // notification channels, templates and routing
+	ScopeUsersAdmin         = "users:admin"         // user management
+)
+
+// Scopes lists the valid API token scopes
+var Scopes = []string{ScopeRead, ScopeAlertsWrite, ScopeMachinesAdmin, ScopeNotificationsWrite, ScopeUsersAdmin}
+
+const (
+	// APITokenPrefix starts every API token, so they are easy to recognise in scripts and secret scanners
+	APITokenPrefix = "lst_"
+
+	// apiTokenDisplayLength is how much of a token is kept to tell tokens apart
+	apiTokenDisplayLength = len(APITokenPrefix) + 8
+
+	// apiTokenTouchInterval is how often a token's last use is recorded
+	apiTokenTouchInterval = time.Minute
+)
+
+// ErrInvalidAPIToken is returned when a token is unknown, revoked or expired
+var ErrInvalidAPIToken = errors.New("invalid or expired API token")
+
+// areas maps the paths API tokens and roles are checked against to the permission that allows
+// changing them. Reads need ScopeRead, or readScope when set. An empty scope means the path is
+// read-only, so any request to it only needs the read permission. Paths not listed are denied to
+// API tokens and to every role, except the self-service paths in selfServicePaths.
+var areas = []struct {
+	path      string
+	scope     string
+	readScope string
+}{
+	{"/alerts/", ScopeAlertsWrite, ""},
+	{"/silences", ScopeAlertsWrite, ""},
+	{"/machines", ScopeMachinesAdmin, ""},
+	{"/agent/register", ScopeMachinesAdmin, ""},
+	{"/notifications/", ScopeNotificationsWrite, ""},
+	{"/auth/users", ScopeUsersAdmin, ScopeUsersAdmin},
+	{"/auth/2fa/policy", ScopeUsersAdmin, ""},
+	{"/auth/lockouts", ScopeUsersAdmin, ScopeUsersAdmin},
+	{"/audit", ScopeUsersAdmin, ScopeUsersAdmin},
+	{"/auth/me", "", ""},
+	{"/metrics", "", ""},
+	{"/system/", "", ""},
+	{"/ws", "", ""},
+}
+
+// CreateAPIToken creates an API token for the user and returns it along with the token itself,
+// which is not stored and cannot be retrieved again
+func (s *Service) CreateAPIToken(ctx context.Context, userID int, name string, scopes []string, expiresAt *time.Time) (*storage.APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name cannot be empty")
+	}
+	if len(name) > 100 {
+		return nil, "", fmt.Errorf("name must not be longer than 100 characters")
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("at least one scope is required")
+	}
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user: %w", err)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, "", fmt.Errorf("unknown scope %q, valid scopes are %s", scope, strings.Join(Scopes, ", "))
+		}
+		if !RoleHas(user.Role, scope) {
+			return nil, "", fmt.Errorf("scope %q is not available to the %s role", scope, user.Role)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("expiry must be in the future")
+	}
+
+	secret, err := generateSecureToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	token := APITokenPrefix + strings.TrimRight(secret, "=")
+	tokenHash, err := hashToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	created, err := s.store.CreateAPIToken(ctx, storage.APIToken{
+		UserID:      userID,
+		Name:        name,
+		TokenPrefix: token[:apiTokenDisplayLength],
+		Scopes:      slices.Compact(scopes),
+		ExpiresAt:   expiresAt,
+		TokenHash:   tokenHash,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create API token: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{Action: "api_token.create", TargetType: "api_token", TargetID: created.ID, After: created})
+
+	return created, token, nil
+}
+
+// ListAPITokens returns the user's API tokens that have not been revoked
+func (s *Service) ListAPITokens(ctx context.Context, userID int) ([]storage.APIToken, error) {
+	tokens, err := s.store.ListAPITokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes one of the user's API tokens
+func (s *Service) RevokeAPIToken(ctx context.Context, userID, tokenID int) error {
+	if err := s.store.RevokeAPIToken(ctx, tokenID, userID); err != nil {
+		if errors.Is(err, storage.ErrAPITokenNotFound) {
+			return fmt.Errorf("API token not found")
+		}
+		return fmt.Errorf("failed to revoke API token: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{Action: "api_token.revoke", TargetType: "api_token", TargetID: tokenID})
+	return nil
+}
+
+// AuthenticateAPIToken returns the active API token matching token and records its use
+func (s *Service) AuthenticateAPIToken(ctx context.Context, token, ipAddress string) (*storage.APIToken, error) {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return nil, ErrInvalidAPIToken
+	}
+	tokenHash, err := hashToken(token)
+	if err != nil {
+		return nil, ErrInvalidAPIToken
+	}
+
+	apiToken, err := s.store.GetAPITokenByHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPITokenNotFound) {
+			return nil, ErrInvalidAPIToken
+		}
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	if apiToken.RevokedAt != nil || (apiToken.ExpiresAt != nil && !time.Now().Before(*apiToken.ExpiresAt)) {
+		return nil, ErrInvalidAPIToken
+	}
+
+	if apiToken.LastUsedAt == nil || time.Since(*apiToken.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.store.TouchAPIToken(ctx, apiToken.ID, ipAddress); err != nil {
+			log.Printf("Warning: failed to record use of API token %d: %v", apiToken.ID, err)
+		}
+	}
+
+	return apiToken, nil
+}
+
+// APITokenAllows reports whether the token's scopes allow the request
+func APITokenAllows(token *storage.APIToken, r *http.Request) bool {
+	allowed, listed := permissionsAllow(token.Scopes, r)
+	return listed && allowed
+}
+
+// permissionsAllow reports whether the permissions allow the request, and whether its path is
+// listed in areas at all
+func permissionsAllow(permissions []string, r *http.Request) (allowed, listed bool) {
+	for _, area := range areas {
+		if r.URL.Path != area.path && !strings.HasPrefix(r.URL.Path, strings.TrimSuffix(area.path, "/")+"/") {
+			continue
+		}
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || area.scope == "" {
+			readScope := area.readScope
+			if readScope == "" {
+				readScope = ScopeRead
+			}
+			if slices.Contains(permissions, readScope) {
+				return true, true
+			}
+		}
+		return area.scope != "" && slices.Contains(permissions, area.scope), true
+	}
+	return false, false
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, if any
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < len("Bearer ") || !strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(header[len("Bearer "):]), true
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func newAPITokenTestService(t *testing.T) (*Service, *storage.SQLiteStore, *storage.User) {
+	t.Helper()
+
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	service, err := NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	user, err := store.CreateUser(context.Background(), "ci@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	return service, store, user
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	created, token, err := service.CreateAPIToken(ctx, user.ID, " Terraform ", []string{ScopeRead, ScopeAlertsWrite, ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+	if !strings.HasPrefix(token, APITokenPrefix) || !strings.HasPrefix(token, created.TokenPrefix) {
+		t.Errorf("Expected token %q to start with %q and its display prefix %q", token, APITokenPrefix, created.TokenPrefix)
+	}
+	if created.Name != "Terraform" || strings.Join(created.Scopes, ",") != "alerts:write,read" {
+		t.Errorf("Unexpected created token: %+v", created)
+	}
+
+	// Only the hash is stored
+	stored, err := store.GetAPITokenByHash(ctx, created.TokenHash)
+	if err != nil {
+		t.Fatalf("Failed to get token by hash: %v", err)
+	}
+	if stored.TokenHash == token || strings.Contains(stored.TokenHash, token) {
+		t.Error("Expected the token to be stored hashed")
+	}
+
+	past := time.Now().Add(-time.Hour)
+	for name, call := range map[string]func() error{
+		"empty name": func() error {
+			_, _, err := service.CreateAPIToken(ctx, user.ID, " ", []string{ScopeRead}, nil)
+			return err
+		},
+		"no scopes": func() error { _, _, err := service.CreateAPIToken(ctx, user.ID, "CI", nil, nil); return err },
+		"unknown scope": func() error {
+			_, _, err := service.CreateAPIToken(ctx, user.ID, "CI", []string{"root"}, nil)
+			return err
+		},
+		"past expiry": func() error {
+			_, _, err := service.CreateAPIToken(ctx, user.ID, "CI", []string{ScopeRead}, &past)
+			return err
+		},
+	} {
+		if err := call(); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	created, token, err := service.CreateAPIToken(ctx, user.ID, "CI", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+
+	authenticated, err := service.AuthenticateAPIToken(ctx, token, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("AuthenticateAPIToken failed: %v", err)
+	}
+	if authenticated.ID != created.ID {
+		t.Errorf("Expected token %d, got %d", created.ID, authenticated.ID)
+	}
+	stored, _ := store.GetAPITokenByHash(ctx, created.TokenHash)
+	if stored.LastUsedAt == nil || stored.LastUsedIP != "10.0.0.1" {
+		t.Errorf("Expected last use to be recorded, got %+v", stored)
+	}
+
+	for _, bad := range []string{"", "lst_unknown", token[len(APITokenPrefix):]} {
+		if _, err := service.AuthenticateAPIToken(ctx, bad, ""); !errors.Is(err, ErrInvalidAPIToken) {
+			t.Errorf("Expected ErrInvalidAPIToken for %q, got %v", bad, err)
+		}
+	}
+
+	if err := service.RevokeAPIToken(ctx, user.ID, created.ID); err != nil {
+		t.Fatalf("RevokeAPIToken failed: %v", err)
+	}
+	if _, err := service.AuthenticateAPIToken(ctx, token, ""); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("Expected a revoked token to be rejected, got %v", err)
+	}
+
+	// Expired tokens are rejected
+	expired := APITokenPrefix + "expired"
+	expiredHash, _ := hashToken(expired)
+	expiredAt := time.Now().Add(-time.Minute)
+	_, err = store.CreateAPIToken(ctx, storage.APIToken{UserID: user.ID, Name: "Expired", TokenPrefix: expired,
+		Scopes: []string{ScopeRead}, ExpiresAt: &expiredAt, TokenHash: expiredHash})
+	if err != nil {
+		t.Fatalf("Failed to create expired token: %v", err)
+	}
+	if _, err := service.AuthenticateAPIToken(ctx, expired, ""); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Errorf("Expected an expired token to be rejected, got %v", err)
+	}
+}
+
+func TestAPITokenAllows(t *testing.T) {
+	tests := []struct {
+		scopes []string
+		method string
+		path   string
+		want   bool
+	}{
+		{[]string{ScopeRead}, http.MethodGet, "/metrics", true},
+		{[]string{ScopeRead}, http.MethodGet, "/alerts/events", true},
+		{[]string{ScopeRead}, http.MethodGet, "/machines", true},
+		{[]string{ScopeRead}, http.MethodPost, "/alerts/rules", false},
+		{[]string{ScopeRead}, http.MethodDelete, "/machines/3", false},
+		{[]string{ScopeAlertsWrite}, http.MethodPost, "/alerts/rules", true},
+		{[]string{ScopeAlertsWrite}, http.MethodGet, "/alerts/rules", true},
+		{[]string{ScopeAlertsWrite}, http.MethodPost, "/alerts/rules/backtest", true},
+		{[]string{ScopeAlertsWrite}, http.MethodPost, "/alerts/events/5/ack", true},
+		{[]string{ScopeAlertsWrite}, http.MethodPut, "/silences/2", true},
+		{[]string{ScopeAlertsWrite}, http.MethodGet, "/metrics", false},
+		{[]string{ScopeAlertsWrite}, http.MethodDelete, "/machines/3", false},
+		{[]string{ScopeMachinesAdmin}, http.MethodDelete, "/machines/3", true},
+		{[]string{ScopeMachinesAdmin}, http.MethodPost, "/agent/register", true},
+		{[]string{ScopeNotificationsWrite}, http.MethodPost, "/notifications/webhooks", true},
+		{[]string{ScopeUsersAdmin}, http.MethodPost, "/auth/users", true},
+		{[]string{ScopeRead}, http.MethodGet, "/auth/me", true},
+		// Token and session management needs a browser session
+		{Scopes, http.MethodGet, "/auth/tokens", false},
+		{Scopes, http.MethodPost, "/auth/tokens", false},
+		{Scopes, http.MethodDelete, "/auth/sessions", false},
+		{Scopes, http.MethodPost, "/auth/change-password", false},
+		{Scopes, http.MethodGet, "/auth/meow", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := APITokenAllows(&storage.APIToken{Scopes: tt.scopes}, r); got != tt.want {
+			t.Errorf("APITokenAllows(%v, %s %s) = %v, want %v", tt.scopes, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRequireAuthMiddleware_APIToken(t *testing.T) {
+	service, _, user := newAPITokenTestService(t)
+	_, token, err := service.CreateAPIToken(context.Background(), user.ID, "CI", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+
+	handler := service.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, _ := GetUserFromContext(r.Context())
+		if _, ok := GetAPITokenFromContext(r.Context()); !ok {
+			http.Error(w, "API token not in context", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(u.Email))
+	}))
+
+	tests := []struct {
+		method string
+		auth   string
+		want   int
+	}{
+		{http.MethodGet, "Bearer " + token, http.StatusOK},
+		{http.MethodGet, "bearer " + token, http.StatusOK},
+		{http.MethodPost, "Bearer " + token, http.StatusForbidden},
+		{http.MethodGet, "Bearer lst_invalid", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/alerts/rules", nil)
+		req.Header.Set("Authorization", tt.auth)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s with %q: expected %d, got %d", tt.method, tt.auth[:10], tt.want, rec.Code)
+		}
+		if tt.want == http.StatusOK && rec.Body.String() != user.Email {
+			t.Errorf("Expected the token's user, got %q", rec.Body.String())
+		}
+	}
+
+	// Tokens of users the two-factor policy applies to stop working until they set it up
+	if err := service.SetTwoFactorPolicy(context.Background(), TwoFactorAll); err != nil {
+		t.Fatalf("SetTwoFactorPolicy failed: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/alerts/rules", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 while two-factor authentication is not set up, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// AuditLog returns the audit entries matching the filter, newest first
+func (s *Service) AuditLog(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	entries, err := s.store.ListAuditEntries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	return entries, nil
+}
@@ -9,7 +9,7 @@ import (
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
-// BootstrapAdmin creates or updates an admin user if ADMIN_EMAIL and ADMIN_PASSWORD env vars are set
+// BootstrapAdmin creates or updates an owner if ADMIN_EMAIL and ADMIN_PASSWORD env vars are set
 func BootstrapAdmin(ctx context.Context, store storage.Store) error {
 	adminEmail := os.Getenv("ADMIN_EMAIL")
 	adminPassword := os.Getenv("ADMIN_PASSWORD")
@@ -62,13 +62,13 @@ func TestLoginHandler_Success(t *testing.T) {
 			return
 		}
 
-		token, err := service.CreateSession(user.ID)
+		session, err := service.CreateSession(r.Context(), user.ID, ClientFromRequest(r))
 		if err != nil {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		SetSessionCookie(w, token, int(ttl.Seconds()), false) // false for testing
+		SetSessionCookie(w, session.AccessToken, int(ttl.Seconds()), false) // false for testing
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -218,16 +218,16 @@ func TestRequireAuthMiddleware_Authenticated(t *testing.T) {
 	}
 
 	// Create a valid session token
-	token, err := service.CreateSession(user.ID)
+	session, err := service.CreateSession(ctx, user.ID, ClientInfo{})
 	if err != nil {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
 
 	// Create request with valid session cookie
-	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
 	req.AddCookie(&http.Cookie{
 		Name:  CookieName,
-		Value: token,
+		Value: session.AccessToken,
 	})
 	rec := httptest.NewRecorder()
 
@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// JWT signing algorithms
+const (
+	AlgHS256 = "HS256" // HMAC-SHA256 with a shared secret, verifiable only by LunaSentri
+	AlgEdDSA = "EdDSA" // Ed25519, verifiable by anyone with the public key from the JWKS endpoint
+)
+
+// DefaultKeyID identifies the key made from AUTH_JWT_SECRET
+const DefaultKeyID = "default"
+
+// purposeKeyID identifies the key signing tokens LunaSentri issues to itself
+const purposeKeyID = "internal"
+
+// SigningKey signs and verifies JWTs. A key without a private part only verifies.
+type SigningKey struct {
+	ID        string // the token header's kid
+	Algorithm string // AlgHS256 or AlgEdDSA
+
+	secret     []byte             // HS256
+	privateKey ed25519.PrivateKey // EdDSA, nil for a verification-only key
+	publicKey  ed25519.PublicKey  // EdDSA
+}
+
+// NewHMACKey returns an HS256 key
+func NewHMACKey(id string, secret []byte) (*SigningKey, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("key %q: secret cannot be empty", id)
+	}
+	return &SigningKey{ID: id, Algorithm: AlgHS256, secret: secret}, nil
+}
+
+// NewEd25519Key returns an EdDSA key from a private key
+func NewEd25519Key(id string, privateKey ed25519.PrivateKey) (*SigningKey, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("key %q: Ed25519 private key must be %d bytes", id, ed25519.PrivateKeySize)
+	}
+	return &SigningKey{
+		ID:         id,
+		Algorithm:  AlgEdDSA,
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// NewEd25519PublicKey returns an EdDSA key that only verifies, for a retired key whose private
+// part is gone
+func NewEd25519PublicKey(id string, publicKey ed25519.PublicKey) (*SigningKey, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("key %q: Ed25519 public key must be %d bytes", id, ed25519.PublicKeySize)
+	}
+	return &SigningKey{ID: id, Algorithm: AlgEdDSA, publicKey: publicKey}, nil
+}
+
+// canSign reports whether the key has what it takes to sign
+func (k *SigningKey) canSign() bool {
+	return k.Algorithm == AlgHS256 || k.privateKey != nil
+}
+
+// sign returns the base64url signature of the message
+func (k *SigningKey) sign(message string) string {
+	var signature []byte
+	if k.Algorithm == AlgEdDSA {
+		signature = ed25519.Sign(k.privateKey, []byte(message))
+	} else {
+		h := hmac.New(sha256.New, k.secret)
+		h.Write([]byte(message))
+		signature = h.Sum(nil)
+	}
+	return base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// verify reports whether signature is the key's base64url signature of the message
+func (k *SigningKey) verify(message, signature string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	if k.Algorithm == AlgEdDSA {
+		return ed25519.Verify(k.publicKey, []byte(message), raw)
+	}
+	h := hmac.New(sha256.New, k.secret)
+	h.Write([]byte(message))
+	return hmac.Equal(raw, h.Sum(nil))
+}
+
+// KeySet holds the key new tokens are signed with and the older keys that still verify tokens
+// signed before a rotation
+type KeySet struct {
+	primary *SigningKey
+	keys    []*SigningKey // primary first
+}
+
+// NewKeySet returns a key set signing with primary and also verifying with the other keys
+func NewKeySet(primary *SigningKey, verificationKeys ...*SigningKey) (*KeySet, error) {
+	if primary == nil {
+		return nil, fmt.Errorf("a signing key is required")
+	}
+	if !primary.canSign() {
+		return nil, fmt.Errorf("key %q cannot sign: it has no private key", primary.ID)
+	}
+
+	keys := append([]*SigningKey{primary}, verificationKeys...)
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if seen[key.ID] {
+			return nil, fmt.Errorf("duplicate key ID %q", key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return &KeySet{primary: primary, keys: keys}, nil
+}
+
+// Primary returns the key new tokens are signed with
+func (ks *KeySet) Primary() *SigningKey {
+	return ks.primary
+}
+
+// Keys returns every key, the primary one first
+func (ks *KeySet) Keys() []*SigningKey {
+	return ks.keys
+}
+
+// candidates returns the keys that may have signed a token with the header. Tokens without a
+// kid predate key IDs and were signed with an HS256 secret; keys without an ID match any kid.
+func (ks *KeySet) candidates(kid, alg string) []*SigningKey {
+	var keys []*SigningKey
+	for _, key := range ks.keys {
+		if key.Algorithm == alg && (key.ID == kid || key.ID == "" || (kid == "" && alg == AlgHS256)) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ParseKeySet parses AUTH_JWT_KEYS, a comma-separated list of kid:algorithm:key entries, the
+// first of which signs new tokens while the rest only verify. Algorithms are:
+//
+//	hs256           key is the shared secret
+//	ed25519         key is the base64 Ed25519 seed (32 bytes) or private key (64 bytes)
+//	ed25519-public  key is the base64 Ed25519 public key of a retired key, verification only
+//
+// A non-empty legacySecret, from AUTH_JWT_SECRET, is added as an HS256 verification key with
+// DefaultKeyID; with no keys listed, it is the signing key instead.
+func ParseKeySet(spec, legacySecret string) (*KeySet, error) {
+	var keys []*SigningKey
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		key, err := parseKey(entry)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if legacySecret != "" {
+		key, err := NewHMACKey(DefaultKeyID, []byte(legacySecret))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no JWT keys configured")
+	}
+	return NewKeySet(keys[0], keys[1:]...)
+}
+
+// parseKey parses one kid:algorithm:key entry of AUTH_JWT_KEYS
+func parseKey(entry string) (*SigningKey, error) {
+	parts := strings.SplitN(entry, ":", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return nil, fmt.Errorf("JWT key must be kid:algorithm:key")
+	}
+	id, alg, material := parts[0], strings.ToLower(parts[1]), parts[2]
+
+	switch alg {
+	case "hs256":
+		return NewHMACKey(id, []byte(material))
+	case "ed25519", "ed25519-public":
+		raw, err := base64.StdEncoding.DecodeString(material)
+		if err != nil {
+			if raw, err = base64.RawURLEncoding.DecodeString(material); err != nil {
+				return nil, fmt.Errorf("key %q: key must be base64", id)
+			}
+		}
+		if alg == "ed25519-public" {
+			return NewEd25519PublicKey(id, raw)
+		}
+		if len(raw) == ed25519.SeedSize {
+			raw = ed25519.NewKeyFromSeed(raw)
+		}
+		return NewEd25519Key(id, raw)
+	default:
+		return nil, fmt.Errorf("key %q: unknown algorithm %q", id, parts[1])
+	}
+}
+
+// purposeKey returns the HS256 key that signs tokens LunaSentri issues to itself, such as login
+// challenges. It is derived from the primary key's secret or private key, so it is never published
+// and no token it signs verifies against the key set or the JWKS. Rotating the primary key
+// rotates it too, which only cuts short the few minutes such tokens last.
+func (ks *KeySet) purposeKey() *SigningKey {
+	material := ks.primary.secret
+	if ks.primary.Algorithm == AlgEdDSA {
+		material = ks.primary.privateKey.Seed()
+	}
+	h := hmac.New(sha256.New, material)
+	h.Write([]byte("lunasentri purpose tokens"))
+	return &SigningKey{ID: purposeKeyID, Algorithm: AlgHS256, secret: h.Sum(nil)}
+}
+
+// JWK is a public key in JSON Web Key form
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys other services verify tokens with. HS256 secrets are never
+// published, so tokens signed with them can only be verified by LunaSentri.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if key.Algorithm != AlgEdDSA {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(key.publicKey),
+			KeyID:     key.ID,
+			Algorithm: AlgEdDSA,
+			Use:       "sig",
+		})
+	}
+	return jwks
+}
+
+// JWKS returns the public keys that verify the service's tokens
+func (s *Service) JWKS() JWKS {
+	return s.keys.JWKS()
+}
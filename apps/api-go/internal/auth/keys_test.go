@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeySet(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize))
+	public := base64.StdEncoding.EncodeToString(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name      string
+		spec      string
+		secret    string
+		primary   string
+		algorithm string
+		keys      int
+		wantErr   bool
+	}{
+		{name: "secret only", secret: "s3cret", primary: DefaultKeyID, algorithm: AlgHS256, keys: 1},
+		{name: "keys only", spec: "new:ed25519:" + seed + ", old:hs256:a:b", primary: "new", algorithm: AlgEdDSA, keys: 2},
+		{name: "secret verifies", spec: "new:HS256:other", secret: "s3cret", primary: "new", algorithm: AlgHS256, keys: 2},
+		{name: "retired public key", spec: "new:hs256:x,old:ed25519-public:" + public, primary: "new", algorithm: AlgHS256, keys: 2},
+		{name: "nothing", wantErr: true},
+		{name: "missing algorithm", spec: "new", wantErr: true},
+		{name: "empty secret", spec: "new:hs256:", wantErr: true},
+		{name: "unknown algorithm", spec: "new:rs256:x", wantErr: true},
+		{name: "bad base64", spec: "new:ed25519:not base64", wantErr: true},
+		{name: "wrong seed size", spec: "new:ed25519:" + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+		{name: "public key signing", spec: "old:ed25519-public:" + public, wantErr: true},
+		{name: "duplicate ID", spec: "default:hs256:x", secret: "s3cret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseKeySet(tt.spec, tt.secret)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseKeySet failed: %v", err)
+			}
+			if keys.Primary().ID != tt.primary || keys.Primary().Algorithm != tt.algorithm || len(keys.Keys()) != tt.keys {
+				t.Errorf("Expected primary %s (%s) of %d keys, got %s (%s) of %d", tt.primary, tt.algorithm, tt.keys,
+					keys.Primary().ID, keys.Primary().Algorithm, len(keys.Keys()))
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	store := newMockStore()
+	ctx := context.Background()
+	hashedPassword, _ := HashPassword("password123")
+	user, err := store.CreateUser(ctx, "rotate@example.com", hashedPassword)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	serviceWith := func(spec, secret string) *Service {
+		keys, err := ParseKeySet(spec, secret)
+		if err != nil {
+			t.Fatalf("ParseKeySet failed: %v", err)
+		}
+		service, err := NewServiceWithKeys(store, keys, 15*time.Minute)
+		if err != nil {
+			t.Fatalf("NewServiceWithKeys failed: %v", err)
+		}
+		return service
+	}
+
+	// Tokens from before key IDs carry no kid and were signed with AUTH_JWT_SECRET
+	before := serviceWith("", "old-secret")
+	oldTokens, err := before.CreateSession(ctx, user.ID, ClientInfo{})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	session := oldTokens.SessionID
+	legacy, err := CreateSessionJWT(user.ID, session, []byte("old-secret"), time.Minute)
+	if err != nil {
+		t.Fatalf("CreateSessionJWT failed: %v", err)
+	}
+
+	// Rotating to an Ed25519 key keeps the old secret for verification: nobody is logged out
+	seed := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", ed25519.SeedSize)))
+	after := serviceWith("2025-06:ed25519:"+seed, "old-secret")
+	for name, token := range map[string]string{"old": oldTokens.AccessToken, "legacy": legacy} {
+		if _, err := after.ValidateSession(ctx, token, ""); err != nil {
+			t.Errorf("Expected the %s token to stay valid after the rotation, got %v", name, err)
+		}
+	}
+
+	newTokens, err := after.RefreshSession(ctx, oldTokens.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RefreshSession failed: %v", err)
+	}
+	header := decodeSegment(t, newTokens.AccessToken, 0)
+	if header["kid"] != "2025-06" || header["alg"] != AlgEdDSA {
+		t.Errorf("Expected new tokens to be signed with the new key, got header %v", header)
+	}
+	if _, err := after.ValidateSession(ctx, newTokens.AccessToken, ""); err != nil {
+		t.Errorf("Expected the new token to be valid, got %v", err)
+	}
+
+	// Once the old secret is dropped, tokens signed with it stop working
+	retired := serviceWith("2025-06:ed25519:"+seed, "")
+	if _, err := retired.ValidateSession(ctx, oldTokens.AccessToken, ""); err == nil {
+		t.Error("Expected a token signed with a dropped key to be rejected")
+	}
+	if _, err := retired.ValidateSession(ctx, newTokens.AccessToken, ""); err != nil {
+		t.Errorf("Expected the new token to stay valid, got %v", err)
+	}
+
+	// A token cannot switch the algorithm to HMAC keyed with the published public key
+	public := ed25519.NewKeyFromSeed([]byte(strings.Repeat("k", ed25519.SeedSize))).Public().(ed25519.PublicKey)
+	forgedHeader := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT","kid":"2025-06"}`))
+	message := forgedHeader + "." + strings.Split(newTokens.AccessToken, ".")[1]
+	h := hmac.New(sha256.New, public)
+	h.Write([]byte(message))
+	forged := message + "." + base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+	if _, err := retired.ValidateSession(ctx, forged, ""); err == nil {
+		t.Error("Expected a token with a switched algorithm to be rejected")
+	}
+
+	// Only public keys are published
+	jwks := after.JWKS()
+	if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "2025-06" || jwks.Keys[0].X != base64.RawURLEncoding.EncodeToString(public) {
+		t.Errorf("Expected only the Ed25519 key in the JWKS, got %+v", jwks)
+	}
+}
+
+// decodeSegment decodes a JSON part of a JWT
+func decodeSegment(t *testing.T, token string, index int) map[string]any {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[index])
+	if err != nil {
+		t.Fatalf("Failed to decode token: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		t.Fatalf("Failed to unmarshal token: %v", err)
+	}
+	return fields
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// Kinds of attempts, counted separately so that, say, password reset requests do not lock anyone
+// out of logging in
+const (
+	AttemptLogin         = "login"          // passwords and two-factor codes
+	AttemptPasswordReset = "password-reset" // reset requests and reset tokens
+	AttemptAgent         = "agent"          // agent API keys
+)
+
+// LockoutPolicy decides how failed attempts for a key are slowed down and locked out
+type LockoutPolicy struct {
+	FreeAttempts    int           // failures allowed before delays start
+	BaseDelay       time.Duration // wait after the first failure beyond the free ones, doubling with each further failure
+	MaxDelay        time.Duration // longest wait between attempts short of a lockout
+	LockoutAfter    int           // failures that lock the key out
+	LockoutDuration time.Duration // how long a lockout lasts
+	ResetAfter      time.Duration // failures are forgotten after this long without one
+}
+
+var (
+	// AccountLockoutPolicy applies to attempts against one account
+	AccountLockoutPolicy = LockoutPolicy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+
+	// IPLockoutPolicy applies to attempts from one client IP, which may be shared by many users
+	IPLockoutPolicy = LockoutPolicy{
+		FreeAttempts:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		LockoutAfter:    50,
+		LockoutDuration: 15 * time.Minute,
+		ResetAfter:      time.Hour,
+	}
+)
+
+// AccountKey returns the key counting attempts of a kind against the account with the email
+func AccountKey(kind, email string) string {
+	return kind + "-account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPKey returns the key counting attempts of a kind from the client IP
+func IPKey(kind, ip string) string {
+	return kind + "-ip:" + ip
+}
+
+// apiKeyPrefixLength is how much of an API key identifies it in a throttle key
+const apiKeyPrefixLength = 8
+
+// APIKeyKey returns the key counting attempts of a kind with the API key. Only the key's first
+// characters are used, so the key itself is never stored.
+func APIKeyKey(kind, apiKey string) string {
+	return kind + "-key:" + apiKey[:min(len(apiKey), apiKeyPrefixLength)]
+}
+
+// LoginThrottle slows down and locks out repeated failed attempts to guess credentials, per
+// account and per client IP. A nil LoginThrottle allows everything.
+type LoginThrottle struct {
+	store   storage.Store
+	account LockoutPolicy
+	ip      LockoutPolicy
+}
+
+// NewLoginThrottle creates a throttle with the default policies
+func NewLoginThrottle(store storage.Store) *LoginThrottle {
+	return &LoginThrottle{store: store, account: AccountLockoutPolicy, ip: IPLockoutPolicy}
+}
+
+// SetLockout changes how many failures lock an account out, and for how long. Client IPs are
+// locked out after five times as many.
+func (t *LoginThrottle) SetLockout(attempts int, duration time.Duration) error {
+	if attempts <= t.account.FreeAttempts {
+		return fmt.Errorf("lockout attempts must be more than %d", t.account.FreeAttempts)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("lockout duration must be positive")
+	}
+	t.account.LockoutAfter = attempts
+	t.account.LockoutDuration = duration
+	t.ip.LockoutAfter = 5 * attempts
+	t.ip.LockoutDuration = duration
+	return nil
+}
+
+// policy returns the policy for the key
+func (t *LoginThrottle) policy(key string) LockoutPolicy {
+	if strings.Contains(key, "-account:") || strings.Contains(key, "-key:") {
+		return t.account
+	}
+	return t.ip
+}
+
+// Check returns how long the client must wait before its next attempt, zero if it may try now
+func (t *LoginThrottle) Check(ctx context.Context, keys ...string) (time.Duration, error) {
+	if t == nil {
+		return 0, nil
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		attempts, err := t.store.GetLoginAttempts(ctx, key)
+		if errors.Is(err, storage.ErrLoginAttemptsNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to check login attempts: %w", err)
+		}
+		wait = max(wait, t.policy(key).wait(attempts, now))
+	}
+	return wait, nil
+}
+
+// Fail records a failed attempt for each key, locking out keys that reach their policy's limit
+func (t *LoginThrottle) Fail(ctx context.Context, keys ...string) {
+	if t == nil {
+		return
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		policy := t.policy(key)
+		attempts, err := t.store.RecordLoginFailure(ctx, key, now.Add(-policy.ResetAfter))
+		if err != nil {
+			log.Printf("Failed to record failed attempt for %s: %v", key, err)
+			continue
+		}
+		if attempts.Failures < policy.LockoutAfter || (attempts.LockedUntil != nil && attempts.LockedUntil.After(now)) {
+			continue
+		}
+
+		until := now.Add(policy.LockoutDuration)
+		if err := t.store.LockLogin(ctx, key, until); err != nil {
+			log.Printf("Failed to lock out %s: %v", key, err)
+			continue
+		}
+		log.Printf("Lockout: %s locked until %s after %d failed attempts", key, until.UTC().Format(time.RFC3339), attempts.Failures)
+		audit.Record(ctx, t.store, audit.Event{Action: "lockout.lock", TargetType: "lockout", TargetID: key})
+	}
+}
+
+// Succeed forgets the failed attempts for each key after a successful attempt
+func (t *LoginThrottle) Succeed(ctx context.Context, keys ...string) {
+	if t == nil {
+		return
+	}
+
+	for _, key := range keys {
+		if err := t.store.ClearLoginAttempts(ctx, key); err != nil && !errors.Is(err, storage.ErrLoginAttemptsNotFound) {
+			log.Printf("Failed to clear failed attempts for %s: %v", key, err)
+		}
+	}
+}
+
+// wait returns how long after now the next attempt is allowed
+func (p LockoutPolicy) wait(attempts *storage.LoginAttempts, now time.Time) time.Duration {
+	if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+		return attempts.LockedUntil.Sub(now)
+	}
+	if now.Sub(attempts.LastFailureAt) >= p.ResetAfter || attempts.Failures <= p.FreeAttempts {
+		return 0
+	}
+
+	delay := p.MaxDelay
+	if extra := attempts.Failures - p.FreeAttempts - 1; extra < 30 && p.BaseDelay<<extra < p.MaxDelay {
+		delay = p.BaseDelay << extra
+	}
+	return max(attempts.LastFailureAt.Add(delay).Sub(now), 0)
+}
+
+// Throttle returns the throttle guarding logins, password resets and agent API keys
+func (s *Service) Throttle() *LoginThrottle {
+	return s.throttle
+}
+
+// LoginLockouts returns the accounts and client IPs currently locked out
+func (s *Service) LoginLockouts(ctx context.Context) ([]storage.LoginAttempts, error) {
+	lockouts, err := s.store.ListLoginLockouts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lockouts: %w", err)
+	}
+	return lockouts, nil
+}
+
+// Unlock lifts the lockout of a key from LoginLockouts and forgets its failed attempts
+func (s *Service) Unlock(ctx context.Context, actor *storage.User, key string) error {
+	if err := s.store.ClearLoginAttempts(ctx, key); err != nil {
+		return err
+	}
+	log.Printf("Lockout: %s unlocked by user %d", key, actor.ID)
+	audit.Record(ctx, s.store, audit.Event{Action: "lockout.unlock", TargetType: "lockout", TargetID: key})
+	return nil
+}
+
+// UnlockUser lifts any lockout of the user's account, for logins and password resets alike
+func (s *Service) UnlockUser(ctx context.Context, actor *storage.User, userID int) error {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	for _, key := range []string{AccountKey(AttemptLogin, user.Email), AccountKey(AttemptPasswordReset, user.Email)} {
+		if err := s.store.ClearLoginAttempts(ctx, key); err != nil && !errors.Is(err, storage.ErrLoginAttemptsNotFound) {
+			return fmt.Errorf("failed to unlock user: %w", err)
+		}
+	}
+	log.Printf("Lockout: user %d unlocked by user %d", userID, actor.ID)
+	audit.Record(ctx, s.store, audit.Event{Action: "lockout.unlock", TargetType: "user", TargetID: userID})
+	return nil
+}
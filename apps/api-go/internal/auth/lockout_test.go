@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestLockoutPolicyWait(t *testing.T) {
+	now := time.Now()
+	policy := AccountLockoutPolicy
+	locked := now.Add(10 * time.Minute)
+
+	tests := []struct {
+		name     string
+		attempts storage.LoginAttempts
+		want     time.Duration
+	}{
+		{"free attempts", storage.LoginAttempts{Failures: 3, LastFailureAt: now}, 0},
+		{"first delay", storage.LoginAttempts{Failures: 4, LastFailureAt: now}, time.Second},
+		{"doubling", storage.LoginAttempts{Failures: 6, LastFailureAt: now}, 4 * time.Second},
+		{"capped", storage.LoginAttempts{Failures: 40, LastFailureAt: now}, time.Minute},
+		{"partly waited", storage.LoginAttempts{Failures: 5, LastFailureAt: now.Add(-time.Second)}, time.Second},
+		{"fully waited", storage.LoginAttempts{Failures: 5, LastFailureAt: now.Add(-time.Minute)}, 0},
+		{"forgotten", storage.LoginAttempts{Failures: 9, LastFailureAt: now.Add(-2 * time.Hour)}, 0},
+		{"locked out", storage.LoginAttempts{Failures: 10, LastFailureAt: now, LockedUntil: &locked}, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := policy.wait(&tt.attempts, now); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	service, _, user := newAPITokenTestService(t)
+	ctx := context.Background()
+	throttle := service.Throttle()
+	accountKey := AccountKey(AttemptLogin, user.Email)
+	ipKey := IPKey(AttemptLogin, "203.0.113.7")
+
+	if err := throttle.SetLockout(3, time.Minute); err == nil {
+		t.Error("Expected a lockout within the free attempts to be rejected")
+	}
+	if err := throttle.SetLockout(5, 0); err == nil {
+		t.Error("Expected a zero lockout duration to be rejected")
+	}
+	if err := throttle.SetLockout(5, time.Hour); err != nil {
+		t.Fatalf("SetLockout failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		throttle.Fail(ctx, ipKey, accountKey)
+	}
+	if wait, err := throttle.Check(ctx, ipKey, accountKey); err != nil || wait != 0 {
+		t.Fatalf("Expected no wait within the free attempts, got %v, %v", wait, err)
+	}
+	throttle.Fail(ctx, ipKey, accountKey)
+	if wait, _ := throttle.Check(ctx, ipKey, accountKey); wait <= 0 || wait > time.Second {
+		t.Errorf("Expected a delay of up to a second, got %v", wait)
+	}
+
+	// The fifth failure locks the account out, but not the client IP
+	throttle.Fail(ctx, ipKey, accountKey)
+	if wait, _ := throttle.Check(ctx, accountKey); wait < 59*time.Minute {
+		t.Errorf("Expected the account to be locked for an hour, got %v", wait)
+	}
+	lockouts, err := service.LoginLockouts(ctx)
+	if err != nil {
+		t.Fatalf("LoginLockouts failed: %v", err)
+	}
+	if len(lockouts) != 1 || lockouts[0].Key != accountKey {
+		t.Fatalf("Expected only the account to be locked out, got %+v", lockouts)
+	}
+
+	if err := service.UnlockUser(ctx, user, user.ID); err != nil {
+		t.Fatalf("UnlockUser failed: %v", err)
+	}
+	if wait, _ := throttle.Check(ctx, accountKey); wait != 0 {
+		t.Errorf("Expected the account to be unlocked, got a wait of %v", wait)
+	}
+	if err := service.UnlockUser(ctx, user, 9999); err == nil {
+		t.Error("Expected unlocking an unknown user to fail")
+	}
+
+	// A success forgets the failures
+	throttle.Succeed(ctx, ipKey)
+	if wait, _ := throttle.Check(ctx, ipKey); wait != 0 {
+		t.Errorf("Expected no wait after a success, got %v", wait)
+	}
+
+	// A nil throttle allows everything
+	var none *LoginThrottle
+	none.Fail(ctx, ipKey)
+	if wait, err := none.Check(ctx, ipKey); wait != 0 || err != nil {
+		t.Errorf("Expected a nil throttle to allow everything, got %v, %v", wait, err)
+	}
+}
@@ -14,11 +14,25 @@ type contextKey string
 const (
 	// UserContextKey is the key used to store the user in the request context
 	UserContextKey contextKey = "user"
+
+	// SessionContextKey is the key used to store the session ID in the request context
+	SessionContextKey contextKey = "session"
+
+	// APITokenContextKey is the key used to store the API token in the request context
+	APITokenContextKey contextKey = "api_token"
 )
 
-// RequireAuth is a middleware that validates the session and loads the user into the context
+// RequireAuth is a middleware that validates the session, or the API token of an
+// "Authorization: Bearer" header, checks the user's role allows the request and loads the user
+// and the organization the request acts in into the context. Session users the two-factor policy
+// applies to are held to setting it up first.
 func (s *Service) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			s.serveWithAPIToken(w, r, token, next)
+			return
+		}
+
 		// Get session cookie
 		token, err := GetSessionCookie(r)
 		if err != nil {
@@ -27,8 +41,8 @@ func (s *Service) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		// Validate JWT and get user ID
-		userID, err := s.ValidateSession(token)
+		// Validate JWT and its session
+		claims, err := s.ValidateSession(r.Context(), token, clientIP(r))
 		if err != nil {
 			log.Printf("Session validation failed: %v", err)
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -36,21 +50,100 @@ func (s *Service) RequireAuth(next http.Handler) http.Handler {
 		}
 
 		// Get user from database
-		user, err := s.GetUser(r.Context(), userID)
+		user, err := s.GetUser(r.Context(), claims.UserID)
 		if err != nil {
 			log.Printf("Failed to get user: %v", err)
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		// Store user in context
+		if !RoleAllows(user.Role, r) {
+			log.Printf("User %d with role %s denied %s %s", user.ID, user.Role, r.Method, r.URL.Path)
+			http.Error(w, "Forbidden: your role does not allow this request", http.StatusForbidden)
+			return
+		}
+
+		// Users the two-factor policy applies to can only set it up until they have
+		setupNeeded, err := s.needsTwoFactorSetup(r, user)
+		if err != nil {
+			log.Printf("Failed to check two-factor policy: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if setupNeeded {
+			http.Error(w, "Forbidden: set up two-factor authentication to continue", http.StatusForbidden)
+			return
+		}
+
+		// Store user and session in context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		ctx = context.WithValue(ctx, SessionContextKey, claims.SessionID)
+		s.serveInOrganization(w, r.WithContext(ctx), user, next)
 	})
 }
 
+// serveWithAPIToken authenticates a request made with an API token and serves it if both the
+// token's scopes and its owner's role allow it
+func (s *Service) serveWithAPIToken(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	apiToken, err := s.AuthenticateAPIToken(r.Context(), token, clientIP(r))
+	if err != nil {
+		log.Printf("API token authentication failed: %v", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !APITokenAllows(apiToken, r) {
+		log.Printf("API token %d denied %s %s: scopes %v", apiToken.ID, r.Method, r.URL.Path, apiToken.Scopes)
+		http.Error(w, "Forbidden: API token scopes do not allow this request", http.StatusForbidden)
+		return
+	}
+
+	user, err := s.GetUser(r.Context(), apiToken.UserID)
+	if err != nil {
+		log.Printf("Failed to get user: %v", err)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// A token never does more than its owner's current role allows
+	if !RoleAllows(user.Role, r) {
+		log.Printf("API token %d denied %s %s: role %s", apiToken.ID, r.Method, r.URL.Path, user.Role)
+		http.Error(w, "Forbidden: your role does not allow this request", http.StatusForbidden)
+		return
+	}
+
+	// Nor does it get around the two-factor policy while its owner has not set it up
+	setupNeeded, err := s.needsTwoFactorSetup(r, user)
+	if err != nil {
+		log.Printf("Failed to check two-factor policy: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if setupNeeded {
+		log.Printf("API token %d denied %s %s: user %d has not set up two-factor authentication", apiToken.ID, r.Method, r.URL.Path, user.ID)
+		http.Error(w, "Forbidden: set up two-factor authentication to continue", http.StatusForbidden)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), UserContextKey, user)
+	ctx = context.WithValue(ctx, APITokenContextKey, apiToken)
+	s.serveInOrganization(w, r.WithContext(ctx), user, next)
+}
+
 // GetUserFromContext retrieves the user from the request context
 func GetUserFromContext(ctx context.Context) (*storage.User, bool) {
 	user, ok := ctx.Value(UserContextKey).(*storage.User)
 	return user, ok
 }
+
+// GetSessionIDFromContext retrieves the ID of the request's session from the context
+func GetSessionIDFromContext(ctx context.Context) (int, bool) {
+	sessionID, ok := ctx.Value(SessionContextKey).(int)
+	return sessionID, ok
+}
+
+// GetAPITokenFromContext retrieves the API token the request was made with, if any
+func GetAPITokenFromContext(ctx context.Context) (*storage.APIToken, bool) {
+	token, ok := ctx.Value(APITokenContextKey).(*storage.APIToken)
+	return token, ok
+}
@@ -0,0 +1,688 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/config"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// OIDCLoginTTL is how long a user has to sign in at the provider before the login expires
+	OIDCLoginTTL = 10 * time.Minute
+
+	// OIDCStateCookieName is the cookie tying a single sign-on callback to the browser that started it
+	OIDCStateCookieName = "lunasentri_oidc_state"
+
+	// oidcCookiePath limits the state cookie to the single sign-on endpoints
+	oidcCookiePath = "/auth/oidc"
+
+	// oidcKeyRefreshInterval is the least time between fetches of the provider's signing keys, so
+	// tokens with unknown key IDs cannot make us hammer the provider
+	oidcKeyRefreshInterval = time.Minute
+
+	// oidcClockSkew is how far the provider's clock may be ahead or behind ours
+	oidcClockSkew = time.Minute
+
+	// oidcMaxResponseSize bounds the provider responses we read
+	oidcMaxResponseSize = 1 << 20
+)
+
+var (
+	// ErrOIDCNotConfigured is returned when single sign-on is used without a provider configured
+	ErrOIDCNotConfigured = errors.New("single sign-on is not configured")
+
+	// ErrInvalidOIDCLogin is returned when a single sign-on callback does not match a login in
+	// progress, or the provider's response cannot be trusted
+	ErrInvalidOIDCLogin = errors.New("invalid or expired single sign-on login")
+
+	// ErrOIDCUserNotProvisioned is returned when a provider user has no account and automatic
+	// provisioning is off
+	ErrOIDCUserNotProvisioned = errors.New("no account exists for this single sign-on user")
+
+	// ErrPasswordLoginDisabled is returned by password operations when users must sign in with
+	// single sign-on
+	ErrPasswordLoginDisabled = errors.New("password login is disabled")
+)
+
+// OIDCProvider signs users in with an OpenID Connect provider, using the authorization code flow
+// with PKCE. The provider's endpoints and signing keys are discovered on first use.
+type OIDCProvider struct {
+	config *config.OIDCConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey // signing keys by key ID
+	keysFetchedAt time.Time
+}
+
+// oidcDiscovery is the part of the provider's discovery document we use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcIdentity is who the provider says signed in
+type oidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// NewOIDCProvider creates a provider from its configuration, checking the role mapping
+func NewOIDCProvider(cfg *config.OIDCConfig) (*OIDCProvider, error) {
+	if !cfg.IsEnabled() {
+		return nil, fmt.Errorf("OIDC issuer URL and client ID are required")
+	}
+	if _, err := url.ParseRequestURI(cfg.RedirectURL); err != nil {
+		return nil, fmt.Errorf("invalid OIDC redirect URL: %w", err)
+	}
+	for group, role := range cfg.RoleMapping {
+		if !slices.Contains(Roles, role) {
+			return nil, fmt.Errorf("unknown role %q for group %q, valid roles are %s", role, group, strings.Join(Roles, ", "))
+		}
+	}
+	if cfg.DefaultRole != "" && !slices.Contains(Roles, cfg.DefaultRole) {
+		return nil, fmt.Errorf("unknown default role %q, valid roles are %s", cfg.DefaultRole, strings.Join(Roles, ", "))
+	}
+
+	return &OIDCProvider{
+		config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Name returns the provider's display name
+func (p *OIDCProvider) Name() string {
+	return p.config.ProviderName
+}
+
+// SetOIDCProvider enables single sign-on with the provider
+func (s *Service) SetOIDCProvider(provider *OIDCProvider) {
+	s.oidc = provider
+}
+
+// OIDCProvider returns the single sign-on provider, or nil if single sign-on is not configured
+func (s *Service) OIDCProvider() *OIDCProvider {
+	return s.oidc
+}
+
+// SetPasswordLogin turns password login, password reset and self-registration on or off. It can
+// only be turned off while single sign-on is configured, so users are never locked out.
+func (s *Service) SetPasswordLogin(enabled bool) error {
+	if !enabled && s.oidc == nil {
+		return fmt.Errorf("password login can only be disabled when single sign-on is configured")
+	}
+	s.passwordLoginDisabled = !enabled
+	return nil
+}
+
+// PasswordLoginEnabled reports whether users can sign in with a password
+func (s *Service) PasswordLoginEnabled() bool {
+	return !s.passwordLoginDisabled
+}
+
+// BeginOIDCLogin starts a single sign-on login, returning the provider URL to send the browser to
+// and the state to bind to the browser. returnTo is the web app path to land on afterwards.
+func (s *Service) BeginOIDCLogin(ctx context.Context, returnTo string) (string, string, error) {
+	if s.oidc == nil {
+		return "", "", ErrOIDCNotConfigured
+	}
+
+	discovery, err := s.oidc.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomOIDCValue()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomOIDCValue()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randomOIDCValue()
+	if err != nil {
+		return "", "", err
+	}
+	stateHash, err := hashToken(state)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = s.store.CreateOIDCLogin(ctx, storage.OIDCLogin{
+		StateHash:    stateHash,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ReturnTo:     safeReturnPath(returnTo),
+		ExpiresAt:    time.Now().Add(OIDCLoginTTL),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start single sign-on login: %w", err)
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", s.oidc.config.ClientID)
+	query.Set("redirect_uri", s.oidc.config.RedirectURL)
+	query.Set("scope", strings.Join(s.oidc.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), state, nil
+}
+
+// CompleteOIDCLogin finishes a single sign-on login from the provider's callback, exchanging the
+// code for an ID token. It returns the user, creating or linking their account as configured,
+// and the web app path to land on.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, state, code string) (*storage.User, string, error) {
+	if s.oidc == nil {
+		return nil, "", ErrOIDCNotConfigured
+	}
+	if state == "" || code == "" {
+		return nil, "", ErrInvalidOIDCLogin
+	}
+
+	stateHash, err := hashToken(state)
+	if err != nil {
+		return nil, "", ErrInvalidOIDCLogin
+	}
+	login, err := s.store.ConsumeOIDCLogin(ctx, stateHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrOIDCLoginNotFound) {
+			return nil, "", ErrInvalidOIDCLogin
+		}
+		return nil, "", fmt.Errorf("failed to get single sign-on login: %w", err)
+	}
+
+	identity, err := s.oidc.exchange(ctx, code, login)
+	if err != nil {
+		return nil, "", err
+	}
+
+	user, err := s.oidcUser(ctx, identity)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, login.ReturnTo, nil
+}
+
+// oidcUser returns the account for the provider identity: the one already linked to it, else the
+// one with its email, else a new one. Roles follow the provider's groups when a mapping is set.
+func (s *Service) oidcUser(ctx context.Context, identity *oidcIdentity) (*storage.User, error) {
+	cfg := s.oidc.config
+
+	user, err := s.store.GetUserByIdentity(ctx, cfg.IssuerURL, identity.Subject)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		if identity.Email == "" {
+			return nil, fmt.Errorf("%w: the provider did not send the %s claim", ErrInvalidOIDCLogin, cfg.EmailClaim)
+		}
+		// The email decides which account the identity gets, so it must be verified unless the
+		// provider is trusted to only hand out addresses its users own
+		if !identity.EmailVerified && !cfg.TrustEmail {
+			return nil, fmt.Errorf("%w: the provider has not verified %s", ErrInvalidOIDCLogin, identity.Email)
+		}
+
+		user, err = s.store.GetUserByEmail(ctx, identity.Email)
+		if errors.Is(err, storage.ErrUserNotFound) {
+			if !cfg.AutoProvision {
+				return nil, ErrOIDCUserNotProvisioned
+			}
+			user, err = s.provisionOIDCUser(ctx, identity)
+		} else if err == nil {
+			log.Printf("Linking single sign-on identity %s to existing user %d", identity.Subject, user.ID)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find single sign-on user: %w", err)
+	}
+
+	err = s.store.LinkUserIdentity(ctx, storage.UserIdentity{
+		UserID:  user.ID,
+		Issuer:  cfg.IssuerURL,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.RoleMapping) > 0 {
+		if role := s.oidc.mappedRole(identity.Groups); role != user.Role {
+			if err := s.store.SetUserRole(ctx, user.ID, role); err != nil {
+				// Most likely the last owner, who keeps their role
+				log.Printf("Warning: failed to give single sign-on user %d the %s role: %v", user.ID, role, err)
+			} else {
+				log.Printf("Single sign-on user %d role changed from %s to %s", user.ID, user.Role, role)
+				user.Role = role
+				user.IsAdmin = role == storage.RoleOwner || role == storage.RoleAdmin
+			}
+		}
+	}
+
+	return user, nil
+}
+
+// provisionOIDCUser creates an account for a provider user on their first login. It gets a random
+// password nobody knows; the first user ever becomes the owner, as with registration.
+func (s *Service) provisionOIDCUser(ctx context.Context, identity *oidcIdentity) (*storage.User, error) {
+	user, _, err := s.CreateUser(ctx, identity.Email, "")
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Provisioned user %d (%s) on their first single sign-on login", user.ID, user.Email)
+
+	role := s.oidc.defaultRole()
+	if user.Role == storage.RoleOwner || role == user.Role || len(s.oidc.config.RoleMapping) > 0 {
+		return user, nil
+	}
+	if err := s.store.SetUserRole(ctx, user.ID, role); err != nil {
+		return nil, fmt.Errorf("failed to set role of provisioned user: %w", err)
+	}
+	user.Role = role
+	user.IsAdmin = role == storage.RoleOwner || role == storage.RoleAdmin
+
+	return user, nil
+}
+
+// mappedRole returns the most privileged role the groups map to, or the default role
+func (p *OIDCProvider) mappedRole(groups []string) string {
+	for _, role := range Roles {
+		for _, group := range groups {
+			if p.config.RoleMapping[group] == role {
+				return role
+			}
+		}
+	}
+	return p.defaultRole()
+}
+
+// defaultRole returns the role of users in none of the mapped groups. Unless configured it is
+// viewer, so signing in through the provider grants no more than reading.
+func (p *OIDCProvider) defaultRole() string {
+	if p.config.DefaultRole != "" {
+		return p.config.DefaultRole
+	}
+	return storage.RoleViewer
+}
+
+// discover fetches and caches the provider's discovery document
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := p.getJSON(ctx, p.config.IssuerURL+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.config.IssuerURL {
+		return nil, fmt.Errorf("OIDC provider issuer %q does not match %q", discovery.Issuer, p.config.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC provider discovery document is missing endpoints")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// exchange redeems the authorization code and returns the identity in the verified ID token
+func (p *OIDCProvider) exchange(ctx context.Context, code string, login *storage.OIDCLogin) (*oidcIdentity, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"code_verifier": {login.CodeVerifier},
+	}
+	if p.config.ClientSecret == "" {
+		form.Set("client_id", p.config.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: provider refused the code: %s %s", ErrInvalidOIDCLogin, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: provider returned no ID token", ErrInvalidOIDCLogin)
+	}
+
+	return p.verifyIDToken(ctx, tokens.IDToken, login.Nonce)
+}
+
+// verifyIDToken checks the ID token's signature, issuer, audience, lifetime and nonce, and
+// returns the identity in it
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, token, nonce string) (*oidcIdentity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed ID token", ErrInvalidOIDCLogin)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed ID token header", ErrInvalidOIDCLogin)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed ID token signature", ErrInvalidOIDCLogin)
+	}
+
+	key, err := p.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	valid := false
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		valid = header.Alg == "RS256" && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case *ecdsa.PublicKey:
+		if header.Alg == "ES256" && len(signature) == 64 {
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			valid = ecdsa.Verify(pub, digest[:], r, s)
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("%w: ID token signature is invalid", ErrInvalidOIDCLogin)
+	}
+
+	var claims map[string]any
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed ID token claims", ErrInvalidOIDCLogin)
+	}
+
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != p.config.IssuerURL {
+		return nil, fmt.Errorf("%w: ID token issuer %q is not trusted", ErrInvalidOIDCLogin, iss)
+	}
+	audiences := claimStrings(claims["aud"])
+	if !slices.Contains(audiences, p.config.ClientID) {
+		return nil, fmt.Errorf("%w: ID token is not for this client", ErrInvalidOIDCLogin)
+	}
+	if azp, ok := claims["azp"].(string); ok && azp != p.config.ClientID {
+		return nil, fmt.Errorf("%w: ID token was issued to another client", ErrInvalidOIDCLogin)
+	}
+	now := time.Now()
+	exp, _ := claims["exp"].(float64)
+	if exp == 0 || now.Add(-oidcClockSkew).Unix() > int64(exp) {
+		return nil, fmt.Errorf("%w: ID token expired", ErrInvalidOIDCLogin)
+	}
+	if iat, ok := claims["iat"].(float64); ok && int64(iat) > now.Add(oidcClockSkew).Unix() {
+		return nil, fmt.Errorf("%w: ID token issued in the future", ErrInvalidOIDCLogin)
+	}
+	if got, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: ID token nonce does not match", ErrInvalidOIDCLogin)
+	}
+
+	identity := &oidcIdentity{}
+	identity.Subject, _ = claims["sub"].(string)
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("%w: ID token has no subject", ErrInvalidOIDCLogin)
+	}
+	identity.Email, _ = claims[p.config.EmailClaim].(string)
+	identity.Email = strings.TrimSpace(identity.Email)
+	// A missing claim counts as unverified
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Groups = claimStrings(claims[p.config.GroupsClaim])
+
+	return identity, nil
+}
+
+// signingKey returns the provider's key with the ID, fetching the keys again if it is new. An
+// empty key ID matches the only key, if there is just one.
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lookup := func() crypto.PublicKey {
+		if kid == "" && len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key
+			}
+		}
+		return p.keys[kid]
+	}
+
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcKeyRefreshInterval {
+		return nil, fmt.Errorf("%w: unknown ID token signing key %q", ErrInvalidOIDCLogin, kid)
+	}
+
+	var jwks struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch OIDC signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("Warning: skipping OIDC signing key %q: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key := lookup(); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown ID token signing key %q", ErrInvalidOIDCLogin, kid)
+}
+
+// getJSON fetches a JSON document from the provider
+func (p *OIDCProvider) getJSON(ctx context.Context, rawURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(v)
+}
+
+// oidcJWK is an RSA or P-256 public key in a JSON Web Key Set
+type oidcJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey decodes the JWK
+func (k oidcJWK) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("malformed RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("malformed EC key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("EC key is not on its curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// randomOIDCValue returns a random state, nonce or PKCE verifier. 32 bytes encode to 43 URL-safe
+// characters, the shortest verifier RFC 7636 allows.
+func randomOIDCValue() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// decodeJWTPart decodes a base64url JSON part of a JWT
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// claimStrings returns a claim that is a string or a list of strings as a list
+func claimStrings(claim any) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// safeReturnPath returns the path if it stays within the web app, or "" otherwise
+func safeReturnPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.ContainsAny(path, "\\\r\n") {
+		return ""
+	}
+	return path
+}
+
+// SetOIDCStateCookie ties a single sign-on login to the browser starting it, so a callback URL
+// cannot be used to sign someone else's browser in
+func SetOIDCStateCookie(w http.ResponseWriter, state string, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    state,
+		Path:     oidcCookiePath,
+		MaxAge:   int(OIDCLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ClearOIDCStateCookie clears the single sign-on state cookie
+func ClearOIDCStateCookie(w http.ResponseWriter, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OIDCStateCookieName,
+		Value:    "",
+		Path:     oidcCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// CheckOIDCState reports whether the callback's state matches the browser's state cookie
+func CheckOIDCState(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(OIDCStateCookieName)
+	return err == nil && state != "" && subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) == 1
+}
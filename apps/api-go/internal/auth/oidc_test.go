@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/config"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const testOIDCClientID = "lunasentri"
+
+// testOIDCProvider is a stand-in OpenID Connect provider. Tests "sign in" at it by calling
+// authorize with the claims the ID token should carry.
+type testOIDCProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	signer crypto.Signer
+	alg    string
+
+	mu    sync.Mutex
+	codes map[string]testOIDCCode
+
+	// tamper, if set, edits claims after the provider fills them in
+	tamper func(claims map[string]any)
+}
+
+type testOIDCCode struct {
+	challenge   string
+	redirectURI string
+	claims      map[string]any
+}
+
+func newTestOIDCProvider(t *testing.T, alg string) *testOIDCProvider {
+	t.Helper()
+
+	p := &testOIDCProvider{t: t, alg: alg, codes: map[string]testOIDCCode{}}
+	var jwk map[string]string
+	switch alg {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate RSA key: %v", err)
+		}
+		p.signer = key
+		jwk = map[string]string{
+			"kty": "RSA", "kid": "test-key", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	case "ES256":
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatalf("Failed to generate EC key: %v", err)
+		}
+		p.signer = key
+		jwk = map[string]string{
+			"kty": "EC", "kid": "test-key", "crv": "P-256",
+			"x": base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y": base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk}})
+	})
+	mux.HandleFunc("/token", p.handleToken)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// config returns a configuration for a client of this provider
+func (p *testOIDCProvider) config() *config.OIDCConfig {
+	return &config.OIDCConfig{
+		IssuerURL:     p.server.URL,
+		ClientID:      testOIDCClientID,
+		ClientSecret:  "client-secret",
+		RedirectURL:   "http://api.example.com/auth/oidc/callback",
+		Scopes:        []string{"openid", "email", "groups"},
+		ProviderName:  "Test IdP",
+		EmailClaim:    "email",
+		GroupsClaim:   "groups",
+		RoleMapping:   map[string]string{},
+		AutoProvision: true,
+	}
+}
+
+// authorize plays the user signing in at the authorization URL, returning the callback's state and code
+func (p *testOIDCProvider) authorize(authURL string, claims map[string]any) (string, string) {
+	p.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("Invalid authorization URL: %v", err)
+	}
+	query := u.Query()
+	if query.Get("response_type") != "code" || query.Get("client_id") != testOIDCClientID ||
+		query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		p.t.Fatalf("Unexpected authorization request %s", u.RawQuery)
+	}
+
+	full := map[string]any{
+		"iss":   p.server.URL,
+		"aud":   testOIDCClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+	for k, v := range claims {
+		full[k] = v
+	}
+
+	code, _ := randomOIDCValue()
+	p.mu.Lock()
+	p.codes[code] = testOIDCCode{
+		challenge:   query.Get("code_challenge"),
+		redirectURI: query.Get("redirect_uri"),
+		claims:      full,
+	}
+	p.mu.Unlock()
+
+	return query.Get("state"), code
+}
+
+func (p *testOIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, _ := r.BasicAuth()
+	if clientID != testOIDCClientID || secret != "client-secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	code, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	p.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != code.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != code.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if p.tamper != nil {
+		p.tamper(code.claims)
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": p.sign(code.claims), "token_type": "Bearer"})
+}
+
+// sign issues an ID token with the claims
+func (p *testOIDCProvider) sign(claims map[string]any) string {
+	p.t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": p.alg, "kid": "test-key", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	message := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(message))
+
+	var signature []byte
+	switch key := p.signer.(type) {
+	case *rsa.PrivateKey:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			p.t.Fatalf("Failed to sign: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return message + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newOIDCTestService returns a service using a stand-in provider configured by configure
+func newOIDCTestService(t *testing.T, alg string, configure func(*config.OIDCConfig)) (*Service, *storage.SQLiteStore, *testOIDCProvider) {
+	t.Helper()
+
+	service, store, _ := newAPITokenTestService(t)
+	provider := newTestOIDCProvider(t, alg)
+	cfg := provider.config()
+	if configure != nil {
+		configure(cfg)
+	}
+	oidc, err := NewOIDCProvider(cfg)
+	if err != nil {
+		t.Fatalf("NewOIDCProvider failed: %v", err)
+	}
+	service.SetOIDCProvider(oidc)
+	return service, store, provider
+}
+
+// oidcLogin signs in through the provider with the claims
+func oidcLogin(t *testing.T, service *Service, provider *testOIDCProvider, claims map[string]any) (*storage.User, string, error) {
+	t.Helper()
+
+	authURL, state, err := service.BeginOIDCLogin(context.Background(), "/machines")
+	if err != nil {
+		t.Fatalf("BeginOIDCLogin failed: %v", err)
+	}
+	callbackState, code := provider.authorize(authURL, claims)
+	if callbackState != state {
+		t.Fatalf("Expected the provider to echo state %q, got %q", state, callbackState)
+	}
+	return service.CompleteOIDCLogin(context.Background(), state, code)
+}
+
+func TestOIDCLoginProvisionsAndLinksUsers(t *testing.T) {
+	for _, alg := range []string{"RS256", "ES256"} {
+		t.Run(alg, func(t *testing.T) {
+			service, store, provider := newOIDCTestService(t, alg, nil)
+			ctx := context.Background()
+
+			user, returnTo, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-1", "email": "new@example.com", "email_verified": true})
+			if err != nil {
+				t.Fatalf("CompleteOIDCLogin failed: %v", err)
+			}
+			// SSO users get the viewer role unless configured otherwise
+			if user.Email != "new@example.com" || user.Role != storage.RoleViewer || returnTo != "/machines" {
+				t.Errorf("Unexpected user %+v returning to %q", user, returnTo)
+			}
+			if linked, err := store.GetUserByIdentity(ctx, provider.server.URL, "sub-1"); err != nil || linked.ID != user.ID {
+				t.Errorf("Expected the identity to be linked to user %d, got %+v, %v", user.ID, linked, err)
+			}
+
+			// The subject, not the email, identifies the user from then on
+			again, _, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-1", "email": "renamed@example.com"})
+			if err != nil {
+				t.Fatalf("Second login failed: %v", err)
+			}
+			if again.ID != user.ID {
+				t.Errorf("Expected user %d again, got %d", user.ID, again.ID)
+			}
+
+			// Existing accounts are linked by email
+			existing, _ := store.GetUserByEmail(ctx, "ci@example.com")
+			linked, _, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-2", "email": "ci@example.com", "email_verified": true})
+			if err != nil {
+				t.Fatalf("Login of existing user failed: %v", err)
+			}
+			if linked.ID != existing.ID {
+				t.Errorf("Expected existing user %d, got %d", existing.ID, linked.ID)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginRejections(t *testing.T) {
+	service, _, provider := newOIDCTestService(t, "RS256", nil)
+	ctx := context.Background()
+	claims := map[string]any{"sub": "sub-1", "email": "new@example.com", "email_verified": true}
+
+	tests := []struct {
+		name   string
+		tamper func(map[string]any)
+	}{
+		{"wrong nonce", func(c map[string]any) { c["nonce"] = "other" }},
+		{"wrong audience", func(c map[string]any) { c["aud"] = "someone-else" }},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"unverified email", func(c map[string]any) { c["email_verified"] = false }},
+		{"email verification not reported", func(c map[string]any) { delete(c, "email_verified") }},
+		{"no subject", func(c map[string]any) { delete(c, "sub") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.tamper = tt.tamper
+			defer func() { provider.tamper = nil }()
+
+			if _, _, err := oidcLogin(t, service, provider, claims); !errors.Is(err, ErrInvalidOIDCLogin) {
+				t.Errorf("Expected ErrInvalidOIDCLogin, got %v", err)
+			}
+		})
+	}
+
+	// A token signed by another key is refused
+	impostor := newTestOIDCProvider(t, "RS256")
+	authURL, state, _ := service.BeginOIDCLogin(ctx, "")
+	_, code := provider.authorize(authURL, claims)
+	provider.mu.Lock()
+	issued := provider.codes[code].claims
+	provider.mu.Unlock()
+	if _, err := service.oidc.verifyIDToken(ctx, impostor.sign(issued), issued["nonce"].(string)); !errors.Is(err, ErrInvalidOIDCLogin) {
+		t.Errorf("Expected a token signed by another key to be refused, got %v", err)
+	}
+
+	// Each state is good for one callback
+	if _, _, err := service.CompleteOIDCLogin(ctx, state, code); err != nil {
+		t.Fatalf("CompleteOIDCLogin failed: %v", err)
+	}
+	if _, _, err := service.CompleteOIDCLogin(ctx, state, code); !errors.Is(err, ErrInvalidOIDCLogin) {
+		t.Errorf("Expected a replayed state to be refused, got %v", err)
+	}
+}
+
+func TestOIDCLoginWithoutProvisioning(t *testing.T) {
+	service, _, provider := newOIDCTestService(t, "RS256", func(cfg *config.OIDCConfig) {
+		cfg.AutoProvision = false
+	})
+
+	if _, _, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-1", "email": "stranger@example.com", "email_verified": true}); !errors.Is(err, ErrOIDCUserNotProvisioned) {
+		t.Errorf("Expected ErrOIDCUserNotProvisioned, got %v", err)
+	}
+	if _, _, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-2", "email": "ci@example.com", "email_verified": true}); err != nil {
+		t.Errorf("Expected an existing user to sign in, got %v", err)
+	}
+}
+
+func TestOIDCLoginTrustedEmail(t *testing.T) {
+	service, store, provider := newOIDCTestService(t, "RS256", func(cfg *config.OIDCConfig) {
+		cfg.TrustEmail = true
+	})
+
+	// A trusted provider links accounts by email even without an email_verified claim
+	existing, _ := store.GetUserByEmail(context.Background(), "ci@example.com")
+	linked, _, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-1", "email": "ci@example.com"})
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin failed: %v", err)
+	}
+	if linked.ID != existing.ID {
+		t.Errorf("Expected existing user %d, got %d", existing.ID, linked.ID)
+	}
+}
+
+func TestOIDCConfiguredDefaultRole(t *testing.T) {
+	service, _, provider := newOIDCTestService(t, "RS256", func(cfg *config.OIDCConfig) {
+		cfg.DefaultRole = storage.RoleOperator
+	})
+
+	user, _, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-1", "email": "new@example.com", "email_verified": true})
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin failed: %v", err)
+	}
+	if user.Role != storage.RoleOperator {
+		t.Errorf("Expected the configured default role, got %s", user.Role)
+	}
+}
+
+func TestOIDCGroupRoleMapping(t *testing.T) {
+	service, store, provider := newOIDCTestService(t, "RS256", func(cfg *config.OIDCConfig) {
+		cfg.RoleMapping = map[string]string{"ops": storage.RoleOperator, "platform-admins": storage.RoleAdmin}
+		cfg.DefaultRole = storage.RoleViewer
+	})
+	ctx := context.Background()
+
+	user, _, err := oidcLogin(t, service, provider, map[string]any{"sub": "sub-1", "email": "new@example.com", "email_verified": true, "groups": []string{"ops", "platform-admins"}})
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin failed: %v", err)
+	}
+	if user.Role != storage.RoleAdmin || !user.IsAdmin {
+		t.Errorf("Expected the most privileged mapped role, got %s", user.Role)
+	}
+
+	// Leaving the groups takes the role away at the next login
+	user, _, err = oidcLogin(t, service, provider, map[string]any{"sub": "sub-1", "email": "new@example.com", "email_verified": true, "groups": "unmapped"})
+	if err != nil {
+		t.Fatalf("CompleteOIDCLogin failed: %v", err)
+	}
+	if stored, _ := store.GetUserByID(ctx, user.ID); user.Role != storage.RoleViewer || stored.Role != storage.RoleViewer {
+		t.Errorf("Expected the default role, got %s (stored %s)", user.Role, stored.Role)
+	}
+
+	if _, err := NewOIDCProvider(&config.OIDCConfig{IssuerURL: "https://idp", ClientID: "c", RedirectURL: "https://api/cb",
+		RoleMapping: map[string]string{"ops": "superuser"}}); err == nil {
+		t.Error("Expected an unknown mapped role to be rejected")
+	}
+}
+
+func TestPasswordLoginDisabled(t *testing.T) {
+	service, store, _ := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	if err := service.SetPasswordLogin(false); err == nil {
+		t.Fatal("Expected password login to stay on without single sign-on")
+	}
+
+	hash, _ := HashPassword("password123")
+	store.CreateUser(ctx, "alice@example.com", hash)
+	provider := newTestOIDCProvider(t, "RS256")
+	oidc, _ := NewOIDCProvider(provider.config())
+	service.SetOIDCProvider(oidc)
+	if err := service.SetPasswordLogin(false); err != nil {
+		t.Fatalf("SetPasswordLogin failed: %v", err)
+	}
+
+	if _, err := service.Authenticate(ctx, "alice@example.com", "password123"); !errors.Is(err, ErrPasswordLoginDisabled) {
+		t.Errorf("Expected ErrPasswordLoginDisabled, got %v", err)
+	}
+	if _, err := service.GeneratePasswordReset(ctx, "alice@example.com", time.Hour); !errors.Is(err, ErrPasswordLoginDisabled) {
+		t.Errorf("Expected password resets to be refused, got %v", err)
+	}
+}
+
+func TestSafeReturnPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"/machines?id=1":       "/machines?id=1",
+		"":                     "",
+		"https://evil.example": "",
+		"//evil.example":       "",
+		"/\\evil.example":      "",
+	} {
+		if got := safeReturnPath(path); got != want {
+			t.Errorf("safeReturnPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
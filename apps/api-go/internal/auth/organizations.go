@@ -0,0 +1,481 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// OrgContextKey is the key used to store the membership of the organization a request acts in
+	OrgContextKey contextKey = "organization"
+
+	// OrgHeader selects the organization a request acts in. The org_id query parameter does the
+	// same for requests that cannot set headers, such as WebSocket connections.
+	OrgHeader = "X-Organization-ID"
+
+	// DefaultOrgInvitationTTL is how long an invitation can be accepted for
+	DefaultOrgInvitationTTL = 7 * 24 * time.Hour
+)
+
+// orgAreas are the paths whose resources belong to an organization, so the member's role in it
+// applies on top of their user role
+var orgAreas = []string{"/machines", "/agent/register", "/notifications/", "/alerts/", "/silences"}
+
+// ErrOrgForbidden is returned when a member's role in an organization does not allow an action
+var ErrOrgForbidden = errors.New("your role in this organization does not allow this")
+
+// ErrInvalidOrgInvitation is returned when an invitation is unknown, accepted or expired
+var ErrInvalidOrgInvitation = errors.New("invalid or expired invitation")
+
+// orgInvitationEmail is the body of the email that delivers an invitation link
+var orgInvitationEmail = template.Must(template.New("org_invitation").Parse(`Hello,
+
+{{.Inviter}} invited you to join the {{.Organization}} organization on LunaSentri as {{.Role}}.
+Sign in or ask an administrator for an account with this email address, then open this link to accept:
+
+{{.Link}}
+
+The invitation expires at {{.ExpiresAt.UTC.Format "2006-01-02 15:04 MST"}}.
+If you were not expecting it, you can ignore this email.
+`))
+
+// SetInvitationURL sets the page where invited users accept invitations; the token is added to
+// it as the "token" query parameter. Invitations are emailed when a password reset mailer is set.
+func (s *Service) SetInvitationURL(acceptURL string) error {
+	u, err := url.Parse(acceptURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invitation URL must be an absolute http(s) URL")
+	}
+
+	s.invitationURL = u
+	return nil
+}
+
+// GetOrgFromContext retrieves the user's membership of the organization the request acts in
+func GetOrgFromContext(ctx context.Context) (*storage.OrgMember, bool) {
+	member, ok := ctx.Value(OrgContextKey).(*storage.OrgMember)
+	return member, ok
+}
+
+// serveInOrganization resolves the organization the request acts in, checks the user's role in
+// it and serves the request with the membership in the context
+func (s *Service) serveInOrganization(w http.ResponseWriter, r *http.Request, user *storage.User, next http.Handler) {
+	member, status, err := s.requestOrganization(r, user)
+	if err != nil {
+		log.Printf("User %d cannot act in the requested organization: %v", user.ID, err)
+		switch status {
+		case http.StatusBadRequest:
+			http.Error(w, "Invalid organization ID", status)
+		case http.StatusForbidden:
+			http.Error(w, "Forbidden: you are not a member of this organization", status)
+		default:
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if isOrgArea(r) && !RoleAllows(member.Role, r) {
+		log.Printf("User %d with role %s in organization %d denied %s %s", user.ID, member.Role, member.OrgID, r.Method, r.URL.Path)
+		http.Error(w, "Forbidden: your role in this organization does not allow this request", http.StatusForbidden)
+		return
+	}
+
+	// Record the user's changes in the audit log
+	actor := audit.UserActor(user, clientIP(r))
+	if token, ok := GetAPITokenFromContext(r.Context()); ok {
+		actor.APITokenID = token.ID
+	}
+	ctx := audit.WithActor(context.WithValue(r.Context(), OrgContextKey, member), actor)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// requestOrganization returns the user's membership of the organization selected by the request,
+// or of their personal organization when none is selected, with the status to fail with otherwise
+func (s *Service) requestOrganization(r *http.Request, user *storage.User) (*storage.OrgMember, int, error) {
+	selected := r.Header.Get(OrgHeader)
+	if selected == "" {
+		selected = r.URL.Query().Get("org_id")
+	}
+
+	if selected == "" {
+		orgs, err := s.store.ListUserOrganizations(r.Context(), user.ID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		if len(orgs) == 0 {
+			return nil, http.StatusForbidden, fmt.Errorf("user has no organizations")
+		}
+		org := orgs[0]
+		return &storage.OrgMember{OrgID: org.ID, UserID: user.ID, Email: user.Email, Role: org.Role, CreatedAt: org.CreatedAt}, 0, nil
+	}
+
+	orgID, err := strconv.Atoi(selected)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid organization ID %q", selected)
+	}
+	member, err := s.store.GetOrgMember(r.Context(), orgID, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgMemberNotFound) {
+			return nil, http.StatusForbidden, fmt.Errorf("not a member of organization %d", orgID)
+		}
+		return nil, http.StatusInternalServerError, err
+	}
+	return member, 0, nil
+}
+
+// isOrgArea reports whether the request is for resources that belong to an organization
+func isOrgArea(r *http.Request) bool {
+	for _, path := range orgAreas {
+		if r.URL.Path == path || strings.HasPrefix(r.URL.Path, strings.TrimSuffix(path, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOrgName trims an organization name and checks its length
+func validateOrgName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name cannot be empty")
+	}
+	if len(name) > 100 {
+		return "", fmt.Errorf("name must not be longer than 100 characters")
+	}
+	return name, nil
+}
+
+// ListOrganizations returns the organizations the user belongs to with their role in each
+func (s *Service) ListOrganizations(ctx context.Context, userID int) ([]storage.UserOrganization, error) {
+	orgs, err := s.store.ListUserOrganizations(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// CreateOrganization creates an organization owned by the user
+func (s *Service) CreateOrganization(ctx context.Context, userID int, name string) (*storage.Organization, error) {
+	name, err := validateOrgName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.store.CreateOrganization(ctx, name, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: org.ID, Action: "org.create", TargetType: "org", TargetID: org.ID, After: org})
+	return org, nil
+}
+
+// RenameOrganization renames the member's organization. Owners and admins can rename it.
+func (s *Service) RenameOrganization(ctx context.Context, actor *storage.OrgMember, name string) (*storage.Organization, error) {
+	if !RoleHas(actor.Role, ScopeUsersAdmin) {
+		return nil, ErrOrgForbidden
+	}
+	name, err := validateOrgName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Kept for the audit log; when missing, the rename below reports why
+	before, _ := s.store.GetOrganization(ctx, actor.OrgID)
+	org, err := s.store.RenameOrganization(ctx, actor.OrgID, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename organization: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: org.ID, Action: "org.update", TargetType: "org", TargetID: org.ID, Before: before, After: org})
+	return org, nil
+}
+
+// DeleteOrganization deletes the member's organization and everything it owns. Only owners can
+// delete an organization, and personal organizations are only deleted with their user.
+func (s *Service) DeleteOrganization(ctx context.Context, actor *storage.OrgMember) error {
+	if actor.Role != storage.RoleOwner {
+		return ErrOrgForbidden
+	}
+
+	if err := s.store.DeleteOrganization(ctx, actor.OrgID); err != nil {
+		if strings.Contains(err.Error(), "personal organization") {
+			return fmt.Errorf("cannot delete a personal organization")
+		}
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: actor.OrgID, Action: "org.delete", TargetType: "org", TargetID: actor.OrgID})
+	return nil
+}
+
+// Membership returns the user's membership of an organization. Organizations the user does not
+// belong to are reported as not found.
+func (s *Service) Membership(ctx context.Context, orgID, userID int) (*storage.OrgMember, error) {
+	member, err := s.store.GetOrgMember(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgMemberNotFound) {
+			return nil, fmt.Errorf("organization not found")
+		}
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	return member, nil
+}
+
+// ListOrgMembers returns the members of an organization
+func (s *Service) ListOrgMembers(ctx context.Context, orgID int) ([]storage.OrgMember, error) {
+	members, err := s.store.ListOrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	return members, nil
+}
+
+// SetOrgMemberRole changes another member's role in the actor's organization, following the
+// same rules as user roles: admins manage operators and viewers, owners manage everyone.
+func (s *Service) SetOrgMemberRole(ctx context.Context, actor *storage.OrgMember, userID int, role string) (*storage.OrgMember, error) {
+	if userID == actor.UserID {
+		return nil, fmt.Errorf("cannot change your own role")
+	}
+	if err := checkOrgRoleAssignment(actor, role); err != nil {
+		return nil, err
+	}
+
+	member, err := s.getOrgMember(ctx, actor.OrgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageRole(actor.Role, member.Role) {
+		return nil, ErrRoleForbidden
+	}
+
+	if err := s.store.SetOrgMemberRole(ctx, actor.OrgID, userID, role); err != nil {
+		if strings.Contains(err.Error(), "last owner") {
+			return nil, fmt.Errorf("cannot remove the last owner of the organization")
+		}
+		return nil, fmt.Errorf("failed to set member role: %w", err)
+	}
+
+	before := *member
+	member.Role = role
+	audit.Record(ctx, s.store, audit.Event{OrgID: actor.OrgID, Action: "org_member.update", TargetType: "user", TargetID: userID,
+		Before: &before, After: member})
+	return member, nil
+}
+
+// RemoveOrgMember removes a member from the actor's organization. Members can always leave;
+// removing someone else follows the same rules as changing their role.
+func (s *Service) RemoveOrgMember(ctx context.Context, actor *storage.OrgMember, userID int) error {
+	if userID != actor.UserID {
+		if !RoleHas(actor.Role, ScopeUsersAdmin) {
+			return ErrOrgForbidden
+		}
+		member, err := s.getOrgMember(ctx, actor.OrgID, userID)
+		if err != nil {
+			return err
+		}
+		if !canManageRole(actor.Role, member.Role) {
+			return ErrRoleForbidden
+		}
+	}
+
+	if err := s.store.RemoveOrgMember(ctx, actor.OrgID, userID); err != nil {
+		if errors.Is(err, storage.ErrOrgMemberNotFound) {
+			return fmt.Errorf("member not found")
+		}
+		if strings.Contains(err.Error(), "last owner") {
+			return fmt.Errorf("cannot remove the last owner of the organization")
+		}
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: actor.OrgID, Action: "org_member.remove", TargetType: "user", TargetID: userID})
+	return nil
+}
+
+// InviteToOrganization invites an email address to the actor's organization with the role. The
+// invitation token is returned so it can be shared, and emailed when a mailer is configured.
+func (s *Service) InviteToOrganization(ctx context.Context, actor *storage.OrgMember, email, role string) (*storage.OrgInvitation, string, error) {
+	if err := checkOrgRoleAssignment(actor, role); err != nil {
+		return nil, "", err
+	}
+	email = strings.TrimSpace(email)
+	if _, err := mail.ParseAddress(email); err != nil || strings.ContainsAny(email, "<> ") {
+		return nil, "", fmt.Errorf("invalid email address")
+	}
+
+	members, err := s.store.ListOrgMembers(ctx, actor.OrgID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list organization members: %w", err)
+	}
+	for _, member := range members {
+		if strings.EqualFold(member.Email, email) {
+			return nil, "", fmt.Errorf("%s is already a member of this organization", email)
+		}
+	}
+
+	token, err := generateSecureToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	tokenHash, err := hashToken(token)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash invitation token: %w", err)
+	}
+
+	invitedBy := actor.UserID
+	invitation, err := s.store.CreateOrgInvitation(ctx, storage.OrgInvitation{
+		OrgID:     actor.OrgID,
+		Email:     email,
+		Role:      role,
+		InvitedBy: &invitedBy,
+		ExpiresAt: time.Now().Add(DefaultOrgInvitationTTL),
+		TokenHash: tokenHash,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: actor.OrgID, Action: "org_invitation.create", TargetType: "org_invitation",
+		TargetID: invitation.ID, After: invitation})
+
+	if s.mailer != nil && s.invitationURL != nil {
+		org, err := s.store.GetOrganization(ctx, actor.OrgID)
+		if err != nil {
+			log.Printf("Warning: failed to load organization %d, invitation for %s was not emailed: %v", actor.OrgID, email, err)
+		} else {
+			go s.sendOrgInvitationEmail(actor.Email, org.Name, *invitation, token)
+		}
+	} else {
+		log.Printf("Warning: no mailer configured, invitation link for %s was not sent", email)
+	}
+
+	return invitation, token, nil
+}
+
+// InvitationLink returns the link that accepts an invitation, or "" when no invitation URL is set
+func (s *Service) InvitationLink(token string) string {
+	if s.invitationURL == nil {
+		return ""
+	}
+	link := *s.invitationURL
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// sendOrgInvitationEmail emails an invitation link
+func (s *Service) sendOrgInvitationEmail(inviter, orgName string, invitation storage.OrgInvitation, token string) {
+	var body bytes.Buffer
+	err := orgInvitationEmail.Execute(&body, struct {
+		Inviter      string
+		Organization string
+		Role         string
+		Link         string
+		ExpiresAt    time.Time
+	}{inviter, orgName, invitation.Role, s.InvitationLink(token), invitation.ExpiresAt})
+	if err != nil {
+		log.Printf("Failed to render invitation email: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetEmailTimeout)
+	defer cancel()
+
+	if err := s.mailer.SendMail(ctx, invitation.Email, "You're invited to "+orgName+" on LunaSentri", body.String()); err != nil {
+		log.Printf("Failed to send invitation email to %s: %v", invitation.Email, err)
+		return
+	}
+	log.Printf("Invitation email sent to %s", invitation.Email)
+}
+
+// ListOrgInvitations returns the invitations of an organization that have not been accepted
+func (s *Service) ListOrgInvitations(ctx context.Context, orgID int) ([]storage.OrgInvitation, error) {
+	invitations, err := s.store.ListOrgInvitations(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	return invitations, nil
+}
+
+// RevokeOrgInvitation deletes one of the actor's organization's invitations
+func (s *Service) RevokeOrgInvitation(ctx context.Context, actor *storage.OrgMember, id int) error {
+	if !RoleHas(actor.Role, ScopeUsersAdmin) {
+		return ErrOrgForbidden
+	}
+
+	if err := s.store.DeleteOrgInvitation(ctx, id, actor.OrgID); err != nil {
+		if errors.Is(err, storage.ErrOrgInvitationNotFound) {
+			return fmt.Errorf("invitation not found")
+		}
+		return fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: actor.OrgID, Action: "org_invitation.revoke", TargetType: "org_invitation", TargetID: id})
+	return nil
+}
+
+// AcceptOrgInvitation adds the user to the organization of the invitation with the token. The
+// invitation must have been sent to the user's email address.
+func (s *Service) AcceptOrgInvitation(ctx context.Context, user *storage.User, token string) (*storage.OrgMember, error) {
+	tokenHash, err := hashToken(token)
+	if err != nil {
+		return nil, ErrInvalidOrgInvitation
+	}
+
+	invitation, err := s.store.GetOrgInvitationByHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgInvitationNotFound) {
+			return nil, ErrInvalidOrgInvitation
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	if invitation.AcceptedAt != nil || !time.Now().Before(invitation.ExpiresAt) {
+		return nil, ErrInvalidOrgInvitation
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, fmt.Errorf("this invitation was sent to a different email address")
+	}
+
+	member, err := s.store.AcceptOrgInvitation(ctx, invitation.ID, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgInvitationNotFound) {
+			return nil, ErrInvalidOrgInvitation
+		}
+		if strings.Contains(err.Error(), "already a member") {
+			return nil, fmt.Errorf("you are already a member of this organization")
+		}
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{OrgID: member.OrgID, Action: "org_invitation.accept", TargetType: "org_invitation",
+		TargetID: invitation.ID, After: member})
+	return member, nil
+}
+
+// getOrgMember loads a member of an organization
+func (s *Service) getOrgMember(ctx context.Context, orgID, userID int) (*storage.OrgMember, error) {
+	member, err := s.store.GetOrgMember(ctx, orgID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrOrgMemberNotFound) {
+			return nil, fmt.Errorf("member not found")
+		}
+		return nil, fmt.Errorf("failed to get member: %w", err)
+	}
+	return member, nil
+}
+
+// checkOrgRoleAssignment returns an error unless the actor may give a member of their organization the role
+func checkOrgRoleAssignment(actor *storage.OrgMember, role string) error {
+	if !RoleHas(actor.Role, ScopeUsersAdmin) {
+		return ErrOrgForbidden
+	}
+	return CheckRoleAssignment(&storage.User{Role: actor.Role}, role)
+}
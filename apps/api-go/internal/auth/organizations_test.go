@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestRequireAuthMiddleware_Organizations(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	team, _ := store.CreateOrganization(ctx, "Team", owner.ID)
+	if _, err := store.AddOrgMember(ctx, team.ID, user.ID, storage.RoleViewer); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	outsider, _ := store.CreateOrganization(ctx, "Outsiders", owner.ID)
+	personal, _ := store.GetPersonalOrganization(ctx, user.ID)
+
+	session, err := service.CreateSession(ctx, user.ID, ClientInfo{})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	var got *storage.OrgMember
+	handler := service.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetOrgFromContext(r.Context())
+	}))
+	serve := func(method, org string) int {
+		got = nil
+		req := httptest.NewRequest(method, "/machines/1", nil)
+		req.AddCookie(&http.Cookie{Name: CookieName, Value: session.AccessToken})
+		if org != "" {
+			req.Header.Set(OrgHeader, org)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Without a selection requests act in the personal organization
+	if code := serve(http.MethodDelete, ""); code != http.StatusOK || got == nil || got.OrgID != personal.ID {
+		t.Errorf("Expected the personal organization, got %d with %+v", code, got)
+	}
+
+	if code := serve(http.MethodGet, strconv.Itoa(team.ID)); code != http.StatusOK || got == nil || got.OrgID != team.ID {
+		t.Errorf("Expected the selected organization, got %d with %+v", code, got)
+	}
+
+	// The member's role in the organization applies on top of their user role
+	if code := serve(http.MethodDelete, strconv.Itoa(team.ID)); code != http.StatusForbidden {
+		t.Errorf("Expected a viewer of the organization not to delete machines, got %d", code)
+	}
+
+	if code := serve(http.MethodGet, strconv.Itoa(outsider.ID)); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for an organization the user is not a member of, got %d", code)
+	}
+	if code := serve(http.MethodGet, "team"); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid organization ID, got %d", code)
+	}
+}
+
+func TestOrganizationMemberRoles(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	org, err := service.CreateOrganization(ctx, user.ID, "  Platform ")
+	if err != nil {
+		t.Fatalf("CreateOrganization failed: %v", err)
+	}
+	if org.Name != "Platform" {
+		t.Errorf("Expected the name to be trimmed, got %q", org.Name)
+	}
+	if _, err := service.CreateOrganization(ctx, user.ID, " "); err == nil {
+		t.Error("Expected an empty name to be rejected")
+	}
+
+	admin, _ := store.CreateUser(ctx, "admin@example.com", "hash")
+	viewer, _ := store.CreateUser(ctx, "viewer@example.com", "hash")
+	store.AddOrgMember(ctx, org.ID, admin.ID, storage.RoleAdmin)
+	store.AddOrgMember(ctx, org.ID, viewer.ID, storage.RoleViewer)
+
+	ownerMember, _ := service.Membership(ctx, org.ID, user.ID)
+	adminMember, _ := service.Membership(ctx, org.ID, admin.ID)
+	viewerMember, _ := service.Membership(ctx, org.ID, viewer.ID)
+
+	if _, err := service.SetOrgMemberRole(ctx, adminMember, viewer.ID, storage.RoleOperator); err != nil {
+		t.Errorf("Expected an admin to promote a viewer to operator: %v", err)
+	}
+	if _, err := service.SetOrgMemberRole(ctx, adminMember, viewer.ID, storage.RoleOwner); !errors.Is(err, ErrRoleForbidden) {
+		t.Errorf("Expected an admin not to make owners, got %v", err)
+	}
+	if err := service.RemoveOrgMember(ctx, adminMember, user.ID); !errors.Is(err, ErrRoleForbidden) {
+		t.Errorf("Expected an admin not to remove the owner, got %v", err)
+	}
+	if _, _, err := service.InviteToOrganization(ctx, viewerMember, "new@example.com", storage.RoleViewer); !errors.Is(err, ErrOrgForbidden) {
+		t.Errorf("Expected a viewer not to invite, got %v", err)
+	}
+	if err := service.DeleteOrganization(ctx, adminMember); !errors.Is(err, ErrOrgForbidden) {
+		t.Errorf("Expected an admin not to delete the organization, got %v", err)
+	}
+
+	// Members can always leave, but the last owner cannot
+	if err := service.RemoveOrgMember(ctx, viewerMember, viewer.ID); err != nil {
+		t.Errorf("Expected a member to leave: %v", err)
+	}
+	if err := service.RemoveOrgMember(ctx, ownerMember, user.ID); err == nil || !strings.Contains(err.Error(), "last owner") {
+		t.Errorf("Expected the last owner not to leave, got %v", err)
+	}
+
+	if _, err := service.Membership(ctx, org.ID, viewer.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a former member's membership not to be found, got %v", err)
+	}
+
+	personal, _ := store.GetPersonalOrganization(ctx, user.ID)
+	personalMember, _ := service.Membership(ctx, personal.ID, user.ID)
+	if err := service.DeleteOrganization(ctx, personalMember); err == nil || !strings.Contains(err.Error(), "personal organization") {
+		t.Errorf("Expected a personal organization not to be deleted, got %v", err)
+	}
+	if err := service.DeleteOrganization(ctx, ownerMember); err != nil {
+		t.Errorf("Expected the owner to delete the organization: %v", err)
+	}
+}
+
+func TestOrganizationInvitations(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	if err := service.SetInvitationURL("https://sentri.example.com/invitations/accept"); err != nil {
+		t.Fatalf("SetInvitationURL failed: %v", err)
+	}
+
+	org, _ := service.CreateOrganization(ctx, user.ID, "Platform")
+	owner, _ := service.Membership(ctx, org.ID, user.ID)
+	invitee, _ := store.CreateUser(ctx, "invitee@example.com", "hash")
+	stranger, _ := store.CreateUser(ctx, "stranger@example.com", "hash")
+
+	if _, _, err := service.InviteToOrganization(ctx, owner, "not-an-email", storage.RoleViewer); err == nil {
+		t.Error("Expected an invalid email to be rejected")
+	}
+	if _, _, err := service.InviteToOrganization(ctx, owner, user.Email, storage.RoleViewer); err == nil || !strings.Contains(err.Error(), "already a member") {
+		t.Errorf("Expected inviting a member to fail, got %v", err)
+	}
+
+	invitation, token, err := service.InviteToOrganization(ctx, owner, "Invitee@Example.com", storage.RoleOperator)
+	if err != nil {
+		t.Fatalf("InviteToOrganization failed: %v", err)
+	}
+	if invitation.TokenHash == token {
+		t.Error("Expected the invitation token to be stored hashed")
+	}
+	if link := service.InvitationLink(token); !strings.HasPrefix(link, "https://sentri.example.com/invitations/accept?token=") {
+		t.Errorf("Unexpected invitation link %q", link)
+	}
+
+	if _, err := service.AcceptOrgInvitation(ctx, stranger, token); err == nil || !strings.Contains(err.Error(), "different email") {
+		t.Errorf("Expected another user not to accept the invitation, got %v", err)
+	}
+	if _, err := service.AcceptOrgInvitation(ctx, invitee, "wrong-token"); !errors.Is(err, ErrInvalidOrgInvitation) {
+		t.Errorf("Expected ErrInvalidOrgInvitation for an unknown token, got %v", err)
+	}
+
+	member, err := service.AcceptOrgInvitation(ctx, invitee, token)
+	if err != nil {
+		t.Fatalf("AcceptOrgInvitation failed: %v", err)
+	}
+	if member.OrgID != org.ID || member.Role != storage.RoleOperator {
+		t.Errorf("Unexpected membership: %+v", member)
+	}
+	if _, err := service.AcceptOrgInvitation(ctx, invitee, token); !errors.Is(err, ErrInvalidOrgInvitation) {
+		t.Errorf("Expected an accepted invitation not to be reused, got %v", err)
+	}
+
+	// Expired invitations cannot be accepted
+	_, expiredToken, _ := service.InviteToOrganization(ctx, owner, stranger.Email, storage.RoleViewer)
+	expiredHash, _ := hashToken(expiredToken)
+	expired, _ := store.GetOrgInvitationByHash(ctx, expiredHash)
+	store.DeleteOrgInvitation(ctx, expired.ID, org.ID)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if _, err := store.CreateOrgInvitation(ctx, *expired); err != nil {
+		t.Fatalf("Failed to store expired invitation: %v", err)
+	}
+	if _, err := service.AcceptOrgInvitation(ctx, stranger, expiredToken); !errors.Is(err, ErrInvalidOrgInvitation) {
+		t.Errorf("Expected an expired invitation to be rejected, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
@@ -9,8 +10,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"text/template"
 	"time"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
@@ -22,6 +26,39 @@ const (
 	PasswordResetTokenLength = 32
 )
 
+// passwordResetEmailTimeout bounds how long delivering a reset email may take
+const passwordResetEmailTimeout = 30 * time.Second
+
+// passwordResetEmail is the body of the email that delivers a reset link
+var passwordResetEmail = template.Must(template.New("password_reset").Parse(`Hello,
+
+Someone asked to reset the password of the LunaSentri account {{.Email}}.
+Open this link to choose a new password:
+
+{{.Link}}
+
+The link can be used once and expires at {{.ExpiresAt.UTC.Format "2006-01-02 15:04 MST"}}.
+If you did not ask for a password reset, you can ignore this email.
+`))
+
+// Mailer sends email on behalf of the auth service
+type Mailer interface {
+	SendMail(ctx context.Context, to, subject, body string) error
+}
+
+// SetPasswordResetMailer makes the service email reset links. resetURL is the page where users
+// choose a new password; the token is added to it as the "token" query parameter.
+func (s *Service) SetPasswordResetMailer(mailer Mailer, resetURL string) error {
+	u, err := url.Parse(resetURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("password reset URL must be an absolute http(s) URL")
+	}
+
+	s.mailer = mailer
+	s.resetURL = u
+	return nil
+}
+
 // GeneratePasswordResetRequest represents the request to generate a password reset token
 type GeneratePasswordResetRequest struct {
 	Email string `json:"email"`
@@ -43,6 +80,9 @@ func (s *Service) GeneratePasswordReset(ctx context.Context, email string, passw
 	if email == "" {
 		return "", fmt.Errorf("email cannot be empty")
 	}
+	if s.passwordLoginDisabled {
+		return "", ErrPasswordLoginDisabled
+	}
 
 	// Get user by email
 	user, err := s.store.GetUserByEmail(ctx, email)
@@ -79,9 +119,44 @@ func (s *Service) GeneratePasswordReset(ctx context.Context, email string, passw
 
 	log.Printf("Password reset token generated for user %s (expires at %v)", user.Email, expiresAt)
 
+	// Send in the background so the response time doesn't reveal whether the account exists
+	if s.mailer != nil {
+		go s.sendPasswordResetEmail(user.Email, token, expiresAt)
+	} else {
+		log.Printf("Warning: no mailer configured, password reset link for %s was not sent", user.Email)
+	}
+
 	return token, nil
 }
 
+// sendPasswordResetEmail emails a reset link for the token
+func (s *Service) sendPasswordResetEmail(email, token string, expiresAt time.Time) {
+	link := *s.resetURL
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	var body bytes.Buffer
+	err := passwordResetEmail.Execute(&body, struct {
+		Email     string
+		Link      string
+		ExpiresAt time.Time
+	}{email, link.String(), expiresAt})
+	if err != nil {
+		log.Printf("Failed to render password reset email: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetEmailTimeout)
+	defer cancel()
+
+	if err := s.mailer.SendMail(ctx, email, "Reset your LunaSentri password", body.String()); err != nil {
+		log.Printf("Failed to send password reset email to %s: %v", email, err)
+		return
+	}
+	log.Printf("Password reset email sent to %s", email)
+}
+
 // ResetPassword resets a user's password using a valid reset token
 func (s *Service) ResetPassword(ctx context.Context, token, newPassword string) error {
 	if token == "" {
@@ -95,6 +170,9 @@ func (s *Service) ResetPassword(ctx context.Context, token, newPassword string)
 	if len(newPassword) < 8 {
 		return fmt.Errorf("password must be at least 8 characters long")
 	}
+	if s.passwordLoginDisabled {
+		return ErrPasswordLoginDisabled
+	}
 
 	// Hash the token to look it up
 	tokenHash, err := hashToken(token)
@@ -130,6 +208,13 @@ func (s *Service) ResetPassword(ctx context.Context, token, newPassword string)
 		// Don't fail the request since the password was already updated
 	}
 
+	// End every session, since one may belong to whoever knew the old password
+	if _, err := s.store.RevokeUserSessions(ctx, passwordReset.UserID, 0); err != nil {
+		return fmt.Errorf("password reset but failed to end sessions: %w", err)
+	}
+	ctx = audit.WithActor(ctx, audit.Actor{Type: storage.ActorUser, ID: passwordReset.UserID})
+	audit.Record(ctx, s.store, audit.Event{Action: "user.password_reset", TargetType: "user", TargetID: passwordReset.UserID})
+
 	log.Printf("Password successfully reset for user ID %d", passwordReset.UserID)
 
 	return nil
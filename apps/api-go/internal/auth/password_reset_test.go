@@ -2,6 +2,8 @@ package auth
 
 import (
 	"context"
+	"net/url"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -424,3 +426,61 @@ func TestHashToken_EmptyToken(t *testing.T) {
 		t.Errorf("Expected 'token cannot be empty' error, got: %v", err)
 	}
 }
+
+// recordingMailer records the email it is asked to send
+type recordingMailer struct {
+	sent chan string // recipient and body
+}
+
+func (m *recordingMailer) SendMail(ctx context.Context, to, subject, body string) error {
+	m.sent <- to + "\n" + body
+	return nil
+}
+
+func TestGeneratePasswordReset_EmailsLink(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer store.Close()
+
+	service, err := NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	if err := service.SetPasswordResetMailer(&recordingMailer{}, "/reset-password"); err == nil {
+		t.Error("Expected a relative reset URL to be rejected")
+	}
+	mailer := &recordingMailer{sent: make(chan string, 1)}
+	if err := service.SetPasswordResetMailer(mailer, "https://lunasentri.example.com/reset-password"); err != nil {
+		t.Fatalf("Failed to set mailer: %v", err)
+	}
+
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "test@example.com", "hash")
+
+	token, err := service.GeneratePasswordReset(ctx, user.Email, time.Hour)
+	if err != nil {
+		t.Fatalf("GeneratePasswordReset failed: %v", err)
+	}
+
+	select {
+	case email := <-mailer.sent:
+		link := "https://lunasentri.example.com/reset-password?token=" + url.QueryEscape(token)
+		if !strings.HasPrefix(email, "test@example.com\n") || !strings.Contains(email, link) {
+			t.Errorf("Expected an email to test@example.com with %s, got:\n%s", link, email)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the reset email")
+	}
+
+	// Unknown addresses get no email
+	if _, err := service.GeneratePasswordReset(ctx, "nobody@example.com", time.Hour); err != nil {
+		t.Fatalf("GeneratePasswordReset failed: %v", err)
+	}
+	select {
+	case email := <-mailer.sent:
+		t.Errorf("Expected no email for an unknown address, got:\n%s", email)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// Roles lists the valid user roles, from most to least privileged
+var Roles = []string{storage.RoleOwner, storage.RoleAdmin, storage.RoleOperator, storage.RoleViewer}
+
+// rolePermissions maps each role to its permissions, which share their names with API token scopes
+var rolePermissions = map[string][]string{
+	storage.RoleOwner:    Scopes,
+	storage.RoleAdmin:    Scopes,
+	storage.RoleOperator: {ScopeRead, ScopeAlertsWrite, ScopeMachinesAdmin, ScopeNotificationsWrite},
+	storage.RoleViewer:   {ScopeRead},
+}
+
+// ErrRoleForbidden is returned when a user tries to manage a user or role above their own
+var ErrRoleForbidden = errors.New("only owners can manage owners and admins")
+
+// RoleHas reports whether the role grants the permission
+func RoleHas(role, permission string) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// selfServicePaths are the paths outside areas that every role may use, because they only
+// touch the user's own account or check permissions themselves. API tokens cannot use them.
+var selfServicePaths = []string{"/auth/change-password", "/auth/sessions", "/auth/tokens", "/auth/2fa", "/orgs"}
+
+// RoleAllows reports whether the role allows the request. Paths outside areas are denied unless
+// they are in selfServicePaths.
+func RoleAllows(role string, r *http.Request) bool {
+	permissions, known := rolePermissions[role]
+	allowed, listed := permissionsAllow(permissions, r)
+	if listed {
+		return allowed
+	}
+	return known && isSelfService(r)
+}
+
+// isSelfService reports whether the request is for one of the selfServicePaths
+func isSelfService(r *http.Request) bool {
+	for _, path := range selfServicePaths {
+		if r.URL.Path == path || strings.HasPrefix(r.URL.Path, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// canManageRole reports whether a user with the actor's role may manage users with, or assign, the role
+func canManageRole(actorRole, role string) bool {
+	switch actorRole {
+	case storage.RoleOwner:
+		return true
+	case storage.RoleAdmin:
+		return role == storage.RoleOperator || role == storage.RoleViewer
+	default:
+		return false
+	}
+}
+
+// CheckRoleAssignment returns an error unless the actor may give a user the role
+func CheckRoleAssignment(actor *storage.User, role string) error {
+	if !slices.Contains(Roles, role) {
+		return fmt.Errorf("unknown role %q, valid roles are %s", role, strings.Join(Roles, ", "))
+	}
+	if !canManageRole(actor.Role, role) {
+		return ErrRoleForbidden
+	}
+	return nil
+}
+
+// SetUserRole changes another user's role. Admins can only move users between operator and
+// viewer; owners can assign any role, as long as an owner remains.
+func (s *Service) SetUserRole(ctx context.Context, actor *storage.User, userID int, role string) (*storage.User, error) {
+	if userID == actor.ID {
+		return nil, fmt.Errorf("cannot change your own role")
+	}
+	if err := CheckRoleAssignment(actor, role); err != nil {
+		return nil, err
+	}
+
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == storage.ErrUserNotFound {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !canManageRole(actor.Role, user.Role) {
+		return nil, ErrRoleForbidden
+	}
+
+	if err := s.store.SetUserRole(ctx, userID, role); err != nil {
+		if strings.Contains(err.Error(), "cannot remove the last owner") {
+			return nil, fmt.Errorf("cannot remove the last owner")
+		}
+		return nil, fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	before := *user
+	user.Role = role
+	user.IsAdmin = role == storage.RoleOwner || role == storage.RoleAdmin
+	audit.Record(ctx, s.store, audit.Event{Action: "user.update", TargetType: "user", TargetID: userID, Before: &before, After: user})
+	return user, nil
+}
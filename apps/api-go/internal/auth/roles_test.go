@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role   string
+		method string
+		path   string
+		want   bool
+	}{
+		{storage.RoleViewer, http.MethodGet, "/machines", true},
+		{storage.RoleViewer, http.MethodGet, "/alerts/rules", true},
+		{storage.RoleViewer, http.MethodGet, "/ws", true},
+		{storage.RoleViewer, http.MethodDelete, "/machines/3", false},
+		{storage.RoleViewer, http.MethodDelete, "/alerts/rules/2", false},
+		{storage.RoleViewer, http.MethodPost, "/alerts/events/5/ack", false},
+		{storage.RoleViewer, http.MethodPost, "/notifications/webhooks", false},
+		{storage.RoleViewer, http.MethodGet, "/auth/users", false},
+		{storage.RoleOperator, http.MethodDelete, "/machines/3", true},
+		{storage.RoleOperator, http.MethodPut, "/silences/2", true},
+		{storage.RoleOperator, http.MethodPost, "/alerts/events/5/ack", true},
+		// Alert rules belong to the organization, so operators manage them
+		{storage.RoleOperator, http.MethodPut, "/alerts/rules/2", true},
+		{storage.RoleOperator, http.MethodPost, "/alerts/rules/backtest", true},
+		{storage.RoleOperator, http.MethodPost, "/notifications/routes", true},
+		{storage.RoleOperator, http.MethodGet, "/auth/users", false},
+		{storage.RoleOperator, http.MethodPost, "/auth/users", false},
+		{storage.RoleAdmin, http.MethodGet, "/auth/users", true},
+		{storage.RoleAdmin, http.MethodPut, "/auth/users/4/role", true},
+		{storage.RoleOwner, http.MethodDelete, "/auth/users/4", true},
+		// Read-only areas leave unsupported methods to the handler
+		{storage.RoleViewer, http.MethodPost, "/system/info", true},
+		// Everyone manages their own sessions, tokens and password
+		{storage.RoleViewer, http.MethodDelete, "/auth/sessions", true},
+		{storage.RoleViewer, http.MethodPost, "/auth/tokens", true},
+		{storage.RoleViewer, http.MethodPost, "/auth/change-password", true},
+		{storage.RoleViewer, http.MethodPost, "/auth/2fa/enable", true},
+		{storage.RoleViewer, http.MethodPost, "/orgs", true},
+		// Paths nobody listed are denied
+		{storage.RoleOwner, http.MethodGet, "/unlisted", false},
+		{storage.RoleOwner, http.MethodGet, "/auth/tokens-export", false},
+		// Unknown roles only get what is open to everyone
+		{"", http.MethodGet, "/machines", false},
+		{"", http.MethodGet, "/auth/me", false},
+		{"", http.MethodDelete, "/auth/sessions", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := RoleAllows(tt.role, r); got != tt.want {
+			t.Errorf("RoleAllows(%q, %s %s) = %v, want %v", tt.role, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	service, store, _ := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	owner, _ := store.UpsertAdmin(ctx, "owner@example.com", "hash")
+	admin, _ := store.CreateUser(ctx, "admin@example.com", "hash")
+	store.SetUserRole(ctx, admin.ID, storage.RoleAdmin)
+	admin.Role = storage.RoleAdmin
+	support, _ := store.CreateUser(ctx, "support@example.com", "hash")
+
+	// Admins move users between operator and viewer
+	updated, err := service.SetUserRole(ctx, admin, support.ID, storage.RoleViewer)
+	if err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if updated.Role != storage.RoleViewer || updated.IsAdmin {
+		t.Errorf("Expected a viewer, got %+v", updated)
+	}
+
+	// but cannot hand out admin or touch owners
+	if _, err := service.SetUserRole(ctx, admin, support.ID, storage.RoleAdmin); !errors.Is(err, ErrRoleForbidden) {
+		t.Errorf("Expected ErrRoleForbidden promoting to admin, got %v", err)
+	}
+	if _, err := service.SetUserRole(ctx, admin, owner.ID, storage.RoleViewer); !errors.Is(err, ErrRoleForbidden) {
+		t.Errorf("Expected ErrRoleForbidden demoting the owner, got %v", err)
+	}
+
+	// Owners can assign any role
+	if _, err := service.SetUserRole(ctx, owner, support.ID, storage.RoleAdmin); err != nil {
+		t.Errorf("Expected an owner to make an admin: %v", err)
+	}
+	if _, err := service.SetUserRole(ctx, owner, admin.ID, storage.RoleOwner); err != nil {
+		t.Errorf("Expected an owner to make an owner: %v", err)
+	}
+
+	if _, err := service.SetUserRole(ctx, owner, owner.ID, storage.RoleViewer); err == nil {
+		t.Error("Expected changing your own role to fail")
+	}
+	if _, err := service.SetUserRole(ctx, owner, support.ID, "superuser"); err == nil {
+		t.Error("Expected an unknown role to fail")
+	}
+	if _, err := service.SetUserRole(ctx, owner, 999, storage.RoleViewer); err == nil {
+		t.Error("Expected an unknown user to fail")
+	}
+}
+
+func TestCreateAPIToken_LimitedToRole(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+	store.SetUserRole(ctx, user.ID, storage.RoleViewer)
+
+	if _, _, err := service.CreateAPIToken(ctx, user.ID, "CI", []string{ScopeMachinesAdmin}, nil); err == nil {
+		t.Error("Expected a viewer not to get a machines:admin token")
+	}
+	if _, _, err := service.CreateAPIToken(ctx, user.ID, "Dashboard", []string{ScopeRead}, nil); err != nil {
+		t.Errorf("Expected a viewer to get a read token: %v", err)
+	}
+}
+
+func TestRequireAuthMiddleware_Roles(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	_, token, err := service.CreateAPIToken(ctx, user.ID, "CI", []string{ScopeMachinesAdmin}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken failed: %v", err)
+	}
+	session, err := service.CreateSession(ctx, user.ID, ClientInfo{})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	handler := service.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(method string, bearer bool) int {
+		req := httptest.NewRequest(method, "/machines/1", nil)
+		if bearer {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else {
+			req.AddCookie(&http.Cookie{Name: CookieName, Value: session.AccessToken})
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(http.MethodDelete, false); code != http.StatusOK {
+		t.Errorf("Expected an operator to delete machines, got %d", code)
+	}
+
+	// Becoming a viewer takes effect at once, for sessions and existing tokens alike
+	store.SetUserRole(ctx, user.ID, storage.RoleViewer)
+	if code := serve(http.MethodGet, false); code != http.StatusOK {
+		t.Errorf("Expected a viewer to see machines, got %d", code)
+	}
+	if code := serve(http.MethodDelete, false); code != http.StatusForbidden {
+		t.Errorf("Expected a viewer not to delete machines, got %d", code)
+	}
+	if code := serve(http.MethodDelete, true); code != http.StatusForbidden {
+		t.Errorf("Expected a viewer's token not to delete machines, got %d", code)
+	}
+}
@@ -3,6 +3,8 @@ package auth
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sync/atomic"
 	"time"
 
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
@@ -10,24 +12,57 @@ import (
 
 // Service provides authentication operations
 type Service struct {
-	store     storage.Store
-	jwtSecret []byte
-	accessTTL time.Duration
+	store      storage.Store
+	keys       *KeySet // signs and verifies JWTs
+	purpose    *KeySet // signs and verifies tokens the service issues to itself, never published
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	mailer     Mailer // delivers password reset links and invitations, nil if email is not configured
+	resetURL   *url.URL
+
+	invitationURL *url.URL // page where invitations are accepted
+
+	twoFactorPolicy atomic.Pointer[string] // cached two-factor policy, loaded on first use
+
+	oidc                  *OIDCProvider // single sign-on provider, nil if not configured
+	passwordLoginDisabled bool          // users must sign in with single sign-on
+
+	throttle *LoginThrottle // slows down and locks out guessing of credentials
 }
 
-// NewService creates a new authentication service
+// NewService creates a new authentication service signing JWTs with an HS256 secret
 func NewService(store storage.Store, jwtSecret string, accessTTL time.Duration) (*Service, error) {
 	if jwtSecret == "" {
 		return nil, fmt.Errorf("JWT secret cannot be empty")
 	}
+	keys, err := ParseKeySet("", jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	return NewServiceWithKeys(store, keys, accessTTL)
+}
+
+// NewServiceWithKeys creates a new authentication service signing JWTs with the key set's
+// primary key
+func NewServiceWithKeys(store storage.Store, keys *KeySet, accessTTL time.Duration) (*Service, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("JWT keys are required")
+	}
 	if accessTTL <= 0 {
 		return nil, fmt.Errorf("access token TTL must be positive")
 	}
+	purpose, err := NewKeySet(keys.purposeKey())
+	if err != nil {
+		return nil, err
+	}
 
 	return &Service{
-		store:     store,
-		jwtSecret: []byte(jwtSecret),
-		accessTTL: accessTTL,
+		store:      store,
+		keys:       keys,
+		purpose:    purpose,
+		accessTTL:  accessTTL,
+		refreshTTL: max(DefaultRefreshTTL, accessTTL),
+		throttle:   NewLoginThrottle(store),
 	}, nil
 }
 
@@ -39,6 +74,9 @@ func (s *Service) Authenticate(ctx context.Context, email, password string) (*st
 	if password == "" {
 		return nil, fmt.Errorf("password cannot be empty")
 	}
+	if s.passwordLoginDisabled {
+		return nil, ErrPasswordLoginDisabled
+	}
 
 	// Get user by email
 	user, err := s.store.GetUserByEmail(ctx, email)
@@ -63,13 +101,3 @@ func (s *Service) GetUser(ctx context.Context, id int) (*storage.User, error) {
 
 	return user, nil
 }
-
-// CreateSession creates a new JWT token for the given user
-func (s *Service) CreateSession(userID int) (string, error) {
-	return CreateJWT(userID, s.jwtSecret, s.accessTTL)
-}
-
-// ValidateSession validates a JWT token and returns the user ID
-func (s *Service) ValidateSession(token string) (int, error) {
-	return ValidateJWT(token, s.jwtSecret)
-}
@@ -11,12 +11,14 @@ import (
 
 // mockStore is a mock implementation of storage.Store for testing
 type mockStore struct {
-	users map[string]*storage.User
+	users    map[string]*storage.User
+	sessions map[int]*storage.Session
 }
 
 func newMockStore() *mockStore {
 	return &mockStore{
-		users: make(map[string]*storage.User),
+		users:    make(map[string]*storage.User),
+		sessions: make(map[int]*storage.Session),
 	}
 }
 
@@ -25,6 +27,7 @@ func (m *mockStore) CreateUser(ctx context.Context, email, passwordHash string)
 		ID:           len(m.users) + 1,
 		Email:        email,
 		PasswordHash: passwordHash,
+		Role:         storage.RoleOperator,
 		CreatedAt:    time.Now(),
 	}
 	m.users[email] = user
@@ -48,7 +51,13 @@ func (m *mockStore) GetUserByID(ctx context.Context, id int) (*storage.User, err
 }
 
 func (m *mockStore) UpsertAdmin(ctx context.Context, email, passwordHash string) (*storage.User, error) {
-	return m.CreateUser(ctx, email, passwordHash)
+	user, err := m.CreateUser(ctx, email, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	user.IsAdmin = true
+	user.Role = storage.RoleOwner
+	return user, nil
 }
 
 func (m *mockStore) UpdateUserPassword(ctx context.Context, userID int, passwordHash string) error {
@@ -114,6 +123,18 @@ func (m *mockStore) DeleteUser(ctx context.Context, id int) error {
 		}
 	}
 
+	if userToDelete.Role == storage.RoleOwner {
+		ownerCount := 0
+		for _, user := range m.users {
+			if user.Role == storage.RoleOwner {
+				ownerCount++
+			}
+		}
+		if ownerCount <= 1 {
+			return fmt.Errorf("cannot delete the last owner")
+		}
+	}
+
 	// Delete user
 	for email, user := range m.users {
 		if user.ID == id {
@@ -132,6 +153,9 @@ func (m *mockStore) PromoteToAdmin(ctx context.Context, userID int) error {
 	for _, user := range m.users {
 		if user.ID == userID {
 			user.IsAdmin = true
+			if user.Role != storage.RoleOwner {
+				user.Role = storage.RoleAdmin
+			}
 			return nil
 		}
 	}
@@ -143,37 +167,23 @@ func (m *mockStore) DeletePasswordResetsForUser(ctx context.Context, userID int)
 }
 
 // Alert Rules methods (stub implementations for testing)
-func (m *mockStore) ListAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
+func (m *mockStore) ListAlertRules(ctx context.Context, orgID int) ([]storage.AlertRule, error) {
 	return []storage.AlertRule{}, nil
 }
 
-func (m *mockStore) CreateAlertRule(ctx context.Context, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
-	return &storage.AlertRule{
-		ID:           1,
-		Name:         name,
-		Metric:       metric,
-		ThresholdPct: thresholdPct,
-		Comparison:   comparison,
-		TriggerAfter: triggerAfter,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}, nil
+func (m *mockStore) ListAllAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
+	return []storage.AlertRule{}, nil
 }
 
-func (m *mockStore) UpdateAlertRule(ctx context.Context, id int, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
-	return &storage.AlertRule{
-		ID:           id,
-		Name:         name,
-		Metric:       metric,
-		ThresholdPct: thresholdPct,
-		Comparison:   comparison,
-		TriggerAfter: triggerAfter,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}, nil
+func (m *mockStore) GetAlertRule(ctx context.Context, id int, orgID int) (*storage.AlertRule, error) {
+	return nil, fmt.Errorf("not implemented")
 }
 
-func (m *mockStore) DeleteAlertRule(ctx context.Context, id int) error {
+func (m *mockStore) SaveAlertRule(ctx context.Context, rule storage.AlertRule) (*storage.AlertRule, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteAlertRule(ctx context.Context, id int, orgID int) error {
 	return nil
 }
 
@@ -182,7 +192,7 @@ func (m *mockStore) ListAlertEvents(ctx context.Context, limit int) ([]storage.A
 	return []storage.AlertEvent{}, nil
 }
 
-func (m *mockStore) CreateAlertEvent(ctx context.Context, ruleID int, value float64) (*storage.AlertEvent, error) {
+func (m *mockStore) CreateAlertEvent(ctx context.Context, ruleID int, machineID *int, value float64) (*storage.AlertEvent, error) {
 	return &storage.AlertEvent{
 		ID:           1,
 		RuleID:       ruleID,
@@ -196,9 +206,13 @@ func (m *mockStore) AckAlertEvent(ctx context.Context, id int) error {
 	return nil
 }
 
+func (m *mockStore) GetAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("alert event with id %d not found", id)
+}
+
 // Webhook methods
 func (m *mockStore) CreateWebhook(ctx context.Context, userID int, url string, secretHash string) (*storage.Webhook, error) {
-	return &storage.Webhook{ID: 1, UserID: userID, URL: url, SecretHash: secretHash, IsActive: true}, nil
+	return &storage.Webhook{ID: 1, OrgID: userID, URL: url, SecretHash: secretHash, IsActive: true}, nil
 }
 
 func (m *mockStore) ListWebhooks(ctx context.Context, userID int) ([]storage.Webhook, error) {
@@ -206,11 +220,11 @@ func (m *mockStore) ListWebhooks(ctx context.Context, userID int) ([]storage.Web
 }
 
 func (m *mockStore) GetWebhook(ctx context.Context, id int, userID int) (*storage.Webhook, error) {
-	return &storage.Webhook{ID: id, UserID: userID, URL: "https://example.com/webhook", IsActive: true}, nil
+	return &storage.Webhook{ID: id, OrgID: userID, URL: "https://example.com/webhook", IsActive: true}, nil
 }
 
 func (m *mockStore) UpdateWebhook(ctx context.Context, id int, userID int, url string, secretHash *string, isActive *bool) (*storage.Webhook, error) {
-	return &storage.Webhook{ID: id, UserID: userID, URL: url, IsActive: true}, nil
+	return &storage.Webhook{ID: id, OrgID: userID, URL: url, IsActive: true}, nil
 }
 
 func (m *mockStore) DeleteWebhook(ctx context.Context, id int, userID int) error {
@@ -234,15 +248,15 @@ func (m *mockStore) ListEmailRecipients(ctx context.Context, userID int) ([]stor
 }
 
 func (m *mockStore) GetEmailRecipient(ctx context.Context, id int, userID int) (*storage.EmailRecipient, error) {
-	return &storage.EmailRecipient{ID: id, UserID: userID, Email: "test@example.com", IsActive: true}, nil
+	return &storage.EmailRecipient{ID: id, OrgID: userID, Email: "test@example.com", IsActive: true}, nil
 }
 
 func (m *mockStore) CreateEmailRecipient(ctx context.Context, userID int, email string) (*storage.EmailRecipient, error) {
-	return &storage.EmailRecipient{ID: 1, UserID: userID, Email: email, IsActive: true}, nil
+	return &storage.EmailRecipient{ID: 1, OrgID: userID, Email: email, IsActive: true}, nil
 }
 
 func (m *mockStore) UpdateEmailRecipient(ctx context.Context, id int, userID int, email string, isActive *bool) (*storage.EmailRecipient, error) {
-	return &storage.EmailRecipient{ID: id, UserID: userID, Email: email, IsActive: true}, nil
+	return &storage.EmailRecipient{ID: id, OrgID: userID, Email: email, IsActive: true}, nil
 }
 
 func (m *mockStore) DeleteEmailRecipient(ctx context.Context, id int, userID int) error {
@@ -266,15 +280,15 @@ func (m *mockStore) ListTelegramRecipients(ctx context.Context, userID int) ([]s
 }
 
 func (m *mockStore) GetTelegramRecipient(ctx context.Context, id int, userID int) (*storage.TelegramRecipient, error) {
-	return &storage.TelegramRecipient{ID: id, UserID: userID, ChatID: "123456789", IsActive: true}, nil
+	return &storage.TelegramRecipient{ID: id, OrgID: userID, ChatID: "123456789", IsActive: true}, nil
 }
 
-func (m *mockStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string) (*storage.TelegramRecipient, error) {
-	return &storage.TelegramRecipient{ID: 1, UserID: userID, ChatID: chatID, IsActive: true}, nil
+func (m *mockStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string, createdBy int) (*storage.TelegramRecipient, error) {
+	return &storage.TelegramRecipient{ID: 1, OrgID: userID, ChatID: chatID, IsActive: true}, nil
 }
 
 func (m *mockStore) UpdateTelegramRecipient(ctx context.Context, id int, userID int, chatID string, isActive *bool) (*storage.TelegramRecipient, error) {
-	return &storage.TelegramRecipient{ID: id, UserID: userID, ChatID: chatID, IsActive: true}, nil
+	return &storage.TelegramRecipient{ID: id, OrgID: userID, ChatID: chatID, IsActive: true}, nil
 }
 
 func (m *mockStore) DeleteTelegramRecipient(ctx context.Context, id int, userID int) error {
@@ -295,15 +309,15 @@ func (m *mockStore) UpdateTelegramDeliveryState(ctx context.Context, id int, las
 
 // Machine methods (stub implementations for testing)
 func (m *mockStore) CreateMachine(ctx context.Context, userID int, name, hostname, description, apiKeyHash string) (*storage.Machine, error) {
-	return &storage.Machine{ID: 1, UserID: userID, Name: name, Hostname: hostname, Description: description, APIKey: apiKeyHash, Status: "offline"}, nil
+	return &storage.Machine{ID: 1, OrgID: userID, Name: name, Hostname: hostname, Description: description, APIKey: apiKeyHash, Status: "offline"}, nil
 }
 
 func (m *mockStore) GetMachineByID(ctx context.Context, id int) (*storage.Machine, error) {
-	return &storage.Machine{ID: id, UserID: 1, Name: "test-machine", Hostname: "test-host", APIKey: "hash", Status: "online"}, nil
+	return &storage.Machine{ID: id, OrgID: 1, Name: "test-machine", Hostname: "test-host", APIKey: "hash", Status: "online"}, nil
 }
 
 func (m *mockStore) GetMachineByAPIKey(ctx context.Context, apiKeyHash string) (*storage.Machine, error) {
-	return &storage.Machine{ID: 1, UserID: 1, Name: "test-machine", Hostname: "test-host", APIKey: apiKeyHash, Status: "online"}, nil
+	return &storage.Machine{ID: 1, OrgID: 1, Name: "test-machine", Hostname: "test-host", APIKey: apiKeyHash, Status: "online"}, nil
 }
 
 func (m *mockStore) ListMachines(ctx context.Context, userID int) ([]storage.Machine, error) {
@@ -360,6 +374,18 @@ func (m *mockStore) ClearMachineOfflineNotification(ctx context.Context, machine
 	return nil
 }
 
+func (m *mockStore) HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error {
+	return nil
+}
+
+func (m *mockStore) MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error) {
+	return false, nil
+}
+
+func (m *mockStore) ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error {
+	return nil
+}
+
 // Machine credential management methods (stub implementations for testing)
 func (m *mockStore) SetMachineEnabled(ctx context.Context, machineID int, enabled bool) error {
 	return nil
@@ -404,6 +430,476 @@ func (m *mockStore) ListMachineAPIKeys(ctx context.Context, machineID int) ([]st
 	return []storage.MachineAPIKey{}, nil
 }
 
+// Alert resolution and notification template methods (stub implementations for testing)
+func (m *mockStore) ResolveAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListNotificationTemplates(ctx context.Context, userID int) ([]storage.NotificationTemplate, error) {
+	return []storage.NotificationTemplate{}, nil
+}
+
+func (m *mockStore) GetNotificationTemplate(ctx context.Context, userID int, channel, eventType string) (*storage.NotificationTemplate, error) {
+	return nil, storage.ErrNotificationTemplateNotFound
+}
+
+func (m *mockStore) UpsertNotificationTemplate(ctx context.Context, userID int, channel, eventType, body string) (*storage.NotificationTemplate, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteNotificationTemplate(ctx context.Context, id int, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Delivery settings methods (stub implementations for testing)
+func (m *mockStore) UpdateWebhookDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ResetWebhookCircuit(ctx context.Context, id int, userID int) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UpdateTelegramDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ResetTelegramCircuit(ctx context.Context, id int, userID int) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RotateWebhookSecret(ctx context.Context, id int, userID int, newSecretHash string, previousExpiresAt time.Time) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListActiveSilences(ctx context.Context, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockStore) ListTelegramRecipientsByChatID(ctx context.Context, chatID string) ([]storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetNotificationPolicy(ctx context.Context, userID int) (*storage.NotificationPolicy, error) {
+	return nil, storage.ErrNotificationPolicyNotFound
+}
+
+func (m *mockStore) UpsertNotificationPolicy(ctx context.Context, policy storage.NotificationPolicy) (*storage.NotificationPolicy, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) AddPendingDigestEntry(ctx context.Context, entry storage.PendingDigestEntry) (*storage.PendingDigestEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListPendingDigestEntries(ctx context.Context) ([]storage.PendingDigestEntry, error) {
+	return nil, nil
+}
+
+func (m *mockStore) DeletePendingDigestEntries(ctx context.Context, orgID, maxID int) error {
+	return nil
+}
+
+func (m *mockStore) ListNotificationRoutes(ctx context.Context, userID int) ([]storage.NotificationRoute, error) {
+	return []storage.NotificationRoute{}, nil
+}
+
+func (m *mockStore) GetNotificationRoute(ctx context.Context, id int, userID int) (*storage.NotificationRoute, error) {
+	return nil, fmt.Errorf("notification route with id %d not found for user %d", id, userID)
+}
+
+func (m *mockStore) CreateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockStore) UpdateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockStore) DeleteNotificationRoute(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockStore) GetMachineTags(ctx context.Context, machineID int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *mockStore) SetMachineTags(ctx context.Context, machineID int, tags []string) error {
+	return nil
+}
+
+func (m *mockStore) ListEscalationPolicies(ctx context.Context, userID int) ([]storage.EscalationPolicy, error) {
+	return []storage.EscalationPolicy{}, nil
+}
+
+func (m *mockStore) GetEscalationPolicy(ctx context.Context, id int, userID int) (*storage.EscalationPolicy, error) {
+	return nil, fmt.Errorf("escalation policy with id %d not found for user %d", id, userID)
+}
+
+func (m *mockStore) CreateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockStore) UpdateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockStore) DeleteEscalationPolicy(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockStore) ListPendingEscalations(ctx context.Context) ([]storage.PendingEscalation, error) {
+	return []storage.PendingEscalation{}, nil
+}
+
+func (m *mockStore) RecordAlertEscalation(ctx context.Context, eventID int, step int, escalatedAt time.Time) error {
+	return nil
+}
+
+func (m *mockStore) GetSilence(ctx context.Context, id int, orgID int) (*storage.Silence, error) {
+	return nil, fmt.Errorf("silence with id %d not found", id)
+}
+
+func (m *mockStore) ListSilences(ctx context.Context, orgID int, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockStore) UpdateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return &silence, nil
+}
+
+func (m *mockStore) DeleteSilence(ctx context.Context, id int, orgID int) error {
+	return nil
+}
+
+func (m *mockStore) SetAlertEventExpectedRange(ctx context.Context, id int, low, high float64) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetMetricBaseline(ctx context.Context, machineID int, metric, seasonality string, slot int) (*storage.MetricBaseline, error) {
+	return nil, storage.ErrMetricBaselineNotFound
+}
+
+func (m *mockStore) UpsertMetricBaseline(ctx context.Context, baseline storage.MetricBaseline) error {
+	return nil
+}
+
+func (m *mockStore) ListOpenAlertEvents(ctx context.Context) ([]storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListAlertRuleStates(ctx context.Context) ([]storage.AlertRuleState, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UpsertAlertRuleState(ctx context.Context, state storage.AlertRuleState) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteAlertRuleStates(ctx context.Context, ruleID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) QueryAlertEvents(ctx context.Context, filter storage.AlertEventFilter) ([]storage.AlertEvent, *storage.AlertEventCursor, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateSession(ctx context.Context, session storage.Session) (*storage.Session, error) {
+	now := time.Now()
+	session.ID = len(m.sessions) + 1
+	session.CreatedAt, session.LastUsedAt = now, now
+	m.sessions[session.ID] = &session
+	created := session
+	return &created, nil
+}
+
+func (m *mockStore) GetSession(ctx context.Context, id int) (*storage.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, storage.ErrSessionNotFound
+	}
+	found := *session
+	return &found, nil
+}
+
+func (m *mockStore) GetSessionByRefreshHash(ctx context.Context, tokenHash string) (*storage.Session, error) {
+	for _, session := range m.sessions {
+		if session.RefreshTokenHash == tokenHash || session.PreviousRefreshHash == tokenHash {
+			found := *session
+			return &found, nil
+		}
+	}
+	return nil, storage.ErrSessionNotFound
+}
+
+func (m *mockStore) RotateSessionRefreshToken(ctx context.Context, id int, oldHash, newHash string, expiresAt time.Time, ipAddress string) (*storage.Session, error) {
+	session, ok := m.sessions[id]
+	if !ok || session.RevokedAt != nil || session.RefreshTokenHash != oldHash {
+		return nil, storage.ErrSessionNotFound
+	}
+	now := time.Now()
+	session.PreviousRefreshHash, session.RefreshTokenHash = oldHash, newHash
+	session.RotatedAt, session.ExpiresAt, session.LastUsedAt, session.IPAddress = &now, expiresAt, now, ipAddress
+	rotated := *session
+	return &rotated, nil
+}
+
+func (m *mockStore) TouchSession(ctx context.Context, id int, ipAddress string) error {
+	if session, ok := m.sessions[id]; ok {
+		session.LastUsedAt, session.IPAddress = time.Now(), ipAddress
+	}
+	return nil
+}
+
+func (m *mockStore) ListSessions(ctx context.Context, userID int) ([]storage.Session, error) {
+	sessions := []storage.Session{}
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && time.Now().Before(session.ExpiresAt) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockStore) RevokeSession(ctx context.Context, id, userID int) error {
+	session, ok := m.sessions[id]
+	if !ok || session.UserID != userID || session.RevokedAt != nil {
+		return storage.ErrSessionNotFound
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	return nil
+}
+
+func (m *mockStore) RevokeUserSessions(ctx context.Context, userID, exceptID int) (int, error) {
+	revoked := 0
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.ID != exceptID && session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (m *mockStore) CreateAPIToken(ctx context.Context, token storage.APIToken) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetAPITokenByHash(ctx context.Context, tokenHash string) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListAPITokens(ctx context.Context, userID int) ([]storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RevokeAPIToken(ctx context.Context, id, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) TouchAPIToken(ctx context.Context, id int, ipAddress string) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Two-factor authentication methods (stub implementations for testing)
+func (m *mockStore) GetUserTOTP(ctx context.Context, userID int) (*storage.UserTOTP, error) {
+	return nil, storage.ErrTOTPNotFound
+}
+
+func (m *mockStore) SetUserTOTPSecret(ctx context.Context, userID int, secret string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) EnableUserTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteUserTOTP(ctx context.Context, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) GetSetting(ctx context.Context, key string) (string, error) {
+	return "", storage.ErrSettingNotFound
+}
+
+func (m *mockStore) SetSetting(ctx context.Context, key, value string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*storage.User, error) {
+	return nil, storage.ErrUserNotFound
+}
+
+func (m *mockStore) LinkUserIdentity(ctx context.Context, identity storage.UserIdentity) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateOIDCLogin(ctx context.Context, login storage.OIDCLogin) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*storage.OIDCLogin, error) {
+	return nil, storage.ErrOIDCLoginNotFound
+}
+
+func (m *mockStore) GetLoginAttempts(ctx context.Context, key string) (*storage.LoginAttempts, error) {
+	return nil, storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockStore) RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ClearLoginAttempts(ctx context.Context, key string) error {
+	return storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockStore) ListLoginLockouts(ctx context.Context) ([]storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateAuditEntry(ctx context.Context, entry storage.AuditEntry) (*storage.AuditEntry, error) {
+	return &entry, nil
+}
+
+func (m *mockStore) ListAuditEntries(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) SetUserRole(ctx context.Context, userID int, role string) error {
+	var target *storage.User
+	owners := 0
+	for _, user := range m.users {
+		if user.ID == userID {
+			target = user
+		}
+		if user.Role == storage.RoleOwner {
+			owners++
+		}
+	}
+	if target == nil {
+		return storage.ErrUserNotFound
+	}
+	if target.Role == storage.RoleOwner && role != storage.RoleOwner && owners <= 1 {
+		return fmt.Errorf("cannot remove the last owner")
+	}
+	target.Role = role
+	target.IsAdmin = role == storage.RoleOwner || role == storage.RoleAdmin
+	return nil
+}
+
+// Organization methods (stub implementations for testing)
+func (m *mockStore) CreateOrganization(ctx context.Context, name string, ownerID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetOrganization(ctx context.Context, id int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetPersonalOrganization(ctx context.Context, userID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RenameOrganization(ctx context.Context, id int, name string) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteOrganization(ctx context.Context, id int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListOrgMembers(ctx context.Context, orgID int) ([]storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) AddOrgMember(ctx context.Context, orgID, userID int, role string) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) SetOrgMemberRole(ctx context.Context, orgID, userID int, role string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RemoveOrgMember(ctx context.Context, orgID, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateOrgInvitation(ctx context.Context, invitation storage.OrgInvitation) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetOrgInvitationByHash(ctx context.Context, tokenHash string) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListOrgInvitations(ctx context.Context, orgID int) ([]storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteOrgInvitation(ctx context.Context, id, orgID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) AcceptOrgInvitation(ctx context.Context, id, userID int) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// ListOrganizations, ListUserOrganizations and GetOrgMember give every user a personal
+// organization sharing the user's ID, which is enough for RequireAuth to resolve one
+func (m *mockStore) ListOrganizations(ctx context.Context) ([]storage.Organization, error) {
+	var orgs []storage.Organization
+	for _, user := range m.users {
+		orgs = append(orgs, storage.Organization{ID: user.ID, Name: user.Email, Personal: true})
+	}
+	return orgs, nil
+}
+
+func (m *mockStore) ListUserOrganizations(ctx context.Context, userID int) ([]storage.UserOrganization, error) {
+	user, err := m.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, nil
+	}
+	return []storage.UserOrganization{{
+		Organization: storage.Organization{ID: user.ID, Name: user.Email, Personal: true},
+		Role:         storage.RoleOwner,
+	}}, nil
+}
+
+func (m *mockStore) GetOrgMember(ctx context.Context, orgID, userID int) (*storage.OrgMember, error) {
+	user, err := m.GetUserByID(ctx, userID)
+	if err != nil || orgID != userID {
+		return nil, storage.ErrOrgMemberNotFound
+	}
+	return &storage.OrgMember{OrgID: orgID, UserID: userID, Email: user.Email, Role: storage.RoleOwner}, nil
+}
+
 func (m *mockStore) Close() error {
 	return nil
 }
@@ -549,12 +1045,12 @@ func TestCreateSession(t *testing.T) {
 	}
 
 	userID := 123
-	token, err := service.CreateSession(userID)
+	tokens, err := service.CreateSession(context.Background(), userID, ClientInfo{})
 	if err != nil {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
 
-	if token == "" {
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
 		t.Fatal("CreateSession returned empty token")
 	}
 }
@@ -570,18 +1066,48 @@ func TestValidateSession(t *testing.T) {
 	}
 
 	userID := 456
-	token, err := service.CreateSession(userID)
+	tokens, err := service.CreateSession(context.Background(), userID, ClientInfo{})
 	if err != nil {
 		t.Fatalf("CreateSession failed: %v", err)
 	}
 
-	validatedUserID, err := service.ValidateSession(token)
+	claims, err := service.ValidateSession(context.Background(), tokens.AccessToken, "")
 	if err != nil {
 		t.Fatalf("ValidateSession failed: %v", err)
 	}
 
-	if validatedUserID != userID {
-		t.Fatalf("Expected user ID %d, got %d", userID, validatedUserID)
+	if claims.UserID != userID {
+		t.Fatalf("Expected user ID %d, got %d", userID, claims.UserID)
+	}
+	if claims.SessionID != tokens.SessionID {
+		t.Fatalf("Expected session ID %d, got %d", tokens.SessionID, claims.SessionID)
+	}
+	if claims.Type != AccessTokenType || claims.Audience != AccessTokenAudience {
+		t.Fatalf("Expected typ %q and aud %q, got %q and %q", AccessTokenType, AccessTokenAudience, claims.Type, claims.Audience)
+	}
+
+	// Tokens signed with the same key but without the access token's typ and aud are not accepted
+	now := time.Now()
+	for name, claims := range map[string]JWTClaims{
+		"no typ":        {UserID: userID, SessionID: tokens.SessionID, Audience: AccessTokenAudience},
+		"no aud":        {UserID: userID, SessionID: tokens.SessionID, Type: AccessTokenType},
+		"other aud":     {UserID: userID, SessionID: tokens.SessionID, Type: AccessTokenType, Audience: "grafana"},
+		"purpose token": {UserID: userID, SessionID: tokens.SessionID, Type: AccessTokenType, Audience: AccessTokenAudience, Purpose: "2fa"},
+	} {
+		claims.Iat, claims.Exp = now.Unix(), now.Add(ttl).Unix()
+		token, _ := signJWT(claims, service.keys.Primary())
+		if _, err := service.ValidateSession(context.Background(), token, ""); err == nil {
+			t.Errorf("ValidateSession should reject a token with %s", name)
+		}
+	}
+
+	// Tokens without a session are not accepted
+	legacy, err := CreateJWT(userID, []byte(secret), ttl)
+	if err != nil {
+		t.Fatalf("CreateJWT failed: %v", err)
+	}
+	if _, err := service.ValidateSession(context.Background(), legacy, ""); err == nil {
+		t.Fatal("ValidateSession should reject a token without a session")
 	}
 }
 
@@ -1,8 +1,6 @@
 package auth
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -14,32 +12,77 @@ import (
 const (
 	// CookieName is the name of the session cookie
 	CookieName = "lunasentri_session"
+
+	// RefreshCookieName is the name of the refresh token cookie
+	RefreshCookieName = "lunasentri_refresh"
+
+	// refreshCookiePath limits the refresh token cookie to the auth endpoints that use it
+	refreshCookiePath = "/auth"
+
+	// AccessTokenType is the typ claim of access tokens
+	AccessTokenType = "access"
+
+	// AccessTokenAudience is the aud claim of access tokens, naming the API they are for
+	AccessTokenAudience = "lunasentri-api"
 )
 
 // JWTClaims represents the claims in a JWT token
 type JWTClaims struct {
-	UserID int   `json:"uid"`
-	Exp    int64 `json:"exp"`
-	Iat    int64 `json:"iat"`
+	UserID    int    `json:"uid"`
+	SessionID int    `json:"sid,omitempty"` // server-side session the token belongs to
+	Type      string `json:"typ,omitempty"` // AccessTokenType on access tokens
+	Audience  string `json:"aud,omitempty"` // AccessTokenAudience on access tokens
+	Purpose   string `json:"pur,omitempty"` // set on tokens that are not access tokens, such as login challenges
+	Exp       int64  `json:"exp"`
+	Iat       int64  `json:"iat"`
+}
+
+// isAccessToken reports whether the claims are those of an access token for the API. Tokens
+// without the typ and aud claims are refused, so no other token signed with the same keys, nor
+// one minted for another service, passes as one.
+func (c *JWTClaims) isAccessToken() bool {
+	return c.Type == AccessTokenType && c.Audience == AccessTokenAudience && c.Purpose == ""
 }
 
 // CreateJWT creates a new JWT token for the given user ID
 func CreateJWT(userID int, secret []byte, ttl time.Duration) (string, error) {
+	return CreateSessionJWT(userID, 0, secret, ttl)
+}
+
+// CreateSessionJWT creates a new JWT token for the given user ID and session
+func CreateSessionJWT(userID, sessionID int, secret []byte, ttl time.Duration) (string, error) {
+	return signJWT(sessionClaims(userID, sessionID, ttl), secretKey(secret))
+}
+
+// sessionClaims returns the claims of an access token for the user and session
+func sessionClaims(userID, sessionID int, ttl time.Duration) JWTClaims {
 	now := time.Now()
-	claims := JWTClaims{
-		UserID: userID,
-		Iat:    now.Unix(),
-		Exp:    now.Add(ttl).Unix(),
+	return JWTClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Type:      AccessTokenType,
+		Audience:  AccessTokenAudience,
+		Iat:       now.Unix(),
+		Exp:       now.Add(ttl).Unix(),
 	}
+}
 
-	// Create header
-	header := map[string]string{
-		"alg": "HS256",
-		"typ": "JWT",
-	}
+// secretKey returns an HS256 key without an ID, which verifies tokens whatever their kid
+func secretKey(secret []byte) *SigningKey {
+	return &SigningKey{Algorithm: AlgHS256, secret: secret}
+}
+
+// jwtHeader is the header of a JWT token
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid,omitempty"`
+}
 
+// signJWT creates a JWT token carrying the claims, signed with the key
+func signJWT(claims JWTClaims, key *SigningKey) (string, error) {
 	// Encode header
-	headerJSON, err := json.Marshal(header)
+	headerJSON, err := json.Marshal(jwtHeader{Algorithm: key.Algorithm, Type: "JWT", KeyID: key.ID})
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal header: %w", err)
 	}
@@ -54,7 +97,7 @@ func CreateJWT(userID int, secret []byte, ttl time.Duration) (string, error) {
 
 	// Create signature
 	message := headerEncoded + "." + claimsEncoded
-	signature := createSignature(message, secret)
+	signature := key.sign(message)
 
 	// Combine parts
 	token := message + "." + signature
@@ -62,49 +105,75 @@ func CreateJWT(userID int, secret []byte, ttl time.Duration) (string, error) {
 	return token, nil
 }
 
-// ValidateJWT validates a JWT token and returns the user ID
+// ValidateJWT validates an access token and returns the user ID
 func ValidateJWT(token string, secret []byte) (int, error) {
+	claims, err := ParseJWT(token, secret)
+	if err != nil {
+		return 0, err
+	}
+	if !claims.isAccessToken() {
+		return 0, fmt.Errorf("not an access token")
+	}
+	return claims.UserID, nil
+}
+
+// ParseJWT validates an HS256 JWT token signed with the secret and returns its claims
+func ParseJWT(token string, secret []byte) (*JWTClaims, error) {
+	return parseJWT(token, &KeySet{keys: []*SigningKey{secretKey(secret)}})
+}
+
+// parseJWT validates a JWT token signed with one of the keys and returns its claims
+func parseJWT(token string, keys *KeySet) (*JWTClaims, error) {
 	// Split token into parts
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		return 0, fmt.Errorf("invalid token format")
+		return nil, fmt.Errorf("invalid token format")
 	}
 
 	headerEncoded := parts[0]
 	claimsEncoded := parts[1]
 	signature := parts[2]
 
-	// Verify signature
+	// Decode header, which names the key and algorithm
+	headerJSON, err := base64.RawURLEncoding.DecodeString(headerEncoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode header: %w", err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal header: %w", err)
+	}
+
+	// Verify signature with the keys the header allows, so a token cannot pick its own algorithm
 	message := headerEncoded + "." + claimsEncoded
-	expectedSignature := createSignature(message, secret)
-	if signature != expectedSignature {
-		return 0, fmt.Errorf("invalid signature")
+	verified := false
+	for _, key := range keys.candidates(header.KeyID, header.Algorithm) {
+		if key.verify(message, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("invalid signature")
 	}
 
 	// Decode claims
 	claimsJSON, err := base64.RawURLEncoding.DecodeString(claimsEncoded)
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode claims: %w", err)
+		return nil, fmt.Errorf("failed to decode claims: %w", err)
 	}
 
 	var claims JWTClaims
 	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal claims: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal claims: %w", err)
 	}
 
 	// Check expiration
 	if time.Now().Unix() > claims.Exp {
-		return 0, fmt.Errorf("token expired")
+		return nil, fmt.Errorf("token expired")
 	}
 
-	return claims.UserID, nil
-}
-
-// createSignature creates an HMAC-SHA256 signature for the given message
-func createSignature(message string, secret []byte) string {
-	h := hmac.New(sha256.New, secret)
-	h.Write([]byte(message))
-	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+	return &claims, nil
 }
 
 // SetSessionCookie sets the session cookie on the response
@@ -143,3 +212,40 @@ func GetSessionCookie(r *http.Request) (string, error) {
 	}
 	return cookie.Value, nil
 }
+
+// SetRefreshCookie sets the refresh token cookie on the response
+func SetRefreshCookie(w http.ResponseWriter, token string, maxAge int, secure bool) {
+	cookie := &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    token,
+		Path:     refreshCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, cookie)
+}
+
+// ClearRefreshCookie clears the refresh token cookie
+func ClearRefreshCookie(w http.ResponseWriter, secure bool) {
+	cookie := &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    "",
+		Path:     refreshCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+	http.SetCookie(w, cookie)
+}
+
+// GetRefreshCookie retrieves the refresh token cookie from the request
+func GetRefreshCookie(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(RefreshCookieName)
+	if err != nil {
+		return "", fmt.Errorf("refresh cookie not found")
+	}
+	return cookie.Value, nil
+}
@@ -0,0 +1,424 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// DefaultRefreshTTL is how long a session lasts without being used
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+
+	// sessionTouchInterval is how often a session's last use is recorded
+	sessionTouchInterval = time.Minute
+
+	// refreshReuseGrace is how long a rotated refresh token is turned away without ending the
+	// session, so that a client racing itself to refresh is not taken for a thief
+	refreshReuseGrace = time.Minute
+)
+
+var (
+	// ErrInvalidSession is returned when a token does not belong to an active session
+	ErrInvalidSession = errors.New("invalid or expired session")
+
+	// ErrRefreshTokenReused is returned when a refresh token that was already rotated is used
+	// again. The session is revoked, since the token may have been stolen.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// ClientInfo describes the client a session is used from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// ClientFromRequest returns the client that sent the request
+func ClientFromRequest(r *http.Request) ClientInfo {
+	return ClientInfo{UserAgent: r.UserAgent(), IPAddress: clientIP(r)}
+}
+
+// trustedProxies are the reverse proxies whose X-Forwarded-For and X-Real-IP headers are believed
+var trustedProxies []*net.IPNet
+
+// SetTrustedProxies sets the reverse proxies, as IPs or CIDR ranges, allowed to report the client
+// IP in X-Forwarded-For or X-Real-IP. Without any, the headers are ignored, since clients could
+// otherwise pick the IP they are throttled and audited under.
+func SetTrustedProxies(proxies []string) error {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	trustedProxies = nets
+	return nil
+}
+
+// isTrustedProxy reports whether the IP belongs to a trusted proxy
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the request's client IP. The headers set by reverse proxies are only used when
+// the request comes from a trusted proxy; X-Forwarded-For is read from the right, skipping the
+// trusted proxies the request passed through, since clients can put anything on its left.
+func clientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remote = host
+	}
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && !isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+		return xri
+	}
+	return remote
+}
+
+// SessionTokens are the credentials issued for a session
+type SessionTokens struct {
+	SessionID        int
+	AccessToken      string    // short-lived JWT sent with every request
+	RefreshToken     string    // long-lived token exchanged for new credentials, rotated on each use
+	RefreshExpiresAt time.Time // when the refresh token expires unless used
+}
+
+// SetRefreshTTL sets how long a session lasts without being used
+func (s *Service) SetRefreshTTL(ttl time.Duration) error {
+	if ttl < s.accessTTL {
+		return fmt.Errorf("refresh token TTL must not be shorter than the access token TTL")
+	}
+	s.refreshTTL = ttl
+	return nil
+}
+
+// CreateSession starts a session for the user on the given client
+func (s *Service) CreateSession(ctx context.Context, userID int, client ClientInfo) (*SessionTokens, error) {
+	refreshToken, refreshHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.store.CreateSession(ctx, storage.Session{
+		UserID:           userID,
+		Device:           deviceName(client.UserAgent),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		ExpiresAt:        time.Now().Add(s.refreshTTL).UTC(),
+		RefreshTokenHash: refreshHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.sessionTokens(session, refreshToken)
+}
+
+// Login methods recorded in the audit log
+const (
+	LoginPassword = "password"
+	LoginOIDC     = "oidc"
+)
+
+// loginAttempt is what the audit log records about a login
+type loginAttempt struct {
+	Email     string `json:"email,omitempty"`
+	Method    string `json:"method"`
+	SessionID int    `json:"session_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Login starts a session for a user who signed in with method and records the login. Logins are
+// taken by the user, whoever the context says is acting.
+func (s *Service) Login(ctx context.Context, user *storage.User, method string, client ClientInfo) (*SessionTokens, error) {
+	tokens, err := s.CreateSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = audit.WithActor(ctx, audit.UserActor(user, client.IPAddress))
+	audit.Record(ctx, s.store, audit.Event{Action: "user.login", TargetType: "user", TargetID: user.ID,
+		After: loginAttempt{Email: user.Email, Method: method, SessionID: tokens.SessionID}})
+	return tokens, nil
+}
+
+// RecordLoginFailure records a failed login as email, which may be empty or unknown, taken by the
+// context's actor
+func (s *Service) RecordLoginFailure(ctx context.Context, email, method, reason string) {
+	event := audit.Event{Action: "user.login_failed", TargetType: "user",
+		After: loginAttempt{Email: email, Method: method, Reason: reason}}
+	if email != "" {
+		if user, err := s.store.GetUserByEmail(ctx, email); err == nil {
+			event.TargetID = user.ID
+		}
+	}
+	audit.Record(ctx, s.store, event)
+}
+
+// ValidateSession validates an access token and returns its claims. The token's session must
+// still be active, so revoked sessions are rejected immediately rather than when the token expires.
+func (s *Service) ValidateSession(ctx context.Context, token, ipAddress string) (*JWTClaims, error) {
+	claims, err := parseJWT(token, s.keys)
+	if err != nil {
+		return nil, err
+	}
+	if claims.SessionID == 0 || !claims.isAccessToken() {
+		return nil, ErrInvalidSession
+	}
+
+	session, err := s.store.GetSession(ctx, claims.SessionID)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if !sessionActive(session) || session.UserID != claims.UserID {
+		return nil, ErrInvalidSession
+	}
+
+	if time.Since(session.LastUsedAt) >= sessionTouchInterval {
+		if err := s.store.TouchSession(ctx, session.ID, ipAddress); err != nil {
+			log.Printf("Warning: failed to record use of session %d: %v", session.ID, err)
+		}
+	}
+
+	return claims, nil
+}
+
+// RefreshSession exchanges a refresh token for new session credentials. The refresh token is
+// rotated; presenting a rotated token again revokes the session.
+func (s *Service) RefreshSession(ctx context.Context, refreshToken string, client ClientInfo) (*SessionTokens, error) {
+	session, err := s.sessionByRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if !sessionActive(session) {
+		return nil, ErrInvalidSession
+	}
+
+	refreshHash, _ := hashToken(refreshToken)
+	if refreshHash != session.RefreshTokenHash {
+		if session.RotatedAt != nil && time.Since(*session.RotatedAt) < refreshReuseGrace {
+			return nil, ErrInvalidSession
+		}
+		if err := s.store.RevokeSession(ctx, session.ID, session.UserID); err != nil && !errors.Is(err, storage.ErrSessionNotFound) {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		log.Printf("Warning: rotated refresh token of session %d reused, session revoked", session.ID)
+		return nil, ErrRefreshTokenReused
+	}
+
+	newToken, newHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session, err = s.store.RotateSessionRefreshToken(ctx, session.ID, refreshHash, newHash,
+		time.Now().Add(s.refreshTTL).UTC(), client.IPAddress)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return s.sessionTokens(session, newToken)
+}
+
+// EndSession revokes the session the given tokens belong to. Either token may be empty; the
+// refresh token is tried first since it outlives the access token.
+func (s *Service) EndSession(ctx context.Context, accessToken, refreshToken string) error {
+	if refreshToken != "" {
+		if session, err := s.sessionByRefreshToken(ctx, refreshToken); err == nil {
+			return s.RevokeSession(ctx, session.UserID, session.ID)
+		}
+	}
+	if accessToken != "" {
+		if claims, err := parseJWT(accessToken, s.keys); err == nil && claims.SessionID != 0 && claims.isAccessToken() {
+			return s.RevokeSession(ctx, claims.UserID, claims.SessionID)
+		}
+	}
+	return ErrInvalidSession
+}
+
+// ListSessions returns the user's active sessions, most recently used first
+func (s *Service) ListSessions(ctx context.Context, userID int) ([]storage.Session, error) {
+	sessions, err := s.store.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	if err := s.store.RevokeSession(ctx, sessionID, userID); err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return fmt.Errorf("session not found")
+		}
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{Action: "session.revoke", TargetType: "session", TargetID: sessionID})
+	return nil
+}
+
+// RevokeUserSessions ends all of the user's sessions except exceptID (0 to end them all) and
+// returns how many were ended
+func (s *Service) RevokeUserSessions(ctx context.Context, userID, exceptID int) (int, error) {
+	revoked, err := s.store.RevokeUserSessions(ctx, userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if revoked > 0 {
+		audit.Record(ctx, s.store, audit.Event{Action: "session.revoke_all", TargetType: "user", TargetID: userID})
+	}
+	return revoked, nil
+}
+
+// sessionByRefreshToken returns the session a current or rotated refresh token belongs to
+func (s *Service) sessionByRefreshToken(ctx context.Context, refreshToken string) (*storage.Session, error) {
+	refreshHash, err := hashToken(refreshToken)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	session, err := s.store.GetSessionByRefreshHash(ctx, refreshHash)
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return nil, ErrInvalidSession
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return session, nil
+}
+
+// sessionTokens issues an access token for the session alongside its refresh token
+func (s *Service) sessionTokens(session *storage.Session, refreshToken string) (*SessionTokens, error) {
+	accessToken, err := signJWT(sessionClaims(session.UserID, session.ID, s.accessTTL), s.keys.Primary())
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionTokens{
+		SessionID:        session.ID,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// sessionActive reports whether a session is neither revoked nor expired
+func sessionActive(session *storage.Session) bool {
+	return session.RevokedAt == nil && time.Now().Before(session.ExpiresAt)
+}
+
+// newRefreshToken generates a refresh token and the hash it is stored as
+func newRefreshToken() (string, string, error) {
+	token, err := generateSecureToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	hash, err := hashToken(token)
+	if err != nil {
+		return "", "", err
+	}
+	return token, hash, nil
+}
+
+// deviceName returns a short description of the browser and OS in a user agent, such as
+// "Firefox on Linux"
+func deviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	// Order matters: Edge and Opera also claim to be Chrome, and Chrome claims to be Safari
+	browser := ""
+	for _, b := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	// Android and iOS user agents also mention Linux and Mac OS X
+	os := ""
+	for _, o := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, o.token) {
+			os = o.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown device"
+	}
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newSessionTestService(t *testing.T) (*Service, *mockStore, int) {
+	t.Helper()
+
+	store := newMockStore()
+	service, err := NewService(store, "test-secret-key", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	hash, _ := HashPassword("oldpassword123")
+	user, _ := store.CreateUser(context.Background(), "user@example.com", hash)
+	return service, store, user.ID
+}
+
+func TestRefreshSession_RotatesToken(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	first, err := service.CreateSession(ctx, userID, ClientInfo{UserAgent: "curl/8.0", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	second, err := service.RefreshSession(ctx, first.RefreshToken, ClientInfo{IPAddress: "10.0.0.2"})
+	if err != nil {
+		t.Fatalf("RefreshSession failed: %v", err)
+	}
+	if second.SessionID != first.SessionID {
+		t.Errorf("Expected the same session, got %d and %d", first.SessionID, second.SessionID)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("Expected the refresh token to be rotated")
+	}
+	if _, err := service.ValidateSession(ctx, second.AccessToken, ""); err != nil {
+		t.Errorf("Expected the new access token to be valid: %v", err)
+	}
+
+	// The old token is turned away, but right after rotation the session survives since the
+	// client may simply have refreshed twice at once
+	if _, err := service.RefreshSession(ctx, first.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidSession) {
+		t.Fatalf("Expected ErrInvalidSession, got %v", err)
+	}
+	if _, err := service.RefreshSession(ctx, second.RefreshToken, ClientInfo{}); err != nil {
+		t.Fatalf("Expected the current token to still work: %v", err)
+	}
+}
+
+func TestRefreshSession_ReuseRevokesSession(t *testing.T) {
+	service, store, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	first, _ := service.CreateSession(ctx, userID, ClientInfo{})
+	second, err := service.RefreshSession(ctx, first.RefreshToken, ClientInfo{})
+	if err != nil {
+		t.Fatalf("RefreshSession failed: %v", err)
+	}
+
+	// Reusing a token rotated long ago means someone else has it
+	rotatedAt := time.Now().Add(-2 * refreshReuseGrace)
+	store.sessions[first.SessionID].RotatedAt = &rotatedAt
+	if _, err := service.RefreshSession(ctx, first.RefreshToken, ClientInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("Expected ErrRefreshTokenReused, got %v", err)
+	}
+
+	if _, err := service.RefreshSession(ctx, second.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected the session's current token to stop working, got %v", err)
+	}
+	if _, err := service.ValidateSession(ctx, second.AccessToken, ""); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected the session's access token to stop working, got %v", err)
+	}
+}
+
+func TestRevokeSession_RejectsAccessToken(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	tokens, _ := service.CreateSession(ctx, userID, ClientInfo{})
+	if err := service.RevokeSession(ctx, userID+1, tokens.SessionID); err == nil {
+		t.Fatal("Expected revoking another user's session to fail")
+	}
+	if err := service.RevokeSession(ctx, userID, tokens.SessionID); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+
+	if _, err := service.ValidateSession(ctx, tokens.AccessToken, ""); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected a revoked session's access token to be rejected, got %v", err)
+	}
+	if _, err := service.RefreshSession(ctx, tokens.RefreshToken, ClientInfo{}); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected a revoked session's refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestEndSession(t *testing.T) {
+	service, store, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	byRefresh, _ := service.CreateSession(ctx, userID, ClientInfo{})
+	byAccess, _ := service.CreateSession(ctx, userID, ClientInfo{})
+
+	if err := service.EndSession(ctx, "", byRefresh.RefreshToken); err != nil {
+		t.Fatalf("EndSession with refresh token failed: %v", err)
+	}
+	if err := service.EndSession(ctx, byAccess.AccessToken, ""); err != nil {
+		t.Fatalf("EndSession with access token failed: %v", err)
+	}
+	for _, id := range []int{byRefresh.SessionID, byAccess.SessionID} {
+		if store.sessions[id].RevokedAt == nil {
+			t.Errorf("Expected session %d to be revoked", id)
+		}
+	}
+
+	if err := service.EndSession(ctx, "invalid", "unknown"); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("Expected ErrInvalidSession for unknown tokens, got %v", err)
+	}
+}
+
+func TestChangePassword_RevokesSessions(t *testing.T) {
+	service, _, userID := newSessionTestService(t)
+	ctx := context.Background()
+
+	laptop, _ := service.CreateSession(ctx, userID, ClientInfo{})
+	phone, _ := service.CreateSession(ctx, userID, ClientInfo{})
+
+	if err := service.ChangePassword(ctx, userID, "oldpassword123", "newpassword456"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+
+	for _, tokens := range []*SessionTokens{laptop, phone} {
+		if _, err := service.ValidateSession(ctx, tokens.AccessToken, ""); !errors.Is(err, ErrInvalidSession) {
+			t.Errorf("Expected session %d to be revoked, got %v", tokens.SessionID, err)
+		}
+	}
+	sessions, _ := service.ListSessions(ctx, userID)
+	if len(sessions) != 0 {
+		t.Errorf("Expected no active sessions, got %d", len(sessions))
+	}
+}
+
+func TestDeviceName(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0", "Firefox on Linux"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15", "Safari on macOS"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"curl/8.5.0", "curl"},
+		{"", "Unknown device"},
+	}
+
+	for _, tt := range tests {
+		if got := deviceName(tt.userAgent); got != tt.want {
+			t.Errorf("deviceName(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1 ", ""}); err != nil {
+		t.Fatalf("SetTrustedProxies failed: %v", err)
+	}
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	tests := []struct {
+		remoteAddr string
+		xff        string
+		xri        string
+		want       string
+	}{
+		// Headers from anyone but a trusted proxy are ignored
+		{"203.0.113.9:4000", "198.51.100.1", "198.51.100.2", "203.0.113.9"},
+		// The rightmost hop not added by a trusted proxy is the client
+		{"10.1.2.3:4000", "6.6.6.6, 198.51.100.1, 10.9.9.9", "", "198.51.100.1"},
+		{"192.0.2.1:4000", "", "198.51.100.2", "198.51.100.2"},
+		{"10.1.2.3:4000", "", "", "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if tt.xri != "" {
+			r.Header.Set("X-Real-IP", tt.xri)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("clientIP(%s, XFF %q, X-Real-IP %q) = %q, want %q", tt.remoteAddr, tt.xff, tt.xri, got, tt.want)
+		}
+	}
+
+	if err := SetTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("Expected an invalid proxy to be rejected")
+	}
+}
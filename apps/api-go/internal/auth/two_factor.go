@@ -0,0 +1,527 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// totpIssuer names the account in authenticator apps
+	totpIssuer = "LunaSentri"
+
+	// totpPeriod is how long each TOTP code is valid for
+	totpPeriod = 30 * time.Second
+
+	// totpDigits is the length of TOTP codes
+	totpDigits = 6
+
+	// totpSkew is how many periods before or after the current one are accepted, to allow for
+	// clock drift between the server and the authenticator app
+	totpSkew = 1
+
+	// totpSecretLength is the number of random bytes in a TOTP secret, as recommended by RFC 4226
+	totpSecretLength = 20
+
+	// RecoveryCodeCount is how many recovery codes are issued at a time
+	RecoveryCodeCount = 10
+
+	// LoginChallengeTTL is how long a user has to enter their two-factor code after their password
+	LoginChallengeTTL = 5 * time.Minute
+
+	// loginChallengePurpose marks JWTs that stand for a password check awaiting a two-factor code
+	loginChallengePurpose = "2fa"
+
+	// twoFactorPolicySetting is the setting the two-factor policy is stored under
+	twoFactorPolicySetting = "two_factor_policy"
+)
+
+// Two-factor policies, deciding who must set up an authenticator app
+const (
+	TwoFactorOptional = "optional" // users choose for themselves
+	TwoFactorAdmins   = "admins"   // required for owners and admins
+	TwoFactorAll      = "all"      // required for everyone
+)
+
+// TwoFactorPolicies lists the valid two-factor policies
+var TwoFactorPolicies = []string{TwoFactorOptional, TwoFactorAdmins, TwoFactorAll}
+
+var (
+	// ErrInvalidTwoFactorCode is returned when a TOTP or recovery code is wrong, expired or already used
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+	// ErrInvalidLoginChallenge is returned when a login challenge is malformed or expired
+	ErrInvalidLoginChallenge = errors.New("invalid or expired login challenge")
+
+	// ErrTwoFactorNotEnabled is returned when a user without two-factor authentication tries to manage it
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+
+	// ErrTwoFactorAlreadyEnabled is returned when starting enrolment while two-factor authentication is on
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrTwoFactorRequired is returned when a user the policy applies to tries to turn two-factor
+	// authentication off
+	ErrTwoFactorRequired = errors.New("two-factor authentication is required for your account")
+)
+
+// TOTPEnrollment is a pending authenticator app secret, shown to the user once so they can add it
+// to their app
+type TOTPEnrollment struct {
+	Secret          string // base32 secret, for entering by hand
+	ProvisioningURI string // otpauth:// URI, for rendering as a QR code
+}
+
+// TwoFactorStatus describes a user's two-factor authentication
+type TwoFactorStatus struct {
+	Enabled                bool
+	Pending                bool // a secret was generated but not confirmed yet
+	Required               bool // the policy requires the user to enable it
+	RecoveryCodesRemaining int
+}
+
+// TwoFactorStatus returns the state of the user's two-factor authentication
+func (s *Service) TwoFactorStatus(ctx context.Context, user *storage.User) (*TwoFactorStatus, error) {
+	required, err := s.TwoFactorRequired(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Required: required}
+
+	totp, err := s.store.GetUserTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return status, nil
+		}
+		return nil, fmt.Errorf("failed to get TOTP secret: %w", err)
+	}
+	status.Enabled = totp.EnabledAt != nil
+	status.Pending = totp.EnabledAt == nil
+
+	if status.Enabled {
+		status.RecoveryCodesRemaining, err = s.store.CountRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new authenticator app secret for the user. It only takes effect
+// once confirmed with EnableTOTP.
+func (s *Service) BeginTOTPEnrollment(ctx context.Context, userID int) (*TOTPEnrollment, error) {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetUserTOTPSecret(ctx, userID, secret); err != nil {
+		return nil, fmt.Errorf("failed to store TOTP secret: %w", err)
+	}
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(user.Email, secret),
+	}, nil
+}
+
+// EnableTOTP confirms the user's pending secret with a code from their authenticator app and
+// returns their recovery codes, which are only stored hashed and cannot be shown again
+func (s *Service) EnableTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	totp, err := s.store.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return nil, fmt.Errorf("no pending two-factor enrolment, start one first")
+		}
+		return nil, fmt.Errorf("failed to get TOTP secret: %w", err)
+	}
+	if totp.EnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := matchTOTP(totp.Secret, strings.Join(strings.Fields(code), ""), time.Now(), totp.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.EnableUserTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	log.Printf("Two-factor authentication enabled for user %d", userID)
+	audit.Record(ctx, s.store, audit.Event{Action: "user.2fa_enable", TargetType: "user", TargetID: userID})
+	return codes, nil
+}
+
+// DisableTOTP turns off the user's two-factor authentication after checking their password and a
+// current code. Users the policy applies to cannot turn it off.
+func (s *Service) DisableTOTP(ctx context.Context, userID int, password, code string) error {
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	required, err := s.TwoFactorRequired(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+
+	if err := VerifyPassword(user.PasswordHash, password); err != nil {
+		return fmt.Errorf("current password is incorrect")
+	}
+	if err := s.VerifySecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.store.DeleteUserTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	log.Printf("Two-factor authentication disabled by user %d", userID)
+	audit.Record(ctx, s.store, audit.Event{Action: "user.2fa_disable", TargetType: "user", TargetID: userID})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes after checking a current code
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	if err := s.VerifySecondFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+
+	log.Printf("Recovery codes regenerated for user %d", userID)
+	audit.Record(ctx, s.store, audit.Event{Action: "user.recovery_codes_regenerate", TargetType: "user", TargetID: userID})
+	return codes, nil
+}
+
+// ResetTwoFactor turns off another user's two-factor authentication, for when they have lost
+// both their authenticator app and their recovery codes. The same rules as deleting users apply.
+func (s *Service) ResetTwoFactor(ctx context.Context, actor *storage.User, userID int) error {
+	if userID == actor.ID {
+		return fmt.Errorf("cannot reset your own two-factor authentication")
+	}
+
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !canManageRole(actor.Role, user.Role) {
+		return ErrRoleForbidden
+	}
+
+	if err := s.store.DeleteUserTOTP(ctx, userID); err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return fmt.Errorf("failed to reset two-factor authentication: %w", err)
+	}
+
+	log.Printf("User %d reset the two-factor authentication of user %d", actor.ID, userID)
+	audit.Record(ctx, s.store, audit.Event{Action: "user.2fa_reset", TargetType: "user", TargetID: userID})
+	return nil
+}
+
+// VerifySecondFactor checks a code from the user's authenticator app, or one of their recovery
+// codes. Each code is accepted once.
+func (s *Service) VerifySecondFactor(ctx context.Context, userID int, code string) error {
+	totp, err := s.store.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTOTPNotFound) {
+			return ErrTwoFactorNotEnabled
+		}
+		return fmt.Errorf("failed to get TOTP secret: %w", err)
+	}
+	if totp.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.Join(strings.Fields(code), "")
+	if len(code) == totpDigits {
+		step, ok := matchTOTP(totp.Secret, code, time.Now(), totp.LastUsedStep)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		if err := s.store.UseTOTPStep(ctx, userID, step); err != nil {
+			if errors.Is(err, storage.ErrTOTPStepUsed) {
+				return ErrInvalidTwoFactorCode
+			}
+			return fmt.Errorf("failed to record TOTP use: %w", err)
+		}
+		return nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+	hash, _ := hashToken(normalized)
+	if err := s.store.UseRecoveryCode(ctx, userID, hash); err != nil {
+		if errors.Is(err, storage.ErrRecoveryCodeNotFound) {
+			return ErrInvalidTwoFactorCode
+		}
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	remaining, err := s.store.CountRecoveryCodes(ctx, userID)
+	if err == nil {
+		log.Printf("Recovery code used by user %d, %d left", userID, remaining)
+	}
+	return nil
+}
+
+// CreateLoginChallenge returns a short-lived token standing for the user's correct password,
+// which CompleteLoginChallenge exchanges for the user along with a two-factor code
+func (s *Service) CreateLoginChallenge(user *storage.User) (string, error) {
+	now := time.Now()
+	return signJWT(JWTClaims{
+		UserID:  user.ID,
+		Purpose: loginChallengePurpose,
+		Iat:     now.Unix(),
+		Exp:     now.Add(LoginChallengeTTL).Unix(),
+	}, s.purpose.Primary())
+}
+
+// LoginChallengeUser returns the user a login challenge was issued to
+func (s *Service) LoginChallengeUser(ctx context.Context, challenge string) (*storage.User, error) {
+	claims, err := parseJWT(challenge, s.purpose)
+	if err != nil || claims.Purpose != loginChallengePurpose {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	user, err := s.store.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidLoginChallenge
+	}
+	return user, nil
+}
+
+// CompleteLoginChallenge checks the two-factor code for a login challenge and returns the user
+// signing in
+func (s *Service) CompleteLoginChallenge(ctx context.Context, challenge, code string) (*storage.User, error) {
+	user, err := s.LoginChallengeUser(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.VerifySecondFactor(ctx, user.ID, code); err != nil {
+		if errors.Is(err, ErrTwoFactorNotEnabled) {
+			// Turned off by an admin since the password was checked
+			return user, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// TwoFactorPolicy returns who must use two-factor authentication
+func (s *Service) TwoFactorPolicy(ctx context.Context) (string, error) {
+	if policy := s.twoFactorPolicy.Load(); policy != nil {
+		return *policy, nil
+	}
+
+	policy, err := s.store.GetSetting(ctx, twoFactorPolicySetting)
+	if err != nil {
+		if !errors.Is(err, storage.ErrSettingNotFound) {
+			return "", fmt.Errorf("failed to get two-factor policy: %w", err)
+		}
+		policy = TwoFactorOptional
+	}
+	s.twoFactorPolicy.Store(&policy)
+	return policy, nil
+}
+
+// SetTwoFactorPolicy changes who must use two-factor authentication. Users it newly applies to
+// are asked to set it up the next time they use the dashboard.
+func (s *Service) SetTwoFactorPolicy(ctx context.Context, policy string) error {
+	if !slices.Contains(TwoFactorPolicies, policy) {
+		return fmt.Errorf("unknown two-factor policy %q, valid policies are %s", policy, strings.Join(TwoFactorPolicies, ", "))
+	}
+	before, err := s.TwoFactorPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.store.SetSetting(ctx, twoFactorPolicySetting, policy); err != nil {
+		return fmt.Errorf("failed to set two-factor policy: %w", err)
+	}
+	s.twoFactorPolicy.Store(&policy)
+	audit.Record(ctx, s.store, audit.Event{Action: "setting.update", TargetType: "setting", TargetID: twoFactorPolicySetting,
+		Before: before, After: policy})
+	return nil
+}
+
+// TwoFactorRequired reports whether the policy requires the user to use two-factor authentication
+func (s *Service) TwoFactorRequired(ctx context.Context, user *storage.User) (bool, error) {
+	policy, err := s.TwoFactorPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	switch policy {
+	case TwoFactorAll:
+		return true, nil
+	case TwoFactorAdmins:
+		return user.Role == storage.RoleOwner || user.Role == storage.RoleAdmin, nil
+	default:
+		return false, nil
+	}
+}
+
+// twoFactorSetupPaths are the paths users who must set up two-factor authentication can still use
+var twoFactorSetupPaths = []string{"/auth/me", "/auth/2fa", "/auth/sessions"}
+
+// needsTwoFactorSetup reports whether the request must wait until the user sets up two-factor
+// authentication, as the policy requires
+func (s *Service) needsTwoFactorSetup(r *http.Request, user *storage.User) (bool, error) {
+	if user.TOTPEnabled {
+		return false, nil
+	}
+	for _, path := range twoFactorSetupPaths {
+		if r.URL.Path == path || strings.HasPrefix(r.URL.Path, path+"/") {
+			return false, nil
+		}
+	}
+	return s.TwoFactorRequired(r.Context(), user)
+}
+
+// generateTOTPSecret generates a random base32 TOTP secret
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret), nil
+}
+
+// totpProvisioningURI returns the otpauth:// URI authenticator apps read from QR codes
+func totpProvisioningURI(email, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", totpIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	label := url.PathEscape(totpIssuer) + ":" + url.PathEscape(email)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPCode returns the code an authenticator app shows for the secret at the given time
+func TOTPCode(secret string, at time.Time) (string, error) {
+	return totpCode(secret, totpStep(at))
+}
+
+// totpCode computes the RFC 6238 code of the secret for a time step
+func totpCode(secret string, step int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulus := uint32(1)
+	for range totpDigits {
+		modulus *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulus), nil
+}
+
+// totpStep returns the time step of t
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod.Seconds())
+}
+
+// matchTOTP returns the time step the code is valid for around now, skipping steps up to and
+// including lastStep so that codes cannot be replayed
+func matchTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := totpStep(now)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := totpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes generates a set of recovery codes, formatted like "abcde-fghij", along with
+// the hashes they are stored as
+func newRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i], _ = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips the formatting users may type a recovery code with
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
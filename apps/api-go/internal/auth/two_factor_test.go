@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA-1 secret "12345678901234567890", truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := totpCode(secret, totpStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("totpCode failed: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("At %d expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Unix(1111111111, 0)
+	current := totpStep(now)
+	previous, _ := totpCode(secret, current-1)
+	tooOld, _ := totpCode(secret, current-2)
+
+	if step, ok := matchTOTP(secret, previous, now, 0); !ok || step != current-1 {
+		t.Errorf("Expected the previous period's code to match step %d, got %d, %v", current-1, step, ok)
+	}
+	if _, ok := matchTOTP(secret, tooOld, now, 0); ok {
+		t.Error("Expected a code two periods old to be rejected")
+	}
+	if _, ok := matchTOTP(secret, previous, now, current-1); ok {
+		t.Error("Expected a code whose step was already used to be rejected")
+	}
+}
+
+func TestTwoFactorEnrollmentAndVerification(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	enrollment, err := service.BeginTOTPEnrollment(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("BeginTOTPEnrollment failed: %v", err)
+	}
+	uri, err := url.Parse(enrollment.ProvisioningURI)
+	if err != nil || uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Query().Get("secret") != enrollment.Secret ||
+		uri.Query().Get("issuer") != "LunaSentri" || !strings.Contains(uri.Path, user.Email) {
+		t.Errorf("Unexpected provisioning URI %q", enrollment.ProvisioningURI)
+	}
+
+	// Not enabled until confirmed
+	if got, _ := store.GetUserByID(ctx, user.ID); got.TOTPEnabled {
+		t.Error("Expected two-factor authentication to stay off until confirmed")
+	}
+	if _, err := service.EnableTOTP(ctx, user.ID, "000000"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a wrong code to be rejected, got %v", err)
+	}
+
+	code, _ := totpCode(enrollment.Secret, totpStep(time.Now()))
+	recoveryCodes, err := service.EnableTOTP(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("EnableTOTP failed: %v", err)
+	}
+	if len(recoveryCodes) != RecoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d", RecoveryCodeCount, len(recoveryCodes))
+	}
+	if got, _ := store.GetUserByID(ctx, user.ID); !got.TOTPEnabled {
+		t.Error("Expected two-factor authentication to be on")
+	}
+
+	// The code that confirmed enrolment cannot be used again
+	if err := service.VerifySecondFactor(ctx, user.ID, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a replayed code to be rejected, got %v", err)
+	}
+
+	// Recovery codes work once, however they are typed
+	typed := strings.ToUpper(strings.ReplaceAll(recoveryCodes[0], "-", " "))
+	if err := service.VerifySecondFactor(ctx, user.ID, typed); err != nil {
+		t.Errorf("Expected the recovery code to be accepted, got %v", err)
+	}
+	if err := service.VerifySecondFactor(ctx, user.ID, recoveryCodes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a used recovery code to be rejected, got %v", err)
+	}
+	status, err := service.TwoFactorStatus(ctx, user)
+	if err != nil {
+		t.Fatalf("TwoFactorStatus failed: %v", err)
+	}
+	if !status.Enabled || status.RecoveryCodesRemaining != RecoveryCodeCount-1 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// A new enrolment cannot replace an active secret
+	if _, err := service.BeginTOTPEnrollment(ctx, user.ID); !errors.Is(err, ErrTwoFactorAlreadyEnabled) {
+		t.Errorf("Expected ErrTwoFactorAlreadyEnabled, got %v", err)
+	}
+}
+
+func TestLoginChallenge(t *testing.T) {
+	service, _, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	enrollment, _ := service.BeginTOTPEnrollment(ctx, user.ID)
+	code, _ := totpCode(enrollment.Secret, totpStep(time.Now()))
+	recoveryCodes, err := service.EnableTOTP(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("EnableTOTP failed: %v", err)
+	}
+
+	challenge, err := service.CreateLoginChallenge(user)
+	if err != nil {
+		t.Fatalf("CreateLoginChallenge failed: %v", err)
+	}
+
+	// A challenge is not an access token
+	if _, err := service.ValidateSession(ctx, challenge, ""); err == nil {
+		t.Error("Expected a challenge to be refused as a session")
+	}
+
+	// Nor is an access token a challenge
+	session, _ := service.CreateSession(ctx, user.ID, ClientInfo{})
+	if _, err := service.CompleteLoginChallenge(ctx, session.AccessToken, recoveryCodes[0]); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("Expected an access token to be refused as a challenge, got %v", err)
+	}
+
+	// Challenges are signed with an unpublished key of their own, so the access token keys neither
+	// verify them nor can mint them
+	if _, err := parseJWT(challenge, service.keys); err == nil {
+		t.Error("Expected the access token keys not to verify a challenge")
+	}
+	forged, _ := signJWT(JWTClaims{UserID: user.ID, Purpose: loginChallengePurpose, Exp: time.Now().Add(time.Minute).Unix()}, service.keys.Primary())
+	if _, err := service.LoginChallengeUser(ctx, forged); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("Expected a challenge signed with the access token key to be refused, got %v", err)
+	}
+
+	if _, err := service.CompleteLoginChallenge(ctx, challenge, "123456"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Expected a wrong code to be rejected, got %v", err)
+	}
+	got, err := service.CompleteLoginChallenge(ctx, challenge, recoveryCodes[0])
+	if err != nil {
+		t.Fatalf("CompleteLoginChallenge failed: %v", err)
+	}
+	if got.ID != user.ID {
+		t.Errorf("Expected user %d, got %d", user.ID, got.ID)
+	}
+}
+
+func TestTwoFactorPolicy(t *testing.T) {
+	service, store, operator := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	owner, err := store.UpsertAdmin(ctx, "owner@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create owner: %v", err)
+	}
+
+	if policy, _ := service.TwoFactorPolicy(ctx); policy != TwoFactorOptional {
+		t.Errorf("Expected the default policy to be optional, got %s", policy)
+	}
+	if err := service.SetTwoFactorPolicy(ctx, "sometimes"); err == nil {
+		t.Error("Expected an unknown policy to be rejected")
+	}
+	if err := service.SetTwoFactorPolicy(ctx, TwoFactorAdmins); err != nil {
+		t.Fatalf("SetTwoFactorPolicy failed: %v", err)
+	}
+
+	// The policy survives a restart
+	restarted, _ := NewService(store, "test-secret", 15*time.Minute)
+	if policy, _ := restarted.TwoFactorPolicy(ctx); policy != TwoFactorAdmins {
+		t.Errorf("Expected the stored policy, got %s", policy)
+	}
+
+	if required, _ := service.TwoFactorRequired(ctx, owner); !required {
+		t.Error("Expected two-factor authentication to be required for the owner")
+	}
+	if required, _ := service.TwoFactorRequired(ctx, operator); required {
+		t.Error("Expected two-factor authentication to stay optional for the operator")
+	}
+
+	// Until they set it up, the owner can only reach the setup endpoints
+	for path, want := range map[string]bool{"/machines": true, "/auth/2fa/setup": false, "/auth/me": false} {
+		needed, err := service.needsTwoFactorSetup(httptest.NewRequest("GET", path, nil), owner)
+		if err != nil {
+			t.Fatalf("needsTwoFactorSetup failed: %v", err)
+		}
+		if needed != want {
+			t.Errorf("Expected setup needed for %s to be %v", path, want)
+		}
+	}
+}
+
+func TestResetTwoFactor(t *testing.T) {
+	service, store, user := newAPITokenTestService(t)
+	ctx := context.Background()
+
+	enrollment, _ := service.BeginTOTPEnrollment(ctx, user.ID)
+	code, _ := totpCode(enrollment.Secret, totpStep(time.Now()))
+	if _, err := service.EnableTOTP(ctx, user.ID, code); err != nil {
+		t.Fatalf("EnableTOTP failed: %v", err)
+	}
+
+	viewer := &storage.User{ID: 999, Role: storage.RoleViewer}
+	if err := service.ResetTwoFactor(ctx, viewer, user.ID); !errors.Is(err, ErrRoleForbidden) {
+		t.Errorf("Expected a viewer to be refused, got %v", err)
+	}
+
+	admin := &storage.User{ID: 998, Role: storage.RoleAdmin}
+	if err := service.ResetTwoFactor(ctx, admin, user.ID); err != nil {
+		t.Fatalf("ResetTwoFactor failed: %v", err)
+	}
+	if got, _ := store.GetUserByID(ctx, user.ID); got.TOTPEnabled {
+		t.Error("Expected two-factor authentication to be off after the reset")
+	}
+	if n, _ := store.CountRecoveryCodes(ctx, user.ID); n != 0 {
+		t.Errorf("Expected recovery codes to be removed, %d left", n)
+	}
+	if err := service.ResetTwoFactor(ctx, admin, user.ID); !errors.Is(err, ErrTwoFactorNotEnabled) {
+		t.Errorf("Expected ErrTwoFactorNotEnabled, got %v", err)
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"log"
 	"strings"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
@@ -28,9 +30,15 @@ type CreateUserResponse struct {
 	TempPassword string `json:"temp_password,omitempty"` // Only present if password was generated
 }
 
-// CreateUser creates a new user with email validation
-// If this is the first user, they are automatically promoted to admin
+// CreateUser creates a new operator with email validation
+// If this is the first user, they are automatically made the owner
 func (s *Service) CreateUser(ctx context.Context, email, password string) (*storage.User, string, error) {
+	return s.CreateUserWithRole(ctx, email, password, "")
+}
+
+// CreateUserWithRole creates a new user with the role, or an operator if role is empty. Callers
+// check that the role may be given. The first user is still made the owner.
+func (s *Service) CreateUserWithRole(ctx context.Context, email, password, role string) (*storage.User, string, error) {
 	// Validate email
 	if email == "" {
 		return nil, "", fmt.Errorf("email cannot be empty")
@@ -72,18 +80,29 @@ func (s *Service) CreateUser(ctx context.Context, email, password string) (*stor
 		return nil, "", fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Promote to admin if first user
+	// Make the first user the owner
 	if userCount == 0 {
-		err = s.store.PromoteToAdmin(ctx, user.ID)
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to promote first user to admin: %w", err)
+		role = storage.RoleOwner
+	}
+	if role != "" && role != user.Role {
+		if err := s.store.SetUserRole(ctx, user.ID, role); err != nil {
+			return nil, "", fmt.Errorf("failed to give the new user the %s role: %w", role, err)
 		}
-		user.IsAdmin = true
+		user.Role = role
+		user.IsAdmin = role == storage.RoleOwner || role == storage.RoleAdmin
 	}
+	audit.Record(ctx, s.store, audit.Event{Action: "user.create", TargetType: "user", TargetID: user.ID, After: user})
 
 	return user, tempPassword, nil
 }
 
+// RegisterUser creates an account for someone signing up on their own. They become a viewer
+// until an admin gives them more access; the first user still becomes the owner.
+func (s *Service) RegisterUser(ctx context.Context, email, password string) (*storage.User, error) {
+	user, _, err := s.CreateUserWithRole(ctx, email, password, storage.RoleViewer)
+	return user, err
+}
+
 // ListUsers returns all users
 func (s *Service) ListUsers(ctx context.Context) ([]storage.User, error) {
 	users, err := s.store.ListUsers(ctx)
@@ -100,17 +119,42 @@ func (s *Service) DeleteUser(ctx context.Context, userID, currentUserID int) err
 		return fmt.Errorf("cannot delete your own account")
 	}
 
-	// Delete the user (store will prevent deleting last admin)
-	err := s.store.DeleteUser(ctx, userID)
+	// Only owners can delete owners and admins
+	currentUser, err := s.store.GetUserByID(ctx, currentUserID)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	user, err := s.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if err == storage.ErrUserNotFound {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !canManageRole(currentUser.Role, user.Role) {
+		return ErrRoleForbidden
+	}
+
+	// Delete the user (store will prevent deleting the last admin or owner)
+	err = s.store.DeleteUser(ctx, userID)
 	if err != nil {
 		if strings.Contains(err.Error(), "cannot delete the last admin") {
 			return fmt.Errorf("cannot delete the last admin")
 		}
+		if strings.Contains(err.Error(), "cannot delete the last owner") {
+			return fmt.Errorf("cannot delete the last owner")
+		}
 		if err == storage.ErrUserNotFound {
 			return fmt.Errorf("user not found")
 		}
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
+	audit.Record(ctx, s.store, audit.Event{Action: "user.delete", TargetType: "user", TargetID: userID, Before: user})
+
+	// Sessions are deleted with the user; revoke them too in case foreign keys are not enforced
+	if _, err := s.store.RevokeUserSessions(ctx, userID, 0); err != nil {
+		log.Printf("Warning: failed to revoke sessions of deleted user %d: %v", userID, err)
+	}
 
 	return nil
 }
@@ -162,6 +206,12 @@ func (s *Service) ChangePassword(ctx context.Context, userID int, currentPasswor
 		fmt.Printf("Warning: failed to delete password resets for user %d: %v\n", userID, err)
 	}
 
+	// End every session, since one may belong to whoever knew the old password
+	if _, err := s.store.RevokeUserSessions(ctx, userID, 0); err != nil {
+		return fmt.Errorf("password changed but failed to end sessions: %w", err)
+	}
+	audit.Record(ctx, s.store, audit.Event{Action: "user.password_change", TargetType: "user", TargetID: userID})
+
 	return nil
 }
 
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -378,6 +379,18 @@ func TestCreateUser_FirstUserIsAdmin(t *testing.T) {
 	}
 }
 
+func TestRegisterUser_IsViewer(t *testing.T) {
+	service, _, _ := newAPITokenTestService(t)
+
+	user, err := service.RegisterUser(context.Background(), "signup@example.com", "password123")
+	if err != nil {
+		t.Fatalf("RegisterUser failed: %v", err)
+	}
+	if user.Role != storage.RoleViewer || user.IsAdmin {
+		t.Errorf("Expected a self-registered user to be a viewer, got role %q", user.Role)
+	}
+}
+
 func TestDeleteUser_CannotDeleteLastAdmin(t *testing.T) {
 	store, err := storage.NewSQLiteStore("file::memory:?cache=shared")
 	if err != nil {
@@ -414,7 +427,13 @@ func TestDeleteUser_CannotDeleteLastAdmin(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error when deleting last admin")
 	}
-	if !strings.Contains(err.Error(), "cannot delete the last admin") {
+	if !errors.Is(err, ErrRoleForbidden) {
+		t.Errorf("Expected ErrRoleForbidden, got: %v", err)
+	}
+
+	// The store refuses too, whoever asks
+	err = store.DeleteUser(ctx, admin.ID)
+	if err == nil || !strings.Contains(err.Error(), "cannot delete the last admin") {
 		t.Errorf("Expected 'cannot delete the last admin' error, got: %v", err)
 	}
 
@@ -458,6 +477,14 @@ func TestDeleteUser_CanDeleteAdminWithMultipleAdmins(t *testing.T) {
 		t.Fatalf("Failed to promote admin2: %v", err)
 	}
 
+	// Admins cannot delete the owner, another owner can
+	if err := service.DeleteUser(ctx, admin1.ID, admin2.ID); !errors.Is(err, ErrRoleForbidden) {
+		t.Fatalf("Expected ErrRoleForbidden deleting the owner as an admin, got %v", err)
+	}
+	if err := store.SetUserRole(ctx, admin2.ID, storage.RoleOwner); err != nil {
+		t.Fatalf("Failed to make admin2 an owner: %v", err)
+	}
+
 	// Delete first admin (should succeed since there's another admin)
 	err = service.DeleteUser(ctx, admin1.ID, admin2.ID)
 	if err != nil {
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DefaultSMTPPort is the mail submission port, which upgrades to TLS with STARTTLS
+const DefaultSMTPPort = 587
+
+// MailConfig holds configuration for outbound email
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string // optional; no authentication when empty
+	SMTPPassword string
+	From         string // sender address, e.g. "LunaSentri <noreply@example.com>"
+}
+
+// LoadMailConfig loads outbound email configuration from environment variables
+func LoadMailConfig() (*MailConfig, error) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, fmt.Errorf("SMTP_HOST environment variable is required")
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		return nil, fmt.Errorf("SMTP_FROM environment variable is required")
+	}
+
+	port := DefaultSMTPPort
+	if portStr := os.Getenv("SMTP_PORT"); portStr != "" {
+		parsedPort, err := strconv.Atoi(portStr)
+		if err != nil || parsedPort <= 0 || parsedPort > 65535 {
+			return nil, fmt.Errorf("SMTP_PORT must be a port number")
+		}
+		port = parsedPort
+	}
+
+	username := os.Getenv("SMTP_USERNAME")
+	password := os.Getenv("SMTP_PASSWORD")
+	if username != "" && password == "" {
+		return nil, fmt.Errorf("SMTP_PASSWORD is required when SMTP_USERNAME is set")
+	}
+
+	return &MailConfig{
+		SMTPHost:     host,
+		SMTPPort:     port,
+		SMTPUsername: username,
+		SMTPPassword: password,
+		From:         from,
+	}, nil
+}
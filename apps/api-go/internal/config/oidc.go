@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Defaults for OpenID Connect single sign-on
+const (
+	DefaultOIDCScopes       = "openid email profile"
+	DefaultOIDCEmailClaim   = "email"
+	DefaultOIDCGroupsClaim  = "groups"
+	DefaultOIDCProviderName = "SSO"
+)
+
+// OIDCConfig holds configuration for OpenID Connect single sign-on
+type OIDCConfig struct {
+	IssuerURL    string // provider discovery document is at IssuerURL + "/.well-known/openid-configuration"
+	ClientID     string
+	ClientSecret string // optional; public clients rely on PKCE alone
+	RedirectURL  string // this API's /auth/oidc/callback, as registered with the provider
+	Scopes       []string
+	ProviderName string // shown on the login button
+
+	EmailClaim    string            // ID token claim holding the user's email
+	GroupsClaim   string            // ID token claim holding the user's groups, for RoleMapping
+	RoleMapping   map[string]string // group -> role; when set, roles follow the provider's groups at every login
+	DefaultRole   string            // role of users in none of the mapped groups; viewer when empty
+	AutoProvision bool              // create users on their first login instead of refusing them
+	TrustEmail    bool              // treat emails as verified without an email_verified claim of true
+}
+
+// LoadOIDCConfig loads OpenID Connect configuration from environment variables
+func LoadOIDCConfig() (*OIDCConfig, error) {
+	issuerURL := strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/")
+	if issuerURL == "" {
+		return nil, fmt.Errorf("OIDC_ISSUER_URL environment variable is required")
+	}
+
+	clientID := os.Getenv("OIDC_CLIENT_ID")
+	if clientID == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID environment variable is required")
+	}
+
+	redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectURL == "" {
+		return nil, fmt.Errorf("OIDC_REDIRECT_URL environment variable is required")
+	}
+
+	scopes := strings.Fields(strings.ReplaceAll(os.Getenv("OIDC_SCOPES"), ",", " "))
+	if len(scopes) == 0 {
+		scopes = strings.Fields(DefaultOIDCScopes)
+	}
+	hasOpenID := false
+	for _, scope := range scopes {
+		hasOpenID = hasOpenID || scope == "openid"
+	}
+	if !hasOpenID {
+		return nil, fmt.Errorf("OIDC_SCOPES must include openid")
+	}
+
+	roleMapping := map[string]string{}
+	if mapping := os.Getenv("OIDC_ROLE_MAPPING"); mapping != "" {
+		for _, pair := range strings.Split(mapping, ",") {
+			group, role, ok := strings.Cut(pair, "=")
+			group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+			if !ok || group == "" || role == "" {
+				return nil, fmt.Errorf("OIDC_ROLE_MAPPING must be a comma-separated list of group=role pairs")
+			}
+			roleMapping[group] = role
+		}
+	}
+
+	return &OIDCConfig{
+		IssuerURL:     issuerURL,
+		ClientID:      clientID,
+		ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:   redirectURL,
+		Scopes:        scopes,
+		ProviderName:  envOr("OIDC_PROVIDER_NAME", DefaultOIDCProviderName),
+		EmailClaim:    envOr("OIDC_EMAIL_CLAIM", DefaultOIDCEmailClaim),
+		GroupsClaim:   envOr("OIDC_GROUPS_CLAIM", DefaultOIDCGroupsClaim),
+		RoleMapping:   roleMapping,
+		DefaultRole:   os.Getenv("OIDC_DEFAULT_ROLE"),
+		AutoProvision: os.Getenv("OIDC_AUTO_PROVISION") != "false",
+		TrustEmail:    os.Getenv("OIDC_TRUST_EMAIL") == "true",
+	}, nil
+}
+
+// IsEnabled returns true if single sign-on is configured
+func (c *OIDCConfig) IsEnabled() bool {
+	return c != nil && c.IssuerURL != "" && c.ClientID != ""
+}
+
+// envOr returns the environment variable, or the fallback when it is unset or empty
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -3,11 +3,25 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+)
+
+// DefaultTelegramAPIBaseURL is the public Telegram Bot API endpoint
+const DefaultTelegramAPIBaseURL = "https://api.telegram.org"
+
+// Telegram bot modes for handling inbound commands
+const (
+	TelegramBotModeOff     = "off"     // Outbound notifications only
+	TelegramBotModePolling = "polling" // Long-poll getUpdates
+	TelegramBotModeWebhook = "webhook" // Telegram pushes updates to /notifications/telegram/webhook
 )
 
 // TelegramConfig holds configuration for Telegram bot notifications
 type TelegramConfig struct {
-	BotToken string
+	BotToken      string
+	APIBaseURL    string // Bot API base URL, overridable for self-hosted Bot API servers and tests
+	BotMode       string // One of the TelegramBotMode* constants
+	WebhookSecret string // Secret token Telegram echoes in X-Telegram-Bot-Api-Secret-Token (webhook mode)
 }
 
 // LoadTelegramConfig loads Telegram configuration from environment variables
@@ -18,8 +32,29 @@ func LoadTelegramConfig() (*TelegramConfig, error) {
 		return nil, fmt.Errorf("TELEGRAM_BOT_TOKEN environment variable is required")
 	}
 
+	apiBaseURL := strings.TrimSuffix(os.Getenv("TELEGRAM_API_BASE_URL"), "/")
+	if apiBaseURL == "" {
+		apiBaseURL = DefaultTelegramAPIBaseURL
+	}
+
+	botMode := strings.ToLower(os.Getenv("TELEGRAM_BOT_MODE"))
+	if botMode == "" {
+		botMode = TelegramBotModeOff
+	}
+	if botMode != TelegramBotModeOff && botMode != TelegramBotModePolling && botMode != TelegramBotModeWebhook {
+		return nil, fmt.Errorf("TELEGRAM_BOT_MODE must be one of off, polling, webhook")
+	}
+
+	webhookSecret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	if botMode == TelegramBotModeWebhook && webhookSecret == "" {
+		return nil, fmt.Errorf("TELEGRAM_WEBHOOK_SECRET is required when TELEGRAM_BOT_MODE=webhook")
+	}
+
 	return &TelegramConfig{
-		BotToken: botToken,
+		BotToken:      botToken,
+		APIBaseURL:    apiBaseURL,
+		BotMode:       botMode,
+		WebhookSecret: webhookSecret,
 	}, nil
 }
 
@@ -27,3 +62,17 @@ func LoadTelegramConfig() (*TelegramConfig, error) {
 func (c *TelegramConfig) IsEnabled() bool {
 	return c != nil && c.BotToken != ""
 }
+
+// BotEnabled returns true if the bot should handle inbound commands
+func (c *TelegramConfig) BotEnabled() bool {
+	return c.IsEnabled() && (c.BotMode == TelegramBotModePolling || c.BotMode == TelegramBotModeWebhook)
+}
+
+// MethodURL returns the Bot API URL for a method such as "sendMessage"
+func (c *TelegramConfig) MethodURL(method string) string {
+	baseURL := c.APIBaseURL
+	if baseURL == "" {
+		baseURL = DefaultTelegramAPIBaseURL
+	}
+	return fmt.Sprintf("%s/bot%s/%s", baseURL, c.BotToken, method)
+}
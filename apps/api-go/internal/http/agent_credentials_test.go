@@ -28,7 +28,8 @@ func TestHandleDisableMachine(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/machines/%d/disable", machine.ID), nil)
 	
 	// Add user to context
-	reqCtx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	reqCtx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(reqCtx)
 
 	w := httptest.NewRecorder()
@@ -71,7 +72,8 @@ func TestHandleEnableMachine(t *testing.T) {
 
 	// Create request to enable
 	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/machines/%d/enable", machine.ID), nil)
-	reqCtx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	reqCtx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(reqCtx)
 
 	w := httptest.NewRecorder()
@@ -102,7 +104,8 @@ func TestHandleRotateMachineAPIKey(t *testing.T) {
 
 	// Create request
 	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/machines/%d/rotate-key", machine.ID), nil)
-	reqCtx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	reqCtx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(reqCtx)
 
 	w := httptest.NewRecorder()
@@ -154,7 +157,8 @@ func TestHandleDisableMachineUnauthorized(t *testing.T) {
 
 	// User2 tries to disable user1's machine
 	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/machines/%d/disable", machine.ID), nil)
-	reqCtx := context.WithValue(req.Context(), auth.UserContextKey, user2)
+	reqCtx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user2), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user2.ID, UserID: user2.ID, Role: storage.RoleOwner})
 	req = req.WithContext(reqCtx)
 
 	w := httptest.NewRecorder()
@@ -61,8 +61,8 @@ func handleAgentRegister(machineService *machines.Service) http.HandlerFunc {
 			return
 		}
 
-		// Get authenticated user from context (set by RequireAuth middleware)
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get the organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -83,14 +83,14 @@ func handleAgentRegister(machineService *machines.Service) http.HandlerFunc {
 		}
 
 		// Register machine
-		machine, apiKey, err := machineService.RegisterMachine(r.Context(), user.ID, req.Name, req.Hostname, req.Description)
+		machine, apiKey, err := machineService.RegisterMachine(r.Context(), org.OrgID, req.Name, req.Hostname, req.Description)
 		if err != nil {
-			log.Printf("Failed to register machine for user %d: %v", user.ID, err)
+			log.Printf("Failed to register machine for organization %d: %v", org.OrgID, err)
 			http.Error(w, "Failed to register machine", http.StatusInternalServerError)
 			return
 		}
 
-		log.Printf("Machine registered: id=%d, name=%s, user_id=%d", machine.ID, machine.Name, user.ID)
+		log.Printf("Machine registered: id=%d, name=%s, org_id=%d", machine.ID, machine.Name, org.OrgID)
 
 		// Return machine details with API key (only time it's visible)
 		response := RegisterMachineResponse{
@@ -171,9 +171,9 @@ func handleAgentMetrics(machineService *machines.Service) http.HandlerFunc {
 		}
 
 		// Log structured info for monitoring
-		userID, _ := GetUserIDFromContext(r.Context())
-		log.Printf("Metrics recorded: machine_id=%d, user_id=%d, remote_ip=%s, cpu=%.1f%%, mem=%.1f%%, disk=%.1f%%, uptime=%.0fs",
-			machineID, userID, getRemoteIP(r), req.CPUPct, req.MemUsedPct, req.DiskUsedPct, func() float64 {
+		orgID, _ := GetOrgIDFromContext(r.Context())
+		log.Printf("Metrics recorded: machine_id=%d, org_id=%d, remote_ip=%s, cpu=%.1f%%, mem=%.1f%%, disk=%.1f%%, uptime=%.0fs",
+			machineID, orgID, getRemoteIP(r), req.CPUPct, req.MemUsedPct, req.DiskUsedPct, func() float64 {
 				if req.UptimeS != nil {
 					return *req.UptimeS
 				}
@@ -185,27 +185,9 @@ func handleAgentMetrics(machineService *machines.Service) http.HandlerFunc {
 	}
 }
 
-// getRemoteIP extracts the remote IP from the request
+// getRemoteIP extracts the remote IP from the request, trusting proxy headers only from trusted proxies
 func getRemoteIP(r *http.Request) string {
-	// Check X-Forwarded-For header first (for reverse proxies)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// X-Forwarded-For can contain multiple IPs, take the first one
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
-		}
-		return strings.TrimSpace(xff)
-	}
-
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return strings.TrimSpace(xri)
-	}
-
-	// Fall back to RemoteAddr
-	if idx := strings.LastIndex(r.RemoteAddr, ":"); idx != -1 {
-		return r.RemoteAddr[:idx]
-	}
-	return r.RemoteAddr
+	return auth.ClientFromRequest(r).IPAddress
 }
 
 // handleListMachines handles GET /machines (requires session auth)
@@ -216,17 +198,17 @@ func handleListMachines(machineService *machines.Service) http.HandlerFunc {
 			return
 		}
 
-		// Get authenticated user from context (set by RequireAuth middleware)
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get the organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
 		// List machines with computed statuses
-		machinesList, err := machineService.ListMachinesWithComputedStatus(r.Context(), user.ID)
+		machinesList, err := machineService.ListMachinesWithComputedStatus(r.Context(), org.OrgID)
 		if err != nil {
-			log.Printf("Failed to list machines for user %d: %v", user.ID, err)
+			log.Printf("Failed to list machines for organization %d: %v", org.OrgID, err)
 			http.Error(w, "Failed to list machines", http.StatusInternalServerError)
 			return
 		}
@@ -244,8 +226,8 @@ func handleDeleteMachine(machineService *machines.Service) http.HandlerFunc {
 			return
 		}
 
-		// Get authenticated user from context
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get the organization from context
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -266,9 +248,9 @@ func handleDeleteMachine(machineService *machines.Service) http.HandlerFunc {
 		}
 
 		// Delete the machine (service will verify ownership)
-		err = machineService.DeleteMachine(r.Context(), machineID, user.ID)
+		err = machineService.DeleteMachine(r.Context(), machineID, org.OrgID)
 		if err != nil {
-			log.Printf("Failed to delete machine %d for user %d: %v", machineID, user.ID, err)
+			log.Printf("Failed to delete machine %d for organization %d: %v", machineID, org.OrgID, err)
 			http.Error(w, "Failed to delete machine", http.StatusInternalServerError)
 			return
 		}
@@ -288,8 +270,8 @@ func handleUpdateMachine(machineService *machines.Service) http.HandlerFunc {
 			return
 		}
 
-		// Get authenticated user from context
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get the organization from context
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -310,19 +292,27 @@ func handleUpdateMachine(machineService *machines.Service) http.HandlerFunc {
 
 		// Parse update request
 		var req struct {
-			Name        *string `json:"name"`
-			Hostname    *string `json:"hostname"`
-			Description *string `json:"description"`
+			Name        *string   `json:"name"`
+			Hostname    *string   `json:"hostname"`
+			Description *string   `json:"description"`
+			Tags        *[]string `json:"tags"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid JSON", http.StatusBadRequest)
 			return
 		}
 
+		if req.Tags != nil {
+			if _, err := machines.NormalizeTags(*req.Tags); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
 		// Update the machine (service will verify ownership)
-		err = machineService.UpdateMachine(r.Context(), machineID, user.ID, req.Name, req.Hostname, req.Description)
+		err = machineService.UpdateMachine(r.Context(), machineID, org.OrgID, req.Name, req.Hostname, req.Description, req.Tags)
 		if err != nil {
-			log.Printf("Failed to update machine %d for user %d: %v", machineID, user.ID, err)
+			log.Printf("Failed to update machine %d for organization %d: %v", machineID, org.OrgID, err)
 			http.Error(w, "Failed to update machine", http.StatusInternalServerError)
 			return
 		}
@@ -342,8 +332,8 @@ func handleDisableMachine(machineService *machines.Service) http.HandlerFunc {
 			return
 		}
 
-		// Get authenticated user from context
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get the organization from context
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -369,15 +359,15 @@ func handleDisableMachine(machineService *machines.Service) http.HandlerFunc {
 		}
 
 		// Disable the machine
-		if err := machineService.DisableMachine(r.Context(), machineID, user.ID); err != nil {
-			log.Printf("Failed to disable machine %d for user %d: %v", machineID, user.ID, err)
+		if err := machineService.DisableMachine(r.Context(), machineID, org.OrgID); err != nil {
+			log.Printf("Failed to disable machine %d for organization %d: %v", machineID, org.OrgID, err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to disable machine"})
 			return
 		}
 
-		log.Printf("Machine disabled: id=%d, user_id=%d", machineID, user.ID)
+		log.Printf("Machine disabled: id=%d, org_id=%d", machineID, org.OrgID)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"message": "Machine disabled successfully",
@@ -393,8 +383,8 @@ func handleEnableMachine(machineService *machines.Service) http.HandlerFunc {
 			return
 		}
 
-		// Get authenticated user from context
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get the organization from context
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -420,15 +410,15 @@ func handleEnableMachine(machineService *machines.Service) http.HandlerFunc {
 		}
 
 		// Enable the machine
-		if err := machineService.EnableMachine(r.Context(), machineID, user.ID); err != nil {
-			log.Printf("Failed to enable machine %d for user %d: %v", machineID, user.ID, err)
+		if err := machineService.EnableMachine(r.Context(), machineID, org.OrgID); err != nil {
+			log.Printf("Failed to enable machine %d for organization %d: %v", machineID, org.OrgID, err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to enable machine"})
 			return
 		}
 
-		log.Printf("Machine enabled: id=%d, user_id=%d", machineID, user.ID)
+		log.Printf("Machine enabled: id=%d, org_id=%d", machineID, org.OrgID)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"message": "Machine enabled successfully",
@@ -444,8 +434,8 @@ func handleRotateMachineAPIKey(machineService *machines.Service) http.HandlerFun
 			return
 		}
 
-		// Get authenticated user from context
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get the organization from context
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -471,16 +461,16 @@ func handleRotateMachineAPIKey(machineService *machines.Service) http.HandlerFun
 		}
 
 		// Rotate the API key
-		newAPIKey, err := machineService.RotateMachineAPIKey(r.Context(), machineID, user.ID)
+		newAPIKey, err := machineService.RotateMachineAPIKey(r.Context(), machineID, org.OrgID)
 		if err != nil {
-			log.Printf("Failed to rotate API key for machine %d, user %d: %v", machineID, user.ID, err)
+			log.Printf("Failed to rotate API key for machine %d, organization %d: %v", machineID, org.OrgID, err)
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to rotate API key"})
 			return
 		}
 
-		log.Printf("API key rotated for machine: id=%d, user_id=%d", machineID, user.ID)
+		log.Printf("API key rotated for machine: id=%d, org_id=%d", machineID, org.OrgID)
 
 		// Return the new API key (only time it's visible)
 		w.Header().Set("Content-Type", "application/json")
@@ -58,12 +58,12 @@ func createTestUserWithSession(t *testing.T, authService *auth.Service) (int, st
 	}
 
 	// Create session token
-	token, err := authService.CreateSession(user.ID)
+	session, err := authService.CreateSession(ctx, user.ID, auth.ClientInfo{})
 	if err != nil {
 		t.Fatalf("Failed to create session token: %v", err)
 	}
 
-	return user.ID, token
+	return user.ID, session.AccessToken
 }
 
 func TestAgentRegister(t *testing.T) {
@@ -120,8 +120,12 @@ func TestAgentRegister(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to get machine from database: %v", err)
 		}
-		if machine.UserID != userID {
-			t.Errorf("Expected machine user_id %d, got %d", userID, machine.UserID)
+		org, err := store.GetPersonalOrganization(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("Failed to get personal organization: %v", err)
+		}
+		if machine.OrgID != org.ID {
+			t.Errorf("Expected machine org_id %d, got %d", org.ID, machine.OrgID)
 		}
 	})
 
@@ -220,7 +224,7 @@ func TestAgentMetrics(t *testing.T) {
 		httpReq.Header.Set("X-API-Key", apiKey)
 
 		w := httptest.NewRecorder()
-		handler := RequireAPIKey(machineService)(http.HandlerFunc(handleAgentMetrics(machineService)))
+		handler := RequireAPIKey(machineService, nil)(http.HandlerFunc(handleAgentMetrics(machineService)))
 		handler.ServeHTTP(w, httpReq)
 
 		if w.Code != http.StatusAccepted {
@@ -291,7 +295,7 @@ func TestAgentMetrics(t *testing.T) {
 		httpReq.Header.Set("X-API-Key", apiKey)
 
 		w := httptest.NewRecorder()
-		handler := RequireAPIKey(machineService)(http.HandlerFunc(handleAgentMetrics(machineService)))
+		handler := RequireAPIKey(machineService, nil)(http.HandlerFunc(handleAgentMetrics(machineService)))
 		handler.ServeHTTP(w, httpReq)
 
 		if w.Code != http.StatusBadRequest {
@@ -312,7 +316,7 @@ func TestAgentMetrics(t *testing.T) {
 		httpReq.Header.Set("X-API-Key", "invalid-key")
 
 		w := httptest.NewRecorder()
-		handler := RequireAPIKey(machineService)(http.HandlerFunc(handleAgentMetrics(machineService)))
+		handler := RequireAPIKey(machineService, nil)(http.HandlerFunc(handleAgentMetrics(machineService)))
 		handler.ServeHTTP(w, httpReq)
 
 		if w.Code != http.StatusUnauthorized {
@@ -332,7 +336,7 @@ func TestAgentMetrics(t *testing.T) {
 		httpReq.Header.Set("Content-Type", "application/json")
 
 		w := httptest.NewRecorder()
-		handler := RequireAPIKey(machineService)(http.HandlerFunc(handleAgentMetrics(machineService)))
+		handler := RequireAPIKey(machineService, nil)(http.HandlerFunc(handleAgentMetrics(machineService)))
 		handler.ServeHTTP(w, httpReq)
 
 		if w.Code != http.StatusUnauthorized {
@@ -374,18 +378,18 @@ func TestAPIKeyMiddleware(t *testing.T) {
 				t.Errorf("Expected machine ID %d, got %d", machine.ID, machineID)
 			}
 
-			userID, ok := GetUserIDFromContext(r.Context())
+			orgID, ok := GetOrgIDFromContext(r.Context())
 			if !ok {
-				t.Error("Expected user ID in context")
+				t.Error("Expected organization ID in context")
 			}
-			if userID != user.ID {
-				t.Errorf("Expected user ID %d, got %d", user.ID, userID)
+			if orgID != machine.OrgID {
+				t.Errorf("Expected organization ID %d, got %d", machine.OrgID, orgID)
 			}
 
 			w.WriteHeader(http.StatusOK)
 		})
 
-		handler := RequireAPIKey(machineService)(testHandler)
+		handler := RequireAPIKey(machineService, nil)(testHandler)
 		handler.ServeHTTP(w, httpReq)
 
 		if w.Code != http.StatusOK {
@@ -403,7 +407,7 @@ func TestAPIKeyMiddleware(t *testing.T) {
 			w.WriteHeader(http.StatusOK)
 		})
 
-		handler := RequireAPIKey(machineService)(testHandler)
+		handler := RequireAPIKey(machineService, nil)(testHandler)
 		handler.ServeHTTP(w, httpReq)
 
 		if w.Code != http.StatusOK {
@@ -473,8 +477,8 @@ func TestListMachines(t *testing.T) {
 		foundMachine1 := false
 		foundMachine2 := false
 		for _, m := range machinesList {
-			if m.UserID != userID {
-				t.Errorf("Machine %d belongs to user %d, expected %d", m.ID, m.UserID, userID)
+			if m.OrgID != machine1.OrgID {
+				t.Errorf("Machine %d belongs to organization %d, expected %d", m.ID, m.OrgID, machine1.OrgID)
 			}
 			if m.ID == machine1.ID {
 				foundMachine1 = true
@@ -1,25 +1,60 @@
 package router
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/alerts"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
-// AlertRuleRequest represents an alert rule request/response
+// AlertRuleRequest represents an alert rule request/response. On PUT, omitted fields keep the rule's
+// current value.
 type AlertRuleRequest struct {
 	Name         string  `json:"name"`
 	Metric       string  `json:"metric"`
 	ThresholdPct float64 `json:"threshold_pct"`
 	Comparison   string  `json:"comparison"`
 	TriggerAfter int     `json:"trigger_after"`
+	Severity     string  `json:"severity"` // "info", "warning" (default) or "critical"
+
+	EscalationPolicyID *int `json:"escalation_policy_id"` // one of the caller's escalation policies, null for none
+
+	ClearThresholdPct *float64 `json:"clear_threshold_pct"` // null clears at threshold_pct
+	ClearAfter        int      `json:"clear_after"`         // consecutive clear samples before resolving, defaults to 1
+	ForSeconds        int      `json:"for_seconds"`         // minimum breach duration before firing
+	ClearForSeconds   int      `json:"clear_for_seconds"`   // minimum clear duration before resolving
+
+	Type           string `json:"type"`            // "threshold" (default), "rate", "forecast", "anomaly" or "expression"
+	WindowSeconds  int    `json:"window_seconds"`  // history used by rate and forecast rules, defaults to 1 hour
+	HorizonSeconds int    `json:"horizon_seconds"` // how far ahead forecast rules look, defaults to 24 hours
+	Seasonality    string `json:"seasonality"`     // baseline of anomaly rules: "none", "daily" (default) or "weekly"
+	Expression     string `json:"expression"`      // condition of expression rules, e.g. "cpu_pct > 90 && mem_used_pct > 80"
 }
 
+// AlertRuleBacktestRequest is a rule definition to replay over a machine's metrics history
+type AlertRuleBacktestRequest struct {
+	AlertRuleRequest
+	MachineID int       `json:"machine_id"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+}
+
+// maxRuleDurationSeconds caps for_seconds and clear_for_seconds
+const maxRuleDurationSeconds = 24 * 60 * 60
+
 // AlertEventAckRequest represents an alert event acknowledgment request
 type AlertEventAckRequest struct {
 	// No body needed, ID comes from URL
@@ -30,11 +65,34 @@ func validateAlertRule(req *AlertRuleRequest) error {
 	if req.Name == "" {
 		return fmt.Errorf("name is required")
 	}
-	if req.Metric != "cpu_pct" && req.Metric != "mem_used_pct" && req.Metric != "disk_used_pct" {
+	if req.Type != "" && !storage.IsValidRuleType(req.Type) {
+		return fmt.Errorf("type must be one of: threshold, rate, forecast, anomaly, expression")
+	}
+	if req.Type == storage.RuleTypeExpression {
+		if err := validateRuleExpression(req); err != nil {
+			return err
+		}
+	} else if req.Expression != "" {
+		return fmt.Errorf("expression only applies to expression rules")
+	}
+	if !isRuleMetric(req.Metric) {
 		return fmt.Errorf("metric must be one of: cpu_pct, mem_used_pct, disk_used_pct")
 	}
-	if req.ThresholdPct < 0 || req.ThresholdPct > 100 {
-		return fmt.Errorf("threshold_pct must be between 0 and 100")
+
+	// Rate rules compare percentage points per hour, which may be negative, and anomaly rules
+	// compare standard deviations from the baseline
+	minThreshold, maxThreshold := 0.0, 100.0
+	switch req.Type {
+	case storage.RuleTypeRate:
+		minThreshold = -100
+	case storage.RuleTypeAnomaly:
+		maxThreshold = alerts.MaxAnomalyDeviation
+		if req.ThresholdPct <= 0 {
+			return fmt.Errorf("threshold_pct must be above 0 for anomaly rules")
+		}
+	}
+	if req.ThresholdPct < minThreshold || req.ThresholdPct > maxThreshold {
+		return fmt.Errorf("threshold_pct must be between %.0f and %.0f", minThreshold, maxThreshold)
 	}
 	if req.Comparison != "above" && req.Comparison != "below" {
 		return fmt.Errorf("comparison must be 'above' or 'below'")
@@ -42,17 +100,139 @@ func validateAlertRule(req *AlertRuleRequest) error {
 	if req.TriggerAfter < 1 {
 		return fmt.Errorf("trigger_after must be >= 1")
 	}
+	if req.Severity != "" && !storage.IsValidSeverity(req.Severity) {
+		return fmt.Errorf("severity must be one of: info, warning, critical")
+	}
+	if clearPct := req.ClearThresholdPct; clearPct != nil {
+		if req.Type == storage.RuleTypeForecast {
+			return fmt.Errorf("clear_threshold_pct does not apply to forecast rules")
+		}
+		if *clearPct < minThreshold || *clearPct > maxThreshold {
+			return fmt.Errorf("clear_threshold_pct must be between %.0f and %.0f", minThreshold, maxThreshold)
+		}
+		// Anomaly rules measure both thresholds as distances from the baseline
+		if req.Type == storage.RuleTypeAnomaly && *clearPct > req.ThresholdPct {
+			return fmt.Errorf("clear_threshold_pct must not be above threshold_pct for anomaly rules")
+		}
+		if req.Type != storage.RuleTypeAnomaly && req.Comparison == "above" && *clearPct > req.ThresholdPct {
+			return fmt.Errorf("clear_threshold_pct must not be above threshold_pct for 'above' rules")
+		}
+		if req.Type != storage.RuleTypeAnomaly && req.Comparison == "below" && *clearPct < req.ThresholdPct {
+			return fmt.Errorf("clear_threshold_pct must not be below threshold_pct for 'below' rules")
+		}
+	}
+	if req.ClearAfter < 0 {
+		return fmt.Errorf("clear_after must be >= 1")
+	}
+	if req.ForSeconds < 0 || req.ForSeconds > maxRuleDurationSeconds {
+		return fmt.Errorf("for_seconds must be between 0 and %d", maxRuleDurationSeconds)
+	}
+	if req.ClearForSeconds < 0 || req.ClearForSeconds > maxRuleDurationSeconds {
+		return fmt.Errorf("clear_for_seconds must be between 0 and %d", maxRuleDurationSeconds)
+	}
+
+	trend := req.Type == storage.RuleTypeRate || req.Type == storage.RuleTypeForecast
+	if req.WindowSeconds != 0 {
+		if !trend {
+			return fmt.Errorf("window_seconds only applies to rate and forecast rules")
+		}
+		window := time.Duration(req.WindowSeconds) * time.Second
+		if window < alerts.MinTrendWindow || window > alerts.MaxTrendWindow {
+			return fmt.Errorf("window_seconds must be between %d and %d",
+				int(alerts.MinTrendWindow/time.Second), int(alerts.MaxTrendWindow/time.Second))
+		}
+	}
+	if req.HorizonSeconds != 0 {
+		if req.Type != storage.RuleTypeForecast {
+			return fmt.Errorf("horizon_seconds only applies to forecast rules")
+		}
+		horizon := time.Duration(req.HorizonSeconds) * time.Second
+		if horizon < time.Minute || horizon > alerts.MaxForecastHorizon {
+			return fmt.Errorf("horizon_seconds must be between 60 and %d", int(alerts.MaxForecastHorizon/time.Second))
+		}
+	}
+	if req.Seasonality != "" {
+		if req.Type != storage.RuleTypeAnomaly {
+			return fmt.Errorf("seasonality only applies to anomaly rules")
+		}
+		if !storage.IsValidSeasonality(req.Seasonality) {
+			return fmt.Errorf("seasonality must be one of: none, daily, weekly")
+		}
+	}
 	return nil
 }
 
+// validateRuleExpression parses the condition of an expression rule and fills in the fields it
+// makes optional: the metric whose value events record defaults to the first one the expression
+// uses, and the comparison, which expression rules ignore, to "above"
+func validateRuleExpression(req *AlertRuleRequest) error {
+	expr, err := alerts.ParseExpression(req.Expression)
+	if err != nil {
+		return fmt.Errorf("invalid expression: %v", err)
+	}
+	if req.ThresholdPct != 0 || req.ClearThresholdPct != nil {
+		return fmt.Errorf("threshold_pct and clear_threshold_pct do not apply to expression rules")
+	}
+
+	if req.Metric == "" {
+		for _, name := range expr.Variables() {
+			if isRuleMetric(name) {
+				req.Metric = name
+				break
+			}
+		}
+		if req.Metric == "" {
+			return fmt.Errorf("expression must use cpu_pct, mem_used_pct or disk_used_pct")
+		}
+	} else if !slices.Contains(expr.Variables(), req.Metric) {
+		return fmt.Errorf("metric must be one the expression uses")
+	}
+
+	if req.Comparison == "" {
+		req.Comparison = "above"
+	}
+	req.Expression = expr.String()
+	return nil
+}
+
+// isRuleMetric reports whether name is a metric alert rules can watch
+func isRuleMetric(name string) bool {
+	return name == "cpu_pct" || name == "mem_used_pct" || name == "disk_used_pct"
+}
+
+// checkRuleEscalationPolicy verifies that the escalation policy requested for a rule belongs to the rule's organization
+func checkRuleEscalationPolicy(ctx context.Context, alertService *alerts.Service, orgID int, policyID *int) error {
+	if policyID == nil {
+		return nil
+	}
+	if err := alertService.CheckEscalationPolicy(ctx, *policyID, orgID); err != nil {
+		return fmt.Errorf("escalation policy %d not found", *policyID)
+	}
+	return nil
+}
+
+// sameEscalationPolicy reports whether two optional escalation policy IDs are the same
+func sameEscalationPolicy(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 // handleAlertRules handles GET /alerts/rules and POST /alerts/rules
 func handleAlertRules(alertService *alerts.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
 		switch r.Method {
 		case "GET":
-			rules, err := alertService.ListRules(r.Context())
+			rules, err := alertService.ListRules(r.Context(), org.OrgID)
 			if err != nil {
 				log.Printf("Failed to list alert rules: %v", err)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -75,8 +255,14 @@ func handleAlertRules(alertService *alerts.Service) http.HandlerFunc {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			if err := checkRuleEscalationPolicy(r.Context(), alertService, org.OrgID, req.EscalationPolicyID); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 
-			rule, err := alertService.UpsertRule(r.Context(), 0, req.Name, req.Metric, req.Comparison, req.ThresholdPct, req.TriggerAfter)
+			rule := ruleFromRequest(&req)
+			rule.OrgID = org.OrgID
+			saved, err := alertService.SaveRule(r.Context(), rule)
 			if err != nil {
 				log.Printf("Failed to create alert rule: %v", err)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -84,7 +270,7 @@ func handleAlertRules(alertService *alerts.Service) http.HandlerFunc {
 			}
 
 			w.WriteHeader(http.StatusCreated)
-			if err := json.NewEncoder(w).Encode(rule); err != nil {
+			if err := json.NewEncoder(w).Encode(saved); err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 				return
 			}
@@ -95,6 +281,140 @@ func handleAlertRules(alertService *alerts.Service) http.HandlerFunc {
 	}
 }
 
+// handleAlertRuleBacktest handles POST /alerts/rules/backtest
+func handleAlertRuleBacktest(alertService *alerts.Service, machineService *machines.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req AlertRuleBacktestRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		// The rule is not saved, so it does not need a name
+		if req.Name == "" {
+			req.Name = "Backtest"
+		}
+		if err := validateAlertRule(&req.AlertRuleRequest); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.From.IsZero() || req.To.IsZero() || !req.To.After(req.From) {
+			http.Error(w, "from and to are required and to must be after from", http.StatusBadRequest)
+			return
+		}
+		if req.To.Sub(req.From) > alerts.MaxBacktestRange {
+			http.Error(w, fmt.Sprintf("time range must not be longer than %d days", int(alerts.MaxBacktestRange.Hours()/24)), http.StatusBadRequest)
+			return
+		}
+
+		machine, err := machineService.GetMachine(r.Context(), req.MachineID, org.OrgID)
+		if err != nil {
+			http.Error(w, "Machine not found", http.StatusNotFound)
+			return
+		}
+
+		rule := ruleFromRequest(&req.AlertRuleRequest)
+		rule.OrgID = org.OrgID
+		result, err := alertService.Backtest(r.Context(), rule, machine, req.From, req.To)
+		if err != nil {
+			if errors.Is(err, alerts.ErrBacktestTooManySamples) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				log.Printf("Failed to backtest alert rule: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// ruleFromRequest builds an unsaved alert rule from a validated request
+func ruleFromRequest(req *AlertRuleRequest) storage.AlertRule {
+	return storage.AlertRule{
+		Name:               req.Name,
+		Metric:             req.Metric,
+		ThresholdPct:       req.ThresholdPct,
+		Comparison:         req.Comparison,
+		TriggerAfter:       req.TriggerAfter,
+		Severity:           req.Severity,
+		EscalationPolicyID: req.EscalationPolicyID,
+		ClearThresholdPct:  req.ClearThresholdPct,
+		ClearAfter:         req.ClearAfter,
+		ForSeconds:         req.ForSeconds,
+		ClearForSeconds:    req.ClearForSeconds,
+		Type:               req.Type,
+		WindowSeconds:      req.WindowSeconds,
+		HorizonSeconds:     req.HorizonSeconds,
+		Seasonality:        req.Seasonality,
+		Expression:         req.Expression,
+	}
+}
+
+// requestFromRule returns the request that would recreate a saved rule
+func requestFromRule(rule *storage.AlertRule) AlertRuleRequest {
+	return AlertRuleRequest{
+		Name:               rule.Name,
+		Metric:             rule.Metric,
+		ThresholdPct:       rule.ThresholdPct,
+		Comparison:         rule.Comparison,
+		TriggerAfter:       rule.TriggerAfter,
+		Severity:           rule.Severity,
+		EscalationPolicyID: rule.EscalationPolicyID,
+		ClearThresholdPct:  rule.ClearThresholdPct,
+		ClearAfter:         rule.ClearAfter,
+		ForSeconds:         rule.ForSeconds,
+		ClearForSeconds:    rule.ClearForSeconds,
+		Type:               rule.Type,
+		WindowSeconds:      rule.WindowSeconds,
+		HorizonSeconds:     rule.HorizonSeconds,
+		Seasonality:        rule.Seasonality,
+		Expression:         rule.Expression,
+	}
+}
+
+// mergeAlertRuleRequest applies a PUT body to a saved rule. Fields the body omits keep their current
+// value, except that changing the type drops the settings that only applied to the old type.
+func mergeAlertRuleRequest(rule *storage.AlertRule, body []byte) (AlertRuleRequest, error) {
+	req := requestFromRule(rule)
+
+	var change struct {
+		Type *string `json:"type"`
+	}
+	if err := json.Unmarshal(body, &change); err != nil {
+		return req, err
+	}
+	if change.Type != nil && *change.Type != rule.Type {
+		req.WindowSeconds, req.HorizonSeconds, req.Seasonality, req.Expression = 0, 0, "", ""
+		switch *change.Type {
+		case storage.RuleTypeExpression:
+			req.ThresholdPct, req.ClearThresholdPct = 0, nil
+		case storage.RuleTypeForecast:
+			req.ClearThresholdPct = nil
+		}
+	}
+
+	err := json.Unmarshal(body, &req)
+	return req, err
+}
+
 // handleAlertRule handles PUT /alerts/rules/{id} and DELETE /alerts/rules/{id}
 func handleAlertRule(alertService *alerts.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -113,10 +433,32 @@ func handleAlertRule(alertService *alerts.Service) http.HandlerFunc {
 			return
 		}
 
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
 		switch r.Method {
 		case "PUT":
-			var req AlertRuleRequest
-			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			existing, err := alertService.GetRule(r.Context(), id, org.OrgID)
+			if err != nil {
+				if strings.Contains(err.Error(), "not found") {
+					http.Error(w, "Alert rule not found", http.StatusNotFound)
+				} else {
+					log.Printf("Failed to get alert rule: %v", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+			req, err := mergeAlertRuleRequest(existing, body)
+			if err != nil {
 				http.Error(w, "Invalid JSON", http.StatusBadRequest)
 				return
 			}
@@ -125,8 +467,18 @@ func handleAlertRule(alertService *alerts.Service) http.HandlerFunc {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 				return
 			}
+			// A policy the rule already has was checked when it was attached
+			if !sameEscalationPolicy(existing.EscalationPolicyID, req.EscalationPolicyID) {
+				if err := checkRuleEscalationPolicy(r.Context(), alertService, org.OrgID, req.EscalationPolicyID); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
 
-			rule, err := alertService.UpsertRule(r.Context(), id, req.Name, req.Metric, req.Comparison, req.ThresholdPct, req.TriggerAfter)
+			update := ruleFromRequest(&req)
+			update.ID = id
+			update.OrgID = org.OrgID
+			rule, err := alertService.SaveRule(r.Context(), update)
 			if err != nil {
 				if strings.Contains(err.Error(), "not found") {
 					http.Error(w, "Alert rule not found", http.StatusNotFound)
@@ -143,7 +495,7 @@ func handleAlertRule(alertService *alerts.Service) http.HandlerFunc {
 			}
 
 		case "DELETE":
-			if err := alertService.DeleteRule(r.Context(), id); err != nil {
+			if err := alertService.DeleteRule(r.Context(), id, org.OrgID); err != nil {
 				if strings.Contains(err.Error(), "not found") {
 					http.Error(w, "Alert rule not found", http.StatusNotFound)
 				} else {
@@ -161,7 +513,7 @@ func handleAlertRule(alertService *alerts.Service) http.HandlerFunc {
 	}
 }
 
-// handleAlertEvents handles GET /alerts/events
+// handleAlertEvents handles GET /alerts/events - filtered events, one page at a time
 func handleAlertEvents(alertService *alerts.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" {
@@ -171,20 +523,34 @@ func handleAlertEvents(alertService *alerts.Service) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 
-		// Default limit to 50, can be overridden by query param
-		limit := 50
-		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-			if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
-				limit = parsedLimit
-			}
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
 		}
 
-		events, err := alertService.ListActiveEvents(r.Context(), limit)
+		filter, err := parseAlertEventFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Only events on the organization's machines and on the local host
+		filter.OrgID = org.OrgID
+
+		events, next, err := alertService.ListEvents(r.Context(), filter)
 		if err != nil {
 			log.Printf("Failed to list alert events: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
+		if events == nil {
+			events = []storage.AlertEvent{}
+		}
+
+		// The body stays a plain list; the next page is announced in a header
+		if next != nil {
+			w.Header().Set("X-Next-Cursor", encodeAlertEventCursor(next))
+		}
 
 		if err := json.NewEncoder(w).Encode(events); err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -193,6 +559,159 @@ func handleAlertEvents(alertService *alerts.Service) http.HandlerFunc {
 	}
 }
 
+// maxAlertEventsLimit caps the page size of GET /alerts/events
+const maxAlertEventsLimit = 500
+
+// parseAlertEventFilter reads the filters and page of GET /alerts/events from the query string
+func parseAlertEventFilter(r *http.Request) (storage.AlertEventFilter, error) {
+	q := r.URL.Query()
+
+	// Default limit to 50, can be overridden by query param
+	filter := storage.AlertEventFilter{Limit: 50}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 {
+			filter.Limit = min(parsedLimit, maxAlertEventsLimit)
+		}
+	}
+
+	for name, dest := range map[string]**int{"rule_id": &filter.RuleID, "machine_id": &filter.MachineID} {
+		if v := q.Get(name); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil || id <= 0 {
+				return filter, fmt.Errorf("invalid %s", name)
+			}
+			*dest = &id
+		}
+	}
+
+	switch q.Get("state") {
+	case "":
+	case "open", "resolved":
+		resolved := q.Get("state") == "resolved"
+		filter.Resolved = &resolved
+	default:
+		return filter, fmt.Errorf("state must be 'open' or 'resolved'")
+	}
+
+	if v := q.Get("acknowledged"); v != "" {
+		acknowledged, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("acknowledged must be true or false")
+		}
+		filter.Acknowledged = &acknowledged
+	}
+
+	if filter.Severity = q.Get("severity"); filter.Severity != "" && !storage.IsValidSeverity(filter.Severity) {
+		return filter, fmt.Errorf("severity must be 'info', 'warning' or 'critical'")
+	}
+
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*dest = &t
+		}
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := decodeAlertEventCursor(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid cursor")
+		}
+		filter.After = cursor
+	}
+
+	return filter, nil
+}
+
+// encodeAlertEventCursor turns an event cursor into the opaque string clients pass back
+func encodeAlertEventCursor(c *storage.AlertEventCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.TriggeredAt.UnixNano(), c.ID)))
+}
+
+// decodeAlertEventCursor parses a cursor made by encodeAlertEventCursor
+func decodeAlertEventCursor(s string) (*storage.AlertEventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	triggeredAt, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	nanos, err := strconv.ParseInt(triggeredAt, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	eventID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, err
+	}
+	// Event times are stored in the server's time zone, so the cursor compares in it too
+	return &storage.AlertEventCursor{TriggeredAt: time.Unix(0, nanos), ID: eventID}, nil
+}
+
+// handleAlertStats handles GET /alerts/stats - event counts, MTTA and MTTR over a period,
+// the last 7 days by default
+func handleAlertStats(alertService *alerts.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		q := r.URL.Query()
+		to := time.Now().UTC()
+		if v := q.Get("to"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "to must be an RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			to = t
+		}
+		from := to.Add(-7 * 24 * time.Hour)
+		if v := q.Get("from"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "from must be an RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			from = t
+		}
+		if !to.After(from) {
+			http.Error(w, "to must be after from", http.StatusBadRequest)
+			return
+		}
+		if to.Sub(from) > alerts.MaxEventStatsRange {
+			http.Error(w, fmt.Sprintf("time range must not be longer than %d days", int(alerts.MaxEventStatsRange.Hours()/24)), http.StatusBadRequest)
+			return
+		}
+
+		report, err := alertService.EventStats(r.Context(), org.OrgID, from, to)
+		if err != nil {
+			log.Printf("Failed to compute alert event stats: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // handleAlertEventAck handles POST /alerts/events/{id}/ack
 func handleAlertEventAck(alertService *alerts.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -220,7 +739,13 @@ func handleAlertEventAck(alertService *alerts.Service) http.HandlerFunc {
 			return
 		}
 
-		if err := alertService.AcknowledgeEvent(r.Context(), id); err != nil {
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := alertService.AcknowledgeEvent(r.Context(), org.OrgID, id); err != nil {
 			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "already acknowledged") {
 				http.Error(w, "Alert event not found or already acknowledged", http.StatusNotFound)
 			} else {
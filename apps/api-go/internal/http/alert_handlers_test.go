@@ -0,0 +1,326 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/alerts"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestValidateAlertRule_Expression(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     AlertRuleRequest
+		wantErr string
+	}{
+		{
+			name: "metric defaults to the first one used",
+			req:  AlertRuleRequest{Expression: "uptime_s > 60 && mem_used_pct > 95 || cpu_pct > 90"},
+		},
+		{
+			name:    "invalid expression",
+			req:     AlertRuleRequest{Expression: "cpu_pct >"},
+			wantErr: "invalid expression",
+		},
+		{
+			name:    "metric not in expression",
+			req:     AlertRuleRequest{Metric: "disk_used_pct", Expression: "cpu_pct > 90"},
+			wantErr: "metric must be one the expression uses",
+		},
+		{
+			name:    "no rule metric",
+			req:     AlertRuleRequest{Expression: "uptime_s < 300"},
+			wantErr: "expression must use",
+		},
+		{
+			name:    "threshold",
+			req:     AlertRuleRequest{Expression: "cpu_pct > 90", ThresholdPct: 90},
+			wantErr: "do not apply to expression rules",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.Name = "Test"
+			req.Type = storage.RuleTypeExpression
+			req.TriggerAfter = 1
+
+			err := validateAlertRule(&req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if req.Metric != "mem_used_pct" || req.Comparison != "above" {
+				t.Errorf("Expected metric mem_used_pct and comparison above, got %q and %q", req.Metric, req.Comparison)
+			}
+		})
+	}
+
+	// Threshold rules cannot carry an expression
+	req := AlertRuleRequest{Name: "Test", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 1, Expression: "cpu_pct > 90"}
+	if err := validateAlertRule(&req); err == nil {
+		t.Error("Expected an expression on a threshold rule to be rejected")
+	}
+}
+
+func TestHandleAlertRules_CreateExpressionRule(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	handler := handleAlertRules(alerts.NewService(store, nil))
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	request := func(method string, orgID int, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/alerts/rules", bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.OrgContextKey,
+			&storage.OrgMember{OrgID: orgID, UserID: orgID, Role: storage.RoleOwner}))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"name":          "Overloaded",
+		"type":          "expression",
+		"expression":    "cpu_pct > 90 && mem_used_pct > 80",
+		"trigger_after": 3,
+	})
+	w := request(http.MethodPost, user.ID, body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var rule storage.AlertRule
+	json.NewDecoder(w.Body).Decode(&rule)
+	if rule.Type != storage.RuleTypeExpression || rule.Expression != "cpu_pct > 90 && mem_used_pct > 80" || rule.Metric != "cpu_pct" {
+		t.Errorf("Unexpected rule: %+v", rule)
+	}
+	if rule.OrgID != user.ID {
+		t.Errorf("Expected the rule to belong to organization %d, got %d", user.ID, rule.OrgID)
+	}
+
+	// Only the organization lists its rules
+	for orgID, want := range map[int]int{user.ID: 1, other.ID: 0} {
+		var rules []storage.AlertRule
+		json.NewDecoder(request(http.MethodGet, orgID, nil).Body).Decode(&rules)
+		if len(rules) != want {
+			t.Errorf("Expected organization %d to list %d rules, got %d", orgID, want, len(rules))
+		}
+	}
+}
+
+func TestHandleAlertRule_PartialUpdate(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	service := alerts.NewService(store, nil)
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	put := func(orgID, ruleID int, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/alerts/rules/"+strconv.Itoa(ruleID), bytes.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), auth.OrgContextKey,
+			&storage.OrgMember{OrgID: orgID, UserID: orgID, Role: storage.RoleOwner}))
+		w := httptest.NewRecorder()
+		handleAlertRule(service)(w, req)
+		return w
+	}
+
+	clearPct := 3.0
+	rule, err := service.SaveRule(ctx, storage.AlertRule{
+		OrgID: user.ID, Name: "Disk trend", Metric: "disk_used_pct", Comparison: "above", ThresholdPct: 5, TriggerAfter: 2,
+		Severity: storage.SeverityCritical, ClearThresholdPct: &clearPct, ClearAfter: 3, ForSeconds: 600,
+		Type: storage.RuleTypeRate, WindowSeconds: 7200,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	// The dashboard only sends the basic fields; everything else must survive the edit
+	body, _ := json.Marshal(map[string]any{"name": "Disk growth", "threshold_pct": 8})
+	w := put(user.ID, rule.ID, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var updated storage.AlertRule
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Name != "Disk growth" || updated.ThresholdPct != 8 {
+		t.Errorf("Expected the sent fields to change, got %+v", updated)
+	}
+	if updated.Type != storage.RuleTypeRate || updated.WindowSeconds != 7200 || updated.Severity != storage.SeverityCritical ||
+		updated.ClearThresholdPct == nil || *updated.ClearThresholdPct != 3 || updated.ClearAfter != 3 || updated.ForSeconds != 600 {
+		t.Errorf("Expected omitted fields to keep their values, got %+v", updated)
+	}
+
+	entries, _ := store.ListAuditEntries(ctx, storage.AuditFilter{Action: "alert_rule.update"})
+	if len(entries) != 1 {
+		t.Errorf("Expected one audit entry for the edit, got %d", len(entries))
+	}
+
+	// Changing the type drops the settings of the old type, and null clears a nullable field
+	body, _ = json.Marshal(map[string]any{"type": "threshold", "threshold_pct": 90, "clear_threshold_pct": nil})
+	w = put(user.ID, rule.ID, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Type != storage.RuleTypeThreshold || updated.WindowSeconds != 0 || updated.ClearThresholdPct != nil {
+		t.Errorf("Expected a threshold rule without window or clear threshold, got %+v", updated)
+	}
+
+	if w := put(user.ID, 9999, body); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing rule, got %d", w.Code)
+	}
+	if w := put(other.ID, rule.ID, body); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another organization's rule, got %d", w.Code)
+	}
+}
+
+func TestHandleAlertRuleBacktest(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	handler := handleAlertRuleBacktest(alerts.NewService(store, nil), machines.NewService(store))
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, owner.ID, "web-1", "web-1", "", "hash")
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, cpu := range []float64{20, 95, 95, 95, 20} {
+		store.InsertMetrics(ctx, machine.ID, cpu, 40, 50, 0, 0, nil, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	backtest := func(user *storage.User, body map[string]any) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/alerts/rules/backtest", bytes.NewReader(payload))
+		req = req.WithContext(context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+			&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner}))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+	body := map[string]any{
+		"metric":        "cpu_pct",
+		"comparison":    "above",
+		"threshold_pct": 90,
+		"trigger_after": 2,
+		"machine_id":    machine.ID,
+		"from":          start,
+		"to":            start.Add(time.Hour),
+	}
+
+	w := backtest(owner, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result alerts.BacktestResult
+	json.NewDecoder(w.Body).Decode(&result)
+	if result.Samples != 5 || len(result.Events) != 1 || result.TimeInAlertSeconds != 120 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	if w := backtest(other, body); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user's machine, got %d", w.Code)
+	}
+
+	body["to"] = start.Add(-time.Hour)
+	if w := backtest(owner, body); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty range, got %d", w.Code)
+	}
+}
+
+func TestHandleAlertEvents_FiltersAndPages(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	handler := handleAlertEvents(alerts.NewService(store, nil))
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	foreign, _ := store.CreateMachine(ctx, other.ID, "web-1", "web-1", "", "hash")
+	rule, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1})
+	foreignRule, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: other.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1})
+	for i := 0; i < 3; i++ {
+		store.CreateAlertEvent(ctx, rule.ID, nil, 90)
+	}
+	// Events of another organization's rules are never listed, on its machines or on the local host
+	store.CreateAlertEvent(ctx, foreignRule.ID, &foreign.ID, 90)
+	store.CreateAlertEvent(ctx, foreignRule.ID, nil, 90)
+
+	list := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/alerts/events?"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), auth.OrgContextKey,
+			&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner}))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// Acknowledging an event between pages neither repeats nor skips events
+	seen := map[int]bool{}
+	query := "limit=2&state=open"
+	for {
+		w := list(query)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var events []storage.AlertEvent
+		json.NewDecoder(w.Body).Decode(&events)
+		for _, event := range events {
+			if seen[event.ID] {
+				t.Errorf("Event %d listed twice", event.ID)
+			}
+			seen[event.ID] = true
+			if err := store.AckAlertEvent(ctx, event.ID); err != nil {
+				t.Fatalf("Failed to acknowledge event: %v", err)
+			}
+		}
+
+		cursor := w.Header().Get("X-Next-Cursor")
+		if cursor == "" {
+			break
+		}
+		query = "limit=2&state=open&cursor=" + cursor
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected 3 events over all pages, got %d", len(seen))
+	}
+
+	for _, query := range []string{"state=closed", "acknowledged=maybe", "severity=high", "from=yesterday", "rule_id=x", "cursor=bm9wZQ"} {
+		if w := list(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// CreateAPITokenRequest represents an API token create request
+type CreateAPITokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // omit for a token that does not expire
+}
+
+// CreateAPITokenResponse represents a created API token, the only time the token is returned
+type CreateAPITokenResponse struct {
+	storage.APIToken
+	Token string `json:"token"`
+}
+
+// handleAPITokens handles GET /auth/tokens and POST /auth/tokens
+func handleAPITokens(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			tokens, err := authService.ListAPITokens(r.Context(), user.ID)
+			if err != nil {
+				log.Printf("Failed to list API tokens for user %d: %v", user.ID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if err := json.NewEncoder(w).Encode(tokens); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		case "POST":
+			var req CreateAPITokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			created, token, err := authService.CreateAPIToken(r.Context(), user.ID, req.Name, req.Scopes, req.ExpiresAt)
+			if err != nil {
+				if strings.Contains(err.Error(), "failed to") {
+					log.Printf("Failed to create API token for user %d: %v", user.ID, err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				} else {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				}
+				return
+			}
+			log.Printf("User %d created API token %d (%s) with scopes %v", user.ID, created.ID, created.Name, created.Scopes)
+
+			w.WriteHeader(http.StatusCreated)
+			if err := json.NewEncoder(w).Encode(CreateAPITokenResponse{APIToken: *created, Token: token}); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleRevokeAPIToken handles DELETE /auth/tokens/{id}
+func handleRevokeAPIToken(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Extract ID from path
+		path := strings.TrimPrefix(r.URL.Path, "/auth/tokens/")
+		if path == "" {
+			http.Error(w, "Token ID required", http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.Atoi(path)
+		if err != nil {
+			http.Error(w, "Invalid token ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := authService.RevokeAPIToken(r.Context(), user.ID, id); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				http.Error(w, "API token not found", http.StatusNotFound)
+			} else {
+				log.Printf("Failed to revoke API token %d: %v", id, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+		log.Printf("User %d revoked API token %d", user.ID, id)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
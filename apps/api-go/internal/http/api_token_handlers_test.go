@@ -0,0 +1,104 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestAPITokenEndpoints(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	ctx := context.Background()
+	user, _, _ := authService.CreateUser(ctx, "user@example.com", "password123")
+	session, _ := authService.CreateSession(ctx, user.ID, auth.ClientInfo{})
+	sessionCookie := &http.Cookie{Name: auth.CookieName, Value: session.AccessToken}
+
+	tokens := authService.RequireAuth(handleAPITokens(authService))
+	revoke := authService.RequireAuth(handleRevokeAPIToken(authService))
+	me := authService.RequireAuth(handleMe())
+
+	// Invalid scopes are rejected
+	body, _ := json.Marshal(CreateAPITokenRequest{Name: "CI", Scopes: []string{"everything"}})
+	req := httptest.NewRequest(http.MethodPost, "/auth/tokens", bytes.NewReader(body))
+	req.AddCookie(sessionCookie)
+	w := httptest.NewRecorder()
+	tokens.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an unknown scope, got %d", w.Code)
+	}
+
+	body, _ = json.Marshal(CreateAPITokenRequest{Name: "CI", Scopes: []string{auth.ScopeRead}})
+	req = httptest.NewRequest(http.MethodPost, "/auth/tokens", bytes.NewReader(body))
+	req.AddCookie(sessionCookie)
+	w = httptest.NewRecorder()
+	tokens.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating a token, got %d: %s", w.Code, w.Body.String())
+	}
+	var created CreateAPITokenResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Token == "" || created.ID == 0 {
+		t.Fatalf("Expected the token in the response, got %+v", created)
+	}
+
+	// The token works as a bearer token, but cannot manage tokens
+	bearer := func(handler http.Handler, method, target string) int {
+		req := httptest.NewRequest(method, target, nil)
+		req.Header.Set("Authorization", "Bearer "+created.Token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := bearer(me, http.MethodGet, "/auth/me"); code != http.StatusOK {
+		t.Errorf("Expected the token to authenticate /auth/me, got %d", code)
+	}
+	if code := bearer(tokens, http.MethodGet, "/auth/tokens"); code != http.StatusForbidden {
+		t.Errorf("Expected the token not to list tokens, got %d", code)
+	}
+
+	// The listing never includes the token itself
+	req = httptest.NewRequest(http.MethodGet, "/auth/tokens", nil)
+	req.AddCookie(sessionCookie)
+	w = httptest.NewRecorder()
+	tokens.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 listing tokens, got %d", w.Code)
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte(created.Token)) || bytes.Contains(w.Body.Bytes(), []byte("token_hash")) {
+		t.Error("Expected the listing not to contain the token or its hash")
+	}
+	var list []storage.APIToken
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 1 || list[0].LastUsedAt == nil {
+		t.Errorf("Expected one token with its last use recorded, got %+v", list)
+	}
+
+	// Revoked tokens stop working at once
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/auth/tokens/%d", created.ID), nil)
+	req.AddCookie(sessionCookie)
+	w = httptest.NewRecorder()
+	revoke.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 revoking the token, got %d", w.Code)
+	}
+	if code := bearer(me, http.MethodGet, "/auth/me"); code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked token to be rejected, got %d", code)
+	}
+}
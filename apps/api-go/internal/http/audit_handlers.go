@@ -0,0 +1,127 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// Page sizes of GET /audit
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// handleAuditLog handles GET /audit (users:admin) - filtered audit entries, newest first, one page
+// at a time. The next page is announced in the X-Next-Cursor header.
+func handleAuditLog(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.Limit = defaultAuditLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err := strconv.Atoi(v); err == nil && limit > 0 {
+				filter.Limit = min(limit, maxAuditLimit)
+			}
+		}
+		if v := r.URL.Query().Get("cursor"); v != "" {
+			if filter.BeforeID, err = strconv.Atoi(v); err != nil || filter.BeforeID <= 0 {
+				http.Error(w, "invalid cursor", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Fetch one extra entry to learn whether there is a next page
+		filter.Limit++
+		entries, err := authService.AuditLog(r.Context(), filter)
+		if err != nil {
+			log.Printf("Failed to list audit entries: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if len(entries) == filter.Limit {
+			entries = entries[:len(entries)-1]
+			w.Header().Set("X-Next-Cursor", strconv.Itoa(entries[len(entries)-1].ID))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// handleAuditExport handles GET /audit/export (users:admin) - every audit entry matching the
+// filters of GET /audit, as a JSON file to download
+func handleAuditExport(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entries, err := authService.AuditLog(r.Context(), filter)
+		if err != nil {
+			log.Printf("Failed to export audit entries: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("audit-log-%s.json", time.Now().UTC().Format("20060102-150405"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(entries)
+	}
+}
+
+// parseAuditFilter reads the filters of GET /audit and GET /audit/export from the query string
+func parseAuditFilter(r *http.Request) (storage.AuditFilter, error) {
+	q := r.URL.Query()
+	filter := storage.AuditFilter{
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+	}
+
+	for name, dest := range map[string]**int{"org_id": &filter.OrgID, "actor_id": &filter.ActorID} {
+		if v := q.Get(name); v != "" {
+			id, err := strconv.Atoi(v)
+			if err != nil || id <= 0 {
+				return filter, fmt.Errorf("invalid %s", name)
+			}
+			*dest = &id
+		}
+	}
+
+	for name, dest := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*dest = &t
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestAuditLogHandlers(t *testing.T) {
+	store := createTestStoreForAgentTests(t)
+	authService := createTestAuthServiceForAgent(t, store)
+	adminID, adminToken := createTestUserWithSession(t, authService)
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		if _, _, err := authService.CreateUser(context.Background(), email, "password123"); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	request := func(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: adminToken})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	list := func(target string) ([]storage.AuditEntry, *httptest.ResponseRecorder) {
+		w := request(authService.RequireAuth(handleAuditLog(authService)), http.MethodGet, target)
+		var entries []storage.AuditEntry
+		json.NewDecoder(w.Body).Decode(&entries)
+		return entries, w
+	}
+
+	// Deleting a user through the API records who did it, from where, and what was deleted
+	users, err := authService.ListUsers(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list users: %v", err)
+	}
+	var bobID int
+	for _, u := range users {
+		if u.Email == "bob@example.com" {
+			bobID = u.ID
+		}
+	}
+	deleteUser := authService.RequireAuth(handleDeleteUser(authService))
+	if w := request(deleteUser, http.MethodDelete, "/auth/users/"+strconv.Itoa(bobID)); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 deleting a user, got %d: %s", w.Code, w.Body.String())
+	}
+
+	entries, w := list("/audit?action=user.delete")
+	if w.Code != http.StatusOK || len(entries) != 1 {
+		t.Fatalf("Expected one user.delete entry, got %d %+v", w.Code, entries)
+	}
+	entry := entries[0]
+	if entry.ActorType != storage.ActorUser || entry.ActorID == nil || *entry.ActorID != adminID ||
+		entry.ActorEmail != "test@example.com" || entry.IPAddress == "" {
+		t.Errorf("Unexpected actor in %+v", entry)
+	}
+	if entry.TargetType != "user" || entry.TargetID != strconv.Itoa(bobID) || !strings.Contains(string(entry.Changes), "bob@example.com") {
+		t.Errorf("Unexpected target in %+v", entry)
+	}
+	if strings.Contains(string(entry.Changes), "$2") {
+		t.Errorf("Expected the password hash to be redacted, got %s", entry.Changes)
+	}
+
+	// Pages are linked by the X-Next-Cursor header
+	entries, w = list("/audit?action=user.&limit=2")
+	cursor := w.Header().Get("X-Next-Cursor")
+	if len(entries) != 2 || cursor == "" {
+		t.Fatalf("Expected a full page with a cursor, got %d entries and cursor %q", len(entries), cursor)
+	}
+	next, w := list("/audit?action=user.&limit=2&cursor=" + cursor)
+	if len(next) != 2 || next[0].ID >= entries[1].ID || w.Header().Get("X-Next-Cursor") != "" {
+		t.Errorf("Expected the last page after the cursor, got %+v (cursor %q)", next, w.Header().Get("X-Next-Cursor"))
+	}
+
+	for _, target := range []string{"/audit?from=yesterday", "/audit?actor_id=x", "/audit?cursor=-1"} {
+		if _, w := list(target); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, w.Code)
+		}
+	}
+
+	// The export holds every matching entry
+	w = request(authService.RequireAuth(handleAuditExport(authService)), http.MethodGet, "/audit/export?target_type=user")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment;") {
+		t.Fatalf("Expected an attachment, got %d %v", w.Code, w.Header())
+	}
+	var exported []storage.AuditEntry
+	if err := json.NewDecoder(w.Body).Decode(&exported); err != nil || len(exported) != 4 {
+		t.Errorf("Expected 4 exported entries, got %d (%v)", len(exported), err)
+	}
+}
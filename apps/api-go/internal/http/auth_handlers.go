@@ -2,19 +2,40 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
-// LoginRequest represents the login request body
+// anonymous returns the request with an anonymous audit log actor at its client IP, for endpoints
+// used before signing in
+func anonymous(r *http.Request) *http.Request {
+	actor := audit.Actor{Type: storage.ActorAnonymous, IPAddress: auth.ClientFromRequest(r).IPAddress}
+	return r.WithContext(audit.WithActor(r.Context(), actor))
+}
+
+// LoginRequest represents the login request body. Users with two-factor authentication either
+// send their code along with their password, or answer the challenge the password returns.
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email     string `json:"email,omitempty"`
+	Password  string `json:"password,omitempty"`
+	Challenge string `json:"challenge,omitempty"` // from a previous login response, instead of email and password
+	Code      string `json:"code,omitempty"`      // authenticator app or recovery code
+}
+
+// LoginChallengeResponse is returned when the password was right but a two-factor code is needed
+type LoginChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	Challenge         string    `json:"challenge"`
+	ExpiresAt         time.Time `json:"expires_at"`
 }
 
 // ForgotPasswordRequest represents the forgot password request body
@@ -24,7 +45,7 @@ type ForgotPasswordRequest struct {
 
 // ForgotPasswordResponse represents the forgot password response
 type ForgotPasswordResponse struct {
-	ResetToken string `json:"reset_token"`
+	ResetToken string `json:"reset_token,omitempty"` // dev mode only
 }
 
 // ResetPasswordRequest represents the reset password request body
@@ -44,13 +65,22 @@ type UserProfile struct {
 	ID        int       `json:"id"`
 	Email     string    `json:"email"`
 	IsAdmin   bool      `json:"is_admin"`
+	Role      string    `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
+
+	TOTPEnabled bool `json:"totp_enabled"` // signs in with an authenticator app code after the password
 }
 
 // CreateUserRequest represents the create user request body
 type CreateUserRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"` // defaults to operator
+}
+
+// SetUserRoleRequest represents the set user role request body
+type SetUserRoleRequest struct {
+	Role string `json:"role"`
 }
 
 // CreateUserResponse represents the create user response
@@ -58,6 +88,7 @@ type CreateUserResponse struct {
 	ID           int       `json:"id"`
 	Email        string    `json:"email"`
 	IsAdmin      bool      `json:"is_admin"`
+	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
 	TempPassword string    `json:"temp_password,omitempty"`
 }
@@ -75,6 +106,12 @@ func handleRegister(authService *auth.Service) http.HandlerFunc {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		r = anonymous(r)
+
+		if !authService.PasswordLoginEnabled() {
+			http.Error(w, "Registration is disabled, sign in with single sign-on", http.StatusForbidden)
+			return
+		}
 
 		var req RegisterRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -103,7 +140,7 @@ func handleRegister(authService *auth.Service) http.HandlerFunc {
 		}
 
 		// Create the user
-		user, tempPassword, err := authService.CreateUser(r.Context(), req.Email, req.Password)
+		user, err := authService.RegisterUser(r.Context(), req.Email, req.Password)
 		if err != nil {
 			log.Printf("Failed to register user: %v", err)
 
@@ -119,13 +156,12 @@ func handleRegister(authService *auth.Service) http.HandlerFunc {
 			return
 		}
 
-		// Return user with optional temp password (shouldn't happen for registration)
 		response := CreateUserResponse{
-			ID:           user.ID,
-			Email:        user.Email,
-			IsAdmin:      user.IsAdmin,
-			CreatedAt:    user.CreatedAt,
-			TempPassword: tempPassword,
+			ID:        user.ID,
+			Email:     user.Email,
+			IsAdmin:   user.IsAdmin,
+			Role:      user.Role,
+			CreatedAt: user.CreatedAt,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -134,13 +170,15 @@ func handleRegister(authService *auth.Service) http.HandlerFunc {
 	}
 }
 
-// handleLogin handles POST /auth/login
+// handleLogin handles POST /auth/login. Users with two-factor authentication get a challenge to
+// answer with their code, unless they sent the code along with their password.
 func handleLogin(authService *auth.Service, accessTTL time.Duration, secureCookie bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		r = anonymous(r)
 
 		var req LoginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -148,51 +186,216 @@ func handleLogin(authService *auth.Service, accessTTL time.Duration, secureCooki
 			return
 		}
 
-		// Authenticate user
-		user, err := authService.Authenticate(r.Context(), req.Email, req.Password)
-		if err != nil {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
-			return
+		// Failed passwords and codes count against both the account and the client IP
+		throttle := authService.Throttle()
+		ipKey := auth.IPKey(auth.AttemptLogin, auth.ClientFromRequest(r).IPAddress)
+
+		// Answer a two-factor challenge, or check the password
+		var user *storage.User
+		var err error
+		if req.Challenge != "" {
+			user, err = authService.LoginChallengeUser(r.Context(), req.Challenge)
+			if err != nil {
+				twoFactorLoginFailed(r, authService, "", err)
+				writeTwoFactorLoginError(w, err)
+				return
+			}
+			email := user.Email
+			accountKey := auth.AccountKey(auth.AttemptLogin, email)
+			if throttled(w, r, throttle, ipKey, accountKey) {
+				authService.RecordLoginFailure(r.Context(), email, auth.LoginPassword, loginThrottled)
+				return
+			}
+
+			user, err = authService.CompleteLoginChallenge(r.Context(), req.Challenge, req.Code)
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+					throttle.Fail(r.Context(), ipKey, accountKey)
+				}
+				twoFactorLoginFailed(r, authService, email, err)
+				writeTwoFactorLoginError(w, err)
+				return
+			}
+			throttle.Succeed(r.Context(), accountKey)
+		} else {
+			accountKey := auth.AccountKey(auth.AttemptLogin, req.Email)
+			if throttled(w, r, throttle, ipKey, accountKey) {
+				authService.RecordLoginFailure(r.Context(), req.Email, auth.LoginPassword, loginThrottled)
+				return
+			}
+
+			user, err = authService.Authenticate(r.Context(), req.Email, req.Password)
+			if errors.Is(err, auth.ErrPasswordLoginDisabled) {
+				authService.RecordLoginFailure(r.Context(), req.Email, auth.LoginPassword, loginDisabled)
+				http.Error(w, "Password login is disabled, sign in with single sign-on", http.StatusForbidden)
+				return
+			}
+			if err != nil {
+				throttle.Fail(r.Context(), ipKey, accountKey)
+				authService.RecordLoginFailure(r.Context(), req.Email, auth.LoginPassword, loginInvalidCredentials)
+				http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+				return
+			}
+
+			if user.TOTPEnabled && req.Code == "" {
+				challenge, err := authService.CreateLoginChallenge(user)
+				if err != nil {
+					log.Printf("Failed to create login challenge: %v", err)
+					http.Error(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(LoginChallengeResponse{
+					TwoFactorRequired: true,
+					Challenge:         challenge,
+					ExpiresAt:         time.Now().Add(auth.LoginChallengeTTL).UTC(),
+				})
+				return
+			}
+			if user.TOTPEnabled {
+				if err := authService.VerifySecondFactor(r.Context(), user.ID, req.Code); err != nil {
+					if errors.Is(err, auth.ErrInvalidTwoFactorCode) {
+						throttle.Fail(r.Context(), ipKey, accountKey)
+					}
+					twoFactorLoginFailed(r, authService, user.Email, err)
+					writeTwoFactorLoginError(w, err)
+					return
+				}
+			}
+			throttle.Succeed(r.Context(), accountKey)
 		}
 
-		// Create session token
-		token, err := authService.CreateSession(user.ID)
+		// Start a session for this device
+		tokens, err := authService.Login(r.Context(), user, auth.LoginPassword, auth.ClientFromRequest(r))
 		if err != nil {
 			log.Printf("Failed to create session: %v", err)
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		// Set session cookie
-		auth.SetSessionCookie(w, token, int(accessTTL.Seconds()), secureCookie)
+		// Set session cookies
+		setSessionCookies(w, tokens, accessTTL, secureCookie)
 
 		// Return user profile
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(UserProfile{
-			ID:        user.ID,
-			Email:     user.Email,
-			IsAdmin:   user.IsAdmin,
-			CreatedAt: user.CreatedAt,
+			ID:          user.ID,
+			Email:       user.Email,
+			IsAdmin:     user.IsAdmin,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt,
+			TOTPEnabled: user.TOTPEnabled,
 		})
 	}
 }
 
-// handleLogout handles POST /auth/logout
-func handleLogout(secureCookie bool) http.HandlerFunc {
+// Reasons a password login failed, as recorded in the audit log
+const (
+	loginInvalidCredentials = "invalid_credentials"
+	loginInvalidCode        = "invalid_code"
+	loginExpired            = "expired"
+	loginDisabled           = "password_login_disabled"
+	loginThrottled          = "throttled"
+)
+
+// twoFactorLoginFailed records a failed second login step as email. Internal errors are not the
+// client's failure and are only logged.
+func twoFactorLoginFailed(r *http.Request, authService *auth.Service, email string, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		authService.RecordLoginFailure(r.Context(), email, auth.LoginPassword, loginInvalidCode)
+	case errors.Is(err, auth.ErrInvalidLoginChallenge):
+		authService.RecordLoginFailure(r.Context(), email, auth.LoginPassword, loginExpired)
+	}
+}
+
+// writeTwoFactorLoginError writes the response for a failed second login step
+func writeTwoFactorLoginError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode):
+		http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+	case errors.Is(err, auth.ErrInvalidLoginChallenge):
+		http.Error(w, "Login expired, sign in again", http.StatusUnauthorized)
+	default:
+		log.Printf("Two-factor login failed: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// handleRefresh handles POST /auth/refresh, exchanging the refresh token cookie for new session
+// cookies. The refresh token is rotated on every use.
+func handleRefresh(authService *auth.Service, accessTTL time.Duration, secureCookie bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Clear session cookie
-		auth.ClearSessionCookie(w, secureCookie)
+		refreshToken, err := auth.GetRefreshCookie(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tokens, err := authService.RefreshSession(r.Context(), refreshToken, auth.ClientFromRequest(r))
+		if err != nil {
+			log.Printf("Session refresh failed: %v", err)
+			if errors.Is(err, auth.ErrInvalidSession) || errors.Is(err, auth.ErrRefreshTokenReused) {
+				clearSessionCookies(w, secureCookie)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		setSessionCookies(w, tokens, accessTTL, secureCookie)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleLogout handles POST /auth/logout, ending the current session
+func handleLogout(authService *auth.Service, secureCookie bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Revoke the session the cookies belong to, if any
+		accessToken, _ := auth.GetSessionCookie(r)
+		refreshToken, _ := auth.GetRefreshCookie(r)
+		if accessToken != "" || refreshToken != "" {
+			if err := authService.EndSession(r.Context(), accessToken, refreshToken); err != nil && !errors.Is(err, auth.ErrInvalidSession) {
+				log.Printf("Failed to end session on logout: %v", err)
+			}
+		}
+
+		// Clear session cookies
+		clearSessionCookies(w, secureCookie)
 
 		// Return 204 No Content
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// handleJWKS handles GET /.well-known/jwks.json - the public keys other services verify
+// LunaSentri tokens with
+func handleJWKS(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Verifiers may cache the keys briefly, so new keys are published as verification-only
+		// keys before they sign anything
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(authService.JWKS())
+	}
+}
+
 // handleMe handles GET /auth/me
 func handleMe() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -211,21 +414,30 @@ func handleMe() http.HandlerFunc {
 		// Return user profile
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(UserProfile{
-			ID:        user.ID,
-			Email:     user.Email,
-			IsAdmin:   user.IsAdmin,
-			CreatedAt: user.CreatedAt,
+			ID:          user.ID,
+			Email:       user.Email,
+			IsAdmin:     user.IsAdmin,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt,
+			TOTPEnabled: user.TOTPEnabled,
 		})
 	}
 }
 
-// handleForgotPassword handles POST /auth/forgot-password
-func handleForgotPassword(authService *auth.Service, passwordResetTTL time.Duration) http.HandlerFunc {
+// handleForgotPassword handles POST /auth/forgot-password. The reset link is emailed; in dev mode
+// the token is also logged and returned so the flow can be tried without a mail server.
+func handleForgotPassword(authService *auth.Service, passwordResetTTL time.Duration, devMode bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		r = anonymous(r)
+
+		if !authService.PasswordLoginEnabled() {
+			http.Error(w, "Password login is disabled, sign in with single sign-on", http.StatusForbidden)
+			return
+		}
 
 		var req ForgotPasswordRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -233,6 +445,17 @@ func handleForgotPassword(authService *auth.Service, passwordResetTTL time.Durat
 			return
 		}
 
+		// Every request counts, so reset emails cannot be used to flood an inbox
+		throttle := authService.Throttle()
+		keys := []string{
+			auth.IPKey(auth.AttemptPasswordReset, auth.ClientFromRequest(r).IPAddress),
+			auth.AccountKey(auth.AttemptPasswordReset, req.Email),
+		}
+		if throttled(w, r, throttle, keys...) {
+			return
+		}
+		throttle.Fail(r.Context(), keys...)
+
 		// Generate password reset token
 		token, err := authService.GeneratePasswordReset(r.Context(), req.Email, passwordResetTTL)
 		if err != nil {
@@ -240,16 +463,16 @@ func handleForgotPassword(authService *auth.Service, passwordResetTTL time.Durat
 			// Still return 202 to avoid leaking user existence
 		}
 
-		// Log the token to stdout for manual testing (dev mode)
-		log.Printf("Password reset token for %s: %s", req.Email, token)
+		var resp ForgotPasswordResponse
+		if devMode {
+			// Log and return the token for manual testing
+			log.Printf("Password reset token for %s: %s", req.Email, token)
+			resp.ResetToken = token
+		}
 
-		// Return token in response (for development)
-		// TODO: In production, send via email instead
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusAccepted)
-		json.NewEncoder(w).Encode(ForgotPasswordResponse{
-			ResetToken: token,
-		})
+		json.NewEncoder(w).Encode(resp)
 	}
 }
 
@@ -260,6 +483,12 @@ func handleResetPassword(authService *auth.Service) http.HandlerFunc {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		r = anonymous(r)
+
+		if !authService.PasswordLoginEnabled() {
+			http.Error(w, "Password login is disabled, sign in with single sign-on", http.StatusForbidden)
+			return
+		}
 
 		var req ResetPasswordRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -267,10 +496,17 @@ func handleResetPassword(authService *auth.Service) http.HandlerFunc {
 			return
 		}
 
+		// Guessing reset tokens counts against the client IP
+		ipKey := auth.IPKey(auth.AttemptPasswordReset, auth.ClientFromRequest(r).IPAddress)
+		if throttled(w, r, authService.Throttle(), ipKey) {
+			return
+		}
+
 		// Reset the password
 		err := authService.ResetPassword(r.Context(), req.Token, req.Password)
 		if err != nil {
 			log.Printf("Password reset failed: %v", err)
+			authService.Throttle().Fail(r.Context(), ipKey)
 			http.Error(w, "Invalid or expired reset token", http.StatusBadRequest)
 			return
 		}
@@ -280,8 +516,9 @@ func handleResetPassword(authService *auth.Service) http.HandlerFunc {
 	}
 }
 
-// handleChangePassword handles POST /auth/change-password (requires authentication)
-func handleChangePassword(authService *auth.Service) http.HandlerFunc {
+// handleChangePassword handles POST /auth/change-password (requires authentication). Changing
+// the password ends every session, so the current device is given a new one.
+func handleChangePassword(authService *auth.Service, accessTTL time.Duration, secureCookie bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -328,6 +565,14 @@ func handleChangePassword(authService *auth.Service) http.HandlerFunc {
 
 		log.Printf("Password successfully changed for user %d", user.ID)
 
+		tokens, err := authService.CreateSession(r.Context(), user.ID, auth.ClientFromRequest(r))
+		if err != nil {
+			log.Printf("Failed to create session after password change for user %d: %v", user.ID, err)
+			clearSessionCookies(w, secureCookie)
+		} else {
+			setSessionCookies(w, tokens, accessTTL, secureCookie)
+		}
+
 		// Return 204 No Content on success
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -353,10 +598,12 @@ func handleListUsers(authService *auth.Service) http.HandlerFunc {
 		profiles := make([]UserProfile, len(users))
 		for i, user := range users {
 			profiles[i] = UserProfile{
-				ID:        user.ID,
-				Email:     user.Email,
-				IsAdmin:   user.IsAdmin,
-				CreatedAt: user.CreatedAt,
+				ID:          user.ID,
+				Email:       user.Email,
+				IsAdmin:     user.IsAdmin,
+				Role:        user.Role,
+				CreatedAt:   user.CreatedAt,
+				TOTPEnabled: user.TOTPEnabled,
 			}
 		}
 
@@ -380,8 +627,23 @@ func handleCreateUser(authService *auth.Service) http.HandlerFunc {
 			return
 		}
 
+		// Get current user from context
+		currentUser, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Check the role can be given before creating the user
+		if req.Role != "" {
+			if err := auth.CheckRoleAssignment(currentUser, req.Role); err != nil {
+				writeRoleError(w, err)
+				return
+			}
+		}
+
 		// Create the user
-		user, tempPassword, err := authService.CreateUser(r.Context(), req.Email, req.Password)
+		user, tempPassword, err := authService.CreateUserWithRole(r.Context(), req.Email, req.Password, req.Role)
 		if err != nil {
 			log.Printf("Failed to create user: %v", err)
 
@@ -408,6 +670,7 @@ func handleCreateUser(authService *auth.Service) http.HandlerFunc {
 			ID:           user.ID,
 			Email:        user.Email,
 			IsAdmin:      user.IsAdmin,
+			Role:         user.Role,
 			CreatedAt:    user.CreatedAt,
 			TempPassword: tempPassword,
 		}
@@ -454,7 +717,9 @@ func handleDeleteUser(authService *auth.Service) http.HandlerFunc {
 
 			// Return appropriate status codes
 			if strings.Contains(err.Error(), "cannot delete your own account") ||
-				strings.Contains(err.Error(), "cannot delete the last admin") {
+				strings.Contains(err.Error(), "cannot delete the last admin") ||
+				strings.Contains(err.Error(), "cannot delete the last owner") ||
+				errors.Is(err, auth.ErrRoleForbidden) {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusForbidden)
 				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -475,3 +740,86 @@ func handleDeleteUser(authService *auth.Service) http.HandlerFunc {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// handleSetUserRole handles PUT /auth/users/{id}/role
+func handleSetUserRole(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Extract user ID from URL path
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/auth/users/"), "/role")
+		userID, err := strconv.Atoi(path)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req SetUserRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		// Get current user from context
+		currentUser, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := authService.SetUserRole(r.Context(), currentUser, userID, req.Role)
+		if err != nil {
+			log.Printf("Failed to set role of user %d: %v", userID, err)
+			writeRoleError(w, err)
+			return
+		}
+		log.Printf("User %d changed the role of user %d to %s", currentUser.ID, user.ID, user.Role)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(UserProfile{
+			ID:          user.ID,
+			Email:       user.Email,
+			IsAdmin:     user.IsAdmin,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt,
+			TOTPEnabled: user.TOTPEnabled,
+		})
+	}
+}
+
+// writeRoleError writes the response for a failed role change
+func writeRoleError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, auth.ErrRoleForbidden),
+		strings.Contains(err.Error(), "cannot change your own role"),
+		strings.Contains(err.Error(), "cannot remove the last owner"):
+		status = http.StatusForbidden
+	case strings.Contains(err.Error(), "unknown role"):
+		status = http.StatusBadRequest
+	case strings.Contains(err.Error(), "user not found"):
+		status = http.StatusNotFound
+	default:
+		http.Error(w, "Internal server error", status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// setSessionCookies sets the access and refresh token cookies of a session
+func setSessionCookies(w http.ResponseWriter, tokens *auth.SessionTokens, accessTTL time.Duration, secureCookie bool) {
+	auth.SetSessionCookie(w, tokens.AccessToken, int(accessTTL.Seconds()), secureCookie)
+	auth.SetRefreshCookie(w, tokens.RefreshToken, int(time.Until(tokens.RefreshExpiresAt).Seconds()), secureCookie)
+}
+
+// clearSessionCookies clears the access and refresh token cookies
+func clearSessionCookies(w http.ResponseWriter, secureCookie bool) {
+	auth.ClearSessionCookie(w, secureCookie)
+	auth.ClearRefreshCookie(w, secureCookie)
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestHandleForgotPassword_TokenOnlyInDevMode(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	store.CreateUser(context.Background(), "admin@example.com", "hash")
+
+	for _, devMode := range []bool{false, true} {
+		body, _ := json.Marshal(ForgotPasswordRequest{Email: "admin@example.com"})
+		w := httptest.NewRecorder()
+		handleForgotPassword(authService, time.Hour, devMode)(w, httptest.NewRequest(http.MethodPost, "/auth/forgot-password", bytes.NewReader(body)))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected 202, got %d", w.Code)
+		}
+
+		var resp ForgotPasswordResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		if devMode && resp.ResetToken == "" {
+			t.Error("Expected the token in dev mode")
+		}
+		if !devMode && resp.ResetToken != "" {
+			t.Error("Expected no token outside dev mode")
+		}
+	}
+}
+
+func TestUserRoleEndpoints(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	ctx := context.Background()
+	owner, _, _ := authService.CreateUser(ctx, "owner@example.com", "password123")
+	admin, _, _ := authService.CreateUser(ctx, "admin@example.com", "password123")
+	admin, _ = authService.SetUserRole(ctx, owner, admin.ID, storage.RoleAdmin)
+
+	as := func(user *storage.User, handler http.HandlerFunc, method, target string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+		w := httptest.NewRecorder()
+		handler(w, req)
+		return w
+	}
+
+	// Admins create read-only accounts for support staff
+	w := as(admin, handleCreateUser(authService), http.MethodPost, "/auth/users", CreateUserRequest{Email: "support@example.com", Role: storage.RoleViewer})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating a viewer, got %d: %s", w.Code, w.Body.String())
+	}
+	var created CreateUserResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Role != storage.RoleViewer {
+		t.Errorf("Expected a viewer, got %q", created.Role)
+	}
+	entries, _ := store.ListAuditEntries(ctx, storage.AuditFilter{TargetType: "user", TargetID: strconv.Itoa(created.ID)})
+	if len(entries) != 1 || entries[0].Action != "user.create" || !strings.Contains(string(entries[0].Changes), `"viewer"`) {
+		t.Errorf("Expected one user.create entry with the role, got %+v", entries)
+	}
+
+	// but cannot create admins
+	w = as(admin, handleCreateUser(authService), http.MethodPost, "/auth/users", CreateUserRequest{Email: "other@example.com", Role: storage.RoleAdmin})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 creating an admin as an admin, got %d", w.Code)
+	}
+	if _, err := store.GetUserByEmail(ctx, "other@example.com"); err != storage.ErrUserNotFound {
+		t.Errorf("Expected no user to be created, got %v", err)
+	}
+
+	roleURL := fmt.Sprintf("/auth/users/%d/role", created.ID)
+	w = as(admin, handleSetUserRole(authService), http.MethodPut, roleURL, SetUserRoleRequest{Role: storage.RoleOperator})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 setting a role, got %d: %s", w.Code, w.Body.String())
+	}
+	var profile UserProfile
+	json.NewDecoder(w.Body).Decode(&profile)
+	if profile.Role != storage.RoleOperator {
+		t.Errorf("Expected an operator, got %q", profile.Role)
+	}
+
+	tests := []struct {
+		actor  *storage.User
+		target string
+		role   string
+		want   int
+	}{
+		{admin, fmt.Sprintf("/auth/users/%d/role", owner.ID), storage.RoleViewer, http.StatusForbidden},
+		{owner, fmt.Sprintf("/auth/users/%d/role", owner.ID), storage.RoleAdmin, http.StatusForbidden},
+		{owner, roleURL, "superuser", http.StatusBadRequest},
+		{owner, "/auth/users/999/role", storage.RoleViewer, http.StatusNotFound},
+		{owner, "/auth/users/abc/role", storage.RoleViewer, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := as(tt.actor, handleSetUserRole(authService), http.MethodPut, tt.target, SetUserRoleRequest{Role: tt.role})
+		if w.Code != tt.want {
+			t.Errorf("PUT %s to %s as %s: expected %d, got %d", tt.target, tt.role, tt.actor.Email, tt.want, w.Code)
+		}
+	}
+
+	// Only owners delete owners
+	w = as(admin, handleDeleteUser(authService), http.MethodDelete, fmt.Sprintf("/auth/users/%d", owner.ID), nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 deleting the owner as an admin, got %d", w.Code)
+	}
+}
+
+func TestHandleJWKS(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	keys, err := auth.ParseKeySet("current:ed25519:"+base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize)), "test-secret")
+	if err != nil {
+		t.Fatalf("Failed to parse keys: %v", err)
+	}
+	authService, err := auth.NewServiceWithKeys(store, keys, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	handleJWKS(authService)(w, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+
+	// The Ed25519 key is published; the HS256 secret is not
+	var jwks auth.JWKS
+	if err := json.NewDecoder(w.Body).Decode(&jwks); err != nil {
+		t.Fatalf("Failed to decode JWKS: %v", err)
+	}
+	if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "current" || jwks.Keys[0].KeyType != "OKP" || jwks.Keys[0].Algorithm != auth.AlgEdDSA {
+		t.Errorf("Unexpected JWKS %+v", jwks)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/metrics"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/notifications"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/silences"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/system"
 	"github.com/gorilla/websocket"
@@ -45,13 +46,18 @@ type RouterConfig struct {
 	AlertService     *alerts.Service
 	SystemService    system.Service
 	MachineService   *machines.Service
+	SilenceService   *silences.Service
 	Store            storage.Store
 	WebhookNotifier  *notifications.Notifier
 	TelegramNotifier *notifications.TelegramNotifier
+	TelegramBot      *notifications.TelegramBot // Set in bot webhook mode to receive updates from Telegram
+	TelegramSecret   string                     // Secret token Telegram sends with webhook updates
 	AccessTTL        time.Duration
 	PasswordResetTTL time.Duration
+	PasswordResetDev bool // Return reset tokens from /auth/forgot-password instead of only emailing them
 	SecureCookie     bool
 	LocalHostMetrics bool
+	AppBaseURL       string // web app URL single sign-on redirects back to
 }
 
 // NewRouter creates a new HTTP router with all routes configured
@@ -74,13 +80,33 @@ func NewRouter(cfg *RouterConfig) *http.ServeMux {
 	// Register auth handlers (public endpoints)
 	mux.HandleFunc("/auth/register", handleRegister(cfg.AuthService))
 	mux.HandleFunc("/auth/login", handleLogin(cfg.AuthService, cfg.AccessTTL, cfg.SecureCookie))
-	mux.HandleFunc("/auth/logout", handleLogout(cfg.SecureCookie))
-	mux.HandleFunc("/auth/forgot-password", handleForgotPassword(cfg.AuthService, cfg.PasswordResetTTL))
+	mux.HandleFunc("/auth/refresh", handleRefresh(cfg.AuthService, cfg.AccessTTL, cfg.SecureCookie))
+	mux.HandleFunc("/auth/logout", handleLogout(cfg.AuthService, cfg.SecureCookie))
+	mux.HandleFunc("/auth/forgot-password", handleForgotPassword(cfg.AuthService, cfg.PasswordResetTTL, cfg.PasswordResetDev))
 	mux.HandleFunc("/auth/reset-password", handleResetPassword(cfg.AuthService))
+	mux.HandleFunc("/auth/oidc", handleOIDC(cfg.AuthService, cfg.AppBaseURL, cfg.AccessTTL, cfg.SecureCookie))
+	mux.HandleFunc("/auth/oidc/", handleOIDC(cfg.AuthService, cfg.AppBaseURL, cfg.AccessTTL, cfg.SecureCookie))
+	mux.HandleFunc("/.well-known/jwks.json", handleJWKS(cfg.AuthService))
 
 	// Protected auth endpoints
 	mux.Handle("/auth/me", cfg.AuthService.RequireAuth(handleMe()))
-	mux.Handle("/auth/change-password", cfg.AuthService.RequireAuth(handleChangePassword(cfg.AuthService)))
+	mux.Handle("/auth/change-password", cfg.AuthService.RequireAuth(handleChangePassword(cfg.AuthService, cfg.AccessTTL, cfg.SecureCookie)))
+	mux.Handle("/auth/sessions", cfg.AuthService.RequireAuth(handleSessions(cfg.AuthService, cfg.SecureCookie)))
+	mux.Handle("/auth/sessions/", cfg.AuthService.RequireAuth(handleRevokeSession(cfg.AuthService, cfg.SecureCookie)))
+	mux.Handle("/auth/tokens", cfg.AuthService.RequireAuth(handleAPITokens(cfg.AuthService)))
+	mux.Handle("/auth/tokens/", cfg.AuthService.RequireAuth(handleRevokeAPIToken(cfg.AuthService)))
+	mux.Handle("/auth/2fa", cfg.AuthService.RequireAuth(handleTwoFactor(cfg.AuthService)))
+	mux.Handle("/auth/2fa/", cfg.AuthService.RequireAuth(handleTwoFactor(cfg.AuthService)))
+	mux.Handle("/auth/lockouts", cfg.AuthService.RequireAuth(handleLockouts(cfg.AuthService)))
+	mux.Handle("/auth/lockouts/", cfg.AuthService.RequireAuth(handleLockouts(cfg.AuthService)))
+
+	// Audit log endpoints (protected)
+	mux.Handle("/audit", cfg.AuthService.RequireAuth(handleAuditLog(cfg.AuthService)))
+	mux.Handle("/audit/export", cfg.AuthService.RequireAuth(handleAuditExport(cfg.AuthService)))
+
+	// Organization endpoints (protected)
+	mux.Handle("/orgs", cfg.AuthService.RequireAuth(handleOrganizations(cfg.AuthService)))
+	mux.Handle("/orgs/", cfg.AuthService.RequireAuth(handleOrganization(cfg.AuthService)))
 
 	// User management endpoints (protected)
 	mux.Handle("/auth/users", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -93,8 +119,19 @@ func NewRouter(cfg *RouterConfig) *http.ServeMux {
 		}
 	})))
 
-	// User delete endpoint (protected) - handle /auth/users/{id}
-	mux.Handle("/auth/users/", cfg.AuthService.RequireAuth(handleDeleteUser(cfg.AuthService)))
+	// User delete, role, two-factor reset and unlock endpoints (protected) - handle /auth/users/{id},
+	// /auth/users/{id}/role, /auth/users/{id}/2fa and /auth/users/{id}/lockout
+	mux.Handle("/auth/users/", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/role") {
+			handleSetUserRole(cfg.AuthService)(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/2fa") {
+			handleResetTwoFactor(cfg.AuthService)(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/lockout") {
+			handleUnlockUser(cfg.AuthService)(w, r)
+		} else {
+			handleDeleteUser(cfg.AuthService)(w, r)
+		}
+	})))
 
 	// Register protected handlers (require authentication)
 	mux.Handle("/metrics", cfg.AuthService.RequireAuth(handleMetrics(cfg.Collector, cfg.ServerStartTime, cfg.AlertService, cfg.MachineService, cfg.LocalHostMetrics)))
@@ -105,8 +142,14 @@ func NewRouter(cfg *RouterConfig) *http.ServeMux {
 	// Alert management endpoints (protected)
 	mux.Handle("/alerts/rules", cfg.AuthService.RequireAuth(handleAlertRules(cfg.AlertService)))
 	mux.Handle("/alerts/rules/", cfg.AuthService.RequireAuth(handleAlertRule(cfg.AlertService)))
+	mux.Handle("/alerts/rules/backtest", cfg.AuthService.RequireAuth(handleAlertRuleBacktest(cfg.AlertService, cfg.MachineService)))
 	mux.Handle("/alerts/events", cfg.AuthService.RequireAuth(handleAlertEvents(cfg.AlertService)))
 	mux.Handle("/alerts/events/", cfg.AuthService.RequireAuth(handleAlertEventAck(cfg.AlertService)))
+	mux.Handle("/alerts/stats", cfg.AuthService.RequireAuth(handleAlertStats(cfg.AlertService)))
+
+	// Silence endpoints (protected)
+	mux.Handle("/silences", cfg.AuthService.RequireAuth(handleSilences(cfg.SilenceService)))
+	mux.Handle("/silences/", cfg.AuthService.RequireAuth(handleSilence(cfg.SilenceService)))
 
 	// Webhook notification endpoints (protected)
 	mux.Handle("/notifications/webhooks", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -124,6 +167,14 @@ func NewRouter(cfg *RouterConfig) *http.ServeMux {
 			notifications.HandleTestWebhook(cfg.WebhookNotifier, cfg.Store)(w, r)
 			return
 		}
+		if strings.HasSuffix(r.URL.Path, "/reset-circuit") && r.Method == http.MethodPost {
+			notifications.HandleResetWebhookCircuit(cfg.Store)(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/rotate-secret") && r.Method == http.MethodPost {
+			notifications.HandleRotateWebhookSecret(cfg.Store)(w, r)
+			return
+		}
 
 		if r.Method == http.MethodPut {
 			notifications.HandleUpdateWebhook(cfg.Store)(w, r)
@@ -136,6 +187,11 @@ func NewRouter(cfg *RouterConfig) *http.ServeMux {
 
 	// Telegram notification endpoints (protected)
 	// Always register endpoints regardless of whether TelegramNotifier is configured
+	// Telegram bot webhook (authenticated by Telegram's secret token, not a user session)
+	if cfg.TelegramBot != nil {
+		mux.HandleFunc("/notifications/telegram/webhook", notifications.HandleTelegramWebhook(cfg.TelegramBot, cfg.TelegramSecret))
+	}
+
 	mux.Handle("/notifications/telegram", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			notifications.HandleListTelegramRecipients(cfg.Store)(w, r)
@@ -152,6 +208,10 @@ func NewRouter(cfg *RouterConfig) *http.ServeMux {
 			notifications.HandleTestTelegram(cfg.Store, cfg.TelegramNotifier)(w, r)
 			return
 		}
+		if strings.HasSuffix(r.URL.Path, "/reset-circuit") && r.Method == http.MethodPost {
+			notifications.HandleResetTelegramCircuit(cfg.Store)(w, r)
+			return
+		}
 		if r.Method == http.MethodPut {
 			notifications.HandleUpdateTelegramRecipient(cfg.Store)(w, r)
 		} else if r.Method == http.MethodDelete {
@@ -163,12 +223,85 @@ func NewRouter(cfg *RouterConfig) *http.ServeMux {
 		}
 	})))
 
+	// Notification template endpoints (protected)
+	mux.Handle("/notifications/templates", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			notifications.HandleListTemplates(cfg.Store)(w, r)
+		} else if r.Method == http.MethodPost {
+			notifications.HandleSaveTemplate(cfg.Store)(w, r)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		}
+	})))
+	mux.Handle("/notifications/templates/", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/notifications/templates/preview" {
+			notifications.HandlePreviewTemplate()(w, r)
+			return
+		}
+		notifications.HandleDeleteTemplate(cfg.Store)(w, r)
+	})))
+
+	// Notification policy endpoints (protected)
+	mux.Handle("/notifications/policy", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			notifications.HandleGetNotificationPolicy(cfg.Store)(w, r)
+		} else if r.Method == http.MethodPut {
+			notifications.HandleUpdateNotificationPolicy(cfg.Store)(w, r)
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+		}
+	})))
+
+	// Notification routing endpoints (protected)
+	mux.Handle("/notifications/routes", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			notifications.HandleListRoutes(cfg.Store)(w, r)
+		} else if r.Method == http.MethodPost {
+			notifications.HandleCreateRoute(cfg.Store)(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/notifications/routes/", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			notifications.HandleUpdateRoute(cfg.Store)(w, r)
+		} else if r.Method == http.MethodDelete {
+			notifications.HandleDeleteRoute(cfg.Store)(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Escalation policy endpoints (protected)
+	mux.Handle("/notifications/escalation-policies", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			notifications.HandleListEscalationPolicies(cfg.Store)(w, r)
+		} else if r.Method == http.MethodPost {
+			notifications.HandleCreateEscalationPolicy(cfg.Store)(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/notifications/escalation-policies/", cfg.AuthService.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			notifications.HandleUpdateEscalationPolicy(cfg.Store)(w, r)
+		} else if r.Method == http.MethodDelete {
+			notifications.HandleDeleteEscalationPolicy(cfg.Store)(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
 	// Agent endpoints
 	// POST /agent/register - Session authenticated (user registers a new machine)
 	mux.Handle("/agent/register", cfg.AuthService.RequireAuth(handleAgentRegister(cfg.MachineService)))
 
 	// POST /agent/metrics - API key authenticated (agent pushes metrics)
-	mux.Handle("/agent/metrics", RequireAPIKey(cfg.MachineService)(http.HandlerFunc(handleAgentMetrics(cfg.MachineService))))
+	mux.Handle("/agent/metrics", RequireAPIKey(cfg.MachineService, cfg.AuthService.Throttle())(http.HandlerFunc(handleAgentMetrics(cfg.MachineService))))
 
 	// Machine management endpoints (session authenticated)
 	mux.Handle("/machines", cfg.AuthService.RequireAuth(handleListMachines(cfg.MachineService)))
@@ -217,6 +350,7 @@ func CORSMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
 
 		// Handle preflight OPTIONS requests
 		if r.Method == "OPTIONS" {
@@ -239,8 +373,9 @@ func firstNonEmpty(values ...string) string {
 }
 
 // evaluateAlerts is a helper function to evaluate alerts with a dedicated background context
-// This prevents context cancellation issues when request/websocket contexts are cancelled
-func evaluateAlerts(alertService *alerts.Service, metricsData metrics.Metrics) {
+// This prevents context cancellation issues when request/websocket contexts are cancelled.
+// machineID is the machine the metrics came from, or 0 for the local host.
+func evaluateAlerts(alertService *alerts.Service, metricsData metrics.Metrics, machineID int) {
 	if alertService == nil {
 		return
 	}
@@ -249,7 +384,13 @@ func evaluateAlerts(alertService *alerts.Service, metricsData metrics.Metrics) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := alertService.Evaluate(ctx, metricsData); err != nil {
+	var err error
+	if machineID != 0 {
+		err = alertService.EvaluateMachine(ctx, machineID, metricsData)
+	} else {
+		err = alertService.Evaluate(ctx, metricsData)
+	}
+	if err != nil {
 		log.Printf("Failed to evaluate alerts: %v", err)
 	}
 }
@@ -274,13 +415,13 @@ func handleMetrics(collector metrics.Collector, startTime time.Time, alertServic
 				return
 			}
 
-			user, ok := auth.GetUserFromContext(r.Context())
+			org, ok := auth.GetOrgFromContext(r.Context())
 			if !ok {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			latest, err := machineService.GetLatestMetrics(r.Context(), machineID, user.ID)
+			latest, err := machineService.GetLatestMetrics(r.Context(), machineID, org.OrgID)
 			if err != nil {
 				if strings.Contains(err.Error(), "no metrics found") {
 					empty := metrics.Metrics{}
@@ -289,7 +430,7 @@ func handleMetrics(collector metrics.Collector, startTime time.Time, alertServic
 					}
 					return
 				}
-				log.Printf("Failed to fetch metrics for machine %d (user %d): %v", machineID, user.ID, err)
+				log.Printf("Failed to fetch metrics for machine %d (organization %d): %v", machineID, org.OrgID, err)
 				http.Error(w, "Metrics not available for this machine", http.StatusInternalServerError)
 				return
 			}
@@ -301,7 +442,7 @@ func handleMetrics(collector metrics.Collector, startTime time.Time, alertServic
 				UptimeS:     latest.UptimeSeconds,
 			}
 
-			evaluateAlerts(alertService, metricsData)
+			evaluateAlerts(alertService, metricsData, machineID)
 
 			if err := json.NewEncoder(w).Encode(metricsData); err != nil {
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -330,7 +471,7 @@ func handleMetrics(collector metrics.Collector, startTime time.Time, alertServic
 
 		metricsData.UptimeS = time.Since(startTime).Seconds()
 
-		evaluateAlerts(alertService, metricsData)
+		evaluateAlerts(alertService, metricsData, 0)
 
 		if err := json.NewEncoder(w).Encode(metricsData); err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -345,7 +486,7 @@ func handleWebSocket(collector metrics.Collector, startTime time.Time, alertServ
 		machineIDParam := r.URL.Query().Get("machine_id")
 		var machineID int
 		var err error
-		var userID int
+		var orgID int
 		streamMachineMetrics := machineIDParam != ""
 
 		if streamMachineMetrics {
@@ -360,15 +501,15 @@ func handleWebSocket(collector metrics.Collector, startTime time.Time, alertServ
 				return
 			}
 
-			user, ok := auth.GetUserFromContext(r.Context())
+			org, ok := auth.GetOrgFromContext(r.Context())
 			if !ok {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
-			userID = user.ID
+			orgID = org.OrgID
 
-			// Ensure user owns machine
-			if _, err := machineService.GetMachine(r.Context(), machineID, user.ID); err != nil {
+			// Ensure the organization owns the machine
+			if _, err := machineService.GetMachine(r.Context(), machineID, org.OrgID); err != nil {
 				http.Error(w, "Access denied", http.StatusForbidden)
 				return
 			}
@@ -431,7 +572,7 @@ func handleWebSocket(collector metrics.Collector, startTime time.Time, alertServ
 				var metricsData metrics.Metrics
 
 				if streamMachineMetrics {
-					latest, err := machineService.GetLatestMetrics(r.Context(), machineID, userID)
+					latest, err := machineService.GetLatestMetrics(r.Context(), machineID, orgID)
 					if err != nil {
 						log.Printf("Failed to fetch metrics for WebSocket stream machine_id=%d: %v", machineID, err)
 						continue
@@ -457,7 +598,7 @@ func handleWebSocket(collector metrics.Collector, startTime time.Time, alertServ
 				}
 
 				// Evaluate alerts with the new metrics using background context
-				evaluateAlerts(alertService, metricsData)
+				evaluateAlerts(alertService, metricsData, machineID)
 
 				// Set write deadline for this message
 				conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -502,21 +643,21 @@ func handleSystemInfo(systemService system.Service, machineService *machines.Ser
 				return
 			}
 
-			user, ok := auth.GetUserFromContext(r.Context())
+			org, ok := auth.GetOrgFromContext(r.Context())
 			if !ok {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			machine, err := machineService.GetMachineWithComputedStatus(r.Context(), machineID, user.ID)
+			machine, err := machineService.GetMachineWithComputedStatus(r.Context(), machineID, org.OrgID)
 			if err != nil {
-				log.Printf("Failed to load machine %d for user %d: %v", machineID, user.ID, err)
+				log.Printf("Failed to load machine %d for organization %d: %v", machineID, org.OrgID, err)
 				http.Error(w, "Machine not found", http.StatusNotFound)
 				return
 			}
 
 			var latestMetrics *storage.MetricsHistory
-			if m, err := machineService.GetLatestMetrics(r.Context(), machineID, user.ID); err == nil {
+			if m, err := machineService.GetLatestMetrics(r.Context(), machineID, org.OrgID); err == nil {
 				latestMetrics = m
 			}
 
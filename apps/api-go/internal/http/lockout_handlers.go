@@ -0,0 +1,110 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// throttled writes 429 Too Many Requests, with a Retry-After header, and returns true when the
+// client must wait before trying the keys again
+func throttled(w http.ResponseWriter, r *http.Request, throttle *auth.LoginThrottle, keys ...string) bool {
+	wait, err := throttle.Check(r.Context(), keys...)
+	if err != nil {
+		// Don't lock everyone out because the check failed
+		log.Printf("Failed to check failed attempts: %v", err)
+		return false
+	}
+	if wait <= 0 {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many failed attempts, try again later", http.StatusTooManyRequests)
+	return true
+}
+
+// handleLockouts handles the lockout endpoints (users:admin):
+//
+//	GET    /auth/lockouts        accounts and client IPs locked out after repeated failures
+//	DELETE /auth/lockouts/{key}  lift a lockout
+func handleLockouts(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/auth/lockouts"), "/")
+		switch {
+		case key == "" && r.Method == http.MethodGet:
+			lockouts, err := authService.LoginLockouts(r.Context())
+			if err != nil {
+				log.Printf("Failed to list lockouts: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(lockouts)
+
+		case key != "" && r.Method == http.MethodDelete:
+			if err := authService.Unlock(r.Context(), user, key); err != nil {
+				if errors.Is(err, storage.ErrLoginAttemptsNotFound) {
+					http.Error(w, "Lockout not found", http.StatusNotFound)
+					return
+				}
+				log.Printf("Failed to unlock %s: %v", key, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleUnlockUser handles DELETE /auth/users/{id}/lockout, lifting a lockout of the user's account
+func handleUnlockUser(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Extract user ID from URL path
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/auth/users/"), "/lockout")
+		userID, err := strconv.Atoi(path)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		currentUser, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := authService.UnlockUser(r.Context(), currentUser, userID); err != nil {
+			if strings.Contains(err.Error(), "user not found") {
+				http.Error(w, "User not found", http.StatusNotFound)
+				return
+			}
+			log.Printf("Failed to unlock user %d: %v", userID, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
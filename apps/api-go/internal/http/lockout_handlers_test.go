@@ -0,0 +1,152 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestLoginLockout(t *testing.T) {
+	store := createTestStoreForAgentTests(t)
+	authService := createTestAuthServiceForAgent(t, store)
+	_, adminToken := createTestUserWithSession(t, authService)
+	ctx := context.Background()
+	user, _, err := authService.CreateUser(ctx, "victim@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	login := handleLogin(authService, 0, false)
+	attempt := func(password string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(LoginRequest{Email: "victim@example.com", Password: password})
+		w := httptest.NewRecorder()
+		login(w, httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body)))
+		return w
+	}
+
+	// The first failures beyond the free ones are slowed down
+	for i := 1; i <= 4; i++ {
+		if w := attempt("wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected 401, got %d", i, w.Code)
+		}
+	}
+	w := attempt("wrong")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After, got %d %v", w.Code, w.Header())
+	}
+
+	// Once locked out, even the right password is refused
+	accountKey := auth.AccountKey(auth.AttemptLogin, user.Email)
+	for i := 0; i < auth.AccountLockoutPolicy.LockoutAfter; i++ {
+		authService.Throttle().Fail(ctx, accountKey)
+	}
+	if w := attempt("password123"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected a locked out account to get 429, got %d", w.Code)
+	}
+
+	// Admins can see and lift the lockout
+	adminRequest := func(handler http.Handler, method, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: adminToken})
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	w = adminRequest(authService.RequireAuth(handleLockouts(authService)), http.MethodGet, "/auth/lockouts")
+	var lockouts []storage.LoginAttempts
+	json.NewDecoder(w.Body).Decode(&lockouts)
+	if w.Code != http.StatusOK || len(lockouts) != 1 || lockouts[0].Key != accountKey {
+		t.Fatalf("Expected the account in the lockouts, got %d %+v", w.Code, lockouts)
+	}
+
+	unlock := authService.RequireAuth(handleUnlockUser(authService))
+	if w := adminRequest(unlock, http.MethodDelete, "/auth/users/9999/lockout"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d", w.Code)
+	}
+	if w := adminRequest(unlock, http.MethodDelete, "/auth/users/"+strconv.Itoa(user.ID)+"/lockout"); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := attempt("password123"); w.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed after the unlock, got %d: %s", w.Code, w.Body.String())
+	}
+
+	remove := authService.RequireAuth(handleLockouts(authService))
+	if w := adminRequest(remove, http.MethodDelete, "/auth/lockouts/"+accountKey); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a lifted lockout, got %d", w.Code)
+	}
+
+	// Every attempt is in the audit log, failures under the client's IP
+	failures, _ := store.ListAuditEntries(ctx, storage.AuditFilter{Action: "user.login_failed", TargetID: strconv.Itoa(user.ID)})
+	reasons := map[string]int{}
+	for _, entry := range failures {
+		var changes map[string]struct{ After string }
+		json.Unmarshal(entry.Changes, &changes)
+		reasons[changes["reason"].After]++
+		if entry.ActorType != storage.ActorAnonymous || entry.IPAddress == "" {
+			t.Errorf("Expected an anonymous actor with an IP, got %+v", entry)
+		}
+	}
+	if reasons["invalid_credentials"] != 4 || reasons["throttled"] != 2 {
+		t.Errorf("Expected 4 invalid credentials and 2 throttled failures, got %v", reasons)
+	}
+	logins, _ := store.ListAuditEntries(ctx, storage.AuditFilter{Action: "user.login", TargetID: strconv.Itoa(user.ID)})
+	if len(logins) != 1 || logins[0].ActorID == nil || *logins[0].ActorID != user.ID {
+		t.Errorf("Expected one login taken by the user, got %+v", logins)
+	}
+}
+
+func TestAgentAPIKeyLockout(t *testing.T) {
+	store := createTestStoreForAgentTests(t)
+	authService := createTestAuthServiceForAgent(t, store)
+	handler := RequireAPIKey(machines.NewService(store), authService.Throttle())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	attempt := func(apiKey string) int {
+		req := httptest.NewRequest(http.MethodPost, "/agent/metrics", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i <= auth.AccountLockoutPolicy.FreeAttempts; i++ {
+		if code := attempt("invalid-key"); code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected 401, got %d", i+1, code)
+		}
+	}
+	if code := attempt("invalid-key"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected repeated invalid keys to get 429, got %d", code)
+	}
+
+	// A working agent on the same IP is not held back by the invalid key
+	owner, _ := store.CreateUser(context.Background(), "owner@example.com", "hash")
+	_, apiKey, err := machines.NewService(store).RegisterMachine(context.Background(), owner.ID, "web-1", "web-1", "")
+	if err != nil {
+		t.Fatalf("Failed to register machine: %v", err)
+	}
+	if code := attempt(apiKey); code != http.StatusOK {
+		t.Errorf("Expected a valid key to be accepted, got %d", code)
+	}
+
+	// Guessing with a new key every time is throttled per IP
+	code := http.StatusUnauthorized
+	for i := 0; i < auth.IPLockoutPolicy.FreeAttempts && code == http.StatusUnauthorized; i++ {
+		code = attempt(fmt.Sprintf("%03d-guess", i))
+	}
+	if code != http.StatusTooManyRequests {
+		t.Errorf("Expected guesses with changing keys to get 429, got %d", code)
+	}
+	if code := attempt(apiKey); code != http.StatusOK {
+		t.Errorf("Expected a valid key to be accepted from a throttled IP, got %d", code)
+	}
+}
@@ -6,21 +6,24 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
-// Context keys for machine and user data
+// Context keys for machine and organization data
 type contextKey string
 
 const (
 	machineIDKey contextKey = "machine_id"
-	userIDKey    contextKey = "user_id"
+	orgIDKey     contextKey = "org_id"
 	machineKey   contextKey = "machine"
 )
 
-// RequireAPIKey is middleware that validates API keys for agent requests
-func RequireAPIKey(machineService *machines.Service) func(http.Handler) http.Handler {
+// RequireAPIKey is middleware that validates API keys for agent requests. Clients sending
+// invalid keys are slowed down and locked out by the throttle.
+func RequireAPIKey(machineService *machines.Service, throttle *auth.LoginThrottle) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract API key from X-API-Key header
@@ -39,9 +42,18 @@ func RequireAPIKey(machineService *machines.Service) func(http.Handler) http.Han
 				return
 			}
 
-			// Authenticate machine using API key
+			// Authenticate machine using API key. Only failures are throttled, per key prefix and
+			// per client IP: the IP count stops guessing with ever-changing keys, and since valid
+			// keys skip the check, a shared IP never locks out a working agent.
 			machine, err := machineService.AuthenticateMachine(r.Context(), apiKey)
 			if err != nil {
+				keyKey := auth.APIKeyKey(auth.AttemptAgent, apiKey)
+				ipKey := auth.IPKey(auth.AttemptAgent, getRemoteIP(r))
+				r = anonymous(r)
+				if throttled(w, r, throttle, keyKey, ipKey) {
+					return
+				}
+				throttle.Fail(r.Context(), keyKey, ipKey)
 				log.Printf("Agent request rejected: invalid API key from %s", getRemoteIP(r))
 				http.Error(w, "Unauthorized: invalid API key", http.StatusUnauthorized)
 				return
@@ -50,13 +62,14 @@ func RequireAPIKey(machineService *machines.Service) func(http.Handler) http.Han
 			// Check if machine is revoked (we could add a "revoked" status in the future)
 			// For now, if the machine exists in the DB, it's valid
 
-			// Add machine ID and user ID to context
+			// Add machine ID and organization ID to context
 			ctx := context.WithValue(r.Context(), machineIDKey, machine.ID)
-			ctx = context.WithValue(ctx, userIDKey, machine.UserID)
+			ctx = context.WithValue(ctx, orgIDKey, machine.OrgID)
 			ctx = context.WithValue(ctx, machineKey, machine)
+			ctx = audit.WithActor(ctx, audit.Actor{Type: storage.ActorMachine, ID: machine.ID})
 
-			log.Printf("Agent authenticated: machine_id=%d, user_id=%d, machine_name=%s, remote_ip=%s",
-				machine.ID, machine.UserID, machine.Name, getRemoteIP(r))
+			log.Printf("Agent authenticated: machine_id=%d, org_id=%d, machine_name=%s, remote_ip=%s",
+				machine.ID, machine.OrgID, machine.Name, getRemoteIP(r))
 
 			// Call next handler with updated context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -70,10 +83,10 @@ func GetMachineIDFromContext(ctx context.Context) (int, bool) {
 	return machineID, ok
 }
 
-// GetUserIDFromContext retrieves the user ID from the request context
-func GetUserIDFromContext(ctx context.Context) (int, bool) {
-	userID, ok := ctx.Value(userIDKey).(int)
-	return userID, ok
+// GetOrgIDFromContext retrieves the ID of the organization owning the machine from the request context
+func GetOrgIDFromContext(ctx context.Context) (int, bool) {
+	orgID, ok := ctx.Value(orgIDKey).(int)
+	return orgID, ok
 }
 
 // GetMachineFromContext retrieves the full machine object from the request context
@@ -0,0 +1,142 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+)
+
+// SSO errors the web app's login page is sent back with, as its sso_error parameter
+const (
+	ssoErrorDenied         = "denied"          // the user or provider cancelled the login
+	ssoErrorInvalid        = "invalid"         // the callback did not match a login started by this browser
+	ssoErrorNotProvisioned = "not_provisioned" // no account, and automatic provisioning is off
+	ssoErrorFailed         = "failed"          // anything else; details are logged
+)
+
+// SSOConfigResponse tells the login page which sign-in methods to offer
+type SSOConfigResponse struct {
+	Enabled       bool   `json:"enabled"`
+	ProviderName  string `json:"provider_name,omitempty"`
+	PasswordLogin bool   `json:"password_login"`
+}
+
+// handleOIDC handles the public single sign-on endpoints:
+//
+//	GET /auth/oidc           sign-in methods on offer
+//	GET /auth/oidc/login     redirect to the provider; ?return_to= is the web app path to land on
+//	GET /auth/oidc/callback  the provider's redirect back, which signs the user in
+func handleOIDC(authService *auth.Service, appBaseURL string, accessTTL time.Duration, secureCookie bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		provider := authService.OIDCProvider()
+		action := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/auth/oidc"), "/")
+		if action == "" {
+			response := SSOConfigResponse{PasswordLogin: authService.PasswordLoginEnabled()}
+			if provider != nil {
+				response.Enabled = true
+				response.ProviderName = provider.Name()
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		if action != "login" && action != "callback" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		if provider == nil {
+			http.Error(w, "Single sign-on is not configured", http.StatusNotFound)
+			return
+		}
+
+		if action == "login" {
+			authURL, state, err := authService.BeginOIDCLogin(r.Context(), r.URL.Query().Get("return_to"))
+			if err != nil {
+				log.Printf("Failed to start single sign-on login: %v", err)
+				http.Error(w, "Single sign-on provider unavailable", http.StatusBadGateway)
+				return
+			}
+			auth.SetOIDCStateCookie(w, state, secureCookie)
+			http.Redirect(w, r, authURL, http.StatusFound)
+			return
+		}
+
+		handleOIDCCallback(w, r, authService, appBaseURL, accessTTL, secureCookie)
+	}
+}
+
+// handleOIDCCallback signs in the user the provider sent back, then redirects to the web app.
+// Failures go back to the login page with an sso_error parameter.
+func handleOIDCCallback(w http.ResponseWriter, r *http.Request, authService *auth.Service, appBaseURL string, accessTTL time.Duration, secureCookie bool) {
+	r = anonymous(r)
+	query := r.URL.Query()
+	loginFailed := func(reason string) {
+		authService.RecordLoginFailure(r.Context(), "", auth.LoginOIDC, reason)
+		http.Redirect(w, r, appBaseURL+"/login?sso_error="+reason, http.StatusFound)
+	}
+
+	validState := auth.CheckOIDCState(r, query.Get("state"))
+	auth.ClearOIDCStateCookie(w, secureCookie)
+
+	if providerErr := query.Get("error"); providerErr != "" {
+		log.Printf("Single sign-on provider returned %s: %s", providerErr, query.Get("error_description"))
+		loginFailed(ssoErrorDenied)
+		return
+	}
+	if !validState {
+		loginFailed(ssoErrorInvalid)
+		return
+	}
+
+	user, returnTo, err := authService.CompleteOIDCLogin(r.Context(), query.Get("state"), query.Get("code"))
+	if err != nil {
+		log.Printf("Single sign-on login failed: %v", err)
+		switch {
+		case errors.Is(err, auth.ErrOIDCUserNotProvisioned):
+			loginFailed(ssoErrorNotProvisioned)
+		case errors.Is(err, auth.ErrInvalidOIDCLogin):
+			loginFailed(ssoErrorInvalid)
+		default:
+			loginFailed(ssoErrorFailed)
+		}
+		return
+	}
+	if returnTo == "" {
+		returnTo = "/"
+	}
+
+	// Users with two-factor authentication still answer a challenge on the login page
+	if user.TOTPEnabled {
+		challenge, err := authService.CreateLoginChallenge(user)
+		if err != nil {
+			log.Printf("Failed to create login challenge: %v", err)
+			loginFailed(ssoErrorFailed)
+			return
+		}
+		params := url.Values{"challenge": {challenge}, "return_to": {returnTo}}
+		http.Redirect(w, r, appBaseURL+"/login?"+params.Encode(), http.StatusFound)
+		return
+	}
+
+	tokens, err := authService.Login(r.Context(), user, auth.LoginOIDC, auth.ClientFromRequest(r))
+	if err != nil {
+		log.Printf("Failed to create session: %v", err)
+		loginFailed(ssoErrorFailed)
+		return
+	}
+	setSessionCookies(w, tokens, accessTTL, secureCookie)
+
+	log.Printf("User %d signed in with single sign-on", user.ID)
+	http.Redirect(w, r, appBaseURL+returnTo, http.StatusFound)
+}
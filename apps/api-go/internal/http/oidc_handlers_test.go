@@ -0,0 +1,213 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/config"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// newTestIdP starts a stand-in OpenID Connect provider that issues ID tokens with the claims for
+// whatever code it is given, echoing the nonce of the last authorization URL
+func newTestIdP(t *testing.T, claims map[string]any) (*httptest.Server, func(authURL string)) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	var server *httptest.Server
+	var nonce string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		idClaims := map[string]any{"iss": server.URL, "aud": "lunasentri", "exp": time.Now().Add(time.Hour).Unix(), "nonce": nonce}
+		for k, v := range claims {
+			idClaims[k] = v
+		}
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1"})
+		payload, _ := json.Marshal(idClaims)
+		message := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(message))
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		json.NewEncoder(w).Encode(map[string]string{"id_token": message + "." + base64.RawURLEncoding.EncodeToString(signature)})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authorize := func(authURL string) {
+		u, _ := url.Parse(authURL)
+		nonce = u.Query().Get("nonce")
+	}
+	return server, authorize
+}
+
+func TestOIDCLoginFlow(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	ctx := context.Background()
+	authService.CreateUser(ctx, "owner@example.com", "password123")
+
+	idp, authorize := newTestIdP(t, map[string]any{"sub": "sub-1", "email": "sso@example.com", "email_verified": true})
+	provider, err := auth.NewOIDCProvider(&config.OIDCConfig{
+		IssuerURL:     idp.URL,
+		ClientID:      "lunasentri",
+		RedirectURL:   "http://api.example.com/auth/oidc/callback",
+		Scopes:        []string{"openid", "email"},
+		ProviderName:  "Test IdP",
+		EmailClaim:    "email",
+		AutoProvision: true,
+	})
+	if err != nil {
+		t.Fatalf("NewOIDCProvider failed: %v", err)
+	}
+	handler := handleOIDC(authService, "http://app.example.com", 15*time.Minute, false)
+	get := func(target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without a provider only the status endpoint answers
+	w := get("/auth/oidc")
+	var status SSOConfigResponse
+	json.NewDecoder(w.Body).Decode(&status)
+	if status.Enabled || !status.PasswordLogin {
+		t.Errorf("Unexpected status without a provider %+v", status)
+	}
+	if w := get("/auth/oidc/login"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a provider, got %d", w.Code)
+	}
+
+	authService.SetOIDCProvider(provider)
+	w = get("/auth/oidc")
+	json.NewDecoder(w.Body).Decode(&status)
+	if !status.Enabled || status.ProviderName != "Test IdP" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	// Start a login; the browser gets a state cookie and is sent to the provider
+	w = get("/auth/oidc/login?return_to=/machines")
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected 302, got %d: %s", w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+	if !strings.HasPrefix(location, idp.URL+"/authorize?") {
+		t.Fatalf("Expected a redirect to the provider, got %s", location)
+	}
+	stateCookie := sessionCookies(w)[auth.OIDCStateCookieName]
+	if stateCookie == nil {
+		t.Fatal("Expected a state cookie")
+	}
+	authorize(location)
+	locationURL, _ := url.Parse(location)
+	callback := "/auth/oidc/callback?code=abc&state=" + url.QueryEscape(locationURL.Query().Get("state"))
+
+	// Another browser cannot complete the login
+	w = get(callback)
+	if got := w.Header().Get("Location"); got != "http://app.example.com/login?sso_error=invalid" {
+		t.Errorf("Expected the login page with an error, got %s", got)
+	}
+
+	w = get(callback, stateCookie)
+	if got := w.Header().Get("Location"); got != "http://app.example.com/machines" {
+		t.Fatalf("Expected a redirect to the return path, got %d %s", w.Code, got)
+	}
+	cookies := sessionCookies(w)
+	if cookies[auth.CookieName] == nil || cookies[auth.RefreshCookieName] == nil {
+		t.Fatal("Expected session cookies")
+	}
+	if _, err := authService.ValidateSession(ctx, cookies[auth.CookieName].Value, ""); err != nil {
+		t.Errorf("Expected a valid session, got %v", err)
+	}
+	if user, err := store.GetUserByEmail(ctx, "sso@example.com"); err != nil || user.Role != storage.RoleViewer {
+		t.Errorf("Expected the user to be provisioned as a viewer, got %+v, %v", user, err)
+	}
+
+	// The state cannot be used twice
+	w = get(callback, stateCookie)
+	if got := w.Header().Get("Location"); got != "http://app.example.com/login?sso_error=invalid" {
+		t.Errorf("Expected a replayed callback to fail, got %s", got)
+	}
+}
+
+func TestPasswordLoginDisabledEndpoints(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	authService.CreateUser(context.Background(), "user@example.com", "password123")
+
+	provider, _ := auth.NewOIDCProvider(&config.OIDCConfig{IssuerURL: "https://idp.example.com", ClientID: "lunasentri",
+		RedirectURL: "http://api.example.com/auth/oidc/callback"})
+	authService.SetOIDCProvider(provider)
+	if err := authService.SetPasswordLogin(false); err != nil {
+		t.Fatalf("SetPasswordLogin failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    any
+	}{
+		{"login", handleLogin(authService, 15*time.Minute, false), LoginRequest{Email: "user@example.com", Password: "password123"}},
+		{"register", handleRegister(authService), RegisterRequest{Email: "new@example.com", Password: "password123"}},
+		{"forgot password", handleForgotPassword(authService, time.Hour, false), ForgotPasswordRequest{Email: "user@example.com"}},
+		{"reset password", handleResetPassword(authService), ResetPasswordRequest{Token: "token", Password: "password123"}},
+	}
+	for _, tt := range tests {
+		data, _ := json.Marshal(tt.body)
+		w := httptest.NewRecorder()
+		tt.handler(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data)))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for %s, got %d: %s", tt.name, w.Code, w.Body.String())
+		}
+	}
+}
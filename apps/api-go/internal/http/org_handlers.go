@@ -0,0 +1,336 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// OrganizationRequest represents an organization create or rename request
+type OrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+// SetOrgMemberRoleRequest represents an organization member role change request
+type SetOrgMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// CreateOrgInvitationRequest represents an organization invitation request
+type CreateOrgInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// CreateOrgInvitationResponse represents a created invitation, the only time its token is returned
+type CreateOrgInvitationResponse struct {
+	storage.OrgInvitation
+	Token string `json:"token"`
+	Link  string `json:"link,omitempty"` // set when an invitation URL is configured
+}
+
+// AcceptOrgInvitationRequest represents an invitation accept request
+type AcceptOrgInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// handleOrganizations handles GET /orgs and POST /orgs
+func handleOrganizations(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			orgs, err := authService.ListOrganizations(r.Context(), user.ID)
+			if err != nil {
+				log.Printf("Failed to list organizations for user %d: %v", user.ID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if err := json.NewEncoder(w).Encode(orgs); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		case "POST":
+			var req OrganizationRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			org, err := authService.CreateOrganization(r.Context(), user.ID, req.Name)
+			if err != nil {
+				writeOrgError(w, err)
+				return
+			}
+			log.Printf("User %d created organization %d (%s)", user.ID, org.ID, org.Name)
+
+			w.WriteHeader(http.StatusCreated)
+			if err := json.NewEncoder(w).Encode(storage.UserOrganization{Organization: *org, Role: storage.RoleOwner}); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleOrganization handles the /orgs/ subtree: /orgs/{id}, /orgs/{id}/members,
+// /orgs/{id}/members/{userID}, /orgs/{id}/members/{userID}/role, /orgs/{id}/invitations,
+// /orgs/{id}/invitations/{invitationID} and /orgs/invitations/accept
+func handleOrganization(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/orgs/"), "/")
+		if path == "invitations/accept" {
+			handleAcceptOrgInvitation(w, r, authService, user)
+			return
+		}
+
+		parts := strings.Split(path, "/")
+		orgID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+			return
+		}
+
+		// Acting on an organization always goes through the user's membership of it, so
+		// organizations the user does not belong to look the same as ones that do not exist
+		actor, err := authService.Membership(r.Context(), orgID, user.ID)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+
+		switch {
+		case len(parts) == 1:
+			handleOrganizationDetail(w, r, authService, actor)
+		case len(parts) == 2 && parts[1] == "members":
+			if r.Method != http.MethodGet {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			members, err := authService.ListOrgMembers(r.Context(), actor.OrgID)
+			if err != nil {
+				writeOrgError(w, err)
+				return
+			}
+			json.NewEncoder(w).Encode(members)
+		case len(parts) == 3 && parts[1] == "members", len(parts) == 4 && parts[1] == "members" && parts[3] == "role":
+			memberID, err := strconv.Atoi(parts[2])
+			if err != nil {
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+			handleOrgMember(w, r, authService, actor, memberID, len(parts) == 4)
+		case len(parts) == 2 && parts[1] == "invitations":
+			handleOrgInvitations(w, r, authService, actor)
+		case len(parts) == 3 && parts[1] == "invitations":
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			invitationID, err := strconv.Atoi(parts[2])
+			if err != nil {
+				http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+				return
+			}
+			if err := authService.RevokeOrgInvitation(r.Context(), actor, invitationID); err != nil {
+				writeOrgError(w, err)
+				return
+			}
+			log.Printf("User %d revoked invitation %d of organization %d", user.ID, invitationID, actor.OrgID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}
+}
+
+// handleOrganizationDetail handles GET, PUT and DELETE /orgs/{id}
+func handleOrganizationDetail(w http.ResponseWriter, r *http.Request, authService *auth.Service, actor *storage.OrgMember) {
+	switch r.Method {
+	case "GET":
+		orgs, err := authService.ListOrganizations(r.Context(), actor.UserID)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		for _, org := range orgs {
+			if org.ID == actor.OrgID {
+				json.NewEncoder(w).Encode(org)
+				return
+			}
+		}
+		http.Error(w, "Organization not found", http.StatusNotFound)
+
+	case "PUT":
+		var req OrganizationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		org, err := authService.RenameOrganization(r.Context(), actor, req.Name)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		log.Printf("User %d renamed organization %d to %s", actor.UserID, org.ID, org.Name)
+
+		json.NewEncoder(w).Encode(storage.UserOrganization{Organization: *org, Role: actor.Role})
+
+	case "DELETE":
+		if err := authService.DeleteOrganization(r.Context(), actor); err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		log.Printf("User %d deleted organization %d", actor.UserID, actor.OrgID)
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOrgMember handles DELETE /orgs/{id}/members/{userID} and PUT /orgs/{id}/members/{userID}/role
+func handleOrgMember(w http.ResponseWriter, r *http.Request, authService *auth.Service, actor *storage.OrgMember, userID int, role bool) {
+	switch {
+	case role && r.Method == http.MethodPut:
+		var req SetOrgMemberRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		member, err := authService.SetOrgMemberRole(r.Context(), actor, userID, req.Role)
+		if err != nil {
+			log.Printf("Failed to set role of user %d in organization %d: %v", userID, actor.OrgID, err)
+			writeOrgError(w, err)
+			return
+		}
+		log.Printf("User %d changed the role of user %d in organization %d to %s", actor.UserID, userID, actor.OrgID, member.Role)
+
+		json.NewEncoder(w).Encode(member)
+
+	case !role && r.Method == http.MethodDelete:
+		if err := authService.RemoveOrgMember(r.Context(), actor, userID); err != nil {
+			log.Printf("Failed to remove user %d from organization %d: %v", userID, actor.OrgID, err)
+			writeOrgError(w, err)
+			return
+		}
+		log.Printf("User %d removed user %d from organization %d", actor.UserID, userID, actor.OrgID)
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOrgInvitations handles GET and POST /orgs/{id}/invitations
+func handleOrgInvitations(w http.ResponseWriter, r *http.Request, authService *auth.Service, actor *storage.OrgMember) {
+	switch r.Method {
+	case "GET":
+		invitations, err := authService.ListOrgInvitations(r.Context(), actor.OrgID)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(invitations)
+
+	case "POST":
+		var req CreateOrgInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		invitation, token, err := authService.InviteToOrganization(r.Context(), actor, req.Email, req.Role)
+		if err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		log.Printf("User %d invited %s to organization %d as %s", actor.UserID, invitation.Email, actor.OrgID, invitation.Role)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreateOrgInvitationResponse{
+			OrgInvitation: *invitation,
+			Token:         token,
+			Link:          authService.InvitationLink(token),
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAcceptOrgInvitation handles POST /orgs/invitations/accept
+func handleAcceptOrgInvitation(w http.ResponseWriter, r *http.Request, authService *auth.Service, user *storage.User) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req AcceptOrgInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invitation token required", http.StatusBadRequest)
+		return
+	}
+
+	member, err := authService.AcceptOrgInvitation(r.Context(), user, req.Token)
+	if err != nil {
+		log.Printf("User %d failed to accept an invitation: %v", user.ID, err)
+		writeOrgError(w, err)
+		return
+	}
+	log.Printf("User %d joined organization %d as %s", user.ID, member.OrgID, member.Role)
+
+	json.NewEncoder(w).Encode(member)
+}
+
+// writeOrgError writes the response for a failed organization request
+func writeOrgError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, auth.ErrOrgForbidden), errors.Is(err, auth.ErrRoleForbidden),
+		strings.Contains(err.Error(), "cannot change your own role"),
+		strings.Contains(err.Error(), "cannot remove the last owner"),
+		strings.Contains(err.Error(), "cannot delete a personal organization"),
+		strings.Contains(err.Error(), "different email address"):
+		status = http.StatusForbidden
+	case strings.Contains(err.Error(), "not found"):
+		status = http.StatusNotFound
+	case strings.Contains(err.Error(), "already a member"):
+		status = http.StatusConflict
+	case strings.Contains(err.Error(), "failed to"):
+		log.Printf("Organization request failed: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,127 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestOrganizationEndpoints(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	machineService := machines.NewService(store)
+	ctx := context.Background()
+
+	owner, _, _ := authService.CreateUser(ctx, "owner@example.com", "password123")
+	invitee, _, _ := authService.CreateUser(ctx, "invitee@example.com", "password123")
+	cookieFor := func(user *storage.User) *http.Cookie {
+		session, _ := authService.CreateSession(ctx, user.ID, auth.ClientInfo{})
+		return &http.Cookie{Name: auth.CookieName, Value: session.AccessToken}
+	}
+	ownerCookie, inviteeCookie := cookieFor(owner), cookieFor(invitee)
+
+	orgs := authService.RequireAuth(handleOrganizations(authService))
+	org := authService.RequireAuth(handleOrganization(authService))
+	list := authService.RequireAuth(handleListMachines(machineService))
+	serve := func(handler http.Handler, cookie *http.Cookie, method, target string, body any, orgID int) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(payload))
+		req.AddCookie(cookie)
+		if orgID != 0 {
+			req.Header.Set(auth.OrgHeader, strconv.Itoa(orgID))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(orgs, ownerCookie, http.MethodPost, "/orgs", OrganizationRequest{Name: "Platform"}, 0)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating an organization, got %d: %s", w.Code, w.Body.String())
+	}
+	var created storage.UserOrganization
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Name != "Platform" || created.Role != storage.RoleOwner {
+		t.Fatalf("Unexpected organization: %+v", created)
+	}
+	base := fmt.Sprintf("/orgs/%d", created.ID)
+
+	// Machines registered in the organization are shared with its members
+	if _, _, err := machineService.RegisterMachine(ctx, created.ID, "web-1", "web-1", ""); err != nil {
+		t.Fatalf("Failed to register machine: %v", err)
+	}
+
+	// Organizations the user does not belong to look like they do not exist
+	if w := serve(org, inviteeCookie, http.MethodGet, base, nil, 0); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a non-member, got %d", w.Code)
+	}
+	if w := serve(list, inviteeCookie, http.MethodGet, "/machines", nil, created.ID); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 selecting an organization the user is not a member of, got %d", w.Code)
+	}
+
+	w = serve(org, ownerCookie, http.MethodPost, base+"/invitations", CreateOrgInvitationRequest{Email: invitee.Email, Role: storage.RoleViewer}, 0)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 inviting a member, got %d: %s", w.Code, w.Body.String())
+	}
+	var invitation CreateOrgInvitationResponse
+	json.NewDecoder(w.Body).Decode(&invitation)
+	if invitation.Token == "" {
+		t.Fatalf("Expected the invitation token in the response, got %+v", invitation)
+	}
+
+	if w := serve(org, inviteeCookie, http.MethodPost, "/orgs/invitations/accept", AcceptOrgInvitationRequest{Token: invitation.Token}, 0); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 accepting the invitation, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(list, inviteeCookie, http.MethodGet, "/machines", nil, created.ID)
+	var machinesList []storage.Machine
+	json.NewDecoder(w.Body).Decode(&machinesList)
+	if w.Code != http.StatusOK || len(machinesList) != 1 {
+		t.Errorf("Expected the new member to see the organization's machine, got %d: %+v", w.Code, machinesList)
+	}
+
+	// Viewers cannot manage the organization
+	if w := serve(org, inviteeCookie, http.MethodPut, base, OrganizationRequest{Name: "Mine"}, 0); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer renaming the organization, got %d", w.Code)
+	}
+
+	w = serve(org, ownerCookie, http.MethodGet, base+"/members", nil, 0)
+	var members []storage.OrgMember
+	json.NewDecoder(w.Body).Decode(&members)
+	if len(members) != 2 {
+		t.Fatalf("Expected 2 members, got %+v", members)
+	}
+
+	target := fmt.Sprintf("%s/members/%d/role", base, invitee.ID)
+	if w := serve(org, ownerCookie, http.MethodPut, target, SetOrgMemberRoleRequest{Role: storage.RoleAdmin}, 0); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 changing a member's role, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(org, ownerCookie, http.MethodDelete, fmt.Sprintf("%s/members/%d", base, owner.ID), nil, 0); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 removing the last owner, got %d", w.Code)
+	}
+
+	if w := serve(org, ownerCookie, http.MethodDelete, base, nil, 0); w.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting the organization, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,134 @@
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+)
+
+// SessionResponse represents a signed-in device in the sessions list
+type SessionResponse struct {
+	ID         int       `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // the session making the request
+}
+
+// handleSessions handles GET /auth/sessions and DELETE /auth/sessions. DELETE signs the user out
+// everywhere, or only on their other devices with ?others=true.
+func handleSessions(authService *auth.Service, secureCookie bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		currentID, _ := auth.GetSessionIDFromContext(r.Context())
+
+		switch r.Method {
+		case "GET":
+			sessions, err := authService.ListSessions(r.Context(), user.ID)
+			if err != nil {
+				log.Printf("Failed to list sessions for user %d: %v", user.ID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			response := make([]SessionResponse, len(sessions))
+			for i, session := range sessions {
+				response[i] = SessionResponse{
+					ID:         session.ID,
+					Device:     session.Device,
+					UserAgent:  session.UserAgent,
+					IPAddress:  session.IPAddress,
+					CreatedAt:  session.CreatedAt,
+					LastUsedAt: session.LastUsedAt,
+					ExpiresAt:  session.ExpiresAt,
+					Current:    session.ID == currentID,
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(response); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		case "DELETE":
+			keepID := 0
+			if r.URL.Query().Get("others") == "true" {
+				keepID = currentID
+			}
+
+			revoked, err := authService.RevokeUserSessions(r.Context(), user.ID, keepID)
+			if err != nil {
+				log.Printf("Failed to revoke sessions for user %d: %v", user.ID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			log.Printf("Revoked %d sessions of user %d", revoked, user.ID)
+
+			if keepID == 0 {
+				clearSessionCookies(w, secureCookie)
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleRevokeSession handles DELETE /auth/sessions/{id}
+func handleRevokeSession(authService *auth.Service, secureCookie bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Extract ID from path
+		path := strings.TrimPrefix(r.URL.Path, "/auth/sessions/")
+		if path == "" {
+			http.Error(w, "Session ID required", http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.Atoi(path)
+		if err != nil {
+			http.Error(w, "Invalid session ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := authService.RevokeSession(r.Context(), user.ID, id); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				http.Error(w, "Session not found", http.StatusNotFound)
+			} else {
+				log.Printf("Failed to revoke session %d: %v", id, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// Revoking the current session signs this device out
+		if currentID, _ := auth.GetSessionIDFromContext(r.Context()); currentID == id {
+			clearSessionCookies(w, secureCookie)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,173 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// sessionCookies returns the session cookies a response set, by name
+func sessionCookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	return cookies
+}
+
+func TestSessionEndpoints(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	if _, _, err := authService.CreateUser(context.Background(), "user@example.com", "password123"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	login := func(userAgent string) map[string]*http.Cookie {
+		body, _ := json.Marshal(LoginRequest{Email: "user@example.com", Password: "password123"})
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(body))
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		handleLogin(authService, 15*time.Minute, false)(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected login to succeed, got %d", w.Code)
+		}
+		cookies := sessionCookies(w)
+		if cookies[auth.CookieName] == nil || cookies[auth.RefreshCookieName] == nil {
+			t.Fatalf("Expected session and refresh cookies, got %v", cookies)
+		}
+		return cookies
+	}
+	serve := func(handler http.Handler, method, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	sessions := authService.RequireAuth(handleSessions(authService, false))
+	revoke := authService.RequireAuth(handleRevokeSession(authService, false))
+	refresh := handleRefresh(authService, 15*time.Minute, false)
+
+	laptop := login("Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
+	phone := login("curl/8.5.0")
+
+	// Both devices are listed, the caller's marked as current
+	w := serve(sessions, http.MethodGet, "/auth/sessions", laptop[auth.CookieName])
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 listing sessions, got %d", w.Code)
+	}
+	var list []SessionResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(list))
+	}
+	var phoneID int
+	for _, s := range list {
+		if s.Current != (s.Device == "Firefox on Linux") {
+			t.Errorf("Expected only the laptop session to be current, got %+v", s)
+		}
+		if s.Device == "curl" {
+			phoneID = s.ID
+		}
+	}
+
+	// Refreshing rotates both cookies
+	w = serve(refresh, http.MethodPost, "/auth/refresh", phone[auth.RefreshCookieName])
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 refreshing, got %d", w.Code)
+	}
+	refreshed := sessionCookies(w)
+	if refreshed[auth.RefreshCookieName].Value == phone[auth.RefreshCookieName].Value {
+		t.Error("Expected a new refresh token")
+	}
+
+	// Revoking the phone from the laptop signs the phone out at once
+	w = serve(revoke, http.MethodDelete, fmt.Sprintf("/auth/sessions/%d", phoneID), laptop[auth.CookieName])
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 revoking, got %d", w.Code)
+	}
+	if w := serve(sessions, http.MethodGet, "/auth/sessions", refreshed[auth.CookieName]); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked session's access token to be rejected, got %d", w.Code)
+	}
+	if w := serve(refresh, http.MethodPost, "/auth/refresh", refreshed[auth.RefreshCookieName]); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked session's refresh token to be rejected, got %d", w.Code)
+	}
+	if w := serve(revoke, http.MethodDelete, fmt.Sprintf("/auth/sessions/%d", phoneID), laptop[auth.CookieName]); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking a revoked session, got %d", w.Code)
+	}
+
+	// Logging out ends the session server-side, not just the cookie
+	w = serve(handleLogout(authService, false), http.MethodPost, "/auth/logout", laptop[auth.CookieName], laptop[auth.RefreshCookieName])
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 logging out, got %d", w.Code)
+	}
+	if w := serve(sessions, http.MethodGet, "/auth/sessions", laptop[auth.CookieName]); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the logged out session's access token to be rejected, got %d", w.Code)
+	}
+}
+
+func TestSessionEndpoints_LogoutEverywhere(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	ctx := context.Background()
+	user, _, _ := authService.CreateUser(ctx, "user@example.com", "password123")
+
+	current, _ := authService.CreateSession(ctx, user.ID, auth.ClientInfo{})
+	other, _ := authService.CreateSession(ctx, user.ID, auth.ClientInfo{})
+	sessions := authService.RequireAuth(handleSessions(authService, false))
+
+	// ?others=true keeps the caller signed in
+	req := httptest.NewRequest(http.MethodDelete, "/auth/sessions?others=true", nil)
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: current.AccessToken})
+	w := httptest.NewRecorder()
+	sessions.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	if _, err := authService.ValidateSession(ctx, other.AccessToken, ""); err == nil {
+		t.Error("Expected the other session to be revoked")
+	}
+	if _, err := authService.ValidateSession(ctx, current.AccessToken, ""); err != nil {
+		t.Errorf("Expected the current session to remain: %v", err)
+	}
+
+	// Without it every session ends, including the caller's
+	req = httptest.NewRequest(http.MethodDelete, "/auth/sessions", nil)
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: current.AccessToken})
+	w = httptest.NewRecorder()
+	sessions.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", w.Code)
+	}
+	if _, err := authService.ValidateSession(ctx, current.AccessToken, ""); err == nil {
+		t.Error("Expected the current session to be revoked")
+	}
+	if c := sessionCookies(w)[auth.CookieName]; c == nil || c.MaxAge >= 0 {
+		t.Error("Expected the session cookie to be cleared")
+	}
+}
@@ -0,0 +1,205 @@
+package router
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/silences"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// SilenceRequest represents a silence create/update request
+type SilenceRequest struct {
+	RuleID          *int       `json:"rule_id"`    // null matches every rule
+	MachineID       *int       `json:"machine_id"` // null matches every machine
+	Tag             string     `json:"tag"`        // only machines with this tag, empty for any
+	Reason          string     `json:"reason"`
+	StartsAt        *time.Time `json:"starts_at"`        // defaults to now
+	EndsAt          *time.Time `json:"ends_at"`          // required for one-off silences
+	Schedule        string     `json:"schedule"`         // cron expression starting each recurring window
+	DurationSeconds int        `json:"duration_seconds"` // length of each recurring window
+	Timezone        string     `json:"timezone"`         // timezone for the schedule, defaults to UTC
+}
+
+// silence converts the request into a silence starting now unless starts_at is set
+func (req *SilenceRequest) silence() storage.Silence {
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+
+	return storage.Silence{
+		RuleID:          req.RuleID,
+		MachineID:       req.MachineID,
+		Tag:             req.Tag,
+		Reason:          strings.TrimSpace(req.Reason),
+		StartsAt:        startsAt,
+		EndsAt:          req.EndsAt,
+		Schedule:        strings.TrimSpace(req.Schedule),
+		DurationSeconds: req.DurationSeconds,
+		Timezone:        req.Timezone,
+	}
+}
+
+// handleSilences handles GET /silences and POST /silences
+func handleSilences(silenceService *silences.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			list, err := silenceService.List(r.Context(), org.OrgID)
+			if err != nil {
+				log.Printf("Failed to list silences: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if err := json.NewEncoder(w).Encode(list); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		case "POST":
+			var req SilenceRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			silence := req.silence()
+			if err := silenceService.Validate(r.Context(), org.OrgID, &silence); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			created, err := silenceService.Create(r.Context(), org.OrgID, user.ID, silence)
+			if err != nil {
+				log.Printf("Failed to create silence: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			if err := json.NewEncoder(w).Encode(created); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// handleSilence handles GET, PUT and DELETE /silences/{id}
+func handleSilence(silenceService *silences.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Extract ID from path
+		path := strings.TrimPrefix(r.URL.Path, "/silences/")
+		if path == "" {
+			http.Error(w, "Silence ID required", http.StatusBadRequest)
+			return
+		}
+
+		id, err := strconv.Atoi(path)
+		if err != nil {
+			http.Error(w, "Invalid silence ID", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case "GET":
+			silence, err := silenceService.Get(r.Context(), org.OrgID, id)
+			if err != nil {
+				if strings.Contains(err.Error(), "not found") {
+					http.Error(w, "Silence not found", http.StatusNotFound)
+				} else {
+					log.Printf("Failed to get silence: %v", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			if err := json.NewEncoder(w).Encode(silence); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		case "PUT":
+			var req SilenceRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			silence := req.silence()
+			silence.ID = id
+			if err := silenceService.Validate(r.Context(), org.OrgID, &silence); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			updated, err := silenceService.Update(r.Context(), org.OrgID, user.ID, silence)
+			if err != nil {
+				if strings.Contains(err.Error(), "not found") {
+					http.Error(w, "Silence not found", http.StatusNotFound)
+				} else {
+					log.Printf("Failed to update silence: %v", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			if err := json.NewEncoder(w).Encode(updated); err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+		case "DELETE":
+			if err := silenceService.Delete(r.Context(), org.OrgID, user.ID, id); err != nil {
+				if strings.Contains(err.Error(), "not found") {
+					http.Error(w, "Silence not found", http.StatusNotFound)
+				} else {
+					log.Printf("Failed to delete silence: %v", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
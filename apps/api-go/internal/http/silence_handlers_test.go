@@ -0,0 +1,118 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/silences"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// silenceRequest sends a request to a silence handler as user acting in the organization orgID
+func silenceRequest(t *testing.T, handler http.HandlerFunc, user *storage.User, orgID int, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req = req.WithContext(context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: orgID, UserID: user.ID, Role: storage.RoleOwner}))
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestSilenceHandlers(t *testing.T) {
+	store := createTestStoreForAgentTests(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	editor, _ := store.CreateUser(ctx, "editor@example.com", "hash")
+	service := silences.NewService(store)
+
+	// Patch night: every Tuesday from 02:00 for three hours
+	w := silenceRequest(t, handleSilences(service), user, user.ID, http.MethodPost, "/silences", SilenceRequest{
+		Tag:             "database",
+		Reason:          "patch night",
+		Schedule:        "0 2 * * 2",
+		DurationSeconds: 3 * 3600,
+		Timezone:        "Europe/Berlin",
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var created storage.Silence
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.CreatedBy != user.ID || created.Schedule != "0 2 * * 2" || created.StartsAt.IsZero() {
+		t.Errorf("Unexpected silence: %+v", created)
+	}
+
+	// A one-off silence needs an end
+	w = silenceRequest(t, handleSilences(service), user, user.ID, http.MethodPost, "/silences", SilenceRequest{Reason: "deploy"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without ends_at, got %d", w.Code)
+	}
+
+	w = silenceRequest(t, handleSilences(service), user, user.ID, http.MethodGet, "/silences", nil)
+	var list []storage.Silence
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("Expected one silence, got %d: %s", w.Code, w.Body.String())
+	}
+
+	path := fmt.Sprintf("/silences/%d", created.ID)
+	end := time.Now().Add(time.Hour)
+
+	// Other organizations cannot see or change the silence
+	w = silenceRequest(t, handleSilences(service), editor, editor.ID, http.MethodGet, "/silences", nil)
+	list = nil
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list) != 0 {
+		t.Errorf("Expected no silences for another organization, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+		w = silenceRequest(t, handleSilence(service), editor, editor.ID, method, path, SilenceRequest{Reason: "deploy", EndsAt: &end})
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %s from another organization, got %d", method, w.Code)
+		}
+	}
+
+	w = silenceRequest(t, handleSilence(service), editor, user.ID, http.MethodPut, path, SilenceRequest{Reason: "deploy", EndsAt: &end})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var updated storage.Silence
+	json.NewDecoder(w.Body).Decode(&updated)
+	if updated.Recurring() || updated.CreatedBy != user.ID || updated.UpdatedBy == nil || *updated.UpdatedBy != editor.ID {
+		t.Errorf("Unexpected silence after update: %+v", updated)
+	}
+
+	w = silenceRequest(t, handleSilence(service), user, user.ID, http.MethodDelete, path, nil)
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", w.Code)
+	}
+	w = silenceRequest(t, handleSilence(service), user, user.ID, http.MethodGet, path, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after delete, got %d", w.Code)
+	}
+
+	// Each change is audited once, in the organization, newest first
+	entries, _ := store.ListAuditEntries(ctx, storage.AuditFilter{OrgID: &user.ID, Action: "silence."})
+	var actions []string
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	if strings.Join(actions, ",") != "silence.delete,silence.update,silence.create" {
+		t.Errorf("Expected one entry per change, got %v", actions)
+	}
+}
@@ -0,0 +1,235 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+)
+
+// TwoFactorStatusResponse represents the user's two-factor authentication state
+type TwoFactorStatusResponse struct {
+	Enabled                bool   `json:"enabled"`
+	Pending                bool   `json:"pending"`  // set up but not confirmed with a code yet
+	Required               bool   `json:"required"` // the policy requires it for this user
+	RecoveryCodesRemaining int    `json:"recovery_codes_remaining"`
+	Policy                 string `json:"policy"`
+}
+
+// TwoFactorSetupResponse represents a new authenticator app secret
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to show as a QR code
+}
+
+// TwoFactorCodeRequest represents a request confirmed with an authenticator app or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// DisableTwoFactorRequest represents the request to turn off two-factor authentication
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// RecoveryCodesResponse represents newly issued recovery codes, returned only this once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorPolicyRequest represents the two-factor policy
+type TwoFactorPolicyRequest struct {
+	Policy string `json:"policy"` // "optional" | "admins" | "all"
+}
+
+// handleTwoFactor handles the /auth/2fa endpoints:
+//
+//	GET    /auth/2fa                 two-factor status
+//	DELETE /auth/2fa                 turn two-factor authentication off
+//	POST   /auth/2fa/setup           generate an authenticator app secret
+//	POST   /auth/2fa/enable          confirm the secret with a code, returning recovery codes
+//	POST   /auth/2fa/recovery-codes  replace the recovery codes
+//	GET    /auth/2fa/policy          who must use two-factor authentication
+//	PUT    /auth/2fa/policy          change the policy (users:admin)
+func handleTwoFactor(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch action := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/auth/2fa"), "/"); {
+		case action == "" && r.Method == http.MethodGet:
+			status, err := authService.TwoFactorStatus(r.Context(), user)
+			if err != nil {
+				log.Printf("Failed to get two-factor status of user %d: %v", user.ID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			policy, _ := authService.TwoFactorPolicy(r.Context())
+
+			json.NewEncoder(w).Encode(TwoFactorStatusResponse{
+				Enabled:                status.Enabled,
+				Pending:                status.Pending,
+				Required:               status.Required,
+				RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+				Policy:                 policy,
+			})
+
+		case action == "" && r.Method == http.MethodDelete:
+			var req DisableTwoFactorRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			if err := authService.DisableTOTP(r.Context(), user.ID, req.Password, req.Code); err != nil {
+				writeTwoFactorError(w, user.ID, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case action == "setup" && r.Method == http.MethodPost:
+			enrollment, err := authService.BeginTOTPEnrollment(r.Context(), user.ID)
+			if err != nil {
+				writeTwoFactorError(w, user.ID, err)
+				return
+			}
+
+			json.NewEncoder(w).Encode(TwoFactorSetupResponse{
+				Secret:          enrollment.Secret,
+				ProvisioningURI: enrollment.ProvisioningURI,
+			})
+
+		case action == "enable" && r.Method == http.MethodPost:
+			var req TwoFactorCodeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			codes, err := authService.EnableTOTP(r.Context(), user.ID, req.Code)
+			if err != nil {
+				writeTwoFactorError(w, user.ID, err)
+				return
+			}
+			json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+
+		case action == "recovery-codes" && r.Method == http.MethodPost:
+			var req TwoFactorCodeRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			codes, err := authService.RegenerateRecoveryCodes(r.Context(), user.ID, req.Code)
+			if err != nil {
+				writeTwoFactorError(w, user.ID, err)
+				return
+			}
+			json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+
+		case action == "policy" && r.Method == http.MethodGet:
+			policy, err := authService.TwoFactorPolicy(r.Context())
+			if err != nil {
+				log.Printf("Failed to get two-factor policy: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(TwoFactorPolicyRequest{Policy: policy})
+
+		case action == "policy" && r.Method == http.MethodPut:
+			var req TwoFactorPolicyRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid JSON", http.StatusBadRequest)
+				return
+			}
+
+			if err := authService.SetTwoFactorPolicy(r.Context(), req.Policy); err != nil {
+				if strings.Contains(err.Error(), "unknown two-factor policy") {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				} else {
+					log.Printf("Failed to set two-factor policy: %v", err)
+					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				}
+				return
+			}
+			log.Printf("User %d set the two-factor policy to %s", user.ID, req.Policy)
+			json.NewEncoder(w).Encode(req)
+
+		case action == "" || action == "setup" || action == "enable" || action == "recovery-codes" || action == "policy":
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}
+}
+
+// handleResetTwoFactor handles DELETE /auth/users/{id}/2fa, turning off another user's two-factor
+// authentication when they have lost their authenticator app and recovery codes
+func handleResetTwoFactor(authService *auth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Extract user ID from URL path
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/auth/users/"), "/2fa")
+		userID, err := strconv.Atoi(path)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		currentUser, ok := auth.GetUserFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if err := authService.ResetTwoFactor(r.Context(), currentUser, userID); err != nil {
+			writeTwoFactorError(w, currentUser.ID, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeTwoFactorError writes the response for a failed two-factor operation
+func writeTwoFactorError(w http.ResponseWriter, userID int, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, auth.ErrInvalidTwoFactorCode),
+		strings.Contains(err.Error(), "current password is incorrect"):
+		status = http.StatusUnauthorized
+	case errors.Is(err, auth.ErrRoleForbidden),
+		errors.Is(err, auth.ErrTwoFactorRequired),
+		strings.Contains(err.Error(), "cannot reset your own"):
+		status = http.StatusForbidden
+	case errors.Is(err, auth.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, auth.ErrTwoFactorNotEnabled),
+		strings.Contains(err.Error(), "no pending two-factor enrolment"):
+		status = http.StatusConflict
+	case strings.Contains(err.Error(), "user not found"):
+		status = http.StatusNotFound
+	default:
+		log.Printf("Two-factor operation failed for user %d: %v", userID, err)
+		http.Error(w, "Internal Server Error", status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,169 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestTwoFactorLogin(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	ctx := context.Background()
+	user, _, _ := authService.CreateUser(ctx, "user@example.com", "password123")
+	session, _ := authService.CreateSession(ctx, user.ID, auth.ClientInfo{})
+	sessionCookie := &http.Cookie{Name: auth.CookieName, Value: session.AccessToken}
+
+	twoFactor := authService.RequireAuth(handleTwoFactor(authService))
+	login := handleLogin(authService, 15*time.Minute, false)
+	post := func(handler http.Handler, target string, body any, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Enrol
+	w := post(twoFactor, "/auth/2fa/setup", nil, sessionCookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 starting setup, got %d: %s", w.Code, w.Body.String())
+	}
+	var setup TwoFactorSetupResponse
+	json.NewDecoder(w.Body).Decode(&setup)
+
+	code, _ := auth.TOTPCode(setup.Secret, time.Now())
+	w = post(twoFactor, "/auth/2fa/enable", TwoFactorCodeRequest{Code: code}, sessionCookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 enabling, got %d: %s", w.Code, w.Body.String())
+	}
+	var recovery RecoveryCodesResponse
+	json.NewDecoder(w.Body).Decode(&recovery)
+	if len(recovery.RecoveryCodes) != auth.RecoveryCodeCount {
+		t.Fatalf("Expected %d recovery codes, got %d", auth.RecoveryCodeCount, len(recovery.RecoveryCodes))
+	}
+
+	// The password alone now only gets a challenge, without session cookies
+	w = post(login, "/auth/login", LoginRequest{Email: "user@example.com", Password: "password123"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with a challenge, got %d", w.Code)
+	}
+	if cookies := sessionCookies(w); cookies[auth.CookieName] != nil {
+		t.Fatal("Expected no session cookie before the second step")
+	}
+	var challenge LoginChallengeResponse
+	json.NewDecoder(w.Body).Decode(&challenge)
+	if !challenge.TwoFactorRequired || challenge.Challenge == "" {
+		t.Fatalf("Expected a two-factor challenge, got %+v", challenge)
+	}
+
+	// A wrong code is refused
+	w = post(login, "/auth/login", LoginRequest{Challenge: challenge.Challenge, Code: "000000"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for a wrong code, got %d", w.Code)
+	}
+
+	// A recovery code completes the login
+	w = post(login, "/auth/login", LoginRequest{Challenge: challenge.Challenge, Code: recovery.RecoveryCodes[0]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 completing the login, got %d: %s", w.Code, w.Body.String())
+	}
+	if cookies := sessionCookies(w); cookies[auth.CookieName] == nil {
+		t.Fatal("Expected a session cookie after the second step")
+	}
+	var profile UserProfile
+	json.NewDecoder(w.Body).Decode(&profile)
+	if !profile.TOTPEnabled {
+		t.Error("Expected the profile to show two-factor authentication enabled")
+	}
+
+	// The code can also be sent along with the password
+	w = post(login, "/auth/login", LoginRequest{Email: "user@example.com", Password: "password123", Code: recovery.RecoveryCodes[1]})
+	if w.Code != http.StatusOK || sessionCookies(w)[auth.CookieName] == nil {
+		t.Fatalf("Expected a one-step login to succeed, got %d", w.Code)
+	}
+}
+
+func TestTwoFactorPolicyEnforcement(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	defer store.Close()
+	authService, err := auth.NewService(store, "test-secret", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create auth service: %v", err)
+	}
+	ctx := context.Background()
+	owner, _ := store.UpsertAdmin(ctx, "owner@example.com", "hash")
+	operator, _, _ := authService.CreateUser(ctx, "operator@example.com", "password123")
+	cookie := func(userID int) *http.Cookie {
+		session, _ := authService.CreateSession(ctx, userID, auth.ClientInfo{})
+		return &http.Cookie{Name: auth.CookieName, Value: session.AccessToken}
+	}
+	ownerCookie, operatorCookie := cookie(owner.ID), cookie(operator.ID)
+
+	twoFactor := authService.RequireAuth(handleTwoFactor(authService))
+	users := authService.RequireAuth(handleListUsers(authService))
+	serve := func(handler http.Handler, method, target string, body any, c *http.Cookie) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(data))
+		req.AddCookie(c)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Only user admins can change the policy
+	if w := serve(twoFactor, http.MethodPut, "/auth/2fa/policy", TwoFactorPolicyRequest{Policy: auth.TwoFactorAdmins}, operatorCookie); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for an operator, got %d", w.Code)
+	}
+	if w := serve(twoFactor, http.MethodPut, "/auth/2fa/policy", TwoFactorPolicyRequest{Policy: auth.TwoFactorAdmins}, ownerCookie); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 setting the policy, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The owner is held to setup, while the status endpoint says why
+	if w := serve(users, http.MethodGet, "/auth/users", nil, ownerCookie); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 until two-factor authentication is set up, got %d", w.Code)
+	}
+	w := serve(twoFactor, http.MethodGet, "/auth/2fa", nil, ownerCookie)
+	var status TwoFactorStatusResponse
+	json.NewDecoder(w.Body).Decode(&status)
+	if w.Code != http.StatusOK || !status.Required || status.Enabled || status.Policy != auth.TwoFactorAdmins {
+		t.Fatalf("Unexpected status %d %+v", w.Code, status)
+	}
+
+	w = serve(twoFactor, http.MethodPost, "/auth/2fa/setup", nil, ownerCookie)
+	var setup TwoFactorSetupResponse
+	json.NewDecoder(w.Body).Decode(&setup)
+	code, _ := auth.TOTPCode(setup.Secret, time.Now())
+	if w := serve(twoFactor, http.MethodPost, "/auth/2fa/enable", TwoFactorCodeRequest{Code: code}, ownerCookie); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 enabling, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(users, http.MethodGet, "/auth/users", nil, ownerCookie); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 once set up, got %d", w.Code)
+	}
+
+	// Required two-factor authentication cannot be turned off
+	if w := serve(twoFactor, http.MethodDelete, "/auth/2fa", DisableTwoFactorRequest{Code: code}, ownerCookie); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 disabling required two-factor authentication, got %d", w.Code)
+	}
+}
@@ -16,6 +16,9 @@ type HeartbeatStore interface {
 	RecordMachineOfflineNotification(ctx context.Context, machineID int, notifiedAt time.Time) error
 	GetMachineLastOfflineNotification(ctx context.Context, machineID int) (time.Time, error)
 	ClearMachineOfflineNotification(ctx context.Context, machineID int) error
+	HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error
+	MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error)
+	ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error
 }
 
 // HeartbeatNotifier defines the interface for sending heartbeat notifications
@@ -26,10 +29,17 @@ type HeartbeatNotifier interface {
 	NotifyMachineOnline(ctx context.Context, machine storage.Machine) error
 }
 
+// HeartbeatSilencer decides whether machine status notifications are currently silenced
+type HeartbeatSilencer interface {
+	// MachineSilenced reports whether notifications for the machine are silenced at the given time
+	MachineSilenced(ctx context.Context, machine storage.Machine, at time.Time) bool
+}
+
 // HeartbeatMonitor monitors machine heartbeats and triggers notifications
 type HeartbeatMonitor struct {
 	store            HeartbeatStore
 	notifier         HeartbeatNotifier
+	silencer         HeartbeatSilencer
 	logger           *log.Logger
 	checkInterval    time.Duration
 	offlineThreshold time.Duration
@@ -56,6 +66,16 @@ func NewHeartbeatMonitor(store HeartbeatStore, notifier HeartbeatNotifier, logge
 	}
 }
 
+// SetSilencer sets the silencer consulted before sending machine status notifications
+func (m *HeartbeatMonitor) SetSilencer(silencer HeartbeatSilencer) {
+	m.silencer = silencer
+}
+
+// silenced reports whether status notifications for a machine are currently silenced
+func (m *HeartbeatMonitor) silenced(ctx context.Context, machine *storage.Machine, now time.Time) bool {
+	return m.silencer != nil && m.silencer.MachineSilenced(ctx, *machine, now)
+}
+
 // Start begins the heartbeat monitoring loop in a background goroutine
 func (m *HeartbeatMonitor) Start(ctx context.Context) {
 	go m.run(ctx)
@@ -135,6 +155,17 @@ func (m *HeartbeatMonitor) checkMachine(ctx context.Context, machine *storage.Ma
 			m.logger.Printf("Warning: failed to clear offline notification for machine %d: %v", machine.ID, err)
 		}
 
+		// Nobody was told the machine went down, so there is nothing to recover from
+		if m.held(ctx, machine) {
+			m.release(ctx, machine)
+			m.logger.Printf("Machine %d (%s) recovered during a silence, skipping online notification", machine.ID, machine.Hostname)
+			return nil
+		}
+		if m.silenced(ctx, machine, now) {
+			m.logger.Printf("Machine %d (%s) is silenced, skipping online notification", machine.ID, machine.Hostname)
+			return nil
+		}
+
 		// Send recovery notification
 		if m.notifier != nil {
 			machine.Status = "online"
@@ -154,6 +185,15 @@ func (m *HeartbeatMonitor) checkMachine(ctx context.Context, machine *storage.Ma
 			return fmt.Errorf("failed to update machine status: %w", err)
 		}
 
+		// Hold the notification back while silenced; it is sent if the machine is still down afterwards
+		if m.silenced(ctx, machine, now) {
+			if err := m.store.HoldMachineOfflineNotification(ctx, machine.ID, now); err != nil {
+				m.logger.Printf("Warning: failed to hold offline notification for machine %d: %v", machine.ID, err)
+			}
+			m.logger.Printf("Machine %d (%s) is silenced, holding back offline notification", machine.ID, machine.Hostname)
+			return nil
+		}
+
 		// Check if we've already notified
 		lastNotified, err := m.store.GetMachineLastOfflineNotification(ctx, machine.ID)
 		if err != nil {
@@ -163,24 +203,19 @@ func (m *HeartbeatMonitor) checkMachine(ctx context.Context, machine *storage.Ma
 
 		// Only notify if we haven't notified recently (avoid duplicates)
 		if lastNotified.IsZero() || now.Sub(lastNotified) > m.offlineThreshold {
-			if m.notifier != nil {
-				machine.Status = "offline"
-				if err := m.notifier.NotifyMachineOffline(ctx, *machine); err != nil {
-					m.logger.Printf("Failed to send offline notification for machine %d: %v", machine.ID, err)
-				} else {
-					// Record that we sent the notification
-					if err := m.store.RecordMachineOfflineNotification(ctx, machine.ID, now); err != nil {
-						m.logger.Printf("Warning: failed to record offline notification for machine %d: %v", machine.ID, err)
-					}
-				}
-			}
+			m.notifyOffline(ctx, machine, now)
 		}
 		return nil
 	}
 
 	// Still offline (no change)
 	if previousStatus == "offline" && newStatus == "offline" {
-		// Do nothing - already offline and already notified
+		// The silence that held back the offline notification is over and the machine is still down
+		if m.held(ctx, machine) && !m.silenced(ctx, machine, now) {
+			m.release(ctx, machine)
+			m.logger.Printf("Machine %d (%s) is still offline after its silence ended", machine.ID, machine.Hostname)
+			m.notifyOffline(ctx, machine, now)
+		}
 		return nil
 	}
 
@@ -200,3 +235,39 @@ func (m *HeartbeatMonitor) checkMachine(ctx context.Context, machine *storage.Ma
 
 	return nil
 }
+
+// held reports whether a machine's offline notification was held back by a silence. Held
+// notifications are stored so they survive restarts.
+func (m *HeartbeatMonitor) held(ctx context.Context, machine *storage.Machine) bool {
+	held, err := m.store.MachineOfflineNotificationHeld(ctx, machine.ID)
+	if err != nil {
+		m.logger.Printf("Warning: failed to check held offline notification for machine %d: %v", machine.ID, err)
+		return false
+	}
+	return held
+}
+
+// release forgets a machine's held offline notification
+func (m *HeartbeatMonitor) release(ctx context.Context, machine *storage.Machine) {
+	if err := m.store.ReleaseMachineOfflineNotification(ctx, machine.ID); err != nil {
+		m.logger.Printf("Warning: failed to release held offline notification for machine %d: %v", machine.ID, err)
+	}
+}
+
+// notifyOffline sends an offline notification for a machine and records it
+func (m *HeartbeatMonitor) notifyOffline(ctx context.Context, machine *storage.Machine, now time.Time) {
+	if m.notifier == nil {
+		return
+	}
+
+	machine.Status = "offline"
+	if err := m.notifier.NotifyMachineOffline(ctx, *machine); err != nil {
+		m.logger.Printf("Failed to send offline notification for machine %d: %v", machine.ID, err)
+		return
+	}
+
+	// Record that we sent the notification
+	if err := m.store.RecordMachineOfflineNotification(ctx, machine.ID, now); err != nil {
+		m.logger.Printf("Warning: failed to record offline notification for machine %d: %v", machine.ID, err)
+	}
+}
@@ -30,6 +30,7 @@ func (m *MockHeartbeatNotifier) NotifyMachineOnline(ctx context.Context, machine
 type MockHeartbeatStore struct {
 	machines             []storage.Machine
 	offlineNotifications map[int]time.Time
+	heldNotifications    map[int]time.Time
 	statusUpdates        map[int]string
 }
 
@@ -37,6 +38,7 @@ func NewMockHeartbeatStore() *MockHeartbeatStore {
 	return &MockHeartbeatStore{
 		machines:             []storage.Machine{},
 		offlineNotifications: make(map[int]time.Time),
+		heldNotifications:    make(map[int]time.Time),
 		statusUpdates:        make(map[int]string),
 	}
 }
@@ -74,6 +76,23 @@ func (m *MockHeartbeatStore) ClearMachineOfflineNotification(ctx context.Context
 	return nil
 }
 
+func (m *MockHeartbeatStore) HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error {
+	if _, ok := m.heldNotifications[machineID]; !ok {
+		m.heldNotifications[machineID] = heldAt
+	}
+	return nil
+}
+
+func (m *MockHeartbeatStore) MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error) {
+	_, ok := m.heldNotifications[machineID]
+	return ok, nil
+}
+
+func (m *MockHeartbeatStore) ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error {
+	delete(m.heldNotifications, machineID)
+	return nil
+}
+
 // testLogger creates a logger for tests
 func testLogger() *log.Logger {
 	return log.New(os.Stderr, "[TEST] ", log.LstdFlags)
@@ -88,7 +107,7 @@ func TestHeartbeatMonitor_MachineGoesOffline(t *testing.T) {
 	store.machines = []storage.Machine{
 		{
 			ID:       1,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "test-machine",
 			Hostname: "test-01",
 			Status:   "online",
@@ -132,7 +151,7 @@ func TestHeartbeatMonitor_MachineComesBackOnline(t *testing.T) {
 	store.machines = []storage.Machine{
 		{
 			ID:       1,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "test-machine",
 			Hostname: "test-01",
 			Status:   "offline",
@@ -176,7 +195,7 @@ func TestHeartbeatMonitor_NoDuplicateNotifications(t *testing.T) {
 	store.machines = []storage.Machine{
 		{
 			ID:       1,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "test-machine",
 			Hostname: "test-01",
 			Status:   "offline",
@@ -211,7 +230,7 @@ func TestHeartbeatMonitor_StaysOnline(t *testing.T) {
 	store.machines = []storage.Machine{
 		{
 			ID:       1,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "test-machine",
 			Hostname: "test-01",
 			Status:   "online",
@@ -253,7 +272,7 @@ func TestHeartbeatMonitor_StaysOffline(t *testing.T) {
 	store.machines = []storage.Machine{
 		{
 			ID:       1,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "test-machine",
 			Hostname: "test-01",
 			Status:   "offline",
@@ -291,21 +310,21 @@ func TestHeartbeatMonitor_MultipleMachines(t *testing.T) {
 	store.machines = []storage.Machine{
 		{
 			ID:       1,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "machine-1",
 			Status:   "online",
 			LastSeen: now.Add(-30 * time.Second), // Still online
 		},
 		{
 			ID:       2,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "machine-2",
 			Status:   "online",
 			LastSeen: now.Add(-5 * time.Minute), // Should go offline
 		},
 		{
 			ID:       3,
-			UserID:   1,
+			OrgID:    1,
 			Name:     "machine-3",
 			Status:   "offline",
 			LastSeen: now.Add(-1 * time.Minute), // Should come online
@@ -344,3 +363,123 @@ func TestHeartbeatMonitor_MultipleMachines(t *testing.T) {
 		t.Error("Expected online notification for machine 3")
 	}
 }
+
+// fakeSilencer silences every machine while on is set
+type fakeSilencer struct {
+	on bool
+}
+
+func (f *fakeSilencer) MachineSilenced(ctx context.Context, machine storage.Machine, at time.Time) bool {
+	return f.on
+}
+
+func TestHeartbeatMonitor_SilencedOfflineIsHeldBack(t *testing.T) {
+	store := NewMockHeartbeatStore()
+	notifier := &MockHeartbeatNotifier{}
+
+	now := time.Now()
+	store.machines = []storage.Machine{
+		{ID: 1, OrgID: 1, Name: "db-1", Hostname: "db-1", Status: "online", LastSeen: now.Add(-3 * time.Minute)},
+	}
+
+	cfg := HeartbeatConfig{
+		CheckInterval:    100 * time.Millisecond,
+		OfflineThreshold: 2 * time.Minute,
+	}
+
+	silencer := &fakeSilencer{on: true}
+	monitor := NewHeartbeatMonitor(store, notifier, testLogger(), cfg)
+	monitor.SetSilencer(silencer)
+	ctx := context.Background()
+
+	// Goes offline during the silence: status changes, nobody is paged
+	monitor.checkAllMachines(ctx)
+	if store.statusUpdates[1] != "offline" {
+		t.Errorf("Expected machine to be marked offline, got: %s", store.statusUpdates[1])
+	}
+	if len(notifier.OfflineNotifications) != 0 {
+		t.Fatalf("Expected no offline notification while silenced, got: %d", len(notifier.OfflineNotifications))
+	}
+
+	// Still down once the silence ends: the held notification is sent once
+	silencer.on = false
+	monitor.checkAllMachines(ctx)
+	monitor.checkAllMachines(ctx)
+	if len(notifier.OfflineNotifications) != 1 {
+		t.Errorf("Expected 1 offline notification after the silence, got: %d", len(notifier.OfflineNotifications))
+	}
+	if _, ok := store.offlineNotifications[1]; !ok {
+		t.Error("Expected offline notification to be recorded")
+	}
+}
+
+func TestHeartbeatMonitor_HeldOfflineSurvivesRestart(t *testing.T) {
+	store := NewMockHeartbeatStore()
+	notifier := &MockHeartbeatNotifier{}
+
+	now := time.Now()
+	store.machines = []storage.Machine{
+		{ID: 1, OrgID: 1, Name: "db-1", Hostname: "db-1", Status: "online", LastSeen: now.Add(-3 * time.Minute)},
+	}
+
+	cfg := HeartbeatConfig{
+		CheckInterval:    100 * time.Millisecond,
+		OfflineThreshold: 2 * time.Minute,
+	}
+	ctx := context.Background()
+
+	monitor := NewHeartbeatMonitor(store, notifier, testLogger(), cfg)
+	monitor.SetSilencer(&fakeSilencer{on: true})
+	monitor.checkAllMachines(ctx)
+	if len(notifier.OfflineNotifications) != 0 {
+		t.Fatalf("Expected no offline notification while silenced, got: %d", len(notifier.OfflineNotifications))
+	}
+
+	// The API restarts after the silence ended, with the machine still down
+	restarted := NewHeartbeatMonitor(store, notifier, testLogger(), cfg)
+	restarted.SetSilencer(&fakeSilencer{on: false})
+	restarted.checkAllMachines(ctx)
+
+	if len(notifier.OfflineNotifications) != 1 {
+		t.Errorf("Expected the held offline notification after a restart, got: %d", len(notifier.OfflineNotifications))
+	}
+	if len(store.heldNotifications) != 0 {
+		t.Errorf("Expected the held notification to be released, got: %v", store.heldNotifications)
+	}
+}
+
+func TestHeartbeatMonitor_RecoversDuringSilence(t *testing.T) {
+	store := NewMockHeartbeatStore()
+	notifier := &MockHeartbeatNotifier{}
+
+	now := time.Now()
+	store.machines = []storage.Machine{
+		{ID: 1, OrgID: 1, Name: "db-1", Hostname: "db-1", Status: "online", LastSeen: now.Add(-3 * time.Minute)},
+	}
+
+	cfg := HeartbeatConfig{
+		CheckInterval:    100 * time.Millisecond,
+		OfflineThreshold: 2 * time.Minute,
+	}
+
+	silencer := &fakeSilencer{on: true}
+	monitor := NewHeartbeatMonitor(store, notifier, testLogger(), cfg)
+	monitor.SetSilencer(silencer)
+	ctx := context.Background()
+
+	monitor.checkAllMachines(ctx)
+
+	// The reboot finishes before the silence ends
+	store.machines[0].LastSeen = time.Now()
+	monitor.checkAllMachines(ctx)
+	silencer.on = false
+	monitor.checkAllMachines(ctx)
+
+	if store.statusUpdates[1] != "online" {
+		t.Errorf("Expected machine to be back online, got: %s", store.statusUpdates[1])
+	}
+	if len(notifier.OfflineNotifications) != 0 || len(notifier.OnlineNotifications) != 0 {
+		t.Errorf("Expected no notifications for a reboot inside a silence, got offline=%d online=%d",
+			len(notifier.OfflineNotifications), len(notifier.OnlineNotifications))
+	}
+}
@@ -6,11 +6,20 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
+const (
+	// MaxTags is the maximum number of tags on a machine
+	MaxTags = 20
+	// MaxTagLength is the maximum length of a machine tag
+	MaxTagLength = 50
+)
+
 // Service provides machine management operations
 type Service struct {
 	store storage.Store
@@ -51,8 +60,8 @@ func HashAPIKey(apiKey string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-// RegisterMachine registers a new machine for a user
-func (s *Service) RegisterMachine(ctx context.Context, userID int, name, hostname, description string) (*storage.Machine, string, error) {
+// RegisterMachine registers a new machine for an organization
+func (s *Service) RegisterMachine(ctx context.Context, orgID int, name, hostname, description string) (*storage.Machine, string, error) {
 	// Generate API key
 	apiKey, err := GenerateAPIKey()
 	if err != nil {
@@ -63,38 +72,51 @@ func (s *Service) RegisterMachine(ctx context.Context, userID int, name, hostnam
 	apiKeyHash := HashAPIKey(apiKey)
 
 	// Create machine in database
-	machine, err := s.store.CreateMachine(ctx, userID, name, hostname, description, apiKeyHash)
+	machine, err := s.store.CreateMachine(ctx, orgID, name, hostname, description, apiKeyHash)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create machine: %w", err)
 	}
+	s.audit(ctx, "machine.create", machine.ID, orgID, nil, machine)
 
 	// Return machine and the plaintext API key (only time it's visible)
 	return machine, apiKey, nil
 }
 
-// GetMachine retrieves a machine by ID, ensuring the user owns it
-func (s *Service) GetMachine(ctx context.Context, machineID, userID int) (*storage.Machine, error) {
+// GetMachine retrieves a machine by ID, ensuring the organization owns it
+func (s *Service) GetMachine(ctx context.Context, machineID, orgID int) (*storage.Machine, error) {
 	machine, err := s.store.GetMachineByID(ctx, machineID)
 	if err != nil {
 		return nil, err
 	}
 
 	// Verify ownership
-	if machine.UserID != userID {
-		return nil, fmt.Errorf("access denied: machine does not belong to user")
+	if machine.OrgID != orgID {
+		return nil, fmt.Errorf("access denied: machine does not belong to organization")
 	}
 
 	return machine, nil
 }
 
-// ListMachines lists all machines for a user
-func (s *Service) ListMachines(ctx context.Context, userID int) ([]storage.Machine, error) {
-	return s.store.ListMachines(ctx, userID)
+// ListMachines lists all machines for an organization
+func (s *Service) ListMachines(ctx context.Context, orgID int) ([]storage.Machine, error) {
+	return s.store.ListMachines(ctx, orgID)
 }
 
-// DeleteMachine deletes a machine, ensuring the user owns it
-func (s *Service) DeleteMachine(ctx context.Context, machineID, userID int) error {
-	return s.store.DeleteMachine(ctx, machineID, userID)
+// DeleteMachine deletes a machine, ensuring the organization owns it
+func (s *Service) DeleteMachine(ctx context.Context, machineID, orgID int) error {
+	// Kept for the audit log; when missing, the delete below reports why
+	machine, _ := s.GetMachine(ctx, machineID, orgID)
+
+	if err := s.store.DeleteMachine(ctx, machineID, orgID); err != nil {
+		return err
+	}
+	s.audit(ctx, "machine.delete", machineID, orgID, machine, nil)
+	return nil
+}
+
+// audit records an action on one of the organization's machines in the audit log
+func (s *Service) audit(ctx context.Context, action string, machineID, orgID int, before, after *storage.Machine) {
+	audit.Record(ctx, s.store, audit.Event{OrgID: orgID, Action: action, TargetType: "machine", TargetID: machineID, Before: before, After: after})
 }
 
 // AuthenticateMachine validates an API key and returns the associated machine
@@ -152,9 +174,9 @@ func (s *Service) RecordMetrics(ctx context.Context, machineID int, cpuPct, memU
 }
 
 // GetLatestMetrics retrieves the latest metrics for a machine
-func (s *Service) GetLatestMetrics(ctx context.Context, machineID, userID int) (*storage.MetricsHistory, error) {
+func (s *Service) GetLatestMetrics(ctx context.Context, machineID, orgID int) (*storage.MetricsHistory, error) {
 	// Verify ownership
-	machine, err := s.GetMachine(ctx, machineID, userID)
+	machine, err := s.GetMachine(ctx, machineID, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -164,9 +186,9 @@ func (s *Service) GetLatestMetrics(ctx context.Context, machineID, userID int) (
 }
 
 // GetMetricsHistory retrieves historical metrics for a machine
-func (s *Service) GetMetricsHistory(ctx context.Context, machineID, userID int, from, to time.Time, limit int) ([]storage.MetricsHistory, error) {
+func (s *Service) GetMetricsHistory(ctx context.Context, machineID, orgID int, from, to time.Time, limit int) ([]storage.MetricsHistory, error) {
 	// Verify ownership
-	machine, err := s.GetMachine(ctx, machineID, userID)
+	machine, err := s.GetMachine(ctx, machineID, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -205,8 +227,8 @@ func (s *Service) UpdateMachineStatuses(ctx context.Context) error {
 }
 
 // GetMachineWithComputedStatus retrieves a machine and computes its real-time status
-func (s *Service) GetMachineWithComputedStatus(ctx context.Context, machineID, userID int) (*storage.Machine, error) {
-	machine, err := s.GetMachine(ctx, machineID, userID)
+func (s *Service) GetMachineWithComputedStatus(ctx context.Context, machineID, orgID int) (*storage.Machine, error) {
+	machine, err := s.GetMachine(ctx, machineID, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -214,12 +236,16 @@ func (s *Service) GetMachineWithComputedStatus(ctx context.Context, machineID, u
 	// Compute real-time status
 	machine.Status = ComputeStatus(machine.LastSeen)
 
+	if machine.Tags, err = s.store.GetMachineTags(ctx, machine.ID); err != nil {
+		return nil, err
+	}
+
 	return machine, nil
 }
 
-// ListMachinesWithComputedStatus lists all machines for a user with computed statuses
-func (s *Service) ListMachinesWithComputedStatus(ctx context.Context, userID int) ([]storage.Machine, error) {
-	machines, err := s.ListMachines(ctx, userID)
+// ListMachinesWithComputedStatus lists all machines for an organization with computed statuses
+func (s *Service) ListMachinesWithComputedStatus(ctx context.Context, orgID int) ([]storage.Machine, error) {
+	machines, err := s.ListMachines(ctx, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -227,21 +253,26 @@ func (s *Service) ListMachinesWithComputedStatus(ctx context.Context, userID int
 	// Compute real-time status for each machine
 	for i := range machines {
 		machines[i].Status = ComputeStatus(machines[i].LastSeen)
+
+		if machines[i].Tags, err = s.store.GetMachineTags(ctx, machines[i].ID); err != nil {
+			return nil, err
+		}
 	}
 
 	return machines, nil
 }
 
-// UpdateMachine updates machine details, ensuring the user owns it
-func (s *Service) UpdateMachine(ctx context.Context, machineID, userID int, name, hostname, description *string) error {
+// UpdateMachine updates machine details and, when tags is not nil, replaces its tags, ensuring the
+// organization owns it. The whole update is recorded as one audit entry.
+func (s *Service) UpdateMachine(ctx context.Context, machineID, orgID int, name, hostname, description *string, tags *[]string) error {
 	// First verify ownership
 	machine, err := s.store.GetMachineByID(ctx, machineID)
 	if err != nil {
 		return err
 	}
 
-	if machine.UserID != userID {
-		return fmt.Errorf("access denied: machine does not belong to user")
+	if machine.OrgID != orgID {
+		return fmt.Errorf("access denied: machine does not belong to organization")
 	}
 
 	// Build update query dynamically based on what's provided
@@ -256,19 +287,85 @@ func (s *Service) UpdateMachine(ctx context.Context, machineID, userID int, name
 		updates["description"] = *description
 	}
 
+	var normalized []string
+	if tags != nil {
+		if normalized, err = NormalizeTags(*tags); err != nil {
+			return err
+		}
+	}
+
 	// If nothing to update, return early
-	if len(updates) == 0 {
+	if len(updates) == 0 && tags == nil {
 		return nil
 	}
 
+	if machine.Tags, err = s.store.GetMachineTags(ctx, machineID); err != nil {
+		return err
+	}
+
 	// Perform update in storage layer
-	return s.store.UpdateMachineDetails(ctx, machineID, updates)
+	if len(updates) > 0 {
+		if err := s.store.UpdateMachineDetails(ctx, machineID, updates); err != nil {
+			return err
+		}
+	}
+	if tags != nil {
+		if err := s.store.SetMachineTags(ctx, machineID, normalized); err != nil {
+			return err
+		}
+	}
+
+	after, err := s.store.GetMachineByID(ctx, machineID)
+	if err != nil {
+		after = nil
+	} else if after.Tags, err = s.store.GetMachineTags(ctx, machineID); err != nil {
+		after = nil
+	}
+	s.audit(ctx, "machine.update", machineID, orgID, machine, after)
+	return nil
+}
+
+// auditUpdate records a change to a machine in the audit log, comparing it with its state before
+func (s *Service) auditUpdate(ctx context.Context, action string, before *storage.Machine) {
+	after, err := s.store.GetMachineByID(ctx, before.ID)
+	if err != nil {
+		after = nil
+	}
+	s.audit(ctx, action, before.ID, before.OrgID, before, after)
+}
+
+// NormalizeTags trims, lowercases and de-duplicates machine tags, rejecting invalid ones
+func NormalizeTags(tags []string) ([]string, error) {
+	if len(tags) > MaxTags {
+		return nil, fmt.Errorf("a machine can have at most %d tags", MaxTags)
+	}
+
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("tags must not be empty")
+		}
+		if len(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+		if strings.ContainsAny(tag, " ,") {
+			return nil, fmt.Errorf("tag %q must not contain spaces or commas", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized, nil
 }
 
 // DisableMachine disables a machine, preventing it from posting metrics
-func (s *Service) DisableMachine(ctx context.Context, machineID, userID int) error {
+func (s *Service) DisableMachine(ctx context.Context, machineID, orgID int) error {
 	// Verify ownership
-	machine, err := s.GetMachine(ctx, machineID, userID)
+	machine, err := s.GetMachine(ctx, machineID, orgID)
 	if err != nil {
 		return err
 	}
@@ -277,14 +374,15 @@ func (s *Service) DisableMachine(ctx context.Context, machineID, userID int) err
 	if err := s.store.SetMachineEnabled(ctx, machine.ID, false); err != nil {
 		return fmt.Errorf("failed to disable machine: %w", err)
 	}
+	s.auditUpdate(ctx, "machine.disable", machine)
 
 	return nil
 }
 
 // EnableMachine re-enables a machine
-func (s *Service) EnableMachine(ctx context.Context, machineID, userID int) error {
+func (s *Service) EnableMachine(ctx context.Context, machineID, orgID int) error {
 	// Verify ownership
-	machine, err := s.GetMachine(ctx, machineID, userID)
+	machine, err := s.GetMachine(ctx, machineID, orgID)
 	if err != nil {
 		return err
 	}
@@ -293,14 +391,15 @@ func (s *Service) EnableMachine(ctx context.Context, machineID, userID int) erro
 	if err := s.store.SetMachineEnabled(ctx, machine.ID, true); err != nil {
 		return fmt.Errorf("failed to enable machine: %w", err)
 	}
+	s.auditUpdate(ctx, "machine.enable", machine)
 
 	return nil
 }
 
 // RotateMachineAPIKey generates a new API key for a machine and revokes the old one
-func (s *Service) RotateMachineAPIKey(ctx context.Context, machineID, userID int) (string, error) {
+func (s *Service) RotateMachineAPIKey(ctx context.Context, machineID, orgID int) (string, error) {
 	// Verify ownership
-	machine, err := s.GetMachine(ctx, machineID, userID)
+	machine, err := s.GetMachine(ctx, machineID, orgID)
 	if err != nil {
 		return "", err
 	}
@@ -323,15 +422,16 @@ func (s *Service) RotateMachineAPIKey(ctx context.Context, machineID, userID int
 	if _, err := s.store.CreateMachineAPIKey(ctx, machine.ID, newAPIKeyHash); err != nil {
 		return "", fmt.Errorf("failed to create new API key: %w", err)
 	}
+	s.audit(ctx, "machine.rotate_key", machine.ID, orgID, nil, nil)
 
 	// Return the plaintext API key (only time it's visible)
 	return newAPIKey, nil
 }
 
 // GetMachineAPIKeyInfo retrieves information about a machine's API keys
-func (s *Service) GetMachineAPIKeyInfo(ctx context.Context, machineID, userID int) ([]storage.MachineAPIKey, error) {
+func (s *Service) GetMachineAPIKeyInfo(ctx context.Context, machineID, orgID int) ([]storage.MachineAPIKey, error) {
 	// Verify ownership
-	machine, err := s.GetMachine(ctx, machineID, userID)
+	machine, err := s.GetMachine(ctx, machineID, orgID)
 	if err != nil {
 		return nil, err
 	}
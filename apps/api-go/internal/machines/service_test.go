@@ -3,6 +3,8 @@ package machines
 import (
 	"context"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -292,6 +294,35 @@ func TestMachineService(t *testing.T) {
 		}
 	})
 
+	t.Run("UpdateMachine", func(t *testing.T) {
+		machine, _, err := service.RegisterMachine(ctx, user.ID, "update-test", "update.com", "")
+		if err != nil {
+			t.Fatalf("Failed to register machine: %v", err)
+		}
+
+		name := "renamed"
+		tags := []string{"Web", "prod"}
+		if err := service.UpdateMachine(ctx, machine.ID, user.ID, &name, nil, nil, &tags); err != nil {
+			t.Fatalf("Failed to update machine: %v", err)
+		}
+		updated, _ := service.GetMachine(ctx, machine.ID, user.ID)
+		stored, _ := store.GetMachineTags(ctx, machine.ID)
+		if updated.Name != "renamed" || strings.Join(stored, ",") != "prod,web" {
+			t.Errorf("Expected the name and tags to change, got %q %v", updated.Name, stored)
+		}
+
+		// Details and tags changed together are one audit entry
+		entries, _ := store.ListAuditEntries(ctx, storage.AuditFilter{Action: "machine.update", TargetID: strconv.Itoa(machine.ID)})
+		if len(entries) != 1 || !strings.Contains(string(entries[0].Changes), `"tags"`) || !strings.Contains(string(entries[0].Changes), `"name"`) {
+			t.Errorf("Expected one entry with the name and tags, got %+v", entries)
+		}
+
+		other := 9999
+		if err := service.UpdateMachine(ctx, machine.ID, other, &name, nil, nil, nil); err == nil {
+			t.Error("Expected another organization to be denied")
+		}
+	})
+
 	t.Run("DeleteMachine", func(t *testing.T) {
 		machine, _, err := service.RegisterMachine(ctx, user.ID, "delete-test", "delete.com", "Delete test machine")
 		if err != nil {
@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/config"
+)
+
+// serveSMTP accepts one SMTP session on ln and sends its envelope and message to received
+func serveSMTP(ln net.Listener, received chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var transcript strings.Builder
+	inData := false
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if inData {
+			if line == ".\r\n" {
+				inData = false
+				fmt.Fprint(conn, "250 OK\r\n")
+				continue
+			}
+			transcript.WriteString(line)
+			continue
+		}
+
+		switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+		case strings.HasPrefix(cmd, "EHLO"):
+			fmt.Fprint(conn, "250-localhost\r\n250 8BITMIME\r\n")
+		case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+			transcript.WriteString(line)
+			fmt.Fprint(conn, "250 OK\r\n")
+		case cmd == "DATA":
+			inData = true
+			fmt.Fprint(conn, "354 End data with <CR><LF>.<CR><LF>\r\n")
+		case cmd == "QUIT":
+			fmt.Fprint(conn, "221 Bye\r\n")
+			received <- transcript.String()
+			return
+		default:
+			fmt.Fprint(conn, "502 Command not implemented\r\n")
+		}
+	}
+}
+
+func TestSMTPMailer_SendMail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+	received := make(chan string, 1)
+	go serveSMTP(ln, received)
+
+	host, portStr, _ := net.SplitHostPort(ln.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	mailer, err := NewSMTPMailer(&config.MailConfig{SMTPHost: host, SMTPPort: port, From: "LunaSentri <noreply@example.com>"})
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := mailer.SendMail(ctx, "ops@example.com", "Reset your password", "Hello,\nopen this link.\n"); err != nil {
+		t.Fatalf("SendMail failed: %v", err)
+	}
+
+	transcript := <-received
+	for _, want := range []string{
+		"MAIL FROM:<noreply@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"From: \"LunaSentri\" <noreply@example.com>\r\n",
+		"To: <ops@example.com>\r\n",
+		"Subject: Reset your password\r\n",
+		"\r\n\r\nHello,\r\nopen this link.\r\n",
+	} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("Expected the session to contain %q, got:\n%s", want, transcript)
+		}
+	}
+}
+
+func TestSMTPMailer_RejectsInvalidMessages(t *testing.T) {
+	if _, err := NewSMTPMailer(&config.MailConfig{SMTPHost: "localhost", SMTPPort: 25, From: "not an address"}); err == nil {
+		t.Error("Expected an invalid sender to be rejected")
+	}
+
+	mailer, err := NewSMTPMailer(&config.MailConfig{SMTPHost: "localhost", SMTPPort: 25, From: "noreply@example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create mailer: %v", err)
+	}
+	ctx := context.Background()
+	if err := mailer.SendMail(ctx, "ops@example.com\r\nBcc: victim@example.com", "Hi", "body"); err == nil {
+		t.Error("Expected a recipient with a line break to be rejected")
+	}
+	if err := mailer.SendMail(ctx, "ops@example.com", "Hi\r\nBcc: victim@example.com", "body"); err == nil {
+		t.Error("Expected a subject with a line break to be rejected")
+	}
+}
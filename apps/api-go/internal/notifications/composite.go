@@ -54,3 +54,91 @@ func (c *CompositeNotifier) Notify(ctx context.Context, rule storage.AlertRule,
 
 	return nil
 }
+
+// NotifyResolved sends the resolution notification to all channels that support it
+func (c *CompositeNotifier) NotifyResolved(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	if event == nil {
+		return nil
+	}
+
+	for _, notifier := range c.notifiers {
+		resolver, ok := notifier.(AlertResolutionNotifier)
+		if !ok {
+			continue
+		}
+
+		go func(n AlertResolutionNotifier) {
+			notifyCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := n.NotifyResolved(notifyCtx, rule, event); err != nil {
+				c.logger.Printf("[COMPOSITE_NOTIFIER] failed to send resolution notification: %v", err)
+			}
+		}(resolver)
+	}
+
+	return nil
+}
+
+// NotifyOrg sends an alert.* event to one organization on all channels that support per-organization delivery
+func (c *CompositeNotifier) NotifyOrg(ctx context.Context, orgID int, eventType string, rule storage.AlertRule, event storage.AlertEvent) error {
+	for _, notifier := range c.notifiers {
+		orgNotifier, ok := notifier.(OrgAlertNotifier)
+		if !ok {
+			continue
+		}
+
+		go func(n OrgAlertNotifier) {
+			notifyCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := n.NotifyOrg(notifyCtx, orgID, eventType, rule, event); err != nil {
+				c.logger.Printf("[COMPOSITE_NOTIFIER] failed to send notification to organization %d: %v", orgID, err)
+			}
+		}(orgNotifier)
+	}
+
+	return nil
+}
+
+// NotifyOrgDigest sends a digest to one organization on all channels that support per-organization delivery
+func (c *CompositeNotifier) NotifyOrgDigest(ctx context.Context, orgID int, digest AlertDigest) error {
+	for _, notifier := range c.notifiers {
+		orgNotifier, ok := notifier.(OrgAlertNotifier)
+		if !ok {
+			continue
+		}
+
+		go func(n OrgAlertNotifier) {
+			notifyCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := n.NotifyOrgDigest(notifyCtx, orgID, digest); err != nil {
+				c.logger.Printf("[COMPOSITE_NOTIFIER] failed to send digest to organization %d: %v", orgID, err)
+			}
+		}(orgNotifier)
+	}
+
+	return nil
+}
+
+// NotifyEscalation pages an escalation step on all channels that support escalations
+func (c *CompositeNotifier) NotifyEscalation(ctx context.Context, orgID int, escalation AlertEscalation) error {
+	for _, notifier := range c.notifiers {
+		escalationNotifier, ok := notifier.(EscalationNotifier)
+		if !ok {
+			continue
+		}
+
+		go func(n EscalationNotifier) {
+			notifyCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := n.NotifyEscalation(notifyCtx, orgID, escalation); err != nil {
+				c.logger.Printf("[COMPOSITE_NOTIFIER] failed to send escalation to organization %d: %v", orgID, err)
+			}
+		}(escalationNotifier)
+	}
+
+	return nil
+}
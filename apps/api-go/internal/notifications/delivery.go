@@ -0,0 +1,143 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// Built-in delivery policy, used when neither the server nor the endpoint overrides it
+const (
+	MinAttemptInterval = 30 * time.Second // Minimum interval between delivery attempts
+	FailureThreshold   = 3                // Number of failures within window to trigger cooldown
+	FailureWindow      = 10 * time.Minute // Time window for counting failures
+	CooldownDuration   = 15 * time.Minute // Duration of cooldown after reaching failure threshold
+
+	// Telegram chats receive every alert, resolution and escalation on their own, so a burst of
+	// alerts must not be throttled down to one message per interval
+	TelegramMinAttemptInterval = time.Duration(0)
+)
+
+// DeliveryPolicy controls rate limiting and the circuit breaker for a notification endpoint
+type DeliveryPolicy struct {
+	MinAttemptInterval time.Duration // Minimum interval between delivery attempts, 0 disables rate limiting
+	FailureThreshold   int           // Number of failures within the window that opens the circuit
+	FailureWindow      time.Duration // Time window for counting failures
+	CooldownDuration   time.Duration // How long the circuit stays open
+}
+
+// DefaultDeliveryPolicy returns the built-in delivery policy
+func DefaultDeliveryPolicy() DeliveryPolicy {
+	return DeliveryPolicy{
+		MinAttemptInterval: MinAttemptInterval,
+		FailureThreshold:   FailureThreshold,
+		FailureWindow:      FailureWindow,
+		CooldownDuration:   CooldownDuration,
+	}
+}
+
+// DefaultTelegramDeliveryPolicy returns the built-in delivery policy for Telegram recipients,
+// which keeps the circuit breaker but does not rate limit
+func DefaultTelegramDeliveryPolicy() DeliveryPolicy {
+	p := DefaultDeliveryPolicy()
+	p.MinAttemptInterval = TelegramMinAttemptInterval
+	return p
+}
+
+// Validate checks that the policy values are usable
+func (p DeliveryPolicy) Validate() error {
+	if p.MinAttemptInterval < 0 {
+		return fmt.Errorf("min attempt interval must not be negative")
+	}
+	if p.FailureThreshold < 1 {
+		return fmt.Errorf("failure threshold must be at least 1")
+	}
+	if p.FailureWindow <= 0 {
+		return fmt.Errorf("failure window must be positive")
+	}
+	if p.CooldownDuration <= 0 {
+		return fmt.Errorf("cooldown duration must be positive")
+	}
+	return nil
+}
+
+// WithOverrides returns the policy with an endpoint's configured overrides applied
+func (p DeliveryPolicy) WithOverrides(settings storage.DeliverySettings) DeliveryPolicy {
+	if settings.MinAttemptIntervalSeconds != nil {
+		p.MinAttemptInterval = time.Duration(*settings.MinAttemptIntervalSeconds) * time.Second
+	}
+	if settings.FailureThreshold != nil {
+		p.FailureThreshold = *settings.FailureThreshold
+	}
+	if settings.FailureWindowSeconds != nil {
+		p.FailureWindow = time.Duration(*settings.FailureWindowSeconds) * time.Second
+	}
+	if settings.CooldownSeconds != nil {
+		p.CooldownDuration = time.Duration(*settings.CooldownSeconds) * time.Second
+	}
+	return p
+}
+
+// checkPreconditions returns a RateLimitError if an endpoint is in cooldown or was attempted too recently.
+// subject names the endpoint in the error message, e.g. "Webhook".
+func (p DeliveryPolicy) checkPreconditions(subject string, now time.Time, cooldownUntil, lastAttemptAt *time.Time) *RateLimitError {
+	if cooldownUntil != nil && now.Before(*cooldownUntil) {
+		return &RateLimitError{
+			Type:    "cooldown",
+			Message: fmt.Sprintf("%s in cooldown until %s", subject, cooldownUntil.Format(time.RFC3339)),
+			RetryAt: cooldownUntil,
+		}
+	}
+
+	if lastAttemptAt != nil && p.MinAttemptInterval > 0 {
+		timeSinceLastAttempt := now.Sub(*lastAttemptAt)
+		if timeSinceLastAttempt < p.MinAttemptInterval {
+			delay := p.MinAttemptInterval - timeSinceLastAttempt
+			retryAt := lastAttemptAt.Add(p.MinAttemptInterval)
+			return &RateLimitError{
+				Type:    "rate_limit",
+				Message: fmt.Sprintf("Rate limit active, can retry in %s", delay.String()),
+				RetryAt: &retryAt,
+			}
+		}
+	}
+
+	return nil
+}
+
+// shouldEnterCooldown determines if an endpoint's circuit should open based on recent failures
+func (p DeliveryPolicy) shouldEnterCooldown(now time.Time, failureCount int, lastErrorAt *time.Time) bool {
+	// If we haven't reached the failure threshold, no cooldown
+	if failureCount < p.FailureThreshold {
+		return false
+	}
+
+	// If we don't have a last error timestamp, enter cooldown as a safety measure
+	if lastErrorAt == nil {
+		return true
+	}
+
+	// Check if the failures occurred within the failure window
+	return lastErrorAt.After(now.Add(-p.FailureWindow))
+}
+
+// validateDeliverySettings validates endpoint delivery overrides submitted through the API
+func validateDeliverySettings(settings *storage.DeliverySettings) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.MinAttemptIntervalSeconds != nil && *settings.MinAttemptIntervalSeconds < 0 {
+		return fmt.Errorf("min_attempt_interval_seconds must not be negative")
+	}
+	if settings.FailureThreshold != nil && *settings.FailureThreshold < 1 {
+		return fmt.Errorf("failure_threshold must be at least 1")
+	}
+	if settings.FailureWindowSeconds != nil && *settings.FailureWindowSeconds < 1 {
+		return fmt.Errorf("failure_window_seconds must be at least 1")
+	}
+	if settings.CooldownSeconds != nil && *settings.CooldownSeconds < 1 {
+		return fmt.Errorf("cooldown_seconds must be at least 1")
+	}
+	return nil
+}
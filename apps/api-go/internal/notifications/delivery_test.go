@@ -0,0 +1,127 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestDeliveryPolicy_WithOverrides(t *testing.T) {
+	policy := DefaultDeliveryPolicy().WithOverrides(storage.DeliverySettings{
+		MinAttemptIntervalSeconds: intPtr(0),
+		CooldownSeconds:           intPtr(60),
+	})
+
+	if policy.MinAttemptInterval != 0 {
+		t.Errorf("Expected min attempt interval override 0, got %v", policy.MinAttemptInterval)
+	}
+	if policy.CooldownDuration != time.Minute {
+		t.Errorf("Expected cooldown override 1m, got %v", policy.CooldownDuration)
+	}
+	if policy.FailureThreshold != FailureThreshold || policy.FailureWindow != FailureWindow {
+		t.Errorf("Expected unset fields to keep defaults, got %+v", policy)
+	}
+
+	// A zero interval disables rate limiting entirely
+	now := time.Now()
+	lastAttempt := now.Add(-time.Second)
+	if err := policy.checkPreconditions("Webhook", now, nil, &lastAttempt); err != nil {
+		t.Errorf("Expected no rate limit with zero interval, got %v", err)
+	}
+
+	// Cooldown still applies
+	cooldownUntil := now.Add(time.Minute)
+	if err := policy.checkPreconditions("Webhook", now, &cooldownUntil, nil); err == nil || err.Type != "cooldown" {
+		t.Errorf("Expected cooldown error, got %v", err)
+	}
+}
+
+func TestDeliveryPolicy_ShouldEnterCooldown(t *testing.T) {
+	policy := DeliveryPolicy{MinAttemptInterval: 0, FailureThreshold: 5, FailureWindow: time.Minute, CooldownDuration: time.Minute}
+	now := time.Now()
+	recent := now.Add(-30 * time.Second)
+	old := now.Add(-2 * time.Minute)
+
+	if policy.shouldEnterCooldown(now, 4, &recent) {
+		t.Error("Expected no cooldown below threshold")
+	}
+	if !policy.shouldEnterCooldown(now, 5, &recent) {
+		t.Error("Expected cooldown at threshold within window")
+	}
+	if policy.shouldEnterCooldown(now, 5, &old) {
+		t.Error("Expected no cooldown when failures are outside the window")
+	}
+}
+
+func TestDefaultTelegramDeliveryPolicy(t *testing.T) {
+	policy := DefaultTelegramDeliveryPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("Expected Telegram policy to be valid, got %v", err)
+	}
+
+	// Back-to-back alerts to the same chat are all delivered
+	now := time.Now()
+	lastAttempt := now.Add(-time.Second)
+	if err := policy.checkPreconditions("Telegram recipient", now, nil, &lastAttempt); err != nil {
+		t.Errorf("Expected no rate limit for Telegram, got %v", err)
+	}
+	if policy.FailureThreshold != FailureThreshold || policy.CooldownDuration != CooldownDuration {
+		t.Errorf("Expected Telegram to keep the default circuit breaker, got %+v", policy)
+	}
+}
+
+func TestDeliveryPolicy_Validate(t *testing.T) {
+	if err := DefaultDeliveryPolicy().Validate(); err != nil {
+		t.Fatalf("Expected default policy to be valid, got %v", err)
+	}
+
+	invalid := DefaultDeliveryPolicy()
+	invalid.FailureThreshold = 0
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected error for zero failure threshold")
+	}
+
+	if err := validateDeliverySettings(&storage.DeliverySettings{CooldownSeconds: intPtr(0)}); err == nil {
+		t.Error("Expected error for zero cooldown_seconds")
+	}
+	if err := validateDeliverySettings(&storage.DeliverySettings{MinAttemptIntervalSeconds: intPtr(0)}); err != nil {
+		t.Errorf("Expected zero min_attempt_interval_seconds to be allowed, got %v", err)
+	}
+}
+
+func TestHandleResetWebhookCircuit(t *testing.T) {
+	store := newMockHTTPStore()
+	handler := HandleResetWebhookCircuit(store)
+
+	webhook, _ := store.CreateWebhook(context.Background(), 1, "https://example.com/webhook", "hash")
+	cooldownUntil := time.Now().Add(time.Hour)
+	store.webhooks[1][0].FailureCount = 3
+	store.webhooks[1][0].CooldownUntil = &cooldownUntil
+
+	req := createAuthenticatedRequest("POST", fmt.Sprintf("/notifications/webhooks/%d/reset-circuit", webhook.ID), nil, 1)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if store.webhooks[1][0].FailureCount != 0 || store.webhooks[1][0].CooldownUntil != nil {
+		t.Errorf("Expected circuit to be reset, got %+v", store.webhooks[1][0])
+	}
+
+	// Other users cannot reset the circuit
+	req = createAuthenticatedRequest("POST", fmt.Sprintf("/notifications/webhooks/%d/reset-circuit", webhook.ID), nil, 2)
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for another user's webhook, got %d", w.Code)
+	}
+}
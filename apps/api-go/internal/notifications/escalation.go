@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/silences"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// DefaultEscalationWait is how long a step waits when a policy does not set wait_seconds
+	DefaultEscalationWait = 15 * time.Minute
+	// MinEscalationWait is the shortest wait a step can configure
+	MinEscalationWait = time.Minute
+	// MaxEscalationWait is the longest wait a step can configure
+	MaxEscalationWait = 24 * time.Hour
+	// MaxEscalationSteps is the maximum number of steps in a policy
+	MaxEscalationSteps = 10
+
+	// escalationCheckInterval is how often unacknowledged events are checked for due steps
+	escalationCheckInterval = 30 * time.Second
+)
+
+// EscalationScheduler advances firing alert events that nobody has acknowledged through the
+// steps of their rule's escalation policy. An event stops escalating once it is acknowledged,
+// resolved or has run out of steps.
+type EscalationScheduler struct {
+	store    storage.Store
+	notifier EscalationNotifier
+	silencer *silences.Service
+	logger   *log.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewEscalationScheduler creates a scheduler that pages escalation steps through notifier
+func NewEscalationScheduler(store storage.Store, notifier EscalationNotifier, logger *log.Logger) *EscalationScheduler {
+	return &EscalationScheduler{
+		store:    store,
+		notifier: notifier,
+		silencer: silences.NewService(store),
+		logger:   logger,
+		interval: escalationCheckInterval,
+		now:      time.Now,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Start begins checking for due escalation steps in a background goroutine
+func (e *EscalationScheduler) Start(ctx context.Context) {
+	go e.run(ctx)
+	e.logger.Printf("[ESCALATION] scheduler started (check interval: %v)", e.interval)
+}
+
+// Stop stops the scheduler and waits for it to finish
+func (e *EscalationScheduler) Stop() {
+	close(e.stopCh)
+	<-e.doneCh
+	e.logger.Println("[ESCALATION] scheduler stopped")
+}
+
+// run is the scheduler loop
+func (e *EscalationScheduler) run(ctx context.Context) {
+	defer close(e.doneCh)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.advance(ctx, e.now())
+		case <-e.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// advance pages the next step of every pending escalation that is due at now. Each step waits
+// from the previous one, so at most one step per event is paged per check.
+func (e *EscalationScheduler) advance(ctx context.Context, now time.Time) {
+	pending, err := e.store.ListPendingEscalations(ctx)
+	if err != nil {
+		e.logger.Printf("[ESCALATION] failed to list pending escalations: %v", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	for _, p := range pending {
+		if p.Step >= len(p.Policy.Steps) {
+			continue
+		}
+
+		step := p.Policy.Steps[p.Step]
+		if now.Before(p.LastEscalatedAt.Add(time.Duration(step.WaitSeconds) * time.Second)) {
+			continue
+		}
+
+		// A policy only pages its organization about its own rules
+		if p.Rule.OrgID != p.Policy.OrgID {
+			continue
+		}
+
+		if e.silencer.AlertSilenced(ctx, p.Rule, p.Event.MachineID, now) != nil {
+			continue
+		}
+
+		// Record the step before paging so a failing channel never pages the same step twice
+		if err := e.store.RecordAlertEscalation(ctx, p.Event.ID, p.Step+1, now); err != nil {
+			e.logger.Printf("[ESCALATION] failed to record step %d for event=%d: %v", p.Step+1, p.Event.ID, err)
+			continue
+		}
+
+		escalation := AlertEscalation{
+			PolicyName:           p.Policy.Name,
+			Step:                 p.Step + 1,
+			Rule:                 p.Rule,
+			Event:                p.Event,
+			WebhookIDs:           step.WebhookIDs,
+			TelegramRecipientIDs: step.TelegramRecipientIDs,
+		}
+		if err := e.notifier.NotifyEscalation(ctx, p.Policy.OrgID, escalation); err != nil {
+			e.logger.Printf("[ESCALATION] failed to page step %d for event=%d: %v", escalation.Step, p.Event.ID, err)
+			continue
+		}
+
+		e.logger.Printf("[ESCALATION] event=%d rule=%q paged step %d/%d of policy %q",
+			p.Event.ID, p.Rule.Name, escalation.Step, len(p.Policy.Steps), p.Policy.Name)
+	}
+}
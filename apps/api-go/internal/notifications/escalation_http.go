@@ -0,0 +1,207 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// EscalationPolicyRequest represents the request body for creating or replacing an escalation policy
+type EscalationPolicyRequest struct {
+	Name  string                   `json:"name"`
+	Steps []storage.EscalationStep `json:"steps"` // wait_seconds defaults to 15 minutes when omitted
+}
+
+// validate checks the request and that every destination belongs to the organization, returning the policy to store
+func (r *EscalationPolicyRequest) validate(ctx context.Context, store storage.Store, orgID int) (storage.EscalationPolicy, error) {
+	policy := storage.EscalationPolicy{
+		OrgID: orgID,
+		Name:  strings.TrimSpace(r.Name),
+	}
+
+	if policy.Name == "" {
+		return policy, fmt.Errorf("name is required")
+	}
+	if len(r.Steps) == 0 || len(r.Steps) > MaxEscalationSteps {
+		return policy, fmt.Errorf("steps must contain between 1 and %d steps", MaxEscalationSteps)
+	}
+
+	minWait := int(MinEscalationWait / time.Second)
+	maxWait := int(MaxEscalationWait / time.Second)
+
+	for i, step := range r.Steps {
+		if step.WaitSeconds == 0 {
+			step.WaitSeconds = int(DefaultEscalationWait / time.Second)
+		}
+		if step.WaitSeconds < minWait || step.WaitSeconds > maxWait {
+			return policy, fmt.Errorf("step %d: wait_seconds must be between %d and %d", i+1, minWait, maxWait)
+		}
+
+		if len(step.WebhookIDs) == 0 && len(step.TelegramRecipientIDs) == 0 {
+			return policy, fmt.Errorf("step %d: at least one webhook or Telegram recipient is required", i+1)
+		}
+		for _, id := range step.WebhookIDs {
+			if _, err := store.GetWebhook(ctx, id, orgID); err != nil {
+				return policy, fmt.Errorf("step %d: webhook %d not found", i+1, id)
+			}
+		}
+		for _, id := range step.TelegramRecipientIDs {
+			if _, err := store.GetTelegramRecipient(ctx, id, orgID); err != nil {
+				return policy, fmt.Errorf("step %d: Telegram recipient %d not found", i+1, id)
+			}
+		}
+
+		policy.Steps = append(policy.Steps, step)
+	}
+
+	return policy, nil
+}
+
+// HandleListEscalationPolicies handles GET /notifications/escalation-policies
+func HandleListEscalationPolicies(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		policies, err := store.ListEscalationPolicies(r.Context(), org.OrgID)
+		if err != nil {
+			http.Error(w, "Failed to list escalation policies", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policies)
+	}
+}
+
+// HandleCreateEscalationPolicy handles POST /notifications/escalation-policies
+func HandleCreateEscalationPolicy(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req EscalationPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		policy, err := req.validate(r.Context(), store, org.OrgID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		created, err := store.CreateEscalationPolicy(r.Context(), policy)
+		if err != nil {
+			http.Error(w, "Failed to create escalation policy", http.StatusInternalServerError)
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "escalation_policy.create", TargetType: "escalation_policy",
+			TargetID: created.ID, After: created})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+	}
+}
+
+// HandleUpdateEscalationPolicy handles PUT /notifications/escalation-policies/{id}
+func HandleUpdateEscalationPolicy(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := escalationPolicyIDFromPath(r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid escalation policy ID", http.StatusBadRequest)
+			return
+		}
+
+		var req EscalationPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		policy, err := req.validate(r.Context(), store, org.OrgID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		policy.ID = id
+
+		// Kept for the audit log; when missing, the update below reports why
+		before, _ := store.GetEscalationPolicy(r.Context(), id, org.OrgID)
+
+		updated, err := store.UpdateEscalationPolicy(r.Context(), policy)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				http.Error(w, "Escalation policy not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to update escalation policy", http.StatusInternalServerError)
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "escalation_policy.update", TargetType: "escalation_policy",
+			TargetID: id, Before: before, After: updated})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+	}
+}
+
+// HandleDeleteEscalationPolicy handles DELETE /notifications/escalation-policies/{id}
+func HandleDeleteEscalationPolicy(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		id, err := escalationPolicyIDFromPath(r.URL.Path)
+		if err != nil {
+			http.Error(w, "Invalid escalation policy ID", http.StatusBadRequest)
+			return
+		}
+
+		// Kept for the audit log; when missing, the delete below reports why
+		before, _ := store.GetEscalationPolicy(r.Context(), id, org.OrgID)
+
+		if err := store.DeleteEscalationPolicy(r.Context(), id, org.OrgID); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				http.Error(w, "Escalation policy not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "Failed to delete escalation policy", http.StatusInternalServerError)
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "escalation_policy.delete", TargetType: "escalation_policy",
+			TargetID: id, Before: before})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// escalationPolicyIDFromPath extracts the policy ID from /notifications/escalation-policies/{id}
+func escalationPolicyIDFromPath(path string) (int, error) {
+	return strconv.Atoi(strings.TrimPrefix(path, "/notifications/escalation-policies/"))
+}
@@ -0,0 +1,213 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// recordingEscalations records escalation pages instead of sending them
+type recordingEscalations struct {
+	mu    sync.Mutex
+	pages []AlertEscalation
+}
+
+func (r *recordingEscalations) NotifyEscalation(ctx context.Context, userID int, escalation AlertEscalation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pages = append(r.pages, escalation)
+	return nil
+}
+
+func (r *recordingEscalations) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.pages)
+}
+
+// setupEscalation creates a rule with a two step policy (15 minutes, then 10 more) and a firing event
+func setupEscalation(t *testing.T) (*EscalationScheduler, *recordingEscalations, *storage.SQLiteStore, *storage.AlertEvent) {
+	t.Helper()
+	ctx := context.Background()
+
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	user, _ := store.CreateUser(ctx, "oncall@example.com", "hash")
+	policy, err := store.CreateEscalationPolicy(ctx, storage.EscalationPolicy{
+		OrgID: user.ID,
+		Name:  "Secondary on-call",
+		Steps: []storage.EscalationStep{
+			{WaitSeconds: 900, TelegramRecipientIDs: []int{1}},
+			{WaitSeconds: 600, WebhookIDs: []int{2}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	rule, err := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 90,
+		TriggerAfter: 1, EscalationPolicyID: &policy.ID})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	event, _ := store.CreateAlertEvent(ctx, rule.ID, nil, 95)
+
+	pages := &recordingEscalations{}
+	scheduler := NewEscalationScheduler(store, pages, log.New(io.Discard, "", 0))
+	return scheduler, pages, store, event
+}
+
+func TestEscalationScheduler_AdvancesThroughSteps(t *testing.T) {
+	scheduler, pages, _, event := setupEscalation(t)
+	ctx := context.Background()
+	fired := event.TriggeredAt
+
+	steps := []struct {
+		after time.Duration
+		pages int
+	}{
+		{14 * time.Minute, 0},
+		{15 * time.Minute, 1}, // secondary on-call
+		{16 * time.Minute, 1}, // the second step waits from the first
+		{25 * time.Minute, 2},
+		{time.Hour, 2}, // out of steps
+	}
+	for _, step := range steps {
+		scheduler.advance(ctx, fired.Add(step.after))
+		if got := pages.count(); got != step.pages {
+			t.Fatalf("After %v: expected %d pages, got %d", step.after, step.pages, got)
+		}
+	}
+
+	first, second := pages.pages[0], pages.pages[1]
+	if first.Step != 1 || first.PolicyName != "Secondary on-call" || first.Event.ID != event.ID || len(first.TelegramRecipientIDs) != 1 {
+		t.Errorf("Unexpected first page: %+v", first)
+	}
+	if second.Step != 2 || len(second.WebhookIDs) != 1 || second.WebhookIDs[0] != 2 {
+		t.Errorf("Unexpected second page: %+v", second)
+	}
+}
+
+func TestEscalationScheduler_StopsOnAckAndResolve(t *testing.T) {
+	scheduler, pages, store, event := setupEscalation(t)
+	ctx := context.Background()
+
+	if err := store.AckAlertEvent(ctx, event.ID); err != nil {
+		t.Fatalf("Failed to ack event: %v", err)
+	}
+	scheduler.advance(ctx, event.TriggeredAt.Add(time.Hour))
+	if pages.count() != 0 {
+		t.Errorf("Expected no pages for an acknowledged event, got %d", pages.count())
+	}
+
+	resolved, _ := store.CreateAlertEvent(ctx, event.RuleID, nil, 97)
+	store.ResolveAlertEvent(ctx, resolved.ID)
+	scheduler.advance(ctx, resolved.TriggeredAt.Add(time.Hour))
+	if pages.count() != 0 {
+		t.Errorf("Expected no pages for a resolved event, got %d", pages.count())
+	}
+}
+
+func TestEscalationScheduler_SkipsSilencedRules(t *testing.T) {
+	scheduler, pages, store, event := setupEscalation(t)
+	ctx := context.Background()
+	now := event.TriggeredAt.Add(20 * time.Minute)
+
+	ruleID := event.RuleID
+	end := now.Add(time.Hour)
+	silence := storage.Silence{OrgID: 1, RuleID: &ruleID, CreatedBy: 1, Reason: "maintenance", StartsAt: now.Add(-time.Hour), EndsAt: &end}
+	if _, err := store.CreateSilence(ctx, silence); err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+
+	scheduler.advance(ctx, now)
+	if pages.count() != 0 {
+		t.Errorf("Expected no pages while the rule is silenced, got %d", pages.count())
+	}
+}
+
+func TestEscalationScheduler_OnlyPagesThePolicysOrganization(t *testing.T) {
+	scheduler, pages, store, event := setupEscalation(t)
+	ctx := context.Background()
+
+	// The policy belongs to the first user's personal organization; a rule of
+	// another organization that still points at it must not page anyone
+	rule, _ := store.GetAlertRule(ctx, event.RuleID, 1)
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	foreignRule, err := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: other.ID, Name: "Foreign CPU", Metric: "cpu_pct", Comparison: "above",
+		ThresholdPct: 90, TriggerAfter: 1, EscalationPolicyID: rule.EscalationPolicyID})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	foreignEvent, _ := store.CreateAlertEvent(ctx, foreignRule.ID, nil, 95)
+
+	scheduler.advance(ctx, foreignEvent.TriggeredAt.Add(20*time.Minute))
+
+	paged := map[int]bool{}
+	for _, page := range pages.pages {
+		paged[page.Event.ID] = true
+	}
+	if !paged[event.ID] {
+		t.Errorf("Expected the organization's own rule to be paged, got %v", paged)
+	}
+	if paged[foreignEvent.ID] {
+		t.Error("Expected no page for another organization's rule")
+	}
+}
+
+func TestHandleCreateEscalationPolicy(t *testing.T) {
+	_, _, store, _ := setupEscalation(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "author@example.com", "hash")
+	webhook, _ := store.CreateWebhook(ctx, user.ID, "https://example.com/hook", "secret-hash")
+	foreign, _ := store.CreateWebhook(ctx, 1, "https://example.com/other", "secret-hash")
+
+	tests := []struct {
+		name           string
+		body           EscalationPolicyRequest
+		expectedStatus int
+	}{
+		{"valid", EscalationPolicyRequest{Name: "Secondary", Steps: []storage.EscalationStep{{WebhookIDs: []int{webhook.ID}}}}, http.StatusCreated},
+		{"missing name", EscalationPolicyRequest{Steps: []storage.EscalationStep{{WebhookIDs: []int{webhook.ID}}}}, http.StatusBadRequest},
+		{"no steps", EscalationPolicyRequest{Name: "Secondary"}, http.StatusBadRequest},
+		{"wait too short", EscalationPolicyRequest{Name: "Secondary", Steps: []storage.EscalationStep{{WaitSeconds: 5, WebhookIDs: []int{webhook.ID}}}}, http.StatusBadRequest},
+		{"no destinations", EscalationPolicyRequest{Name: "Secondary", Steps: []storage.EscalationStep{{WaitSeconds: 300}}}, http.StatusBadRequest},
+		{"foreign webhook", EscalationPolicyRequest{Name: "Secondary", Steps: []storage.EscalationStep{{WebhookIDs: []int{foreign.ID}}}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/notifications/escalation-policies", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+				&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner}))
+			w := httptest.NewRecorder()
+
+			HandleCreateEscalationPolicy(store)(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	policies, _ := store.ListEscalationPolicies(ctx, user.ID)
+	if len(policies) != 1 || policies[0].Steps[0].WaitSeconds != int(DefaultEscalationWait/time.Second) {
+		t.Errorf("Expected one policy with the default 15 minute wait, got %+v", policies)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
@@ -20,6 +21,8 @@ type WebhookRequest struct {
 	URL      string `json:"url"`
 	Secret   string `json:"secret"`
 	IsActive *bool  `json:"is_active,omitempty"`
+	// DeliverySettings replaces all delivery overrides when present; omitted fields revert to server defaults
+	DeliverySettings *storage.DeliverySettings `json:"delivery_settings,omitempty"`
 }
 
 // WebhookResponse represents the response body for webhook operations
@@ -35,6 +38,23 @@ type WebhookResponse struct {
 	CreatedAt      time.Time  `json:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at"`
 	SecretLastFour string     `json:"secret_last_four"`
+	// DeliverySettings holds the webhook's overrides; null fields use the server defaults
+	DeliverySettings storage.DeliverySettings `json:"delivery_settings"`
+	// PreviousSecretExpiresAt is set while a rotated-out secret still signs deliveries
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
+
+// Secret rotation grace period bounds
+const (
+	DefaultSecretRotationGracePeriod = 24 * time.Hour
+	MaxSecretRotationGracePeriod     = 7 * 24 * time.Hour
+)
+
+// RotateSecretRequest represents the request body for rotating a webhook secret
+type RotateSecretRequest struct {
+	Secret string `json:"secret"`
+	// GracePeriodSeconds is how long the old secret keeps signing deliveries (default 24h, max 7 days)
+	GracePeriodSeconds *int `json:"grace_period_seconds,omitempty"`
 }
 
 // validateWebhookRequest validates webhook request data
@@ -66,7 +86,7 @@ func validateWebhookRequest(req *WebhookRequest) error {
 		}
 	}
 
-	return nil
+	return validateDeliverySettings(req.DeliverySettings)
 }
 
 // getSecretLastFour returns the last 4 characters of the secret for display
@@ -79,7 +99,7 @@ func getSecretLastFour(secret string) string {
 
 // webhookToResponse converts a storage.Webhook to WebhookResponse (hiding secret hash)
 func webhookToResponse(webhook storage.Webhook, secretLastFour string) WebhookResponse {
-	return WebhookResponse{
+	response := WebhookResponse{
 		ID:             webhook.ID,
 		URL:            webhook.URL,
 		IsActive:       webhook.IsActive,
@@ -91,7 +111,13 @@ func webhookToResponse(webhook storage.Webhook, secretLastFour string) WebhookRe
 		CreatedAt:      webhook.CreatedAt,
 		UpdatedAt:      webhook.UpdatedAt,
 		SecretLastFour: secretLastFour,
+
+		DeliverySettings: webhook.DeliverySettings,
 	}
+	if webhook.PreviousSecretExpiresAt != nil && time.Now().Before(*webhook.PreviousSecretExpiresAt) {
+		response.PreviousSecretExpiresAt = webhook.PreviousSecretExpiresAt
+	}
+	return response
 }
 
 // HandleListWebhooks handles GET /notifications/webhooks
@@ -102,15 +128,15 @@ func HandleListWebhooks(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		// Get user from context (set by RequireAuth middleware)
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		// Get webhooks for the user
-		webhooks, err := store.ListWebhooks(r.Context(), user.ID)
+		// Get webhooks for the organization
+		webhooks, err := store.ListWebhooks(r.Context(), org.OrgID)
 		if err != nil {
 			http.Error(w, `{"error":"Failed to list webhooks"}`, http.StatusInternalServerError)
 			return
@@ -136,8 +162,8 @@ func HandleCreateWebhook(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		// Get user from context (set by RequireAuth middleware)
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -178,12 +204,12 @@ func HandleCreateWebhook(store storage.Store) http.HandlerFunc {
 		secretLastFour := getSecretLastFour(req.Secret)
 
 		// Create webhook
-		webhook, err := store.CreateWebhook(r.Context(), user.ID, req.URL, secretHash)
+		webhook, err := store.CreateWebhook(r.Context(), org.OrgID, req.URL, secretHash)
 		if err != nil {
 			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusConflict)
-				json.NewEncoder(w).Encode(map[string]string{"error": "Webhook URL already exists for this user"})
+				json.NewEncoder(w).Encode(map[string]string{"error": "Webhook URL already exists for this organization"})
 				return
 			}
 			http.Error(w, `{"error":"Failed to create webhook"}`, http.StatusInternalServerError)
@@ -192,7 +218,7 @@ func HandleCreateWebhook(store storage.Store) http.HandlerFunc {
 
 		// Update is_active if provided and different from default
 		if req.IsActive != nil && !*req.IsActive {
-			updatedWebhook, err := store.UpdateWebhook(r.Context(), webhook.ID, user.ID, "", nil, req.IsActive)
+			updatedWebhook, err := store.UpdateWebhook(r.Context(), webhook.ID, org.OrgID, "", nil, req.IsActive)
 			if err != nil {
 				http.Error(w, `{"error":"Failed to update webhook status"}`, http.StatusInternalServerError)
 				return
@@ -200,6 +226,19 @@ func HandleCreateWebhook(store storage.Store) http.HandlerFunc {
 			webhook = updatedWebhook
 		}
 
+		// Apply delivery overrides if provided
+		if req.DeliverySettings != nil {
+			updatedWebhook, err := store.UpdateWebhookDeliverySettings(r.Context(), webhook.ID, org.OrgID, *req.DeliverySettings)
+			if err != nil {
+				http.Error(w, `{"error":"Failed to update webhook delivery settings"}`, http.StatusInternalServerError)
+				return
+			}
+			webhook = updatedWebhook
+		}
+
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "webhook.create", TargetType: "webhook",
+			TargetID: webhook.ID, After: webhook})
+
 		// Return response
 		response := webhookToResponse(*webhook, secretLastFour)
 		w.Header().Set("Content-Type", "application/json")
@@ -216,8 +255,8 @@ func HandleUpdateWebhook(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		// Get user from context (set by RequireAuth middleware)
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -259,6 +298,13 @@ func HandleUpdateWebhook(store storage.Store) http.HandlerFunc {
 			}
 		}
 
+		if err := validateDeliverySettings(req.DeliverySettings); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
 		// Prepare update parameters
 		var secretHash *string
 		var secretLastFour string
@@ -276,8 +322,11 @@ func HandleUpdateWebhook(store storage.Store) http.HandlerFunc {
 			secretLastFour = getSecretLastFour(req.Secret)
 		}
 
+		// Kept for the audit log; when missing, the update below reports why
+		before, _ := store.GetWebhook(r.Context(), webhookID, org.OrgID)
+
 		// Update webhook
-		webhook, err := store.UpdateWebhook(r.Context(), webhookID, user.ID, req.URL, secretHash, req.IsActive)
+		webhook, err := store.UpdateWebhook(r.Context(), webhookID, org.OrgID, req.URL, secretHash, req.IsActive)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
 				w.Header().Set("Content-Type", "application/json")
@@ -288,13 +337,25 @@ func HandleUpdateWebhook(store storage.Store) http.HandlerFunc {
 			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusConflict)
-				json.NewEncoder(w).Encode(map[string]string{"error": "Webhook URL already exists for this user"})
+				json.NewEncoder(w).Encode(map[string]string{"error": "Webhook URL already exists for this organization"})
 				return
 			}
 			http.Error(w, `{"error":"Failed to update webhook"}`, http.StatusInternalServerError)
 			return
 		}
 
+		// Replace delivery overrides if provided
+		if req.DeliverySettings != nil {
+			webhook, err = store.UpdateWebhookDeliverySettings(r.Context(), webhookID, org.OrgID, *req.DeliverySettings)
+			if err != nil {
+				http.Error(w, `{"error":"Failed to update webhook delivery settings"}`, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "webhook.update", TargetType: "webhook",
+			TargetID: webhookID, Before: before, After: webhook})
+
 		// If no secret was updated, show "****" for last four
 		if secretLastFour == "" {
 			secretLastFour = "****"
@@ -315,8 +376,8 @@ func HandleDeleteWebhook(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		// Get user from context (set by RequireAuth middleware)
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -340,8 +401,11 @@ func HandleDeleteWebhook(store storage.Store) http.HandlerFunc {
 			return
 		}
 
+		// Kept for the audit log; when missing, the delete below reports why
+		before, _ := store.GetWebhook(r.Context(), webhookID, org.OrgID)
+
 		// Delete webhook
-		err = store.DeleteWebhook(r.Context(), webhookID, user.ID)
+		err = store.DeleteWebhook(r.Context(), webhookID, org.OrgID)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
 				w.Header().Set("Content-Type", "application/json")
@@ -352,12 +416,152 @@ func HandleDeleteWebhook(store storage.Store) http.HandlerFunc {
 			http.Error(w, `{"error":"Failed to delete webhook"}`, http.StatusInternalServerError)
 			return
 		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "webhook.delete", TargetType: "webhook",
+			TargetID: webhookID, Before: before})
 
 		// Return 204 No Content on success
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// HandleResetWebhookCircuit handles POST /notifications/webhooks/{id}/reset-circuit.
+// Clears the failure count, cooldown and rate limit window so deliveries resume immediately.
+func HandleResetWebhookCircuit(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Get organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Extract webhook ID from URL path
+		// Path format: /notifications/webhooks/{id}/reset-circuit
+		path := strings.TrimPrefix(r.URL.Path, "/notifications/webhooks/")
+		path = strings.TrimSuffix(path, "/reset-circuit")
+
+		if path == "" || path == r.URL.Path {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Webhook ID required"})
+			return
+		}
+
+		// Parse webhook ID
+		webhookID, err := strconv.Atoi(path)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid webhook ID"})
+			return
+		}
+
+		webhook, err := store.ResetWebhookCircuit(r.Context(), webhookID, org.OrgID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Webhook not found"})
+				return
+			}
+			http.Error(w, `{"error":"Failed to reset webhook circuit"}`, http.StatusInternalServerError)
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "webhook.reset_circuit", TargetType: "webhook", TargetID: webhookID})
+
+		response := webhookToResponse(*webhook, "****")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// HandleRotateWebhookSecret handles POST /notifications/webhooks/{id}/rotate-secret.
+// The new secret takes effect immediately; the old one keeps signing deliveries until the grace period ends.
+func HandleRotateWebhookSecret(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Get organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		// Extract webhook ID from URL path
+		// Path format: /notifications/webhooks/{id}/rotate-secret
+		path := strings.TrimPrefix(r.URL.Path, "/notifications/webhooks/")
+		path = strings.TrimSuffix(path, "/rotate-secret")
+
+		if path == "" || path == r.URL.Path {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Webhook ID required"})
+			return
+		}
+
+		// Parse webhook ID
+		webhookID, err := strconv.Atoi(path)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid webhook ID"})
+			return
+		}
+
+		var req RotateSecretRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
+			return
+		}
+
+		if len(req.Secret) < 16 || len(req.Secret) > 128 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "secret must be between 16 and 128 characters"})
+			return
+		}
+
+		gracePeriod := DefaultSecretRotationGracePeriod
+		if req.GracePeriodSeconds != nil {
+			gracePeriod = time.Duration(*req.GracePeriodSeconds) * time.Second
+			if gracePeriod < 0 || gracePeriod > MaxSecretRotationGracePeriod {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "grace_period_seconds must be between 0 and 604800"})
+				return
+			}
+		}
+
+		webhook, err := store.RotateWebhookSecret(r.Context(), webhookID, org.OrgID, storage.HashSecret(req.Secret), time.Now().Add(gracePeriod))
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Webhook not found"})
+				return
+			}
+			http.Error(w, `{"error":"Failed to rotate webhook secret"}`, http.StatusInternalServerError)
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "webhook.rotate_secret", TargetType: "webhook", TargetID: webhookID})
+
+		response := webhookToResponse(*webhook, getSecretLastFour(req.Secret))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
 // TestWebhookResponse represents the response body for test webhook operation
 type TestWebhookResponse struct {
 	Status      string `json:"status"`
@@ -373,8 +577,8 @@ func HandleTestWebhook(notifier WebhookTester, store storage.Store) http.Handler
 			return
 		}
 
-		// Get user from context (set by RequireAuth middleware)
-		user, ok := auth.GetUserFromContext(r.Context())
+		// Get organization from context (set by RequireAuth middleware)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
@@ -402,7 +606,7 @@ func HandleTestWebhook(notifier WebhookTester, store storage.Store) http.Handler
 		}
 
 		// Fetch webhook with ownership verification
-		webhook, err := store.GetWebhook(r.Context(), webhookID, user.ID)
+		webhook, err := store.GetWebhook(r.Context(), webhookID, org.OrgID)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
 				w.Header().Set("Content-Type", "application/json")
@@ -455,4 +659,3 @@ func HandleTestWebhook(notifier WebhookTester, store storage.Store) http.Handler
 		json.NewEncoder(w).Encode(response)
 	}
 }
-
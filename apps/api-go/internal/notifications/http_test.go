@@ -62,7 +62,7 @@ func (m *mockHTTPStore) CreateWebhook(ctx context.Context, userID int, url, secr
 
 	webhook := &storage.Webhook{
 		ID:         m.nextWebhookID,
-		UserID:     userID,
+		OrgID:      userID,
 		URL:        url,
 		SecretHash: secretHash,
 		IsActive:   true,
@@ -171,22 +171,22 @@ func (m *mockHTTPStore) PromoteToAdmin(ctx context.Context, userID int) error {
 func (m *mockHTTPStore) DeletePasswordResetsForUser(ctx context.Context, userID int) error {
 	return fmt.Errorf("not implemented")
 }
-func (m *mockHTTPStore) CreateAlertRule(ctx context.Context, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
+func (m *mockHTTPStore) ListAlertRules(ctx context.Context, orgID int) ([]storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockHTTPStore) ListAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
+func (m *mockHTTPStore) ListAllAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockHTTPStore) GetAlertRule(ctx context.Context, id int) (*storage.AlertRule, error) {
+func (m *mockHTTPStore) SaveAlertRule(ctx context.Context, rule storage.AlertRule) (*storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockHTTPStore) UpdateAlertRule(ctx context.Context, id int, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
+func (m *mockHTTPStore) GetAlertRule(ctx context.Context, id int, orgID int) (*storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockHTTPStore) DeleteAlertRule(ctx context.Context, id int) error {
+func (m *mockHTTPStore) DeleteAlertRule(ctx context.Context, id int, orgID int) error {
 	return fmt.Errorf("not implemented")
 }
-func (m *mockHTTPStore) CreateAlertEvent(ctx context.Context, ruleID int, value float64) (*storage.AlertEvent, error) {
+func (m *mockHTTPStore) CreateAlertEvent(ctx context.Context, ruleID int, machineID *int, value float64) (*storage.AlertEvent, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 func (m *mockHTTPStore) ListAlertEvents(ctx context.Context, limit int) ([]storage.AlertEvent, error) {
@@ -195,6 +195,10 @@ func (m *mockHTTPStore) ListAlertEvents(ctx context.Context, limit int) ([]stora
 func (m *mockHTTPStore) AckAlertEvent(ctx context.Context, id int) error {
 	return fmt.Errorf("not implemented")
 }
+
+func (m *mockHTTPStore) GetAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("alert event with id %d not found", id)
+}
 func (m *mockHTTPStore) ListActiveEvents(ctx context.Context, limit int) ([]storage.AlertEvent, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -237,7 +241,7 @@ func (m *mockHTTPStore) ListTelegramRecipients(ctx context.Context, userID int)
 func (m *mockHTTPStore) GetTelegramRecipient(ctx context.Context, id int, userID int) (*storage.TelegramRecipient, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockHTTPStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string) (*storage.TelegramRecipient, error) {
+func (m *mockHTTPStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string, createdBy int) (*storage.TelegramRecipient, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 func (m *mockHTTPStore) UpdateTelegramRecipient(ctx context.Context, id int, userID int, chatID string, isActive *bool) (*storage.TelegramRecipient, error) {
@@ -313,6 +317,18 @@ func (m *mockHTTPStore) ClearMachineOfflineNotification(ctx context.Context, mac
 	return nil
 }
 
+func (m *mockHTTPStore) HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error {
+	return nil
+}
+
+func (m *mockHTTPStore) MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error) {
+	return false, nil
+}
+
+func (m *mockHTTPStore) ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error {
+	return nil
+}
+
 // Machine credential management methods (not implemented for these tests)
 func (m *mockHTTPStore) SetMachineEnabled(ctx context.Context, machineID int, enabled bool) error {
 	return fmt.Errorf("not implemented")
@@ -342,6 +358,420 @@ func (m *mockHTTPStore) ListMachineAPIKeys(ctx context.Context, machineID int) (
 	return nil, fmt.Errorf("not implemented")
 }
 
+// Alert resolution and notification template methods (stub implementations for testing)
+func (m *mockHTTPStore) ResolveAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListNotificationTemplates(ctx context.Context, userID int) ([]storage.NotificationTemplate, error) {
+	return []storage.NotificationTemplate{}, nil
+}
+
+func (m *mockHTTPStore) GetNotificationTemplate(ctx context.Context, userID int, channel, eventType string) (*storage.NotificationTemplate, error) {
+	return nil, storage.ErrNotificationTemplateNotFound
+}
+
+func (m *mockHTTPStore) UpsertNotificationTemplate(ctx context.Context, userID int, channel, eventType, body string) (*storage.NotificationTemplate, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) DeleteNotificationTemplate(ctx context.Context, id int, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Delivery settings methods (stub implementations for testing)
+func (m *mockHTTPStore) UpdateWebhookDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.Webhook, error) {
+	for i, webhook := range m.webhooks[userID] {
+		if webhook.ID == id {
+			webhook.DeliverySettings = settings
+			m.webhooks[userID][i] = webhook
+			return &webhook, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook with id %d not found for user %d", id, userID)
+}
+
+func (m *mockHTTPStore) ResetWebhookCircuit(ctx context.Context, id int, userID int) (*storage.Webhook, error) {
+	for i, webhook := range m.webhooks[userID] {
+		if webhook.ID == id {
+			webhook.FailureCount = 0
+			webhook.CooldownUntil = nil
+			webhook.LastAttemptAt = nil
+			m.webhooks[userID][i] = webhook
+			return &webhook, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook with id %d not found for user %d", id, userID)
+}
+
+func (m *mockHTTPStore) UpdateTelegramDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ResetTelegramCircuit(ctx context.Context, id int, userID int) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) RotateWebhookSecret(ctx context.Context, id int, userID int, newSecretHash string, previousExpiresAt time.Time) (*storage.Webhook, error) {
+	for i, webhook := range m.webhooks[userID] {
+		if webhook.ID == id {
+			previous := webhook.SecretHash
+			webhook.PreviousSecretHash = &previous
+			webhook.PreviousSecretExpiresAt = &previousExpiresAt
+			webhook.SecretHash = newSecretHash
+			m.webhooks[userID][i] = webhook
+			return &webhook, nil
+		}
+	}
+	return nil, fmt.Errorf("webhook with id %d not found for user %d", id, userID)
+}
+
+func (m *mockHTTPStore) CreateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListActiveSilences(ctx context.Context, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockHTTPStore) ListTelegramRecipientsByChatID(ctx context.Context, chatID string) ([]storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetNotificationPolicy(ctx context.Context, userID int) (*storage.NotificationPolicy, error) {
+	return nil, storage.ErrNotificationPolicyNotFound
+}
+
+func (m *mockHTTPStore) UpsertNotificationPolicy(ctx context.Context, policy storage.NotificationPolicy) (*storage.NotificationPolicy, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) AddPendingDigestEntry(ctx context.Context, entry storage.PendingDigestEntry) (*storage.PendingDigestEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListPendingDigestEntries(ctx context.Context) ([]storage.PendingDigestEntry, error) {
+	return nil, nil
+}
+
+func (m *mockHTTPStore) DeletePendingDigestEntries(ctx context.Context, orgID, maxID int) error {
+	return nil
+}
+
+func (m *mockHTTPStore) ListNotificationRoutes(ctx context.Context, userID int) ([]storage.NotificationRoute, error) {
+	return []storage.NotificationRoute{}, nil
+}
+
+func (m *mockHTTPStore) GetNotificationRoute(ctx context.Context, id int, userID int) (*storage.NotificationRoute, error) {
+	return nil, fmt.Errorf("notification route with id %d not found for user %d", id, userID)
+}
+
+func (m *mockHTTPStore) CreateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockHTTPStore) UpdateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockHTTPStore) DeleteNotificationRoute(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockHTTPStore) GetMachineTags(ctx context.Context, machineID int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *mockHTTPStore) SetMachineTags(ctx context.Context, machineID int, tags []string) error {
+	return nil
+}
+
+func (m *mockHTTPStore) ListEscalationPolicies(ctx context.Context, userID int) ([]storage.EscalationPolicy, error) {
+	return []storage.EscalationPolicy{}, nil
+}
+
+func (m *mockHTTPStore) GetEscalationPolicy(ctx context.Context, id int, userID int) (*storage.EscalationPolicy, error) {
+	return nil, fmt.Errorf("escalation policy with id %d not found for user %d", id, userID)
+}
+
+func (m *mockHTTPStore) CreateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockHTTPStore) UpdateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockHTTPStore) DeleteEscalationPolicy(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockHTTPStore) ListPendingEscalations(ctx context.Context) ([]storage.PendingEscalation, error) {
+	return []storage.PendingEscalation{}, nil
+}
+
+func (m *mockHTTPStore) RecordAlertEscalation(ctx context.Context, eventID int, step int, escalatedAt time.Time) error {
+	return nil
+}
+
+func (m *mockHTTPStore) GetSilence(ctx context.Context, id int, orgID int) (*storage.Silence, error) {
+	return nil, fmt.Errorf("silence with id %d not found", id)
+}
+
+func (m *mockHTTPStore) ListSilences(ctx context.Context, orgID int, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockHTTPStore) UpdateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return &silence, nil
+}
+
+func (m *mockHTTPStore) DeleteSilence(ctx context.Context, id int, orgID int) error {
+	return nil
+}
+
+func (m *mockHTTPStore) SetAlertEventExpectedRange(ctx context.Context, id int, low, high float64) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetMetricBaseline(ctx context.Context, machineID int, metric, seasonality string, slot int) (*storage.MetricBaseline, error) {
+	return nil, storage.ErrMetricBaselineNotFound
+}
+
+func (m *mockHTTPStore) UpsertMetricBaseline(ctx context.Context, baseline storage.MetricBaseline) error {
+	return nil
+}
+
+func (m *mockHTTPStore) ListOpenAlertEvents(ctx context.Context) ([]storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListAlertRuleStates(ctx context.Context) ([]storage.AlertRuleState, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) UpsertAlertRuleState(ctx context.Context, state storage.AlertRuleState) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) DeleteAlertRuleStates(ctx context.Context, ruleID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) QueryAlertEvents(ctx context.Context, filter storage.AlertEventFilter) ([]storage.AlertEvent, *storage.AlertEventCursor, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) CreateSession(ctx context.Context, session storage.Session) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetSession(ctx context.Context, id int) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetSessionByRefreshHash(ctx context.Context, tokenHash string) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) RotateSessionRefreshToken(ctx context.Context, id int, oldHash, newHash string, expiresAt time.Time, ipAddress string) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) TouchSession(ctx context.Context, id int, ipAddress string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListSessions(ctx context.Context, userID int) ([]storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) RevokeSession(ctx context.Context, id, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) RevokeUserSessions(ctx context.Context, userID, exceptID int) (int, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) CreateAPIToken(ctx context.Context, token storage.APIToken) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetAPITokenByHash(ctx context.Context, tokenHash string) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListAPITokens(ctx context.Context, userID int) ([]storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) RevokeAPIToken(ctx context.Context, id, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) TouchAPIToken(ctx context.Context, id int, ipAddress string) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Two-factor authentication methods (stub implementations for testing)
+func (m *mockHTTPStore) GetUserTOTP(ctx context.Context, userID int) (*storage.UserTOTP, error) {
+	return nil, storage.ErrTOTPNotFound
+}
+
+func (m *mockHTTPStore) SetUserTOTPSecret(ctx context.Context, userID int, secret string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) EnableUserTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) DeleteUserTOTP(ctx context.Context, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockHTTPStore) GetSetting(ctx context.Context, key string) (string, error) {
+	return "", storage.ErrSettingNotFound
+}
+
+func (m *mockHTTPStore) SetSetting(ctx context.Context, key, value string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*storage.User, error) {
+	return nil, storage.ErrUserNotFound
+}
+
+func (m *mockHTTPStore) LinkUserIdentity(ctx context.Context, identity storage.UserIdentity) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) CreateOIDCLogin(ctx context.Context, login storage.OIDCLogin) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*storage.OIDCLogin, error) {
+	return nil, storage.ErrOIDCLoginNotFound
+}
+
+func (m *mockHTTPStore) GetLoginAttempts(ctx context.Context, key string) (*storage.LoginAttempts, error) {
+	return nil, storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockHTTPStore) RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ClearLoginAttempts(ctx context.Context, key string) error {
+	return storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockHTTPStore) ListLoginLockouts(ctx context.Context) ([]storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) CreateAuditEntry(ctx context.Context, entry storage.AuditEntry) (*storage.AuditEntry, error) {
+	return &entry, nil
+}
+
+func (m *mockHTTPStore) ListAuditEntries(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) SetUserRole(ctx context.Context, userID int, role string) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Organization methods (stub implementations for testing)
+func (m *mockHTTPStore) CreateOrganization(ctx context.Context, name string, ownerID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetOrganization(ctx context.Context, id int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetPersonalOrganization(ctx context.Context, userID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) RenameOrganization(ctx context.Context, id int, name string) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) DeleteOrganization(ctx context.Context, id int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListOrgMembers(ctx context.Context, orgID int) ([]storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) AddOrgMember(ctx context.Context, orgID, userID int, role string) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) SetOrgMemberRole(ctx context.Context, orgID, userID int, role string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) RemoveOrgMember(ctx context.Context, orgID, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) CreateOrgInvitation(ctx context.Context, invitation storage.OrgInvitation) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetOrgInvitationByHash(ctx context.Context, tokenHash string) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListOrgInvitations(ctx context.Context, orgID int) ([]storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) DeleteOrgInvitation(ctx context.Context, id, orgID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) AcceptOrgInvitation(ctx context.Context, id, userID int) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListOrganizations(ctx context.Context) ([]storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) ListUserOrganizations(ctx context.Context, userID int) ([]storage.UserOrganization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockHTTPStore) GetOrgMember(ctx context.Context, orgID, userID int) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockHTTPStore) Close() error {
 	return nil
 }
@@ -357,7 +787,8 @@ func createAuthenticatedRequest(method, url string, body []byte, userID int) *ht
 
 	// Mock user in context using the same key as the auth middleware
 	user := &storage.User{ID: userID, Email: "test@example.com"}
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	return req.WithContext(ctx)
 }
 
@@ -764,7 +1195,8 @@ func TestHandleTestWebhook_Success(t *testing.T) {
 
 	// Create request with user context
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/1/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -835,7 +1267,8 @@ func TestHandleTestWebhook_NotFound(t *testing.T) {
 
 	// Create request for non-existent webhook
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/999/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -868,7 +1301,8 @@ func TestHandleTestWebhook_InactiveWebhook(t *testing.T) {
 
 	// Create request
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/1/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -910,7 +1344,8 @@ func TestHandleTestWebhook_NotifierFailure(t *testing.T) {
 
 	// Create request
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/1/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -948,7 +1383,8 @@ func TestHandleTestWebhook_InvalidID(t *testing.T) {
 
 	// Create request with invalid ID
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/invalid/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -980,7 +1416,8 @@ func TestHandleTestWebhook_WrongUser(t *testing.T) {
 
 	// Try to access user1's webhook as user2
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/1/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user2)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user2), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user2.ID, UserID: user2.ID, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -998,3 +1435,46 @@ func TestHandleTestWebhook_WrongUser(t *testing.T) {
 		t.Errorf("Expected 0 SendTest calls, got %d", len(notifier.sendTestCalls))
 	}
 }
+
+func TestHandleRotateWebhookSecret(t *testing.T) {
+	store := newMockHTTPStore()
+	handler := HandleRotateWebhookSecret(store)
+
+	webhook, _ := store.CreateWebhook(context.Background(), 1, "https://example.com/webhook", storage.HashSecret("old-secret-123456"))
+
+	body, _ := json.Marshal(RotateSecretRequest{Secret: "new-secret-abcdef"})
+	req := createAuthenticatedRequest("POST", fmt.Sprintf("/notifications/webhooks/%d/rotate-secret", webhook.ID), body, 1)
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response WebhookResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.SecretLastFour != "cdef" {
+		t.Errorf("Expected secret_last_four 'cdef', got '%s'", response.SecretLastFour)
+	}
+	if response.PreviousSecretExpiresAt == nil || time.Until(*response.PreviousSecretExpiresAt) < 23*time.Hour {
+		t.Errorf("Expected default 24h grace period, got %v", response.PreviousSecretExpiresAt)
+	}
+
+	stored := store.webhooks[1][0]
+	if stored.SecretHash != storage.HashSecret("new-secret-abcdef") || *stored.PreviousSecretHash != storage.HashSecret("old-secret-123456") {
+		t.Error("Expected new secret to be active and old secret kept as previous")
+	}
+
+	// Grace period is bounded
+	tooLong := int(MaxSecretRotationGracePeriod/time.Second) + 1
+	body, _ = json.Marshal(RotateSecretRequest{Secret: "another-secret-123", GracePeriodSeconds: &tooLong})
+	req = createAuthenticatedRequest("POST", fmt.Sprintf("/notifications/webhooks/%d/rotate-secret", webhook.ID), body, 1)
+	w = httptest.NewRecorder()
+	handler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for excessive grace period, got %d", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package notifications
 
 import (
 	"context"
+	"time"
 
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
@@ -12,6 +13,64 @@ type AlertNotifier interface {
 	Notify(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error
 }
 
+// AlertResolutionNotifier is implemented by notifiers that also announce resolved alerts
+type AlertResolutionNotifier interface {
+	// NotifyResolved sends notifications for an alert event that has cleared
+	NotifyResolved(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error
+}
+
+// OrgAlertNotifier is implemented by channels that can deliver alerts to a single organization,
+// which lets notification policies hold back or batch events per organization
+type OrgAlertNotifier interface {
+	// NotifyOrg delivers an alert.* event to one organization's destinations
+	NotifyOrg(ctx context.Context, orgID int, eventType string, rule storage.AlertRule, event storage.AlertEvent) error
+	// NotifyOrgDigest delivers a batch of alert events to one organization's destinations as a single message
+	NotifyOrgDigest(ctx context.Context, orgID int, digest AlertDigest) error
+}
+
+// EventAlertDigest is the event type for batched alert deliveries
+const EventAlertDigest = "alert.digest"
+
+// Digest reasons
+const (
+	DigestReasonWindow     = "digest"
+	DigestReasonQuietHours = "quiet_hours"
+)
+
+// DigestEntry is a single alert event held back for a digest
+type DigestEntry struct {
+	EventType string
+	Rule      storage.AlertRule
+	Event     storage.AlertEvent
+}
+
+// AlertDigest is a batch of alert events delivered together
+type AlertDigest struct {
+	Reason  string // DigestReasonWindow or DigestReasonQuietHours
+	Since   time.Time
+	Until   time.Time
+	Entries []DigestEntry
+}
+
+// EscalationNotifier is implemented by channels that can page the destinations of an escalation step
+type EscalationNotifier interface {
+	// NotifyEscalation delivers an escalated alert event to the step's destinations owned by orgID
+	NotifyEscalation(ctx context.Context, orgID int, escalation AlertEscalation) error
+}
+
+// EventAlertEscalated is the event type for alert events paged by an escalation policy step
+const EventAlertEscalated = "alert.escalated"
+
+// AlertEscalation is an escalation policy step paging an alert event nobody has acknowledged
+type AlertEscalation struct {
+	PolicyName           string
+	Step                 int // 1-based position of the step in the policy
+	Rule                 storage.AlertRule
+	Event                storage.AlertEvent
+	WebhookIDs           []int
+	TelegramRecipientIDs []int
+}
+
 // WebhookTester defines the interface for testing webhook delivery
 type WebhookTester interface {
 	// SendTest sends a test notification to a webhook
@@ -25,3 +84,11 @@ func (n *Notifier) Notify(ctx context.Context, rule storage.AlertRule, event *st
 	}
 	return n.Send(ctx, rule, *event)
 }
+
+// NotifyResolved implements AlertResolutionNotifier interface for the webhook notifier
+func (n *Notifier) NotifyResolved(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	if event == nil {
+		return nil
+	}
+	return n.SendResolved(ctx, rule, *event)
+}
@@ -28,35 +28,29 @@ func NewMachineHeartbeatNotifier(store storage.Store, webhookNotifier *Notifier,
 
 // NotifyMachineOffline sends notifications when a machine goes offline
 func (n *MachineHeartbeatNotifier) NotifyMachineOffline(ctx context.Context, machine storage.Machine) error {
-	// Get user for this machine
-	user, err := n.store.GetUserByID(ctx, machine.UserID)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	event := WebhookMachineEvent{
+		Event: EventMachineOffline,
+		Machine: WebhookMachine{
+			ID:          machine.ID,
+			Name:        machine.Name,
+			Hostname:    machine.Hostname,
+			Description: machine.Description,
+			Status:      "offline",
+			LastSeen:    machine.LastSeen,
+		},
 	}
 
 	// Send webhook notifications
 	if n.webhookNotifier != nil {
-		webhooks, err := n.store.ListWebhooks(ctx, user.ID)
+		webhooks, err := n.store.ListWebhooks(ctx, machine.OrgID)
 		if err != nil {
-			n.logger.Printf("Failed to list webhooks for user %d: %v", user.ID, err)
+			n.logger.Printf("Failed to list webhooks for organization %d: %v", machine.OrgID, err)
 		} else {
 			for _, webhook := range webhooks {
 				if !webhook.IsActive {
 					continue
 				}
 
-				event := WebhookMachineEvent{
-					Event: "machine.offline",
-					Machine: WebhookMachine{
-						ID:          machine.ID,
-						Name:        machine.Name,
-						Hostname:    machine.Hostname,
-						Description: machine.Description,
-						Status:      "offline",
-						LastSeen:    machine.LastSeen,
-					},
-				}
-
 				if err := n.webhookNotifier.SendMachineEvent(ctx, webhook, event); err != nil {
 					n.logger.Printf("Failed to send webhook notification for machine %d: %v", machine.ID, err)
 				}
@@ -66,11 +60,11 @@ func (n *MachineHeartbeatNotifier) NotifyMachineOffline(ctx context.Context, mac
 
 	// Send Telegram notifications
 	if n.telegramNotifier != nil {
-		recipients, err := n.store.ListTelegramRecipients(ctx, user.ID)
+		recipients, err := n.store.ListTelegramRecipients(ctx, machine.OrgID)
 		if err != nil {
-			n.logger.Printf("Failed to list Telegram recipients for user %d: %v", user.ID, err)
+			n.logger.Printf("Failed to list Telegram recipients for organization %d: %v", machine.OrgID, err)
 		} else {
-			message := fmt.Sprintf("🔴 *Machine Offline Alert*\n\n"+
+			defaultMessage := fmt.Sprintf("🔴 *Machine Offline Alert*\n\n"+
 				"Machine: `%s`\n"+
 				"Hostname: `%s`\n"+
 				"Status: Offline\n"+
@@ -78,6 +72,7 @@ func (n *MachineHeartbeatNotifier) NotifyMachineOffline(ctx context.Context, mac
 				machine.Name,
 				machine.Hostname,
 				machine.LastSeen.Format("2006-01-02 15:04:05"))
+			message := n.telegramNotifier.renderMessage(ctx, machine.OrgID, newMachineTemplateData(event), defaultMessage)
 
 			for _, recipient := range recipients {
 				if !recipient.IsActive {
@@ -96,35 +91,29 @@ func (n *MachineHeartbeatNotifier) NotifyMachineOffline(ctx context.Context, mac
 
 // NotifyMachineOnline sends notifications when a machine comes back online
 func (n *MachineHeartbeatNotifier) NotifyMachineOnline(ctx context.Context, machine storage.Machine) error {
-	// Get user for this machine
-	user, err := n.store.GetUserByID(ctx, machine.UserID)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	event := WebhookMachineEvent{
+		Event: EventMachineOnline,
+		Machine: WebhookMachine{
+			ID:          machine.ID,
+			Name:        machine.Name,
+			Hostname:    machine.Hostname,
+			Description: machine.Description,
+			Status:      "online",
+			LastSeen:    machine.LastSeen,
+		},
 	}
 
 	// Send webhook notifications
 	if n.webhookNotifier != nil {
-		webhooks, err := n.store.ListWebhooks(ctx, user.ID)
+		webhooks, err := n.store.ListWebhooks(ctx, machine.OrgID)
 		if err != nil {
-			n.logger.Printf("Failed to list webhooks for user %d: %v", user.ID, err)
+			n.logger.Printf("Failed to list webhooks for organization %d: %v", machine.OrgID, err)
 		} else {
 			for _, webhook := range webhooks {
 				if !webhook.IsActive {
 					continue
 				}
 
-				event := WebhookMachineEvent{
-					Event: "machine.online",
-					Machine: WebhookMachine{
-						ID:          machine.ID,
-						Name:        machine.Name,
-						Hostname:    machine.Hostname,
-						Description: machine.Description,
-						Status:      "online",
-						LastSeen:    machine.LastSeen,
-					},
-				}
-
 				if err := n.webhookNotifier.SendMachineEvent(ctx, webhook, event); err != nil {
 					n.logger.Printf("Failed to send webhook notification for machine %d: %v", machine.ID, err)
 				}
@@ -134,11 +123,11 @@ func (n *MachineHeartbeatNotifier) NotifyMachineOnline(ctx context.Context, mach
 
 	// Send Telegram notifications
 	if n.telegramNotifier != nil {
-		recipients, err := n.store.ListTelegramRecipients(ctx, user.ID)
+		recipients, err := n.store.ListTelegramRecipients(ctx, machine.OrgID)
 		if err != nil {
-			n.logger.Printf("Failed to list Telegram recipients for user %d: %v", user.ID, err)
+			n.logger.Printf("Failed to list Telegram recipients for organization %d: %v", machine.OrgID, err)
 		} else {
-			message := fmt.Sprintf("🟢 *Machine Recovery Alert*\n\n"+
+			defaultMessage := fmt.Sprintf("🟢 *Machine Recovery Alert*\n\n"+
 				"Machine: `%s`\n"+
 				"Hostname: `%s`\n"+
 				"Status: Back Online\n"+
@@ -146,6 +135,7 @@ func (n *MachineHeartbeatNotifier) NotifyMachineOnline(ctx context.Context, mach
 				machine.Name,
 				machine.Hostname,
 				machine.LastSeen.Format("2006-01-02 15:04:05"))
+			message := n.telegramNotifier.renderMessage(ctx, machine.OrgID, newMachineTemplateData(event), defaultMessage)
 
 			for _, recipient := range recipients {
 				if !recipient.IsActive {
@@ -0,0 +1,348 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// DefaultDigestWindow is how long events are batched when an organization enables digest mode without a window
+	DefaultDigestWindow = 5 * time.Minute
+	// MinDigestWindow is the shortest digest window an organization can configure
+	MinDigestWindow = time.Minute
+	// MaxDigestWindow is the longest digest window an organization can configure
+	MaxDigestWindow = 24 * time.Hour
+
+	// policyFlushInterval is how often pending digests are checked for delivery
+	policyFlushInterval = 30 * time.Second
+)
+
+// DefaultNotificationPolicy returns the policy applied to organizations that have not configured one:
+// every event is delivered immediately
+func DefaultNotificationPolicy(orgID int) storage.NotificationPolicy {
+	return storage.NotificationPolicy{
+		OrgID:               orgID,
+		Timezone:            "UTC",
+		QuietHoursEnabled:   false,
+		QuietHoursStart:     "22:00",
+		QuietHoursEnd:       "07:00",
+		DigestEnabled:       false,
+		DigestWindowSeconds: int(DefaultDigestWindow / time.Second),
+	}
+}
+
+// pendingDigest holds an organization's events until the digest is due
+type pendingDigest struct {
+	reason  string
+	since   time.Time
+	flushAt time.Time
+	entries []DigestEntry
+	lastID  int // ID of the newest stored entry, deleted with the older ones once the digest is sent
+}
+
+// PolicyNotifier sits in front of the channel notifiers and applies each organization's notification
+// policy: events are held back during quiet hours and batched into digests when enabled. Held events
+// are stored so that digests and quiet hours summaries survive a restart.
+type PolicyNotifier struct {
+	store         storage.Store
+	channels      OrgAlertNotifier
+	logger        *log.Logger
+	flushInterval time.Duration
+	now           func() time.Time
+
+	mu      sync.Mutex
+	pending map[int]*pendingDigest
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// NewPolicyNotifier creates a notifier that delivers through channels according to organization policies
+func NewPolicyNotifier(store storage.Store, channels OrgAlertNotifier, logger *log.Logger) *PolicyNotifier {
+	return &PolicyNotifier{
+		store:         store,
+		channels:      channels,
+		logger:        logger,
+		flushInterval: policyFlushInterval,
+		now:           time.Now,
+		pending:       make(map[int]*pendingDigest),
+		stopCh:        make(chan struct{}),
+		doneCh:        make(chan struct{}),
+	}
+}
+
+// Start reloads the events held before a restart and begins delivering due digests in a background
+// goroutine
+func (p *PolicyNotifier) Start(ctx context.Context) {
+	p.restore(ctx)
+	go p.run(ctx)
+	p.logger.Printf("[POLICY] notifier started (flush interval: %v)", p.flushInterval)
+}
+
+// Stop stops the flush loop. Pending digests stay stored and are sent when due after the next start,
+// so quiet hours are honoured across restarts.
+func (p *PolicyNotifier) Stop() {
+	close(p.stopCh)
+	<-p.doneCh
+	p.logger.Println("[POLICY] notifier stopped")
+}
+
+// restore reloads the held events stored before a restart
+func (p *PolicyNotifier) restore(ctx context.Context) {
+	stored, err := p.store.ListPendingDigestEntries(ctx)
+	if err != nil {
+		p.logger.Printf("[POLICY] failed to load pending digests: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range stored {
+		entry := DigestEntry{EventType: e.EventType, Rule: e.Rule, Event: e.Event}
+		p.hold(e.OrgID, entry, e.Reason, e.CreatedAt, e.FlushAt, e.ID)
+	}
+	if len(stored) > 0 {
+		p.logger.Printf("[POLICY] restored %d held events", len(stored))
+	}
+}
+
+// run is the digest flush loop
+func (p *PolicyNotifier) run(ctx context.Context) {
+	defer close(p.doneCh)
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.flushDue(p.now())
+		case <-p.stopCh:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Notify implements AlertNotifier
+func (p *PolicyNotifier) Notify(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	if event == nil {
+		return nil
+	}
+	return p.dispatch(ctx, EventAlertFired, rule, *event)
+}
+
+// NotifyResolved implements AlertResolutionNotifier
+func (p *PolicyNotifier) NotifyResolved(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	if event == nil {
+		return nil
+	}
+	return p.dispatch(ctx, EventAlertResolved, rule, *event)
+}
+
+// dispatch applies the policy of each organization the event concerns to an alert event
+func (p *PolicyNotifier) dispatch(ctx context.Context, eventType string, rule storage.AlertRule, event storage.AlertEvent) error {
+	orgIDs := eventOrganizations(ctx, p.store, rule, event)
+
+	now := p.now()
+	entry := DigestEntry{EventType: eventType, Rule: rule, Event: event}
+
+	for _, orgID := range orgIDs {
+		policy := p.policyFor(ctx, orgID)
+
+		// Critical alerts always get through quiet hours
+		if quietEnd, quiet := quietHoursEnd(policy, now); quiet && eventSeverity(rule, event) != storage.SeverityCritical {
+			p.enqueue(ctx, orgID, entry, DigestReasonQuietHours, now, quietEnd)
+			continue
+		}
+
+		if policy.DigestEnabled {
+			p.enqueue(ctx, orgID, entry, DigestReasonWindow, now, now.Add(digestWindow(policy)))
+			continue
+		}
+
+		if err := p.channels.NotifyOrg(ctx, orgID, eventType, rule, event); err != nil {
+			p.logger.Printf("[POLICY] failed to notify org=%d: %v", orgID, err)
+		}
+	}
+
+	return nil
+}
+
+// policyFor loads an organization's policy, falling back to immediate delivery when none is configured
+// or it cannot be read
+func (p *PolicyNotifier) policyFor(ctx context.Context, orgID int) storage.NotificationPolicy {
+	policy, err := p.store.GetNotificationPolicy(ctx, orgID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotificationPolicyNotFound) {
+			p.logger.Printf("[POLICY] failed to load policy for org=%d, delivering immediately: %v", orgID, err)
+		}
+		return DefaultNotificationPolicy(orgID)
+	}
+	return *policy
+}
+
+// enqueue stores an event and adds it to the organization's pending digest
+func (p *PolicyNotifier) enqueue(ctx context.Context, orgID int, entry DigestEntry, reason string, now, flushAt time.Time) {
+	var id int
+	stored, err := p.store.AddPendingDigestEntry(ctx, storage.PendingDigestEntry{
+		OrgID:     orgID,
+		Reason:    reason,
+		FlushAt:   flushAt,
+		EventType: entry.EventType,
+		Rule:      entry.Rule,
+		Event:     entry.Event,
+		CreatedAt: now,
+	})
+	if err != nil {
+		// Still deliver it with the digest, it is only lost if the server restarts first
+		p.logger.Printf("[POLICY] failed to store held event for org=%d: %v", orgID, err)
+	} else {
+		id = stored.ID
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hold(orgID, entry, reason, now, flushAt, id)
+}
+
+// hold adds an event to the organization's pending digest. A digest that spans quiet hours is held
+// until they end and reported as a quiet hours summary. The caller must hold p.mu.
+func (p *PolicyNotifier) hold(orgID int, entry DigestEntry, reason string, now, flushAt time.Time, id int) {
+	pending, ok := p.pending[orgID]
+	if !ok {
+		p.pending[orgID] = &pendingDigest{
+			reason:  reason,
+			since:   now,
+			flushAt: flushAt,
+			entries: []DigestEntry{entry},
+			lastID:  id,
+		}
+		return
+	}
+
+	pending.entries = append(pending.entries, entry)
+	if id > pending.lastID {
+		pending.lastID = id
+	}
+	if reason == DigestReasonQuietHours {
+		pending.reason = DigestReasonQuietHours
+		if flushAt.After(pending.flushAt) {
+			pending.flushAt = flushAt
+		}
+	}
+}
+
+// flushDue delivers every pending digest that is due at now
+func (p *PolicyNotifier) flushDue(now time.Time) {
+	p.flush(func(pending *pendingDigest) bool { return !pending.flushAt.After(now) })
+}
+
+// flush delivers and removes the pending digests selected by due
+func (p *PolicyNotifier) flush(due func(*pendingDigest) bool) {
+	p.mu.Lock()
+	ready := make(map[int]*pendingDigest)
+	for orgID, pending := range p.pending {
+		if due(pending) {
+			ready[orgID] = pending
+			delete(p.pending, orgID)
+		}
+	}
+	p.mu.Unlock()
+
+	until := p.now()
+	for orgID, pending := range ready {
+		digest := AlertDigest{
+			Reason:  pending.reason,
+			Since:   pending.since,
+			Until:   until,
+			Entries: pending.entries,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := p.channels.NotifyOrgDigest(ctx, orgID, digest); err != nil {
+			p.logger.Printf("[POLICY] failed to send digest to org=%d: %v", orgID, err)
+		} else {
+			p.logger.Printf("[POLICY] sent %s digest to org=%d events=%d", digest.Reason, orgID, len(digest.Entries))
+		}
+		if pending.lastID > 0 {
+			if err := p.store.DeletePendingDigestEntries(ctx, orgID, pending.lastID); err != nil {
+				p.logger.Printf("[POLICY] failed to clear sent digest for org=%d: %v", orgID, err)
+			}
+		}
+		cancel()
+	}
+}
+
+// digestWindow returns the configured digest window, clamped to the allowed range
+func digestWindow(policy storage.NotificationPolicy) time.Duration {
+	window := time.Duration(policy.DigestWindowSeconds) * time.Second
+	if window <= 0 {
+		return DefaultDigestWindow
+	}
+	if window < MinDigestWindow {
+		return MinDigestWindow
+	}
+	if window > MaxDigestWindow {
+		return MaxDigestWindow
+	}
+	return window
+}
+
+// quietHoursEnd reports whether t falls within the policy's quiet hours and, if so, when they end.
+// Ranges whose end is before their start run overnight, e.g. 22:00-07:00.
+func quietHoursEnd(policy storage.NotificationPolicy, t time.Time) (time.Time, bool) {
+	if !policy.QuietHoursEnabled {
+		return time.Time{}, false
+	}
+
+	start, err := parseClock(policy.QuietHoursStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := parseClock(policy.QuietHoursEnd)
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(policy.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	local := t.In(loc)
+	minutes := local.Hour()*60 + local.Minute()
+
+	var inside, endsTomorrow bool
+	if start < end {
+		inside = minutes >= start && minutes < end
+	} else {
+		inside = minutes >= start || minutes < end
+		endsTomorrow = minutes >= start
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+
+	year, month, day := local.Date()
+	if endsTomorrow {
+		day++
+	}
+	return time.Date(year, month, day, end/60, end%60, 0, 0, loc), true
+}
+
+// parseClock parses an "HH:MM" time of day into minutes after midnight
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
@@ -0,0 +1,138 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// NotificationPolicyRequest represents the request body for updating a notification policy
+type NotificationPolicyRequest struct {
+	Timezone            string `json:"timezone"`
+	QuietHoursEnabled   bool   `json:"quiet_hours_enabled"`
+	QuietHoursStart     string `json:"quiet_hours_start"`
+	QuietHoursEnd       string `json:"quiet_hours_end"`
+	DigestEnabled       bool   `json:"digest_enabled"`
+	DigestWindowSeconds int    `json:"digest_window_seconds"`
+}
+
+// Validate validates the notification policy request
+func (r *NotificationPolicyRequest) Validate() error {
+	if r.Timezone == "" {
+		return fmt.Errorf("timezone is required")
+	}
+	if _, err := time.LoadLocation(r.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", r.Timezone)
+	}
+
+	start, err := parseClock(r.QuietHoursStart)
+	if err != nil {
+		return fmt.Errorf("quiet_hours_start: %w", err)
+	}
+	end, err := parseClock(r.QuietHoursEnd)
+	if err != nil {
+		return fmt.Errorf("quiet_hours_end: %w", err)
+	}
+	if r.QuietHoursEnabled && start == end {
+		return fmt.Errorf("quiet_hours_start and quiet_hours_end must differ")
+	}
+
+	window := time.Duration(r.DigestWindowSeconds) * time.Second
+	if window < MinDigestWindow || window > MaxDigestWindow {
+		return fmt.Errorf("digest_window_seconds must be between %d and %d",
+			int(MinDigestWindow/time.Second), int(MaxDigestWindow/time.Second))
+	}
+
+	return nil
+}
+
+// HandleGetNotificationPolicy handles GET /notifications/policy.
+// Returns the default policy when the organization has not configured one.
+func HandleGetNotificationPolicy(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+			return
+		}
+
+		policy, err := store.GetNotificationPolicy(r.Context(), org.OrgID)
+		if errors.Is(err, storage.ErrNotificationPolicyNotFound) {
+			defaultPolicy := DefaultNotificationPolicy(org.OrgID)
+			policy, err = &defaultPolicy, nil
+		}
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to get notification policy: %v", err)})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policy)
+	}
+}
+
+// HandleUpdateNotificationPolicy handles PUT /notifications/policy
+func HandleUpdateNotificationPolicy(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+			return
+		}
+
+		var req NotificationPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid request body: %v", err)})
+			return
+		}
+
+		if err := req.Validate(); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		// Kept for the audit log; an organization without a policy has the default one
+		before, err := store.GetNotificationPolicy(r.Context(), org.OrgID)
+		if err != nil {
+			defaultPolicy := DefaultNotificationPolicy(org.OrgID)
+			before = &defaultPolicy
+		}
+
+		policy, err := store.UpsertNotificationPolicy(r.Context(), storage.NotificationPolicy{
+			OrgID:               org.OrgID,
+			Timezone:            req.Timezone,
+			QuietHoursEnabled:   req.QuietHoursEnabled,
+			QuietHoursStart:     req.QuietHoursStart,
+			QuietHoursEnd:       req.QuietHoursEnd,
+			DigestEnabled:       req.DigestEnabled,
+			DigestWindowSeconds: req.DigestWindowSeconds,
+		})
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to save notification policy: %v", err)})
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "notification_policy.update", TargetType: "notification_policy",
+			TargetID: org.OrgID, Before: before, After: policy})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policy)
+	}
+}
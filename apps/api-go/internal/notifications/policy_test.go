@@ -0,0 +1,285 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// recordingChannels records per-user deliveries instead of sending them
+type recordingChannels struct {
+	mu        sync.Mutex
+	immediate map[int][]string
+	digests   map[int][]AlertDigest
+}
+
+func newRecordingChannels() *recordingChannels {
+	return &recordingChannels{immediate: make(map[int][]string), digests: make(map[int][]AlertDigest)}
+}
+
+func (r *recordingChannels) NotifyOrg(ctx context.Context, orgID int, eventType string, rule storage.AlertRule, event storage.AlertEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.immediate[orgID] = append(r.immediate[orgID], eventType)
+	return nil
+}
+
+func (r *recordingChannels) NotifyOrgDigest(ctx context.Context, orgID int, digest AlertDigest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.digests[orgID] = append(r.digests[orgID], digest)
+	return nil
+}
+
+func setupPolicyNotifier(t *testing.T) (*PolicyNotifier, *recordingChannels, *storage.SQLiteStore) {
+	t.Helper()
+
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	channels := newRecordingChannels()
+	notifier := NewPolicyNotifier(store, channels, log.New(io.Discard, "", 0))
+	return notifier, channels, store
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	policy := storage.NotificationPolicy{
+		Timezone:          "Europe/Berlin",
+		QuietHoursEnabled: true,
+		QuietHoursStart:   "22:00",
+		QuietHoursEnd:     "07:00",
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		quiet   bool
+		endsAt  time.Time
+		enabled bool
+	}{
+		{"before start", time.Date(2026, 3, 10, 21, 59, 0, 0, berlin), false, time.Time{}, true},
+		{"evening", time.Date(2026, 3, 10, 23, 30, 0, 0, berlin), true, time.Date(2026, 3, 11, 7, 0, 0, 0, berlin), true},
+		{"early morning", time.Date(2026, 3, 11, 6, 15, 0, 0, berlin), true, time.Date(2026, 3, 11, 7, 0, 0, 0, berlin), true},
+		{"at end", time.Date(2026, 3, 11, 7, 0, 0, 0, berlin), false, time.Time{}, true},
+		{"utc input", time.Date(2026, 3, 10, 22, 30, 0, 0, time.UTC), true, time.Date(2026, 3, 11, 7, 0, 0, 0, berlin), true},
+		{"disabled", time.Date(2026, 3, 10, 23, 30, 0, 0, berlin), false, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := policy
+			p.QuietHoursEnabled = tt.enabled
+			endsAt, quiet := quietHoursEnd(p, tt.at)
+			if quiet != tt.quiet {
+				t.Fatalf("Expected quiet=%v, got %v", tt.quiet, quiet)
+			}
+			if quiet && !endsAt.Equal(tt.endsAt) {
+				t.Errorf("Expected quiet hours to end at %v, got %v", tt.endsAt, endsAt)
+			}
+		})
+	}
+}
+
+func TestPolicyNotifier_ImmediateWithoutPolicy(t *testing.T) {
+	notifier, channels, store := setupPolicyNotifier(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "a@example.com", "hash")
+	rule := storage.AlertRule{ID: 1, OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct"}
+
+	notifier.Notify(ctx, rule, &storage.AlertEvent{ID: 1, RuleID: 1, Value: 95})
+	notifier.NotifyResolved(ctx, rule, &storage.AlertEvent{ID: 1, RuleID: 1, Value: 95})
+
+	if got := channels.immediate[user.ID]; len(got) != 2 || got[0] != EventAlertFired || got[1] != EventAlertResolved {
+		t.Errorf("Expected fired and resolved delivered immediately, got %v", got)
+	}
+}
+
+func TestPolicyNotifier_Digest(t *testing.T) {
+	notifier, channels, store := setupPolicyNotifier(t)
+	ctx := context.Background()
+
+	digestUser, _ := store.CreateUser(ctx, "digest@example.com", "hash")
+	plainUser, _ := store.CreateUser(ctx, "plain@example.com", "hash")
+	if _, err := store.UpsertNotificationPolicy(ctx, storage.NotificationPolicy{
+		OrgID: digestUser.ID, Timezone: "UTC", QuietHoursStart: "22:00", QuietHoursEnd: "07:00",
+		DigestEnabled: true, DigestWindowSeconds: 300,
+	}); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return start }
+
+	rule := storage.AlertRule{ID: 1, OrgID: digestUser.ID, Name: "High CPU", Metric: "cpu_pct"}
+	for i := 1; i <= 3; i++ {
+		notifier.Notify(ctx, rule, &storage.AlertEvent{ID: i, RuleID: 1, Value: 90})
+	}
+	plainRule := storage.AlertRule{ID: 2, OrgID: plainUser.ID, Name: "High CPU", Metric: "cpu_pct"}
+	notifier.Notify(ctx, plainRule, &storage.AlertEvent{ID: 4, RuleID: 2, Value: 90})
+
+	if len(channels.immediate[digestUser.ID]) != 0 {
+		t.Errorf("Expected no immediate deliveries in digest mode, got %v", channels.immediate[digestUser.ID])
+	}
+	if len(channels.immediate[plainUser.ID]) != 1 {
+		t.Errorf("Expected other users to be unaffected, got %v", channels.immediate[plainUser.ID])
+	}
+
+	notifier.flushDue(start.Add(4 * time.Minute))
+	if len(channels.digests[digestUser.ID]) != 0 {
+		t.Fatal("Expected digest to wait for the window to close")
+	}
+
+	notifier.flushDue(start.Add(5 * time.Minute))
+	digests := channels.digests[digestUser.ID]
+	if len(digests) != 1 {
+		t.Fatalf("Expected one digest, got %d", len(digests))
+	}
+	if digests[0].Reason != DigestReasonWindow || len(digests[0].Entries) != 3 {
+		t.Errorf("Expected a window digest with 3 entries, got %s with %d", digests[0].Reason, len(digests[0].Entries))
+	}
+}
+
+func TestPolicyNotifier_QuietHours(t *testing.T) {
+	notifier, channels, store := setupPolicyNotifier(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "night@example.com", "hash")
+	if _, err := store.UpsertNotificationPolicy(ctx, storage.NotificationPolicy{
+		OrgID: user.ID, Timezone: "UTC", QuietHoursEnabled: true, QuietHoursStart: "22:00", QuietHoursEnd: "07:00",
+		DigestWindowSeconds: 300,
+	}); err != nil {
+		t.Fatalf("Failed to save policy: %v", err)
+	}
+
+	night := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return night }
+
+	rule := storage.AlertRule{ID: 1, OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct"}
+	notifier.Notify(ctx, rule, &storage.AlertEvent{ID: 1, RuleID: 1, Value: 90})
+	notifier.NotifyResolved(ctx, rule, &storage.AlertEvent{ID: 1, RuleID: 1, Value: 90})
+
+	if len(channels.immediate[user.ID]) != 0 {
+		t.Errorf("Expected events to be held during quiet hours, got %v", channels.immediate[user.ID])
+	}
+
+	notifier.flushDue(time.Date(2026, 3, 11, 6, 59, 0, 0, time.UTC))
+	if len(channels.digests[user.ID]) != 0 {
+		t.Fatal("Expected summary to wait until quiet hours end")
+	}
+
+	notifier.flushDue(time.Date(2026, 3, 11, 7, 0, 0, 0, time.UTC))
+	digests := channels.digests[user.ID]
+	if len(digests) != 1 || digests[0].Reason != DigestReasonQuietHours || len(digests[0].Entries) != 2 {
+		t.Fatalf("Expected one quiet hours summary with 2 entries, got %+v", digests)
+	}
+
+	// After quiet hours events flow immediately again
+	notifier.now = func() time.Time { return time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC) }
+	notifier.Notify(ctx, rule, &storage.AlertEvent{ID: 2, RuleID: 1, Value: 91})
+	if len(channels.immediate[user.ID]) != 1 {
+		t.Errorf("Expected immediate delivery outside quiet hours, got %v", channels.immediate[user.ID])
+	}
+}
+
+func TestPolicyNotifier_PendingSurvivesRestart(t *testing.T) {
+	notifier, _, store := setupPolicyNotifier(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "night@example.com", "hash")
+	store.UpsertNotificationPolicy(ctx, storage.NotificationPolicy{
+		OrgID: user.ID, Timezone: "UTC", QuietHoursEnabled: true, QuietHoursStart: "22:00", QuietHoursEnd: "07:00",
+		DigestWindowSeconds: 300,
+	})
+
+	night := time.Date(2026, 3, 10, 23, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return night }
+	notifier.Start(ctx)
+	notifier.Notify(ctx, storage.AlertRule{ID: 1, OrgID: user.ID, Name: "High CPU"}, &storage.AlertEvent{ID: 1, RuleID: 1, Value: 90})
+	notifier.Stop()
+
+	// A new notifier picks up the summary and still holds it until quiet hours end
+	channels := newRecordingChannels()
+	restarted := NewPolicyNotifier(store, channels, log.New(io.Discard, "", 0))
+	restarted.now = notifier.now
+	restarted.Start(ctx)
+	defer restarted.Stop()
+
+	restarted.flushDue(night.Add(time.Hour))
+	if len(channels.digests[user.ID]) != 0 {
+		t.Fatal("Expected the summary to wait until quiet hours end after a restart")
+	}
+
+	restarted.flushDue(time.Date(2026, 3, 11, 7, 0, 0, 0, time.UTC))
+	digests := channels.digests[user.ID]
+	if len(digests) != 1 || digests[0].Reason != DigestReasonQuietHours || len(digests[0].Entries) != 1 {
+		t.Fatalf("Expected the restored quiet hours summary, got %+v", digests)
+	}
+	if digests[0].Entries[0].Rule.Name != "High CPU" || !digests[0].Since.Equal(night) {
+		t.Errorf("Expected the stored rule and hold time, got %+v", digests[0])
+	}
+
+	remaining, err := store.ListPendingDigestEntries(ctx)
+	if err != nil || len(remaining) != 0 {
+		t.Errorf("Expected sent entries to be removed, got %d (%v)", len(remaining), err)
+	}
+}
+
+func TestHandleUpdateNotificationPolicy(t *testing.T) {
+	_, _, store := setupPolicyNotifier(t)
+	user, _ := store.CreateUser(context.Background(), "a@example.com", "hash")
+
+	tests := []struct {
+		name           string
+		body           NotificationPolicyRequest
+		expectedStatus int
+	}{
+		{"valid", NotificationPolicyRequest{Timezone: "America/New_York", QuietHoursEnabled: true, QuietHoursStart: "23:00", QuietHoursEnd: "06:30", DigestWindowSeconds: 600}, http.StatusOK},
+		{"unknown timezone", NotificationPolicyRequest{Timezone: "Mars/Olympus", QuietHoursStart: "23:00", QuietHoursEnd: "06:30", DigestWindowSeconds: 600}, http.StatusBadRequest},
+		{"bad clock", NotificationPolicyRequest{Timezone: "UTC", QuietHoursStart: "25:00", QuietHoursEnd: "06:30", DigestWindowSeconds: 600}, http.StatusBadRequest},
+		{"empty quiet range", NotificationPolicyRequest{Timezone: "UTC", QuietHoursEnabled: true, QuietHoursStart: "06:30", QuietHoursEnd: "06:30", DigestWindowSeconds: 600}, http.StatusBadRequest},
+		{"window too short", NotificationPolicyRequest{Timezone: "UTC", QuietHoursStart: "23:00", QuietHoursEnd: "06:30", DigestWindowSeconds: 10}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPut, "/notifications/policy", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+				&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner}))
+			w := httptest.NewRecorder()
+
+			HandleUpdateNotificationPolicy(store)(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/notifications/policy", nil)
+	req = req.WithContext(context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner}))
+	w := httptest.NewRecorder()
+	HandleGetNotificationPolicy(store)(w, req)
+
+	var policy storage.NotificationPolicy
+	json.NewDecoder(w.Body).Decode(&policy)
+	if policy.Timezone != "America/New_York" || policy.QuietHoursStart != "23:00" || !policy.QuietHoursEnabled {
+		t.Errorf("Expected saved policy, got %+v", policy)
+	}
+}
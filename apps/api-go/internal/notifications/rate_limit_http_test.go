@@ -17,7 +17,7 @@ func TestHandleTestWebhook_RateLimited(t *testing.T) {
 	// Create a webhook
 	webhook := storage.Webhook{
 		ID:       1,
-		UserID:   1,
+		OrgID:    1,
 		URL:      "https://example.com/webhook",
 		IsActive: true,
 	}
@@ -34,7 +34,8 @@ func TestHandleTestWebhook_RateLimited(t *testing.T) {
 
 	// Create request
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/1/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, &storage.User{ID: 1})
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, &storage.User{ID: 1}), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: 1, UserID: 1, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -69,7 +70,7 @@ func TestHandleTestWebhook_RateLimitWithInterval(t *testing.T) {
 	// Create a webhook
 	webhook := storage.Webhook{
 		ID:       1,
-		UserID:   1,
+		OrgID:    1,
 		URL:      "https://example.com/webhook",
 		IsActive: true,
 	}
@@ -86,7 +87,8 @@ func TestHandleTestWebhook_RateLimitWithInterval(t *testing.T) {
 
 	// Create request
 	req := httptest.NewRequest(http.MethodPost, "/notifications/webhooks/1/test", nil)
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, &storage.User{ID: 1})
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, &storage.User{ID: 1}), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: 1, UserID: 1, Role: storage.RoleOwner})
 	req = req.WithContext(ctx)
 	rec := httptest.NewRecorder()
 
@@ -22,7 +22,7 @@ func TestNotifier_checkDeliveryPreconditions(t *testing.T) {
 	// Test webhook with no rate limiting constraints
 	webhook := storage.Webhook{
 		ID:            1,
-		UserID:        1,
+		OrgID:         1,
 		URL:           "https://example.com/webhook",
 		SecretHash:    "hashedsecret",
 		IsActive:      true,
@@ -0,0 +1,138 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/silences"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// eventSeverity returns the severity an event fired with, falling back to the rule's
+func eventSeverity(rule storage.AlertRule, event storage.AlertEvent) string {
+	if event.Severity != "" {
+		return event.Severity
+	}
+	if rule.Severity != "" {
+		return rule.Severity
+	}
+	return storage.SeverityWarning
+}
+
+// ruleType returns the type of a rule, defaulting to a threshold rule
+func ruleType(rule storage.AlertRule) string {
+	if rule.Type != "" {
+		return rule.Type
+	}
+	return storage.RuleTypeThreshold
+}
+
+// eventOrganizations returns the IDs of the organizations to notify of an alert event: the
+// organization of the rule that fired it, on one of its machines or on the local host, unless it
+// silenced the event.
+func eventOrganizations(ctx context.Context, store storage.Store, rule storage.AlertRule, event storage.AlertEvent) []int {
+	if silence := silences.NewService(store).AlertSilenced(ctx, rule, event.MachineID, time.Now()); silence != nil {
+		log.Printf("[SILENCE] event %d is silenced for organization %d by silence %d", event.ID, rule.OrgID, silence.ID)
+		return nil
+	}
+	return []int{rule.OrgID}
+}
+
+// routeTargets is the set of destinations an organization's routes select for an alert event
+type routeTargets struct {
+	all      bool // the organization has no routes, so every active destination receives the event
+	webhooks map[int]bool
+	telegram map[int]bool
+}
+
+// allowsWebhook reports whether the event should be delivered to the webhook
+func (r routeTargets) allowsWebhook(id int) bool {
+	return r.all || r.webhooks[id]
+}
+
+// allowsTelegram reports whether the event should be delivered to the Telegram recipient
+func (r routeTargets) allowsTelegram(id int) bool {
+	return r.all || r.telegram[id]
+}
+
+// routeMatches reports whether a route applies to an event with the given severity and machine tags
+func routeMatches(route storage.NotificationRoute, severity string, machineTags []string) bool {
+	if len(route.Severities) > 0 && !containsString(route.Severities, severity) {
+		return false
+	}
+	if len(route.MachineTags) == 0 {
+		return true
+	}
+	for _, tag := range machineTags {
+		if containsString(route.MachineTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRoutes works out which of an organization's destinations should receive an alert event.
+// Organizations without routes get every event everywhere; when routes cannot be loaded delivery
+// also falls back to every destination so a storage error never swallows an alert.
+func resolveRoutes(ctx context.Context, store storage.Store, logger *log.Logger, orgID int, rule storage.AlertRule, event storage.AlertEvent) routeTargets {
+	routes, err := store.ListNotificationRoutes(ctx, orgID)
+	if err != nil {
+		logger.Printf("[ROUTING] failed to load routes for org=%d, delivering to all destinations: %v", orgID, err)
+		return routeTargets{all: true}
+	}
+	if len(routes) == 0 {
+		return routeTargets{all: true}
+	}
+
+	var machineTags []string
+	if event.MachineID != nil {
+		machineTags, err = store.GetMachineTags(ctx, *event.MachineID)
+		if err != nil {
+			logger.Printf("[ROUTING] failed to load tags for machine=%d: %v", *event.MachineID, err)
+		}
+	}
+
+	targets := routeTargets{webhooks: make(map[int]bool), telegram: make(map[int]bool)}
+	severity := eventSeverity(rule, event)
+	for _, route := range routes {
+		if !routeMatches(route, severity, machineTags) {
+			continue
+		}
+		for _, id := range route.WebhookIDs {
+			targets.webhooks[id] = true
+		}
+		for _, id := range route.TelegramRecipientIDs {
+			targets.telegram[id] = true
+		}
+	}
+
+	return targets
+}
+
+// resolveDigestRoutes resolves routes for every entry of a digest, in order
+func resolveDigestRoutes(ctx context.Context, store storage.Store, logger *log.Logger, orgID int, digest AlertDigest) []routeTargets {
+	targets := make([]routeTargets, len(digest.Entries))
+	for i, entry := range digest.Entries {
+		targets[i] = resolveRoutes(ctx, store, logger, orgID, entry.Rule, entry.Event)
+	}
+	return targets
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package notifications
+
+import (
+	"context"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestRouteMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    storage.NotificationRoute
+		severity string
+		tags     []string
+		want     bool
+	}{
+		{"catch-all", storage.NotificationRoute{}, storage.SeverityInfo, nil, true},
+		{"severity match", storage.NotificationRoute{Severities: []string{"critical"}}, "critical", nil, true},
+		{"severity mismatch", storage.NotificationRoute{Severities: []string{"critical"}}, "warning", nil, false},
+		{"tag match", storage.NotificationRoute{MachineTags: []string{"db", "cache"}}, "info", []string{"prod", "db"}, true},
+		{"tag mismatch", storage.NotificationRoute{MachineTags: []string{"db"}}, "info", []string{"web"}, false},
+		{"tag route without machine", storage.NotificationRoute{MachineTags: []string{"db"}}, "info", nil, false},
+		{"both must match", storage.NotificationRoute{Severities: []string{"critical"}, MachineTags: []string{"db"}}, "warning", []string{"db"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routeMatches(tt.route, tt.severity, tt.tags); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResolveRoutes(t *testing.T) {
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	logger := log.New(io.Discard, "", 0)
+	user, _ := store.CreateUser(ctx, "oncall@example.com", "hash")
+	db, _ := store.CreateMachine(ctx, user.ID, "db-01", "db-01.local", "", "key1")
+	web, _ := store.CreateMachine(ctx, user.ID, "web-01", "web-01.local", "", "key2")
+	store.SetMachineTags(ctx, db.ID, []string{"db"})
+
+	rule := storage.AlertRule{ID: 1, OrgID: user.ID, Name: "High CPU", Severity: storage.SeverityWarning}
+
+	// No routes: everything goes everywhere
+	if targets := resolveRoutes(ctx, store, logger, user.ID, rule, storage.AlertEvent{}); !targets.allowsWebhook(99) || !targets.allowsTelegram(99) {
+		t.Error("Expected users without routes to receive events on every destination")
+	}
+
+	store.CreateNotificationRoute(ctx, storage.NotificationRoute{OrgID: user.ID, Name: "pager", Severities: []string{"critical"}, WebhookIDs: []int{1}})
+	store.CreateNotificationRoute(ctx, storage.NotificationRoute{OrgID: user.ID, Name: "dba", MachineTags: []string{"db"}, TelegramRecipientIDs: []int{5}})
+
+	critical := resolveRoutes(ctx, store, logger, user.ID, rule, storage.AlertEvent{Severity: storage.SeverityCritical, MachineID: &web.ID})
+	if !critical.allowsWebhook(1) || critical.allowsTelegram(5) {
+		t.Errorf("Expected critical web event to page webhook 1 only, got %+v", critical)
+	}
+
+	dbWarning := resolveRoutes(ctx, store, logger, user.ID, rule, storage.AlertEvent{Severity: storage.SeverityWarning, MachineID: &db.ID})
+	if dbWarning.allowsWebhook(1) || !dbWarning.allowsTelegram(5) {
+		t.Errorf("Expected db warning to reach Telegram recipient 5 only, got %+v", dbWarning)
+	}
+
+	unmatched := resolveRoutes(ctx, store, logger, user.ID, rule, storage.AlertEvent{Severity: storage.SeverityInfo})
+	if unmatched.allowsWebhook(1) || unmatched.allowsTelegram(5) {
+		t.Errorf("Expected unmatched event to reach no destination, got %+v", unmatched)
+	}
+}
@@ -7,60 +7,168 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/config"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
 
-const (
-	TelegramAPIURL = "https://api.telegram.org/bot%s/sendMessage"
-)
-
 // TelegramNotifier handles Telegram notifications for alert events
 type TelegramNotifier struct {
-	store  storage.Store
-	config *config.TelegramConfig
-	client *http.Client
-	logger *log.Logger
+	store    storage.Store
+	config   *config.TelegramConfig
+	client   *http.Client
+	logger   *log.Logger
+	defaults DeliveryPolicy
 }
 
 // NewTelegramNotifier creates a new Telegram notifier
 func NewTelegramNotifier(store storage.Store, cfg *config.TelegramConfig, logger *log.Logger) *TelegramNotifier {
 	return &TelegramNotifier{
-		store:  store,
-		config: cfg,
-		client: &http.Client{Timeout: 10 * time.Second},
-		logger: logger,
+		store:    store,
+		config:   cfg,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+		defaults: DefaultTelegramDeliveryPolicy(),
+	}
+}
+
+// SetDeliveryDefaults sets the server-wide delivery policy for recipients without their own overrides
+func (t *TelegramNotifier) SetDeliveryDefaults(policy DeliveryPolicy) {
+	t.defaults = policy
+}
+
+// deliveryPolicy returns the effective delivery policy for a recipient
+func (t *TelegramNotifier) deliveryPolicy(recipient storage.TelegramRecipient) DeliveryPolicy {
+	return t.defaults.WithOverrides(recipient.DeliverySettings)
+}
+
+// checkDeliveryPreconditions verifies if a delivery to the recipient should proceed based on rate limiting and cooldown
+func (t *TelegramNotifier) checkDeliveryPreconditions(recipient storage.TelegramRecipient) error {
+	rateLimitErr := t.deliveryPolicy(recipient).checkPreconditions("Telegram recipient", time.Now(), recipient.CooldownUntil, recipient.LastAttemptAt)
+	if rateLimitErr == nil {
+		return nil
+	}
+
+	t.logger.Printf("[TELEGRAM] throttled recipient=%d reason=%s retry_at=%s",
+		recipient.ID, rateLimitErr.Type, rateLimitErr.RetryAt.Format(time.RFC3339))
+	return rateLimitErr
+}
+
+// recordFailure records a failed delivery and opens the recipient's circuit if the failure threshold is reached
+func (t *TelegramNotifier) recordFailure(ctx context.Context, recipient storage.TelegramRecipient, attemptAt time.Time) {
+	now := time.Now()
+	if err := t.store.IncrementTelegramFailure(ctx, recipient.ID, now); err != nil {
+		t.logger.Printf("[TELEGRAM] failed to increment failure count for recipient=%d: %v", recipient.ID, err)
+		return
+	}
+
+	updated, err := t.store.GetTelegramRecipient(ctx, recipient.ID, recipient.OrgID)
+	if err != nil {
+		t.logger.Printf("[TELEGRAM] failed to fetch updated recipient=%d: %v", recipient.ID, err)
+		return
+	}
+
+	policy := t.deliveryPolicy(*updated)
+	if !policy.shouldEnterCooldown(now, updated.FailureCount, updated.LastErrorAt) {
+		return
+	}
+
+	cooldownUntil := now.Add(policy.CooldownDuration)
+	if err := t.store.UpdateTelegramDeliveryState(ctx, recipient.ID, attemptAt, &cooldownUntil); err != nil {
+		t.logger.Printf("[TELEGRAM] failed to set cooldown for recipient=%d: %v", recipient.ID, err)
+		return
 	}
+	t.logger.Printf("[TELEGRAM] cooldown recipient=%d until=%s", recipient.ID, cooldownUntil.Format(time.RFC3339))
 }
 
 // Send sends Telegram notifications for an alert event to all active recipients
 func (t *TelegramNotifier) Send(ctx context.Context, rule storage.AlertRule, event storage.AlertEvent) error {
+	return t.sendAlert(ctx, EventAlertFired, rule, event, t.buildAlertMessage(rule, event))
+}
+
+// SendResolved sends Telegram notifications for a resolved alert event to all active recipients
+func (t *TelegramNotifier) SendResolved(ctx context.Context, rule storage.AlertRule, event storage.AlertEvent) error {
+	return t.sendAlert(ctx, EventAlertResolved, rule, event, t.buildResolvedMessage(rule, event))
+}
+
+// sendAlert delivers an alert.* event to all active recipients, using each owner's template when configured
+func (t *TelegramNotifier) sendAlert(ctx context.Context, eventType string, rule storage.AlertRule, event storage.AlertEvent, defaultMessage string) error {
 	if !t.config.IsEnabled() {
 		return nil
 	}
 
-	recipients, err := t.getAllActiveRecipients(ctx)
+	recipients, err := t.getActiveRecipientsForEvent(ctx, rule, event)
 	if err != nil {
 		return fmt.Errorf("failed to fetch active Telegram recipients: %w", err)
 	}
 
-	if len(recipients) == 0 {
-		t.logger.Println("[TELEGRAM] No active recipients found")
+	t.deliverAlert(ctx, recipients, eventType, rule, event, defaultMessage)
+	return nil
+}
+
+// NotifyOrg delivers an alert.* event to the organization's active recipients selected by its notification routes
+func (t *TelegramNotifier) NotifyOrg(ctx context.Context, orgID int, eventType string, rule storage.AlertRule, event storage.AlertEvent) error {
+	if !t.config.IsEnabled() {
 		return nil
 	}
 
-	message := t.buildAlertMessage(rule, event)
+	recipients, err := t.getActiveRecipientsForOrg(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch active Telegram recipients for organization %d: %w", orgID, err)
+	}
 
+	targets := resolveRoutes(ctx, t.store, t.logger, orgID, rule, event)
+	var routed []storage.TelegramRecipient
 	for _, recipient := range recipients {
+		if targets.allowsTelegram(recipient.ID) {
+			routed = append(routed, recipient)
+		}
+	}
+
+	defaultMessage := t.buildAlertMessage(rule, event)
+	if eventType == EventAlertResolved {
+		defaultMessage = t.buildResolvedMessage(rule, event)
+	}
+
+	t.deliverAlert(ctx, routed, eventType, rule, event, defaultMessage)
+	return nil
+}
+
+// NotifyOrgDigest delivers a batch of alert events to an organization's active recipients as one message per recipient.
+// Each recipient only receives the entries its notification routes select.
+func (t *TelegramNotifier) NotifyOrgDigest(ctx context.Context, orgID int, digest AlertDigest) error {
+	if !t.config.IsEnabled() {
+		return nil
+	}
+
+	recipients, err := t.getActiveRecipientsForOrg(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch active Telegram recipients for organization %d: %w", orgID, err)
+	}
+
+	targets := resolveDigestRoutes(ctx, t.store, t.logger, orgID, digest)
+
+	for _, recipient := range recipients {
+		routed := digest
+		routed.Entries = nil
+		for i, entry := range digest.Entries {
+			if targets[i].allowsTelegram(recipient.ID) {
+				routed.Entries = append(routed.Entries, entry)
+			}
+		}
+		if len(routed.Entries) == 0 {
+			continue
+		}
+
+		message := t.buildDigestMessage(routed)
 		go func(r storage.TelegramRecipient) {
-			// Create independent context to avoid cancellation from parent
 			sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
 			if err := t.sendToRecipient(sendCtx, r, message); err != nil {
-				t.logger.Printf("[TELEGRAM] failed to send to chat_id=%s: %v", r.ChatID, err)
+				t.logger.Printf("[TELEGRAM] failed to send digest to chat_id=%s: %v", r.ChatID, err)
 			}
 		}(recipient)
 	}
@@ -68,6 +176,62 @@ func (t *TelegramNotifier) Send(ctx context.Context, rule storage.AlertRule, eve
 	return nil
 }
 
+// NotifyEscalation pages the organization's active recipients listed in an escalation step.
+// Notification routes do not apply: the step names its destinations explicitly.
+func (t *TelegramNotifier) NotifyEscalation(ctx context.Context, orgID int, escalation AlertEscalation) error {
+	if !t.config.IsEnabled() {
+		return nil
+	}
+
+	recipients, err := t.getActiveRecipientsForOrg(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch active Telegram recipients for organization %d: %w", orgID, err)
+	}
+
+	var paged []storage.TelegramRecipient
+	for _, recipient := range recipients {
+		if containsInt(escalation.TelegramRecipientIDs, recipient.ID) {
+			paged = append(paged, recipient)
+		}
+	}
+	if len(paged) == 0 {
+		return nil
+	}
+
+	t.deliverAlert(ctx, paged, EventAlertEscalated, escalation.Rule, escalation.Event, t.buildEscalationMessage(escalation))
+	return nil
+}
+
+// deliverAlert renders and sends an alert.* event to the given recipients concurrently
+func (t *TelegramNotifier) deliverAlert(ctx context.Context, recipients []storage.TelegramRecipient, eventType string, rule storage.AlertRule, event storage.AlertEvent, defaultMessage string) {
+	if len(recipients) == 0 {
+		t.logger.Println("[TELEGRAM] No active recipients found")
+		return
+	}
+
+	data := newAlertTemplateData(eventType, rule, event)
+
+	// Offer an Acknowledge button when the bot is around to handle it
+	var replyMarkup *InlineKeyboardMarkup
+	if (eventType == EventAlertFired || eventType == EventAlertEscalated) && t.config.BotEnabled() && event.ID != 0 {
+		replyMarkup = ackKeyboard(event.ID)
+	}
+
+	for _, recipient := range recipients {
+		message := t.renderMessage(ctx, recipient.OrgID, data, defaultMessage)
+
+		go func(r storage.TelegramRecipient, message string) {
+			// Create independent context to avoid cancellation from parent
+			sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := t.deliver(sendCtx, r, message, replyMarkup); err != nil {
+				t.logger.Printf("[TELEGRAM] failed to send to chat_id=%s: %v", r.ChatID, err)
+			}
+		}(recipient, message)
+	}
+}
+
 // SendTest sends a test message to verify configuration
 func (t *TelegramNotifier) SendTest(ctx context.Context, recipient storage.TelegramRecipient) error {
 	if !t.config.IsEnabled() {
@@ -81,39 +245,60 @@ func (t *TelegramNotifier) SendTest(ctx context.Context, recipient storage.Teleg
 	return t.sendToRecipient(ctx, recipient, message)
 }
 
-// getAllActiveRecipients fetches all active Telegram recipients from all users
-func (t *TelegramNotifier) getAllActiveRecipients(ctx context.Context) ([]storage.TelegramRecipient, error) {
-	users, err := t.store.ListUsers(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
-	}
+// getActiveRecipientsForEvent fetches the active Telegram recipients of the organizations an alert event concerns
+func (t *TelegramNotifier) getActiveRecipientsForEvent(ctx context.Context, rule storage.AlertRule, event storage.AlertEvent) ([]storage.TelegramRecipient, error) {
+	orgIDs := eventOrganizations(ctx, t.store, rule, event)
 
 	var allRecipients []storage.TelegramRecipient
-	for _, user := range users {
-		recipients, err := t.store.ListTelegramRecipients(ctx, user.ID)
+	for _, orgID := range orgIDs {
+		recipients, err := t.getActiveRecipientsForOrg(ctx, orgID)
 		if err != nil {
-			t.logger.Printf("[TELEGRAM] Failed to fetch recipients for user %d: %v", user.ID, err)
+			t.logger.Printf("[TELEGRAM] Failed to fetch recipients for organization %d: %v", orgID, err)
 			continue
 		}
-
-		for _, recipient := range recipients {
-			if recipient.IsActive {
-				allRecipients = append(allRecipients, recipient)
-			}
-		}
+		allRecipients = append(allRecipients, recipients...)
 	}
 
 	return allRecipients, nil
 }
 
+// getActiveRecipientsForOrg fetches an organization's active Telegram recipients
+func (t *TelegramNotifier) getActiveRecipientsForOrg(ctx context.Context, orgID int) ([]storage.TelegramRecipient, error) {
+	recipients, err := t.store.ListTelegramRecipients(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []storage.TelegramRecipient
+	for _, recipient := range recipients {
+		if recipient.IsActive {
+			active = append(active, recipient)
+		}
+	}
+	return active, nil
+}
+
 // sendToRecipient sends a message to a specific Telegram chat
 func (t *TelegramNotifier) sendToRecipient(ctx context.Context, recipient storage.TelegramRecipient, message string) error {
-	apiURL := fmt.Sprintf(TelegramAPIURL, t.config.BotToken)
+	return t.deliver(ctx, recipient, message, nil)
+}
+
+// deliver sends a message with optional inline buttons to a recipient, applying the delivery policy
+func (t *TelegramNotifier) deliver(ctx context.Context, recipient storage.TelegramRecipient, message string, replyMarkup *InlineKeyboardMarkup) error {
+	// Check rate limiting and cooldown before proceeding
+	if err := t.checkDeliveryPreconditions(recipient); err != nil {
+		return err
+	}
+
+	apiURL := t.config.MethodURL("sendMessage")
 
 	payload := map[string]interface{}{
 		"chat_id": recipient.ChatID,
 		"text":    message,
 	}
+	if replyMarkup != nil {
+		payload["reply_markup"] = replyMarkup
+	}
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -134,7 +319,7 @@ func (t *TelegramNotifier) sendToRecipient(ctx context.Context, recipient storag
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		t.store.IncrementTelegramFailure(ctx, recipient.ID, time.Now())
+		t.recordFailure(ctx, recipient, now)
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
@@ -142,7 +327,7 @@ func (t *TelegramNotifier) sendToRecipient(ctx context.Context, recipient storag
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		var errorBody bytes.Buffer
 		errorBody.ReadFrom(resp.Body)
-		t.store.IncrementTelegramFailure(ctx, recipient.ID, time.Now())
+		t.recordFailure(ctx, recipient, now)
 		return fmt.Errorf("Telegram API error status=%d body=%s", resp.StatusCode, errorBody.String())
 	}
 
@@ -152,32 +337,176 @@ func (t *TelegramNotifier) sendToRecipient(ctx context.Context, recipient storag
 	return nil
 }
 
-// buildAlertMessage builds the Telegram message for an alert
-func (t *TelegramNotifier) buildAlertMessage(rule storage.AlertRule, event storage.AlertEvent) string {
+// renderMessage renders the organization's Telegram template for the event, falling back to the
+// default message when no template is configured or the template fails to render
+func (t *TelegramNotifier) renderMessage(ctx context.Context, orgID int, data TemplateData, defaultMessage string) string {
+	body, err := loadOrgTemplate(ctx, t.store, orgID, ChannelTelegram, data.Event)
+	if err != nil {
+		t.logger.Printf("[TELEGRAM] failed to load template for org=%d event=%s: %v", orgID, data.Event, err)
+	}
+
+	if body == "" {
+		return defaultMessage
+	}
+
+	message, err := RenderTemplate(ChannelTelegram, body, data)
+	if err != nil {
+		t.logger.Printf("[TELEGRAM] template render failed for org=%d event=%s, using default message: %v",
+			orgID, data.Event, err)
+		return defaultMessage
+	}
+
+	return message
+}
+
+// conditionText describes when a rule fires, e.g. "above 90.0%" or "reaches 95.0% within 24h"
+func conditionText(rule storage.AlertRule) string {
 	comparisonText := "above"
 	if rule.Comparison == "below" {
 		comparisonText = "below"
 	}
 
+	switch rule.Type {
+	case storage.RuleTypeRate:
+		return fmt.Sprintf("changing %s %.1f%%/h", comparisonText, rule.ThresholdPct)
+	case storage.RuleTypeForecast:
+		return fmt.Sprintf("projected to reach %.1f%% within %s", rule.ThresholdPct,
+			(time.Duration(rule.HorizonSeconds) * time.Second).String())
+	case storage.RuleTypeExpression:
+		return rule.Expression
+	case storage.RuleTypeAnomaly:
+		return fmt.Sprintf("%.1fσ %s the %s baseline", rule.ThresholdPct, comparisonText, rule.Seasonality)
+	default:
+		return fmt.Sprintf("%s %.1f%%", comparisonText, rule.ThresholdPct)
+	}
+}
+
+// valueText formats an event value in the unit of its rule's type
+func valueText(rule storage.AlertRule, value float64) string {
+	switch rule.Type {
+	case storage.RuleTypeRate:
+		return fmt.Sprintf("%+.1f%%/h", value)
+	case storage.RuleTypeForecast:
+		return fmt.Sprintf("threshold in %.1fh", value)
+	default:
+		return fmt.Sprintf("%.1f%%", value)
+	}
+}
+
+// expectedText describes the range an anomaly rule expected, or is empty for other events
+func expectedText(event storage.AlertEvent) string {
+	if event.ExpectedLow == nil || event.ExpectedHigh == nil {
+		return ""
+	}
+	return fmt.Sprintf("Expected: %.1f%% - %.1f%%\n", *event.ExpectedLow, *event.ExpectedHigh)
+}
+
+// buildAlertMessage builds the Telegram message for an alert
+func (t *TelegramNotifier) buildAlertMessage(rule storage.AlertRule, event storage.AlertEvent) string {
+	severity := eventSeverity(rule, event)
+
 	// Use plain text instead of Markdown to avoid parsing issues
 	return fmt.Sprintf(
-		"🚨 LunaSentri Alert\n\n"+
+		"%s LunaSentri Alert (%s)\n\n"+
 			"Rule: %s\n"+
 			"Metric: %s\n"+
-			"Condition: %s %.1f%%\n"+
-			"Current Value: %.1f%%\n"+
+			"Condition: %s\n"+
+			"Current Value: %s\n"+
+			"%s"+
 			"Triggered: %s\n\n"+
 			"Alert triggered after %d consecutive samples",
+		severityEmoji(severity),
+		strings.ToUpper(severity),
 		rule.Name,
 		rule.Metric,
-		comparisonText,
-		rule.ThresholdPct,
-		event.Value,
+		conditionText(rule),
+		valueText(rule, event.Value),
+		expectedText(event),
 		event.TriggeredAt.Format("2006-01-02 15:04:05"),
 		rule.TriggerAfter,
 	)
 }
 
+// buildEscalationMessage builds the Telegram message for an alert paged by an escalation step
+func (t *TelegramNotifier) buildEscalationMessage(escalation AlertEscalation) string {
+	return fmt.Sprintf(
+		"⏫ Escalated: not acknowledged since %s (%s, step %d)\n\n%s",
+		escalation.Event.TriggeredAt.Format("2006-01-02 15:04:05"),
+		escalation.PolicyName,
+		escalation.Step,
+		t.buildAlertMessage(escalation.Rule, escalation.Event),
+	)
+}
+
+// buildResolvedMessage builds the Telegram message for a resolved alert
+func (t *TelegramNotifier) buildResolvedMessage(rule storage.AlertRule, event storage.AlertEvent) string {
+	resolvedAt := time.Now()
+	if event.ResolvedAt != nil {
+		resolvedAt = *event.ResolvedAt
+	}
+
+	return fmt.Sprintf(
+		"✅ LunaSentri Alert Resolved\n\n"+
+			"Rule: %s\n"+
+			"Metric: %s\n"+
+			"Condition: %s\n"+
+			"Triggered: %s\n"+
+			"Resolved: %s",
+		rule.Name,
+		rule.Metric,
+		conditionText(rule),
+		event.TriggeredAt.Format("2006-01-02 15:04:05"),
+		resolvedAt.Format("2006-01-02 15:04:05"),
+	)
+}
+
+// buildDigestMessage builds the Telegram message summarising a batch of alert events
+func (t *TelegramNotifier) buildDigestMessage(digest AlertDigest) string {
+	var b strings.Builder
+
+	if digest.Reason == DigestReasonQuietHours {
+		b.WriteString("🌙 LunaSentri Quiet Hours Summary\n\n")
+	} else {
+		b.WriteString("📋 LunaSentri Alert Digest\n\n")
+	}
+	fmt.Fprintf(&b, "%d alert event(s) between %s and %s\n",
+		len(digest.Entries),
+		digest.Since.Format("2006-01-02 15:04"),
+		digest.Until.Format("2006-01-02 15:04"))
+
+	for _, entry := range digest.Entries {
+		status := severityEmoji(eventSeverity(entry.Rule, entry.Event)) + " Fired"
+		if entry.EventType == EventAlertResolved {
+			status = "✅ Resolved"
+		}
+		fmt.Fprintf(&b, "\n%s: %s (%s %s) at %s",
+			status,
+			entry.Rule.Name,
+			entry.Rule.Metric,
+			valueText(entry.Rule, entry.Event.Value),
+			entry.Event.TriggeredAt.Format("15:04:05"))
+	}
+
+	// Long digests are cut at the Telegram limit rather than rejected
+	message := []rune(b.String())
+	if len(message) > MaxTelegramMessageLength {
+		return string(message[:MaxTelegramMessageLength-1]) + "…"
+	}
+	return string(message)
+}
+
+// severityEmoji returns the marker used for a severity in Telegram messages
+func severityEmoji(severity string) string {
+	switch severity {
+	case storage.SeverityCritical:
+		return "🚨"
+	case storage.SeverityInfo:
+		return "ℹ️"
+	default:
+		return "⚠️"
+	}
+}
+
 // Notify implements AlertNotifier interface
 func (t *TelegramNotifier) Notify(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
 	if event == nil {
@@ -185,3 +514,11 @@ func (t *TelegramNotifier) Notify(ctx context.Context, rule storage.AlertRule, e
 	}
 	return t.Send(ctx, rule, *event)
 }
+
+// NotifyResolved implements AlertResolutionNotifier interface
+func (t *TelegramNotifier) NotifyResolved(ctx context.Context, rule storage.AlertRule, event *storage.AlertEvent) error {
+	if event == nil {
+		return nil
+	}
+	return t.SendResolved(ctx, rule, *event)
+}
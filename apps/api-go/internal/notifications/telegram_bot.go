@@ -0,0 +1,561 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// MaxTelegramSilenceDuration caps silences created from chat
+	MaxTelegramSilenceDuration = 7 * 24 * time.Hour
+	// telegramPollTimeout is the long-poll timeout passed to getUpdates
+	telegramPollTimeout = 25 * time.Second
+	// telegramAckCallbackPrefix prefixes callback data of Acknowledge buttons
+	telegramAckCallbackPrefix = "ack:"
+)
+
+// TelegramUpdate is an incoming update from the Bot API
+type TelegramUpdate struct {
+	UpdateID      int                    `json:"update_id"`
+	Message       *TelegramMessage       `json:"message,omitempty"`
+	CallbackQuery *TelegramCallbackQuery `json:"callback_query,omitempty"`
+}
+
+// TelegramMessage is a chat message
+type TelegramMessage struct {
+	MessageID int           `json:"message_id"`
+	From      *TelegramUser `json:"from,omitempty"`
+	Chat      TelegramChat  `json:"chat"`
+	Text      string        `json:"text"`
+}
+
+// TelegramChat identifies the chat a message belongs to
+type TelegramChat struct {
+	ID int64 `json:"id"`
+}
+
+// TelegramUser is the Telegram account that sent a message or pressed a button
+type TelegramUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username,omitempty"`
+}
+
+// TelegramCallbackQuery is sent when a user presses an inline button
+type TelegramCallbackQuery struct {
+	ID      string           `json:"id"`
+	From    TelegramUser     `json:"from"`
+	Message *TelegramMessage `json:"message,omitempty"`
+	Data    string           `json:"data"`
+}
+
+// InlineKeyboardMarkup attaches inline buttons to a message
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+// InlineKeyboardButton is a single inline button
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// ackKeyboard returns the inline keyboard with an Acknowledge button for an alert event
+func ackKeyboard(eventID int) *InlineKeyboardMarkup {
+	return &InlineKeyboardMarkup{
+		InlineKeyboard: [][]InlineKeyboardButton{{
+			{Text: "✅ Acknowledge", CallbackData: fmt.Sprintf("%s%d", telegramAckCallbackPrefix, eventID)},
+		}},
+	}
+}
+
+// AlertAcknowledger acknowledges alert events on behalf of the bot
+type AlertAcknowledger interface {
+	AcknowledgeEvent(ctx context.Context, orgID, eventID int) error
+}
+
+// TelegramBot handles inbound commands and button presses from registered Telegram chats
+type TelegramBot struct {
+	store        storage.Store
+	notifier     *TelegramNotifier
+	acknowledger AlertAcknowledger
+	logger       *log.Logger
+	client       *http.Client
+	stopCh       chan struct{}
+	doneCh       chan struct{}
+}
+
+// NewTelegramBot creates a bot that replies through the notifier's Bot API configuration
+func NewTelegramBot(store storage.Store, notifier *TelegramNotifier, acknowledger AlertAcknowledger, logger *log.Logger) *TelegramBot {
+	return &TelegramBot{
+		store:        store,
+		notifier:     notifier,
+		acknowledger: acknowledger,
+		logger:       logger,
+		// Must outlive the getUpdates long poll
+		client: &http.Client{Timeout: telegramPollTimeout + 10*time.Second},
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+}
+
+// Start begins long polling getUpdates in a background goroutine
+func (b *TelegramBot) Start(ctx context.Context) {
+	go b.run(ctx)
+	b.logger.Println("[TELEGRAM] bot started (mode: polling)")
+}
+
+// Stop gracefully stops the polling loop
+func (b *TelegramBot) Stop() {
+	close(b.stopCh)
+	<-b.doneCh
+	b.logger.Println("[TELEGRAM] bot stopped")
+}
+
+// run is the long polling loop
+func (b *TelegramBot) run(ctx context.Context) {
+	defer close(b.doneCh)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-b.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	offset := 0
+	for {
+		updates, err := b.getUpdates(ctx, offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			b.logger.Printf("[TELEGRAM] getUpdates failed: %v", err)
+			select {
+			case <-time.After(5 * time.Second):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			b.HandleUpdate(ctx, update)
+		}
+	}
+}
+
+// getUpdates long-polls the Bot API for updates after offset
+func (b *TelegramBot) getUpdates(ctx context.Context, offset int) ([]TelegramUpdate, error) {
+	var updates []TelegramUpdate
+	err := b.callAPI(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(telegramPollTimeout / time.Second),
+		"allowed_updates": []string{"message", "callback_query"},
+	}, &updates)
+	return updates, err
+}
+
+// HandleUpdate processes a single update from polling or the webhook endpoint
+func (b *TelegramBot) HandleUpdate(ctx context.Context, update TelegramUpdate) {
+	switch {
+	case update.CallbackQuery != nil:
+		b.handleCallback(ctx, *update.CallbackQuery)
+	case update.Message != nil && strings.HasPrefix(update.Message.Text, "/"):
+		b.handleCommand(ctx, *update.Message)
+	}
+}
+
+// Reasons authorize refuses a chat
+var (
+	errTelegramChatNotLinked = errors.New("chat not linked")
+	errTelegramChatAmbiguous = errors.New("chat linked to several organizations")
+)
+
+// authorize maps a chat to the LunaSentri organization that registered it as an active recipient.
+// A chat active in several organizations is refused, since its commands could act on any of them.
+func (b *TelegramBot) authorize(ctx context.Context, chatID int64) (*storage.TelegramRecipient, error) {
+	recipients, err := b.store.ListTelegramRecipientsByChatID(ctx, strconv.FormatInt(chatID, 10))
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] failed to look up chat_id=%d: %v", chatID, err)
+		return nil, errTelegramChatNotLinked
+	}
+
+	var linked *storage.TelegramRecipient
+	for _, recipient := range recipients {
+		if !recipient.IsActive {
+			continue
+		}
+		if linked != nil {
+			return nil, errTelegramChatAmbiguous
+		}
+		linked = &recipient
+	}
+	if linked == nil {
+		return nil, errTelegramChatNotLinked
+	}
+	return linked, nil
+}
+
+// canRespond reports whether the member who linked the chat may acknowledge and silence alerts in
+// its organization. Chats whose member has left or been deleted can only read.
+func (b *TelegramBot) canRespond(ctx context.Context, recipient *storage.TelegramRecipient) bool {
+	if recipient.CreatedBy == nil {
+		return false
+	}
+	member, err := b.store.GetOrgMember(ctx, recipient.OrgID, *recipient.CreatedBy)
+	if err != nil {
+		if !errors.Is(err, storage.ErrOrgMemberNotFound) {
+			b.logger.Printf("[TELEGRAM] failed to look up member of chat_id=%s: %v", recipient.ChatID, err)
+		}
+		return false
+	}
+	return auth.RoleHas(member.Role, auth.ScopeAlertsWrite)
+}
+
+// telegramReadOnlyText explains why a chat may not change anything
+const telegramReadOnlyText = "This chat was linked by a member who cannot acknowledge or silence alerts. " +
+	"An operator, admin or owner has to link it under Settings → Telegram."
+
+// handleCommand dispatches a slash command
+func (b *TelegramBot) handleCommand(ctx context.Context, msg TelegramMessage) {
+	fields := strings.Fields(msg.Text)
+	// Commands in group chats may be addressed as /cmd@BotName
+	command := strings.ToLower(strings.SplitN(fields[0], "@", 2)[0])
+	args := fields[1:]
+
+	recipient, err := b.authorize(ctx, msg.Chat.ID)
+	if errors.Is(err, errTelegramChatAmbiguous) {
+		b.logger.Printf("[TELEGRAM] rejected command=%s from chat_id=%d linked to several organizations", command, msg.Chat.ID)
+		b.reply(ctx, msg.Chat.ID, fmt.Sprintf(
+			"This chat (ID %d) is linked to several LunaSentri organizations.\n\nRemove or disable it in all but one of them under Settings → Telegram, then try again.", msg.Chat.ID))
+		return
+	}
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] rejected command=%s from unregistered chat_id=%d", command, msg.Chat.ID)
+		b.reply(ctx, msg.Chat.ID, fmt.Sprintf(
+			"This chat (ID %d) is not linked to a LunaSentri account.\n\nAdd the chat ID under Settings → Telegram, then try again.", msg.Chat.ID))
+		return
+	}
+
+	var response string
+	switch command {
+	case "/start", "/help":
+		response = telegramHelpText
+	case "/status":
+		response = b.statusText(ctx, recipient.OrgID)
+	case "/machines":
+		response = b.machinesText(ctx, recipient.OrgID)
+	case "/ack":
+		response = b.ackCommand(ctx, recipient, args)
+	case "/silence":
+		response = b.silenceCommand(ctx, recipient, args)
+	default:
+		response = "Unknown command. Send /help for the list of commands."
+	}
+
+	b.reply(ctx, msg.Chat.ID, response)
+}
+
+const telegramHelpText = "🌙 LunaSentri bot\n\n" +
+	"/status - machines and open alerts\n" +
+	"/machines - your machines and their status\n" +
+	"/ack <event_id> - acknowledge an alert\n" +
+	"/silence <rule_id> <duration> - silence a rule, e.g. /silence 3 1h"
+
+// statusText summarises machine health and open alerts
+func (b *TelegramBot) statusText(ctx context.Context, orgID int) string {
+	machines, err := b.store.ListMachines(ctx, orgID)
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] failed to list machines for org=%d: %v", orgID, err)
+		return "Failed to load status, please try again."
+	}
+
+	online := 0
+	for _, machine := range machines {
+		if machine.Status == "online" {
+			online++
+		}
+	}
+
+	open, err := b.openEvents(ctx, orgID)
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] failed to list alert events: %v", err)
+		return "Failed to load status, please try again."
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Machines: %d online, %d offline\n", online, len(machines)-online)
+	fmt.Fprintf(&sb, "Open alerts: %d", len(open))
+
+	if len(open) > 0 {
+		ruleNames := b.ruleNames(ctx, orgID)
+		for i, event := range open {
+			if i == 5 {
+				fmt.Fprintf(&sb, "\n…and %d more", len(open)-5)
+				break
+			}
+			fmt.Fprintf(&sb, "\n#%d %s (%.1f%%) since %s", event.ID, ruleNames[event.RuleID], event.Value,
+				event.TriggeredAt.UTC().Format("Jan 2 15:04 UTC"))
+		}
+	}
+
+	return sb.String()
+}
+
+// machinesText lists the organization's machines
+func (b *TelegramBot) machinesText(ctx context.Context, orgID int) string {
+	machines, err := b.store.ListMachines(ctx, orgID)
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] failed to list machines for org=%d: %v", orgID, err)
+		return "Failed to load machines, please try again."
+	}
+
+	if len(machines) == 0 {
+		return "No machines registered yet."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Machines:")
+	for _, machine := range machines {
+		icon := "🔴"
+		if machine.Status == "online" {
+			icon = "🟢"
+		}
+		fmt.Fprintf(&sb, "\n%s %s (%s", icon, machine.Name, machine.Status)
+		if !machine.LastSeen.IsZero() {
+			fmt.Fprintf(&sb, ", last seen %s ago", time.Since(machine.LastSeen).Round(time.Second))
+		}
+		sb.WriteString(")")
+	}
+	return sb.String()
+}
+
+// ackCommand handles /ack <event_id>
+func (b *TelegramBot) ackCommand(ctx context.Context, recipient *storage.TelegramRecipient, args []string) string {
+	if len(args) != 1 {
+		return "Usage: /ack <event_id>"
+	}
+	if !b.canRespond(ctx, recipient) {
+		return telegramReadOnlyText
+	}
+
+	eventID, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil || eventID <= 0 {
+		return "Usage: /ack <event_id>"
+	}
+
+	return b.acknowledge(ctx, recipient, eventID)
+}
+
+// acknowledge acknowledges an event and returns the reply text
+func (b *TelegramBot) acknowledge(ctx context.Context, recipient *storage.TelegramRecipient, eventID int) string {
+	if err := b.acknowledger.AcknowledgeEvent(ctx, recipient.OrgID, eventID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return fmt.Sprintf("Alert #%d not found or already acknowledged.", eventID)
+		}
+		b.logger.Printf("[TELEGRAM] failed to acknowledge event=%d: %v", eventID, err)
+		return "Failed to acknowledge alert, please try again."
+	}
+
+	b.logger.Printf("[TELEGRAM] event=%d acknowledged by org=%d via chat_id=%s", eventID, recipient.OrgID, recipient.ChatID)
+	return fmt.Sprintf("✅ Alert #%d acknowledged.", eventID)
+}
+
+// silenceCommand handles /silence <rule_id> <duration>
+func (b *TelegramBot) silenceCommand(ctx context.Context, recipient *storage.TelegramRecipient, args []string) string {
+	const usage = "Usage: /silence <rule_id> <duration>, e.g. /silence 3 1h"
+	if len(args) != 2 {
+		return usage
+	}
+	if !b.canRespond(ctx, recipient) {
+		return telegramReadOnlyText
+	}
+
+	ruleID, err := strconv.Atoi(args[0])
+	if err != nil || ruleID <= 0 {
+		return usage
+	}
+
+	duration, err := time.ParseDuration(args[1])
+	if err != nil || duration <= 0 {
+		return usage
+	}
+	if duration > MaxTelegramSilenceDuration {
+		return "Silences from Telegram can last at most 168h."
+	}
+
+	rule, err := b.store.GetAlertRule(ctx, ruleID, recipient.OrgID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return fmt.Sprintf("Rule %d not found.", ruleID)
+		}
+		b.logger.Printf("[TELEGRAM] failed to silence rule=%d: %v", ruleID, err)
+		return "Failed to silence rule, please try again."
+	}
+
+	now := time.Now()
+	end := now.Add(duration)
+	silence, err := b.store.CreateSilence(ctx, storage.Silence{
+		OrgID:     recipient.OrgID,
+		RuleID:    &ruleID,
+		Reason:    "Silenced from Telegram chat " + recipient.ChatID,
+		StartsAt:  now,
+		EndsAt:    &end,
+		CreatedBy: *recipient.CreatedBy, // set, since canRespond checked the member
+	})
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] failed to silence rule=%d: %v", ruleID, err)
+		return "Failed to silence rule, please try again."
+	}
+
+	b.logger.Printf("[TELEGRAM] rule=%d silenced until %s by org=%d user=%d via chat_id=%s", ruleID, silence.EndsAt.Format(time.RFC3339),
+		recipient.OrgID, silence.CreatedBy, recipient.ChatID)
+	return fmt.Sprintf("🔕 Rule '%s' silenced until %s.", rule.Name, silence.EndsAt.UTC().Format("Jan 2 15:04 UTC"))
+}
+
+// handleCallback handles inline button presses
+func (b *TelegramBot) handleCallback(ctx context.Context, query TelegramCallbackQuery) {
+	if query.Message == nil {
+		b.answerCallback(ctx, query.ID, "")
+		return
+	}
+
+	recipient, err := b.authorize(ctx, query.Message.Chat.ID)
+	if errors.Is(err, errTelegramChatAmbiguous) {
+		b.logger.Printf("[TELEGRAM] rejected callback from chat_id=%d linked to several organizations", query.Message.Chat.ID)
+		b.answerCallback(ctx, query.ID, "This chat is linked to several LunaSentri organizations.")
+		return
+	}
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] rejected callback from unregistered chat_id=%d", query.Message.Chat.ID)
+		b.answerCallback(ctx, query.ID, "This chat is not linked to a LunaSentri account.")
+		return
+	}
+
+	if !strings.HasPrefix(query.Data, telegramAckCallbackPrefix) {
+		b.answerCallback(ctx, query.ID, "Unknown action.")
+		return
+	}
+
+	eventID, err := strconv.Atoi(strings.TrimPrefix(query.Data, telegramAckCallbackPrefix))
+	if err != nil {
+		b.answerCallback(ctx, query.ID, "Unknown action.")
+		return
+	}
+	if !b.canRespond(ctx, recipient) {
+		b.answerCallback(ctx, query.ID, telegramReadOnlyText)
+		return
+	}
+
+	b.answerCallback(ctx, query.ID, b.acknowledge(ctx, recipient, eventID))
+
+	// Remove the button so it can't be pressed again
+	if err := b.callAPI(ctx, "editMessageReplyMarkup", map[string]interface{}{
+		"chat_id":      query.Message.Chat.ID,
+		"message_id":   query.Message.MessageID,
+		"reply_markup": InlineKeyboardMarkup{InlineKeyboard: [][]InlineKeyboardButton{}},
+	}, nil); err != nil {
+		b.logger.Printf("[TELEGRAM] failed to remove ack button: %v", err)
+	}
+}
+
+// openEvents returns the organization's alert events that are neither acknowledged nor resolved
+func (b *TelegramBot) openEvents(ctx context.Context, orgID int) ([]storage.AlertEvent, error) {
+	unresolved, unacknowledged := false, false
+	open, _, err := b.store.QueryAlertEvents(ctx, storage.AlertEventFilter{
+		OrgID:        orgID,
+		Resolved:     &unresolved,
+		Acknowledged: &unacknowledged,
+		Limit:        100,
+	})
+	return open, err
+}
+
+// ruleNames maps the IDs of the organization's rules to names
+func (b *TelegramBot) ruleNames(ctx context.Context, orgID int) map[int]string {
+	names := make(map[int]string)
+	rules, err := b.store.ListAlertRules(ctx, orgID)
+	if err != nil {
+		b.logger.Printf("[TELEGRAM] failed to list alert rules: %v", err)
+		return names
+	}
+	for _, rule := range rules {
+		names[rule.ID] = rule.Name
+	}
+	return names
+}
+
+// reply sends a plain text message to a chat
+func (b *TelegramBot) reply(ctx context.Context, chatID int64, text string) {
+	if err := b.callAPI(ctx, "sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}, nil); err != nil {
+		b.logger.Printf("[TELEGRAM] failed to reply to chat_id=%d: %v", chatID, err)
+	}
+}
+
+// answerCallback acknowledges a button press, optionally showing a notice to the user
+func (b *TelegramBot) answerCallback(ctx context.Context, callbackID, text string) {
+	payload := map[string]interface{}{"callback_query_id": callbackID}
+	if text != "" {
+		payload["text"] = text
+	}
+	if err := b.callAPI(ctx, "answerCallbackQuery", payload, nil); err != nil {
+		b.logger.Printf("[TELEGRAM] failed to answer callback: %v", err)
+	}
+}
+
+// callAPI invokes a Bot API method and decodes its result into result (if non-nil)
+func (b *TelegramBot) callAPI(ctx context.Context, method string, payload interface{}, result interface{}) error {
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", b.notifier.config.MethodURL(method), bytes.NewReader(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("failed to decode %s response (status=%d): %w", method, resp.StatusCode, err)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("Telegram API error method=%s status=%d: %s", method, resp.StatusCode, apiResp.Description)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(apiResp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+	}
+	return nil
+}
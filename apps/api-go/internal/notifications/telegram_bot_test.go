@@ -0,0 +1,403 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/config"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// fakeBotAPI is a minimal stand-in for the Telegram Bot API
+type fakeBotAPI struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	calls   []fakeBotCall
+	updates []TelegramUpdate
+}
+
+type fakeBotCall struct {
+	Method  string
+	Payload map[string]interface{}
+}
+
+func newFakeBotAPI(t *testing.T) *fakeBotAPI {
+	api := &fakeBotAPI{}
+	api.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		api.mu.Lock()
+		api.calls = append(api.calls, fakeBotCall{Method: method, Payload: payload})
+		var result interface{} = true
+		if method == "getUpdates" {
+			result = api.updates
+			api.updates = nil
+		}
+		api.mu.Unlock()
+
+		if method == "getUpdates" && result.([]TelegramUpdate) == nil {
+			// Emulate an empty long poll without holding the test up
+			time.Sleep(20 * time.Millisecond)
+			result = []TelegramUpdate{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+	}))
+	t.Cleanup(api.server.Close)
+	return api
+}
+
+func (a *fakeBotAPI) config() *config.TelegramConfig {
+	return &config.TelegramConfig{
+		BotToken:      "test-token",
+		APIBaseURL:    a.server.URL,
+		BotMode:       config.TelegramBotModeWebhook,
+		WebhookSecret: "hook-secret",
+	}
+}
+
+// callsTo returns recorded calls to a Bot API method
+func (a *fakeBotAPI) callsTo(method string) []fakeBotCall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var calls []fakeBotCall
+	for _, call := range a.calls {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// lastReply returns the text of the most recent sendMessage call
+func (a *fakeBotAPI) lastReply(t *testing.T) string {
+	t.Helper()
+	calls := a.callsTo("sendMessage")
+	if len(calls) == 0 {
+		t.Fatal("Expected a sendMessage call")
+	}
+	text, _ := calls[len(calls)-1].Payload["text"].(string)
+	return text
+}
+
+// storeAcknowledger acknowledges events directly in the store
+type storeAcknowledger struct {
+	store storage.Store
+}
+
+func (a storeAcknowledger) AcknowledgeEvent(ctx context.Context, orgID, eventID int) error {
+	return a.store.AckAlertEvent(ctx, eventID)
+}
+
+type botFixture struct {
+	api   *fakeBotAPI
+	store *storage.SQLiteStore
+	bot   *TelegramBot
+	rule  *storage.AlertRule
+	event *storage.AlertEvent
+}
+
+func setupTelegramBot(t *testing.T) *botFixture {
+	t.Helper()
+
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "oncall@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := store.CreateTelegramRecipient(ctx, user.ID, "1001", user.ID); err != nil {
+		t.Fatalf("Failed to create recipient: %v", err)
+	}
+	if _, err := store.CreateMachine(ctx, user.ID, "web-01", "web-01.local", "", "keyhash"); err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+	rule, err := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1})
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	event, err := store.CreateAlertEvent(ctx, rule.ID, nil, 93.5)
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	api := newFakeBotAPI(t)
+	logger := log.New(io.Discard, "", 0)
+	notifier := NewTelegramNotifier(store, api.config(), logger)
+	bot := NewTelegramBot(store, notifier, storeAcknowledger{store}, logger)
+
+	return &botFixture{api: api, store: store, bot: bot, rule: rule, event: event}
+}
+
+func commandUpdate(chatID int64, text string) TelegramUpdate {
+	return TelegramUpdate{UpdateID: 1, Message: &TelegramMessage{MessageID: 10, Chat: TelegramChat{ID: chatID}, Text: text}}
+}
+
+func TestTelegramBot_RejectsUnregisteredChat(t *testing.T) {
+	f := setupTelegramBot(t)
+
+	f.bot.HandleUpdate(context.Background(), commandUpdate(2002, "/ack 1"))
+
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "not linked") || !strings.Contains(reply, "2002") {
+		t.Errorf("Expected not-linked reply with chat ID, got %q", reply)
+	}
+
+	events, _ := f.store.ListAlertEvents(context.Background(), 10)
+	if events[0].Acknowledged {
+		t.Error("Expected event to stay unacknowledged for an unregistered chat")
+	}
+}
+
+func TestTelegramBot_RejectsChatOfSeveralOrganizations(t *testing.T) {
+	f := setupTelegramBot(t)
+	ctx := context.Background()
+
+	other, _ := f.store.CreateUser(ctx, "other@example.com", "hash")
+	if _, err := f.store.CreateTelegramRecipient(ctx, other.ID, "1001", other.ID); err != nil {
+		t.Fatalf("Failed to create recipient: %v", err)
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(1001, "/ack "+strconv.Itoa(f.event.ID)))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "several") {
+		t.Errorf("Expected a reply naming several organizations, got %q", reply)
+	}
+	events, _ := f.store.ListAlertEvents(ctx, 10)
+	if events[0].Acknowledged {
+		t.Error("Expected event to stay unacknowledged for a chat of several organizations")
+	}
+}
+
+func TestTelegramBot_ViewerChatCannotRespond(t *testing.T) {
+	f := setupTelegramBot(t)
+	ctx := context.Background()
+
+	viewer, _ := f.store.CreateUser(ctx, "viewer@example.com", "hash")
+	if _, err := f.store.AddOrgMember(ctx, f.rule.OrgID, viewer.ID, storage.RoleViewer); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	if _, err := f.store.CreateTelegramRecipient(ctx, f.rule.OrgID, "3003", viewer.ID); err != nil {
+		t.Fatalf("Failed to create recipient: %v", err)
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(3003, "/status"))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "Open alerts: 1") {
+		t.Errorf("Expected a viewer's chat to read the status, got %q", reply)
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(3003, "/ack "+strconv.Itoa(f.event.ID)))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "cannot acknowledge") {
+		t.Errorf("Expected /ack to be refused, got %q", reply)
+	}
+	f.bot.HandleUpdate(ctx, commandUpdate(3003, "/silence "+strconv.Itoa(f.rule.ID)+" 1h"))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "cannot acknowledge or silence") {
+		t.Errorf("Expected /silence to be refused, got %q", reply)
+	}
+	f.bot.HandleUpdate(ctx, TelegramUpdate{
+		UpdateID: 2,
+		CallbackQuery: &TelegramCallbackQuery{
+			ID:      "cb-1",
+			Message: &TelegramMessage{MessageID: 77, Chat: TelegramChat{ID: 3003}},
+			Data:    "ack:" + strconv.Itoa(f.event.ID),
+		},
+	})
+	if edits := f.api.callsTo("editMessageReplyMarkup"); len(edits) != 0 {
+		t.Errorf("Expected the button to stay, got %d edits", len(edits))
+	}
+
+	events, _ := f.store.ListAlertEvents(ctx, 10)
+	if events[0].Acknowledged {
+		t.Error("Expected event to stay unacknowledged")
+	}
+	if silences, _ := f.store.ListActiveSilences(ctx, time.Now()); len(silences) != 0 {
+		t.Errorf("Expected no silences, got %+v", silences)
+	}
+
+	// The chat can respond once its member is promoted
+	f.store.SetOrgMemberRole(ctx, f.rule.OrgID, viewer.ID, storage.RoleOperator)
+	f.bot.HandleUpdate(ctx, commandUpdate(3003, "/ack "+strconv.Itoa(f.event.ID)))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "acknowledged.") {
+		t.Errorf("Expected an operator's chat to acknowledge, got %q", reply)
+	}
+
+	// Silences are recorded against the member who linked the chat, not the organization's owner
+	f.bot.HandleUpdate(ctx, commandUpdate(3003, "/silence "+strconv.Itoa(f.rule.ID)+" 1h"))
+	silences, _ := f.store.ListActiveSilences(ctx, time.Now())
+	if len(silences) != 1 || silences[0].CreatedBy != viewer.ID {
+		t.Errorf("Expected one silence created by user %d, got %+v", viewer.ID, silences)
+	}
+}
+
+func TestTelegramBot_Commands(t *testing.T) {
+	f := setupTelegramBot(t)
+	ctx := context.Background()
+
+	f.bot.HandleUpdate(ctx, commandUpdate(1001, "/status"))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "Open alerts: 1") || !strings.Contains(reply, "High CPU") {
+		t.Errorf("Unexpected /status reply: %q", reply)
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(1001, "/machines"))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "web-01") {
+		t.Errorf("Unexpected /machines reply: %q", reply)
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(1001, "/silence@LunaSentriBot "+strconv.Itoa(f.rule.ID)+" 1h"))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "silenced until") {
+		t.Errorf("Unexpected /silence reply: %q", reply)
+	}
+	silences, err := f.store.ListActiveSilences(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to list silences: %v", err)
+	}
+	if len(silences) != 1 || *silences[0].RuleID != f.rule.ID {
+		t.Fatalf("Expected one silence for rule %d, got %+v", f.rule.ID, silences)
+	}
+	// Recorded against the member who linked the chat, whose personal organization this is
+	if silences[0].CreatedBy != f.rule.OrgID || !strings.Contains(silences[0].Reason, "1001") {
+		t.Errorf("Expected the silence to be created by the chat's member from chat 1001, got %+v", silences[0])
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(1001, "/silence "+strconv.Itoa(f.rule.ID)+" 30d"))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "Usage") {
+		t.Errorf("Expected usage for unparseable duration, got %q", reply)
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(1001, "/ack "+strconv.Itoa(f.event.ID)))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "acknowledged") {
+		t.Errorf("Unexpected /ack reply: %q", reply)
+	}
+	events, _ := f.store.ListAlertEvents(ctx, 10)
+	if !events[0].Acknowledged {
+		t.Error("Expected event to be acknowledged")
+	}
+
+	f.bot.HandleUpdate(ctx, commandUpdate(1001, "/ack "+strconv.Itoa(f.event.ID)))
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "already acknowledged") {
+		t.Errorf("Expected already-acknowledged reply, got %q", reply)
+	}
+}
+
+func TestTelegramBot_AcknowledgeButton(t *testing.T) {
+	f := setupTelegramBot(t)
+	ctx := context.Background()
+
+	f.bot.HandleUpdate(ctx, TelegramUpdate{
+		UpdateID: 2,
+		CallbackQuery: &TelegramCallbackQuery{
+			ID:      "cb-1",
+			From:    TelegramUser{ID: 42},
+			Message: &TelegramMessage{MessageID: 77, Chat: TelegramChat{ID: 1001}},
+			Data:    "ack:" + strconv.Itoa(f.event.ID),
+		},
+	})
+
+	events, _ := f.store.ListAlertEvents(ctx, 10)
+	if !events[0].Acknowledged {
+		t.Error("Expected event to be acknowledged from the button")
+	}
+
+	answers := f.api.callsTo("answerCallbackQuery")
+	if len(answers) != 1 || !strings.Contains(answers[0].Payload["text"].(string), "acknowledged") {
+		t.Errorf("Expected callback to be answered, got %+v", answers)
+	}
+	if edits := f.api.callsTo("editMessageReplyMarkup"); len(edits) != 1 {
+		t.Errorf("Expected the button to be removed, got %d edits", len(edits))
+	}
+}
+
+func TestTelegramNotifier_AlertIncludesAckButton(t *testing.T) {
+	f := setupTelegramBot(t)
+
+	rule := *f.rule
+	if err := f.bot.notifier.Send(context.Background(), rule, *f.event); err != nil {
+		t.Fatalf("Failed to send alert: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(f.api.callsTo("sendMessage")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	calls := f.api.callsTo("sendMessage")
+	if len(calls) != 1 {
+		t.Fatalf("Expected one alert message, got %d", len(calls))
+	}
+	markup, _ := json.Marshal(calls[0].Payload["reply_markup"])
+	if !strings.Contains(string(markup), `"callback_data":"ack:`+strconv.Itoa(f.event.ID)+`"`) {
+		t.Errorf("Expected Acknowledge button in alert message, got %s", markup)
+	}
+}
+
+func TestHandleTelegramWebhook(t *testing.T) {
+	f := setupTelegramBot(t)
+	handler := HandleTelegramWebhook(f.bot, "hook-secret")
+
+	body, _ := json.Marshal(commandUpdate(1001, "/help"))
+
+	req := httptest.NewRequest(http.MethodPost, "/notifications/telegram/webhook", bytes.NewReader(body))
+	req.Header.Set(TelegramSecretTokenHeader, "wrong")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for wrong secret, got %d", w.Code)
+	}
+	if len(f.api.callsTo("sendMessage")) != 0 {
+		t.Error("Expected no reply for an unauthenticated update")
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/notifications/telegram/webhook", bytes.NewReader(body))
+	req.Header.Set(TelegramSecretTokenHeader, "hook-secret")
+	w = httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "/silence") {
+		t.Errorf("Expected help text, got %q", reply)
+	}
+}
+
+func TestTelegramBot_Polling(t *testing.T) {
+	f := setupTelegramBot(t)
+	f.api.mu.Lock()
+	f.api.updates = []TelegramUpdate{commandUpdate(1001, "/machines")}
+	f.api.mu.Unlock()
+
+	f.bot.Start(context.Background())
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(f.api.callsTo("sendMessage")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.bot.Stop()
+
+	if reply := f.api.lastReply(t); !strings.Contains(reply, "web-01") {
+		t.Errorf("Expected /machines reply from polled update, got %q", reply)
+	}
+
+	// The next poll must confirm the processed update
+	polls := f.api.callsTo("getUpdates")
+	if len(polls) < 2 || polls[1].Payload["offset"].(float64) != 2 {
+		t.Errorf("Expected second poll with offset 2, got %+v", polls)
+	}
+}
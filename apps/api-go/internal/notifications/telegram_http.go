@@ -2,13 +2,16 @@ package notifications
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
 )
@@ -17,12 +20,14 @@ import (
 type TelegramRecipientRequest struct {
 	ChatID   string `json:"chat_id"`
 	IsActive *bool  `json:"is_active,omitempty"`
+	// DeliverySettings replaces all delivery overrides when present; omitted fields revert to server defaults
+	DeliverySettings *storage.DeliverySettings `json:"delivery_settings,omitempty"`
 }
 
 // TelegramRecipientResponse represents the response body for Telegram recipient operations
 type TelegramRecipientResponse struct {
 	ID            int        `json:"id"`
-	UserID        int        `json:"user_id"`
+	OrgID         int        `json:"org_id"`
 	ChatID        string     `json:"chat_id"`
 	IsActive      bool       `json:"is_active"`
 	CreatedAt     time.Time  `json:"created_at"`
@@ -31,6 +36,8 @@ type TelegramRecipientResponse struct {
 	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
 	FailureCount  int        `json:"failure_count"`
 	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+	// DeliverySettings holds the recipient's overrides; null fields use the server defaults
+	DeliverySettings storage.DeliverySettings `json:"delivery_settings"`
 }
 
 // validateTelegramRecipientRequest validates Telegram recipient request data
@@ -44,14 +51,14 @@ func validateTelegramRecipientRequest(req *TelegramRecipientRequest) error {
 		return fmt.Errorf("chat_id must be a valid numeric string")
 	}
 
-	return nil
+	return validateDeliverySettings(req.DeliverySettings)
 }
 
 // telegramRecipientToResponse converts a storage.TelegramRecipient to TelegramRecipientResponse
 func telegramRecipientToResponse(recipient storage.TelegramRecipient) TelegramRecipientResponse {
 	return TelegramRecipientResponse{
 		ID:            recipient.ID,
-		UserID:        recipient.UserID,
+		OrgID:         recipient.OrgID,
 		ChatID:        recipient.ChatID,
 		IsActive:      recipient.IsActive,
 		CreatedAt:     recipient.CreatedAt,
@@ -60,6 +67,8 @@ func telegramRecipientToResponse(recipient storage.TelegramRecipient) TelegramRe
 		LastErrorAt:   recipient.LastErrorAt,
 		FailureCount:  recipient.FailureCount,
 		CooldownUntil: recipient.CooldownUntil,
+
+		DeliverySettings: recipient.DeliverySettings,
 	}
 }
 
@@ -73,7 +82,7 @@ func HandleListTelegramRecipients(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		user, ok := r.Context().Value(auth.UserContextKey).(*storage.User)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -81,7 +90,7 @@ func HandleListTelegramRecipients(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		recipients, err := store.ListTelegramRecipients(r.Context(), user.ID)
+		recipients, err := store.ListTelegramRecipients(r.Context(), org.OrgID)
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
@@ -109,7 +118,7 @@ func HandleCreateTelegramRecipient(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		user, ok := r.Context().Value(auth.UserContextKey).(*storage.User)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -132,7 +141,7 @@ func HandleCreateTelegramRecipient(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		recipient, err := store.CreateTelegramRecipient(r.Context(), user.ID, req.ChatID)
+		recipient, err := store.CreateTelegramRecipient(r.Context(), org.OrgID, req.ChatID, org.UserID)
 		if err != nil {
 			if strings.Contains(err.Error(), "already exists") {
 				w.Header().Set("Content-Type", "application/json")
@@ -146,6 +155,20 @@ func HandleCreateTelegramRecipient(store storage.Store) http.HandlerFunc {
 			return
 		}
 
+		// Apply delivery overrides if provided
+		if req.DeliverySettings != nil {
+			recipient, err = store.UpdateTelegramDeliverySettings(r.Context(), recipient.ID, org.OrgID, *req.DeliverySettings)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to update delivery settings: %v", err)})
+				return
+			}
+		}
+
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "telegram_recipient.create", TargetType: "telegram_recipient",
+			TargetID: recipient.ID, After: recipient})
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(telegramRecipientToResponse(*recipient))
@@ -162,7 +185,7 @@ func HandleUpdateTelegramRecipient(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		user, ok := r.Context().Value(auth.UserContextKey).(*storage.User)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -205,7 +228,17 @@ func HandleUpdateTelegramRecipient(store storage.Store) http.HandlerFunc {
 			}
 		}
 
-		recipient, err := store.UpdateTelegramRecipient(r.Context(), id, user.ID, req.ChatID, req.IsActive)
+		if err := validateDeliverySettings(req.DeliverySettings); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		// Kept for the audit log; when missing, the update below reports why
+		before, _ := store.GetTelegramRecipient(r.Context(), id, org.OrgID)
+
+		recipient, err := store.UpdateTelegramRecipient(r.Context(), id, org.OrgID, req.ChatID, req.IsActive)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "unauthorized") {
 				w.Header().Set("Content-Type", "application/json")
@@ -219,6 +252,19 @@ func HandleUpdateTelegramRecipient(store storage.Store) http.HandlerFunc {
 			return
 		}
 
+		// Replace delivery overrides if provided
+		if req.DeliverySettings != nil {
+			recipient, err = store.UpdateTelegramDeliverySettings(r.Context(), id, org.OrgID, *req.DeliverySettings)
+			if err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to update delivery settings: %v", err)})
+				return
+			}
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "telegram_recipient.update", TargetType: "telegram_recipient",
+			TargetID: id, Before: before, After: recipient})
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(telegramRecipientToResponse(*recipient))
 	}
@@ -234,7 +280,7 @@ func HandleDeleteTelegramRecipient(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		user, ok := r.Context().Value(auth.UserContextKey).(*storage.User)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -259,7 +305,10 @@ func HandleDeleteTelegramRecipient(store storage.Store) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DeleteTelegramRecipient(r.Context(), id, user.ID); err != nil {
+		// Kept for the audit log; when missing, the delete below reports why
+		before, _ := store.GetTelegramRecipient(r.Context(), id, org.OrgID)
+
+		if err := store.DeleteTelegramRecipient(r.Context(), id, org.OrgID); err != nil {
 			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "unauthorized") {
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusNotFound)
@@ -271,11 +320,69 @@ func HandleDeleteTelegramRecipient(store storage.Store) http.HandlerFunc {
 			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to delete telegram recipient: %v", err)})
 			return
 		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "telegram_recipient.delete", TargetType: "telegram_recipient",
+			TargetID: id, Before: before})
 
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// HandleResetTelegramCircuit handles POST /notifications/telegram/{id}/reset-circuit.
+// Clears the failure count, cooldown and rate limit window so deliveries resume immediately.
+func HandleResetTelegramCircuit(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+			return
+		}
+
+		// Extract ID from URL path (format: /notifications/telegram/{id}/reset-circuit)
+		pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(pathParts) < 4 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid URL path"})
+			return
+		}
+
+		id, err := strconv.Atoi(pathParts[2])
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid telegram recipient ID"})
+			return
+		}
+
+		recipient, err := store.ResetTelegramCircuit(r.Context(), id, org.OrgID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "unauthorized") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to reset telegram circuit: %v", err)})
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "telegram_recipient.reset_circuit", TargetType: "telegram_recipient", TargetID: id})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(telegramRecipientToResponse(*recipient))
+	}
+}
+
 // HandleTestTelegram handles POST /notifications/telegram/{id}/test
 func HandleTestTelegram(store storage.Store, telegramNotifier *TelegramNotifier) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -286,7 +393,7 @@ func HandleTestTelegram(store storage.Store, telegramNotifier *TelegramNotifier)
 			return
 		}
 
-		user, ok := r.Context().Value(auth.UserContextKey).(*storage.User)
+		org, ok := auth.GetOrgFromContext(r.Context())
 		if !ok {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -320,7 +427,7 @@ func HandleTestTelegram(store storage.Store, telegramNotifier *TelegramNotifier)
 		}
 
 		// Verify ownership
-		recipient, err := store.GetTelegramRecipient(r.Context(), id, user.ID)
+		recipient, err := store.GetTelegramRecipient(r.Context(), id, org.OrgID)
 		if err != nil {
 			if strings.Contains(err.Error(), "not found") {
 				w.Header().Set("Content-Type", "application/json")
@@ -339,6 +446,14 @@ func HandleTestTelegram(store storage.Store, telegramNotifier *TelegramNotifier)
 		defer cancel()
 
 		if err := telegramNotifier.SendTest(ctx, *recipient); err != nil {
+			var rateLimitErr *RateLimitError
+			if errors.As(err, &rateLimitErr) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(map[string]string{"error": rateLimitErr.Message})
+				return
+			}
+
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to send test message: %v", err)})
@@ -352,3 +467,42 @@ func HandleTestTelegram(store storage.Store, telegramNotifier *TelegramNotifier)
 		})
 	}
 }
+
+// TelegramSecretTokenHeader carries the secret token Telegram was given in setWebhook
+const TelegramSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// HandleTelegramWebhook handles POST /notifications/telegram/webhook, called by Telegram in bot webhook mode.
+// Requests must carry the configured secret token; updates are processed before responding.
+func HandleTelegramWebhook(bot *TelegramBot, secretToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		provided := r.Header.Get(TelegramSecretTokenHeader)
+		if secretToken == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(secretToken)) != 1 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid secret token"})
+			return
+		}
+
+		var update TelegramUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid JSON"})
+			return
+		}
+
+		// Detach from the request so a slow reply is not cut short when Telegram disconnects
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		bot.HandleUpdate(ctx, update)
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -51,7 +51,7 @@ func (m *mockTelegramStore) GetTelegramRecipient(ctx context.Context, id int, us
 	return nil, fmt.Errorf("telegram recipient with id %d not found for user %d", id, userID)
 }
 
-func (m *mockTelegramStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string) (*storage.TelegramRecipient, error) {
+func (m *mockTelegramStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string, createdBy int) (*storage.TelegramRecipient, error) {
 	if m.failCreate {
 		return nil, fmt.Errorf("mock create failure")
 	}
@@ -65,10 +65,11 @@ func (m *mockTelegramStore) CreateTelegramRecipient(ctx context.Context, userID
 
 	recipient := &storage.TelegramRecipient{
 		ID:        m.nextTelegramID,
-		UserID:    userID,
+		OrgID:     userID,
 		ChatID:    chatID,
 		IsActive:  true,
 		CreatedAt: time.Now(),
+		CreatedBy: &createdBy,
 	}
 	m.nextTelegramID++
 
@@ -166,22 +167,22 @@ func (m *mockTelegramStore) PromoteToAdmin(ctx context.Context, userID int) erro
 func (m *mockTelegramStore) DeletePasswordResetsForUser(ctx context.Context, userID int) error {
 	return fmt.Errorf("not implemented")
 }
-func (m *mockTelegramStore) CreateAlertRule(ctx context.Context, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
+func (m *mockTelegramStore) ListAlertRules(ctx context.Context, orgID int) ([]storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockTelegramStore) ListAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
+func (m *mockTelegramStore) ListAllAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockTelegramStore) GetAlertRule(ctx context.Context, id int) (*storage.AlertRule, error) {
+func (m *mockTelegramStore) SaveAlertRule(ctx context.Context, rule storage.AlertRule) (*storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockTelegramStore) UpdateAlertRule(ctx context.Context, id int, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
+func (m *mockTelegramStore) GetAlertRule(ctx context.Context, id int, orgID int) (*storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockTelegramStore) DeleteAlertRule(ctx context.Context, id int) error {
+func (m *mockTelegramStore) DeleteAlertRule(ctx context.Context, id int, orgID int) error {
 	return fmt.Errorf("not implemented")
 }
-func (m *mockTelegramStore) CreateAlertEvent(ctx context.Context, ruleID int, value float64) (*storage.AlertEvent, error) {
+func (m *mockTelegramStore) CreateAlertEvent(ctx context.Context, ruleID int, machineID *int, value float64) (*storage.AlertEvent, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 func (m *mockTelegramStore) ListAlertEvents(ctx context.Context, limit int) ([]storage.AlertEvent, error) {
@@ -190,6 +191,10 @@ func (m *mockTelegramStore) ListAlertEvents(ctx context.Context, limit int) ([]s
 func (m *mockTelegramStore) AckAlertEvent(ctx context.Context, id int) error {
 	return fmt.Errorf("not implemented")
 }
+
+func (m *mockTelegramStore) GetAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("alert event with id %d not found", id)
+}
 func (m *mockTelegramStore) ListActiveEvents(ctx context.Context, limit int) ([]storage.AlertEvent, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -304,6 +309,18 @@ func (m *mockTelegramStore) ClearMachineOfflineNotification(ctx context.Context,
 	return nil
 }
 
+func (m *mockTelegramStore) HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error {
+	return nil
+}
+
+func (m *mockTelegramStore) MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error) {
+	return false, nil
+}
+
+func (m *mockTelegramStore) ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error {
+	return nil
+}
+
 // Machine credential management methods (not implemented for these tests)
 func (m *mockTelegramStore) SetMachineEnabled(ctx context.Context, machineID int, enabled bool) error {
 	return fmt.Errorf("not implemented")
@@ -333,6 +350,394 @@ func (m *mockTelegramStore) ListMachineAPIKeys(ctx context.Context, machineID in
 	return nil, fmt.Errorf("not implemented")
 }
 
+// Alert resolution and notification template methods (stub implementations for testing)
+func (m *mockTelegramStore) ResolveAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListNotificationTemplates(ctx context.Context, userID int) ([]storage.NotificationTemplate, error) {
+	return []storage.NotificationTemplate{}, nil
+}
+
+func (m *mockTelegramStore) GetNotificationTemplate(ctx context.Context, userID int, channel, eventType string) (*storage.NotificationTemplate, error) {
+	return nil, storage.ErrNotificationTemplateNotFound
+}
+
+func (m *mockTelegramStore) UpsertNotificationTemplate(ctx context.Context, userID int, channel, eventType, body string) (*storage.NotificationTemplate, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) DeleteNotificationTemplate(ctx context.Context, id int, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Delivery settings methods (stub implementations for testing)
+func (m *mockTelegramStore) UpdateWebhookDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ResetWebhookCircuit(ctx context.Context, id int, userID int) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) UpdateTelegramDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ResetTelegramCircuit(ctx context.Context, id int, userID int) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) RotateWebhookSecret(ctx context.Context, id int, userID int, newSecretHash string, previousExpiresAt time.Time) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) CreateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListActiveSilences(ctx context.Context, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockTelegramStore) ListTelegramRecipientsByChatID(ctx context.Context, chatID string) ([]storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetNotificationPolicy(ctx context.Context, userID int) (*storage.NotificationPolicy, error) {
+	return nil, storage.ErrNotificationPolicyNotFound
+}
+
+func (m *mockTelegramStore) UpsertNotificationPolicy(ctx context.Context, policy storage.NotificationPolicy) (*storage.NotificationPolicy, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) AddPendingDigestEntry(ctx context.Context, entry storage.PendingDigestEntry) (*storage.PendingDigestEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListPendingDigestEntries(ctx context.Context) ([]storage.PendingDigestEntry, error) {
+	return nil, nil
+}
+
+func (m *mockTelegramStore) DeletePendingDigestEntries(ctx context.Context, orgID, maxID int) error {
+	return nil
+}
+
+func (m *mockTelegramStore) ListNotificationRoutes(ctx context.Context, userID int) ([]storage.NotificationRoute, error) {
+	return []storage.NotificationRoute{}, nil
+}
+
+func (m *mockTelegramStore) GetNotificationRoute(ctx context.Context, id int, userID int) (*storage.NotificationRoute, error) {
+	return nil, fmt.Errorf("notification route with id %d not found for user %d", id, userID)
+}
+
+func (m *mockTelegramStore) CreateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockTelegramStore) UpdateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockTelegramStore) DeleteNotificationRoute(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockTelegramStore) GetMachineTags(ctx context.Context, machineID int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *mockTelegramStore) SetMachineTags(ctx context.Context, machineID int, tags []string) error {
+	return nil
+}
+
+func (m *mockTelegramStore) ListEscalationPolicies(ctx context.Context, userID int) ([]storage.EscalationPolicy, error) {
+	return []storage.EscalationPolicy{}, nil
+}
+
+func (m *mockTelegramStore) GetEscalationPolicy(ctx context.Context, id int, userID int) (*storage.EscalationPolicy, error) {
+	return nil, fmt.Errorf("escalation policy with id %d not found for user %d", id, userID)
+}
+
+func (m *mockTelegramStore) CreateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockTelegramStore) UpdateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockTelegramStore) DeleteEscalationPolicy(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockTelegramStore) ListPendingEscalations(ctx context.Context) ([]storage.PendingEscalation, error) {
+	return []storage.PendingEscalation{}, nil
+}
+
+func (m *mockTelegramStore) RecordAlertEscalation(ctx context.Context, eventID int, step int, escalatedAt time.Time) error {
+	return nil
+}
+
+func (m *mockTelegramStore) GetSilence(ctx context.Context, id int, orgID int) (*storage.Silence, error) {
+	return nil, fmt.Errorf("silence with id %d not found", id)
+}
+
+func (m *mockTelegramStore) ListSilences(ctx context.Context, orgID int, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockTelegramStore) UpdateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return &silence, nil
+}
+
+func (m *mockTelegramStore) DeleteSilence(ctx context.Context, id int, orgID int) error {
+	return nil
+}
+
+func (m *mockTelegramStore) SetAlertEventExpectedRange(ctx context.Context, id int, low, high float64) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetMetricBaseline(ctx context.Context, machineID int, metric, seasonality string, slot int) (*storage.MetricBaseline, error) {
+	return nil, storage.ErrMetricBaselineNotFound
+}
+
+func (m *mockTelegramStore) UpsertMetricBaseline(ctx context.Context, baseline storage.MetricBaseline) error {
+	return nil
+}
+
+func (m *mockTelegramStore) ListOpenAlertEvents(ctx context.Context) ([]storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListAlertRuleStates(ctx context.Context) ([]storage.AlertRuleState, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) UpsertAlertRuleState(ctx context.Context, state storage.AlertRuleState) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) DeleteAlertRuleStates(ctx context.Context, ruleID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) QueryAlertEvents(ctx context.Context, filter storage.AlertEventFilter) ([]storage.AlertEvent, *storage.AlertEventCursor, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) CreateSession(ctx context.Context, session storage.Session) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetSession(ctx context.Context, id int) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetSessionByRefreshHash(ctx context.Context, tokenHash string) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) RotateSessionRefreshToken(ctx context.Context, id int, oldHash, newHash string, expiresAt time.Time, ipAddress string) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) TouchSession(ctx context.Context, id int, ipAddress string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListSessions(ctx context.Context, userID int) ([]storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) RevokeSession(ctx context.Context, id, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) RevokeUserSessions(ctx context.Context, userID, exceptID int) (int, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) CreateAPIToken(ctx context.Context, token storage.APIToken) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetAPITokenByHash(ctx context.Context, tokenHash string) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListAPITokens(ctx context.Context, userID int) ([]storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) RevokeAPIToken(ctx context.Context, id, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) TouchAPIToken(ctx context.Context, id int, ipAddress string) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Two-factor authentication methods (stub implementations for testing)
+func (m *mockTelegramStore) GetUserTOTP(ctx context.Context, userID int) (*storage.UserTOTP, error) {
+	return nil, storage.ErrTOTPNotFound
+}
+
+func (m *mockTelegramStore) SetUserTOTPSecret(ctx context.Context, userID int, secret string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) EnableUserTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) DeleteUserTOTP(ctx context.Context, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockTelegramStore) GetSetting(ctx context.Context, key string) (string, error) {
+	return "", storage.ErrSettingNotFound
+}
+
+func (m *mockTelegramStore) SetSetting(ctx context.Context, key, value string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*storage.User, error) {
+	return nil, storage.ErrUserNotFound
+}
+
+func (m *mockTelegramStore) LinkUserIdentity(ctx context.Context, identity storage.UserIdentity) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) CreateOIDCLogin(ctx context.Context, login storage.OIDCLogin) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*storage.OIDCLogin, error) {
+	return nil, storage.ErrOIDCLoginNotFound
+}
+
+func (m *mockTelegramStore) GetLoginAttempts(ctx context.Context, key string) (*storage.LoginAttempts, error) {
+	return nil, storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockTelegramStore) RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ClearLoginAttempts(ctx context.Context, key string) error {
+	return storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockTelegramStore) ListLoginLockouts(ctx context.Context) ([]storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) CreateAuditEntry(ctx context.Context, entry storage.AuditEntry) (*storage.AuditEntry, error) {
+	return &entry, nil
+}
+
+func (m *mockTelegramStore) ListAuditEntries(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) SetUserRole(ctx context.Context, userID int, role string) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Organization methods (stub implementations for testing)
+func (m *mockTelegramStore) CreateOrganization(ctx context.Context, name string, ownerID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetOrganization(ctx context.Context, id int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetPersonalOrganization(ctx context.Context, userID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) RenameOrganization(ctx context.Context, id int, name string) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) DeleteOrganization(ctx context.Context, id int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListOrgMembers(ctx context.Context, orgID int) ([]storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) AddOrgMember(ctx context.Context, orgID, userID int, role string) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) SetOrgMemberRole(ctx context.Context, orgID, userID int, role string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) RemoveOrgMember(ctx context.Context, orgID, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) CreateOrgInvitation(ctx context.Context, invitation storage.OrgInvitation) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetOrgInvitationByHash(ctx context.Context, tokenHash string) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListOrgInvitations(ctx context.Context, orgID int) ([]storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) DeleteOrgInvitation(ctx context.Context, id, orgID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) AcceptOrgInvitation(ctx context.Context, id, userID int) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListOrganizations(ctx context.Context) ([]storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) ListUserOrganizations(ctx context.Context, userID int) ([]storage.UserOrganization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockTelegramStore) GetOrgMember(ctx context.Context, orgID, userID int) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockTelegramStore) Close() error {
 	return nil
 }
@@ -351,7 +756,8 @@ func createTelegramAuthRequest(method, url string, body []byte, userID int) *htt
 		ID:    userID,
 		Email: fmt.Sprintf("user%d@example.com", userID),
 	}
-	ctx := context.WithValue(req.Context(), auth.UserContextKey, user)
+	ctx := context.WithValue(context.WithValue(req.Context(), auth.UserContextKey, user), auth.OrgContextKey,
+		&storage.OrgMember{OrgID: user.ID, UserID: user.ID, Role: storage.RoleOwner})
 	return req.WithContext(ctx)
 }
 
@@ -370,8 +776,8 @@ func TestHandleListTelegramRecipients(t *testing.T) {
 			method: http.MethodGet,
 			userID: 1,
 			setupStore: func(m *mockTelegramStore) {
-				m.CreateTelegramRecipient(context.Background(), 1, "123456789")
-				m.CreateTelegramRecipient(context.Background(), 1, "987654321")
+				m.CreateTelegramRecipient(context.Background(), 1, "123456789", 1)
+				m.CreateTelegramRecipient(context.Background(), 1, "987654321", 1)
 			},
 			expectedStatus: http.StatusOK,
 			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
@@ -538,7 +944,7 @@ func TestHandleCreateTelegramRecipient(t *testing.T) {
 				ChatID: "123456789",
 			},
 			setupStore: func(m *mockTelegramStore) {
-				m.CreateTelegramRecipient(context.Background(), 1, "123456789")
+				m.CreateTelegramRecipient(context.Background(), 1, "123456789", 1)
 			},
 			expectedStatus: http.StatusConflict,
 			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
@@ -618,7 +1024,7 @@ func TestHandleTestTelegram(t *testing.T) {
 			userID:      1,
 			recipientID: "1",
 			setupStore: func(m *mockTelegramStore) {
-				m.CreateTelegramRecipient(context.Background(), 1, "123456789")
+				m.CreateTelegramRecipient(context.Background(), 1, "123456789", 1)
 			},
 			notifierNil:    true,
 			expectedStatus: http.StatusServiceUnavailable,
@@ -723,7 +1129,7 @@ func TestHandleUpdateTelegramRecipient(t *testing.T) {
 				IsActive: boolPtr(false),
 			},
 			setupStore: func(m *mockTelegramStore) {
-				m.CreateTelegramRecipient(context.Background(), 1, "123456789")
+				m.CreateTelegramRecipient(context.Background(), 1, "123456789", 1)
 			},
 			expectedStatus: http.StatusOK,
 			checkResponse: func(t *testing.T, rec *httptest.ResponseRecorder) {
@@ -819,7 +1225,7 @@ func TestHandleDeleteTelegramRecipient(t *testing.T) {
 			userID:      1,
 			recipientID: "1",
 			setupStore: func(m *mockTelegramStore) {
-				m.CreateTelegramRecipient(context.Background(), 1, "123456789")
+				m.CreateTelegramRecipient(context.Background(), 1, "123456789", 1)
 			},
 			expectedStatus: http.StatusNoContent,
 			checkResponse:  nil,
@@ -0,0 +1,205 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/auth"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// TemplateRequest represents the request body for saving or previewing a notification template
+type TemplateRequest struct {
+	Channel   string `json:"channel"`
+	EventType string `json:"event_type"`
+	Body      string `json:"body"`
+}
+
+// TemplatePreviewResponse represents the response body for a template preview
+type TemplatePreviewResponse struct {
+	Rendered string       `json:"rendered"`
+	Data     TemplateData `json:"data"`
+}
+
+// HandleListTemplates handles GET /notifications/templates
+func HandleListTemplates(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+			return
+		}
+
+		templates, err := store.ListNotificationTemplates(r.Context(), org.OrgID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to list templates: %v", err)})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(templates)
+	}
+}
+
+// HandleSaveTemplate handles POST /notifications/templates.
+// Creates the template for the channel and event type, or replaces the existing one.
+func HandleSaveTemplate(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+			return
+		}
+
+		var req TemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid request body: %v", err)})
+			return
+		}
+
+		if err := ValidateTemplate(req.Channel, req.EventType, req.Body); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		// Kept for the audit log; missing when the template is new
+		before, _ := store.GetNotificationTemplate(r.Context(), org.OrgID, req.Channel, req.EventType)
+
+		tmpl, err := store.UpsertNotificationTemplate(r.Context(), org.OrgID, req.Channel, req.EventType, req.Body)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to save template: %v", err)})
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "notification_template.save", TargetType: "notification_template",
+			TargetID: tmpl.ID, Before: before, After: tmpl})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tmpl)
+	}
+}
+
+// HandleDeleteTemplate handles DELETE /notifications/templates/{id}
+func HandleDeleteTemplate(store storage.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		org, ok := auth.GetOrgFromContext(r.Context())
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+			return
+		}
+
+		// Extract ID from URL path
+		pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(pathParts) < 3 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid URL path"})
+			return
+		}
+
+		id, err := strconv.Atoi(pathParts[2])
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid template ID"})
+			return
+		}
+
+		if err := store.DeleteNotificationTemplate(r.Context(), id, org.OrgID); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Failed to delete template: %v", err)})
+			return
+		}
+		audit.Record(r.Context(), store, audit.Event{OrgID: org.OrgID, Action: "notification_template.delete", TargetType: "notification_template", TargetID: id})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandlePreviewTemplate handles POST /notifications/templates/preview.
+// Renders the submitted template against sample data for the event type without saving it.
+func HandlePreviewTemplate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
+			return
+		}
+
+		var req TemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Invalid request body: %v", err)})
+			return
+		}
+
+		if err := ValidateTemplate(req.Channel, req.EventType, req.Body); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		data := SampleTemplateData(req.EventType)
+		rendered, err := RenderTemplate(req.Channel, req.Body, data)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(TemplatePreviewResponse{
+			Rendered: rendered,
+			Data:     data,
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+// Notification channels that support user-defined templates
+const (
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+)
+
+// Event types that can be rendered through templates
+const (
+	EventAlertFired     = "alert.fired"
+	EventAlertResolved  = "alert.resolved"
+	EventMachineOffline = "machine.offline"
+	EventMachineOnline  = "machine.online"
+)
+
+const (
+	// MaxTemplateSize is the maximum size of a template body in bytes
+	MaxTemplateSize = 8 * 1024
+	// MaxTelegramMessageLength is the maximum length of a Telegram message in characters
+	MaxTelegramMessageLength = 4096
+)
+
+// TemplateData is the value templates are executed against.
+//
+// Available fields:
+//
+//	.Event       event type ("alert.fired", "alert.resolved", "machine.offline", "machine.online")
+//	.Timestamp   time the event happened (time.Time)
+//	.Rule        alert rule, set for alert.* events (nil otherwise)
+//	.Alert       alert event, set for alert.* events (nil otherwise)
+//	.Machine     machine, set for machine.* events (nil otherwise)
+type TemplateData struct {
+	Event     string           `json:"event"`
+	Timestamp time.Time        `json:"timestamp"`
+	Rule      *TemplateRule    `json:"rule,omitempty"`
+	Alert     *TemplateAlert   `json:"alert,omitempty"`
+	Machine   *TemplateMachine `json:"machine,omitempty"`
+}
+
+// TemplateRule exposes alert rule fields to templates
+type TemplateRule struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	Expression   string  `json:"expression,omitempty"`
+	Metric       string  `json:"metric"`
+	Comparison   string  `json:"comparison"`
+	ThresholdPct float64 `json:"threshold_pct"`
+	TriggerAfter int     `json:"trigger_after"`
+}
+
+// TemplateAlert exposes alert event fields to templates
+type TemplateAlert struct {
+	EventID      int        `json:"event_id"`
+	Severity     string     `json:"severity"`
+	Value        float64    `json:"value"`
+	ExpectedLow  *float64   `json:"expected_low,omitempty"` // range an anomaly rule expected the value in
+	ExpectedHigh *float64   `json:"expected_high,omitempty"`
+	TriggeredAt  time.Time  `json:"triggered_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	Acknowledged bool       `json:"acknowledged"`
+}
+
+// TemplateMachine exposes machine fields to templates
+type TemplateMachine struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Hostname    string    `json:"hostname"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// templateFuncs are the helper functions available to templates in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, useful for embedding strings safely in webhook bodies
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// formatTime formats a time with a Go layout string, e.g. {{formatTime "2006-01-02 15:04" .Timestamp}}
+	"formatTime": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+}
+
+// IsValidTemplateChannel reports whether templates can be configured for the channel
+func IsValidTemplateChannel(channel string) bool {
+	return channel == ChannelWebhook || channel == ChannelTelegram
+}
+
+// IsValidTemplateEvent reports whether templates can be configured for the event type
+func IsValidTemplateEvent(eventType string) bool {
+	switch eventType {
+	case EventAlertFired, EventAlertResolved, EventMachineOffline, EventMachineOnline:
+		return true
+	}
+	return false
+}
+
+// ValidateTemplate checks that a template parses and renders a valid message for the
+// channel when executed against sample data for the event type
+func ValidateTemplate(channel, eventType, body string) error {
+	if !IsValidTemplateChannel(channel) {
+		return fmt.Errorf("channel must be one of: %s, %s", ChannelWebhook, ChannelTelegram)
+	}
+	if !IsValidTemplateEvent(eventType) {
+		return fmt.Errorf("event_type must be one of: %s, %s, %s, %s",
+			EventAlertFired, EventAlertResolved, EventMachineOffline, EventMachineOnline)
+	}
+	if strings.TrimSpace(body) == "" {
+		return errors.New("body is required")
+	}
+	if len(body) > MaxTemplateSize {
+		return fmt.Errorf("body must be at most %d bytes", MaxTemplateSize)
+	}
+
+	_, err := RenderTemplate(channel, body, SampleTemplateData(eventType))
+	return err
+}
+
+// RenderTemplate executes a template body against data and checks that the output is
+// deliverable on the channel: valid JSON for webhooks, a non-empty message within
+// Telegram's length limit for Telegram
+func RenderTemplate(channel, body string, data TemplateData) (string, error) {
+	tmpl, err := template.New(channel).Option("missingkey=error").Funcs(templateFuncs).Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	output := buf.String()
+	switch channel {
+	case ChannelWebhook:
+		if !json.Valid(buf.Bytes()) {
+			return "", errors.New("webhook template must render valid JSON")
+		}
+	case ChannelTelegram:
+		if strings.TrimSpace(output) == "" {
+			return "", errors.New("telegram template rendered an empty message")
+		}
+		if utf8.RuneCountInString(output) > MaxTelegramMessageLength {
+			return "", fmt.Errorf("telegram template rendered a message longer than %d characters", MaxTelegramMessageLength)
+		}
+	}
+
+	return output, nil
+}
+
+// SampleTemplateData returns representative data for an event type, used to validate and preview templates
+func SampleTemplateData(eventType string) TemplateData {
+	now := time.Now().UTC().Truncate(time.Second)
+	data := TemplateData{
+		Event:     eventType,
+		Timestamp: now,
+	}
+
+	switch eventType {
+	case EventAlertFired, EventAlertResolved:
+		data.Rule = &TemplateRule{
+			ID:           1,
+			Name:         "High CPU",
+			Type:         storage.RuleTypeThreshold,
+			Metric:       "cpu_pct",
+			Comparison:   "above",
+			ThresholdPct: 80.0,
+			TriggerAfter: 3,
+		}
+		data.Alert = &TemplateAlert{
+			EventID:     42,
+			Severity:    storage.SeverityCritical,
+			Value:       92.5,
+			TriggeredAt: now.Add(-5 * time.Minute),
+		}
+		if eventType == EventAlertResolved {
+			data.Alert.ResolvedAt = &now
+		}
+	case EventMachineOffline, EventMachineOnline:
+		status := "offline"
+		if eventType == EventMachineOnline {
+			status = "online"
+		}
+		data.Machine = &TemplateMachine{
+			ID:          7,
+			Name:        "web-01",
+			Hostname:    "web-01.example.com",
+			Description: "Production web server",
+			Status:      status,
+			LastSeen:    now.Add(-3 * time.Minute),
+		}
+	}
+
+	return data
+}
+
+// newAlertTemplateData builds template data for an alert event
+func newAlertTemplateData(eventType string, rule storage.AlertRule, event storage.AlertEvent) TemplateData {
+	timestamp := event.TriggeredAt
+	if eventType == EventAlertResolved && event.ResolvedAt != nil {
+		timestamp = *event.ResolvedAt
+	}
+
+	return TemplateData{
+		Event:     eventType,
+		Timestamp: timestamp,
+		Rule: &TemplateRule{
+			ID:           rule.ID,
+			Name:         rule.Name,
+			Type:         ruleType(rule),
+			Expression:   rule.Expression,
+			Metric:       rule.Metric,
+			Comparison:   rule.Comparison,
+			ThresholdPct: rule.ThresholdPct,
+			TriggerAfter: rule.TriggerAfter,
+		},
+		Alert: &TemplateAlert{
+			EventID:      event.ID,
+			Severity:     eventSeverity(rule, event),
+			Value:        event.Value,
+			ExpectedLow:  event.ExpectedLow,
+			ExpectedHigh: event.ExpectedHigh,
+			TriggeredAt:  event.TriggeredAt,
+			ResolvedAt:   event.ResolvedAt,
+			Acknowledged: event.Acknowledged,
+		},
+	}
+}
+
+// newMachineTemplateData builds template data for a machine status event
+func newMachineTemplateData(event WebhookMachineEvent) TemplateData {
+	return TemplateData{
+		Event:     event.Event,
+		Timestamp: time.Now(),
+		Machine: &TemplateMachine{
+			ID:          event.Machine.ID,
+			Name:        event.Machine.Name,
+			Hostname:    event.Machine.Hostname,
+			Description: event.Machine.Description,
+			Status:      event.Machine.Status,
+			LastSeen:    event.Machine.LastSeen,
+		},
+	}
+}
+
+// loadOrgTemplate returns the template body an organization configured for a channel and event type,
+// or an empty string when none is configured
+func loadOrgTemplate(ctx context.Context, store storage.Store, orgID int, channel, eventType string) (string, error) {
+	tmpl, err := store.GetNotificationTemplate(ctx, orgID, channel, eventType)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotificationTemplateNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return tmpl.Body, nil
+}
@@ -0,0 +1,264 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		channel   string
+		eventType string
+		body      string
+		wantErr   string
+	}{
+		{
+			name:      "valid telegram alert template",
+			channel:   ChannelTelegram,
+			eventType: EventAlertFired,
+			body:      "{{.Rule.Name}} is {{.Alert.Value}}",
+		},
+		{
+			name:      "valid webhook machine template",
+			channel:   ChannelWebhook,
+			eventType: EventMachineOffline,
+			body:      `{"text": {{json .Machine.Name}}, "status": {{json .Machine.Status}}}`,
+		},
+		{
+			name:      "invalid channel",
+			channel:   "sms",
+			eventType: EventAlertFired,
+			body:      "hello",
+			wantErr:   "channel must be one of",
+		},
+		{
+			name:      "invalid event type",
+			channel:   ChannelTelegram,
+			eventType: "alert.exploded",
+			body:      "hello",
+			wantErr:   "event_type must be one of",
+		},
+		{
+			name:      "empty body",
+			channel:   ChannelTelegram,
+			eventType: EventAlertFired,
+			body:      "   ",
+			wantErr:   "body is required",
+		},
+		{
+			name:      "parse error",
+			channel:   ChannelTelegram,
+			eventType: EventAlertFired,
+			body:      "{{.Rule.Name",
+			wantErr:   "invalid template",
+		},
+		{
+			name:      "unknown field",
+			channel:   ChannelTelegram,
+			eventType: EventAlertFired,
+			body:      "{{.Rule.Owner}}",
+			wantErr:   "failed to render template",
+		},
+		{
+			name:      "machine field on alert event",
+			channel:   ChannelTelegram,
+			eventType: EventAlertFired,
+			body:      "{{.Machine.Name}}",
+			wantErr:   "failed to render template",
+		},
+		{
+			name:      "webhook template renders invalid JSON",
+			channel:   ChannelWebhook,
+			eventType: EventAlertFired,
+			body:      `{"text": "{{.Rule.Name}}"`,
+			wantErr:   "valid JSON",
+		},
+		{
+			name:      "telegram template renders empty message",
+			channel:   ChannelTelegram,
+			eventType: EventAlertFired,
+			body:      "{{if false}}never{{end}}",
+			wantErr:   "empty message",
+		},
+		{
+			name:      "body too large",
+			channel:   ChannelTelegram,
+			eventType: EventAlertFired,
+			body:      strings.Repeat("a", MaxTemplateSize+1),
+			wantErr:   "at most",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplate(tt.channel, tt.eventType, tt.body)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got nil", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestRenderTemplate_Helpers(t *testing.T) {
+	data := SampleTemplateData(EventAlertResolved)
+	data.Rule.Name = `Disk "root" full`
+
+	body := `{"rule": {{json .Rule.Name}}, "metric": "{{upper .Rule.Metric}}", "at": "{{formatTime "2006-01-02" .Timestamp}}", "resolved": {{if .Alert.ResolvedAt}}true{{else}}false{{end}}}`
+	rendered, err := RenderTemplate(ChannelWebhook, body, data)
+	if err != nil {
+		t.Fatalf("Failed to render template: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(rendered), &decoded); err != nil {
+		t.Fatalf("Rendered template is not valid JSON: %v", err)
+	}
+	if decoded["rule"] != `Disk "root" full` {
+		t.Errorf("Expected escaped rule name, got %v", decoded["rule"])
+	}
+	if decoded["metric"] != "CPU_PCT" {
+		t.Errorf("Expected upper-cased metric, got %v", decoded["metric"])
+	}
+	if decoded["at"] != data.Timestamp.Format("2006-01-02") {
+		t.Errorf("Expected formatted timestamp, got %v", decoded["at"])
+	}
+	if decoded["resolved"] != true {
+		t.Errorf("Expected resolved to be true, got %v", decoded["resolved"])
+	}
+}
+
+func TestNotifier_Send_UsesUserTemplate(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := newMockStore()
+	store.users = []storage.User{{ID: 1, Email: "test@example.com"}}
+	store.webhooks[1] = []storage.Webhook{
+		{ID: 1, OrgID: 1, URL: server.URL, SecretHash: storage.HashSecret("test-secret"), IsActive: true},
+	}
+	store.templates["1/webhook/alert.fired"] = `{"text": {{json (printf "%s fired at %.1f" .Rule.Name .Alert.Value)}}}`
+
+	notifier := NewNotifier(store, log.New(io.Discard, "", 0))
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1}
+	event := storage.AlertEvent{ID: 5, RuleID: 1, Value: 91.25, TriggeredAt: time.Now()}
+
+	if err := notifier.Send(context.Background(), rule, event); err != nil {
+		t.Fatalf("Failed to send notification: %v", err)
+	}
+
+	select {
+	case body := <-received:
+		expected := `{"text": "High CPU fired at 91.2"}`
+		if !bytes.Equal(body, []byte(expected)) {
+			t.Errorf("Expected body %s, got %s", expected, body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for webhook delivery")
+	}
+}
+
+func TestNotifier_Send_FallsBackOnBrokenTemplate(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := newMockStore()
+	store.users = []storage.User{{ID: 1, Email: "test@example.com"}}
+	store.webhooks[1] = []storage.Webhook{
+		{ID: 1, OrgID: 1, URL: server.URL, SecretHash: storage.HashSecret("test-secret"), IsActive: true},
+	}
+	// Renders invalid JSON, so the default payload must be sent instead
+	store.templates["1/webhook/alert.fired"] = `not json {{.Rule.Name}}`
+
+	notifier := NewNotifier(store, log.New(io.Discard, "", 0))
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1}
+	event := storage.AlertEvent{ID: 5, RuleID: 1, Value: 91.25, TriggeredAt: time.Now()}
+
+	if err := notifier.Send(context.Background(), rule, event); err != nil {
+		t.Fatalf("Failed to send notification: %v", err)
+	}
+
+	select {
+	case body := <-received:
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("Expected default JSON payload, got %s", body)
+		}
+		if payload.Event != EventAlertFired || payload.EventID != 5 {
+			t.Errorf("Unexpected default payload: %+v", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for webhook delivery")
+	}
+}
+
+func TestHandlePreviewTemplate(t *testing.T) {
+	handler := HandlePreviewTemplate()
+
+	reqBody, _ := json.Marshal(TemplateRequest{
+		Channel:   ChannelTelegram,
+		EventType: EventMachineOnline,
+		Body:      "{{.Machine.Name}} is {{.Machine.Status}}",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/notifications/templates/preview", bytes.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp TemplatePreviewResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Rendered != "web-01 is online" {
+		t.Errorf("Expected rendered preview 'web-01 is online', got %q", resp.Rendered)
+	}
+	if resp.Data.Machine == nil || resp.Data.Event != EventMachineOnline {
+		t.Errorf("Expected sample machine data in response, got %+v", resp.Data)
+	}
+
+	// Invalid template is rejected
+	reqBody, _ = json.Marshal(TemplateRequest{
+		Channel:   ChannelWebhook,
+		EventType: EventMachineOnline,
+		Body:      "{{.Machine.Name}}",
+	})
+	req = httptest.NewRequest(http.MethodPost, "/notifications/templates/preview", bytes.NewReader(reqBody))
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid webhook template, got %d", rec.Code)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -14,27 +15,41 @@ import (
 	"time"
 
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
-)
-
-const (
-	// TODO: Make these configurable later
-	MinAttemptInterval = 30 * time.Second // Minimum interval between delivery attempts
-	FailureThreshold   = 3                // Number of failures within window to trigger cooldown
-	FailureWindow      = 10 * time.Minute // Time window for counting failures
-	CooldownDuration   = 15 * time.Minute // Duration of cooldown after reaching failure threshold
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/pkg/webhookverify"
 )
 
 // WebhookPayload represents the JSON payload sent to webhooks
 type WebhookPayload struct {
-	RuleID       int     `json:"rule_id"`
-	RuleName     string  `json:"rule_name"`
-	Metric       string  `json:"metric"`
-	Comparison   string  `json:"comparison"`
-	ThresholdPct float64 `json:"threshold_pct"`
-	TriggerAfter int     `json:"trigger_after"`
-	Value        float64 `json:"value"`
-	TriggeredAt  string  `json:"triggered_at"`
-	EventID      int     `json:"event_id"`
+	Event        string   `json:"event"` // "alert.fired", "alert.resolved" or "alert.escalated"
+	RuleID       int      `json:"rule_id"`
+	RuleName     string   `json:"rule_name"`
+	RuleType     string   `json:"rule_type"`            // "threshold", "rate" (value in %/hour), "forecast" (value in hours until threshold_pct), "anomaly" or "expression"
+	Expression   string   `json:"expression,omitempty"` // condition of expression rules
+	Metric       string   `json:"metric"`
+	Comparison   string   `json:"comparison"`
+	ThresholdPct float64  `json:"threshold_pct"`
+	TriggerAfter int      `json:"trigger_after"`
+	Severity     string   `json:"severity"` // "info", "warning" or "critical"
+	Value        float64  `json:"value"`
+	ExpectedLow  *float64 `json:"expected_low,omitempty"` // range an anomaly rule expected the value in
+	ExpectedHigh *float64 `json:"expected_high,omitempty"`
+	TriggeredAt  string   `json:"triggered_at"`
+	EventID      int      `json:"event_id"`
+	MachineID    *int     `json:"machine_id,omitempty"`
+	ResolvedAt   string   `json:"resolved_at,omitempty"`
+
+	EscalationPolicy string `json:"escalation_policy,omitempty"` // set for alert.escalated
+	EscalationStep   int    `json:"escalation_step,omitempty"`   // 1-based step, set for alert.escalated
+}
+
+// WebhookDigestPayload batches several alert events into one webhook delivery
+type WebhookDigestPayload struct {
+	Event       string           `json:"event"`  // "alert.digest"
+	Reason      string           `json:"reason"` // "digest" or "quiet_hours"
+	WindowStart string           `json:"window_start"`
+	WindowEnd   string           `json:"window_end"`
+	Count       int              `json:"count"`
+	Alerts      []WebhookPayload `json:"alerts"`
 }
 
 // WebhookMachineEvent represents a machine status event payload for webhooks
@@ -55,9 +70,10 @@ type WebhookMachine struct {
 
 // Notifier handles webhook notifications for alert events
 type Notifier struct {
-	store  storage.Store
-	client *http.Client
-	logger *log.Logger
+	store    storage.Store
+	client   *http.Client
+	logger   *log.Logger
+	defaults DeliveryPolicy
 }
 
 // NewNotifier creates a new webhook notifier
@@ -67,39 +83,151 @@ func NewNotifier(store storage.Store, logger *log.Logger) *Notifier {
 	}
 
 	return &Notifier{
-		store:  store,
-		client: client,
-		logger: logger,
+		store:    store,
+		client:   client,
+		logger:   logger,
+		defaults: DefaultDeliveryPolicy(),
 	}
 }
 
+// SetDeliveryDefaults sets the server-wide delivery policy for webhooks without their own overrides
+func (n *Notifier) SetDeliveryDefaults(policy DeliveryPolicy) {
+	n.defaults = policy
+}
+
+// deliveryPolicy returns the effective delivery policy for a webhook
+func (n *Notifier) deliveryPolicy(webhook storage.Webhook) DeliveryPolicy {
+	return n.defaults.WithOverrides(webhook.DeliverySettings)
+}
+
 // Send sends webhook notifications for an alert event to all active webhooks
 func (n *Notifier) Send(ctx context.Context, rule storage.AlertRule, event storage.AlertEvent) error {
-	// For now, fetch all active webhooks since we don't have multi-tenant yet
-	// TODO: Once multi-tenant lands, filter by rule owner
-	webhooks, err := n.getAllActiveWebhooks(ctx)
+	return n.sendAlert(ctx, EventAlertFired, rule, event)
+}
+
+// SendResolved sends webhook notifications for a resolved alert event to all active webhooks
+func (n *Notifier) SendResolved(ctx context.Context, rule storage.AlertRule, event storage.AlertEvent) error {
+	return n.sendAlert(ctx, EventAlertResolved, rule, event)
+}
+
+// sendAlert delivers an alert.* event to all active webhooks
+func (n *Notifier) sendAlert(ctx context.Context, eventType string, rule storage.AlertRule, event storage.AlertEvent) error {
+	webhooks, err := n.getActiveWebhooksForEvent(ctx, rule, event)
 	if err != nil {
 		return fmt.Errorf("failed to fetch active webhooks: %w", err)
 	}
 
+	n.deliverAlert(webhooks, eventType, rule, event)
+	return nil
+}
+
+// NotifyOrg delivers an alert.* event to the organization's active webhooks selected by its notification routes
+func (n *Notifier) NotifyOrg(ctx context.Context, orgID int, eventType string, rule storage.AlertRule, event storage.AlertEvent) error {
+	webhooks, err := n.getActiveWebhooksForOrg(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch active webhooks for organization %d: %w", orgID, err)
+	}
+
+	targets := resolveRoutes(ctx, n.store, n.logger, orgID, rule, event)
+	var routed []storage.Webhook
+	for _, webhook := range webhooks {
+		if targets.allowsWebhook(webhook.ID) {
+			routed = append(routed, webhook)
+		}
+	}
+
+	n.deliverAlert(routed, eventType, rule, event)
+	return nil
+}
+
+// NotifyOrgDigest delivers a batch of alert events to an organization's active webhooks as one payload per webhook.
+// Each webhook only receives the entries its notification routes select.
+func (n *Notifier) NotifyOrgDigest(ctx context.Context, orgID int, digest AlertDigest) error {
+	webhooks, err := n.getActiveWebhooksForOrg(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch active webhooks for organization %d: %w", orgID, err)
+	}
 	if len(webhooks) == 0 {
-		n.logger.Println("No active webhooks found, skipping notification")
 		return nil
 	}
 
-	// Build payload
-	payload := WebhookPayload{
-		RuleID:       rule.ID,
-		RuleName:     rule.Name,
-		Metric:       rule.Metric,
-		Comparison:   rule.Comparison,
-		ThresholdPct: rule.ThresholdPct,
-		TriggerAfter: rule.TriggerAfter,
-		Value:        event.Value,
-		TriggeredAt:  event.TriggeredAt.Format(time.RFC3339),
-		EventID:      event.ID,
+	targets := resolveDigestRoutes(ctx, n.store, n.logger, orgID, digest)
+
+	for _, webhook := range webhooks {
+		payload := WebhookDigestPayload{
+			Event:       EventAlertDigest,
+			Reason:      digest.Reason,
+			WindowStart: digest.Since.Format(time.RFC3339),
+			WindowEnd:   digest.Until.Format(time.RFC3339),
+			Alerts:      []WebhookPayload{},
+		}
+		for i, entry := range digest.Entries {
+			if targets[i].allowsWebhook(webhook.ID) {
+				payload.Alerts = append(payload.Alerts, newWebhookPayload(entry.EventType, entry.Rule, entry.Event))
+			}
+		}
+		if len(payload.Alerts) == 0 {
+			continue
+		}
+		payload.Count = len(payload.Alerts)
+
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal digest payload: %w", err)
+		}
+
+		go func(w storage.Webhook) {
+			sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			if err := n.sendToWebhook(sendCtx, w, payloadBytes); err != nil {
+				n.logger.Printf("Failed to send digest webhook to %s: %v", w.URL, err)
+			}
+		}(webhook)
 	}
 
+	return nil
+}
+
+// NotifyEscalation pages the organization's active webhooks listed in an escalation step.
+// Notification routes do not apply: the step names its destinations explicitly.
+func (n *Notifier) NotifyEscalation(ctx context.Context, orgID int, escalation AlertEscalation) error {
+	webhooks, err := n.getActiveWebhooksForOrg(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch active webhooks for organization %d: %w", orgID, err)
+	}
+
+	var paged []storage.Webhook
+	for _, webhook := range webhooks {
+		if containsInt(escalation.WebhookIDs, webhook.ID) {
+			paged = append(paged, webhook)
+		}
+	}
+	if len(paged) == 0 {
+		return nil
+	}
+
+	payload := newWebhookPayload(EventAlertEscalated, escalation.Rule, escalation.Event)
+	payload.EscalationPolicy = escalation.PolicyName
+	payload.EscalationStep = escalation.Step
+
+	n.deliverPayload(paged, newAlertTemplateData(EventAlertEscalated, escalation.Rule, escalation.Event), payload)
+	return nil
+}
+
+// deliverAlert renders and sends an alert.* event to the given webhooks concurrently
+func (n *Notifier) deliverAlert(webhooks []storage.Webhook, eventType string, rule storage.AlertRule, event storage.AlertEvent) {
+	if len(webhooks) == 0 {
+		n.logger.Println("No active webhooks found, skipping notification")
+		return
+	}
+
+	n.deliverPayload(webhooks, newAlertTemplateData(eventType, rule, event), newWebhookPayload(eventType, rule, event))
+}
+
+// deliverPayload renders and sends an alert payload to the given webhooks concurrently,
+// using each owner's template for the event type when configured
+func (n *Notifier) deliverPayload(webhooks []storage.Webhook, data TemplateData, payload WebhookPayload) {
 	// Send to all webhooks concurrently
 	for _, webhook := range webhooks {
 		go func(w storage.Webhook) {
@@ -107,13 +235,43 @@ func (n *Notifier) Send(ctx context.Context, rule storage.AlertRule, event stora
 			sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 
-			if err := n.sendToWebhook(sendCtx, w, payload); err != nil {
+			body, err := n.renderWebhookBody(sendCtx, w, data, payload)
+			if err != nil {
+				n.logger.Printf("Failed to build webhook payload for %s: %v", w.URL, err)
+				return
+			}
+
+			if err := n.sendToWebhook(sendCtx, w, body); err != nil {
 				n.logger.Printf("Failed to send webhook to %s: %v", w.URL, err)
 			}
 		}(webhook)
 	}
+}
 
-	return nil
+// newWebhookPayload builds the default JSON payload for an alert.* event
+func newWebhookPayload(eventType string, rule storage.AlertRule, event storage.AlertEvent) WebhookPayload {
+	payload := WebhookPayload{
+		Event:        eventType,
+		RuleID:       rule.ID,
+		RuleName:     rule.Name,
+		RuleType:     ruleType(rule),
+		Expression:   rule.Expression,
+		Metric:       rule.Metric,
+		Comparison:   rule.Comparison,
+		ThresholdPct: rule.ThresholdPct,
+		TriggerAfter: rule.TriggerAfter,
+		Severity:     eventSeverity(rule, event),
+		Value:        event.Value,
+		ExpectedLow:  event.ExpectedLow,
+		ExpectedHigh: event.ExpectedHigh,
+		TriggeredAt:  event.TriggeredAt.Format(time.RFC3339),
+		EventID:      event.ID,
+		MachineID:    event.MachineID,
+	}
+	if event.ResolvedAt != nil {
+		payload.ResolvedAt = event.ResolvedAt.Format(time.RFC3339)
+	}
+	return payload
 }
 
 // SendMachineEvent sends a machine status event to a webhook
@@ -129,20 +287,20 @@ func (n *Notifier) SendMachineEvent(ctx context.Context, webhook storage.Webhook
 		n.logger.Printf("Failed to update webhook delivery state: %v", err)
 	}
 
-	// Marshal payload
-	payload, err := json.Marshal(event)
+	// Render the organization's template or fall back to the default payload
+	payload, err := n.renderWebhookBody(ctx, webhook, newMachineTemplateData(event), event)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return fmt.Errorf("failed to build payload: %w", err)
 	}
 
-	// Create signature
-	signature, err := n.createSignature(payload, webhook.SecretHash)
+	// Sign the delivery
+	headers, err := n.signDelivery(webhook, payload, newDeliveryID(), now)
 	if err != nil {
 		return fmt.Errorf("failed to create signature: %w", err)
 	}
 
 	// Send HTTP request
-	statusCode, err := n.makeHTTPRequest(ctx, webhook.URL, payload, signature)
+	statusCode, err := n.makeHTTPRequest(ctx, webhook.URL, payload, headers)
 	if err != nil {
 		// Record failure
 		if updateErr := n.store.IncrementWebhookFailure(ctx, webhook.ID, now); updateErr != nil {
@@ -153,7 +311,7 @@ func (n *Notifier) SendMachineEvent(ctx context.Context, webhook storage.Webhook
 		webhook.FailureCount++ // Simulate increment for cooldown check
 		webhook.LastErrorAt = &now
 		if n.shouldEnterCooldown(webhook) {
-			cooldownUntil := now.Add(CooldownDuration)
+			cooldownUntil := now.Add(n.deliveryPolicy(webhook).CooldownDuration)
 			if updateErr := n.store.UpdateWebhookDeliveryState(ctx, webhook.ID, now, &cooldownUntil); updateErr != nil {
 				n.logger.Printf("Failed to set webhook cooldown: %v", updateErr)
 			}
@@ -181,6 +339,7 @@ func (n *Notifier) SendTest(ctx context.Context, webhook storage.Webhook) error
 
 	// Build test payload with clearly marked test data
 	payload := WebhookPayload{
+		Event:        EventAlertFired,
 		RuleID:       0,
 		RuleName:     "Test Webhook",
 		Metric:       "cpu_pct",
@@ -192,56 +351,77 @@ func (n *Notifier) SendTest(ctx context.Context, webhook storage.Webhook) error
 		EventID:      0,
 	}
 
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
 	// Send the test payload (reuses existing sendToWebhook logic)
-	return n.sendToWebhook(ctx, webhook, payload)
+	return n.sendToWebhook(ctx, webhook, payloadBytes)
 }
 
-// getAllActiveWebhooks fetches all active webhooks from all users
-// TODO: Replace with user-scoped fetch once multi-tenant is implemented
-func (n *Notifier) getAllActiveWebhooks(ctx context.Context) ([]storage.Webhook, error) {
-	// For now, we need to get all users and then their webhooks
-	// This is a temporary solution until we have proper multi-tenant support
-	users, err := n.store.ListUsers(ctx)
+// renderWebhookBody renders the webhook owner's template for the event, falling back to the
+// default JSON payload when no template is configured or the template fails to render
+func (n *Notifier) renderWebhookBody(ctx context.Context, webhook storage.Webhook, data TemplateData, fallback interface{}) ([]byte, error) {
+	body, err := loadOrgTemplate(ctx, n.store, webhook.OrgID, ChannelWebhook, data.Event)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		n.logger.Printf("[WEBHOOK] failed to load template for org=%d event=%s: %v", webhook.OrgID, data.Event, err)
 	}
 
+	if body != "" {
+		rendered, err := RenderTemplate(ChannelWebhook, body, data)
+		if err == nil {
+			return []byte(rendered), nil
+		}
+		n.logger.Printf("[WEBHOOK] template render failed for org=%d event=%s, using default payload: %v",
+			webhook.OrgID, data.Event, err)
+	}
+
+	return json.Marshal(fallback)
+}
+
+// getActiveWebhooksForOrg fetches an organization's active webhooks
+func (n *Notifier) getActiveWebhooksForOrg(ctx context.Context, orgID int) ([]storage.Webhook, error) {
+	webhooks, err := n.store.ListWebhooks(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []storage.Webhook
+	for _, webhook := range webhooks {
+		if webhook.IsActive {
+			active = append(active, webhook)
+		}
+	}
+	return active, nil
+}
+
+// getActiveWebhooksForEvent fetches the active webhooks of the organizations an alert event concerns
+func (n *Notifier) getActiveWebhooksForEvent(ctx context.Context, rule storage.AlertRule, event storage.AlertEvent) ([]storage.Webhook, error) {
+	orgIDs := eventOrganizations(ctx, n.store, rule, event)
+
 	var allWebhooks []storage.Webhook
-	for _, user := range users {
-		webhooks, err := n.store.ListWebhooks(ctx, user.ID)
+	for _, orgID := range orgIDs {
+		webhooks, err := n.getActiveWebhooksForOrg(ctx, orgID)
 		if err != nil {
-			n.logger.Printf("Failed to fetch webhooks for user %d: %v", user.ID, err)
+			n.logger.Printf("Failed to fetch webhooks for organization %d: %v", orgID, err)
 			continue
 		}
-
-		// Filter active webhooks
-		for _, webhook := range webhooks {
-			if webhook.IsActive {
-				allWebhooks = append(allWebhooks, webhook)
-			}
-		}
+		allWebhooks = append(allWebhooks, webhooks...)
 	}
 
 	return allWebhooks, nil
 }
 
 // sendToWebhook sends the payload to a specific webhook with retry logic
-func (n *Notifier) sendToWebhook(ctx context.Context, webhook storage.Webhook, payload WebhookPayload) error {
+func (n *Notifier) sendToWebhook(ctx context.Context, webhook storage.Webhook, payloadBytes []byte) error {
 	// Check rate limiting and cooldown before proceeding
 	if err := n.checkDeliveryPreconditions(webhook); err != nil {
 		return err
 	}
 
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	// Create HMAC signature
-	signature, err := n.createSignature(payloadBytes, webhook.SecretHash)
-	if err != nil {
-		return fmt.Errorf("failed to create signature: %w", err)
-	}
+	// Retries reuse the delivery ID so receivers can deduplicate them
+	deliveryID := newDeliveryID()
 
 	// Extract domain from URL for safe logging (never log full URL or secrets)
 	webhookURL, _ := url.Parse(webhook.URL)
@@ -265,7 +445,13 @@ func (n *Notifier) sendToWebhook(ctx context.Context, webhook storage.Webhook, p
 		default:
 		}
 
-		statusCode, err := n.makeHTTPRequest(ctx, webhook.URL, payloadBytes, signature)
+		// Each attempt is signed with a fresh timestamp
+		headers, err := n.signDelivery(webhook, payloadBytes, deliveryID, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to create signature: %w", err)
+		}
+
+		statusCode, err := n.makeHTTPRequest(ctx, webhook.URL, payloadBytes, headers)
 		if err == nil {
 			// Success - mark webhook as successful and log
 			if markErr := n.store.MarkWebhookSuccess(ctx, webhook.ID, time.Now()); markErr != nil {
@@ -288,11 +474,11 @@ func (n *Notifier) sendToWebhook(ctx context.Context, webhook storage.Webhook, p
 			}
 
 			// Check if we should enter cooldown based on failure threshold
-			updatedWebhook, fetchErr := n.store.GetWebhook(ctx, webhook.ID, webhook.UserID)
+			updatedWebhook, fetchErr := n.store.GetWebhook(ctx, webhook.ID, webhook.OrgID)
 			if fetchErr != nil {
 				n.logger.Printf("[WEBHOOK] failed to fetch updated webhook=%d: %v", webhook.ID, fetchErr)
 			} else if n.shouldEnterCooldown(*updatedWebhook) {
-				cooldownUntil := time.Now().Add(CooldownDuration)
+				cooldownUntil := time.Now().Add(n.deliveryPolicy(*updatedWebhook).CooldownDuration)
 				if cooldownErr := n.store.UpdateWebhookDeliveryState(ctx, webhook.ID, now, &cooldownUntil); cooldownErr != nil {
 					n.logger.Printf("[WEBHOOK] failed to set cooldown for webhook=%d: %v", webhook.ID, cooldownErr)
 				} else {
@@ -318,6 +504,48 @@ func (n *Notifier) sendToWebhook(ctx context.Context, webhook storage.Webhook, p
 	return nil
 }
 
+// signDelivery builds the signature headers for one delivery attempt. The legacy v1 signature
+// covers only the body; v2 signatures bind the timestamp and delivery ID as well, and are made
+// with both the current and the previous secret while a rotation is in progress.
+func (n *Notifier) signDelivery(webhook storage.Webhook, payload []byte, deliveryID string, now time.Time) (http.Header, error) {
+	legacySignature, err := n.createSignature(payload, webhook.SecretHash)
+	if err != nil {
+		return nil, err
+	}
+
+	secretHashes := []string{webhook.SecretHash}
+	if webhook.PreviousSecretHash != nil && webhook.PreviousSecretExpiresAt != nil && now.Before(*webhook.PreviousSecretExpiresAt) {
+		secretHashes = append(secretHashes, *webhook.PreviousSecretHash)
+	}
+
+	timestamp := now.Unix()
+	signatures := make([]string, 0, len(secretHashes))
+	for _, secretHex := range secretHashes {
+		key, err := hex.DecodeString(secretHex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode secret hex: %w", err)
+		}
+		signatures = append(signatures, webhookverify.Sign(key, timestamp, deliveryID, payload))
+	}
+
+	headers := make(http.Header)
+	headers.Set(webhookverify.HeaderSignature, legacySignature)
+	headers.Set(webhookverify.HeaderSignatureV2, webhookverify.FormatSignatures(signatures...))
+	headers.Set(webhookverify.HeaderTimestamp, fmt.Sprintf("%d", timestamp))
+	headers.Set(webhookverify.HeaderEventID, deliveryID)
+	return headers, nil
+}
+
+// newDeliveryID generates a random identifier for a webhook delivery
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to the clock just in case
+		return fmt.Sprintf("evt_%d", time.Now().UnixNano())
+	}
+	return "evt_" + hex.EncodeToString(b)
+}
+
 // createSignature creates HMAC-SHA256 signature for the payload
 func (n *Notifier) createSignature(payload []byte, secretHex string) (string, error) {
 	// Decode hex secret
@@ -335,14 +563,16 @@ func (n *Notifier) createSignature(payload []byte, secretHex string) (string, er
 }
 
 // makeHTTPRequest makes the actual HTTP request to the webhook URL
-func (n *Notifier) makeHTTPRequest(ctx context.Context, url string, payload []byte, signature string) (int, error) {
+func (n *Notifier) makeHTTPRequest(ctx context.Context, url string, payload []byte, signatureHeaders http.Header) (int, error) {
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	for name, values := range signatureHeaders {
+		req.Header[name] = values
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-LunaSentri-Signature", signature)
 	req.Header.Set("User-Agent", "LunaSentri-Webhook/1.0")
 
 	resp, err := n.client.Do(req)
@@ -360,54 +590,25 @@ func (n *Notifier) makeHTTPRequest(ctx context.Context, url string, payload []by
 
 // checkDeliveryPreconditions verifies if a webhook delivery should proceed based on rate limiting and cooldown
 func (n *Notifier) checkDeliveryPreconditions(webhook storage.Webhook) error {
-	now := time.Now()
+	rateLimitErr := n.deliveryPolicy(webhook).checkPreconditions("Webhook", time.Now(), webhook.CooldownUntil, webhook.LastAttemptAt)
+	if rateLimitErr == nil {
+		return nil
+	}
 
-	// Check if webhook is in cooldown
-	if webhook.CooldownUntil != nil && now.Before(*webhook.CooldownUntil) {
+	if rateLimitErr.Type == "cooldown" {
 		n.logger.Printf("[WEBHOOK] throttled webhook=%d reason=cooldown until=%s",
 			webhook.ID, webhook.CooldownUntil.Format(time.RFC3339))
-		return &RateLimitError{
-			Type:    "cooldown",
-			Message: fmt.Sprintf("Webhook in cooldown until %s", webhook.CooldownUntil.Format(time.RFC3339)),
-			RetryAt: webhook.CooldownUntil,
-		}
+	} else {
+		n.logger.Printf("[WEBHOOK] rate_limited webhook=%d retry_at=%s",
+			webhook.ID, rateLimitErr.RetryAt.Format(time.RFC3339))
 	}
 
-	// Check minimum interval between attempts
-	if webhook.LastAttemptAt != nil {
-		timeSinceLastAttempt := now.Sub(*webhook.LastAttemptAt)
-		if timeSinceLastAttempt < MinAttemptInterval {
-			delay := MinAttemptInterval - timeSinceLastAttempt
-			n.logger.Printf("[WEBHOOK] rate_limited webhook=%d delay=%s",
-				webhook.ID, delay.String())
-			retryAt := webhook.LastAttemptAt.Add(MinAttemptInterval)
-			return &RateLimitError{
-				Type:    "rate_limit",
-				Message: fmt.Sprintf("Rate limit active, can retry in %s", delay.String()),
-				RetryAt: &retryAt,
-			}
-		}
-	}
-
-	return nil
+	return rateLimitErr
 }
 
 // shouldEnterCooldown determines if a webhook should enter cooldown based on recent failures
 func (n *Notifier) shouldEnterCooldown(webhook storage.Webhook) bool {
-	// If we haven't reached the failure threshold, no cooldown
-	if webhook.FailureCount < FailureThreshold {
-		return false
-	}
-
-	// If we don't have a last error timestamp, enter cooldown as a safety measure
-	if webhook.LastErrorAt == nil {
-		return true
-	}
-
-	// Check if the failures occurred within the failure window
-	now := time.Now()
-	failureWindowStart := now.Add(-FailureWindow)
-	return webhook.LastErrorAt.After(failureWindowStart)
+	return n.deliveryPolicy(webhook).shouldEnterCooldown(time.Now(), webhook.FailureCount, webhook.LastErrorAt)
 }
 
 // RateLimitError represents an error due to rate limiting or cooldown
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/pkg/webhookverify"
 )
 
 // mockStore implements storage.Store for testing
@@ -24,6 +25,7 @@ type mockStore struct {
 	webhooks      map[int][]storage.Webhook // userID -> webhooks
 	failureCounts map[int]int               // webhookID -> failure count
 	successTimes  map[int]time.Time         // webhookID -> last success time
+	templates     map[string]string         // "userID/channel/eventType" -> template body
 }
 
 func newMockStore() *mockStore {
@@ -31,6 +33,7 @@ func newMockStore() *mockStore {
 		webhooks:      make(map[int][]storage.Webhook),
 		failureCounts: make(map[int]int),
 		successTimes:  make(map[int]time.Time),
+		templates:     make(map[string]string),
 	}
 }
 
@@ -103,27 +106,34 @@ func (m *mockStore) PromoteToAdmin(ctx context.Context, userID int) error {
 func (m *mockStore) DeletePasswordResetsForUser(ctx context.Context, userID int) error {
 	return fmt.Errorf("not implemented")
 }
-func (m *mockStore) ListAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
+func (m *mockStore) ListAlertRules(ctx context.Context, orgID int) ([]storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockStore) CreateAlertRule(ctx context.Context, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
+func (m *mockStore) ListAllAlertRules(ctx context.Context) ([]storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockStore) UpdateAlertRule(ctx context.Context, id int, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*storage.AlertRule, error) {
+func (m *mockStore) GetAlertRule(ctx context.Context, id int, orgID int) (*storage.AlertRule, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockStore) DeleteAlertRule(ctx context.Context, id int) error {
+func (m *mockStore) SaveAlertRule(ctx context.Context, rule storage.AlertRule) (*storage.AlertRule, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+func (m *mockStore) DeleteAlertRule(ctx context.Context, id int, orgID int) error {
 	return fmt.Errorf("not implemented")
 }
 func (m *mockStore) ListAlertEvents(ctx context.Context, limit int) ([]storage.AlertEvent, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockStore) CreateAlertEvent(ctx context.Context, ruleID int, value float64) (*storage.AlertEvent, error) {
+func (m *mockStore) CreateAlertEvent(ctx context.Context, ruleID int, machineID *int, value float64) (*storage.AlertEvent, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 func (m *mockStore) AckAlertEvent(ctx context.Context, id int) error {
 	return fmt.Errorf("not implemented")
 }
+
+func (m *mockStore) GetAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("alert event with id %d not found", id)
+}
 func (m *mockStore) CreateWebhook(ctx context.Context, userID int, url, secretHash string) (*storage.Webhook, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -163,7 +173,7 @@ func (m *mockStore) ListTelegramRecipients(ctx context.Context, userID int) ([]s
 func (m *mockStore) GetTelegramRecipient(ctx context.Context, id int, userID int) (*storage.TelegramRecipient, error) {
 	return nil, fmt.Errorf("not implemented")
 }
-func (m *mockStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string) (*storage.TelegramRecipient, error) {
+func (m *mockStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string, createdBy int) (*storage.TelegramRecipient, error) {
 	return nil, fmt.Errorf("not implemented")
 }
 func (m *mockStore) UpdateTelegramRecipient(ctx context.Context, id int, userID int, chatID string, isActive *bool) (*storage.TelegramRecipient, error) {
@@ -239,6 +249,18 @@ func (m *mockStore) ClearMachineOfflineNotification(ctx context.Context, machine
 	return nil
 }
 
+func (m *mockStore) HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error {
+	return nil
+}
+
+func (m *mockStore) MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error) {
+	return false, nil
+}
+
+func (m *mockStore) ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error {
+	return nil
+}
+
 // Machine credential management methods (not implemented for these tests)
 func (m *mockStore) SetMachineEnabled(ctx context.Context, machineID int, enabled bool) error {
 	return fmt.Errorf("not implemented")
@@ -268,6 +290,403 @@ func (m *mockStore) ListMachineAPIKeys(ctx context.Context, machineID int) ([]st
 	return nil, fmt.Errorf("not implemented")
 }
 
+// Alert resolution and notification template methods (stub implementations for testing)
+func (m *mockStore) ResolveAlertEvent(ctx context.Context, id int) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListNotificationTemplates(ctx context.Context, userID int) ([]storage.NotificationTemplate, error) {
+	return []storage.NotificationTemplate{}, nil
+}
+
+func (m *mockStore) GetNotificationTemplate(ctx context.Context, userID int, channel, eventType string) (*storage.NotificationTemplate, error) {
+	body, ok := m.templates[fmt.Sprintf("%d/%s/%s", userID, channel, eventType)]
+	if !ok {
+		return nil, storage.ErrNotificationTemplateNotFound
+	}
+	return &storage.NotificationTemplate{OrgID: userID, Channel: channel, EventType: eventType, Body: body}, nil
+}
+
+func (m *mockStore) UpsertNotificationTemplate(ctx context.Context, userID int, channel, eventType, body string) (*storage.NotificationTemplate, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteNotificationTemplate(ctx context.Context, id int, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Delivery settings methods (stub implementations for testing)
+func (m *mockStore) UpdateWebhookDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ResetWebhookCircuit(ctx context.Context, id int, userID int) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UpdateTelegramDeliverySettings(ctx context.Context, id int, userID int, settings storage.DeliverySettings) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ResetTelegramCircuit(ctx context.Context, id int, userID int) (*storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RotateWebhookSecret(ctx context.Context, id int, userID int, newSecretHash string, previousExpiresAt time.Time) (*storage.Webhook, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListActiveSilences(ctx context.Context, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockStore) ListTelegramRecipientsByChatID(ctx context.Context, chatID string) ([]storage.TelegramRecipient, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetNotificationPolicy(ctx context.Context, userID int) (*storage.NotificationPolicy, error) {
+	return nil, storage.ErrNotificationPolicyNotFound
+}
+
+func (m *mockStore) UpsertNotificationPolicy(ctx context.Context, policy storage.NotificationPolicy) (*storage.NotificationPolicy, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) AddPendingDigestEntry(ctx context.Context, entry storage.PendingDigestEntry) (*storage.PendingDigestEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListPendingDigestEntries(ctx context.Context) ([]storage.PendingDigestEntry, error) {
+	return nil, nil
+}
+
+func (m *mockStore) DeletePendingDigestEntries(ctx context.Context, orgID, maxID int) error {
+	return nil
+}
+
+func (m *mockStore) ListNotificationRoutes(ctx context.Context, userID int) ([]storage.NotificationRoute, error) {
+	return []storage.NotificationRoute{}, nil
+}
+
+func (m *mockStore) GetNotificationRoute(ctx context.Context, id int, userID int) (*storage.NotificationRoute, error) {
+	return nil, fmt.Errorf("notification route with id %d not found for user %d", id, userID)
+}
+
+func (m *mockStore) CreateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockStore) UpdateNotificationRoute(ctx context.Context, route storage.NotificationRoute) (*storage.NotificationRoute, error) {
+	return &route, nil
+}
+
+func (m *mockStore) DeleteNotificationRoute(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockStore) GetMachineTags(ctx context.Context, machineID int) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *mockStore) SetMachineTags(ctx context.Context, machineID int, tags []string) error {
+	return nil
+}
+
+func (m *mockStore) ListEscalationPolicies(ctx context.Context, userID int) ([]storage.EscalationPolicy, error) {
+	return []storage.EscalationPolicy{}, nil
+}
+
+func (m *mockStore) GetEscalationPolicy(ctx context.Context, id int, userID int) (*storage.EscalationPolicy, error) {
+	return nil, fmt.Errorf("escalation policy with id %d not found for user %d", id, userID)
+}
+
+func (m *mockStore) CreateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockStore) UpdateEscalationPolicy(ctx context.Context, policy storage.EscalationPolicy) (*storage.EscalationPolicy, error) {
+	return &policy, nil
+}
+
+func (m *mockStore) DeleteEscalationPolicy(ctx context.Context, id int, userID int) error {
+	return nil
+}
+
+func (m *mockStore) ListPendingEscalations(ctx context.Context) ([]storage.PendingEscalation, error) {
+	return []storage.PendingEscalation{}, nil
+}
+
+func (m *mockStore) RecordAlertEscalation(ctx context.Context, eventID int, step int, escalatedAt time.Time) error {
+	return nil
+}
+
+func (m *mockStore) GetSilence(ctx context.Context, id int, orgID int) (*storage.Silence, error) {
+	return nil, fmt.Errorf("silence with id %d not found", id)
+}
+
+func (m *mockStore) ListSilences(ctx context.Context, orgID int, at time.Time) ([]storage.Silence, error) {
+	return []storage.Silence{}, nil
+}
+
+func (m *mockStore) UpdateSilence(ctx context.Context, silence storage.Silence) (*storage.Silence, error) {
+	return &silence, nil
+}
+
+func (m *mockStore) DeleteSilence(ctx context.Context, id int, orgID int) error {
+	return nil
+}
+
+func (m *mockStore) SetAlertEventExpectedRange(ctx context.Context, id int, low, high float64) (*storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetMetricBaseline(ctx context.Context, machineID int, metric, seasonality string, slot int) (*storage.MetricBaseline, error) {
+	return nil, storage.ErrMetricBaselineNotFound
+}
+
+func (m *mockStore) UpsertMetricBaseline(ctx context.Context, baseline storage.MetricBaseline) error {
+	return nil
+}
+
+func (m *mockStore) ListOpenAlertEvents(ctx context.Context) ([]storage.AlertEvent, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListAlertRuleStates(ctx context.Context) ([]storage.AlertRuleState, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UpsertAlertRuleState(ctx context.Context, state storage.AlertRuleState) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteAlertRuleStates(ctx context.Context, ruleID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) QueryAlertEvents(ctx context.Context, filter storage.AlertEventFilter) ([]storage.AlertEvent, *storage.AlertEventCursor, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateSession(ctx context.Context, session storage.Session) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetSession(ctx context.Context, id int) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetSessionByRefreshHash(ctx context.Context, tokenHash string) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RotateSessionRefreshToken(ctx context.Context, id int, oldHash, newHash string, expiresAt time.Time, ipAddress string) (*storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) TouchSession(ctx context.Context, id int, ipAddress string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListSessions(ctx context.Context, userID int) ([]storage.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RevokeSession(ctx context.Context, id, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RevokeUserSessions(ctx context.Context, userID, exceptID int) (int, error) {
+	return 0, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateAPIToken(ctx context.Context, token storage.APIToken) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetAPITokenByHash(ctx context.Context, tokenHash string) (*storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListAPITokens(ctx context.Context, userID int) ([]storage.APIToken, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RevokeAPIToken(ctx context.Context, id, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) TouchAPIToken(ctx context.Context, id int, ipAddress string) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Two-factor authentication methods (stub implementations for testing)
+func (m *mockStore) GetUserTOTP(ctx context.Context, userID int) (*storage.UserTOTP, error) {
+	return nil, storage.ErrTOTPNotFound
+}
+
+func (m *mockStore) SetUserTOTPSecret(ctx context.Context, userID int, secret string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) EnableUserTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteUserTOTP(ctx context.Context, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockStore) GetSetting(ctx context.Context, key string) (string, error) {
+	return "", storage.ErrSettingNotFound
+}
+
+func (m *mockStore) SetSetting(ctx context.Context, key, value string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*storage.User, error) {
+	return nil, storage.ErrUserNotFound
+}
+
+func (m *mockStore) LinkUserIdentity(ctx context.Context, identity storage.UserIdentity) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateOIDCLogin(ctx context.Context, login storage.OIDCLogin) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*storage.OIDCLogin, error) {
+	return nil, storage.ErrOIDCLoginNotFound
+}
+
+func (m *mockStore) GetLoginAttempts(ctx context.Context, key string) (*storage.LoginAttempts, error) {
+	return nil, storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockStore) RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ClearLoginAttempts(ctx context.Context, key string) error {
+	return storage.ErrLoginAttemptsNotFound
+}
+
+func (m *mockStore) ListLoginLockouts(ctx context.Context) ([]storage.LoginAttempts, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateAuditEntry(ctx context.Context, entry storage.AuditEntry) (*storage.AuditEntry, error) {
+	return &entry, nil
+}
+
+func (m *mockStore) ListAuditEntries(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) SetUserRole(ctx context.Context, userID int, role string) error {
+	return fmt.Errorf("not implemented")
+}
+
+// Organization methods (stub implementations for testing)
+func (m *mockStore) CreateOrganization(ctx context.Context, name string, ownerID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetOrganization(ctx context.Context, id int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetPersonalOrganization(ctx context.Context, userID int) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RenameOrganization(ctx context.Context, id int, name string) (*storage.Organization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteOrganization(ctx context.Context, id int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListOrgMembers(ctx context.Context, orgID int) ([]storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) AddOrgMember(ctx context.Context, orgID, userID int, role string) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) SetOrgMemberRole(ctx context.Context, orgID, userID int, role string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) RemoveOrgMember(ctx context.Context, orgID, userID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) CreateOrgInvitation(ctx context.Context, invitation storage.OrgInvitation) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetOrgInvitationByHash(ctx context.Context, tokenHash string) (*storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) ListOrgInvitations(ctx context.Context, orgID int) ([]storage.OrgInvitation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) DeleteOrgInvitation(ctx context.Context, id, orgID int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) AcceptOrgInvitation(ctx context.Context, id, userID int) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// ListOrganizations gives every user a personal organization sharing the user's ID
+func (m *mockStore) ListOrganizations(ctx context.Context) ([]storage.Organization, error) {
+	orgs := make([]storage.Organization, len(m.users))
+	for i, user := range m.users {
+		orgs[i] = storage.Organization{ID: user.ID, Name: user.Email, Personal: true}
+	}
+	return orgs, nil
+}
+
+func (m *mockStore) ListUserOrganizations(ctx context.Context, userID int) ([]storage.UserOrganization, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockStore) GetOrgMember(ctx context.Context, orgID, userID int) (*storage.OrgMember, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockStore) Close() error {
 	return nil
 }
@@ -310,7 +729,7 @@ func TestNotifier_Send_Success(t *testing.T) {
 	store.webhooks[1] = []storage.Webhook{
 		{
 			ID:         1,
-			UserID:     1,
+			OrgID:      1,
 			URL:        server.URL,
 			SecretHash: secretHash,
 			IsActive:   true,
@@ -323,6 +742,7 @@ func TestNotifier_Send_Success(t *testing.T) {
 	// Test data
 	rule := storage.AlertRule{
 		ID:           1,
+		OrgID:        1,
 		Name:         "Test Rule",
 		Metric:       "cpu_pct",
 		Comparison:   "above",
@@ -395,7 +815,7 @@ func TestNotifier_Send_Failure_And_Retry(t *testing.T) {
 	store.webhooks[1] = []storage.Webhook{
 		{
 			ID:         1,
-			UserID:     1,
+			OrgID:      1,
 			URL:        server.URL,
 			SecretHash: secretHash,
 			IsActive:   true,
@@ -406,7 +826,7 @@ func TestNotifier_Send_Failure_And_Retry(t *testing.T) {
 	notifier := NewNotifier(store, log.Default())
 
 	// Test data
-	rule := storage.AlertRule{ID: 1, Name: "Test Rule"}
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "Test Rule"}
 	event := storage.AlertEvent{ID: 1, RuleID: 1, Value: 85.5, TriggeredAt: time.Now()}
 
 	// Send notification
@@ -450,7 +870,7 @@ func TestNotifier_Send_MaxRetries_Exceeded(t *testing.T) {
 	store.webhooks[1] = []storage.Webhook{
 		{
 			ID:         1,
-			UserID:     1,
+			OrgID:      1,
 			URL:        server.URL,
 			SecretHash: secretHash,
 			IsActive:   true,
@@ -461,7 +881,7 @@ func TestNotifier_Send_MaxRetries_Exceeded(t *testing.T) {
 	notifier := NewNotifier(store, log.Default())
 
 	// Test data
-	rule := storage.AlertRule{ID: 1, Name: "Test Rule"}
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "Test Rule"}
 	event := storage.AlertEvent{ID: 1, RuleID: 1, Value: 85.5, TriggeredAt: time.Now()}
 
 	// Send notification
@@ -502,7 +922,7 @@ func TestNotifier_Send_Context_Cancellation(t *testing.T) {
 	store.webhooks[1] = []storage.Webhook{
 		{
 			ID:         1,
-			UserID:     1,
+			OrgID:      1,
 			URL:        server.URL,
 			SecretHash: secretHash,
 			IsActive:   true,
@@ -513,7 +933,7 @@ func TestNotifier_Send_Context_Cancellation(t *testing.T) {
 	notifier := NewNotifier(store, log.Default())
 
 	// Test data
-	rule := storage.AlertRule{ID: 1, Name: "Test Rule"}
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "Test Rule"}
 	event := storage.AlertEvent{ID: 1, RuleID: 1, Value: 85.5, TriggeredAt: time.Now()}
 
 	// Create context with short timeout
@@ -579,7 +999,7 @@ func TestNotifier_Send_NoActiveWebhooks(t *testing.T) {
 	notifier := NewNotifier(store, log.Default())
 
 	// Test data
-	rule := storage.AlertRule{ID: 1, Name: "Test Rule"}
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "Test Rule"}
 	event := storage.AlertEvent{ID: 1, RuleID: 1, Value: 85.5, TriggeredAt: time.Now()}
 
 	// Send notification
@@ -599,7 +1019,7 @@ func TestNotifier_Send_InactiveWebhooks(t *testing.T) {
 	store.webhooks[1] = []storage.Webhook{
 		{
 			ID:         1,
-			UserID:     1,
+			OrgID:      1,
 			URL:        "http://example.com/webhook",
 			SecretHash: storage.HashSecret("secret"),
 			IsActive:   false, // Inactive
@@ -610,7 +1030,7 @@ func TestNotifier_Send_InactiveWebhooks(t *testing.T) {
 	notifier := NewNotifier(store, log.Default())
 
 	// Test data
-	rule := storage.AlertRule{ID: 1, Name: "Test Rule"}
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "Test Rule"}
 	event := storage.AlertEvent{ID: 1, RuleID: 1, Value: 85.5, TriggeredAt: time.Now()}
 
 	// Send notification
@@ -663,7 +1083,7 @@ func TestNotifier_SendTest_Success(t *testing.T) {
 	secret := "test-secret-key-12345"
 	webhook := storage.Webhook{
 		ID:         1,
-		UserID:     1,
+		OrgID:      1,
 		URL:        server.URL,
 		SecretHash: storage.HashSecret(secret),
 		IsActive:   true,
@@ -718,7 +1138,7 @@ func TestNotifier_SendTest_Failure(t *testing.T) {
 	secret := "test-secret-key-12345"
 	webhook := storage.Webhook{
 		ID:         1,
-		UserID:     1,
+		OrgID:      1,
 		URL:        server.URL,
 		SecretHash: storage.HashSecret(secret),
 		IsActive:   true,
@@ -736,3 +1156,75 @@ func TestNotifier_SendTest_Failure(t *testing.T) {
 		t.Errorf("Expected failure count 1, got %d", store.failureCounts[webhook.ID])
 	}
 }
+
+func TestNotifier_SignDelivery_V2(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan delivery, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	oldHash := storage.HashSecret("old-secret-123456")
+	expiresAt := time.Now().Add(time.Hour)
+
+	store := newMockStore()
+	store.users = []storage.User{{ID: 1, Email: "test@example.com"}}
+	store.webhooks[1] = []storage.Webhook{
+		{
+			ID:                      1,
+			OrgID:                   1,
+			URL:                     server.URL,
+			SecretHash:              storage.HashSecret("new-secret-123456"),
+			IsActive:                true,
+			PreviousSecretHash:      &oldHash,
+			PreviousSecretExpiresAt: &expiresAt,
+		},
+	}
+
+	notifier := NewNotifier(store, log.New(io.Discard, "", 0))
+	rule := storage.AlertRule{ID: 1, OrgID: 1, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 80, TriggerAfter: 1}
+	event := storage.AlertEvent{ID: 7, RuleID: 1, Value: 91, TriggeredAt: time.Now()}
+
+	if err := notifier.Send(context.Background(), rule, event); err != nil {
+		t.Fatalf("Failed to send notification: %v", err)
+	}
+
+	var got delivery
+	select {
+	case got = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for webhook delivery")
+	}
+
+	if !strings.HasPrefix(got.header.Get(webhookverify.HeaderEventID), "evt_") {
+		t.Errorf("Expected delivery ID header, got %q", got.header.Get(webhookverify.HeaderEventID))
+	}
+	if got.header.Get(webhookverify.HeaderSignature) == "" {
+		t.Error("Expected legacy signature header to still be sent")
+	}
+
+	// Receivers on either secret accept the delivery during the rotation window
+	for _, secret := range []string{"new-secret-123456", "old-secret-123456"} {
+		if _, err := webhookverify.New(secret).Verify(got.header, got.body); err != nil {
+			t.Errorf("Expected delivery to verify with %s, got %v", secret, err)
+		}
+	}
+
+	// Once the window has passed only the new secret signs
+	expired := time.Now().Add(-time.Minute)
+	webhook := store.webhooks[1][0]
+	webhook.PreviousSecretExpiresAt = &expired
+	headers, err := notifier.signDelivery(webhook, got.body, "evt_test", time.Now())
+	if err != nil {
+		t.Fatalf("Failed to sign delivery: %v", err)
+	}
+	if _, err := webhookverify.New("old-secret-123456").Verify(headers, got.body); err == nil {
+		t.Error("Expected expired previous secret to no longer verify")
+	}
+}
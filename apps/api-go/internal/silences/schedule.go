@@ -0,0 +1,141 @@
+package silences
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five-field cron expression ("minute hour day-of-month month day-of-week")
+// that marks the start of each window of a recurring silence.
+//
+// Each field accepts "*", single values, ranges ("1-5"), steps ("*/15", "0-30/10") and
+// comma-separated lists of those. Day of week runs from 0 (Sunday) to 6; 7 is also Sunday.
+// As in cron, when both day of month and day of week are restricted a day matching either is used.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+// scheduleField describes the allowed range of a cron field
+type scheduleField struct {
+	name     string
+	min, max int
+}
+
+var scheduleFields = []scheduleField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseSchedule parses a five-field cron expression
+func ParseSchedule(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(scheduleFields) {
+		return nil, fmt.Errorf("schedule must have 5 fields (minute hour day-of-month month day-of-week), got %d", len(fields))
+	}
+
+	var bits [5]uint64
+	var wildcard [5]bool
+	for i, field := range fields {
+		b, err := parseScheduleField(field, scheduleFields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+		wildcard[i] = field == "*"
+	}
+
+	// Sunday can be written as 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: wildcard[2],
+		dowAny: wildcard[4],
+	}, nil
+}
+
+// parseScheduleField parses one cron field into a bit set of allowed values
+func parseScheduleField(field string, spec scheduleField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", spec.name, field)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		lo, hi := spec.min, spec.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field %q", spec.name, field)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", spec.name, field)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				// "5/15" means every 15 starting at 5
+				hi = spec.max
+			}
+		}
+
+		if lo < spec.min || hi > spec.max {
+			return 0, fmt.Errorf("%s field %q must be between %d and %d", spec.name, field, spec.min, spec.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// Matches reports whether a window starts at the minute containing t, in t's location
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.minute&(1<<uint(t.Minute())) == 0 ||
+		s.hour&(1<<uint(t.Hour())) == 0 ||
+		s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// InWindow reports whether at falls within a window of the given length started by the schedule
+func (s *Schedule) InWindow(at time.Time, length time.Duration, loc *time.Location) bool {
+	for start := at.Truncate(time.Minute); at.Sub(start) < length; start = start.Add(-time.Minute) {
+		if s.Matches(start.In(loc)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package silences
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 2 * * 2",
+		"*/15 0-6 1,15 * 1-5",
+		"30 22 * 1-3,10-12 0,7",
+		"5/20 * * * *",
+	}
+	for _, expr := range valid {
+		if _, err := ParseSchedule(expr); err != nil {
+			t.Errorf("ParseSchedule(%q) failed: %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"0 2 * *",
+		"0 2 * * 2 2025",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) should have failed", expr)
+		}
+	}
+}
+
+func TestSchedule_Matches(t *testing.T) {
+	// 2025-01-07 is a Tuesday
+	tuesday := time.Date(2025, 1, 7, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"0 2 * * 2", tuesday, true},
+		{"0 2 * * 2", tuesday.Add(time.Minute), false},
+		{"0 2 * * 2", tuesday.AddDate(0, 0, 1), false},
+		{"*/15 * * * *", tuesday.Add(45 * time.Minute), true},
+		{"*/15 * * * *", tuesday.Add(50 * time.Minute), false},
+		{"0 2 * * 0", tuesday.AddDate(0, 0, 5), true},
+		{"0 2 * * 7", tuesday.AddDate(0, 0, 5), true},
+		// Day of month and day of week both restricted: either matches
+		{"0 2 1 * 2", tuesday, true},
+		{"0 2 1 * 3", tuesday, false},
+		{"0 2 7 * 3", tuesday, true},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q) failed: %v", tt.expr, err)
+		}
+		if got := schedule.Matches(tt.at); got != tt.want {
+			t.Errorf("%q at %v: expected %v, got %v", tt.expr, tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestSchedule_InWindow(t *testing.T) {
+	// Patch night: every Tuesday from 02:00 Berlin time for three hours
+	schedule, err := ParseSchedule("0 2 * * 2")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	start := time.Date(2025, 1, 7, 2, 0, 0, 0, berlin)
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{start.Add(-time.Minute), false},
+		{start, true},
+		{start.Add(90 * time.Minute), true},
+		{start.Add(3*time.Hour - time.Second), true},
+		{start.Add(3 * time.Hour), false},
+		{start.AddDate(0, 0, 7).Add(time.Hour), true},
+		{start.AddDate(0, 0, 1).Add(time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		if got := schedule.InWindow(tt.at.UTC(), 3*time.Hour, berlin); got != tt.want {
+			t.Errorf("At %v: expected %v, got %v", tt.at, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package silences
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/audit"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/machines"
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+const (
+	// MinWindow is the shortest window a recurring silence can have
+	MinWindow = time.Minute
+	// MaxWindow is the longest window a recurring silence can have
+	MaxWindow = 7 * 24 * time.Hour
+)
+
+// Target identifies what a notification is about and who receives it, for matching against silences
+type Target struct {
+	OrgID     int  // organization being notified; only its own silences apply
+	RuleID    *int // alert rule, nil for machine status notifications
+	MachineID *int // machine, nil for alerts on the local host
+}
+
+// Service manages silences and decides whether notifications are silenced
+type Service struct {
+	store storage.Store
+}
+
+// NewService creates a new silence service
+func NewService(store storage.Store) *Service {
+	return &Service{store: store}
+}
+
+// Silenced returns the silence that mutes notifications about target at the given time, or nil.
+// A storage error never silences anything: an alert is better than a missed outage.
+func (s *Service) Silenced(ctx context.Context, target Target, at time.Time) *storage.Silence {
+	silences, err := s.store.ListActiveSilences(ctx, at)
+	if err != nil {
+		log.Printf("[SILENCE] failed to load silences: %v", err)
+		return nil
+	}
+	return s.match(ctx, silences, target, at)
+}
+
+// match returns the first of silences that mutes notifications about target at the given time
+func (s *Service) match(ctx context.Context, silences []storage.Silence, target Target, at time.Time) *storage.Silence {
+	var err error
+	var tags []string
+	tagsLoaded := false
+
+	for i := range silences {
+		silence := silences[i]
+
+		if silence.Tag != "" && !tagsLoaded && target.MachineID != nil {
+			if tags, err = s.store.GetMachineTags(ctx, *target.MachineID); err != nil {
+				log.Printf("[SILENCE] failed to load tags for machine=%d: %v", *target.MachineID, err)
+			}
+			tagsLoaded = true
+		}
+
+		if Matches(silence, target, tags, at) {
+			return &silence
+		}
+	}
+
+	return nil
+}
+
+// MachineSilenced reports whether machine status notifications for a machine are silenced
+func (s *Service) MachineSilenced(ctx context.Context, machine storage.Machine, at time.Time) bool {
+	return s.Silenced(ctx, Target{OrgID: machine.OrgID, MachineID: &machine.ID}, at) != nil
+}
+
+// AlertSilenced returns a silence muting an alert of rule on machineID, or nil. Alerts are only
+// sent to the rule's organization, so only its silences apply, on a machine as on the local host.
+func (s *Service) AlertSilenced(ctx context.Context, rule storage.AlertRule, machineID *int, at time.Time) *storage.Silence {
+	return s.Silenced(ctx, Target{OrgID: rule.OrgID, RuleID: &rule.ID, MachineID: machineID}, at)
+}
+
+// Matches reports whether a silence applies to target at the given time.
+// machineTags are the tags of the target's machine.
+func Matches(silence storage.Silence, target Target, machineTags []string, at time.Time) bool {
+	if !silence.Active(at) || silence.OrgID != target.OrgID {
+		return false
+	}
+
+	if silence.RuleID != nil && (target.RuleID == nil || *target.RuleID != *silence.RuleID) {
+		return false
+	}
+	if silence.MachineID != nil && (target.MachineID == nil || *target.MachineID != *silence.MachineID) {
+		return false
+	}
+	if silence.Tag != "" && (target.MachineID == nil || !hasTag(machineTags, silence.Tag)) {
+		return false
+	}
+
+	if !silence.Recurring() {
+		return true
+	}
+
+	schedule, err := ParseSchedule(silence.Schedule)
+	if err != nil {
+		log.Printf("[SILENCE] silence %d has an invalid schedule %q: %v", silence.ID, silence.Schedule, err)
+		return false
+	}
+	loc, err := time.LoadLocation(silence.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return schedule.InWindow(at, time.Duration(silence.DurationSeconds)*time.Second, loc)
+}
+
+// Validate checks a silence created or edited in the organization orgID and normalizes its tag
+// and timezone. Machine silences are limited to the organization's own machines. Alert rules are
+// shared by the installation, so any rule can be silenced, but only for the organization itself.
+func (s *Service) Validate(ctx context.Context, orgID int, silence *storage.Silence) error {
+	if silence.StartsAt.IsZero() {
+		return fmt.Errorf("starts_at is required")
+	}
+	if silence.EndsAt != nil && !silence.EndsAt.After(silence.StartsAt) {
+		return fmt.Errorf("ends_at must be after starts_at")
+	}
+
+	if silence.Recurring() {
+		if _, err := ParseSchedule(silence.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+		window := time.Duration(silence.DurationSeconds) * time.Second
+		if window < MinWindow || window > MaxWindow {
+			return fmt.Errorf("duration_seconds must be between %d and %d", int(MinWindow/time.Second), int(MaxWindow/time.Second))
+		}
+		if silence.Timezone == "" {
+			silence.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(silence.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", silence.Timezone)
+		}
+	} else {
+		if silence.EndsAt == nil {
+			return fmt.Errorf("ends_at is required unless a schedule is set")
+		}
+		if silence.DurationSeconds != 0 {
+			return fmt.Errorf("duration_seconds only applies to silences with a schedule")
+		}
+		silence.Timezone = "UTC"
+	}
+
+	if silence.Tag != "" {
+		tags, err := machines.NormalizeTags([]string{silence.Tag})
+		if err != nil {
+			return err
+		}
+		silence.Tag = tags[0]
+	}
+
+	if silence.RuleID != nil {
+		if _, err := s.store.GetAlertRule(ctx, *silence.RuleID, orgID); err != nil {
+			return fmt.Errorf("alert rule %d not found", *silence.RuleID)
+		}
+	}
+
+	if silence.MachineID != nil {
+		machine, err := s.store.GetMachineByID(ctx, *silence.MachineID)
+		if err != nil || machine.OrgID != orgID {
+			return fmt.Errorf("machine %d not found", *silence.MachineID)
+		}
+	}
+
+	return nil
+}
+
+// List returns an organization's silences that have not ended yet
+func (s *Service) List(ctx context.Context, orgID int) ([]storage.Silence, error) {
+	return s.store.ListSilences(ctx, orgID, time.Now())
+}
+
+// Get returns an organization's silence by ID
+func (s *Service) Get(ctx context.Context, orgID, id int) (*storage.Silence, error) {
+	return s.store.GetSilence(ctx, id, orgID)
+}
+
+// Create stores a validated silence in orgID on behalf of userID
+func (s *Service) Create(ctx context.Context, orgID, userID int, silence storage.Silence) (*storage.Silence, error) {
+	silence.OrgID = orgID
+	silence.CreatedBy = userID
+
+	created, err := s.store.CreateSilence(ctx, silence)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[SILENCE] silence %d created by user=%d: %s", created.ID, userID, describe(*created))
+	s.audit(ctx, "silence.create", orgID, created.ID, nil, created)
+	return created, nil
+}
+
+// Update replaces a validated silence in orgID on behalf of userID
+func (s *Service) Update(ctx context.Context, orgID, userID int, silence storage.Silence) (*storage.Silence, error) {
+	silence.OrgID = orgID
+	silence.UpdatedBy = &userID
+
+	before, err := s.store.GetSilence(ctx, silence.ID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.store.UpdateSilence(ctx, silence)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[SILENCE] silence %d updated by user=%d: %s", updated.ID, userID, describe(*updated))
+	s.audit(ctx, "silence.update", orgID, updated.ID, before, updated)
+	return updated, nil
+}
+
+// Delete removes a silence in orgID on behalf of userID
+func (s *Service) Delete(ctx context.Context, orgID, userID, id int) error {
+	before, err := s.store.GetSilence(ctx, id, orgID)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteSilence(ctx, id, orgID); err != nil {
+		return err
+	}
+
+	log.Printf("[SILENCE] silence %d deleted by user=%d", id, userID)
+	s.audit(ctx, "silence.delete", orgID, id, before, nil)
+	return nil
+}
+
+// audit records a change to a silence
+func (s *Service) audit(ctx context.Context, action string, orgID, silenceID int, before, after *storage.Silence) {
+	audit.Record(ctx, s.store, audit.Event{OrgID: orgID, Action: action, TargetType: "silence", TargetID: silenceID, Before: before, After: after})
+}
+
+// describe summarises what a silence matches and when, for logging
+func describe(silence storage.Silence) string {
+	scope := "everything"
+	switch {
+	case silence.RuleID != nil && silence.MachineID != nil:
+		scope = fmt.Sprintf("rule=%d machine=%d", *silence.RuleID, *silence.MachineID)
+	case silence.RuleID != nil:
+		scope = fmt.Sprintf("rule=%d", *silence.RuleID)
+	case silence.MachineID != nil:
+		scope = fmt.Sprintf("machine=%d", *silence.MachineID)
+	}
+	if silence.Tag != "" {
+		scope += " tag=" + silence.Tag
+	}
+
+	when := "until forever"
+	if silence.EndsAt != nil {
+		when = "until " + silence.EndsAt.Format(time.RFC3339)
+	}
+	if silence.Recurring() {
+		when = fmt.Sprintf("%q for %ds (%s) %s", silence.Schedule, silence.DurationSeconds, silence.Timezone, when)
+	}
+
+	return scope + " " + when
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
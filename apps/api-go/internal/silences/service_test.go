@@ -0,0 +1,192 @@
+package silences
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Constantin-E-T/lunasentri/apps/api-go/internal/storage"
+)
+
+func setupTestService(t *testing.T) (*Service, *storage.SQLiteStore) {
+	t.Helper()
+
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return NewService(store), store
+}
+
+func TestService_Silenced(t *testing.T) {
+	service, store := setupTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	cpu, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 1})
+	mem, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High memory", Metric: "mem_used_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 1})
+	db, _ := store.CreateMachine(ctx, user.ID, "db-1", "db-1", "", "hash-1")
+	web, _ := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash-2")
+	if err := store.SetMachineTags(ctx, db.ID, []string{"database"}); err != nil {
+		t.Fatalf("Failed to tag machine: %v", err)
+	}
+
+	end := now.Add(time.Hour)
+	create := func(silence storage.Silence) {
+		silence.OrgID = user.ID
+		silence.CreatedBy = user.ID
+		silence.StartsAt = now.Add(-time.Minute)
+		silence.EndsAt = &end
+		if _, err := store.CreateSilence(ctx, silence); err != nil {
+			t.Fatalf("Failed to create silence: %v", err)
+		}
+	}
+	create(storage.Silence{RuleID: &cpu.ID, MachineID: &web.ID})
+	create(storage.Silence{Tag: "database"})
+
+	tests := []struct {
+		name   string
+		target Target
+		want   bool
+	}{
+		{"rule on machine", Target{OrgID: user.ID, RuleID: &cpu.ID, MachineID: &web.ID}, true},
+		{"other rule on machine", Target{OrgID: user.ID, RuleID: &mem.ID, MachineID: &web.ID}, false},
+		{"rule on local host", Target{OrgID: user.ID, RuleID: &cpu.ID}, false},
+		{"machine status", Target{OrgID: user.ID, MachineID: &web.ID}, false},
+		{"tagged machine", Target{OrgID: user.ID, RuleID: &mem.ID, MachineID: &db.ID}, true},
+		{"tagged machine status", Target{OrgID: user.ID, MachineID: &db.ID}, true},
+		{"other organization", Target{OrgID: user.ID + 1, RuleID: &cpu.ID, MachineID: &web.ID}, false},
+	}
+	for _, tt := range tests {
+		if got := service.Silenced(ctx, tt.target, now) != nil; got != tt.want {
+			t.Errorf("%s: expected silenced=%v, got %v", tt.name, tt.want, got)
+		}
+	}
+
+	if service.Silenced(ctx, Target{OrgID: user.ID, MachineID: &db.ID}, end.Add(time.Minute)) != nil {
+		t.Error("Expected no silence after it ended")
+	}
+}
+
+func TestService_RecurringSilence(t *testing.T) {
+	service, store := setupTestService(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "db-1", "db-1", "", "hash")
+
+	// Patch night: Tuesdays 02:00-05:00 UTC, starting on 2025-01-07
+	start := time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	if _, err := store.CreateSilence(ctx, storage.Silence{
+		OrgID:           user.ID,
+		MachineID:       &machine.ID,
+		StartsAt:        start,
+		Schedule:        "0 2 * * 2",
+		DurationSeconds: 3 * 3600,
+		CreatedBy:       user.ID,
+	}); err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{start.Add(time.Hour), false},
+		{start.Add(2 * time.Hour), true},
+		{start.Add(4 * time.Hour), true},
+		{start.Add(5 * time.Hour), false},
+		{start.AddDate(0, 0, 28).Add(3 * time.Hour), true},
+		{start.AddDate(0, 0, -7).Add(3 * time.Hour), false}, // before the silence starts
+	}
+	for _, tt := range tests {
+		if got := service.MachineSilenced(ctx, *machine, tt.at); got != tt.want {
+			t.Errorf("At %v: expected silenced=%v, got %v", tt.at, tt.want, got)
+		}
+	}
+}
+
+func TestService_AlertSilenced(t *testing.T) {
+	service, store := setupTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	rule, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 1})
+	machine, _ := store.CreateMachine(ctx, user.ID, "db-1", "db-1", "", "hash-1")
+
+	end := now.Add(time.Hour)
+	if _, err := store.CreateSilence(ctx, storage.Silence{OrgID: user.ID, RuleID: &rule.ID, StartsAt: now.Add(-time.Minute), EndsAt: &end, CreatedBy: user.ID}); err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+
+	if service.AlertSilenced(ctx, *rule, &machine.ID, now) == nil {
+		t.Error("Expected the rule to be silenced on the organization's own machine")
+	}
+	if service.AlertSilenced(ctx, *rule, nil, now) == nil {
+		t.Error("Expected the rule to be silenced on the local host")
+	}
+
+	// Only the rule's organization's silences apply
+	if _, err := store.CreateSilence(ctx, storage.Silence{OrgID: other.ID, StartsAt: now.Add(-time.Minute), EndsAt: &end, CreatedBy: other.ID}); err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+	memory, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High memory", Metric: "mem_used_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 1})
+	if service.AlertSilenced(ctx, *memory, nil, now) != nil {
+		t.Error("Expected another organization's silence not to mute the rule")
+	}
+}
+
+func TestService_Validate(t *testing.T) {
+	service, store := setupTestService(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	rule, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: user.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 1})
+	machine, _ := store.CreateMachine(ctx, user.ID, "db-1", "db-1", "", "hash-1")
+	foreign, _ := store.CreateMachine(ctx, other.ID, "db-2", "db-2", "", "hash-2")
+	foreignRule, _ := store.SaveAlertRule(ctx, storage.AlertRule{OrgID: other.ID, Name: "High CPU", Metric: "cpu_pct", Comparison: "above", ThresholdPct: 90, TriggerAfter: 1})
+
+	end := now.Add(time.Hour)
+	before := now.Add(-time.Hour)
+	missing := 999
+
+	tests := []struct {
+		name    string
+		silence storage.Silence
+		wantErr bool
+	}{
+		{"one-off", storage.Silence{RuleID: &rule.ID, StartsAt: now, EndsAt: &end}, false},
+		{"recurring", storage.Silence{MachineID: &machine.ID, StartsAt: now, Schedule: "0 2 * * 2", DurationSeconds: 3600, Timezone: "Europe/Berlin"}, false},
+		{"tag", storage.Silence{Tag: " Database ", StartsAt: now, EndsAt: &end}, false},
+		{"no end", storage.Silence{StartsAt: now}, true},
+		{"end before start", storage.Silence{StartsAt: now, EndsAt: &before}, true},
+		{"duration without schedule", storage.Silence{StartsAt: now, EndsAt: &end, DurationSeconds: 3600}, true},
+		{"bad schedule", storage.Silence{StartsAt: now, Schedule: "every tuesday", DurationSeconds: 3600}, true},
+		{"short window", storage.Silence{StartsAt: now, Schedule: "0 2 * * 2", DurationSeconds: 10}, true},
+		{"bad timezone", storage.Silence{StartsAt: now, Schedule: "0 2 * * 2", DurationSeconds: 3600, Timezone: "Mars/Olympus"}, true},
+		{"unknown rule", storage.Silence{RuleID: &missing, StartsAt: now, EndsAt: &end}, true},
+		{"foreign rule", storage.Silence{RuleID: &foreignRule.ID, StartsAt: now, EndsAt: &end}, true},
+		{"foreign machine", storage.Silence{MachineID: &foreign.ID, StartsAt: now, EndsAt: &end}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			silence := tt.silence
+			err := service.Validate(ctx, user.ID, &silence)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+			if tt.name == "tag" && silence.Tag != "database" {
+				t.Errorf("Expected tag to be normalized, got %q", silence.Tag)
+			}
+		})
+	}
+}
@@ -30,12 +30,19 @@ func setupTestDB(t *testing.T) *SQLiteStore {
 	return store
 }
 
+// testRule returns a new threshold rule of the organization with the settings the alert service defaults to
+func testRule(orgID int, name, metric, comparison string, thresholdPct float64, triggerAfter int) AlertRule {
+	return AlertRule{OrgID: orgID, Name: name, Metric: metric, Comparison: comparison, ThresholdPct: thresholdPct,
+		TriggerAfter: triggerAfter, Severity: SeverityWarning, ClearAfter: 1, Type: RuleTypeThreshold}
+}
+
 func TestAlertRules_CRUD(t *testing.T) {
 	store := setupTestDB(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Test creating alert rule
-	rule, err := store.CreateAlertRule(ctx, "High CPU", "cpu_pct", "above", 80.0, 3)
+	rule, err := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 80.0, 3))
 	if err != nil {
 		t.Fatalf("Failed to create alert rule: %v", err)
 	}
@@ -60,7 +67,7 @@ func TestAlertRules_CRUD(t *testing.T) {
 	}
 
 	// Test listing alert rules
-	rules, err := store.ListAlertRules(ctx)
+	rules, err := store.ListAlertRules(ctx, user.ID)
 	if err != nil {
 		t.Fatalf("Failed to list alert rules: %v", err)
 	}
@@ -68,8 +75,30 @@ func TestAlertRules_CRUD(t *testing.T) {
 		t.Errorf("Expected 1 rule, got %d", len(rules))
 	}
 
+	// Rules belong to their organization
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+	if rules, _ := store.ListAlertRules(ctx, other.ID); len(rules) != 0 {
+		t.Errorf("Expected another organization to see no rules, got %d", len(rules))
+	}
+	if _, err := store.GetAlertRule(ctx, rule.ID, other.ID); err == nil {
+		t.Error("Expected another organization not to get the rule")
+	}
+	foreign := *rule
+	foreign.OrgID = other.ID
+	if _, err := store.SaveAlertRule(ctx, foreign); err == nil {
+		t.Error("Expected another organization not to update the rule")
+	}
+	if err := store.DeleteAlertRule(ctx, rule.ID, other.ID); err == nil {
+		t.Error("Expected another organization not to delete the rule")
+	}
+	if all, _ := store.ListAllAlertRules(ctx); len(all) != 1 || all[0].OrgID != user.ID {
+		t.Errorf("Expected the rule in its organization among all rules, got %+v", all)
+	}
+
 	// Test updating alert rule
-	updatedRule, err := store.UpdateAlertRule(ctx, rule.ID, "Very High CPU", "cpu_pct", "above", 90.0, 5)
+	changed := *rule
+	changed.Name, changed.ThresholdPct, changed.TriggerAfter = "Very High CPU", 90.0, 5
+	updatedRule, err := store.SaveAlertRule(ctx, changed)
 	if err != nil {
 		t.Fatalf("Failed to update alert rule: %v", err)
 	}
@@ -87,13 +116,13 @@ func TestAlertRules_CRUD(t *testing.T) {
 	}
 
 	// Test deleting alert rule
-	err = store.DeleteAlertRule(ctx, rule.ID)
+	err = store.DeleteAlertRule(ctx, rule.ID, user.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete alert rule: %v", err)
 	}
 
 	// Verify rule is deleted
-	rules, err = store.ListAlertRules(ctx)
+	rules, err = store.ListAlertRules(ctx, user.ID)
 	if err != nil {
 		t.Fatalf("Failed to list alert rules after deletion: %v", err)
 	}
@@ -102,42 +131,80 @@ func TestAlertRules_CRUD(t *testing.T) {
 	}
 
 	// Test deleting non-existent rule
-	err = store.DeleteAlertRule(ctx, 999)
+	err = store.DeleteAlertRule(ctx, 999, user.ID)
 	if err == nil {
 		t.Error("Expected error when deleting non-existent rule")
 	}
 }
 
+func TestAlertRules_Hysteresis(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	rule, err := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 80.0, 3))
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+	if rule.ClearThresholdPct != nil || rule.ClearAfter != 1 || rule.ForSeconds != 0 || rule.ClearForSeconds != 0 {
+		t.Errorf("Expected rule to clear at its threshold after one sample, got %+v", rule)
+	}
+
+	clearPct := 70.0
+	rule.ClearThresholdPct, rule.ClearAfter, rule.ForSeconds, rule.ClearForSeconds = &clearPct, 3, 120, 300
+	if _, err := store.SaveAlertRule(ctx, *rule); err != nil {
+		t.Fatalf("Failed to update hysteresis: %v", err)
+	}
+
+	rules, err := store.ListAlertRules(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to list alert rules: %v", err)
+	}
+	got := rules[0]
+	if got.ClearThresholdPct == nil || *got.ClearThresholdPct != 70.0 || got.ClearAfter != 3 || got.ForSeconds != 120 || got.ClearForSeconds != 300 {
+		t.Errorf("Unexpected hysteresis: %+v", got)
+	}
+
+	rule.ID = 999
+	if _, err := store.SaveAlertRule(ctx, *rule); err == nil {
+		t.Error("Expected error when updating non-existent rule")
+	}
+}
+
 func TestAlertRules_ValidationConstraints(t *testing.T) {
 	store := setupTestDB(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Test invalid metric
-	_, err := store.CreateAlertRule(ctx, "Test", "invalid_metric", "above", 50.0, 1)
+	_, err := store.SaveAlertRule(ctx, testRule(user.ID, "Test", "invalid_metric", "above", 50.0, 1))
 	if err == nil {
 		t.Error("Expected error for invalid metric")
 	}
 
 	// Test invalid comparison
-	_, err = store.CreateAlertRule(ctx, "Test", "cpu_pct", "invalid_comparison", 50.0, 1)
+	_, err = store.SaveAlertRule(ctx, testRule(user.ID, "Test", "cpu_pct", "invalid_comparison", 50.0, 1))
 	if err == nil {
 		t.Error("Expected error for invalid comparison")
 	}
 
-	// Test invalid threshold (negative)
-	_, err = store.CreateAlertRule(ctx, "Test", "cpu_pct", "above", -1.0, 1)
+	// Test invalid threshold (< -100); rate rules may use negative thresholds down to -100
+	_, err = store.SaveAlertRule(ctx, testRule(user.ID, "Test", "cpu_pct", "above", -101.0, 1))
 	if err == nil {
-		t.Error("Expected error for negative threshold")
+		t.Error("Expected error for threshold < -100")
+	}
+	if _, err := store.SaveAlertRule(ctx, testRule(user.ID, "Falling", "cpu_pct", "below", -5.0, 1)); err != nil {
+		t.Errorf("Expected a negative threshold to be accepted: %v", err)
 	}
 
 	// Test invalid threshold (> 100)
-	_, err = store.CreateAlertRule(ctx, "Test", "cpu_pct", "above", 101.0, 1)
+	_, err = store.SaveAlertRule(ctx, testRule(user.ID, "Test", "cpu_pct", "above", 101.0, 1))
 	if err == nil {
 		t.Error("Expected error for threshold > 100")
 	}
 
 	// Test invalid trigger_after (< 1)
-	_, err = store.CreateAlertRule(ctx, "Test", "cpu_pct", "above", 50.0, 0)
+	_, err = store.SaveAlertRule(ctx, testRule(user.ID, "Test", "cpu_pct", "above", 50.0, 0))
 	if err == nil {
 		t.Error("Expected error for trigger_after < 1")
 	}
@@ -146,15 +213,16 @@ func TestAlertRules_ValidationConstraints(t *testing.T) {
 func TestAlertEvents_CRUD(t *testing.T) {
 	store := setupTestDB(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create a rule first
-	rule, err := store.CreateAlertRule(ctx, "High Memory", "mem_used_pct", "above", 85.0, 2)
+	rule, err := store.SaveAlertRule(ctx, testRule(user.ID, "High Memory", "mem_used_pct", "above", 85.0, 2))
 	if err != nil {
 		t.Fatalf("Failed to create alert rule: %v", err)
 	}
 
 	// Test creating alert event
-	event, err := store.CreateAlertEvent(ctx, rule.ID, 87.5)
+	event, err := store.CreateAlertEvent(ctx, rule.ID, nil, 87.5)
 	if err != nil {
 		t.Fatalf("Failed to create alert event: %v", err)
 	}
@@ -221,19 +289,20 @@ func TestAlertEvents_CRUD(t *testing.T) {
 func TestAlertEvents_CascadeDelete(t *testing.T) {
 	store := setupTestDB(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create a rule
-	rule, err := store.CreateAlertRule(ctx, "Test Rule", "cpu_pct", "above", 50.0, 1)
+	rule, err := store.SaveAlertRule(ctx, testRule(user.ID, "Test Rule", "cpu_pct", "above", 50.0, 1))
 	if err != nil {
 		t.Fatalf("Failed to create alert rule: %v", err)
 	}
 
 	// Create multiple events for the rule
-	_, err = store.CreateAlertEvent(ctx, rule.ID, 60.0)
+	_, err = store.CreateAlertEvent(ctx, rule.ID, nil, 60.0)
 	if err != nil {
 		t.Fatalf("Failed to create alert event 1: %v", err)
 	}
-	_, err = store.CreateAlertEvent(ctx, rule.ID, 70.0)
+	_, err = store.CreateAlertEvent(ctx, rule.ID, nil, 70.0)
 	if err != nil {
 		t.Fatalf("Failed to create alert event 2: %v", err)
 	}
@@ -248,7 +317,7 @@ func TestAlertEvents_CascadeDelete(t *testing.T) {
 	}
 
 	// Delete the rule
-	err = store.DeleteAlertRule(ctx, rule.ID)
+	err = store.DeleteAlertRule(ctx, rule.ID, user.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete alert rule: %v", err)
 	}
@@ -266,33 +335,34 @@ func TestAlertEvents_CascadeDelete(t *testing.T) {
 func TestAlertEvents_OrderingAndLimit(t *testing.T) {
 	store := setupTestDB(t)
 	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
 
 	// Create rules
-	rule1, err := store.CreateAlertRule(ctx, "Rule 1", "cpu_pct", "above", 50.0, 1)
+	rule1, err := store.SaveAlertRule(ctx, testRule(user.ID, "Rule 1", "cpu_pct", "above", 50.0, 1))
 	if err != nil {
 		t.Fatalf("Failed to create rule 1: %v", err)
 	}
-	rule2, err := store.CreateAlertRule(ctx, "Rule 2", "mem_used_pct", "above", 80.0, 1)
+	rule2, err := store.SaveAlertRule(ctx, testRule(user.ID, "Rule 2", "mem_used_pct", "above", 80.0, 1))
 	if err != nil {
 		t.Fatalf("Failed to create rule 2: %v", err)
 	}
 
 	// Create events with different timestamps
-	event1, err := store.CreateAlertEvent(ctx, rule1.ID, 60.0)
+	event1, err := store.CreateAlertEvent(ctx, rule1.ID, nil, 60.0)
 	if err != nil {
 		t.Fatalf("Failed to create event 1: %v", err)
 	}
 
 	time.Sleep(10 * time.Millisecond) // Ensure different timestamps
 
-	event2, err := store.CreateAlertEvent(ctx, rule2.ID, 85.0)
+	event2, err := store.CreateAlertEvent(ctx, rule2.ID, nil, 85.0)
 	if err != nil {
 		t.Fatalf("Failed to create event 2: %v", err)
 	}
 
 	time.Sleep(10 * time.Millisecond)
 
-	event3, err := store.CreateAlertEvent(ctx, rule1.ID, 70.0)
+	event3, err := store.CreateAlertEvent(ctx, rule1.ID, nil, 70.0)
 	if err != nil {
 		t.Fatalf("Failed to create event 3: %v", err)
 	}
@@ -333,3 +403,89 @@ func TestAlertEvents_OrderingAndLimit(t *testing.T) {
 		t.Errorf("Expected 2 events with limit, got %d", len(limitedEvents))
 	}
 }
+
+func TestAlertEvents_Query(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	cpu, _ := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 80, 1))
+	critical := testRule(user.ID, "High memory", "mem_used_pct", "above", 80, 1)
+	critical.Severity = SeverityCritical
+	mem, _ := store.SaveAlertRule(ctx, critical)
+
+	var ids []int
+	for i := 0; i < 5; i++ {
+		ruleID, machineID := cpu.ID, &machine.ID
+		if i%2 == 1 {
+			ruleID, machineID = mem.ID, nil
+		}
+		event, err := store.CreateAlertEvent(ctx, ruleID, machineID, 90)
+		if err != nil {
+			t.Fatalf("Failed to create event: %v", err)
+		}
+		ids = append(ids, event.ID)
+	}
+	store.AckAlertEvent(ctx, ids[4])
+	store.ResolveAlertEvent(ctx, ids[0])
+
+	// Pages follow the listing order, newest first
+	var got []int
+	filter := AlertEventFilter{Limit: 2}
+	for pages := 0; ; pages++ {
+		events, next, err := store.QueryAlertEvents(ctx, filter)
+		if err != nil {
+			t.Fatalf("Failed to query events: %v", err)
+		}
+		for _, e := range events {
+			got = append(got, e.ID)
+		}
+		if next == nil {
+			if pages != 2 {
+				t.Errorf("Expected 3 pages, got %d", pages+1)
+			}
+			break
+		}
+		filter.After = next
+	}
+	want := []int{ids[4], ids[3], ids[2], ids[1], ids[0]}
+	if len(got) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected events %v, got %v", want, got)
+		}
+	}
+
+	resolved, open, acknowledged := true, false, true
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		filter AlertEventFilter
+		want   int
+	}{
+		{"rule", AlertEventFilter{RuleID: &cpu.ID}, 3},
+		{"machine", AlertEventFilter{MachineID: &machine.ID}, 3},
+		{"resolved", AlertEventFilter{Resolved: &resolved}, 1},
+		{"open", AlertEventFilter{Resolved: &open}, 4},
+		{"acknowledged", AlertEventFilter{Acknowledged: &acknowledged}, 1},
+		{"severity", AlertEventFilter{Severity: SeverityCritical}, 2},
+		{"from", AlertEventFilter{From: &future}, 0},
+		{"to", AlertEventFilter{To: &future}, 5},
+		{"combined", AlertEventFilter{RuleID: &cpu.ID, Resolved: &open, Acknowledged: &acknowledged}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Limit = 10
+			events, next, err := store.QueryAlertEvents(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to query events: %v", err)
+			}
+			if len(events) != tt.want || next != nil {
+				t.Errorf("Expected %d events and no next page, got %d (next %v)", tt.want, len(events), next)
+			}
+		})
+	}
+}
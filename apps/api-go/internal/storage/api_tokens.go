@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIToken is a long-lived credential that lets scripts call the API as the user who created it,
+// limited to the token's scopes
+type APIToken struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"` // start of the token, to tell tokens apart
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // nil if the token does not expire
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP  string     `json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	TokenHash   string     `json:"-"`
+}
+
+// apiTokenColumns are the api_tokens columns in scan order
+const apiTokenColumns = `id, user_id, name, token_prefix, scopes, created_at, expires_at, last_used_at, last_used_ip,
+	revoked_at, token_hash`
+
+// scanAPIToken scans a row of apiTokenColumns
+func scanAPIToken(row interface{ Scan(...any) error }) (*APIToken, error) {
+	token := &APIToken{}
+	var scopes string
+	err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.TokenPrefix, &scopes, &token.CreatedAt,
+		&token.ExpiresAt, &token.LastUsedAt, &token.LastUsedIP, &token.RevokedAt, &token.TokenHash)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = strings.Fields(scopes)
+	return token, nil
+}
+
+// CreateAPIToken stores a new API token
+func (s *SQLiteStore) CreateAPIToken(ctx context.Context, token APIToken) (*APIToken, error) {
+	query := `
+		INSERT INTO api_tokens (user_id, name, token_prefix, scopes, created_at, expires_at, token_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + apiTokenColumns
+
+	created, err := scanAPIToken(s.db.QueryRowContext(ctx, query, token.UserID, token.Name, token.TokenPrefix,
+		strings.Join(token.Scopes, " "), time.Now().UTC(), token.ExpiresAt, token.TokenHash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetAPITokenByHash returns the API token with the given hash, including revoked and expired ones
+func (s *SQLiteStore) GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error) {
+	token, err := scanAPIToken(s.db.QueryRowContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = ?`, tokenHash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAPITokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+
+	return token, nil
+}
+
+// ListAPITokens returns a user's API tokens that have not been revoked, newest first
+func (s *SQLiteStore) ListAPITokens(ctx context.Context, userID int) ([]APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + `
+		FROM api_tokens
+		WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		tokens = append(tokens, *token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate API tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes one of a user's API tokens, returning ErrAPITokenNotFound if the user
+// has no such token that is still active
+func (s *SQLiteStore) RevokeAPIToken(ctx context.Context, id, userID int) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API token: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify API token revocation: %w", err)
+	}
+	if rows == 0 {
+		return ErrAPITokenNotFound
+	}
+
+	return nil
+}
+
+// TouchAPIToken records that an API token was used and from where
+func (s *SQLiteStore) TouchAPIToken(ctx context.Context, id int, ipAddress string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ?, last_used_ip = ? WHERE id = ?`,
+		time.Now().UTC(), ipAddress, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch API token: %w", err)
+	}
+
+	return nil
+}
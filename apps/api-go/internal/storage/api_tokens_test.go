@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAPITokens(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice@example.com", "hash")
+	bob, _ := store.CreateUser(ctx, "bob@example.com", "hash")
+	expires := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	ci, err := store.CreateAPIToken(ctx, APIToken{UserID: alice.ID, Name: "CI", TokenPrefix: "lst_abcd1234",
+		Scopes: []string{"alerts:write", "read"}, ExpiresAt: &expires, TokenHash: "hash-ci"})
+	if err != nil {
+		t.Fatalf("Failed to create API token: %v", err)
+	}
+	if ci.Name != "CI" || len(ci.Scopes) != 2 || ci.Scopes[0] != "alerts:write" || ci.ExpiresAt == nil || !ci.ExpiresAt.Equal(expires) {
+		t.Errorf("Unexpected created token: %+v", ci)
+	}
+	store.CreateAPIToken(ctx, APIToken{UserID: alice.ID, Name: "Terraform", TokenPrefix: "lst_efgh5678", Scopes: []string{"read"}, TokenHash: "hash-tf"})
+	store.CreateAPIToken(ctx, APIToken{UserID: bob.ID, Name: "Bob", TokenPrefix: "lst_ijkl9012", Scopes: []string{"read"}, TokenHash: "hash-bob"})
+
+	found, err := store.GetAPITokenByHash(ctx, "hash-ci")
+	if err != nil || found.ID != ci.ID {
+		t.Fatalf("Expected to find token %d by hash, got %+v, %v", ci.ID, found, err)
+	}
+	if _, err := store.GetAPITokenByHash(ctx, "unknown"); err != ErrAPITokenNotFound {
+		t.Errorf("Expected ErrAPITokenNotFound, got %v", err)
+	}
+
+	if err := store.TouchAPIToken(ctx, ci.ID, "10.0.0.1"); err != nil {
+		t.Fatalf("Failed to touch API token: %v", err)
+	}
+	found, _ = store.GetAPITokenByHash(ctx, "hash-ci")
+	if found.LastUsedAt == nil || found.LastUsedIP != "10.0.0.1" {
+		t.Errorf("Expected last use to be recorded, got %+v", found)
+	}
+
+	// Users can only revoke their own tokens, and revoked tokens are not listed
+	if err := store.RevokeAPIToken(ctx, ci.ID, bob.ID); err != ErrAPITokenNotFound {
+		t.Errorf("Expected ErrAPITokenNotFound revoking another user's token, got %v", err)
+	}
+	if err := store.RevokeAPIToken(ctx, ci.ID, alice.ID); err != nil {
+		t.Fatalf("Failed to revoke API token: %v", err)
+	}
+	if err := store.RevokeAPIToken(ctx, ci.ID, alice.ID); err != ErrAPITokenNotFound {
+		t.Errorf("Expected ErrAPITokenNotFound revoking a revoked token, got %v", err)
+	}
+
+	tokens, err := store.ListAPITokens(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to list API tokens: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].Name != "Terraform" {
+		t.Errorf("Expected only the Terraform token, got %+v", tokens)
+	}
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Audit log actor types
+const (
+	ActorUser      = "user"      // a user, with a browser session or an API token
+	ActorMachine   = "machine"   // a monitoring agent using its API key
+	ActorAnonymous = "anonymous" // an unauthenticated request, such as a failed login
+	ActorSystem    = "system"    // the server itself
+)
+
+// AuditEntry records an action: who took it, on what, and what it changed
+type AuditEntry struct {
+	ID         int             `json:"id"`
+	OrgID      *int            `json:"org_id,omitempty"` // organization owning the target, nil for system-wide targets
+	ActorType  string          `json:"actor_type"`
+	ActorID    *int            `json:"actor_id,omitempty"` // user or machine ID
+	ActorEmail string          `json:"actor_email,omitempty"`
+	APITokenID *int            `json:"api_token_id,omitempty"` // API token the user acted with
+	Action     string          `json:"action"`                 // e.g. "machine.delete"
+	TargetType string          `json:"target_type"`            // e.g. "machine"
+	TargetID   string          `json:"target_id,omitempty"`
+	Changes    json.RawMessage `json:"changes,omitempty"` // changed fields with their values before and after
+	IPAddress  string          `json:"ip_address,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditFilter selects audit entries; zero fields match every entry
+type AuditFilter struct {
+	OrgID      *int
+	ActorID    *int
+	Action     string // exact action, or every action starting with it when it ends in "."
+	TargetType string
+	TargetID   string
+	From       *time.Time // created at or after
+	To         *time.Time // created before
+	BeforeID   int        // continue after the entry with this ID
+	Limit      int        // 0 for no limit
+}
+
+// auditColumns are the audit_log columns in scan order
+const auditColumns = `id, org_id, actor_type, actor_id, actor_email, api_token_id, action, target_type, target_id,
+	changes, ip_address, created_at`
+
+// auditFields returns the scan destinations for auditColumns
+func auditFields(entry *AuditEntry, changes *[]byte) []any {
+	return []any{&entry.ID, &entry.OrgID, &entry.ActorType, &entry.ActorID, &entry.ActorEmail, &entry.APITokenID,
+		&entry.Action, &entry.TargetType, &entry.TargetID, changes, &entry.IPAddress, &entry.CreatedAt}
+}
+
+// CreateAuditEntry appends an entry to the audit log
+func (s *SQLiteStore) CreateAuditEntry(ctx context.Context, entry AuditEntry) (*AuditEntry, error) {
+	query := `
+		INSERT INTO audit_log (org_id, actor_type, actor_id, actor_email, api_token_id, action, target_type, target_id,
+			changes, ip_address, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + auditColumns
+
+	var changes any
+	if len(entry.Changes) > 0 {
+		changes = string(entry.Changes)
+	}
+
+	created := &AuditEntry{}
+	var createdChanges []byte
+	err := s.db.QueryRowContext(ctx, query, entry.OrgID, entry.ActorType, entry.ActorID, entry.ActorEmail,
+		entry.APITokenID, entry.Action, entry.TargetType, entry.TargetID, changes, entry.IPAddress,
+		time.Now().UTC()).Scan(auditFields(created, &createdChanges)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audit entry: %w", err)
+	}
+	if len(createdChanges) > 0 {
+		created.Changes = createdChanges
+	}
+
+	return created, nil
+}
+
+// ListAuditEntries returns the audit entries matching the filter, newest first
+func (s *SQLiteStore) ListAuditEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	var conditions []string
+	var args []any
+
+	if filter.OrgID != nil {
+		conditions = append(conditions, "org_id = ?")
+		args = append(args, *filter.OrgID)
+	}
+	if filter.ActorID != nil {
+		conditions = append(conditions, "actor_id = ? AND actor_type = ?")
+		args = append(args, *filter.ActorID, ActorUser)
+	}
+	if strings.HasSuffix(filter.Action, ".") {
+		conditions = append(conditions, "substr(action, 1, ?) = ?")
+		args = append(args, len(filter.Action), filter.Action)
+	} else if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	query += ` ORDER BY id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var changes []byte
+		if err := rows.Scan(auditFields(&entry, &changes)...); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if len(changes) > 0 {
+			entry.Changes = changes
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit entries: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	userID, orgID := 1, 7
+
+	entries := []AuditEntry{
+		{ActorType: ActorUser, ActorID: &userID, ActorEmail: "alice@example.com", Action: "user.create", TargetType: "user", TargetID: "2"},
+		{OrgID: &orgID, ActorType: ActorUser, ActorID: &userID, Action: "machine.update", TargetType: "machine", TargetID: "5",
+			Changes: json.RawMessage(`{"name":{"before":"web-1","after":"web-2"}}`), IPAddress: "203.0.113.7"},
+		{OrgID: &orgID, ActorType: ActorSystem, Action: "machine.delete", TargetType: "machine", TargetID: "5"},
+	}
+	for _, entry := range entries {
+		created, err := store.CreateAuditEntry(ctx, entry)
+		if err != nil {
+			t.Fatalf("Failed to create audit entry: %v", err)
+		}
+		if created.ID == 0 || created.CreatedAt.IsZero() || created.Action != entry.Action {
+			t.Errorf("Unexpected created entry %+v", created)
+		}
+	}
+
+	all, err := store.ListAuditEntries(ctx, AuditFilter{})
+	if err != nil {
+		t.Fatalf("Failed to list audit entries: %v", err)
+	}
+	if len(all) != 3 || all[0].Action != "machine.delete" {
+		t.Fatalf("Expected 3 entries, newest first, got %+v", all)
+	}
+	if string(all[1].Changes) != `{"name":{"before":"web-1","after":"web-2"}}` || all[1].IPAddress != "203.0.113.7" {
+		t.Errorf("Unexpected changes %s", all[1].Changes)
+	}
+	if all[0].Changes != nil || all[0].ActorID != nil {
+		t.Errorf("Expected no changes or actor ID, got %+v", all[0])
+	}
+
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   int
+	}{
+		{"organization", AuditFilter{OrgID: &orgID}, 2},
+		{"actor", AuditFilter{ActorID: &userID}, 2},
+		{"action", AuditFilter{Action: "machine.delete"}, 1},
+		{"action prefix", AuditFilter{Action: "machine."}, 2},
+		{"target", AuditFilter{TargetType: "machine", TargetID: "5"}, 2},
+		{"from", AuditFilter{From: &future}, 0},
+		{"to", AuditFilter{To: &future}, 3},
+		{"page", AuditFilter{BeforeID: all[0].ID, Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		got, err := store.ListAuditEntries(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to list audit entries: %v", tt.name, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("%s: expected %d entries, got %d", tt.name, tt.want, len(got))
+		}
+	}
+
+	// Entries can be neither changed nor deleted
+	if _, err := store.db.ExecContext(ctx, `UPDATE audit_log SET action = 'x'`); err == nil {
+		t.Error("Expected updating the audit log to fail")
+	}
+	if _, err := store.db.ExecContext(ctx, `DELETE FROM audit_log`); err == nil {
+		t.Error("Expected deleting from the audit log to fail")
+	}
+}
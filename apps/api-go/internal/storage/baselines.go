@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MetricBaseline is the running mean and variance of one machine metric in one seasonal slot,
+// learned by anomaly rules
+type MetricBaseline struct {
+	MachineID   int       `json:"machine_id"`
+	Metric      string    `json:"metric"`
+	Seasonality string    `json:"seasonality"` // "none" | "daily" | "weekly"
+	Slot        int       `json:"slot"`        // hour of the day or week the baseline covers, 0 without seasonality
+	Samples     int       `json:"samples"`     // samples learned so far
+	Mean        float64   `json:"mean"`
+	Variance    float64   `json:"variance"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GetMetricBaseline returns the baseline of a machine metric in a seasonal slot,
+// or ErrMetricBaselineNotFound when nothing has been learned yet
+func (s *SQLiteStore) GetMetricBaseline(ctx context.Context, machineID int, metric, seasonality string, slot int) (*MetricBaseline, error) {
+	query := `
+		SELECT machine_id, metric, seasonality, slot, samples, mean, variance, updated_at
+		FROM metric_baselines
+		WHERE machine_id = ? AND metric = ? AND seasonality = ? AND slot = ?`
+
+	var b MetricBaseline
+	err := s.db.QueryRowContext(ctx, query, machineID, metric, seasonality, slot).Scan(
+		&b.MachineID, &b.Metric, &b.Seasonality, &b.Slot, &b.Samples, &b.Mean, &b.Variance, &b.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMetricBaselineNotFound
+		}
+		return nil, fmt.Errorf("failed to get metric baseline: %w", err)
+	}
+
+	return &b, nil
+}
+
+// UpsertMetricBaseline stores the baseline of a machine metric in a seasonal slot
+func (s *SQLiteStore) UpsertMetricBaseline(ctx context.Context, baseline MetricBaseline) error {
+	query := `
+		INSERT INTO metric_baselines (machine_id, metric, seasonality, slot, samples, mean, variance, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(machine_id, metric, seasonality, slot) DO UPDATE SET
+			samples = excluded.samples,
+			mean = excluded.mean,
+			variance = excluded.variance,
+			updated_at = excluded.updated_at`
+
+	_, err := s.db.ExecContext(ctx, query, baseline.MachineID, baseline.Metric, baseline.Seasonality, baseline.Slot,
+		baseline.Samples, baseline.Mean, baseline.Variance, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to upsert metric baseline: %w", err)
+	}
+
+	return nil
+}
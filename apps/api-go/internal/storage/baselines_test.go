@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMetricBaselines(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, err := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+
+	if _, err := store.GetMetricBaseline(ctx, machine.ID, "cpu_pct", SeasonalityDaily, 14); !errors.Is(err, ErrMetricBaselineNotFound) {
+		t.Fatalf("Expected ErrMetricBaselineNotFound, got %v", err)
+	}
+
+	baseline := MetricBaseline{MachineID: machine.ID, Metric: "cpu_pct", Seasonality: SeasonalityDaily, Slot: 14, Samples: 1, Mean: 20}
+	if err := store.UpsertMetricBaseline(ctx, baseline); err != nil {
+		t.Fatalf("Failed to insert baseline: %v", err)
+	}
+	baseline.Samples, baseline.Mean, baseline.Variance = 2, 21, 1
+	if err := store.UpsertMetricBaseline(ctx, baseline); err != nil {
+		t.Fatalf("Failed to update baseline: %v", err)
+	}
+
+	got, err := store.GetMetricBaseline(ctx, machine.ID, "cpu_pct", SeasonalityDaily, 14)
+	if err != nil {
+		t.Fatalf("Failed to get baseline: %v", err)
+	}
+	if got.Samples != 2 || got.Mean != 21 || got.Variance != 1 {
+		t.Errorf("Expected the updated baseline, got %+v", got)
+	}
+
+	// Other slots and seasonalities are separate
+	if _, err := store.GetMetricBaseline(ctx, machine.ID, "cpu_pct", SeasonalityWeekly, 14); !errors.Is(err, ErrMetricBaselineNotFound) {
+		t.Errorf("Expected no weekly baseline, got %v", err)
+	}
+
+	// Baselines are removed with their machine
+	if err := store.DeleteMachine(ctx, machine.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete machine: %v", err)
+	}
+	if _, err := store.GetMetricBaseline(ctx, machine.ID, "cpu_pct", SeasonalityDaily, 14); !errors.Is(err, ErrMetricBaselineNotFound) {
+		t.Errorf("Expected the baseline to be deleted with the machine, got %v", err)
+	}
+}
+
+func TestAlertEvents_ExpectedRange(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	rule, _ := store.SaveAlertRule(ctx, testRule(user.ID, "CPU spike", "cpu_pct", "above", 3, 1))
+	event, err := store.CreateAlertEvent(ctx, rule.ID, nil, 80)
+	if err != nil {
+		t.Fatalf("Failed to create alert event: %v", err)
+	}
+	if event.ExpectedLow != nil || event.ExpectedHigh != nil {
+		t.Error("Expected no expected range on a new event")
+	}
+
+	event, err = store.SetAlertEventExpectedRange(ctx, event.ID, 14.5, 26)
+	if err != nil {
+		t.Fatalf("Failed to set expected range: %v", err)
+	}
+	if event.ExpectedLow == nil || *event.ExpectedLow != 14.5 || event.ExpectedHigh == nil || *event.ExpectedHigh != 26 {
+		t.Errorf("Expected range 14.5-26, got %v-%v", event.ExpectedLow, event.ExpectedHigh)
+	}
+
+	if _, err := store.SetAlertEventExpectedRange(ctx, 999, 0, 1); err == nil {
+		t.Error("Expected an error for a missing event")
+	}
+}
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EscalationStep pages a set of destinations when an alert event is still unacknowledged
+type EscalationStep struct {
+	WaitSeconds          int   `json:"wait_seconds"` // delay after the previous step, or after the event fired for the first step
+	WebhookIDs           []int `json:"webhook_ids"`
+	TelegramRecipientIDs []int `json:"telegram_recipient_ids"`
+}
+
+// EscalationPolicy is an ordered list of steps that page further destinations until an alert
+// event is acknowledged or resolved. Policies are attached to alert rules.
+type EscalationPolicy struct {
+	ID        int              `json:"id"`
+	OrgID     int              `json:"org_id"`
+	Name      string           `json:"name"`
+	Steps     []EscalationStep `json:"steps"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// PendingEscalation is a firing, unacknowledged alert event whose rule has an escalation policy
+type PendingEscalation struct {
+	Rule            AlertRule
+	Event           AlertEvent
+	Policy          EscalationPolicy
+	Step            int       // number of policy steps already paged
+	LastEscalatedAt time.Time // when the last step was paged, or when the event fired if none has been
+}
+
+// escalationPolicyColumns are the escalation_policies columns in scan order
+const escalationPolicyColumns = `id, org_id, name, steps, created_at, updated_at`
+
+// scanEscalationPolicy scans an escalation policy row, decoding its steps
+func scanEscalationPolicy(row interface{ Scan(...any) error }) (*EscalationPolicy, error) {
+	var policy EscalationPolicy
+	var steps string
+
+	if err := row.Scan(&policy.ID, &policy.OrgID, &policy.Name, &steps, &policy.CreatedAt, &policy.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := decodeEscalationSteps(&policy, steps); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// decodeEscalationSteps decodes the JSON steps column into policy
+func decodeEscalationSteps(policy *EscalationPolicy, steps string) error {
+	if err := json.Unmarshal([]byte(steps), &policy.Steps); err != nil {
+		return fmt.Errorf("failed to decode escalation policy %d: %w", policy.ID, err)
+	}
+	for i := range policy.Steps {
+		if policy.Steps[i].WebhookIDs == nil {
+			policy.Steps[i].WebhookIDs = []int{}
+		}
+		if policy.Steps[i].TelegramRecipientIDs == nil {
+			policy.Steps[i].TelegramRecipientIDs = []int{}
+		}
+	}
+	return nil
+}
+
+// encodeEscalationSteps encodes a policy's steps for storage, storing nil lists as empty arrays
+func encodeEscalationSteps(policy EscalationPolicy) (string, error) {
+	steps := make([]EscalationStep, len(policy.Steps))
+	for i, step := range policy.Steps {
+		if step.WebhookIDs == nil {
+			step.WebhookIDs = []int{}
+		}
+		if step.TelegramRecipientIDs == nil {
+			step.TelegramRecipientIDs = []int{}
+		}
+		steps[i] = step
+	}
+
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode escalation steps: %w", err)
+	}
+	return string(data), nil
+}
+
+// ListEscalationPolicies returns an organization's escalation policies
+func (s *SQLiteStore) ListEscalationPolicies(ctx context.Context, orgID int) ([]EscalationPolicy, error) {
+	query := `SELECT ` + escalationPolicyColumns + ` FROM escalation_policies WHERE org_id = ? ORDER BY id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list escalation policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []EscalationPolicy{}
+	for rows.Next() {
+		policy, err := scanEscalationPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan escalation policy: %w", err)
+		}
+		policies = append(policies, *policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate escalation policies: %w", err)
+	}
+
+	return policies, nil
+}
+
+// GetEscalationPolicy retrieves a single escalation policy for a user
+func (s *SQLiteStore) GetEscalationPolicy(ctx context.Context, id int, orgID int) (*EscalationPolicy, error) {
+	query := `SELECT ` + escalationPolicyColumns + ` FROM escalation_policies WHERE id = ? AND org_id = ?`
+
+	policy, err := scanEscalationPolicy(s.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("escalation policy with id %d not found for organization %d", id, orgID)
+		}
+		return nil, fmt.Errorf("failed to get escalation policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// CreateEscalationPolicy creates an escalation policy for policy.OrgID
+func (s *SQLiteStore) CreateEscalationPolicy(ctx context.Context, policy EscalationPolicy) (*EscalationPolicy, error) {
+	steps, err := encodeEscalationSteps(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO escalation_policies (org_id, name, steps, created_at, updated_at)
+              VALUES (?, ?, ?, ?, ?)
+              RETURNING ` + escalationPolicyColumns
+
+	created, err := scanEscalationPolicy(s.db.QueryRowContext(ctx, query, policy.OrgID, policy.Name, steps, now, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create escalation policy: %w", err)
+	}
+
+	return created, nil
+}
+
+// UpdateEscalationPolicy replaces an existing escalation policy owned by policy.OrgID
+func (s *SQLiteStore) UpdateEscalationPolicy(ctx context.Context, policy EscalationPolicy) (*EscalationPolicy, error) {
+	steps, err := encodeEscalationSteps(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE escalation_policies
+              SET name = ?, steps = ?, updated_at = ?
+              WHERE id = ? AND org_id = ?
+              RETURNING ` + escalationPolicyColumns
+
+	updated, err := scanEscalationPolicy(s.db.QueryRowContext(ctx, query, policy.Name, steps, time.Now(), policy.ID, policy.OrgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("escalation policy with id %d not found for organization %d", policy.ID, policy.OrgID)
+		}
+		return nil, fmt.Errorf("failed to update escalation policy: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteEscalationPolicy deletes an organization's escalation policy. Rules using it are left without one.
+func (s *SQLiteStore) DeleteEscalationPolicy(ctx context.Context, id int, orgID int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM escalation_policies WHERE id = ? AND org_id = ?`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete escalation policy: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify deletion: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("escalation policy with id %d not found for organization %d", id, orgID)
+	}
+
+	return nil
+}
+
+// ListPendingEscalations returns every firing alert event that is neither acknowledged nor resolved
+// and was triggered after its rule's escalation policy was attached, oldest first
+func (s *SQLiteStore) ListPendingEscalations(ctx context.Context) ([]PendingEscalation, error) {
+	query := `
+		SELECT r.id, r.org_id, r.name, r.metric, r.threshold_pct, r.comparison, r.trigger_after, r.severity, r.escalation_policy_id,
+		       r.clear_threshold_pct, r.clear_after, r.for_seconds, r.clear_for_seconds, r.type, r.window_seconds, r.horizon_seconds,
+		       r.seasonality, r.expression, r.created_at, r.updated_at,
+		       e.id, e.rule_id, e.triggered_at, e.value, e.acknowledged, e.acknowledged_at, e.resolved_at, e.severity, e.machine_id,
+		       e.expected_low, e.expected_high,
+		       p.id, p.org_id, p.name, p.steps, p.created_at, p.updated_at,
+		       COALESCE(x.step, 0), x.escalated_at, r.escalation_policy_attached_at
+		FROM alert_events e
+		JOIN alert_rules r ON r.id = e.rule_id
+		JOIN escalation_policies p ON p.id = r.escalation_policy_id
+		LEFT JOIN alert_escalations x ON x.event_id = e.id
+		WHERE e.acknowledged = 0 AND e.resolved_at IS NULL
+		ORDER BY e.triggered_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending escalations: %w", err)
+	}
+	defer rows.Close()
+
+	pending := []PendingEscalation{}
+	for rows.Next() {
+		var p PendingEscalation
+		var steps string
+		var escalatedAt, attachedAt *time.Time
+
+		fields := append(alertRuleFields(&p.Rule), alertEventFields(&p.Event)...)
+		fields = append(fields,
+			&p.Policy.ID, &p.Policy.OrgID, &p.Policy.Name, &steps, &p.Policy.CreatedAt, &p.Policy.UpdatedAt,
+			&p.Step, &escalatedAt, &attachedAt)
+		if err := rows.Scan(fields...); err != nil {
+			return nil, fmt.Errorf("failed to scan pending escalation: %w", err)
+		}
+		// Events that were already open when the policy was attached don't escalate
+		if attachedAt == nil || p.Event.TriggeredAt.Before(*attachedAt) {
+			continue
+		}
+		if err := decodeEscalationSteps(&p.Policy, steps); err != nil {
+			return nil, err
+		}
+
+		p.LastEscalatedAt = p.Event.TriggeredAt
+		if escalatedAt != nil {
+			p.LastEscalatedAt = *escalatedAt
+		}
+		pending = append(pending, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate pending escalations: %w", err)
+	}
+
+	return pending, nil
+}
+
+// RecordAlertEscalation records how many escalation steps have been paged for an alert event
+func (s *SQLiteStore) RecordAlertEscalation(ctx context.Context, eventID int, step int, escalatedAt time.Time) error {
+	query := `
+		INSERT INTO alert_escalations (event_id, step, escalated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(event_id) DO UPDATE SET step = excluded.step, escalated_at = excluded.escalated_at
+	`
+
+	if _, err := s.db.ExecContext(ctx, query, eventID, step, escalatedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to record alert escalation: %w", err)
+	}
+
+	return nil
+}
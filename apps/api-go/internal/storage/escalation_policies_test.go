@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEscalationPolicies_CRUD(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "oncall@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	policy, err := store.CreateEscalationPolicy(ctx, EscalationPolicy{
+		OrgID: user.ID,
+		Name:  "Secondary on-call",
+		Steps: []EscalationStep{{WaitSeconds: 900, TelegramRecipientIDs: []int{2}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	if len(policy.Steps) != 1 || policy.Steps[0].WaitSeconds != 900 || policy.Steps[0].WebhookIDs == nil {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+
+	policy.Steps = append(policy.Steps, EscalationStep{WaitSeconds: 1800, WebhookIDs: []int{5}})
+	if _, err := store.UpdateEscalationPolicy(ctx, *policy); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	got, err := store.GetEscalationPolicy(ctx, policy.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get policy: %v", err)
+	}
+	if len(got.Steps) != 2 || !reflect.DeepEqual(got.Steps[1].WebhookIDs, []int{5}) {
+		t.Errorf("Unexpected steps after update: %+v", got.Steps)
+	}
+
+	// Policies are scoped to their owner
+	if _, err := store.GetEscalationPolicy(ctx, policy.ID, other.ID); err == nil {
+		t.Error("Expected policy lookup by another user to fail")
+	}
+	if policies, _ := store.ListEscalationPolicies(ctx, other.ID); len(policies) != 0 {
+		t.Errorf("Expected no policies for other user, got %d", len(policies))
+	}
+	if err := store.DeleteEscalationPolicy(ctx, policy.ID, other.ID); err == nil {
+		t.Error("Expected delete by another user to fail")
+	}
+
+	if err := store.DeleteEscalationPolicy(ctx, policy.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete policy: %v", err)
+	}
+	if policies, _ := store.ListEscalationPolicies(ctx, user.ID); len(policies) != 0 {
+		t.Errorf("Expected no policies after delete, got %d", len(policies))
+	}
+}
+
+func TestPendingEscalations(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "oncall@example.com", "hash")
+	policy, err := store.CreateEscalationPolicy(ctx, EscalationPolicy{
+		OrgID: user.ID,
+		Name:  "Secondary on-call",
+		Steps: []EscalationStep{{WaitSeconds: 900, WebhookIDs: []int{1}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	escalated, _ := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 90, 1))
+	plain, _ := store.SaveAlertRule(ctx, testRule(user.ID, "High memory", "mem_used_pct", "above", 90, 1))
+
+	escalated.EscalationPolicyID = &policy.ID
+	rule, err := store.SaveAlertRule(ctx, *escalated)
+	if err != nil {
+		t.Fatalf("Failed to attach policy: %v", err)
+	}
+	if rule.EscalationPolicyID == nil || *rule.EscalationPolicyID != policy.ID {
+		t.Fatalf("Expected rule to use policy %d, got %v", policy.ID, rule.EscalationPolicyID)
+	}
+
+	event, _ := store.CreateAlertEvent(ctx, escalated.ID, nil, 95)
+	acked, _ := store.CreateAlertEvent(ctx, escalated.ID, nil, 96)
+	resolved, _ := store.CreateAlertEvent(ctx, escalated.ID, nil, 97)
+	store.CreateAlertEvent(ctx, plain.ID, nil, 95)
+
+	store.AckAlertEvent(ctx, acked.ID)
+	store.ResolveAlertEvent(ctx, resolved.ID)
+
+	pending, err := store.ListPendingEscalations(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list pending escalations: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Fatalf("Expected only the open event to be pending, got %d", len(pending))
+	}
+	p := pending[0]
+	if p.Event.ID != event.ID || p.Policy.ID != policy.ID || p.Rule.Name != "High CPU" || len(p.Policy.Steps) != 1 {
+		t.Errorf("Unexpected pending escalation: %+v", p)
+	}
+	if p.Step != 0 || !p.LastEscalatedAt.Equal(event.TriggeredAt) {
+		t.Errorf("Expected no steps paged since the event fired, got step=%d last=%v", p.Step, p.LastEscalatedAt)
+	}
+
+	escalatedAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	if err := store.RecordAlertEscalation(ctx, event.ID, 1, escalatedAt); err != nil {
+		t.Fatalf("Failed to record escalation: %v", err)
+	}
+	pending, _ = store.ListPendingEscalations(ctx)
+	if len(pending) != 1 || pending[0].Step != 1 || !pending[0].LastEscalatedAt.Equal(escalatedAt) {
+		t.Errorf("Expected recorded step, got %+v", pending)
+	}
+
+	// Deleting the policy detaches it from the rule
+	if err := store.DeleteEscalationPolicy(ctx, policy.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete policy: %v", err)
+	}
+	rules, _ := store.ListAlertRules(ctx, user.ID)
+	for _, r := range rules {
+		if r.EscalationPolicyID != nil {
+			t.Errorf("Expected rule %d to lose its policy, got %d", r.ID, *r.EscalationPolicyID)
+		}
+	}
+	if pending, _ := store.ListPendingEscalations(ctx); len(pending) != 0 {
+		t.Errorf("Expected no pending escalations without a policy, got %d", len(pending))
+	}
+}
+
+func TestEscalationPolicies_OnlyEventsAfterAttaching(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "oncall@example.com", "hash")
+	policy, err := store.CreateEscalationPolicy(ctx, EscalationPolicy{
+		OrgID: user.ID,
+		Name:  "Secondary on-call",
+		Steps: []EscalationStep{{WaitSeconds: 900, WebhookIDs: []int{1}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	rule, _ := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 90, 1))
+	stale, _ := store.CreateAlertEvent(ctx, rule.ID, nil, 95)
+
+	saved := *rule
+	saved.EscalationPolicyID = &policy.ID
+	if _, err := store.SaveAlertRule(ctx, saved); err != nil {
+		t.Fatalf("Failed to attach policy: %v", err)
+	}
+	if pending, _ := store.ListPendingEscalations(ctx); len(pending) != 0 {
+		t.Fatalf("Expected events from before the policy to be ignored, got %d", len(pending))
+	}
+
+	fresh, _ := store.CreateAlertEvent(ctx, rule.ID, nil, 96)
+
+	// Saving the rule again with the same policy keeps the attach time
+	saved.Name = "Very high CPU"
+	if _, err := store.SaveAlertRule(ctx, saved); err != nil {
+		t.Fatalf("Failed to save rule: %v", err)
+	}
+	pending, err := store.ListPendingEscalations(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list pending escalations: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Event.ID != fresh.ID || pending[0].Event.ID == stale.ID {
+		t.Errorf("Expected only the event triggered after attaching, got %+v", pending)
+	}
+}
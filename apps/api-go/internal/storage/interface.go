@@ -11,6 +11,42 @@ var (
 	ErrUserNotFound = errors.New("user not found")
 	// ErrPasswordResetNotFound is returned when a password reset token is not found or invalid
 	ErrPasswordResetNotFound = errors.New("password reset token not found")
+	// ErrNotificationTemplateNotFound is returned when no template exists for a channel and event type
+	ErrNotificationTemplateNotFound = errors.New("notification template not found")
+	// ErrNotificationPolicyNotFound is returned when a user has not configured a notification policy
+	ErrNotificationPolicyNotFound = errors.New("notification policy not found")
+	// ErrMetricBaselineNotFound is returned when no baseline has been recorded for a machine, metric and slot
+	ErrMetricBaselineNotFound = errors.New("metric baseline not found")
+	// ErrSessionNotFound is returned when a session does not exist or does not belong to the user
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrAPITokenNotFound is returned when an API token does not exist or does not belong to the user
+	ErrAPITokenNotFound = errors.New("API token not found")
+	// ErrOrganizationNotFound is returned when an organization does not exist
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrOrgMemberNotFound is returned when a user is not a member of the organization
+	ErrOrgMemberNotFound = errors.New("organization member not found")
+	// ErrOrgInvitationNotFound is returned when an invitation does not exist or does not belong to the organization
+	ErrOrgInvitationNotFound = errors.New("organization invitation not found")
+	// ErrTOTPNotFound is returned when a user has not set up an authenticator app
+	ErrTOTPNotFound = errors.New("TOTP secret not found")
+	// ErrTOTPStepUsed is returned when a TOTP code's time step is not newer than the last one used
+	ErrTOTPStepUsed = errors.New("TOTP code already used")
+	// ErrRecoveryCodeNotFound is returned when a recovery code does not exist or was already used
+	ErrRecoveryCodeNotFound = errors.New("recovery code not found")
+	// ErrSettingNotFound is returned when a setting has never been set
+	ErrSettingNotFound = errors.New("setting not found")
+	// ErrOIDCLoginNotFound is returned when a single sign-on login does not exist, was already used or expired
+	ErrOIDCLoginNotFound = errors.New("OIDC login not found")
+	// ErrLoginAttemptsNotFound is returned when no failed attempts are recorded for a key
+	ErrLoginAttemptsNotFound = errors.New("login attempts not found")
+)
+
+// User roles, from most to least privileged
+const (
+	RoleOwner    = "owner"    // everything, including managing owners and admins
+	RoleAdmin    = "admin"    // everything except managing owners and admins
+	RoleOperator = "operator" // monitoring configuration, but not users
+	RoleViewer   = "viewer"   // read-only
 )
 
 // User represents a user in the system
@@ -18,8 +54,10 @@ type User struct {
 	ID           int       `json:"id"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"password_hash"`
-	IsAdmin      bool      `json:"is_admin"`
+	IsAdmin      bool      `json:"is_admin"` // true for owners and admins
+	Role         string    `json:"role"`
 	CreatedAt    time.Time `json:"created_at"`
+	TOTPEnabled  bool      `json:"totp_enabled"` // signs in with an authenticator app code after the password
 }
 
 // Store defines the interface for user storage operations
@@ -48,6 +86,54 @@ type Store interface {
 	// MarkPasswordResetUsed marks a password reset entry as used
 	MarkPasswordResetUsed(ctx context.Context, id int) error
 
+	// Session methods
+	CreateSession(ctx context.Context, session Session) (*Session, error)
+	GetSession(ctx context.Context, id int) (*Session, error)
+	GetSessionByRefreshHash(ctx context.Context, tokenHash string) (*Session, error)
+	RotateSessionRefreshToken(ctx context.Context, id int, oldHash, newHash string, expiresAt time.Time, ipAddress string) (*Session, error)
+	TouchSession(ctx context.Context, id int, ipAddress string) error
+	ListSessions(ctx context.Context, userID int) ([]Session, error)
+	RevokeSession(ctx context.Context, id, userID int) error
+	RevokeUserSessions(ctx context.Context, userID, exceptID int) (int, error)
+
+	// API token methods
+	CreateAPIToken(ctx context.Context, token APIToken) (*APIToken, error)
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error)
+	ListAPITokens(ctx context.Context, userID int) ([]APIToken, error)
+	RevokeAPIToken(ctx context.Context, id, userID int) error
+	TouchAPIToken(ctx context.Context, id int, ipAddress string) error
+
+	// Two-factor authentication methods
+	GetUserTOTP(ctx context.Context, userID int) (*UserTOTP, error)
+	SetUserTOTPSecret(ctx context.Context, userID int, secret string) error
+	EnableUserTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	DeleteUserTOTP(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+
+	// Setting methods
+	GetSetting(ctx context.Context, key string) (string, error)
+	SetSetting(ctx context.Context, key, value string) error
+
+	// Single sign-on methods
+	GetUserByIdentity(ctx context.Context, issuer, subject string) (*User, error)
+	LinkUserIdentity(ctx context.Context, identity UserIdentity) error
+	CreateOIDCLogin(ctx context.Context, login OIDCLogin) error
+	ConsumeOIDCLogin(ctx context.Context, stateHash string) (*OIDCLogin, error)
+
+	// Failed login attempt methods
+	GetLoginAttempts(ctx context.Context, key string) (*LoginAttempts, error)
+	RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*LoginAttempts, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	ClearLoginAttempts(ctx context.Context, key string) error
+	ListLoginLockouts(ctx context.Context) ([]LoginAttempts, error)
+
+	// Audit log methods; the audit log is append-only
+	CreateAuditEntry(ctx context.Context, entry AuditEntry) (*AuditEntry, error)
+	ListAuditEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+
 	// ListUsers retrieves all users ordered by email
 	ListUsers(ctx context.Context) ([]User, error)
 
@@ -60,61 +146,149 @@ type Store interface {
 	// PromoteToAdmin promotes a user to admin status
 	PromoteToAdmin(ctx context.Context, userID int) error
 
+	// SetUserRole changes a user's role, refusing to demote the last owner
+	SetUserRole(ctx context.Context, userID int, role string) error
+
 	// DeletePasswordResetsForUser deletes all password reset tokens for a user
 	DeletePasswordResetsForUser(ctx context.Context, userID int) error
 
+	// Organization methods
+	CreateOrganization(ctx context.Context, name string, ownerID int) (*Organization, error)
+	GetOrganization(ctx context.Context, id int) (*Organization, error)
+	ListOrganizations(ctx context.Context) ([]Organization, error)
+	ListUserOrganizations(ctx context.Context, userID int) ([]UserOrganization, error)
+	GetPersonalOrganization(ctx context.Context, userID int) (*Organization, error)
+	RenameOrganization(ctx context.Context, id int, name string) (*Organization, error)
+	DeleteOrganization(ctx context.Context, id int) error
+
+	// Organization member methods
+	GetOrgMember(ctx context.Context, orgID, userID int) (*OrgMember, error)
+	ListOrgMembers(ctx context.Context, orgID int) ([]OrgMember, error)
+	AddOrgMember(ctx context.Context, orgID, userID int, role string) (*OrgMember, error)
+	SetOrgMemberRole(ctx context.Context, orgID, userID int, role string) error
+	RemoveOrgMember(ctx context.Context, orgID, userID int) error
+
+	// Organization invitation methods
+	CreateOrgInvitation(ctx context.Context, invitation OrgInvitation) (*OrgInvitation, error)
+	GetOrgInvitationByHash(ctx context.Context, tokenHash string) (*OrgInvitation, error)
+	ListOrgInvitations(ctx context.Context, orgID int) ([]OrgInvitation, error)
+	DeleteOrgInvitation(ctx context.Context, id, orgID int) error
+	AcceptOrgInvitation(ctx context.Context, id, userID int) (*OrgMember, error)
+
 	// Alert Rules methods
-	ListAlertRules(ctx context.Context) ([]AlertRule, error)
-	CreateAlertRule(ctx context.Context, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*AlertRule, error)
-	UpdateAlertRule(ctx context.Context, id int, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*AlertRule, error)
-	DeleteAlertRule(ctx context.Context, id int) error
+	ListAlertRules(ctx context.Context, orgID int) ([]AlertRule, error)
+	ListAllAlertRules(ctx context.Context) ([]AlertRule, error)
+	GetAlertRule(ctx context.Context, id int, orgID int) (*AlertRule, error)
+	SaveAlertRule(ctx context.Context, rule AlertRule) (*AlertRule, error)
+	DeleteAlertRule(ctx context.Context, id int, orgID int) error
 
 	// Alert Events methods
 	ListAlertEvents(ctx context.Context, limit int) ([]AlertEvent, error)
-	CreateAlertEvent(ctx context.Context, ruleID int, value float64) (*AlertEvent, error)
+	QueryAlertEvents(ctx context.Context, filter AlertEventFilter) ([]AlertEvent, *AlertEventCursor, error)
+	GetAlertEvent(ctx context.Context, id int) (*AlertEvent, error)
+	CreateAlertEvent(ctx context.Context, ruleID int, machineID *int, value float64) (*AlertEvent, error)
 	AckAlertEvent(ctx context.Context, id int) error
+	ResolveAlertEvent(ctx context.Context, id int) (*AlertEvent, error)
+	SetAlertEventExpectedRange(ctx context.Context, id int, low, high float64) (*AlertEvent, error)
+	ListOpenAlertEvents(ctx context.Context) ([]AlertEvent, error)
+
+	// Metric baseline methods
+	GetMetricBaseline(ctx context.Context, machineID int, metric, seasonality string, slot int) (*MetricBaseline, error)
+	UpsertMetricBaseline(ctx context.Context, baseline MetricBaseline) error
+
+	// Alert rule state methods
+	ListAlertRuleStates(ctx context.Context) ([]AlertRuleState, error)
+	UpsertAlertRuleState(ctx context.Context, state AlertRuleState) error
+	DeleteAlertRuleStates(ctx context.Context, ruleID int) error
+
+	// Escalation policy methods
+	ListEscalationPolicies(ctx context.Context, orgID int) ([]EscalationPolicy, error)
+	GetEscalationPolicy(ctx context.Context, id int, orgID int) (*EscalationPolicy, error)
+	CreateEscalationPolicy(ctx context.Context, policy EscalationPolicy) (*EscalationPolicy, error)
+	UpdateEscalationPolicy(ctx context.Context, policy EscalationPolicy) (*EscalationPolicy, error)
+	DeleteEscalationPolicy(ctx context.Context, id int, orgID int) error
+	ListPendingEscalations(ctx context.Context) ([]PendingEscalation, error)
+	RecordAlertEscalation(ctx context.Context, eventID int, step int, escalatedAt time.Time) error
+
+	// Notification policy methods
+	GetNotificationPolicy(ctx context.Context, orgID int) (*NotificationPolicy, error)
+	UpsertNotificationPolicy(ctx context.Context, policy NotificationPolicy) (*NotificationPolicy, error)
+	AddPendingDigestEntry(ctx context.Context, entry PendingDigestEntry) (*PendingDigestEntry, error)
+	ListPendingDigestEntries(ctx context.Context) ([]PendingDigestEntry, error)
+	DeletePendingDigestEntries(ctx context.Context, orgID, maxID int) error
+
+	// Notification routing methods
+	ListNotificationRoutes(ctx context.Context, orgID int) ([]NotificationRoute, error)
+	GetNotificationRoute(ctx context.Context, id int, orgID int) (*NotificationRoute, error)
+	CreateNotificationRoute(ctx context.Context, route NotificationRoute) (*NotificationRoute, error)
+	UpdateNotificationRoute(ctx context.Context, route NotificationRoute) (*NotificationRoute, error)
+	DeleteNotificationRoute(ctx context.Context, id int, orgID int) error
+
+	// Machine tag methods
+	GetMachineTags(ctx context.Context, machineID int) ([]string, error)
+	SetMachineTags(ctx context.Context, machineID int, tags []string) error
+
+	// Silence methods
+	CreateSilence(ctx context.Context, silence Silence) (*Silence, error)
+	GetSilence(ctx context.Context, id int, orgID int) (*Silence, error)
+	ListSilences(ctx context.Context, orgID int, at time.Time) ([]Silence, error)
+	UpdateSilence(ctx context.Context, silence Silence) (*Silence, error)
+	DeleteSilence(ctx context.Context, id int, orgID int) error
+	ListActiveSilences(ctx context.Context, at time.Time) ([]Silence, error)
 
 	// Webhook methods
-	ListWebhooks(ctx context.Context, userID int) ([]Webhook, error)
-	GetWebhook(ctx context.Context, id int, userID int) (*Webhook, error)
-	CreateWebhook(ctx context.Context, userID int, url, secretHash string) (*Webhook, error)
-	UpdateWebhook(ctx context.Context, id int, userID int, url string, secretHash *string, isActive *bool) (*Webhook, error)
-	DeleteWebhook(ctx context.Context, id int, userID int) error
+	ListWebhooks(ctx context.Context, orgID int) ([]Webhook, error)
+	GetWebhook(ctx context.Context, id int, orgID int) (*Webhook, error)
+	CreateWebhook(ctx context.Context, orgID int, url, secretHash string) (*Webhook, error)
+	UpdateWebhook(ctx context.Context, id int, orgID int, url string, secretHash *string, isActive *bool) (*Webhook, error)
+	DeleteWebhook(ctx context.Context, id int, orgID int) error
 	IncrementWebhookFailure(ctx context.Context, id int, lastErrorAt time.Time) error
 	MarkWebhookSuccess(ctx context.Context, id int, lastSuccessAt time.Time) error
 	UpdateWebhookDeliveryState(ctx context.Context, id int, lastAttemptAt time.Time, cooldownUntil *time.Time) error
+	UpdateWebhookDeliverySettings(ctx context.Context, id int, orgID int, settings DeliverySettings) (*Webhook, error)
+	ResetWebhookCircuit(ctx context.Context, id int, orgID int) (*Webhook, error)
+	RotateWebhookSecret(ctx context.Context, id int, orgID int, newSecretHash string, previousExpiresAt time.Time) (*Webhook, error)
 
 	// Email recipient methods
-	ListEmailRecipients(ctx context.Context, userID int) ([]EmailRecipient, error)
-	GetEmailRecipient(ctx context.Context, id int, userID int) (*EmailRecipient, error)
-	CreateEmailRecipient(ctx context.Context, userID int, email string) (*EmailRecipient, error)
-	UpdateEmailRecipient(ctx context.Context, id int, userID int, email string, isActive *bool) (*EmailRecipient, error)
-	DeleteEmailRecipient(ctx context.Context, id int, userID int) error
+	ListEmailRecipients(ctx context.Context, orgID int) ([]EmailRecipient, error)
+	GetEmailRecipient(ctx context.Context, id int, orgID int) (*EmailRecipient, error)
+	CreateEmailRecipient(ctx context.Context, orgID int, email string) (*EmailRecipient, error)
+	UpdateEmailRecipient(ctx context.Context, id int, orgID int, email string, isActive *bool) (*EmailRecipient, error)
+	DeleteEmailRecipient(ctx context.Context, id int, orgID int) error
 	IncrementEmailFailure(ctx context.Context, id int, lastErrorAt time.Time) error
 	MarkEmailSuccess(ctx context.Context, id int, lastSuccessAt time.Time) error
 	UpdateEmailDeliveryState(ctx context.Context, id int, lastAttemptAt time.Time, cooldownUntil *time.Time) error
 
 	// Telegram recipient methods
-	ListTelegramRecipients(ctx context.Context, userID int) ([]TelegramRecipient, error)
-	GetTelegramRecipient(ctx context.Context, id int, userID int) (*TelegramRecipient, error)
-	CreateTelegramRecipient(ctx context.Context, userID int, chatID string) (*TelegramRecipient, error)
-	UpdateTelegramRecipient(ctx context.Context, id int, userID int, chatID string, isActive *bool) (*TelegramRecipient, error)
-	DeleteTelegramRecipient(ctx context.Context, id int, userID int) error
+	ListTelegramRecipients(ctx context.Context, orgID int) ([]TelegramRecipient, error)
+	GetTelegramRecipient(ctx context.Context, id int, orgID int) (*TelegramRecipient, error)
+	ListTelegramRecipientsByChatID(ctx context.Context, chatID string) ([]TelegramRecipient, error)
+	CreateTelegramRecipient(ctx context.Context, orgID int, chatID string, createdBy int) (*TelegramRecipient, error)
+	UpdateTelegramRecipient(ctx context.Context, id int, orgID int, chatID string, isActive *bool) (*TelegramRecipient, error)
+	DeleteTelegramRecipient(ctx context.Context, id int, orgID int) error
 	IncrementTelegramFailure(ctx context.Context, id int, lastErrorAt time.Time) error
 	MarkTelegramSuccess(ctx context.Context, id int, lastSuccessAt time.Time) error
 	UpdateTelegramDeliveryState(ctx context.Context, id int, lastAttemptAt time.Time, cooldownUntil *time.Time) error
+	UpdateTelegramDeliverySettings(ctx context.Context, id int, orgID int, settings DeliverySettings) (*TelegramRecipient, error)
+	ResetTelegramCircuit(ctx context.Context, id int, orgID int) (*TelegramRecipient, error)
+
+	// Notification template methods
+	ListNotificationTemplates(ctx context.Context, orgID int) ([]NotificationTemplate, error)
+	GetNotificationTemplate(ctx context.Context, orgID int, channel, eventType string) (*NotificationTemplate, error)
+	UpsertNotificationTemplate(ctx context.Context, orgID int, channel, eventType, body string) (*NotificationTemplate, error)
+	DeleteNotificationTemplate(ctx context.Context, id int, orgID int) error
 
 	// Machine methods
-	CreateMachine(ctx context.Context, userID int, name, hostname, description, apiKeyHash string) (*Machine, error)
+	CreateMachine(ctx context.Context, orgID int, name, hostname, description, apiKeyHash string) (*Machine, error)
 	GetMachineByID(ctx context.Context, id int) (*Machine, error)
 	GetMachineByAPIKey(ctx context.Context, apiKeyHash string) (*Machine, error)
-	ListMachines(ctx context.Context, userID int) ([]Machine, error)
+	ListMachines(ctx context.Context, orgID int) ([]Machine, error)
 	ListAllMachines(ctx context.Context) ([]Machine, error)
 	UpdateMachineStatus(ctx context.Context, id int, status string, lastSeen time.Time) error
 	UpdateMachineLastSeen(ctx context.Context, id int, lastSeen time.Time) error
 	UpdateMachineSystemInfo(ctx context.Context, id int, info MachineSystemInfoUpdate) error
 	UpdateMachineDetails(ctx context.Context, id int, updates map[string]interface{}) error
-	DeleteMachine(ctx context.Context, id int, userID int) error
+	DeleteMachine(ctx context.Context, id int, orgID int) error
 
 	// Machine credential management
 	SetMachineEnabled(ctx context.Context, machineID int, enabled bool) error
@@ -129,6 +303,9 @@ type Store interface {
 	RecordMachineOfflineNotification(ctx context.Context, machineID int, notifiedAt time.Time) error
 	GetMachineLastOfflineNotification(ctx context.Context, machineID int) (time.Time, error)
 	ClearMachineOfflineNotification(ctx context.Context, machineID int) error
+	HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error
+	MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error)
+	ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error
 
 	// Metrics history methods
 	InsertMetrics(ctx context.Context, machineID int, cpuPct, memUsedPct, diskUsedPct float64, netRxBytes, netTxBytes int64, uptimeSeconds *float64, timestamp time.Time) error
@@ -149,16 +326,70 @@ type PasswordReset struct {
 	CreatedAt time.Time  `json:"created_at"`
 }
 
+// Alert severities, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// IsValidSeverity reports whether s is a known alert severity
+func IsValidSeverity(s string) bool {
+	return s == SeverityInfo || s == SeverityWarning || s == SeverityCritical
+}
+
+// Alert rule types
+const (
+	RuleTypeThreshold  = "threshold"  // fires on the latest value of the metric
+	RuleTypeRate       = "rate"       // fires on the metric's rate of change, in percentage points per hour
+	RuleTypeForecast   = "forecast"   // fires when the metric is projected to reach the threshold within the horizon
+	RuleTypeAnomaly    = "anomaly"    // fires when the metric deviates from its machine's baseline by threshold_pct standard deviations
+	RuleTypeExpression = "expression" // fires when a boolean expression over the sample's metrics holds
+)
+
+// IsValidRuleType reports whether t is a known alert rule type
+func IsValidRuleType(t string) bool {
+	return t == RuleTypeThreshold || t == RuleTypeRate || t == RuleTypeForecast || t == RuleTypeAnomaly ||
+		t == RuleTypeExpression
+}
+
+// Baseline seasonalities: anomaly baselines are kept separately for each hour of the day or week
+const (
+	SeasonalityNone   = "none"
+	SeasonalityDaily  = "daily"
+	SeasonalityWeekly = "weekly"
+)
+
+// IsValidSeasonality reports whether s is a known baseline seasonality
+func IsValidSeasonality(s string) bool {
+	return s == SeasonalityNone || s == SeasonalityDaily || s == SeasonalityWeekly
+}
+
 // AlertRule represents an alert rule for monitoring metrics
 type AlertRule struct {
 	ID           int       `json:"id"`
+	OrgID        int       `json:"org_id"` // organization whose machines the rule evaluates
 	Name         string    `json:"name"`
 	Metric       string    `json:"metric"` // "cpu_pct", "mem_used_pct", "disk_used_pct"
 	ThresholdPct float64   `json:"threshold_pct"`
 	Comparison   string    `json:"comparison"`    // "above" | "below"
 	TriggerAfter int       `json:"trigger_after"` // number of consecutive samples before firing
+	Severity     string    `json:"severity"`      // "info" | "warning" | "critical"
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+
+	EscalationPolicyID *int `json:"escalation_policy_id"` // policy paging unacknowledged events, nil if none
+
+	ClearThresholdPct *float64 `json:"clear_threshold_pct"` // value the metric must return past to clear, nil for threshold_pct
+	ClearAfter        int      `json:"clear_after"`         // number of consecutive clear samples before resolving
+	ForSeconds        int      `json:"for_seconds"`         // how long the threshold must stay breached before firing, 0 for no minimum
+	ClearForSeconds   int      `json:"clear_for_seconds"`   // how long the metric must stay clear before resolving, 0 for no minimum
+
+	Type           string `json:"type"`            // "threshold" | "rate" | "forecast"
+	WindowSeconds  int    `json:"window_seconds"`  // metrics history rate and forecast rules are computed over
+	HorizonSeconds int    `json:"horizon_seconds"` // how far ahead forecast rules look for the threshold
+	Seasonality    string `json:"seasonality"`     // baseline seasonality of anomaly rules: "none" | "daily" | "weekly"
+	Expression     string `json:"expression"`      // condition of expression rules, e.g. "cpu_pct > 90 && mem_used_pct > 80"
 }
 
 // AlertEvent represents an alert event triggered by a rule
@@ -169,12 +400,39 @@ type AlertEvent struct {
 	Value          float64    `json:"value"`
 	Acknowledged   bool       `json:"acknowledged"`
 	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	Severity       string     `json:"severity"`                // severity of the rule when the event fired
+	MachineID      *int       `json:"machine_id,omitempty"`    // machine whose metrics fired the rule, nil for the local host
+	ExpectedLow    *float64   `json:"expected_low,omitempty"`  // lower end of the range an anomaly rule expected
+	ExpectedHigh   *float64   `json:"expected_high,omitempty"` // upper end of the range an anomaly rule expected
+}
+
+// AlertEventFilter selects alert events; zero fields match every event
+type AlertEventFilter struct {
+	OrgID        int // events fired by the organization's rules
+	RuleID       *int
+	MachineID    *int
+	Resolved     *bool // true for resolved events, false for open ones
+	Acknowledged *bool
+	Severity     string
+	From         *time.Time        // triggered at or after
+	To           *time.Time        // triggered before
+	After        *AlertEventCursor // continue after this event
+	Limit        int
+}
+
+// AlertEventCursor is the position of an event in the order events are listed in, newest first.
+// Neither field changes after an event fires, so acknowledging or resolving events between pages
+// never skips or repeats an event.
+type AlertEventCursor struct {
+	TriggeredAt time.Time
+	ID          int
 }
 
 // Webhook represents a user webhook configuration for alert notifications
 type Webhook struct {
 	ID            int        `json:"id"`
-	UserID        int        `json:"user_id"`
+	OrgID         int        `json:"org_id"`
 	URL           string     `json:"url"`
 	SecretHash    string     `json:"secret_hash"` // SHA-256 hex hash of user-provided secret
 	IsActive      bool       `json:"is_active"`
@@ -185,12 +443,26 @@ type Webhook struct {
 	LastAttemptAt *time.Time `json:"last_attempt_at"` // Last delivery attempt for rate limiting
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
+	DeliverySettings
+
+	// Secret being rotated out; deliveries are signed with both secrets until it expires
+	PreviousSecretHash      *string    `json:"previous_secret_hash,omitempty"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"`
+}
+
+// DeliverySettings holds per-endpoint rate limiting and circuit breaker overrides.
+// Nil fields fall back to the server-wide defaults.
+type DeliverySettings struct {
+	MinAttemptIntervalSeconds *int `json:"min_attempt_interval_seconds"` // Minimum interval between delivery attempts
+	FailureThreshold          *int `json:"failure_threshold"`            // Failures within the window that open the circuit
+	FailureWindowSeconds      *int `json:"failure_window_seconds"`       // Window for counting failures
+	CooldownSeconds           *int `json:"cooldown_seconds"`             // How long the circuit stays open
 }
 
 // EmailRecipient represents an email notification recipient for alert notifications
 type EmailRecipient struct {
 	ID            int        `json:"id"`
-	UserID        int        `json:"user_id"`
+	OrgID         int        `json:"org_id"`
 	Email         string     `json:"email"`
 	IsActive      bool       `json:"is_active"`
 	FailureCount  int        `json:"failure_count"`
@@ -205,7 +477,7 @@ type EmailRecipient struct {
 // TelegramRecipient represents a Telegram chat that receives alert notifications
 type TelegramRecipient struct {
 	ID            int        `json:"id"`
-	UserID        int        `json:"user_id"`
+	OrgID         int        `json:"org_id"`
 	ChatID        string     `json:"chat_id"`
 	IsActive      bool       `json:"is_active"`
 	CreatedAt     time.Time  `json:"created_at"`
@@ -214,4 +486,6 @@ type TelegramRecipient struct {
 	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
 	FailureCount  int        `json:"failure_count"`
 	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+	CreatedBy     *int       `json:"created_by,omitempty"` // member who linked the chat, nil once they are deleted
+	DeliverySettings
 }
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LoginAttempts counts recent failed attempts for a key, such as an account or client IP, and
+// whether it is locked out
+type LoginAttempts struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// GetLoginAttempts returns the failed attempts recorded for the key
+func (s *SQLiteStore) GetLoginAttempts(ctx context.Context, key string) (*LoginAttempts, error) {
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = ?`
+
+	attempts := &LoginAttempts{}
+	err := s.db.QueryRowContext(ctx, query, key).Scan(&attempts.Key, &attempts.Failures, &attempts.LastFailureAt,
+		&attempts.LockedUntil)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrLoginAttemptsNotFound
+		}
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// RecordLoginFailure counts a failed attempt for the key. The count starts over when the last
+// failure was before resetBefore.
+func (s *SQLiteStore) RecordLoginFailure(ctx context.Context, key string, resetBefore time.Time) (*LoginAttempts, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING key, failures, last_failure_at, locked_until`
+
+	attempts := &LoginAttempts{}
+	err := s.db.QueryRowContext(ctx, query, key, time.Now().UTC(), resetBefore.UTC()).Scan(&attempts.Key,
+		&attempts.Failures, &attempts.LastFailureAt, &attempts.LockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// LockLogin locks the key out until the given time
+func (s *SQLiteStore) LockLogin(ctx context.Context, key string, until time.Time) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE login_attempts SET locked_until = ? WHERE key = ?`, until.UTC(), key)
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify login lock: %w", err)
+	}
+	if rows == 0 {
+		return ErrLoginAttemptsNotFound
+	}
+
+	return nil
+}
+
+// ClearLoginAttempts forgets the failed attempts for the key, lifting any lockout
+func (s *SQLiteStore) ClearLoginAttempts(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = ?`, key)
+	if err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify login attempts deletion: %w", err)
+	}
+	if rows == 0 {
+		return ErrLoginAttemptsNotFound
+	}
+
+	return nil
+}
+
+// ListLoginLockouts returns the keys currently locked out, those locked longest first
+func (s *SQLiteStore) ListLoginLockouts(ctx context.Context) ([]LoginAttempts, error) {
+	query := `
+		SELECT key, failures, last_failure_at, locked_until FROM login_attempts
+		WHERE locked_until > ?
+		ORDER BY locked_until DESC, key ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login lockouts: %w", err)
+	}
+	defer rows.Close()
+
+	lockouts := []LoginAttempts{}
+	for rows.Next() {
+		var attempts LoginAttempts
+		if err := rows.Scan(&attempts.Key, &attempts.Failures, &attempts.LastFailureAt, &attempts.LockedUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan login lockout: %w", err)
+		}
+		lockouts = append(lockouts, attempts)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate login lockouts: %w", err)
+	}
+
+	return lockouts, nil
+}
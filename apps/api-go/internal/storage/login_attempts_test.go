@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoginAttempts(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	key := "login-account:alice@example.com"
+
+	if _, err := store.GetLoginAttempts(ctx, key); err != ErrLoginAttemptsNotFound {
+		t.Fatalf("Expected ErrLoginAttemptsNotFound, got %v", err)
+	}
+
+	resetBefore := time.Now().Add(-time.Hour)
+	for i := 1; i <= 3; i++ {
+		attempts, err := store.RecordLoginFailure(ctx, key, resetBefore)
+		if err != nil {
+			t.Fatalf("Failed to record login failure: %v", err)
+		}
+		if attempts.Failures != i {
+			t.Errorf("Expected %d failures, got %d", i, attempts.Failures)
+		}
+	}
+
+	// Failures older than the reset time no longer count
+	attempts, err := store.RecordLoginFailure(ctx, key, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to record login failure: %v", err)
+	}
+	if attempts.Failures != 1 {
+		t.Errorf("Expected the count to start over, got %d", attempts.Failures)
+	}
+
+	if lockouts, _ := store.ListLoginLockouts(ctx); len(lockouts) != 0 {
+		t.Errorf("Expected no lockouts, got %d", len(lockouts))
+	}
+	if err := store.LockLogin(ctx, key, time.Now().Add(15*time.Minute)); err != nil {
+		t.Fatalf("Failed to lock login: %v", err)
+	}
+	if err := store.LockLogin(ctx, "login-ip:10.0.0.1", time.Now().Add(time.Minute)); err != ErrLoginAttemptsNotFound {
+		t.Errorf("Expected ErrLoginAttemptsNotFound locking an unknown key, got %v", err)
+	}
+	lockouts, err := store.ListLoginLockouts(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list lockouts: %v", err)
+	}
+	if len(lockouts) != 1 || lockouts[0].Key != key || lockouts[0].LockedUntil == nil {
+		t.Errorf("Unexpected lockouts %+v", lockouts)
+	}
+
+	if err := store.ClearLoginAttempts(ctx, key); err != nil {
+		t.Fatalf("Failed to clear login attempts: %v", err)
+	}
+	if err := store.ClearLoginAttempts(ctx, key); err != ErrLoginAttemptsNotFound {
+		t.Errorf("Expected ErrLoginAttemptsNotFound clearing twice, got %v", err)
+	}
+}
@@ -11,7 +11,7 @@ import (
 // Machine represents a monitored machine in the system
 type Machine struct {
 	ID              int       `json:"id"`
-	UserID          int       `json:"user_id"`
+	OrgID           int       `json:"org_id"`
 	Name            string    `json:"name"`
 	Hostname        string    `json:"hostname"`
 	Description     string    `json:"description"`
@@ -27,6 +27,7 @@ type Machine struct {
 	DiskTotalGB     int64     `json:"disk_total_gb"`
 	LastBootTime    time.Time `json:"last_boot_time"`
 	CreatedAt       time.Time `json:"created_at"`
+	Tags            []string  `json:"tags,omitempty"` // Loaded separately, see GetMachineTags
 }
 
 // MachineAPIKey represents an API key version for a machine
@@ -64,7 +65,7 @@ type MachineSystemInfoUpdate struct {
 }
 
 // CreateMachine creates a new machine entry and stores the API key in machine_api_keys table
-func (s *SQLiteStore) CreateMachine(ctx context.Context, userID int, name, hostname, description, apiKeyHash string) (*Machine, error) {
+func (s *SQLiteStore) CreateMachine(ctx context.Context, orgID int, name, hostname, description, apiKeyHash string) (*Machine, error) {
 	// Start a transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -74,9 +75,9 @@ func (s *SQLiteStore) CreateMachine(ctx context.Context, userID int, name, hostn
 
 	// Insert machine (api_key column is kept for backward compatibility but not used)
 	query := `
-		INSERT INTO machines (user_id, name, hostname, description, api_key, is_enabled, status, created_at)
+		INSERT INTO machines (org_id, name, hostname, description, api_key, is_enabled, status, created_at)
 		VALUES (?, ?, ?, ?, ?, 1, 'offline', CURRENT_TIMESTAMP)
-		RETURNING id, user_id, name, hostname, description, api_key, is_enabled, status, last_seen, platform, platform_version, kernel_version, cpu_cores, memory_total_mb, disk_total_gb, last_boot_time, created_at
+		RETURNING id, org_id, name, hostname, description, api_key, is_enabled, status, last_seen, platform, platform_version, kernel_version, cpu_cores, memory_total_mb, disk_total_gb, last_boot_time, created_at
 	`
 
 	var m Machine
@@ -90,8 +91,8 @@ func (s *SQLiteStore) CreateMachine(ctx context.Context, userID int, name, hostn
 	var diskTotal sql.NullInt64
 	var lastBoot sql.NullTime
 
-	err = tx.QueryRowContext(ctx, query, userID, name, hostname, description, apiKeyHash).Scan(
-		&m.ID, &m.UserID, &m.Name, &m.Hostname, &desc, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
+	err = tx.QueryRowContext(ctx, query, orgID, name, hostname, description, apiKeyHash).Scan(
+		&m.ID, &m.OrgID, &m.Name, &m.Hostname, &desc, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
 		&platform, &platformVersion, &kernelVersion, &cpuCores, &memoryTotal, &diskTotal, &lastBoot, &m.CreatedAt,
 	)
 	if err != nil {
@@ -143,7 +144,7 @@ func (s *SQLiteStore) CreateMachine(ctx context.Context, userID int, name, hostn
 // GetMachineByID retrieves a machine by ID
 func (s *SQLiteStore) GetMachineByID(ctx context.Context, id int) (*Machine, error) {
 	query := `
-		SELECT id, user_id, name, hostname, description, api_key, is_enabled, status, last_seen,
+		SELECT id, org_id, name, hostname, description, api_key, is_enabled, status, last_seen,
 		       platform, platform_version, kernel_version, cpu_cores,
 		       memory_total_mb, disk_total_gb, last_boot_time, created_at
 		FROM machines
@@ -162,7 +163,7 @@ func (s *SQLiteStore) GetMachineByID(ctx context.Context, id int) (*Machine, err
 	var lastBoot sql.NullTime
 
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&m.ID, &m.UserID, &m.Name, &m.Hostname, &description, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
+		&m.ID, &m.OrgID, &m.Name, &m.Hostname, &description, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
 		&platform, &platformVersion, &kernelVersion, &cpuCores, &memoryTotal, &diskTotal, &lastBoot, &m.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -206,7 +207,7 @@ func (s *SQLiteStore) GetMachineByID(ctx context.Context, id int) (*Machine, err
 // GetMachineByAPIKey retrieves a machine by API key hash using the machine_api_keys table
 func (s *SQLiteStore) GetMachineByAPIKey(ctx context.Context, apiKeyHash string) (*Machine, error) {
 	query := `
-		SELECT m.id, m.user_id, m.name, m.hostname, m.description, m.api_key, m.is_enabled, m.status, m.last_seen,
+		SELECT m.id, m.org_id, m.name, m.hostname, m.description, m.api_key, m.is_enabled, m.status, m.last_seen,
 		       m.platform, m.platform_version, m.kernel_version, m.cpu_cores,
 		       m.memory_total_mb, m.disk_total_gb, m.last_boot_time, m.created_at
 		FROM machines m
@@ -226,7 +227,7 @@ func (s *SQLiteStore) GetMachineByAPIKey(ctx context.Context, apiKeyHash string)
 	var lastBoot sql.NullTime
 
 	err := s.db.QueryRowContext(ctx, query, apiKeyHash).Scan(
-		&m.ID, &m.UserID, &m.Name, &m.Hostname, &description, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
+		&m.ID, &m.OrgID, &m.Name, &m.Hostname, &description, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
 		&platform, &platformVersion, &kernelVersion, &cpuCores, &memoryTotal, &diskTotal, &lastBoot, &m.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -267,18 +268,18 @@ func (s *SQLiteStore) GetMachineByAPIKey(ctx context.Context, apiKeyHash string)
 	return &m, nil
 }
 
-// ListMachines retrieves all machines for a user
-func (s *SQLiteStore) ListMachines(ctx context.Context, userID int) ([]Machine, error) {
+// ListMachines retrieves all machines for an organization
+func (s *SQLiteStore) ListMachines(ctx context.Context, orgID int) ([]Machine, error) {
 	query := `
-		SELECT id, user_id, name, hostname, description, api_key, is_enabled, status, last_seen,
+		SELECT id, org_id, name, hostname, description, api_key, is_enabled, status, last_seen,
 		       platform, platform_version, kernel_version, cpu_cores,
 		       memory_total_mb, disk_total_gb, last_boot_time, created_at
 		FROM machines
-		WHERE user_id = ?
+		WHERE org_id = ?
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.db.QueryContext(ctx, query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list machines: %w", err)
 	}
@@ -297,7 +298,7 @@ func (s *SQLiteStore) ListMachines(ctx context.Context, userID int) ([]Machine,
 		var diskTotal sql.NullInt64
 		var lastBoot sql.NullTime
 		if err := rows.Scan(
-			&m.ID, &m.UserID, &m.Name, &m.Hostname, &description, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
+			&m.ID, &m.OrgID, &m.Name, &m.Hostname, &description, &m.APIKey, &m.IsEnabled, &m.Status, &lastSeen,
 			&platform, &platformVersion, &kernelVersion, &cpuCores, &memoryTotal, &diskTotal, &lastBoot, &m.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan machine: %w", err)
@@ -481,11 +482,11 @@ func (s *SQLiteStore) UpdateMachineDetails(ctx context.Context, id int, updates
 }
 
 // DeleteMachine deletes a machine and all its metrics history
-func (s *SQLiteStore) DeleteMachine(ctx context.Context, id int, userID int) error {
-	// First verify the machine belongs to the user
-	query := `DELETE FROM machines WHERE id = ? AND user_id = ?`
+func (s *SQLiteStore) DeleteMachine(ctx context.Context, id int, orgID int) error {
+	// First verify the machine belongs to the organization
+	query := `DELETE FROM machines WHERE id = ? AND org_id = ?`
 
-	result, err := s.db.ExecContext(ctx, query, id, userID)
+	result, err := s.db.ExecContext(ctx, query, id, orgID)
 	if err != nil {
 		return fmt.Errorf("failed to delete machine: %w", err)
 	}
@@ -585,10 +586,10 @@ func (s *SQLiteStore) GetMetricsHistory(ctx context.Context, machineID int, from
 	return metrics, nil
 }
 
-// ListAllMachines retrieves all machines across all users
+// ListAllMachines retrieves all machines across all organizations
 func (s *SQLiteStore) ListAllMachines(ctx context.Context) ([]Machine, error) {
 	query := `
-		SELECT id, user_id, name, hostname, description, api_key, status, last_seen,
+		SELECT id, org_id, name, hostname, description, api_key, status, last_seen,
 		       platform, platform_version, kernel_version, cpu_cores, memory_total_mb, disk_total_gb, last_boot_time, created_at
 		FROM machines
 		ORDER BY id
@@ -614,7 +615,7 @@ func (s *SQLiteStore) ListAllMachines(ctx context.Context) ([]Machine, error) {
 		var lastBoot sql.NullTime
 
 		if err := rows.Scan(
-			&m.ID, &m.UserID, &m.Name, &m.Hostname, &desc, &m.APIKey, &m.Status, &lastSeen,
+			&m.ID, &m.OrgID, &m.Name, &m.Hostname, &desc, &m.APIKey, &m.Status, &lastSeen,
 			&platform, &platformVersion, &kernelVersion, &cpuCores, &memoryTotal, &diskTotal, &lastBoot, &m.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan machine: %w", err)
@@ -693,6 +694,41 @@ func (s *SQLiteStore) ClearMachineOfflineNotification(ctx context.Context, machi
 	return nil
 }
 
+// HoldMachineOfflineNotification records that a machine's offline notification was held back by a silence
+func (s *SQLiteStore) HoldMachineOfflineNotification(ctx context.Context, machineID int, heldAt time.Time) error {
+	query := `
+		INSERT INTO machine_held_offline_notifications (machine_id, held_at)
+		VALUES (?, ?)
+		ON CONFLICT(machine_id) DO NOTHING
+	`
+	_, err := s.db.ExecContext(ctx, query, machineID, heldAt)
+	if err != nil {
+		return fmt.Errorf("failed to hold offline notification: %w", err)
+	}
+	return nil
+}
+
+// MachineOfflineNotificationHeld reports whether a machine's offline notification is being held back
+func (s *SQLiteStore) MachineOfflineNotificationHeld(ctx context.Context, machineID int) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM machine_held_offline_notifications WHERE machine_id = ?)`
+
+	var held bool
+	if err := s.db.QueryRowContext(ctx, query, machineID).Scan(&held); err != nil {
+		return false, fmt.Errorf("failed to get held offline notification: %w", err)
+	}
+	return held, nil
+}
+
+// ReleaseMachineOfflineNotification clears a held offline notification (when it is sent or the machine recovers)
+func (s *SQLiteStore) ReleaseMachineOfflineNotification(ctx context.Context, machineID int) error {
+	query := `DELETE FROM machine_held_offline_notifications WHERE machine_id = ?`
+	_, err := s.db.ExecContext(ctx, query, machineID)
+	if err != nil {
+		return fmt.Errorf("failed to release held offline notification: %w", err)
+	}
+	return nil
+}
+
 // SetMachineEnabled enables or disables a machine
 func (s *SQLiteStore) SetMachineEnabled(ctx context.Context, machineID int, enabled bool) error {
 	query := `UPDATE machines SET is_enabled = ? WHERE id = ?`
@@ -839,3 +875,48 @@ func (s *SQLiteStore) ListMachineAPIKeys(ctx context.Context, machineID int) ([]
 
 	return keys, nil
 }
+
+// GetMachineTags returns a machine's tags in alphabetical order
+func (s *SQLiteStore) GetMachineTags(ctx context.Context, machineID int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tag FROM machine_tags WHERE machine_id = ? ORDER BY tag`, machineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get machine tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan machine tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// SetMachineTags replaces a machine's tags
+func (s *SQLiteStore) SetMachineTags(ctx context.Context, machineID int, tags []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM machine_tags WHERE machine_id = ?`, machineID); err != nil {
+		return fmt.Errorf("failed to clear machine tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO machine_tags (machine_id, tag) VALUES (?, ?)`, machineID, tag); err != nil {
+			return fmt.Errorf("failed to add machine tag: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
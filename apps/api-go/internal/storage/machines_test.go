@@ -35,8 +35,8 @@ func TestMachineOperations(t *testing.T) {
 		if machine.ID == 0 {
 			t.Error("Expected machine ID to be set")
 		}
-		if machine.UserID != user.ID {
-			t.Errorf("Expected user ID %d, got %d", user.ID, machine.UserID)
+		if machine.OrgID != user.ID {
+			t.Errorf("Expected org ID %d, got %d", user.ID, machine.OrgID)
 		}
 		if machine.Name != "test-machine" {
 			t.Errorf("Expected name 'test-machine', got '%s'", machine.Name)
@@ -115,8 +115,8 @@ func TestMachineOperations(t *testing.T) {
 
 		// All machines should belong to user1
 		for _, m := range machines {
-			if m.UserID != user.ID {
-				t.Errorf("Expected all machines to belong to user %d, got machine with user %d", user.ID, m.UserID)
+			if m.OrgID != user.ID {
+				t.Errorf("Expected all machines to belong to org %d, got machine with org %d", user.ID, m.OrgID)
 			}
 		}
 	})
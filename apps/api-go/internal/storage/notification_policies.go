@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NotificationPolicy controls when and how an organization's alert notifications are delivered
+type NotificationPolicy struct {
+	OrgID               int       `json:"org_id"`
+	Timezone            string    `json:"timezone"` // IANA time zone used for quiet hours, e.g. "Europe/Berlin"
+	QuietHoursEnabled   bool      `json:"quiet_hours_enabled"`
+	QuietHoursStart     string    `json:"quiet_hours_start"` // "HH:MM" local time
+	QuietHoursEnd       string    `json:"quiet_hours_end"`   // "HH:MM" local time, may be before start (overnight)
+	DigestEnabled       bool      `json:"digest_enabled"`
+	DigestWindowSeconds int       `json:"digest_window_seconds"` // How long events are batched before a digest is sent
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// GetNotificationPolicy retrieves an organization's notification policy.
+// Returns ErrNotificationPolicyNotFound when the user has not configured one.
+func (s *SQLiteStore) GetNotificationPolicy(ctx context.Context, orgID int) (*NotificationPolicy, error) {
+	query := `
+		SELECT org_id, timezone, quiet_hours_enabled, quiet_hours_start, quiet_hours_end,
+		       digest_enabled, digest_window_seconds, updated_at
+		FROM notification_policies
+		WHERE org_id = ?
+	`
+
+	var p NotificationPolicy
+	err := s.db.QueryRowContext(ctx, query, orgID).Scan(
+		&p.OrgID, &p.Timezone, &p.QuietHoursEnabled, &p.QuietHoursStart, &p.QuietHoursEnd,
+		&p.DigestEnabled, &p.DigestWindowSeconds, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotificationPolicyNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification policy: %w", err)
+	}
+
+	return &p, nil
+}
+
+// UpsertNotificationPolicy creates or replaces an organization's notification policy
+func (s *SQLiteStore) UpsertNotificationPolicy(ctx context.Context, policy NotificationPolicy) (*NotificationPolicy, error) {
+	query := `
+		INSERT INTO notification_policies (org_id, timezone, quiet_hours_enabled, quiet_hours_start, quiet_hours_end,
+		                                   digest_enabled, digest_window_seconds, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(org_id) DO UPDATE SET
+			timezone = excluded.timezone,
+			quiet_hours_enabled = excluded.quiet_hours_enabled,
+			quiet_hours_start = excluded.quiet_hours_start,
+			quiet_hours_end = excluded.quiet_hours_end,
+			digest_enabled = excluded.digest_enabled,
+			digest_window_seconds = excluded.digest_window_seconds,
+			updated_at = excluded.updated_at
+		RETURNING org_id, timezone, quiet_hours_enabled, quiet_hours_start, quiet_hours_end,
+		          digest_enabled, digest_window_seconds, updated_at
+	`
+
+	var p NotificationPolicy
+	err := s.db.QueryRowContext(ctx, query, policy.OrgID, policy.Timezone, policy.QuietHoursEnabled,
+		policy.QuietHoursStart, policy.QuietHoursEnd, policy.DigestEnabled, policy.DigestWindowSeconds, time.Now()).Scan(
+		&p.OrgID, &p.Timezone, &p.QuietHoursEnabled, &p.QuietHoursStart, &p.QuietHoursEnd,
+		&p.DigestEnabled, &p.DigestWindowSeconds, &p.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save notification policy: %w", err)
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNotificationPolicies_Upsert(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "policy@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if _, err := store.GetNotificationPolicy(ctx, user.ID); err != ErrNotificationPolicyNotFound {
+		t.Fatalf("Expected ErrNotificationPolicyNotFound, got %v", err)
+	}
+
+	policy := NotificationPolicy{
+		OrgID:               user.ID,
+		Timezone:            "Europe/Berlin",
+		QuietHoursEnabled:   true,
+		QuietHoursStart:     "22:00",
+		QuietHoursEnd:       "07:00",
+		DigestWindowSeconds: 300,
+	}
+	if _, err := store.UpsertNotificationPolicy(ctx, policy); err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	policy.DigestEnabled = true
+	policy.DigestWindowSeconds = 900
+	updated, err := store.UpsertNotificationPolicy(ctx, policy)
+	if err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if !updated.DigestEnabled || updated.DigestWindowSeconds != 900 {
+		t.Errorf("Expected digest settings to be updated, got %+v", updated)
+	}
+
+	got, err := store.GetNotificationPolicy(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get policy: %v", err)
+	}
+	if got.Timezone != "Europe/Berlin" || !got.QuietHoursEnabled || got.QuietHoursEnd != "07:00" || !got.DigestEnabled {
+		t.Errorf("Unexpected policy: %+v", got)
+	}
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// NotificationRoute sends alert events matching a severity and machine tag filter to specific destinations.
+// Users without routes receive every alert on all of their active channels.
+type NotificationRoute struct {
+	ID                   int       `json:"id"`
+	OrgID                int       `json:"org_id"`
+	Name                 string    `json:"name"`
+	Severities           []string  `json:"severities"`   // empty matches every severity
+	MachineTags          []string  `json:"machine_tags"` // empty matches every machine; otherwise the machine needs one of the tags
+	WebhookIDs           []int     `json:"webhook_ids"`
+	TelegramRecipientIDs []int     `json:"telegram_recipient_ids"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// routeColumns are the notification_routes columns in scan order
+const routeColumns = `id, org_id, name, severities, machine_tags, webhook_ids, telegram_recipient_ids, created_at, updated_at`
+
+// scanNotificationRoute scans a notification route row, decoding its JSON list columns
+func scanNotificationRoute(row interface{ Scan(...any) error }) (*NotificationRoute, error) {
+	var route NotificationRoute
+	var severities, machineTags, webhookIDs, telegramIDs string
+
+	if err := row.Scan(&route.ID, &route.OrgID, &route.Name, &severities, &machineTags,
+		&webhookIDs, &telegramIDs, &route.CreatedAt, &route.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	for _, field := range []struct {
+		raw  string
+		dest any
+	}{
+		{severities, &route.Severities},
+		{machineTags, &route.MachineTags},
+		{webhookIDs, &route.WebhookIDs},
+		{telegramIDs, &route.TelegramRecipientIDs},
+	} {
+		if err := json.Unmarshal([]byte(field.raw), field.dest); err != nil {
+			return nil, fmt.Errorf("failed to decode notification route %d: %w", route.ID, err)
+		}
+	}
+
+	return &route, nil
+}
+
+// encodeRouteLists encodes a route's list fields for storage, storing nil lists as empty arrays.
+// Marshalling string and int slices cannot fail.
+func encodeRouteLists(route NotificationRoute) (severities, machineTags, webhookIDs, telegramIDs string) {
+	encode := func(v any) string {
+		data, _ := json.Marshal(v)
+		if string(data) == "null" {
+			return "[]"
+		}
+		return string(data)
+	}
+
+	return encode(route.Severities), encode(route.MachineTags),
+		encode(route.WebhookIDs), encode(route.TelegramRecipientIDs)
+}
+
+// ListNotificationRoutes returns an organization's notification routes
+func (s *SQLiteStore) ListNotificationRoutes(ctx context.Context, orgID int) ([]NotificationRoute, error) {
+	query := `SELECT ` + routeColumns + ` FROM notification_routes WHERE org_id = ? ORDER BY id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification routes: %w", err)
+	}
+	defer rows.Close()
+
+	routes := []NotificationRoute{}
+	for rows.Next() {
+		route, err := scanNotificationRoute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification route: %w", err)
+		}
+		routes = append(routes, *route)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate notification routes: %w", err)
+	}
+
+	return routes, nil
+}
+
+// GetNotificationRoute retrieves a single notification route for a user
+func (s *SQLiteStore) GetNotificationRoute(ctx context.Context, id int, orgID int) (*NotificationRoute, error) {
+	query := `SELECT ` + routeColumns + ` FROM notification_routes WHERE id = ? AND org_id = ?`
+
+	route, err := scanNotificationRoute(s.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("notification route with id %d not found for organization %d", id, orgID)
+		}
+		return nil, fmt.Errorf("failed to get notification route: %w", err)
+	}
+
+	return route, nil
+}
+
+// CreateNotificationRoute creates a notification route for route.OrgID
+func (s *SQLiteStore) CreateNotificationRoute(ctx context.Context, route NotificationRoute) (*NotificationRoute, error) {
+	severities, machineTags, webhookIDs, telegramIDs := encodeRouteLists(route)
+
+	now := time.Now()
+	query := `INSERT INTO notification_routes (org_id, name, severities, machine_tags, webhook_ids, telegram_recipient_ids, created_at, updated_at)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+              RETURNING ` + routeColumns
+
+	created, err := scanNotificationRoute(s.db.QueryRowContext(ctx, query, route.OrgID, route.Name,
+		severities, machineTags, webhookIDs, telegramIDs, now, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification route: %w", err)
+	}
+
+	return created, nil
+}
+
+// UpdateNotificationRoute replaces an existing notification route owned by route.OrgID
+func (s *SQLiteStore) UpdateNotificationRoute(ctx context.Context, route NotificationRoute) (*NotificationRoute, error) {
+	severities, machineTags, webhookIDs, telegramIDs := encodeRouteLists(route)
+
+	query := `UPDATE notification_routes
+              SET name = ?, severities = ?, machine_tags = ?, webhook_ids = ?, telegram_recipient_ids = ?, updated_at = ?
+              WHERE id = ? AND org_id = ?
+              RETURNING ` + routeColumns
+
+	updated, err := scanNotificationRoute(s.db.QueryRowContext(ctx, query, route.Name,
+		severities, machineTags, webhookIDs, telegramIDs, time.Now(), route.ID, route.OrgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("notification route with id %d not found for organization %d", route.ID, route.OrgID)
+		}
+		return nil, fmt.Errorf("failed to update notification route: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteNotificationRoute deletes an organization's notification route
+func (s *SQLiteStore) DeleteNotificationRoute(ctx context.Context, id int, orgID int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM notification_routes WHERE id = ? AND org_id = ?`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification route: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify deletion: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("notification route with id %d not found for organization %d", id, orgID)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNotificationRoutes_CRUD(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "routes@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	other, err := store.CreateUser(ctx, "other@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	route, err := store.CreateNotificationRoute(ctx, NotificationRoute{
+		OrgID:      user.ID,
+		Name:       "Page on-call",
+		Severities: []string{SeverityCritical},
+		WebhookIDs: []int{3, 4},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create route: %v", err)
+	}
+	if !reflect.DeepEqual(route.WebhookIDs, []int{3, 4}) || route.MachineTags == nil || len(route.TelegramRecipientIDs) != 0 {
+		t.Errorf("Unexpected route: %+v", route)
+	}
+
+	route.MachineTags = []string{"db"}
+	route.TelegramRecipientIDs = []int{7}
+	if _, err := store.UpdateNotificationRoute(ctx, *route); err != nil {
+		t.Fatalf("Failed to update route: %v", err)
+	}
+
+	routes, err := store.ListNotificationRoutes(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to list routes: %v", err)
+	}
+	if len(routes) != 1 || !reflect.DeepEqual(routes[0].MachineTags, []string{"db"}) || !reflect.DeepEqual(routes[0].TelegramRecipientIDs, []int{7}) {
+		t.Errorf("Unexpected routes after update: %+v", routes)
+	}
+
+	// Routes are scoped to their owner
+	if _, err := store.GetNotificationRoute(ctx, route.ID, other.ID); err == nil {
+		t.Error("Expected route lookup by another user to fail")
+	}
+	if err := store.DeleteNotificationRoute(ctx, route.ID, other.ID); err == nil {
+		t.Error("Expected delete by another user to fail")
+	}
+
+	if err := store.DeleteNotificationRoute(ctx, route.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete route: %v", err)
+	}
+	if routes, _ := store.ListNotificationRoutes(ctx, user.ID); len(routes) != 0 {
+		t.Errorf("Expected no routes after delete, got %d", len(routes))
+	}
+}
+
+func TestMachineTags(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "tags@example.com", "hash")
+	machine, err := store.CreateMachine(ctx, user.ID, "db-01", "db-01.local", "", "keyhash")
+	if err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+
+	if err := store.SetMachineTags(ctx, machine.ID, []string{"prod", "db"}); err != nil {
+		t.Fatalf("Failed to set tags: %v", err)
+	}
+	if err := store.SetMachineTags(ctx, machine.ID, []string{"staging", "db"}); err != nil {
+		t.Fatalf("Failed to replace tags: %v", err)
+	}
+
+	tags, err := store.GetMachineTags(ctx, machine.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tags: %v", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"db", "staging"}) {
+		t.Errorf("Expected [db staging], got %v", tags)
+	}
+}
+
+func TestCreateAlertEvent_SeverityAndMachine(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "sev@example.com", "hash")
+	machine, _ := store.CreateMachine(ctx, user.ID, "web-01", "web-01.local", "", "keyhash")
+	critical := testRule(user.ID, "Disk full", "disk_used_pct", "above", 95, 1)
+	critical.Severity = SeverityCritical
+	rule, err := store.SaveAlertRule(ctx, critical)
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+
+	event, err := store.CreateAlertEvent(ctx, rule.ID, &machine.ID, 97)
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+	if event.Severity != SeverityCritical || event.MachineID == nil || *event.MachineID != machine.ID {
+		t.Errorf("Expected critical event for machine %d, got %+v", machine.ID, event)
+	}
+
+	// Changing the rule later does not rewrite history
+	rule.Severity = SeverityInfo
+	store.SaveAlertRule(ctx, *rule)
+	events, _ := store.ListAlertEvents(ctx, 10)
+	if events[0].Severity != SeverityCritical {
+		t.Errorf("Expected event to keep its original severity, got %q", events[0].Severity)
+	}
+}
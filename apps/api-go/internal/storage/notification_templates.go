@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NotificationTemplate is a user-defined message template for a notification channel and event type
+type NotificationTemplate struct {
+	ID        int       `json:"id"`
+	OrgID     int       `json:"org_id"`
+	Channel   string    `json:"channel"`    // "webhook" | "telegram"
+	EventType string    `json:"event_type"` // "alert.fired", "alert.resolved", "machine.offline", "machine.online"
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListNotificationTemplates retrieves all notification templates for a user
+func (s *SQLiteStore) ListNotificationTemplates(ctx context.Context, orgID int) ([]NotificationTemplate, error) {
+	query := `
+		SELECT id, org_id, channel, event_type, body, created_at, updated_at
+		FROM notification_templates
+		WHERE org_id = ?
+		ORDER BY channel ASC, event_type ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []NotificationTemplate{}
+	for rows.Next() {
+		var t NotificationTemplate
+		if err := rows.Scan(&t.ID, &t.OrgID, &t.Channel, &t.EventType, &t.Body, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// GetNotificationTemplate retrieves the template a user configured for a channel and event type.
+// Returns ErrNotificationTemplateNotFound when none is configured.
+func (s *SQLiteStore) GetNotificationTemplate(ctx context.Context, orgID int, channel, eventType string) (*NotificationTemplate, error) {
+	query := `
+		SELECT id, org_id, channel, event_type, body, created_at, updated_at
+		FROM notification_templates
+		WHERE org_id = ? AND channel = ? AND event_type = ?
+	`
+
+	var t NotificationTemplate
+	err := s.db.QueryRowContext(ctx, query, orgID, channel, eventType).Scan(
+		&t.ID, &t.OrgID, &t.Channel, &t.EventType, &t.Body, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotificationTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get notification template: %w", err)
+	}
+
+	return &t, nil
+}
+
+// UpsertNotificationTemplate creates or replaces the template for a user, channel and event type
+func (s *SQLiteStore) UpsertNotificationTemplate(ctx context.Context, orgID int, channel, eventType, body string) (*NotificationTemplate, error) {
+	now := time.Now()
+	query := `
+		INSERT INTO notification_templates (org_id, channel, event_type, body, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(org_id, channel, event_type) DO UPDATE SET
+			body = excluded.body,
+			updated_at = excluded.updated_at
+		RETURNING id, org_id, channel, event_type, body, created_at, updated_at
+	`
+
+	var t NotificationTemplate
+	err := s.db.QueryRowContext(ctx, query, orgID, channel, eventType, body, now, now).Scan(
+		&t.ID, &t.OrgID, &t.Channel, &t.EventType, &t.Body, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save notification template: %w", err)
+	}
+
+	return &t, nil
+}
+
+// DeleteNotificationTemplate deletes a notification template owned by the user
+func (s *SQLiteStore) DeleteNotificationTemplate(ctx context.Context, id int, orgID int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM notification_templates WHERE id = ? AND org_id = ?`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("notification template with id %d not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNotificationTemplates_CRUD(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "templates@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// Missing template returns sentinel error
+	_, err = store.GetNotificationTemplate(ctx, user.ID, "telegram", "alert.fired")
+	if !errors.Is(err, ErrNotificationTemplateNotFound) {
+		t.Fatalf("Expected ErrNotificationTemplateNotFound, got %v", err)
+	}
+
+	created, err := store.UpsertNotificationTemplate(ctx, user.ID, "telegram", "alert.fired", "{{.Rule.Name}} fired")
+	if err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	if created.ID == 0 || created.Body != "{{.Rule.Name}} fired" {
+		t.Errorf("Unexpected created template: %+v", created)
+	}
+
+	// Upserting the same channel/event replaces the body and keeps the ID
+	updated, err := store.UpsertNotificationTemplate(ctx, user.ID, "telegram", "alert.fired", "{{.Rule.Name}} is firing")
+	if err != nil {
+		t.Fatalf("Failed to update template: %v", err)
+	}
+	if updated.ID != created.ID {
+		t.Errorf("Expected upsert to keep ID %d, got %d", created.ID, updated.ID)
+	}
+
+	got, err := store.GetNotificationTemplate(ctx, user.ID, "telegram", "alert.fired")
+	if err != nil {
+		t.Fatalf("Failed to get template: %v", err)
+	}
+	if got.Body != "{{.Rule.Name}} is firing" {
+		t.Errorf("Expected updated body, got %q", got.Body)
+	}
+
+	if _, err := store.UpsertNotificationTemplate(ctx, user.ID, "webhook", "machine.offline", `{"m": {{json .Machine.Name}}}`); err != nil {
+		t.Fatalf("Failed to create webhook template: %v", err)
+	}
+
+	templates, err := store.ListNotificationTemplates(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to list templates: %v", err)
+	}
+	if len(templates) != 2 {
+		t.Fatalf("Expected 2 templates, got %d", len(templates))
+	}
+
+	// Invalid channel is rejected by the schema
+	if _, err := store.UpsertNotificationTemplate(ctx, user.ID, "sms", "alert.fired", "x"); err == nil {
+		t.Error("Expected error for invalid channel")
+	}
+
+	// Other users cannot delete the template
+	other, err := store.CreateUser(ctx, "other@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create second user: %v", err)
+	}
+	if err := store.DeleteNotificationTemplate(ctx, created.ID, other.ID); err == nil {
+		t.Error("Expected error deleting another user's template")
+	}
+
+	if err := store.DeleteNotificationTemplate(ctx, created.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete template: %v", err)
+	}
+	if _, err := store.GetNotificationTemplate(ctx, user.ID, "telegram", "alert.fired"); !errors.Is(err, ErrNotificationTemplateNotFound) {
+		t.Errorf("Expected template to be deleted, got %v", err)
+	}
+}
+
+func TestAlertEvents_Resolve(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+
+	rule, err := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 80.0, 1))
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+
+	event, err := store.CreateAlertEvent(ctx, rule.ID, nil, 91.0)
+	if err != nil {
+		t.Fatalf("Failed to create alert event: %v", err)
+	}
+	if event.ResolvedAt != nil {
+		t.Error("Expected resolved_at to be nil initially")
+	}
+
+	resolved, err := store.ResolveAlertEvent(ctx, event.ID)
+	if err != nil {
+		t.Fatalf("Failed to resolve alert event: %v", err)
+	}
+	if resolved.ResolvedAt == nil {
+		t.Error("Expected resolved_at to be set")
+	}
+
+	if _, err := store.ResolveAlertEvent(ctx, event.ID); err == nil {
+		t.Error("Expected error when resolving already resolved event")
+	}
+
+	events, err := store.ListAlertEvents(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to list alert events: %v", err)
+	}
+	if len(events) != 1 || events[0].ResolvedAt == nil {
+		t.Errorf("Expected listed event to carry resolved_at, got %+v", events)
+	}
+}
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UserIdentity links a user to their account at an OpenID Connect provider
+type UserIdentity struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"` // the provider's stable user ID ("sub" claim)
+	Email       string     `json:"email"`   // email the provider last reported
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCLogin is a single sign-on login in progress, between the redirect to the provider and its
+// callback. Only a hash of the state parameter is stored.
+type OIDCLogin struct {
+	StateHash    string    `json:"-"`
+	Nonce        string    `json:"-"`
+	CodeVerifier string    `json:"-"` // PKCE verifier sent with the authorization code
+	ReturnTo     string    `json:"return_to"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// GetUserByIdentity returns the user linked to the provider account
+func (s *SQLiteStore) GetUserByIdentity(ctx context.Context, issuer, subject string) (*User, error) {
+	var userID int
+	err := s.db.QueryRowContext(ctx, `SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?`,
+		issuer, subject).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user identity: %w", err)
+	}
+
+	return s.GetUserByID(ctx, userID)
+}
+
+// LinkUserIdentity links the provider account to the user, or records another login if it is
+// already linked
+func (s *SQLiteStore) LinkUserIdentity(ctx context.Context, identity UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, issuer, subject, email, created_at, last_login_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(issuer, subject) DO UPDATE SET user_id = excluded.user_id, email = excluded.email,
+			last_login_at = excluded.last_login_at`
+
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, query, identity.UserID, identity.Issuer, identity.Subject, identity.Email, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to link user identity: %w", err)
+	}
+
+	return nil
+}
+
+// CreateOIDCLogin records a single sign-on login in progress, clearing out expired ones
+func (s *SQLiteStore) CreateOIDCLogin(ctx context.Context, login OIDCLogin) error {
+	now := time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM oidc_logins WHERE expires_at <= ?`, now); err != nil {
+		return fmt.Errorf("failed to delete expired OIDC logins: %w", err)
+	}
+
+	query := `
+		INSERT INTO oidc_logins (state_hash, nonce, code_verifier, return_to, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.ExecContext(ctx, query, login.StateHash, login.Nonce, login.CodeVerifier, login.ReturnTo,
+		login.ExpiresAt.UTC(), now)
+	if err != nil {
+		return fmt.Errorf("failed to create OIDC login: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeOIDCLogin removes and returns the unexpired login with the state hash, so each state is
+// accepted once
+func (s *SQLiteStore) ConsumeOIDCLogin(ctx context.Context, stateHash string) (*OIDCLogin, error) {
+	query := `
+		DELETE FROM oidc_logins WHERE state_hash = ?
+		RETURNING state_hash, nonce, code_verifier, return_to, expires_at, created_at`
+
+	login := &OIDCLogin{}
+	err := s.db.QueryRowContext(ctx, query, stateHash).Scan(&login.StateHash, &login.Nonce, &login.CodeVerifier,
+		&login.ReturnTo, &login.ExpiresAt, &login.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOIDCLoginNotFound
+		}
+		return nil, fmt.Errorf("failed to consume OIDC login: %w", err)
+	}
+	if !time.Now().Before(login.ExpiresAt) {
+		return nil, ErrOIDCLoginNotFound
+	}
+
+	return login, nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUserIdentities(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice@example.com", "hash")
+	bob, _ := store.CreateUser(ctx, "bob@example.com", "hash")
+	issuer := "https://idp.example.com"
+
+	if _, err := store.GetUserByIdentity(ctx, issuer, "sub-1"); err != ErrUserNotFound {
+		t.Fatalf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	if err := store.LinkUserIdentity(ctx, UserIdentity{UserID: alice.ID, Issuer: issuer, Subject: "sub-1", Email: alice.Email}); err != nil {
+		t.Fatalf("Failed to link identity: %v", err)
+	}
+	got, err := store.GetUserByIdentity(ctx, issuer, "sub-1")
+	if err != nil {
+		t.Fatalf("Failed to get user by identity: %v", err)
+	}
+	if got.ID != alice.ID {
+		t.Errorf("Expected user %d, got %d", alice.ID, got.ID)
+	}
+
+	// The same subject at another issuer is another account
+	if _, err := store.GetUserByIdentity(ctx, "https://other.example.com", "sub-1"); err != ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound for another issuer, got %v", err)
+	}
+
+	// Linking again moves the identity rather than duplicating it
+	if err := store.LinkUserIdentity(ctx, UserIdentity{UserID: bob.ID, Issuer: issuer, Subject: "sub-1", Email: bob.Email}); err != nil {
+		t.Fatalf("Failed to relink identity: %v", err)
+	}
+	if got, _ := store.GetUserByIdentity(ctx, issuer, "sub-1"); got == nil || got.ID != bob.ID {
+		t.Errorf("Expected the identity to belong to user %d, got %+v", bob.ID, got)
+	}
+
+	// Deleting the user removes the link
+	if err := store.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if _, err := store.GetUserByIdentity(ctx, issuer, "sub-1"); err != ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound after deleting the user, got %v", err)
+	}
+}
+
+func TestOIDCLogins(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	login := OIDCLogin{
+		StateHash:    "state-hash",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+		ReturnTo:     "/machines",
+		ExpiresAt:    time.Now().Add(5 * time.Minute),
+	}
+	if err := store.CreateOIDCLogin(ctx, login); err != nil {
+		t.Fatalf("Failed to create OIDC login: %v", err)
+	}
+
+	got, err := store.ConsumeOIDCLogin(ctx, "state-hash")
+	if err != nil {
+		t.Fatalf("Failed to consume OIDC login: %v", err)
+	}
+	if got.Nonce != "nonce" || got.CodeVerifier != "verifier" || got.ReturnTo != "/machines" {
+		t.Errorf("Unexpected OIDC login %+v", got)
+	}
+
+	// Each login is used once
+	if _, err := store.ConsumeOIDCLogin(ctx, "state-hash"); err != ErrOIDCLoginNotFound {
+		t.Errorf("Expected ErrOIDCLoginNotFound on reuse, got %v", err)
+	}
+
+	// Expired logins are refused
+	login.StateHash = "expired-hash"
+	login.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := store.CreateOIDCLogin(ctx, login); err != nil {
+		t.Fatalf("Failed to create OIDC login: %v", err)
+	}
+	if _, err := store.ConsumeOIDCLogin(ctx, "expired-hash"); err != ErrOIDCLoginNotFound {
+		t.Errorf("Expected ErrOIDCLoginNotFound for an expired login, got %v", err)
+	}
+}
@@ -0,0 +1,541 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Organization owns machines, notification channels and their settings, which its members share.
+// Every user has a personal organization, created with them and deleted with them.
+type Organization struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Personal  bool      `json:"personal"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserOrganization is an organization along with a member's role in it
+type UserOrganization struct {
+	Organization
+	Role string `json:"role"`
+}
+
+// OrgMember is a user's membership of an organization. Members have one of the user roles,
+// which applies to the organization's resources.
+type OrgMember struct {
+	OrgID     int       `json:"org_id"`
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrgInvitation invites an email address to join an organization with a role
+type OrgInvitation struct {
+	ID         int        `json:"id"`
+	OrgID      int        `json:"org_id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	InvitedBy  *int       `json:"invited_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	TokenHash  string     `json:"-"`
+}
+
+// organizationColumns are the organizations columns in scan order
+const organizationColumns = `id, name, personal_user_id, created_at`
+
+// scanOrganization scans a row of organizationColumns, followed by any extra destinations
+func scanOrganization(row interface{ Scan(...any) error }, extra ...any) (*Organization, error) {
+	var org Organization
+	var personalUserID sql.NullInt64
+	if err := row.Scan(append([]any{&org.ID, &org.Name, &personalUserID, &org.CreatedAt}, extra...)...); err != nil {
+		return nil, err
+	}
+	org.Personal = personalUserID.Valid
+	return &org, nil
+}
+
+// orgInvitationColumns are the org_invitations columns in scan order
+const orgInvitationColumns = `id, org_id, email, role, invited_by, created_at, expires_at, accepted_at, token_hash`
+
+// orgInvitationFields returns the scan destinations for orgInvitationColumns
+func orgInvitationFields(i *OrgInvitation) []any {
+	return []any{&i.ID, &i.OrgID, &i.Email, &i.Role, &i.InvitedBy, &i.CreatedAt, &i.ExpiresAt, &i.AcceptedAt, &i.TokenHash}
+}
+
+// createPersonalOrganization creates a new user's personal organization, with them as its owner
+func createPersonalOrganization(ctx context.Context, tx *sql.Tx, user *User) error {
+	var orgID int
+	err := tx.QueryRowContext(ctx, `INSERT INTO organizations (name, personal_user_id, created_at) VALUES (?, ?, ?) RETURNING id`,
+		user.Email, user.ID, user.CreatedAt).Scan(&orgID)
+	if err != nil {
+		return fmt.Errorf("failed to create personal organization: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO org_members (org_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`,
+		orgID, user.ID, RoleOwner, user.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add personal organization owner: %w", err)
+	}
+	return nil
+}
+
+// leaveOrganizations removes a user who is being deleted from their organizations. Organizations
+// they are the only member of are deleted; a personal organization shared with others is kept
+// and stops being personal. It refuses to leave an organization with other members without an owner.
+func leaveOrganizations(ctx context.Context, tx *sql.Tx, userID int) error {
+	query := `
+		SELECT o.id, o.name, o.personal_user_id IS NOT NULL, m.role,
+		       (SELECT COUNT(*) FROM org_members WHERE org_id = o.id),
+		       (SELECT COUNT(*) FROM org_members WHERE org_id = o.id AND role = ?)
+		FROM organizations o
+		JOIN org_members m ON m.org_id = o.id AND m.user_id = ?`
+
+	rows, err := tx.QueryContext(ctx, query, RoleOwner, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list user organizations: %w", err)
+	}
+
+	var deleteIDs, detachIDs []int
+	for rows.Next() {
+		var id, members, owners int
+		var name, role string
+		var personal bool
+		if err := rows.Scan(&id, &name, &personal, &role, &members, &owners); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan organization: %w", err)
+		}
+
+		switch {
+		case members <= 1:
+			deleteIDs = append(deleteIDs, id)
+		case role == RoleOwner && owners <= 1:
+			rows.Close()
+			return fmt.Errorf("cannot delete the last owner of organization %s", name)
+		case personal:
+			detachIDs = append(detachIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate organizations: %w", err)
+	}
+
+	for _, id := range deleteIDs {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM organizations WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to delete organization: %w", err)
+		}
+	}
+	for _, id := range detachIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE organizations SET personal_user_id = NULL WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("failed to keep shared organization: %w", err)
+		}
+	}
+	return nil
+}
+
+// CreateOrganization creates an organization with ownerID as its owner
+func (s *SQLiteStore) CreateOrganization(ctx context.Context, name string, ownerID int) (*Organization, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	org, err := scanOrganization(tx.QueryRowContext(ctx,
+		`INSERT INTO organizations (name, created_at) VALUES (?, ?) RETURNING `+organizationColumns, name, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO org_members (org_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`,
+		org.ID, ownerID, RoleOwner, now)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to add organization owner: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit organization: %w", err)
+	}
+	return org, nil
+}
+
+// GetOrganization retrieves an organization by ID
+func (s *SQLiteStore) GetOrganization(ctx context.Context, id int) (*Organization, error) {
+	org, err := scanOrganization(s.db.QueryRowContext(ctx, `SELECT `+organizationColumns+` FROM organizations WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return org, nil
+}
+
+// ListOrganizations returns every organization, oldest first
+func (s *SQLiteStore) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+organizationColumns+` FROM organizations ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []Organization{}
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, *org)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// ListUserOrganizations returns the organizations a user belongs to with their role in each,
+// their personal organization first
+func (s *SQLiteStore) ListUserOrganizations(ctx context.Context, userID int) ([]UserOrganization, error) {
+	query := `
+		SELECT o.id, o.name, o.personal_user_id, o.created_at, m.role
+		FROM organizations o
+		JOIN org_members m ON m.org_id = o.id
+		WHERE m.user_id = ?
+		ORDER BY o.personal_user_id IS NOT m.user_id, o.name ASC, o.id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []UserOrganization{}
+	for rows.Next() {
+		var role string
+		org, err := scanOrganization(rows, &role)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, UserOrganization{Organization: *org, Role: role})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// GetPersonalOrganization retrieves a user's personal organization
+func (s *SQLiteStore) GetPersonalOrganization(ctx context.Context, userID int) (*Organization, error) {
+	org, err := scanOrganization(s.db.QueryRowContext(ctx,
+		`SELECT `+organizationColumns+` FROM organizations WHERE personal_user_id = ?`, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("failed to get personal organization: %w", err)
+	}
+	return org, nil
+}
+
+// RenameOrganization changes an organization's name
+func (s *SQLiteStore) RenameOrganization(ctx context.Context, id int, name string) (*Organization, error) {
+	org, err := scanOrganization(s.db.QueryRowContext(ctx,
+		`UPDATE organizations SET name = ? WHERE id = ? RETURNING `+organizationColumns, name, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("failed to rename organization: %w", err)
+	}
+	return org, nil
+}
+
+// DeleteOrganization deletes an organization along with everything it owns. Personal
+// organizations are only deleted with their user.
+func (s *SQLiteStore) DeleteOrganization(ctx context.Context, id int) error {
+	org, err := s.GetOrganization(ctx, id)
+	if err != nil {
+		return err
+	}
+	if org.Personal {
+		return fmt.Errorf("cannot delete a personal organization")
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM organizations WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	return nil
+}
+
+// GetOrgMember retrieves a user's membership of an organization
+func (s *SQLiteStore) GetOrgMember(ctx context.Context, orgID, userID int) (*OrgMember, error) {
+	query := `
+		SELECT m.org_id, m.user_id, u.email, m.role, m.created_at
+		FROM org_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ? AND m.user_id = ?`
+
+	var m OrgMember
+	err := s.db.QueryRowContext(ctx, query, orgID, userID).Scan(&m.OrgID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrgMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to get organization member: %w", err)
+	}
+	return &m, nil
+}
+
+// ListOrgMembers returns an organization's members ordered by email
+func (s *SQLiteStore) ListOrgMembers(ctx context.Context, orgID int) ([]OrgMember, error) {
+	query := `
+		SELECT m.org_id, m.user_id, u.email, m.role, m.created_at
+		FROM org_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = ?
+		ORDER BY u.email ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []OrgMember{}
+	for rows.Next() {
+		var m OrgMember
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization member: %w", err)
+		}
+		members = append(members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organization members: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddOrgMember adds a user to an organization with the role
+func (s *SQLiteStore) AddOrgMember(ctx context.Context, orgID, userID int, role string) (*OrgMember, error) {
+	if err := addOrgMember(ctx, s.db, orgID, userID, role); err != nil {
+		return nil, err
+	}
+	return s.GetOrgMember(ctx, orgID, userID)
+}
+
+// addOrgMember inserts a membership using db, which may be a transaction
+func addOrgMember(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, orgID, userID int, role string) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO org_members (org_id, user_id, role, created_at) VALUES (?, ?, ?, ?)`,
+		orgID, userID, role, time.Now())
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "PRIMARY KEY") {
+			return fmt.Errorf("user %d is already a member of organization %d", userID, orgID)
+		}
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return fmt.Errorf("organization %d or user %d not found", orgID, userID)
+		}
+		return fmt.Errorf("failed to add organization member: %w", err)
+	}
+	return nil
+}
+
+// checkLastOrgOwner returns an error if the user is the organization's only owner
+func checkLastOrgOwner(ctx context.Context, tx *sql.Tx, orgID, userID int) error {
+	var role string
+	err := tx.QueryRowContext(ctx, `SELECT role FROM org_members WHERE org_id = ? AND user_id = ?`, orgID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrOrgMemberNotFound
+		}
+		return fmt.Errorf("failed to get organization member: %w", err)
+	}
+	if role != RoleOwner {
+		return nil
+	}
+
+	var owners int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM org_members WHERE org_id = ? AND role = ?`, orgID, RoleOwner).Scan(&owners)
+	if err != nil {
+		return fmt.Errorf("failed to count organization owners: %w", err)
+	}
+	if owners <= 1 {
+		return fmt.Errorf("cannot remove the last owner of the organization")
+	}
+	return nil
+}
+
+// SetOrgMemberRole changes a member's role, refusing to demote the organization's last owner
+func (s *SQLiteStore) SetOrgMemberRole(ctx context.Context, orgID, userID int, role string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if role != RoleOwner {
+		if err := checkLastOrgOwner(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE org_members SET role = ? WHERE org_id = ? AND user_id = ?`, role, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set organization member role: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to verify role change: %w", err)
+	} else if rows == 0 {
+		return ErrOrgMemberNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role change: %w", err)
+	}
+	return nil
+}
+
+// RemoveOrgMember removes a user from an organization, refusing to remove its last owner
+func (s *SQLiteStore) RemoveOrgMember(ctx context.Context, orgID, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkLastOrgOwner(ctx, tx, orgID, userID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM org_members WHERE org_id = ? AND user_id = ?`, orgID, userID); err != nil {
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit member removal: %w", err)
+	}
+	return nil
+}
+
+// CreateOrgInvitation stores an invitation to an organization
+func (s *SQLiteStore) CreateOrgInvitation(ctx context.Context, invitation OrgInvitation) (*OrgInvitation, error) {
+	query := `
+		INSERT INTO org_invitations (org_id, email, role, token_hash, invited_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + orgInvitationColumns
+
+	created := &OrgInvitation{}
+	err := s.db.QueryRowContext(ctx, query, invitation.OrgID, invitation.Email, invitation.Role, invitation.TokenHash,
+		invitation.InvitedBy, time.Now(), invitation.ExpiresAt).Scan(orgInvitationFields(created)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, fmt.Errorf("failed to create organization invitation: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetOrgInvitationByHash retrieves an invitation by the hash of its token, including accepted and expired ones
+func (s *SQLiteStore) GetOrgInvitationByHash(ctx context.Context, tokenHash string) (*OrgInvitation, error) {
+	invitation := &OrgInvitation{}
+	err := s.db.QueryRowContext(ctx, `SELECT `+orgInvitationColumns+` FROM org_invitations WHERE token_hash = ?`, tokenHash).
+		Scan(orgInvitationFields(invitation)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrgInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get organization invitation: %w", err)
+	}
+	return invitation, nil
+}
+
+// ListOrgInvitations returns an organization's invitations that have not been accepted, newest first
+func (s *SQLiteStore) ListOrgInvitations(ctx context.Context, orgID int) ([]OrgInvitation, error) {
+	query := `SELECT ` + orgInvitationColumns + `
+		FROM org_invitations
+		WHERE org_id = ? AND accepted_at IS NULL
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization invitations: %w", err)
+	}
+	defer rows.Close()
+
+	invitations := []OrgInvitation{}
+	for rows.Next() {
+		var invitation OrgInvitation
+		if err := rows.Scan(orgInvitationFields(&invitation)...); err != nil {
+			return nil, fmt.Errorf("failed to scan organization invitation: %w", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organization invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// DeleteOrgInvitation deletes one of an organization's invitations
+func (s *SQLiteStore) DeleteOrgInvitation(ctx context.Context, id, orgID int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM org_invitations WHERE id = ? AND org_id = ?`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization invitation: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify invitation deletion: %w", err)
+	}
+	if rows == 0 {
+		return ErrOrgInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptOrgInvitation adds the user to the invitation's organization with its role and marks it accepted
+func (s *SQLiteStore) AcceptOrgInvitation(ctx context.Context, id, userID int) (*OrgMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	invitation := &OrgInvitation{}
+	err = tx.QueryRowContext(ctx, `SELECT `+orgInvitationColumns+` FROM org_invitations WHERE id = ? AND accepted_at IS NULL`, id).
+		Scan(orgInvitationFields(invitation)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrgInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get organization invitation: %w", err)
+	}
+
+	if err := addOrgMember(ctx, tx, invitation.OrgID, userID, invitation.Role); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE org_invitations SET accepted_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+		return nil, fmt.Errorf("failed to accept organization invitation: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invitation: %w", err)
+	}
+	return s.GetOrgMember(ctx, invitation.OrgID, userID)
+}
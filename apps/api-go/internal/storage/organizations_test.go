@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrganizations_PersonalOrganization(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "personal@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	org, err := store.GetPersonalOrganization(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get personal organization: %v", err)
+	}
+	if !org.Personal || org.Name != user.Email {
+		t.Errorf("Unexpected personal organization: %+v", org)
+	}
+
+	member, err := store.GetOrgMember(ctx, org.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get membership: %v", err)
+	}
+	if member.Role != RoleOwner || member.Email != user.Email {
+		t.Errorf("Expected the user to own their personal organization, got %+v", member)
+	}
+
+	if err := store.DeleteOrganization(ctx, org.ID); err == nil {
+		t.Error("Expected deleting a personal organization to fail")
+	}
+}
+
+func TestOrganizations_CRUD(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	other, _ := store.CreateUser(ctx, "other@example.com", "hash")
+
+	org, err := store.CreateOrganization(ctx, "Platform", owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to create organization: %v", err)
+	}
+	if org.Personal {
+		t.Error("Expected a created organization not to be personal")
+	}
+
+	orgs, err := store.ListUserOrganizations(ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("Failed to list organizations: %v", err)
+	}
+	if len(orgs) != 2 || !orgs[0].Personal || orgs[1].ID != org.ID || orgs[1].Role != RoleOwner {
+		t.Errorf("Expected the personal organization first and then Platform, got %+v", orgs)
+	}
+	if orgs, _ := store.ListUserOrganizations(ctx, other.ID); len(orgs) != 1 {
+		t.Errorf("Expected the other user to only see their personal organization, got %+v", orgs)
+	}
+
+	renamed, err := store.RenameOrganization(ctx, org.ID, "Infrastructure")
+	if err != nil {
+		t.Fatalf("Failed to rename organization: %v", err)
+	}
+	if renamed.Name != "Infrastructure" {
+		t.Errorf("Expected renamed organization, got %+v", renamed)
+	}
+
+	// Deleting an organization deletes what it owns
+	machine, err := store.CreateMachine(ctx, org.ID, "web-1", "web-1", "", "key-hash")
+	if err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+	if err := store.DeleteOrganization(ctx, org.ID); err != nil {
+		t.Fatalf("Failed to delete organization: %v", err)
+	}
+	if _, err := store.GetOrganization(ctx, org.ID); !errors.Is(err, ErrOrganizationNotFound) {
+		t.Errorf("Expected ErrOrganizationNotFound, got %v", err)
+	}
+	if _, err := store.GetMachineByID(ctx, machine.ID); err == nil {
+		t.Error("Expected the organization's machine to be deleted with it")
+	}
+}
+
+func TestOrganizations_Members(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	member, _ := store.CreateUser(ctx, "member@example.com", "hash")
+	org, _ := store.CreateOrganization(ctx, "Platform", owner.ID)
+
+	if _, err := store.AddOrgMember(ctx, org.ID, member.ID, RoleViewer); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	if _, err := store.AddOrgMember(ctx, org.ID, member.ID, RoleViewer); err == nil {
+		t.Error("Expected adding an existing member to fail")
+	}
+
+	members, err := store.ListOrgMembers(ctx, org.ID)
+	if err != nil {
+		t.Fatalf("Failed to list members: %v", err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("Expected 2 members, got %+v", members)
+	}
+
+	if err := store.SetOrgMemberRole(ctx, org.ID, member.ID, RoleOperator); err != nil {
+		t.Fatalf("Failed to set member role: %v", err)
+	}
+	if got, _ := store.GetOrgMember(ctx, org.ID, member.ID); got.Role != RoleOperator {
+		t.Errorf("Expected operator, got %+v", got)
+	}
+
+	// An organization always keeps an owner
+	if err := store.SetOrgMemberRole(ctx, org.ID, owner.ID, RoleAdmin); err == nil || !strings.Contains(err.Error(), "last owner") {
+		t.Errorf("Expected demoting the last owner to fail, got %v", err)
+	}
+	if err := store.RemoveOrgMember(ctx, org.ID, owner.ID); err == nil || !strings.Contains(err.Error(), "last owner") {
+		t.Errorf("Expected removing the last owner to fail, got %v", err)
+	}
+
+	if err := store.RemoveOrgMember(ctx, org.ID, member.ID); err != nil {
+		t.Fatalf("Failed to remove member: %v", err)
+	}
+	if _, err := store.GetOrgMember(ctx, org.ID, member.ID); !errors.Is(err, ErrOrgMemberNotFound) {
+		t.Errorf("Expected ErrOrgMemberNotFound, got %v", err)
+	}
+	if err := store.RemoveOrgMember(ctx, org.ID, member.ID); !errors.Is(err, ErrOrgMemberNotFound) {
+		t.Errorf("Expected ErrOrgMemberNotFound removing a non-member, got %v", err)
+	}
+}
+
+func TestOrganizations_Invitations(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	invitee, _ := store.CreateUser(ctx, "invitee@example.com", "hash")
+	org, _ := store.CreateOrganization(ctx, "Platform", owner.ID)
+
+	invitation, err := store.CreateOrgInvitation(ctx, OrgInvitation{
+		OrgID:     org.ID,
+		Email:     invitee.Email,
+		Role:      RoleOperator,
+		InvitedBy: &owner.ID,
+		ExpiresAt: time.Now().Add(time.Hour),
+		TokenHash: "token-hash",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create invitation: %v", err)
+	}
+
+	found, err := store.GetOrgInvitationByHash(ctx, "token-hash")
+	if err != nil || found.ID != invitation.ID {
+		t.Fatalf("Expected to find the invitation by hash, got %+v, %v", found, err)
+	}
+	if invitations, _ := store.ListOrgInvitations(ctx, org.ID); len(invitations) != 1 {
+		t.Errorf("Expected 1 pending invitation, got %+v", invitations)
+	}
+
+	member, err := store.AcceptOrgInvitation(ctx, invitation.ID, invitee.ID)
+	if err != nil {
+		t.Fatalf("Failed to accept invitation: %v", err)
+	}
+	if member.OrgID != org.ID || member.Role != RoleOperator {
+		t.Errorf("Unexpected membership: %+v", member)
+	}
+	if _, err := store.AcceptOrgInvitation(ctx, invitation.ID, invitee.ID); !errors.Is(err, ErrOrgInvitationNotFound) {
+		t.Errorf("Expected accepting twice to fail with ErrOrgInvitationNotFound, got %v", err)
+	}
+	if invitations, _ := store.ListOrgInvitations(ctx, org.ID); len(invitations) != 0 {
+		t.Errorf("Expected accepted invitations not to be listed, got %+v", invitations)
+	}
+
+	// Invitations are scoped to their organization
+	other, _ := store.CreateOrgInvitation(ctx, OrgInvitation{
+		OrgID: org.ID, Email: "late@example.com", Role: RoleViewer, ExpiresAt: time.Now().Add(time.Hour), TokenHash: "other-hash",
+	})
+	personal, _ := store.GetPersonalOrganization(ctx, owner.ID)
+	if err := store.DeleteOrgInvitation(ctx, other.ID, personal.ID); !errors.Is(err, ErrOrgInvitationNotFound) {
+		t.Errorf("Expected deleting through another organization to fail, got %v", err)
+	}
+	if err := store.DeleteOrgInvitation(ctx, other.ID, org.ID); err != nil {
+		t.Errorf("Failed to delete invitation: %v", err)
+	}
+}
+
+func TestOrganizations_DeleteUser(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	owner, _ := store.CreateUser(ctx, "owner@example.com", "hash")
+	member, _ := store.CreateUser(ctx, "member@example.com", "hash")
+
+	// The member's personal organization is shared with the owner, who becomes an owner too
+	shared, _ := store.GetPersonalOrganization(ctx, member.ID)
+	if _, err := store.AddOrgMember(ctx, shared.ID, owner.ID, RoleOwner); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	// The owner is the only owner of an organization the member belongs to
+	team, _ := store.CreateOrganization(ctx, "Team", owner.ID)
+	if _, err := store.AddOrgMember(ctx, team.ID, member.ID, RoleViewer); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+
+	if err := store.DeleteUser(ctx, owner.ID); err == nil || !strings.Contains(err.Error(), "last owner of organization Team") {
+		t.Fatalf("Expected deleting the last owner of Team to fail, got %v", err)
+	}
+
+	if err := store.DeleteUser(ctx, member.ID); err != nil {
+		t.Fatalf("Failed to delete member: %v", err)
+	}
+	kept, err := store.GetOrganization(ctx, shared.ID)
+	if err != nil {
+		t.Fatalf("Expected the shared personal organization to be kept: %v", err)
+	}
+	if kept.Personal {
+		t.Error("Expected the kept organization to no longer be personal")
+	}
+	if members, _ := store.ListOrgMembers(ctx, team.ID); len(members) != 1 {
+		t.Errorf("Expected the member to have left Team, got %+v", members)
+	}
+
+	// Deleting the last member deletes their organizations
+	if err := store.DeleteUser(ctx, owner.ID); err != nil {
+		t.Fatalf("Failed to delete owner: %v", err)
+	}
+	if orgs, _ := store.ListOrganizations(ctx); len(orgs) != 0 {
+		t.Errorf("Expected no organizations left, got %+v", orgs)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PendingDigestEntry is an alert event held back for an organization's digest or quiet hours
+// summary. The rule and event are snapshots taken when the event was held.
+type PendingDigestEntry struct {
+	ID        int        `json:"id"`
+	OrgID     int        `json:"org_id"`
+	Reason    string     `json:"reason"`   // Why the event was held, e.g. "digest" or "quiet_hours"
+	FlushAt   time.Time  `json:"flush_at"` // When the digest holding the event is due
+	EventType string     `json:"event_type"`
+	Rule      AlertRule  `json:"rule"`
+	Event     AlertEvent `json:"event"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AddPendingDigestEntry stores a held alert event and returns it with its ID
+func (s *SQLiteStore) AddPendingDigestEntry(ctx context.Context, entry PendingDigestEntry) (*PendingDigestEntry, error) {
+	rule, err := json.Marshal(entry.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode digest rule: %w", err)
+	}
+	event, err := json.Marshal(entry.Event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode digest event: %w", err)
+	}
+
+	query := `
+		INSERT INTO pending_digest_entries (org_id, reason, flush_at, event_type, rule, event, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := s.db.ExecContext(ctx, query, entry.OrgID, entry.Reason, entry.FlushAt, entry.EventType,
+		string(rule), string(event), entry.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save digest entry: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest entry ID: %w", err)
+	}
+	entry.ID = int(id)
+	return &entry, nil
+}
+
+// ListPendingDigestEntries returns every held alert event, oldest first
+func (s *SQLiteStore) ListPendingDigestEntries(ctx context.Context) ([]PendingDigestEntry, error) {
+	query := `
+		SELECT id, org_id, reason, flush_at, event_type, rule, event, created_at
+		FROM pending_digest_entries
+		ORDER BY id ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []PendingDigestEntry
+	for rows.Next() {
+		var e PendingDigestEntry
+		var rule, event string
+		if err := rows.Scan(&e.ID, &e.OrgID, &e.Reason, &e.FlushAt, &e.EventType, &rule, &event, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan digest entry: %w", err)
+		}
+		if err := json.Unmarshal([]byte(rule), &e.Rule); err != nil {
+			return nil, fmt.Errorf("failed to decode digest rule: %w", err)
+		}
+		if err := json.Unmarshal([]byte(event), &e.Event); err != nil {
+			return nil, fmt.Errorf("failed to decode digest event: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating digest entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// DeletePendingDigestEntries removes an organization's held events up to and including maxID, once
+// the digest holding them has been sent
+func (s *SQLiteStore) DeletePendingDigestEntries(ctx context.Context, orgID, maxID int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM pending_digest_entries WHERE org_id = ? AND id <= ?`, orgID, maxID)
+	if err != nil {
+		return fmt.Errorf("failed to delete digest entries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPendingDigestEntries(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "digest@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	var last *PendingDigestEntry
+	for i := 1; i <= 3; i++ {
+		last, err = store.AddPendingDigestEntry(ctx, PendingDigestEntry{
+			OrgID:     user.ID,
+			Reason:    "digest",
+			FlushAt:   now.Add(5 * time.Minute),
+			EventType: "alert.fired",
+			Rule:      AlertRule{ID: 7, Name: "High CPU", Severity: SeverityCritical},
+			Event:     AlertEvent{ID: i, RuleID: 7, Value: 91.5},
+			CreatedAt: now,
+		})
+		if err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+
+	entries, err := store.ListPendingDigestEntries(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list entries: %v", err)
+	}
+	if len(entries) != 3 || entries[0].Event.ID != 1 || entries[2].ID != last.ID {
+		t.Fatalf("Expected 3 entries oldest first, got %+v", entries)
+	}
+	if entries[0].Rule.Name != "High CPU" || entries[0].Rule.Severity != SeverityCritical || entries[0].Event.Value != 91.5 {
+		t.Errorf("Expected the rule and event snapshots to round-trip, got %+v", entries[0])
+	}
+	if !entries[0].FlushAt.Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("Expected flush_at %v, got %v", now.Add(5*time.Minute), entries[0].FlushAt)
+	}
+
+	if err := store.DeletePendingDigestEntries(ctx, user.ID, entries[1].ID); err != nil {
+		t.Fatalf("Failed to delete entries: %v", err)
+	}
+	entries, _ = store.ListPendingDigestEntries(ctx)
+	if len(entries) != 1 || entries[0].ID != last.ID {
+		t.Errorf("Expected only the newest entry to remain, got %+v", entries)
+	}
+}
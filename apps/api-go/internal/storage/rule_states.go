@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// AlertRuleState is how far an alert rule's evaluation against one target has progressed. It is
+// persisted so that restarting the API neither resets a pending breach nor fires a firing rule again.
+type AlertRuleState struct {
+	RuleID              int        `json:"rule_id"`
+	MachineID           *int       `json:"machine_id,omitempty"` // machine the rule is evaluated against, nil for the local host
+	ConsecutiveBreaches int        `json:"consecutive_breaches"`
+	ConsecutiveClears   int        `json:"consecutive_clears"`
+	BreachStartedAt     *time.Time `json:"breach_started_at,omitempty"`
+	ClearStartedAt      *time.Time `json:"clear_started_at,omitempty"`
+	Firing              bool       `json:"firing"`
+	LastValue           float64    `json:"last_value"`
+	LastEvaluated       time.Time  `json:"last_evaluated"`
+	OpenEventID         *int       `json:"open_event_id,omitempty"` // event fired by the rule that is not resolved yet
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// ListAlertRuleStates returns the persisted evaluation state of every rule and target
+func (s *SQLiteStore) ListAlertRuleStates(ctx context.Context) ([]AlertRuleState, error) {
+	query := `
+		SELECT rule_id, machine_id, consecutive_breaches, consecutive_clears, breach_started_at, clear_started_at,
+		       firing, last_value, last_evaluated, open_event_id, updated_at
+		FROM alert_rule_states
+		ORDER BY rule_id, machine_id`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rule states: %w", err)
+	}
+	defer rows.Close()
+
+	var states []AlertRuleState
+	for rows.Next() {
+		var st AlertRuleState
+		err := rows.Scan(&st.RuleID, &st.MachineID, &st.ConsecutiveBreaches, &st.ConsecutiveClears,
+			&st.BreachStartedAt, &st.ClearStartedAt, &st.Firing, &st.LastValue, &st.LastEvaluated,
+			&st.OpenEventID, &st.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule state: %w", err)
+		}
+		states = append(states, st)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate alert rule states: %w", err)
+	}
+
+	return states, nil
+}
+
+// UpsertAlertRuleState stores the evaluation state of a rule against a target,
+// replacing the state stored for the same rule and target
+func (s *SQLiteStore) UpsertAlertRuleState(ctx context.Context, state AlertRuleState) error {
+	query := `
+		INSERT OR REPLACE INTO alert_rule_states (rule_id, machine_id, consecutive_breaches, consecutive_clears,
+			breach_started_at, clear_started_at, firing, last_value, last_evaluated, open_event_id, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.ExecContext(ctx, query, state.RuleID, state.MachineID, state.ConsecutiveBreaches, state.ConsecutiveClears,
+		state.BreachStartedAt, state.ClearStartedAt, state.Firing, state.LastValue, state.LastEvaluated,
+		state.OpenEventID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to upsert alert rule state: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteAlertRuleStates deletes the evaluation state of a rule for every target
+func (s *SQLiteStore) DeleteAlertRuleStates(ctx context.Context, ruleID int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM alert_rule_states WHERE rule_id = ?`, ruleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule states: %w", err)
+	}
+
+	return nil
+}
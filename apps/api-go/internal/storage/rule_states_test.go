@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAlertRuleStates(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	machine, err := store.CreateMachine(ctx, user.ID, "web-1", "web-1", "", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+	rule, err := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 80, 3))
+	if err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	event, err := store.CreateAlertEvent(ctx, rule.ID, &machine.ID, 90)
+	if err != nil {
+		t.Fatalf("Failed to create event: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	local := AlertRuleState{RuleID: rule.ID, ConsecutiveBreaches: 1, BreachStartedAt: &now, LastValue: 85, LastEvaluated: now}
+	remote := AlertRuleState{RuleID: rule.ID, MachineID: &machine.ID, ConsecutiveBreaches: 3, Firing: true, LastValue: 90,
+		LastEvaluated: now, OpenEventID: &event.ID}
+	for _, st := range []AlertRuleState{local, remote} {
+		if err := store.UpsertAlertRuleState(ctx, st); err != nil {
+			t.Fatalf("Failed to save state: %v", err)
+		}
+	}
+
+	// Saving the local host's state again replaces it rather than adding a second one
+	local.ConsecutiveBreaches = 2
+	if err := store.UpsertAlertRuleState(ctx, local); err != nil {
+		t.Fatalf("Failed to update state: %v", err)
+	}
+
+	states, err := store.ListAlertRuleStates(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list states: %v", err)
+	}
+	if len(states) != 2 {
+		t.Fatalf("Expected 2 states, got %d", len(states))
+	}
+	for _, st := range states {
+		switch {
+		case st.MachineID == nil:
+			if st.ConsecutiveBreaches != 2 || st.BreachStartedAt == nil || !st.BreachStartedAt.Equal(now) {
+				t.Errorf("Expected the updated local host state, got %+v", st)
+			}
+		case *st.MachineID == machine.ID:
+			if !st.Firing || st.OpenEventID == nil || *st.OpenEventID != event.ID {
+				t.Errorf("Expected the firing machine state, got %+v", st)
+			}
+		}
+	}
+
+	open, err := store.ListOpenAlertEvents(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list open events: %v", err)
+	}
+	if len(open) != 1 || open[0].ID != event.ID {
+		t.Errorf("Expected event %d to be open, got %+v", event.ID, open)
+	}
+	if _, err := store.ResolveAlertEvent(ctx, event.ID); err != nil {
+		t.Fatalf("Failed to resolve event: %v", err)
+	}
+	if open, _ = store.ListOpenAlertEvents(ctx); len(open) != 0 {
+		t.Errorf("Expected no open events after resolving, got %d", len(open))
+	}
+
+	if err := store.DeleteAlertRuleStates(ctx, rule.ID); err != nil {
+		t.Fatalf("Failed to delete states: %v", err)
+	}
+	if states, _ = store.ListAlertRuleStates(ctx); len(states) != 0 {
+		t.Errorf("Expected no states after deleting, got %d", len(states))
+	}
+}
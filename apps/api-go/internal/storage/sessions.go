@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Session is a signed-in device. Access tokens name their session, which is checked on every
+// request, so revoking a session signs the device out immediately.
+type Session struct {
+	ID                  int        `json:"id"`
+	UserID              int        `json:"user_id"`
+	Device              string     `json:"device"` // browser and OS derived from the user agent
+	UserAgent           string     `json:"user_agent"`
+	IPAddress           string     `json:"ip_address"`
+	CreatedAt           time.Time  `json:"created_at"`
+	LastUsedAt          time.Time  `json:"last_used_at"`
+	ExpiresAt           time.Time  `json:"expires_at"` // when the refresh token expires unless used
+	RevokedAt           *time.Time `json:"revoked_at,omitempty"`
+	RefreshTokenHash    string     `json:"-"`
+	PreviousRefreshHash string     `json:"-"` // refresh token replaced by the last rotation
+	RotatedAt           *time.Time `json:"-"`
+}
+
+// sessionColumns are the sessions columns in scan order
+const sessionColumns = `id, user_id, device, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at,
+	refresh_token_hash, previous_refresh_hash, rotated_at`
+
+// sessionFields returns the scan destinations for sessionColumns
+func sessionFields(s *Session) []any {
+	return []any{&s.ID, &s.UserID, &s.Device, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt,
+		&s.RevokedAt, &s.RefreshTokenHash, &s.PreviousRefreshHash, &s.RotatedAt}
+}
+
+// CreateSession stores a new session
+func (s *SQLiteStore) CreateSession(ctx context.Context, session Session) (*Session, error) {
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO sessions (user_id, device, user_agent, ip_address, created_at, last_used_at, expires_at, refresh_token_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + sessionColumns
+
+	created := &Session{}
+	err := s.db.QueryRowContext(ctx, query, session.UserID, session.Device, session.UserAgent, session.IPAddress,
+		now, now, session.ExpiresAt, session.RefreshTokenHash).Scan(sessionFields(created)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetSession returns a session by ID, including revoked and expired ones
+func (s *SQLiteStore) GetSession(ctx context.Context, id int) (*Session, error) {
+	session := &Session{}
+	err := s.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id).Scan(sessionFields(session)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSessionByRefreshHash returns the session whose current or previous refresh token has the hash
+func (s *SQLiteStore) GetSessionByRefreshHash(ctx context.Context, tokenHash string) (*Session, error) {
+	query := `SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE refresh_token_hash = ? OR previous_refresh_hash = ?`
+
+	session := &Session{}
+	err := s.db.QueryRowContext(ctx, query, tokenHash, tokenHash).Scan(sessionFields(session)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return session, nil
+}
+
+// RotateSessionRefreshToken replaces an active session's refresh token, keeping the old one's
+// hash to recognise reuse, and records the client that used it. It returns ErrSessionNotFound if
+// the session was revoked or its token was rotated concurrently.
+func (s *SQLiteStore) RotateSessionRefreshToken(ctx context.Context, id int, oldHash, newHash string, expiresAt time.Time, ipAddress string) (*Session, error) {
+	now := time.Now().UTC()
+	query := `
+		UPDATE sessions
+		SET previous_refresh_hash = refresh_token_hash, refresh_token_hash = ?, rotated_at = ?,
+			expires_at = ?, last_used_at = ?, ip_address = ?
+		WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL
+		RETURNING ` + sessionColumns
+
+	session := &Session{}
+	err := s.db.QueryRowContext(ctx, query, newHash, now, expiresAt, now, ipAddress, id, oldHash).Scan(sessionFields(session)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to rotate session refresh token: %w", err)
+	}
+
+	return session, nil
+}
+
+// TouchSession records that a session was used
+func (s *SQLiteStore) TouchSession(ctx context.Context, id int, ipAddress string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE sessions SET last_used_at = ?, ip_address = ? WHERE id = ?`,
+		time.Now().UTC(), ipAddress, id)
+	if err != nil {
+		return fmt.Errorf("failed to touch session: %w", err)
+	}
+
+	return nil
+}
+
+// ListSessions returns a user's sessions that are neither revoked nor expired, most recently used first
+func (s *SQLiteStore) ListSessions(ctx context.Context, userID int) ([]Session, error) {
+	query := `SELECT ` + sessionColumns + `
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC, id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var session Session
+		if err := rows.Scan(sessionFields(&session)...); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession revokes one of a user's sessions, returning ErrSessionNotFound if the user has no
+// such active session
+func (s *SQLiteStore) RevokeSession(ctx context.Context, id, userID int) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify session revocation: %w", err)
+	}
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeUserSessions revokes all of a user's active sessions except exceptID (0 to revoke all)
+// and returns how many were revoked
+func (s *SQLiteStore) RevokeUserSessions(ctx context.Context, userID, exceptID int) (int, error) {
+	res, err := s.db.ExecContext(ctx, `UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND id != ? AND revoked_at IS NULL`,
+		time.Now().UTC(), userID, exceptID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count revoked sessions: %w", err)
+	}
+
+	return int(rows), nil
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	alice, _ := store.CreateUser(ctx, "alice@example.com", "hash")
+	bob, _ := store.CreateUser(ctx, "bob@example.com", "hash")
+	expires := time.Now().Add(time.Hour).UTC()
+
+	laptop, err := store.CreateSession(ctx, Session{UserID: alice.ID, Device: "Firefox on Linux", IPAddress: "10.0.0.1",
+		ExpiresAt: expires, RefreshTokenHash: "laptop-1"})
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	phone, _ := store.CreateSession(ctx, Session{UserID: alice.ID, ExpiresAt: expires, RefreshTokenHash: "phone-1"})
+	store.CreateSession(ctx, Session{UserID: alice.ID, ExpiresAt: time.Now().Add(-time.Minute).UTC(), RefreshTokenHash: "expired"})
+	store.CreateSession(ctx, Session{UserID: bob.ID, ExpiresAt: expires, RefreshTokenHash: "bob-1"})
+
+	if laptop.Device != "Firefox on Linux" || laptop.LastUsedAt.IsZero() || laptop.RevokedAt != nil {
+		t.Errorf("Unexpected created session: %+v", laptop)
+	}
+
+	// Rotating keeps the old token findable so its reuse can be detected
+	rotated, err := store.RotateSessionRefreshToken(ctx, laptop.ID, "laptop-1", "laptop-2", expires.Add(time.Hour), "10.0.0.2")
+	if err != nil {
+		t.Fatalf("Failed to rotate refresh token: %v", err)
+	}
+	if rotated.RefreshTokenHash != "laptop-2" || rotated.PreviousRefreshHash != "laptop-1" || rotated.RotatedAt == nil || rotated.IPAddress != "10.0.0.2" {
+		t.Errorf("Unexpected rotated session: %+v", rotated)
+	}
+	for _, hash := range []string{"laptop-1", "laptop-2"} {
+		found, err := store.GetSessionByRefreshHash(ctx, hash)
+		if err != nil || found.ID != laptop.ID {
+			t.Errorf("Expected refresh hash %s to find session %d, got %+v, %v", hash, laptop.ID, found, err)
+		}
+	}
+	if _, err := store.RotateSessionRefreshToken(ctx, laptop.ID, "laptop-1", "laptop-3", expires, ""); err != ErrSessionNotFound {
+		t.Errorf("Expected rotating a stale token to fail with ErrSessionNotFound, got %v", err)
+	}
+	if _, err := store.GetSessionByRefreshHash(ctx, "unknown"); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+
+	// Only active sessions are listed
+	sessions, err := store.ListSessions(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 active sessions, got %d", len(sessions))
+	}
+
+	// Users can only revoke their own sessions
+	if err := store.RevokeSession(ctx, phone.ID, bob.ID); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound revoking another user's session, got %v", err)
+	}
+	if err := store.RevokeSession(ctx, phone.ID, alice.ID); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+	if err := store.RevokeSession(ctx, phone.ID, alice.ID); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound revoking a revoked session, got %v", err)
+	}
+	revokedPhone, _ := store.GetSession(ctx, phone.ID)
+	if revokedPhone.RevokedAt == nil {
+		t.Error("Expected the session to be marked revoked")
+	}
+	if _, err := store.RotateSessionRefreshToken(ctx, phone.ID, "phone-1", "phone-2", expires, ""); err != ErrSessionNotFound {
+		t.Errorf("Expected a revoked session's token not to rotate, got %v", err)
+	}
+
+	// Revoking all of a user's sessions leaves the excepted one and other users' sessions
+	newLaptop, _ := store.CreateSession(ctx, Session{UserID: alice.ID, ExpiresAt: expires, RefreshTokenHash: "laptop-new"})
+	revoked, err := store.RevokeUserSessions(ctx, alice.ID, newLaptop.ID)
+	if err != nil {
+		t.Fatalf("Failed to revoke user sessions: %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("Expected 2 sessions revoked, got %d", revoked)
+	}
+	sessions, _ = store.ListSessions(ctx, alice.ID)
+	if len(sessions) != 1 || sessions[0].ID != newLaptop.ID {
+		t.Errorf("Expected only session %d to remain, got %+v", newLaptop.ID, sessions)
+	}
+	if sessions, _ := store.ListSessions(ctx, bob.ID); len(sessions) != 1 {
+		t.Errorf("Expected bob's session to remain, got %d", len(sessions))
+	}
+}
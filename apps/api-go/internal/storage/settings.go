@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetSetting returns the value of a server-wide setting, or ErrSettingNotFound if it was never set
+func (s *SQLiteStore) GetSetting(ctx context.Context, key string) (string, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrSettingNotFound
+		}
+		return "", fmt.Errorf("failed to get setting %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// SetSetting sets a server-wide setting
+func (s *SQLiteStore) SetSetting(ctx context.Context, key, value string) error {
+	query := `
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+
+	if _, err := s.db.ExecContext(ctx, query, key, value, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to set setting %s: %w", key, err)
+	}
+
+	return nil
+}
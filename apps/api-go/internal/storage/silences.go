@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Silence suppresses notifications for matching alerts and machines during a time window.
+// Silences with a schedule only apply during the recurring windows the schedule starts.
+type Silence struct {
+	ID              int        `json:"id"`
+	OrgID           int        `json:"org_id"`     // Organization whose notifications the silence mutes
+	RuleID          *int       `json:"rule_id"`    // nil matches every rule
+	MachineID       *int       `json:"machine_id"` // nil matches every machine
+	Tag             string     `json:"tag"`        // empty matches every machine, otherwise the machine needs the tag
+	Reason          string     `json:"reason"`
+	StartsAt        time.Time  `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`          // nil for recurring silences that never end
+	Schedule        string     `json:"schedule"`         // cron expression starting each recurring window, empty for one-off silences
+	DurationSeconds int        `json:"duration_seconds"` // length of each recurring window
+	Timezone        string     `json:"timezone"`         // IANA timezone the schedule is evaluated in
+	CreatedBy       int        `json:"created_by"`       // User who created the silence
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedBy       *int       `json:"updated_by,omitempty"` // User who last changed the silence
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Active reports whether the given time falls between the silence's start and end.
+// Recurring silences additionally only apply during their scheduled windows.
+func (s Silence) Active(at time.Time) bool {
+	return !at.Before(s.StartsAt) && (s.EndsAt == nil || at.Before(*s.EndsAt))
+}
+
+// Recurring reports whether the silence repeats on a schedule
+func (s Silence) Recurring() bool {
+	return s.Schedule != ""
+}
+
+// silenceColumns are the silences columns in scan order
+const silenceColumns = `id, org_id, rule_id, machine_id, tag, reason, starts_at, ends_at, schedule, duration_seconds, timezone,
+		created_by, created_at, updated_by, updated_at`
+
+// scanSilence scans a silence row
+func scanSilence(row interface{ Scan(...any) error }) (*Silence, error) {
+	var silence Silence
+	err := row.Scan(&silence.ID, &silence.OrgID, &silence.RuleID, &silence.MachineID, &silence.Tag, &silence.Reason,
+		&silence.StartsAt, &silence.EndsAt, &silence.Schedule, &silence.DurationSeconds, &silence.Timezone,
+		&silence.CreatedBy, &silence.CreatedAt, &silence.UpdatedBy, &silence.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+// utcOrNil converts an optional time to UTC for storage
+func utcOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// CreateSilence creates a silence in silence.OrgID on behalf of silence.CreatedBy
+func (s *SQLiteStore) CreateSilence(ctx context.Context, silence Silence) (*Silence, error) {
+	if silence.Timezone == "" {
+		silence.Timezone = "UTC"
+	}
+
+	now := time.Now().UTC()
+	query := `
+		INSERT INTO silences (org_id, rule_id, machine_id, tag, reason, starts_at, ends_at, schedule, duration_seconds,
+		                      timezone, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING ` + silenceColumns
+
+	created, err := scanSilence(s.db.QueryRowContext(ctx, query, silence.OrgID, silence.RuleID, silence.MachineID, silence.Tag,
+		silence.Reason, silence.StartsAt.UTC(), utcOrNil(silence.EndsAt), silence.Schedule, silence.DurationSeconds,
+		silence.Timezone, silence.CreatedBy, now, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create silence: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetSilence retrieves a silence by ID within an organization
+func (s *SQLiteStore) GetSilence(ctx context.Context, id int, orgID int) (*Silence, error) {
+	query := `SELECT ` + silenceColumns + ` FROM silences WHERE id = ? AND org_id = ?`
+
+	silence, err := scanSilence(s.db.QueryRowContext(ctx, query, id, orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("silence with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get silence: %w", err)
+	}
+
+	return silence, nil
+}
+
+// ListSilences returns an organization's silences that have not ended by the given time, most recently
+// started first
+func (s *SQLiteStore) ListSilences(ctx context.Context, orgID int, at time.Time) ([]Silence, error) {
+	query := `SELECT ` + silenceColumns + `
+		FROM silences
+		WHERE org_id = ? AND (ends_at IS NULL OR ends_at > ?)
+		ORDER BY starts_at DESC, id DESC`
+
+	return s.querySilences(ctx, query, orgID, at.UTC())
+}
+
+// UpdateSilence replaces an existing silence in silence.OrgID, recording silence.UpdatedBy as the editor
+func (s *SQLiteStore) UpdateSilence(ctx context.Context, silence Silence) (*Silence, error) {
+	if silence.Timezone == "" {
+		silence.Timezone = "UTC"
+	}
+
+	query := `
+		UPDATE silences
+		SET rule_id = ?, machine_id = ?, tag = ?, reason = ?, starts_at = ?, ends_at = ?, schedule = ?,
+		    duration_seconds = ?, timezone = ?, updated_by = ?, updated_at = ?
+		WHERE id = ? AND org_id = ?
+		RETURNING ` + silenceColumns
+
+	updated, err := scanSilence(s.db.QueryRowContext(ctx, query, silence.RuleID, silence.MachineID, silence.Tag,
+		silence.Reason, silence.StartsAt.UTC(), utcOrNil(silence.EndsAt), silence.Schedule, silence.DurationSeconds,
+		silence.Timezone, silence.UpdatedBy, time.Now().UTC(), silence.ID, silence.OrgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("silence with id %d not found", silence.ID)
+		}
+		return nil, fmt.Errorf("failed to update silence: %w", err)
+	}
+
+	return updated, nil
+}
+
+// DeleteSilence deletes a silence within an organization
+func (s *SQLiteStore) DeleteSilence(ctx context.Context, id int, orgID int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM silences WHERE id = ? AND org_id = ?`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete silence: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify deletion: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("silence with id %d not found", id)
+	}
+
+	return nil
+}
+
+// ListActiveSilences returns silences of every organization whose start and end include the given time.
+// Recurring silences are returned for their whole lifetime; callers check the schedule.
+func (s *SQLiteStore) ListActiveSilences(ctx context.Context, at time.Time) ([]Silence, error) {
+	query := `SELECT ` + silenceColumns + `
+		FROM silences
+		WHERE ends_at IS NULL OR ends_at > ?
+		ORDER BY ends_at ASC`
+
+	silences, err := s.querySilences(ctx, query, at.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	active := []Silence{}
+	for _, silence := range silences {
+		if silence.Active(at) {
+			active = append(active, silence)
+		}
+	}
+	return active, nil
+}
+
+// querySilences runs a query returning silence rows
+func (s *SQLiteStore) querySilences(ctx context.Context, query string, args ...any) ([]Silence, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list silences: %w", err)
+	}
+	defer rows.Close()
+
+	silences := []Silence{}
+	for rows.Next() {
+		silence, err := scanSilence(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan silence: %w", err)
+		}
+		silences = append(silences, *silence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating silences: %w", err)
+	}
+
+	return silences, nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// oneOffSilence builds a silence between two times
+func oneOffSilence(ruleID *int, userID int, reason string, start, end time.Time) Silence {
+	return Silence{OrgID: userID, RuleID: ruleID, CreatedBy: userID, Reason: reason, StartsAt: start, EndsAt: &end}
+}
+
+func TestSilences_ListActive(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "silences@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	rule, err := store.SaveAlertRule(ctx, testRule(user.ID, "High CPU", "cpu_pct", "above", 80.0, 1))
+	if err != nil {
+		t.Fatalf("Failed to create alert rule: %v", err)
+	}
+
+	now := time.Now()
+	active, err := store.CreateSilence(ctx, oneOffSilence(&rule.ID, user.ID, "deploy", now.Add(-time.Minute), now.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+	if active.CreatedBy != user.ID || active.RuleID == nil || *active.RuleID != rule.ID {
+		t.Errorf("Unexpected silence: %+v", active)
+	}
+
+	// Expired and future silences are not active
+	if _, err := store.CreateSilence(ctx, oneOffSilence(&rule.ID, user.ID, "old", now.Add(-2*time.Hour), now.Add(-time.Hour))); err != nil {
+		t.Fatalf("Failed to create expired silence: %v", err)
+	}
+	if _, err := store.CreateSilence(ctx, oneOffSilence(nil, user.ID, "later", now.Add(time.Hour), now.Add(2*time.Hour))); err != nil {
+		t.Fatalf("Failed to create future silence: %v", err)
+	}
+
+	silences, err := store.ListActiveSilences(ctx, now)
+	if err != nil {
+		t.Fatalf("Failed to list silences: %v", err)
+	}
+	if len(silences) != 1 || silences[0].ID != active.ID {
+		t.Errorf("Expected only silence %d to be active, got %+v", active.ID, silences)
+	}
+
+	silences, err = store.ListActiveSilences(ctx, now.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to list silences: %v", err)
+	}
+	if len(silences) != 1 || silences[0].RuleID != nil {
+		t.Errorf("Expected only the global future silence to be active later, got %+v", silences)
+	}
+}
+
+func TestSilences_CRUD(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "ops@example.com", "hash")
+	editor, _ := store.CreateUser(ctx, "editor@example.com", "hash")
+	machine, err := store.CreateMachine(ctx, user.ID, "db-1", "db-1.internal", "", "key-hash")
+	if err != nil {
+		t.Fatalf("Failed to create machine: %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	created, err := store.CreateSilence(ctx, Silence{
+		OrgID:           user.ID,
+		MachineID:       &machine.ID,
+		Tag:             "db",
+		Reason:          "patch night",
+		StartsAt:        start,
+		Schedule:        "0 2 * * 2",
+		DurationSeconds: 3 * 3600,
+		Timezone:        "Europe/Berlin",
+		CreatedBy:       user.ID,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create silence: %v", err)
+	}
+	if !created.Recurring() || created.EndsAt != nil || created.Timezone != "Europe/Berlin" || created.UpdatedBy != nil || created.OrgID != user.ID {
+		t.Errorf("Unexpected silence: %+v", created)
+	}
+
+	// Recurring silences without an end are listed until deleted
+	silences, err := store.ListSilences(ctx, user.ID, start.AddDate(5, 0, 0))
+	if err != nil {
+		t.Fatalf("Failed to list silences: %v", err)
+	}
+	if len(silences) != 1 || silences[0].ID != created.ID {
+		t.Errorf("Expected silence %d to be listed, got %+v", created.ID, silences)
+	}
+
+	// Other organizations can neither see nor change the silence
+	if silences, _ := store.ListSilences(ctx, editor.ID, start.AddDate(5, 0, 0)); len(silences) != 0 {
+		t.Errorf("Expected no silences for another organization, got %+v", silences)
+	}
+	if _, err := store.GetSilence(ctx, created.ID, editor.ID); err == nil {
+		t.Error("Expected getting another organization's silence to fail")
+	}
+	foreign := *created
+	foreign.OrgID = editor.ID
+	if _, err := store.UpdateSilence(ctx, foreign); err == nil {
+		t.Error("Expected updating another organization's silence to fail")
+	}
+	if err := store.DeleteSilence(ctx, created.ID, editor.ID); err == nil {
+		t.Error("Expected deleting another organization's silence to fail")
+	}
+
+	end := start.Add(48 * time.Hour)
+	created.EndsAt = &end
+	created.UpdatedBy = &editor.ID
+	updated, err := store.UpdateSilence(ctx, *created)
+	if err != nil {
+		t.Fatalf("Failed to update silence: %v", err)
+	}
+	if updated.EndsAt == nil || !updated.EndsAt.Equal(end) || updated.UpdatedBy == nil || *updated.UpdatedBy != editor.ID || updated.CreatedBy != user.ID {
+		t.Errorf("Unexpected silence after update: %+v", updated)
+	}
+
+	got, err := store.GetSilence(ctx, created.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get silence: %v", err)
+	}
+	if got.Tag != "db" || got.Schedule != "0 2 * * 2" || got.DurationSeconds != 3*3600 {
+		t.Errorf("Unexpected silence: %+v", got)
+	}
+
+	if err := store.DeleteSilence(ctx, created.ID, user.ID); err != nil {
+		t.Fatalf("Failed to delete silence: %v", err)
+	}
+	if _, err := store.GetSilence(ctx, created.ID, user.ID); err == nil {
+		t.Error("Expected deleted silence to be gone")
+	}
+	if err := store.DeleteSilence(ctx, created.ID, user.ID); err == nil {
+		t.Error("Expected deleting a missing silence to fail")
+	}
+}
@@ -289,6 +289,729 @@ func (s *SQLiteStore) migrate() error {
             SELECT id, api_key, created_at, NULL
             FROM machines
             WHERE api_key IS NOT NULL AND api_key != '';
+            `,
+		},
+		{
+			version: "016_alert_event_resolution",
+			sql: `
+            ALTER TABLE alert_events ADD COLUMN resolved_at DATETIME;
+            CREATE INDEX IF NOT EXISTS idx_alert_events_resolved_at ON alert_events(resolved_at);
+            `,
+		},
+		{
+			version: "017_notification_templates",
+			sql: `
+            CREATE TABLE IF NOT EXISTS notification_templates (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                user_id INTEGER NOT NULL,
+                channel TEXT NOT NULL CHECK (channel IN ('webhook', 'telegram')),
+                event_type TEXT NOT NULL,
+                body TEXT NOT NULL,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+                UNIQUE(user_id, channel, event_type)
+            );
+            CREATE INDEX IF NOT EXISTS idx_notification_templates_user_id ON notification_templates(user_id);
+            `,
+		},
+		{
+			version: "018_delivery_settings",
+			sql: `
+            ALTER TABLE webhooks ADD COLUMN min_attempt_interval_seconds INTEGER;
+            ALTER TABLE webhooks ADD COLUMN failure_threshold INTEGER;
+            ALTER TABLE webhooks ADD COLUMN failure_window_seconds INTEGER;
+            ALTER TABLE webhooks ADD COLUMN cooldown_seconds INTEGER;
+            ALTER TABLE telegram_recipients ADD COLUMN min_attempt_interval_seconds INTEGER;
+            ALTER TABLE telegram_recipients ADD COLUMN failure_threshold INTEGER;
+            ALTER TABLE telegram_recipients ADD COLUMN failure_window_seconds INTEGER;
+            ALTER TABLE telegram_recipients ADD COLUMN cooldown_seconds INTEGER;
+            `,
+		},
+		{
+			version: "019_webhook_secret_rotation",
+			sql: `
+            ALTER TABLE webhooks ADD COLUMN previous_secret_hash TEXT;
+            ALTER TABLE webhooks ADD COLUMN previous_secret_expires_at DATETIME;
+            `,
+		},
+		{
+			version: "020_notification_policies",
+			sql: `
+            CREATE TABLE IF NOT EXISTS notification_policies (
+                user_id INTEGER PRIMARY KEY,
+                timezone TEXT NOT NULL DEFAULT 'UTC',
+                quiet_hours_enabled BOOLEAN NOT NULL DEFAULT 0,
+                quiet_hours_start TEXT NOT NULL DEFAULT '22:00',
+                quiet_hours_end TEXT NOT NULL DEFAULT '07:00',
+                digest_enabled BOOLEAN NOT NULL DEFAULT 0,
+                digest_window_seconds INTEGER NOT NULL DEFAULT 300,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            `,
+		},
+		{
+			version: "021_alert_severity_routing",
+			sql: `
+            ALTER TABLE alert_rules ADD COLUMN severity TEXT NOT NULL DEFAULT 'warning';
+            ALTER TABLE alert_events ADD COLUMN severity TEXT NOT NULL DEFAULT 'warning';
+            ALTER TABLE alert_events ADD COLUMN machine_id INTEGER REFERENCES machines(id) ON DELETE SET NULL;
+            CREATE TABLE IF NOT EXISTS machine_tags (
+                machine_id INTEGER NOT NULL,
+                tag TEXT NOT NULL,
+                PRIMARY KEY(machine_id, tag),
+                FOREIGN KEY(machine_id) REFERENCES machines(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_machine_tags_tag ON machine_tags(tag);
+            CREATE TABLE IF NOT EXISTS notification_routes (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                user_id INTEGER NOT NULL,
+                name TEXT NOT NULL,
+                severities TEXT NOT NULL DEFAULT '[]',
+                machine_tags TEXT NOT NULL DEFAULT '[]',
+                webhook_ids TEXT NOT NULL DEFAULT '[]',
+                telegram_recipient_ids TEXT NOT NULL DEFAULT '[]',
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_notification_routes_user_id ON notification_routes(user_id);
+            `,
+		},
+		{
+			version: "022_escalation_policies",
+			sql: `
+            CREATE TABLE IF NOT EXISTS escalation_policies (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                user_id INTEGER NOT NULL,
+                name TEXT NOT NULL,
+                steps TEXT NOT NULL DEFAULT '[]',
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_escalation_policies_user_id ON escalation_policies(user_id);
+            ALTER TABLE alert_rules ADD COLUMN escalation_policy_id INTEGER REFERENCES escalation_policies(id) ON DELETE SET NULL;
+            CREATE TABLE IF NOT EXISTS alert_escalations (
+                event_id INTEGER PRIMARY KEY,
+                step INTEGER NOT NULL,
+                escalated_at DATETIME NOT NULL,
+                FOREIGN KEY(event_id) REFERENCES alert_events(id) ON DELETE CASCADE
+            );
+            `,
+		},
+		{
+			version: "023_silences",
+			sql: `
+            CREATE TABLE IF NOT EXISTS silences (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                rule_id INTEGER,
+                machine_id INTEGER,
+                tag TEXT NOT NULL DEFAULT '',
+                reason TEXT NOT NULL DEFAULT '',
+                starts_at DATETIME NOT NULL,
+                ends_at DATETIME,
+                schedule TEXT NOT NULL DEFAULT '',
+                duration_seconds INTEGER NOT NULL DEFAULT 0,
+                timezone TEXT NOT NULL DEFAULT 'UTC',
+                created_by INTEGER NOT NULL,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_by INTEGER,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE,
+                FOREIGN KEY(machine_id) REFERENCES machines(id) ON DELETE CASCADE,
+                FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE CASCADE,
+                FOREIGN KEY(updated_by) REFERENCES users(id) ON DELETE SET NULL
+            );
+            CREATE INDEX IF NOT EXISTS idx_silences_ends_at ON silences(ends_at);
+            `,
+		},
+		{
+			version: "024_alert_rule_hysteresis",
+			sql: `
+            ALTER TABLE alert_rules ADD COLUMN clear_threshold_pct REAL;
+            ALTER TABLE alert_rules ADD COLUMN clear_after INTEGER NOT NULL DEFAULT 1;
+            ALTER TABLE alert_rules ADD COLUMN for_seconds INTEGER NOT NULL DEFAULT 0;
+            ALTER TABLE alert_rules ADD COLUMN clear_for_seconds INTEGER NOT NULL DEFAULT 0;
+            `,
+		},
+		{
+			// Rate rules compare percentage points per hour, which may be negative. SQLite cannot
+			// alter a CHECK constraint, so the table is rebuilt with foreign keys off to keep the
+			// events, routes and silences that reference the rules.
+			version: "025_alert_rule_types",
+			sql: `
+            PRAGMA foreign_keys = OFF;
+            CREATE TABLE alert_rules_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                name TEXT NOT NULL,
+                metric TEXT NOT NULL CHECK (metric IN ('cpu_pct', 'mem_used_pct', 'disk_used_pct')),
+                threshold_pct REAL NOT NULL CHECK (threshold_pct >= -100 AND threshold_pct <= 100),
+                comparison TEXT NOT NULL CHECK (comparison IN ('above', 'below')),
+                trigger_after INTEGER NOT NULL CHECK (trigger_after >= 1),
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                severity TEXT NOT NULL DEFAULT 'warning',
+                escalation_policy_id INTEGER REFERENCES escalation_policies(id) ON DELETE SET NULL,
+                clear_threshold_pct REAL,
+                clear_after INTEGER NOT NULL DEFAULT 1,
+                for_seconds INTEGER NOT NULL DEFAULT 0,
+                clear_for_seconds INTEGER NOT NULL DEFAULT 0,
+                type TEXT NOT NULL DEFAULT 'threshold',
+                window_seconds INTEGER NOT NULL DEFAULT 0,
+                horizon_seconds INTEGER NOT NULL DEFAULT 0
+            );
+            INSERT INTO alert_rules_new (id, name, metric, threshold_pct, comparison, trigger_after, created_at, updated_at,
+                                         severity, escalation_policy_id, clear_threshold_pct, clear_after, for_seconds,
+                                         clear_for_seconds)
+                SELECT id, name, metric, threshold_pct, comparison, trigger_after, created_at, updated_at,
+                       severity, escalation_policy_id, clear_threshold_pct, clear_after, for_seconds,
+                       clear_for_seconds FROM alert_rules;
+            DROP TABLE alert_rules;
+            ALTER TABLE alert_rules_new RENAME TO alert_rules;
+            CREATE INDEX IF NOT EXISTS idx_alert_rules_metric ON alert_rules(metric);
+            PRAGMA foreign_keys = ON;
+            `,
+		},
+		{
+			version: "026_anomaly_baselines",
+			sql: `
+            ALTER TABLE alert_rules ADD COLUMN seasonality TEXT NOT NULL DEFAULT '';
+            ALTER TABLE alert_events ADD COLUMN expected_low REAL;
+            ALTER TABLE alert_events ADD COLUMN expected_high REAL;
+            CREATE TABLE IF NOT EXISTS metric_baselines (
+                machine_id INTEGER NOT NULL,
+                metric TEXT NOT NULL,
+                seasonality TEXT NOT NULL,
+                slot INTEGER NOT NULL,
+                samples INTEGER NOT NULL DEFAULT 0,
+                mean REAL NOT NULL DEFAULT 0,
+                variance REAL NOT NULL DEFAULT 0,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                PRIMARY KEY(machine_id, metric, seasonality, slot),
+                FOREIGN KEY(machine_id) REFERENCES machines(id) ON DELETE CASCADE
+            );
+            `,
+		},
+		{
+			version: "027_alert_rule_expressions",
+			sql: `
+            ALTER TABLE alert_rules ADD COLUMN expression TEXT NOT NULL DEFAULT '';
+            `,
+		},
+		{
+			version: "028_alert_rule_states",
+			sql: `
+            CREATE TABLE IF NOT EXISTS alert_rule_states (
+                rule_id INTEGER NOT NULL,
+                machine_id INTEGER,
+                consecutive_breaches INTEGER NOT NULL DEFAULT 0,
+                consecutive_clears INTEGER NOT NULL DEFAULT 0,
+                breach_started_at DATETIME,
+                clear_started_at DATETIME,
+                firing BOOLEAN NOT NULL DEFAULT 0,
+                last_value REAL NOT NULL DEFAULT 0,
+                last_evaluated DATETIME NOT NULL,
+                open_event_id INTEGER,
+                updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(rule_id) REFERENCES alert_rules(id) ON DELETE CASCADE,
+                FOREIGN KEY(machine_id) REFERENCES machines(id) ON DELETE CASCADE,
+                FOREIGN KEY(open_event_id) REFERENCES alert_events(id) ON DELETE SET NULL
+            );
+            -- One state per rule and target; the local host has no machine ID
+            CREATE UNIQUE INDEX IF NOT EXISTS idx_alert_rule_states_target ON alert_rule_states(rule_id, IFNULL(machine_id, 0));
+
+            -- Rules that were firing before states were persisted keep their latest open event
+            INSERT OR IGNORE INTO alert_rule_states (rule_id, machine_id, consecutive_breaches, breach_started_at,
+                firing, last_value, last_evaluated, open_event_id)
+            SELECT e.rule_id, e.machine_id, r.trigger_after, e.triggered_at, 1, e.value, e.triggered_at, e.id
+            FROM alert_events e
+            JOIN alert_rules r ON r.id = e.rule_id
+            WHERE e.resolved_at IS NULL
+            ORDER BY e.triggered_at DESC, e.id DESC;
+            `,
+		},
+		{
+			version: "029_sessions",
+			sql: `
+            CREATE TABLE IF NOT EXISTS sessions (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                user_id INTEGER NOT NULL,
+                device TEXT NOT NULL DEFAULT '',
+                user_agent TEXT NOT NULL DEFAULT '',
+                ip_address TEXT NOT NULL DEFAULT '',
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                last_used_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                expires_at DATETIME NOT NULL,
+                revoked_at DATETIME,
+                refresh_token_hash TEXT NOT NULL UNIQUE,
+                previous_refresh_hash TEXT NOT NULL DEFAULT '',
+                rotated_at DATETIME,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+            CREATE INDEX IF NOT EXISTS idx_sessions_previous_refresh_hash ON sessions(previous_refresh_hash);
+            `,
+		},
+		{
+			version: "030_api_tokens",
+			sql: `
+            CREATE TABLE IF NOT EXISTS api_tokens (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                user_id INTEGER NOT NULL,
+                name TEXT NOT NULL,
+                token_prefix TEXT NOT NULL,
+                scopes TEXT NOT NULL,
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                expires_at DATETIME,
+                last_used_at DATETIME,
+                last_used_ip TEXT NOT NULL DEFAULT '',
+                revoked_at DATETIME,
+                token_hash TEXT NOT NULL UNIQUE,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+            `,
+		},
+		{
+			version: "031_user_roles",
+			sql: `
+            ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'operator';
+            UPDATE users SET role = 'admin' WHERE is_admin = 1;
+            UPDATE users SET role = 'owner' WHERE id = (SELECT MIN(id) FROM users WHERE is_admin = 1);
+            `,
+		},
+		{
+			// Every user gets a personal organization, which takes over the resources they owned
+			version: "032_organizations",
+			sql: `
+            PRAGMA foreign_keys = OFF;
+            CREATE TABLE IF NOT EXISTS organizations (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                name TEXT NOT NULL,
+                personal_user_id INTEGER UNIQUE,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(personal_user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE TABLE IF NOT EXISTS org_members (
+                org_id INTEGER NOT NULL,
+                user_id INTEGER NOT NULL,
+                role TEXT NOT NULL,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                PRIMARY KEY(org_id, user_id),
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_org_members_user_id ON org_members(user_id);
+            CREATE TABLE IF NOT EXISTS org_invitations (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                email TEXT NOT NULL,
+                role TEXT NOT NULL,
+                token_hash TEXT UNIQUE NOT NULL,
+                invited_by INTEGER,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                expires_at DATETIME NOT NULL,
+                accepted_at DATETIME,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+                FOREIGN KEY(invited_by) REFERENCES users(id) ON DELETE SET NULL
+            );
+            CREATE INDEX IF NOT EXISTS idx_org_invitations_org_id ON org_invitations(org_id);
+
+            INSERT INTO organizations (name, personal_user_id, created_at)
+                SELECT email, id, COALESCE(created_at, CURRENT_TIMESTAMP) FROM users ORDER BY id;
+            INSERT INTO org_members (org_id, user_id, role, created_at)
+                SELECT id, personal_user_id, 'owner', created_at FROM organizations WHERE personal_user_id IS NOT NULL;
+
+            CREATE TABLE machines_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                name TEXT NOT NULL,
+                hostname TEXT,
+                api_key TEXT UNIQUE NOT NULL,
+                status TEXT DEFAULT 'offline',
+                last_seen TIMESTAMP,
+                created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                platform TEXT,
+                platform_version TEXT,
+                kernel_version TEXT,
+                cpu_cores INTEGER,
+                memory_total_mb INTEGER,
+                disk_total_gb INTEGER,
+                last_boot_time TIMESTAMP,
+                description TEXT,
+                is_enabled BOOLEAN DEFAULT 1 NOT NULL,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE
+            );
+            INSERT INTO machines_new (id, org_id, name, hostname, api_key, status, last_seen, created_at, platform,
+                                      platform_version, kernel_version, cpu_cores, memory_total_mb, disk_total_gb,
+                                      last_boot_time, description, is_enabled)
+                SELECT m.id, o.id, m.name, m.hostname, m.api_key, m.status, m.last_seen, m.created_at, m.platform,
+                       m.platform_version, m.kernel_version, m.cpu_cores, m.memory_total_mb, m.disk_total_gb,
+                       m.last_boot_time, m.description, m.is_enabled
+                FROM machines m JOIN organizations o ON o.personal_user_id = m.user_id;
+            DROP TABLE machines;
+            ALTER TABLE machines_new RENAME TO machines;
+            CREATE INDEX IF NOT EXISTS idx_machines_org_id ON machines(org_id);
+            CREATE INDEX IF NOT EXISTS idx_machines_api_key ON machines(api_key);
+            CREATE INDEX IF NOT EXISTS idx_machines_status ON machines(status);
+            CREATE INDEX IF NOT EXISTS idx_machines_is_enabled ON machines(is_enabled);
+
+            CREATE TABLE webhooks_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                url TEXT NOT NULL,
+                secret_hash TEXT NOT NULL,
+                is_active BOOLEAN DEFAULT 1 NOT NULL,
+                failure_count INTEGER DEFAULT 0 NOT NULL,
+                last_success_at DATETIME,
+                last_error_at DATETIME,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                cooldown_until DATETIME,
+                last_attempt_at DATETIME,
+                min_attempt_interval_seconds INTEGER,
+                failure_threshold INTEGER,
+                failure_window_seconds INTEGER,
+                cooldown_seconds INTEGER,
+                previous_secret_hash TEXT,
+                previous_secret_expires_at DATETIME,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+                UNIQUE(org_id, url)
+            );
+            INSERT INTO webhooks_new (id, org_id, url, secret_hash, is_active, failure_count, last_success_at, last_error_at,
+                                      created_at, updated_at, cooldown_until, last_attempt_at, min_attempt_interval_seconds,
+                                      failure_threshold, failure_window_seconds, cooldown_seconds, previous_secret_hash,
+                                      previous_secret_expires_at)
+                SELECT w.id, o.id, w.url, w.secret_hash, w.is_active, w.failure_count, w.last_success_at, w.last_error_at,
+                       w.created_at, w.updated_at, w.cooldown_until, w.last_attempt_at, w.min_attempt_interval_seconds,
+                       w.failure_threshold, w.failure_window_seconds, w.cooldown_seconds, w.previous_secret_hash,
+                       w.previous_secret_expires_at
+                FROM webhooks w JOIN organizations o ON o.personal_user_id = w.user_id;
+            DROP TABLE webhooks;
+            ALTER TABLE webhooks_new RENAME TO webhooks;
+            CREATE INDEX IF NOT EXISTS idx_webhooks_org_id ON webhooks(org_id);
+            CREATE INDEX IF NOT EXISTS idx_webhooks_active ON webhooks(is_active);
+            CREATE INDEX IF NOT EXISTS idx_webhooks_cooldown ON webhooks(cooldown_until);
+
+            CREATE TABLE email_recipients_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                email TEXT NOT NULL,
+                is_active BOOLEAN DEFAULT 1 NOT NULL,
+                failure_count INTEGER DEFAULT 0 NOT NULL,
+                last_success_at DATETIME,
+                last_error_at DATETIME,
+                cooldown_until DATETIME,
+                last_attempt_at DATETIME,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+                UNIQUE(org_id, email)
+            );
+            INSERT INTO email_recipients_new (id, org_id, email, is_active, failure_count, last_success_at, last_error_at,
+                                              cooldown_until, last_attempt_at, created_at, updated_at)
+                SELECT r.id, o.id, r.email, r.is_active, r.failure_count, r.last_success_at, r.last_error_at,
+                       r.cooldown_until, r.last_attempt_at, r.created_at, r.updated_at
+                FROM email_recipients r JOIN organizations o ON o.personal_user_id = r.user_id;
+            DROP TABLE email_recipients;
+            ALTER TABLE email_recipients_new RENAME TO email_recipients;
+            CREATE INDEX IF NOT EXISTS idx_email_recipients_org_id ON email_recipients(org_id);
+            CREATE INDEX IF NOT EXISTS idx_email_recipients_active ON email_recipients(is_active);
+            CREATE INDEX IF NOT EXISTS idx_email_recipients_cooldown ON email_recipients(cooldown_until);
+
+            CREATE TABLE telegram_recipients_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                chat_id TEXT NOT NULL,
+                is_active BOOLEAN DEFAULT 1,
+                created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                last_attempt_at TIMESTAMP,
+                last_success_at TIMESTAMP,
+                last_error_at TIMESTAMP,
+                failure_count INTEGER DEFAULT 0,
+                cooldown_until TIMESTAMP,
+                min_attempt_interval_seconds INTEGER,
+                failure_threshold INTEGER,
+                failure_window_seconds INTEGER,
+                cooldown_seconds INTEGER,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+                UNIQUE(org_id, chat_id)
+            );
+            INSERT INTO telegram_recipients_new (id, org_id, chat_id, is_active, created_at, last_attempt_at, last_success_at,
+                                                 last_error_at, failure_count, cooldown_until, min_attempt_interval_seconds,
+                                                 failure_threshold, failure_window_seconds, cooldown_seconds)
+                SELECT r.id, o.id, r.chat_id, r.is_active, r.created_at, r.last_attempt_at, r.last_success_at,
+                       r.last_error_at, r.failure_count, r.cooldown_until, r.min_attempt_interval_seconds,
+                       r.failure_threshold, r.failure_window_seconds, r.cooldown_seconds
+                FROM telegram_recipients r JOIN organizations o ON o.personal_user_id = r.user_id;
+            DROP TABLE telegram_recipients;
+            ALTER TABLE telegram_recipients_new RENAME TO telegram_recipients;
+            CREATE INDEX IF NOT EXISTS idx_telegram_recipients_org_id ON telegram_recipients(org_id);
+            CREATE INDEX IF NOT EXISTS idx_telegram_recipients_is_active ON telegram_recipients(is_active);
+
+            CREATE TABLE notification_templates_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                channel TEXT NOT NULL CHECK (channel IN ('webhook', 'telegram')),
+                event_type TEXT NOT NULL,
+                body TEXT NOT NULL,
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE,
+                UNIQUE(org_id, channel, event_type)
+            );
+            INSERT INTO notification_templates_new (id, org_id, channel, event_type, body, created_at, updated_at)
+                SELECT t.id, o.id, t.channel, t.event_type, t.body, t.created_at, t.updated_at
+                FROM notification_templates t JOIN organizations o ON o.personal_user_id = t.user_id;
+            DROP TABLE notification_templates;
+            ALTER TABLE notification_templates_new RENAME TO notification_templates;
+            CREATE INDEX IF NOT EXISTS idx_notification_templates_org_id ON notification_templates(org_id);
+
+            CREATE TABLE notification_policies_new (
+                org_id INTEGER PRIMARY KEY,
+                timezone TEXT NOT NULL DEFAULT 'UTC',
+                quiet_hours_enabled BOOLEAN NOT NULL DEFAULT 0,
+                quiet_hours_start TEXT NOT NULL DEFAULT '22:00',
+                quiet_hours_end TEXT NOT NULL DEFAULT '07:00',
+                digest_enabled BOOLEAN NOT NULL DEFAULT 0,
+                digest_window_seconds INTEGER NOT NULL DEFAULT 300,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE
+            );
+            INSERT INTO notification_policies_new (org_id, timezone, quiet_hours_enabled, quiet_hours_start, quiet_hours_end,
+                                                   digest_enabled, digest_window_seconds, updated_at)
+                SELECT o.id, p.timezone, p.quiet_hours_enabled, p.quiet_hours_start, p.quiet_hours_end,
+                       p.digest_enabled, p.digest_window_seconds, p.updated_at
+                FROM notification_policies p JOIN organizations o ON o.personal_user_id = p.user_id;
+            DROP TABLE notification_policies;
+            ALTER TABLE notification_policies_new RENAME TO notification_policies;
+
+            CREATE TABLE notification_routes_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                name TEXT NOT NULL,
+                severities TEXT NOT NULL DEFAULT '[]',
+                machine_tags TEXT NOT NULL DEFAULT '[]',
+                webhook_ids TEXT NOT NULL DEFAULT '[]',
+                telegram_recipient_ids TEXT NOT NULL DEFAULT '[]',
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE
+            );
+            INSERT INTO notification_routes_new (id, org_id, name, severities, machine_tags, webhook_ids,
+                                                 telegram_recipient_ids, created_at, updated_at)
+                SELECT r.id, o.id, r.name, r.severities, r.machine_tags, r.webhook_ids,
+                       r.telegram_recipient_ids, r.created_at, r.updated_at
+                FROM notification_routes r JOIN organizations o ON o.personal_user_id = r.user_id;
+            DROP TABLE notification_routes;
+            ALTER TABLE notification_routes_new RENAME TO notification_routes;
+            CREATE INDEX IF NOT EXISTS idx_notification_routes_org_id ON notification_routes(org_id);
+
+            CREATE TABLE escalation_policies_new (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                name TEXT NOT NULL,
+                steps TEXT NOT NULL DEFAULT '[]',
+                created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE
+            );
+            INSERT INTO escalation_policies_new (id, org_id, name, steps, created_at, updated_at)
+                SELECT p.id, o.id, p.name, p.steps, p.created_at, p.updated_at
+                FROM escalation_policies p JOIN organizations o ON o.personal_user_id = p.user_id;
+            DROP TABLE escalation_policies;
+            ALTER TABLE escalation_policies_new RENAME TO escalation_policies;
+            CREATE INDEX IF NOT EXISTS idx_escalation_policies_org_id ON escalation_policies(org_id);
+            PRAGMA foreign_keys = ON;
+            `,
+		},
+		{
+			version: "033_two_factor",
+			sql: `
+            CREATE TABLE IF NOT EXISTS user_totp (
+                user_id INTEGER PRIMARY KEY,
+                secret TEXT NOT NULL,
+                enabled_at DATETIME,
+                last_used_step INTEGER NOT NULL DEFAULT 0,
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE TABLE IF NOT EXISTS recovery_codes (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                user_id INTEGER NOT NULL,
+                code_hash TEXT NOT NULL,
+                used_at DATETIME,
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_recovery_codes_user_id ON recovery_codes(user_id);
+            CREATE TABLE IF NOT EXISTS settings (
+                key TEXT PRIMARY KEY,
+                value TEXT NOT NULL,
+                updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+            );
+            `,
+		},
+		{
+			version: "034_oidc",
+			sql: `
+            CREATE TABLE IF NOT EXISTS user_identities (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                user_id INTEGER NOT NULL,
+                issuer TEXT NOT NULL,
+                subject TEXT NOT NULL,
+                email TEXT NOT NULL DEFAULT '',
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                last_login_at DATETIME,
+                UNIQUE(issuer, subject),
+                FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+            CREATE TABLE IF NOT EXISTS oidc_logins (
+                state_hash TEXT PRIMARY KEY,
+                nonce TEXT NOT NULL,
+                code_verifier TEXT NOT NULL,
+                return_to TEXT NOT NULL DEFAULT '',
+                expires_at DATETIME NOT NULL,
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+            );
+            `,
+		},
+		{
+			version: "035_login_attempts",
+			sql: `
+            CREATE TABLE IF NOT EXISTS login_attempts (
+                key TEXT PRIMARY KEY,
+                failures INTEGER NOT NULL DEFAULT 0,
+                last_failure_at DATETIME NOT NULL,
+                locked_until DATETIME
+            );
+            CREATE INDEX IF NOT EXISTS idx_login_attempts_locked_until ON login_attempts(locked_until);
+            `,
+		},
+		{
+			version: "036_audit_log",
+			sql: `
+            CREATE TABLE IF NOT EXISTS audit_log (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER,
+                actor_type TEXT NOT NULL,
+                actor_id INTEGER,
+                actor_email TEXT NOT NULL DEFAULT '',
+                api_token_id INTEGER,
+                action TEXT NOT NULL,
+                target_type TEXT NOT NULL,
+                target_id TEXT NOT NULL DEFAULT '',
+                changes TEXT,
+                ip_address TEXT NOT NULL DEFAULT '',
+                created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+            );
+            CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+            CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id);
+            CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id);
+            CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+            BEGIN
+                SELECT RAISE(ABORT, 'audit log is append-only');
+            END;
+            CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+            BEGIN
+                SELECT RAISE(ABORT, 'audit log is append-only');
+            END;
+            `,
+		},
+		{
+			version: "037_pending_digest_entries",
+			sql: `
+            CREATE TABLE IF NOT EXISTS pending_digest_entries (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                org_id INTEGER NOT NULL,
+                reason TEXT NOT NULL,
+                flush_at DATETIME NOT NULL,
+                event_type TEXT NOT NULL,
+                rule TEXT NOT NULL,
+                event TEXT NOT NULL,
+                created_at DATETIME NOT NULL,
+                FOREIGN KEY(org_id) REFERENCES organizations(id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_pending_digest_entries_org_id ON pending_digest_entries(org_id);
+            `,
+		},
+		{
+			// Only events triggered after a policy is attached escalate. Rules that already have one
+			// count from now, so events left open from before don't page everyone at once.
+			version: "038_escalation_policy_attached_at",
+			sql: `
+            ALTER TABLE alert_rules ADD COLUMN escalation_policy_attached_at DATETIME;
+            UPDATE alert_rules SET escalation_policy_attached_at = CURRENT_TIMESTAMP WHERE escalation_policy_id IS NOT NULL;
+            `,
+		},
+		{
+			// Silences belong to the organization that created them and only mute its notifications
+			version: "039_silence_orgs",
+			sql: `
+            ALTER TABLE silences ADD COLUMN org_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+            UPDATE silences SET org_id = COALESCE(
+                (SELECT m.org_id FROM machines m WHERE m.id = silences.machine_id),
+                (SELECT o.id FROM organizations o WHERE o.personal_user_id = silences.created_by)
+            );
+            CREATE INDEX IF NOT EXISTS idx_silences_org_id ON silences(org_id);
+            `,
+		},
+		{
+			version: "040_machine_held_offline_notifications",
+			sql: `
+            CREATE TABLE IF NOT EXISTS machine_held_offline_notifications (
+                machine_id INTEGER PRIMARY KEY,
+                held_at TIMESTAMP NOT NULL,
+                FOREIGN KEY (machine_id) REFERENCES machines(id) ON DELETE CASCADE
+            );
+            `,
+		},
+		{
+			// Alert rules belong to an organization and only evaluate its machines. Existing rules move to
+			// the personal organization of the user the audit log says created them, falling back to the
+			// organization of their escalation policy and then to the first owner's. Escalation policies
+			// of another organization are detached. Without any organization there is no one to alert,
+			// so rules are dropped. API tokens holding the retired rules:admin scope get alerts:write,
+			// which now covers rules.
+			version: "041_alert_rule_orgs",
+			sql: `
+            ALTER TABLE alert_rules ADD COLUMN org_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+            UPDATE alert_rules SET org_id = COALESCE(
+                (SELECT o.id FROM audit_log a JOIN organizations o ON o.personal_user_id = a.actor_id
+                 WHERE a.action = 'alert_rule.create' AND a.target_type = 'alert_rule'
+                   AND a.target_id = CAST(alert_rules.id AS TEXT)
+                 ORDER BY a.id LIMIT 1),
+                (SELECT p.org_id FROM escalation_policies p WHERE p.id = alert_rules.escalation_policy_id),
+                (SELECT o.id FROM users u JOIN organizations o ON o.personal_user_id = u.id
+                 WHERE u.role = 'owner' ORDER BY u.id LIMIT 1),
+                (SELECT MIN(id) FROM organizations)
+            );
+            UPDATE alert_rules SET escalation_policy_id = NULL, escalation_policy_attached_at = NULL
+            WHERE escalation_policy_id NOT IN (SELECT p.id FROM escalation_policies p WHERE p.org_id = alert_rules.org_id);
+            DELETE FROM alert_rules WHERE org_id IS NULL;
+            CREATE INDEX IF NOT EXISTS idx_alert_rules_org_id ON alert_rules(org_id);
+            UPDATE api_tokens SET scopes = TRIM(REPLACE(' ' || scopes || ' ', ' rules:admin ',
+                CASE WHEN ' ' || scopes || ' ' LIKE '% alerts:write %' THEN ' ' ELSE ' alerts:write ' END))
+            WHERE ' ' || scopes || ' ' LIKE '% rules:admin %';
+            `,
+		},
+		{
+			// The bot acts in a Telegram chat with the role of the member who linked it. Existing chats
+			// were linked by the user the audit log says created them or, in a personal organization,
+			// by its user; chats with neither can only read until they are linked again.
+			version: "042_telegram_recipient_creators",
+			sql: `
+            ALTER TABLE telegram_recipients ADD COLUMN created_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+            UPDATE telegram_recipients SET created_by = COALESCE(
+                (SELECT a.actor_id FROM audit_log a JOIN users u ON u.id = a.actor_id
+                 WHERE a.action = 'telegram_recipient.create' AND a.actor_type = 'user'
+                   AND a.target_id = CAST(telegram_recipients.id AS TEXT)
+                 ORDER BY a.id LIMIT 1),
+                (SELECT o.personal_user_id FROM organizations o WHERE o.id = telegram_recipients.org_id)
+            );
             `,
 		},
 	}
@@ -337,21 +1060,38 @@ func (s *SQLiteStore) applyMigration(m migration) error {
 	return nil
 }
 
-// CreateUser creates a new user with the given email and password hash
+// userColumns are the users columns in scan order, followed by whether two-factor authentication is enabled
+const userColumns = `id, email, password_hash, is_admin, role, created_at,
+	EXISTS (SELECT 1 FROM user_totp t WHERE t.user_id = users.id AND t.enabled_at IS NOT NULL)`
+
+// CreateUser creates a new user with the given email and password hash, along with their personal organization
 func (s *SQLiteStore) CreateUser(ctx context.Context, email, passwordHash string) (*User, error) {
+	return s.insertUser(ctx, email, passwordHash, RoleOperator)
+}
+
+// insertUser creates a user with the role and their personal organization
+func (s *SQLiteStore) insertUser(ctx context.Context, email, passwordHash, role string) (*User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-	INSERT INTO users (email, password_hash, is_admin, created_at)
-	VALUES (?, ?, ?, ?)
-	RETURNING id, email, password_hash, is_admin, created_at`
+	INSERT INTO users (email, password_hash, is_admin, role, created_at)
+	VALUES (?, ?, ?, ?, ?)
+	RETURNING id, email, password_hash, is_admin, role, created_at`
 
 	now := time.Now()
 	user := &User{}
 
-	err := s.db.QueryRowContext(ctx, query, email, passwordHash, false, now).Scan(
+	isAdmin := role == RoleOwner || role == RoleAdmin
+	err = tx.QueryRowContext(ctx, query, email, passwordHash, isAdmin, role, now).Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Role,
 		&user.CreatedAt,
 	)
 	if err != nil {
@@ -361,12 +1101,19 @@ func (s *SQLiteStore) CreateUser(ctx context.Context, email, passwordHash string
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	if err := createPersonalOrganization(ctx, tx, user); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user: %w", err)
+	}
 	return user, nil
 }
 
 // GetUserByEmail retrieves a user by their email address
 func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (*User, error) {
-	query := `SELECT id, email, password_hash, is_admin, created_at FROM users WHERE email = ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = ?`
 
 	user := &User{}
 	err := s.db.QueryRowContext(ctx, query, email).Scan(
@@ -374,7 +1121,9 @@ func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (*User,
 		&user.Email,
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Role,
 		&user.CreatedAt,
+		&user.TOTPEnabled,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -388,7 +1137,7 @@ func (s *SQLiteStore) GetUserByEmail(ctx context.Context, email string) (*User,
 
 // GetUserByID retrieves a user by their ID
 func (s *SQLiteStore) GetUserByID(ctx context.Context, id int) (*User, error) {
-	query := `SELECT id, email, password_hash, is_admin, created_at FROM users WHERE id = ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ?`
 
 	user := &User{}
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
@@ -396,7 +1145,9 @@ func (s *SQLiteStore) GetUserByID(ctx context.Context, id int) (*User, error) {
 		&user.Email,
 		&user.PasswordHash,
 		&user.IsAdmin,
+		&user.Role,
 		&user.CreatedAt,
+		&user.TOTPEnabled,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -408,43 +1159,21 @@ func (s *SQLiteStore) GetUserByID(ctx context.Context, id int) (*User, error) {
 	return user, nil
 }
 
-// UpsertAdmin creates or updates an admin user with the given email and password hash
+// UpsertAdmin creates or updates an owner with the given email and password hash
 func (s *SQLiteStore) UpsertAdmin(ctx context.Context, email, passwordHash string) (*User, error) {
 	// Try to get existing user first
 	existingUser, err := s.GetUserByEmail(ctx, email)
 	if err != nil {
 		// User doesn't exist, create new admin user
 		if strings.Contains(err.Error(), "not found") || errors.Is(err, ErrUserNotFound) {
-			query := `
-			INSERT INTO users (email, password_hash, is_admin, created_at)
-			VALUES (?, ?, ?, ?)
-			RETURNING id, email, password_hash, is_admin, created_at`
-
-			now := time.Now()
-			user := &User{}
-
-			err := s.db.QueryRowContext(ctx, query, email, passwordHash, true, now).Scan(
-				&user.ID,
-				&user.Email,
-				&user.PasswordHash,
-				&user.IsAdmin,
-				&user.CreatedAt,
-			)
-			if err != nil {
-				if strings.Contains(err.Error(), "UNIQUE constraint failed: users.email") {
-					return nil, fmt.Errorf("user with email %s already exists", email)
-				}
-				return nil, fmt.Errorf("failed to create admin user: %w", err)
-			}
-
-			return user, nil
+			return s.insertUser(ctx, email, passwordHash, RoleOwner)
 		}
 		return nil, fmt.Errorf("failed to check existing user: %w", err)
 	}
 
-	// User exists, update password hash and ensure is_admin is true
-	query := `UPDATE users SET password_hash = ?, is_admin = 1 WHERE email = ?`
-	_, err = s.db.ExecContext(ctx, query, passwordHash, email)
+	// User exists, update password hash and ensure they are an owner
+	query := `UPDATE users SET password_hash = ?, is_admin = 1, role = ? WHERE email = ?`
+	_, err = s.db.ExecContext(ctx, query, passwordHash, RoleOwner, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user password: %w", err)
 	}
@@ -452,6 +1181,7 @@ func (s *SQLiteStore) UpsertAdmin(ctx context.Context, email, passwordHash strin
 	// Return updated user
 	existingUser.PasswordHash = passwordHash
 	existingUser.IsAdmin = true
+	existingUser.Role = RoleOwner
 	return existingUser, nil
 }
 
@@ -545,7 +1275,7 @@ func (s *SQLiteStore) MarkPasswordResetUsed(ctx context.Context, id int) error {
 
 // ListUsers retrieves all users ordered by email
 func (s *SQLiteStore) ListUsers(ctx context.Context) ([]User, error) {
-	query := `SELECT id, email, password_hash, is_admin, created_at FROM users ORDER BY email ASC`
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY email ASC`
 
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
@@ -556,7 +1286,7 @@ func (s *SQLiteStore) ListUsers(ctx context.Context) ([]User, error) {
 	var users []User
 	for rows.Next() {
 		var user User
-		err := rows.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.CreatedAt)
+		err := rows.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.Role, &user.CreatedAt, &user.TOTPEnabled)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
@@ -575,7 +1305,8 @@ func (s *SQLiteStore) DeleteUser(ctx context.Context, id int) error {
 	// First, check if the user exists and if they are admin
 	var exists bool
 	var isAdmin bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = ?), COALESCE((SELECT is_admin FROM users WHERE id = ?), 0)", id, id).Scan(&exists, &isAdmin)
+	var role string
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = ?), COALESCE((SELECT is_admin FROM users WHERE id = ?), 0), COALESCE((SELECT role FROM users WHERE id = ?), '')", id, id, id).Scan(&exists, &isAdmin, &role)
 	if err != nil {
 		return fmt.Errorf("failed to check user existence: %w", err)
 	}
@@ -597,13 +1328,39 @@ func (s *SQLiteStore) DeleteUser(ctx context.Context, id int) error {
 		}
 	}
 
+	// Check if this is the last owner
+	if role == RoleOwner {
+		var ownerCount int
+		err = s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role = ?", RoleOwner).Scan(&ownerCount)
+		if err != nil {
+			return fmt.Errorf("failed to count owners: %w", err)
+		}
+
+		if ownerCount <= 1 {
+			return fmt.Errorf("cannot delete the last owner")
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := leaveOrganizations(ctx, tx, id); err != nil {
+		return err
+	}
+
 	// Delete the user
 	query := `DELETE FROM users WHERE id = ?`
-	_, err = s.db.ExecContext(ctx, query, id)
+	_, err = tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user deletion: %w", err)
+	}
 	return nil
 }
 
@@ -617,9 +1374,9 @@ func (s *SQLiteStore) CountUsers(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-// PromoteToAdmin promotes a user to admin status
+// PromoteToAdmin promotes a user to admin status, leaving owners as they are
 func (s *SQLiteStore) PromoteToAdmin(ctx context.Context, userID int) error {
-	query := `UPDATE users SET is_admin = 1 WHERE id = ?`
+	query := `UPDATE users SET is_admin = 1, role = CASE WHEN role = 'owner' THEN role ELSE 'admin' END WHERE id = ?`
 	res, err := s.db.ExecContext(ctx, query, userID)
 	if err != nil {
 		return fmt.Errorf("failed to promote user to admin: %w", err)
@@ -634,6 +1391,44 @@ func (s *SQLiteStore) PromoteToAdmin(ctx context.Context, userID int) error {
 	return nil
 }
 
+// SetUserRole changes a user's role, refusing to demote the last owner
+func (s *SQLiteStore) SetUserRole(ctx context.Context, userID int, role string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx, "SELECT role FROM users WHERE id = ?", userID).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user role: %w", err)
+	}
+
+	if current == RoleOwner && role != RoleOwner {
+		var ownerCount int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE role = ?", RoleOwner).Scan(&ownerCount); err != nil {
+			return fmt.Errorf("failed to count owners: %w", err)
+		}
+		if ownerCount <= 1 {
+			return fmt.Errorf("cannot remove the last owner")
+		}
+	}
+
+	isAdmin := role == RoleOwner || role == RoleAdmin
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET role = ?, is_admin = ? WHERE id = ?", role, isAdmin, userID); err != nil {
+		return fmt.Errorf("failed to set user role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role change: %w", err)
+	}
+	return nil
+}
+
 // DeletePasswordResetsForUser deletes all password reset tokens for a user
 func (s *SQLiteStore) DeletePasswordResetsForUser(ctx context.Context, userID int) error {
 	query := `DELETE FROM password_resets WHERE user_id = ?`
@@ -646,79 +1441,140 @@ func (s *SQLiteStore) DeletePasswordResetsForUser(ctx context.Context, userID in
 
 // Alert Rules methods
 
-// ListAlertRules retrieves all alert rules
-func (s *SQLiteStore) ListAlertRules(ctx context.Context) ([]AlertRule, error) {
-	query := `SELECT id, name, metric, threshold_pct, comparison, trigger_after, created_at, updated_at 
+// alertRuleColumns are the alert_rules columns in scan order
+const alertRuleColumns = `id, org_id, name, metric, threshold_pct, comparison, trigger_after, severity, escalation_policy_id,
+              clear_threshold_pct, clear_after, for_seconds, clear_for_seconds, type, window_seconds, horizon_seconds,
+              seasonality, expression, created_at, updated_at`
+
+// alertRuleFields returns the scan destinations for alertRuleColumns
+func alertRuleFields(rule *AlertRule) []any {
+	return []any{&rule.ID, &rule.OrgID, &rule.Name, &rule.Metric, &rule.ThresholdPct, &rule.Comparison, &rule.TriggerAfter,
+		&rule.Severity, &rule.EscalationPolicyID, &rule.ClearThresholdPct, &rule.ClearAfter, &rule.ForSeconds,
+		&rule.ClearForSeconds, &rule.Type, &rule.WindowSeconds, &rule.HorizonSeconds, &rule.Seasonality,
+		&rule.Expression, &rule.CreatedAt, &rule.UpdatedAt}
+}
+
+// ListAlertRules retrieves an organization's alert rules
+func (s *SQLiteStore) ListAlertRules(ctx context.Context, orgID int) ([]AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + `
+              FROM alert_rules WHERE org_id = ? ORDER BY created_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	return scanAlertRules(rows)
+}
+
+// ListAllAlertRules retrieves the alert rules of every organization
+func (s *SQLiteStore) ListAllAlertRules(ctx context.Context) ([]AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + `
               FROM alert_rules ORDER BY created_at DESC`
 
 	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query alert rules: %w", err)
 	}
+	return scanAlertRules(rows)
+}
+
+// scanAlertRules scans and closes rows of alertRuleColumns
+func scanAlertRules(rows *sql.Rows) ([]AlertRule, error) {
 	defer rows.Close()
 
 	var rules []AlertRule
 	for rows.Next() {
 		var rule AlertRule
-		err := rows.Scan(&rule.ID, &rule.Name, &rule.Metric, &rule.ThresholdPct,
-			&rule.Comparison, &rule.TriggerAfter, &rule.CreatedAt, &rule.UpdatedAt)
+		err := rows.Scan(alertRuleFields(&rule)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
 		}
 		rules = append(rules, rule)
 	}
 
-	if err = rows.Err(); err != nil {
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("failed to iterate alert rules: %w", err)
 	}
 
 	return rules, nil
 }
 
-// CreateAlertRule creates a new alert rule
-func (s *SQLiteStore) CreateAlertRule(ctx context.Context, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*AlertRule, error) {
-	now := time.Now()
-	query := `INSERT INTO alert_rules (name, metric, threshold_pct, comparison, trigger_after, created_at, updated_at)
-              VALUES (?, ?, ?, ?, ?, ?, ?)
-              RETURNING id, name, metric, threshold_pct, comparison, trigger_after, created_at, updated_at`
+// GetAlertRule retrieves an organization's alert rule by ID
+func (s *SQLiteStore) GetAlertRule(ctx context.Context, id int, orgID int) (*AlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + `
+              FROM alert_rules WHERE id = ? AND org_id = ?`
 
 	rule := &AlertRule{}
-	err := s.db.QueryRowContext(ctx, query, name, metric, thresholdPct, comparison, triggerAfter, now, now).Scan(
-		&rule.ID, &rule.Name, &rule.Metric, &rule.ThresholdPct,
-		&rule.Comparison, &rule.TriggerAfter, &rule.CreatedAt, &rule.UpdatedAt)
+	err := s.db.QueryRowContext(ctx, query, id, orgID).Scan(alertRuleFields(rule)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create alert rule: %w", err)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("alert rule with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get alert rule: %w", err)
 	}
 
 	return rule, nil
 }
 
-// UpdateAlertRule updates an existing alert rule
-func (s *SQLiteStore) UpdateAlertRule(ctx context.Context, id int, name, metric, comparison string, thresholdPct float64, triggerAfter int) (*AlertRule, error) {
+// SaveAlertRule writes every setting of an alert rule in a single statement, creating the rule in
+// its organization when its ID is 0. An existing rule is only updated in its own organization.
+func (s *SQLiteStore) SaveAlertRule(ctx context.Context, rule AlertRule) (*AlertRule, error) {
 	now := time.Now()
-	query := `UPDATE alert_rules 
-              SET name = ?, metric = ?, threshold_pct = ?, comparison = ?, trigger_after = ?, updated_at = ?
-              WHERE id = ?
-              RETURNING id, name, metric, threshold_pct, comparison, trigger_after, created_at, updated_at`
-
-	rule := &AlertRule{}
-	err := s.db.QueryRowContext(ctx, query, name, metric, thresholdPct, comparison, triggerAfter, now, id).Scan(
-		&rule.ID, &rule.Name, &rule.Metric, &rule.ThresholdPct,
-		&rule.Comparison, &rule.TriggerAfter, &rule.CreatedAt, &rule.UpdatedAt)
+	args := []any{rule.Name, rule.Metric, rule.ThresholdPct, rule.Comparison, rule.TriggerAfter, rule.Severity,
+		rule.EscalationPolicyID, rule.ClearThresholdPct, rule.ClearAfter, rule.ForSeconds, rule.ClearForSeconds,
+		rule.Type, rule.WindowSeconds, rule.HorizonSeconds, rule.Seasonality, rule.Expression, now}
+
+	var query string
+	if rule.ID == 0 {
+		query = `INSERT INTO alert_rules (name, metric, threshold_pct, comparison, trigger_after, severity,
+                                    escalation_policy_id, clear_threshold_pct, clear_after, for_seconds,
+                                    clear_for_seconds, type, window_seconds, horizon_seconds, seasonality,
+                                    expression, updated_at, escalation_policy_attached_at, created_at, org_id)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+              RETURNING ` + alertRuleColumns
+		args = append(args, attachedAt(rule.EscalationPolicyID, now), now, rule.OrgID)
+	} else {
+		query = `UPDATE alert_rules
+              SET name = ?, metric = ?, threshold_pct = ?, comparison = ?, trigger_after = ?, severity = ?,
+                  escalation_policy_id = ?, clear_threshold_pct = ?, clear_after = ?, for_seconds = ?,
+                  clear_for_seconds = ?, type = ?, window_seconds = ?, horizon_seconds = ?, seasonality = ?,
+                  expression = ?, updated_at = ?,
+                  ` + escalationPolicyAttachedAt + `
+              WHERE id = ? AND org_id = ?
+              RETURNING ` + alertRuleColumns
+		args = append(args, rule.EscalationPolicyID, attachedAt(rule.EscalationPolicyID, now), rule.ID, rule.OrgID)
+	}
+
+	saved := &AlertRule{}
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(alertRuleFields(saved)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("alert rule with id %d not found", id)
+			return nil, fmt.Errorf("alert rule with id %d not found", rule.ID)
 		}
-		return nil, fmt.Errorf("failed to update alert rule: %w", err)
+		return nil, fmt.Errorf("failed to save alert rule: %w", err)
 	}
 
-	return rule, nil
+	return saved, nil
+}
+
+// escalationPolicyAttachedAt is the SET clause of an alert rule update that records when a different
+// escalation policy was attached, taking the new policy ID and the time it is attached at. Only
+// events triggered after that escalate.
+const escalationPolicyAttachedAt = `escalation_policy_attached_at = CASE WHEN escalation_policy_id IS ?
+                  THEN escalation_policy_attached_at ELSE ? END`
+
+// attachedAt returns the time an escalation policy is attached at, nil when none is
+func attachedAt(policyID *int, now time.Time) *time.Time {
+	if policyID == nil {
+		return nil
+	}
+	return &now
 }
 
-// DeleteAlertRule deletes an alert rule (and cascades to delete related events)
-func (s *SQLiteStore) DeleteAlertRule(ctx context.Context, id int) error {
-	query := `DELETE FROM alert_rules WHERE id = ?`
-	res, err := s.db.ExecContext(ctx, query, id)
+// DeleteAlertRule deletes an organization's alert rule (and cascades to delete related events)
+func (s *SQLiteStore) DeleteAlertRule(ctx context.Context, id int, orgID int) error {
+	query := `DELETE FROM alert_rules WHERE id = ? AND org_id = ?`
+	res, err := s.db.ExecContext(ctx, query, id, orgID)
 	if err != nil {
 		return fmt.Errorf("failed to delete alert rule: %w", err)
 	}
@@ -736,11 +1592,21 @@ func (s *SQLiteStore) DeleteAlertRule(ctx context.Context, id int) error {
 
 // Alert Events methods
 
+// alertEventColumns are the alert_events columns in scan order
+const alertEventColumns = `id, rule_id, triggered_at, value, acknowledged, acknowledged_at, resolved_at, severity, machine_id,
+              expected_low, expected_high`
+
+// alertEventFields returns the scan destinations for alertEventColumns
+func alertEventFields(event *AlertEvent) []any {
+	return []any{&event.ID, &event.RuleID, &event.TriggeredAt, &event.Value, &event.Acknowledged,
+		&event.AcknowledgedAt, &event.ResolvedAt, &event.Severity, &event.MachineID, &event.ExpectedLow, &event.ExpectedHigh}
+}
+
 // ListAlertEvents retrieves recent alert events (unacknowledged first, limited)
 func (s *SQLiteStore) ListAlertEvents(ctx context.Context, limit int) ([]AlertEvent, error) {
-	query := `SELECT id, rule_id, triggered_at, value, acknowledged, acknowledged_at 
-              FROM alert_events 
-              ORDER BY acknowledged ASC, triggered_at DESC 
+	query := `SELECT ` + alertEventColumns + `
+              FROM alert_events
+              ORDER BY acknowledged ASC, triggered_at DESC, id DESC
               LIMIT ?`
 
 	rows, err := s.db.QueryContext(ctx, query, limit)
@@ -752,9 +1618,143 @@ func (s *SQLiteStore) ListAlertEvents(ctx context.Context, limit int) ([]AlertEv
 	var events []AlertEvent
 	for rows.Next() {
 		var event AlertEvent
-		err := rows.Scan(&event.ID, &event.RuleID, &event.TriggeredAt,
-			&event.Value, &event.Acknowledged, &event.AcknowledgedAt)
+		if err := rows.Scan(alertEventFields(&event)...); err != nil {
+			return nil, fmt.Errorf("failed to scan alert event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate alert events: %w", err)
+	}
+
+	return events, nil
+}
+
+// QueryAlertEvents returns up to filter.Limit events matching the filter, newest first, and the
+// cursor of the last one if more events match
+func (s *SQLiteStore) QueryAlertEvents(ctx context.Context, filter AlertEventFilter) ([]AlertEvent, *AlertEventCursor, error) {
+	if filter.Limit <= 0 {
+		return nil, nil, nil
+	}
+
+	var conditions []string
+	var args []any
+
+	if filter.OrgID != 0 {
+		conditions = append(conditions, "rule_id IN (SELECT id FROM alert_rules WHERE org_id = ?)")
+		args = append(args, filter.OrgID)
+	}
+	if filter.RuleID != nil {
+		conditions = append(conditions, "rule_id = ?")
+		args = append(args, *filter.RuleID)
+	}
+	if filter.MachineID != nil {
+		conditions = append(conditions, "machine_id = ?")
+		args = append(args, *filter.MachineID)
+	}
+	if filter.Resolved != nil {
+		if *filter.Resolved {
+			conditions = append(conditions, "resolved_at IS NOT NULL")
+		} else {
+			conditions = append(conditions, "resolved_at IS NULL")
+		}
+	}
+	if filter.Acknowledged != nil {
+		conditions = append(conditions, "acknowledged = ?")
+		args = append(args, *filter.Acknowledged)
+	}
+	if filter.Severity != "" {
+		conditions = append(conditions, "severity = ?")
+		args = append(args, filter.Severity)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "triggered_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "triggered_at < ?")
+		args = append(args, *filter.To)
+	}
+	if filter.After != nil {
+		conditions = append(conditions, "(triggered_at < ? OR (triggered_at = ? AND id < ?))")
+		args = append(args, filter.After.TriggeredAt, filter.After.TriggeredAt, filter.After.ID)
+	}
+
+	query := `SELECT ` + alertEventColumns + `
+              FROM alert_events`
+	if len(conditions) > 0 {
+		query += `
+              WHERE ` + strings.Join(conditions, " AND ")
+	}
+	// One extra row tells whether another page follows
+	query += `
+              ORDER BY triggered_at DESC, id DESC
+              LIMIT ?`
+	args = append(args, filter.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query alert events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AlertEvent
+	for rows.Next() {
+		var event AlertEvent
+		err := rows.Scan(alertEventFields(&event)...)
 		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan alert event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate alert events: %w", err)
+	}
+
+	if len(events) <= filter.Limit {
+		return events, nil, nil
+	}
+	events = events[:filter.Limit]
+	last := events[len(events)-1]
+	return events, &AlertEventCursor{TriggeredAt: last.TriggeredAt, ID: last.ID}, nil
+}
+
+// GetAlertEvent retrieves an alert event by ID
+func (s *SQLiteStore) GetAlertEvent(ctx context.Context, id int) (*AlertEvent, error) {
+	query := `SELECT ` + alertEventColumns + `
+              FROM alert_events WHERE id = ?`
+
+	event := &AlertEvent{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(alertEventFields(event)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("alert event with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get alert event: %w", err)
+	}
+
+	return event, nil
+}
+
+// ListOpenAlertEvents returns the alert events that have not been resolved, oldest first
+func (s *SQLiteStore) ListOpenAlertEvents(ctx context.Context) ([]AlertEvent, error) {
+	query := `SELECT ` + alertEventColumns + `
+              FROM alert_events 
+              WHERE resolved_at IS NULL
+              ORDER BY triggered_at ASC, id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open alert events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AlertEvent
+	for rows.Next() {
+		var event AlertEvent
+		if err := rows.Scan(alertEventFields(&event)...); err != nil {
 			return nil, fmt.Errorf("failed to scan alert event: %w", err)
 		}
 		events = append(events, event)
@@ -767,17 +1767,16 @@ func (s *SQLiteStore) ListAlertEvents(ctx context.Context, limit int) ([]AlertEv
 	return events, nil
 }
 
-// CreateAlertEvent creates a new alert event
-func (s *SQLiteStore) CreateAlertEvent(ctx context.Context, ruleID int, value float64) (*AlertEvent, error) {
+// CreateAlertEvent creates a new alert event, taking its severity from the rule.
+// machineID is the machine whose metrics fired the rule, or nil for the local host.
+func (s *SQLiteStore) CreateAlertEvent(ctx context.Context, ruleID int, machineID *int, value float64) (*AlertEvent, error) {
 	now := time.Now()
-	query := `INSERT INTO alert_events (rule_id, triggered_at, value, acknowledged)
-              VALUES (?, ?, ?, ?)
-              RETURNING id, rule_id, triggered_at, value, acknowledged, acknowledged_at`
+	query := `INSERT INTO alert_events (rule_id, triggered_at, value, acknowledged, machine_id, severity)
+              VALUES (?, ?, ?, ?, ?, COALESCE((SELECT severity FROM alert_rules WHERE id = ?), 'warning'))
+              RETURNING ` + alertEventColumns
 
 	event := &AlertEvent{}
-	err := s.db.QueryRowContext(ctx, query, ruleID, now, value, false).Scan(
-		&event.ID, &event.RuleID, &event.TriggeredAt,
-		&event.Value, &event.Acknowledged, &event.AcknowledgedAt)
+	err := s.db.QueryRowContext(ctx, query, ruleID, now, value, false, machineID, ruleID).Scan(alertEventFields(event)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create alert event: %w", err)
 	}
@@ -785,6 +1784,25 @@ func (s *SQLiteStore) CreateAlertEvent(ctx context.Context, ruleID int, value fl
 	return event, nil
 }
 
+// SetAlertEventExpectedRange records the range an anomaly rule expected the event's value in
+func (s *SQLiteStore) SetAlertEventExpectedRange(ctx context.Context, id int, low, high float64) (*AlertEvent, error) {
+	query := `UPDATE alert_events 
+              SET expected_low = ?, expected_high = ?
+              WHERE id = ?
+              RETURNING ` + alertEventColumns
+
+	event := &AlertEvent{}
+	err := s.db.QueryRowContext(ctx, query, low, high, id).Scan(alertEventFields(event)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("alert event with id %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to set alert event expected range: %w", err)
+	}
+
+	return event, nil
+}
+
 // AckAlertEvent acknowledges an alert event
 func (s *SQLiteStore) AckAlertEvent(ctx context.Context, id int) error {
 	now := time.Now()
@@ -808,16 +1826,37 @@ func (s *SQLiteStore) AckAlertEvent(ctx context.Context, id int) error {
 	return nil
 }
 
+// ResolveAlertEvent marks an alert event as resolved and returns the updated event
+func (s *SQLiteStore) ResolveAlertEvent(ctx context.Context, id int) (*AlertEvent, error) {
+	now := time.Now()
+	query := `UPDATE alert_events 
+              SET resolved_at = ?
+              WHERE id = ? AND resolved_at IS NULL
+              RETURNING ` + alertEventColumns
+
+	event := &AlertEvent{}
+	err := s.db.QueryRowContext(ctx, query, now, id).Scan(alertEventFields(event)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("alert event with id %d not found or already resolved", id)
+		}
+		return nil, fmt.Errorf("failed to resolve alert event: %w", err)
+	}
+
+	return event, nil
+}
+
 // Webhook methods
 
-// ListWebhooks returns all webhooks for a specific user
-func (s *SQLiteStore) ListWebhooks(ctx context.Context, userID int) ([]Webhook, error) {
-	query := `SELECT id, user_id, url, secret_hash, is_active, failure_count, 
-              last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at
-              FROM webhooks WHERE user_id = ?
+// ListWebhooks returns all webhooks for a specific organization
+func (s *SQLiteStore) ListWebhooks(ctx context.Context, orgID int) ([]Webhook, error) {
+	query := `SELECT id, org_id, url, secret_hash, is_active, failure_count, 
+              last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at,
+              min_attempt_interval_seconds, failure_threshold, failure_window_seconds, cooldown_seconds, previous_secret_hash, previous_secret_expires_at
+              FROM webhooks WHERE org_id = ?
               ORDER BY created_at ASC`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.db.QueryContext(ctx, query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query webhooks: %w", err)
 	}
@@ -826,9 +1865,11 @@ func (s *SQLiteStore) ListWebhooks(ctx context.Context, userID int) ([]Webhook,
 	var webhooks []Webhook
 	for rows.Next() {
 		var w Webhook
-		err := rows.Scan(&w.ID, &w.UserID, &w.URL, &w.SecretHash, &w.IsActive,
+		err := rows.Scan(&w.ID, &w.OrgID, &w.URL, &w.SecretHash, &w.IsActive,
 			&w.FailureCount, &w.LastSuccessAt, &w.LastErrorAt, &w.CooldownUntil, &w.LastAttemptAt,
-			&w.CreatedAt, &w.UpdatedAt)
+			&w.CreatedAt, &w.UpdatedAt,
+			&w.MinAttemptIntervalSeconds, &w.FailureThreshold, &w.FailureWindowSeconds, &w.CooldownSeconds,
+			&w.PreviousSecretHash, &w.PreviousSecretExpiresAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan webhook: %w", err)
 		}
@@ -842,21 +1883,24 @@ func (s *SQLiteStore) ListWebhooks(ctx context.Context, userID int) ([]Webhook,
 	return webhooks, nil
 }
 
-// GetWebhook retrieves a single webhook by ID for a specific user
-func (s *SQLiteStore) GetWebhook(ctx context.Context, id int, userID int) (*Webhook, error) {
-	query := `SELECT id, user_id, url, secret_hash, is_active, failure_count,
-              last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at
-              FROM webhooks WHERE id = ? AND user_id = ?`
+// GetWebhook retrieves a single webhook by ID for a specific organization
+func (s *SQLiteStore) GetWebhook(ctx context.Context, id int, orgID int) (*Webhook, error) {
+	query := `SELECT id, org_id, url, secret_hash, is_active, failure_count,
+              last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at,
+              min_attempt_interval_seconds, failure_threshold, failure_window_seconds, cooldown_seconds, previous_secret_hash, previous_secret_expires_at
+              FROM webhooks WHERE id = ? AND org_id = ?`
 
 	webhook := &Webhook{}
-	err := s.db.QueryRowContext(ctx, query, id, userID).Scan(
-		&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.SecretHash,
+	err := s.db.QueryRowContext(ctx, query, id, orgID).Scan(
+		&webhook.ID, &webhook.OrgID, &webhook.URL, &webhook.SecretHash,
 		&webhook.IsActive, &webhook.FailureCount, &webhook.LastSuccessAt,
-		&webhook.LastErrorAt, &webhook.CooldownUntil, &webhook.LastAttemptAt, &webhook.CreatedAt, &webhook.UpdatedAt)
+		&webhook.LastErrorAt, &webhook.CooldownUntil, &webhook.LastAttemptAt, &webhook.CreatedAt, &webhook.UpdatedAt,
+		&webhook.MinAttemptIntervalSeconds, &webhook.FailureThreshold, &webhook.FailureWindowSeconds, &webhook.CooldownSeconds,
+		&webhook.PreviousSecretHash, &webhook.PreviousSecretExpiresAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("webhook with id %d not found for user %d", id, userID)
+			return nil, fmt.Errorf("webhook with id %d not found for organization %d", id, orgID)
 		}
 		return nil, fmt.Errorf("failed to fetch webhook: %w", err)
 	}
@@ -864,20 +1908,23 @@ func (s *SQLiteStore) GetWebhook(ctx context.Context, id int, userID int) (*Webh
 	return webhook, nil
 }
 
-// CreateWebhook creates a new webhook for a user
-func (s *SQLiteStore) CreateWebhook(ctx context.Context, userID int, url, secretHash string) (*Webhook, error) {
+// CreateWebhook creates a new webhook for an organization
+func (s *SQLiteStore) CreateWebhook(ctx context.Context, orgID int, url, secretHash string) (*Webhook, error) {
 	now := time.Now()
-	query := `INSERT INTO webhooks (user_id, url, secret_hash, is_active, failure_count, 
+	query := `INSERT INTO webhooks (org_id, url, secret_hash, is_active, failure_count, 
               created_at, updated_at)
               VALUES (?, ?, ?, 1, 0, ?, ?)
-              RETURNING id, user_id, url, secret_hash, is_active, failure_count,
-              last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at`
+              RETURNING id, org_id, url, secret_hash, is_active, failure_count,
+              last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at,
+              min_attempt_interval_seconds, failure_threshold, failure_window_seconds, cooldown_seconds, previous_secret_hash, previous_secret_expires_at`
 
 	webhook := &Webhook{}
-	err := s.db.QueryRowContext(ctx, query, userID, url, secretHash, now, now).Scan(
-		&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.SecretHash,
+	err := s.db.QueryRowContext(ctx, query, orgID, url, secretHash, now, now).Scan(
+		&webhook.ID, &webhook.OrgID, &webhook.URL, &webhook.SecretHash,
 		&webhook.IsActive, &webhook.FailureCount, &webhook.LastSuccessAt,
-		&webhook.LastErrorAt, &webhook.CooldownUntil, &webhook.LastAttemptAt, &webhook.CreatedAt, &webhook.UpdatedAt)
+		&webhook.LastErrorAt, &webhook.CooldownUntil, &webhook.LastAttemptAt, &webhook.CreatedAt, &webhook.UpdatedAt,
+		&webhook.MinAttemptIntervalSeconds, &webhook.FailureThreshold, &webhook.FailureWindowSeconds, &webhook.CooldownSeconds,
+		&webhook.PreviousSecretHash, &webhook.PreviousSecretExpiresAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create webhook: %w", err)
 	}
@@ -886,7 +1933,7 @@ func (s *SQLiteStore) CreateWebhook(ctx context.Context, userID int, url, secret
 }
 
 // UpdateWebhook updates an existing webhook
-func (s *SQLiteStore) UpdateWebhook(ctx context.Context, id int, userID int, url string, secretHash *string, isActive *bool) (*Webhook, error) {
+func (s *SQLiteStore) UpdateWebhook(ctx context.Context, id int, orgID int, url string, secretHash *string, isActive *bool) (*Webhook, error) {
 	now := time.Now()
 
 	// Build dynamic query based on what fields are being updated
@@ -899,7 +1946,8 @@ func (s *SQLiteStore) UpdateWebhook(ctx context.Context, id int, userID int, url
 	}
 
 	if secretHash != nil {
-		setParts = append(setParts, "secret_hash = ?")
+		// Replacing the secret outright ends any rotation in progress
+		setParts = append(setParts, "secret_hash = ?", "previous_secret_hash = NULL", "previous_secret_expires_at = NULL")
 		args = append(args, *secretHash)
 	}
 
@@ -909,8 +1957,8 @@ func (s *SQLiteStore) UpdateWebhook(ctx context.Context, id int, userID int, url
 	}
 
 	setClause := strings.Join(setParts, ", ")
-	query := fmt.Sprintf(`UPDATE webhooks SET %s WHERE id = ? AND user_id = ?`, setClause)
-	args = append(args, id, userID)
+	query := fmt.Sprintf(`UPDATE webhooks SET %s WHERE id = ? AND org_id = ?`, setClause)
+	args = append(args, id, orgID)
 
 	res, err := s.db.ExecContext(ctx, query, args...)
 	if err != nil {
@@ -922,19 +1970,22 @@ func (s *SQLiteStore) UpdateWebhook(ctx context.Context, id int, userID int, url
 		return nil, fmt.Errorf("failed to verify update: %w", err)
 	}
 	if rows == 0 {
-		return nil, fmt.Errorf("webhook with id %d not found for user %d", id, userID)
+		return nil, fmt.Errorf("webhook with id %d not found for organization %d", id, orgID)
 	}
 
 	// Return updated webhook
-	selectQuery := `SELECT id, user_id, url, secret_hash, is_active, failure_count,
-                    last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at
-                    FROM webhooks WHERE id = ? AND user_id = ?`
+	selectQuery := `SELECT id, org_id, url, secret_hash, is_active, failure_count,
+                    last_success_at, last_error_at, cooldown_until, last_attempt_at, created_at, updated_at,
+                    min_attempt_interval_seconds, failure_threshold, failure_window_seconds, cooldown_seconds, previous_secret_hash, previous_secret_expires_at
+                    FROM webhooks WHERE id = ? AND org_id = ?`
 
 	webhook := &Webhook{}
-	err = s.db.QueryRowContext(ctx, selectQuery, id, userID).Scan(
-		&webhook.ID, &webhook.UserID, &webhook.URL, &webhook.SecretHash,
+	err = s.db.QueryRowContext(ctx, selectQuery, id, orgID).Scan(
+		&webhook.ID, &webhook.OrgID, &webhook.URL, &webhook.SecretHash,
 		&webhook.IsActive, &webhook.FailureCount, &webhook.LastSuccessAt,
-		&webhook.LastErrorAt, &webhook.CooldownUntil, &webhook.LastAttemptAt, &webhook.CreatedAt, &webhook.UpdatedAt)
+		&webhook.LastErrorAt, &webhook.CooldownUntil, &webhook.LastAttemptAt, &webhook.CreatedAt, &webhook.UpdatedAt,
+		&webhook.MinAttemptIntervalSeconds, &webhook.FailureThreshold, &webhook.FailureWindowSeconds, &webhook.CooldownSeconds,
+		&webhook.PreviousSecretHash, &webhook.PreviousSecretExpiresAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch updated webhook: %w", err)
 	}
@@ -942,11 +1993,11 @@ func (s *SQLiteStore) UpdateWebhook(ctx context.Context, id int, userID int, url
 	return webhook, nil
 }
 
-// DeleteWebhook deletes a webhook for a user
-func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id int, userID int) error {
-	query := `DELETE FROM webhooks WHERE id = ? AND user_id = ?`
+// DeleteWebhook deletes a webhook for an organization
+func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id int, orgID int) error {
+	query := `DELETE FROM webhooks WHERE id = ? AND org_id = ?`
 
-	res, err := s.db.ExecContext(ctx, query, id, userID)
+	res, err := s.db.ExecContext(ctx, query, id, orgID)
 	if err != nil {
 		return fmt.Errorf("failed to delete webhook: %w", err)
 	}
@@ -956,7 +2007,7 @@ func (s *SQLiteStore) DeleteWebhook(ctx context.Context, id int, userID int) err
 		return fmt.Errorf("failed to verify deletion: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("webhook with id %d not found for user %d", id, userID)
+		return fmt.Errorf("webhook with id %d not found for organization %d", id, orgID)
 	}
 
 	return nil
@@ -1028,15 +2079,82 @@ func (s *SQLiteStore) UpdateWebhookDeliveryState(ctx context.Context, id int, la
 	return nil
 }
 
-// ListEmailRecipients returns all email recipients for a user
-func (s *SQLiteStore) ListEmailRecipients(ctx context.Context, userID int) ([]EmailRecipient, error) {
-	query := `SELECT id, user_id, email, is_active, failure_count, last_success_at, 
+// UpdateWebhookDeliverySettings replaces the webhook's rate limiting and circuit breaker overrides
+func (s *SQLiteStore) UpdateWebhookDeliverySettings(ctx context.Context, id int, orgID int, settings DeliverySettings) (*Webhook, error) {
+	query := `UPDATE webhooks
+              SET min_attempt_interval_seconds = ?, failure_threshold = ?, failure_window_seconds = ?, cooldown_seconds = ?, updated_at = ?
+              WHERE id = ? AND org_id = ?`
+
+	res, err := s.db.ExecContext(ctx, query, settings.MinAttemptIntervalSeconds, settings.FailureThreshold,
+		settings.FailureWindowSeconds, settings.CooldownSeconds, time.Now(), id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook delivery settings: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify delivery settings update: %w", err)
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("webhook with id %d not found for organization %d", id, orgID)
+	}
+
+	return s.GetWebhook(ctx, id, orgID)
+}
+
+// ResetWebhookCircuit clears the webhook's failure count, cooldown and rate limit window
+func (s *SQLiteStore) ResetWebhookCircuit(ctx context.Context, id int, orgID int) (*Webhook, error) {
+	query := `UPDATE webhooks
+              SET failure_count = 0, cooldown_until = NULL, last_attempt_at = NULL, updated_at = ?
+              WHERE id = ? AND org_id = ?`
+
+	res, err := s.db.ExecContext(ctx, query, time.Now(), id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset webhook circuit: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify circuit reset: %w", err)
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("webhook with id %d not found for organization %d", id, orgID)
+	}
+
+	return s.GetWebhook(ctx, id, orgID)
+}
+
+// RotateWebhookSecret replaces the webhook's secret while keeping the current one valid until previousExpiresAt
+func (s *SQLiteStore) RotateWebhookSecret(ctx context.Context, id int, orgID int, newSecretHash string, previousExpiresAt time.Time) (*Webhook, error) {
+	query := `UPDATE webhooks
+              SET previous_secret_hash = secret_hash, previous_secret_expires_at = ?, secret_hash = ?, updated_at = ?
+              WHERE id = ? AND org_id = ?`
+
+	res, err := s.db.ExecContext(ctx, query, previousExpiresAt, newSecretHash, time.Now(), id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate webhook secret: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify secret rotation: %w", err)
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("webhook with id %d not found for organization %d", id, orgID)
+	}
+
+	return s.GetWebhook(ctx, id, orgID)
+}
+
+// ListEmailRecipients returns all email recipients for an organization
+func (s *SQLiteStore) ListEmailRecipients(ctx context.Context, orgID int) ([]EmailRecipient, error) {
+	query := `SELECT id, org_id, email, is_active, failure_count, last_success_at, 
                      last_error_at, cooldown_until, last_attempt_at, created_at, updated_at
               FROM email_recipients
-              WHERE user_id = ?
+              WHERE org_id = ?
               ORDER BY created_at DESC`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.db.QueryContext(ctx, query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query email recipients: %w", err)
 	}
@@ -1045,7 +2163,7 @@ func (s *SQLiteStore) ListEmailRecipients(ctx context.Context, userID int) ([]Em
 	var recipients []EmailRecipient
 	for rows.Next() {
 		var r EmailRecipient
-		if err := rows.Scan(&r.ID, &r.UserID, &r.Email, &r.IsActive, &r.FailureCount,
+		if err := rows.Scan(&r.ID, &r.OrgID, &r.Email, &r.IsActive, &r.FailureCount,
 			&r.LastSuccessAt, &r.LastErrorAt, &r.CooldownUntil, &r.LastAttemptAt,
 			&r.CreatedAt, &r.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan email recipient: %w", err)
@@ -1060,16 +2178,16 @@ func (s *SQLiteStore) ListEmailRecipients(ctx context.Context, userID int) ([]Em
 	return recipients, nil
 }
 
-// GetEmailRecipient retrieves a single email recipient by ID and userID
-func (s *SQLiteStore) GetEmailRecipient(ctx context.Context, id int, userID int) (*EmailRecipient, error) {
-	query := `SELECT id, user_id, email, is_active, failure_count, last_success_at,
+// GetEmailRecipient retrieves a single email recipient by ID and orgID
+func (s *SQLiteStore) GetEmailRecipient(ctx context.Context, id int, orgID int) (*EmailRecipient, error) {
+	query := `SELECT id, org_id, email, is_active, failure_count, last_success_at,
                      last_error_at, cooldown_until, last_attempt_at, created_at, updated_at
               FROM email_recipients
-              WHERE id = ? AND user_id = ?`
+              WHERE id = ? AND org_id = ?`
 
 	var r EmailRecipient
-	err := s.db.QueryRowContext(ctx, query, id, userID).Scan(
-		&r.ID, &r.UserID, &r.Email, &r.IsActive, &r.FailureCount,
+	err := s.db.QueryRowContext(ctx, query, id, orgID).Scan(
+		&r.ID, &r.OrgID, &r.Email, &r.IsActive, &r.FailureCount,
 		&r.LastSuccessAt, &r.LastErrorAt, &r.CooldownUntil, &r.LastAttemptAt,
 		&r.CreatedAt, &r.UpdatedAt,
 	)
@@ -1082,11 +2200,11 @@ func (s *SQLiteStore) GetEmailRecipient(ctx context.Context, id int, userID int)
 }
 
 // CreateEmailRecipient creates a new email recipient
-func (s *SQLiteStore) CreateEmailRecipient(ctx context.Context, userID int, email string) (*EmailRecipient, error) {
-	query := `INSERT INTO email_recipients (user_id, email, is_active, failure_count)
+func (s *SQLiteStore) CreateEmailRecipient(ctx context.Context, orgID int, email string) (*EmailRecipient, error) {
+	query := `INSERT INTO email_recipients (org_id, email, is_active, failure_count)
               VALUES (?, ?, 1, 0)`
 
-	result, err := s.db.ExecContext(ctx, query, userID, email)
+	result, err := s.db.ExecContext(ctx, query, orgID, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create email recipient: %w", err)
 	}
@@ -1096,11 +2214,11 @@ func (s *SQLiteStore) CreateEmailRecipient(ctx context.Context, userID int, emai
 		return nil, fmt.Errorf("failed to get email recipient ID: %w", err)
 	}
 
-	return s.GetEmailRecipient(ctx, int(id), userID)
+	return s.GetEmailRecipient(ctx, int(id), orgID)
 }
 
 // UpdateEmailRecipient updates an email recipient
-func (s *SQLiteStore) UpdateEmailRecipient(ctx context.Context, id int, userID int, email string, isActive *bool) (*EmailRecipient, error) {
+func (s *SQLiteStore) UpdateEmailRecipient(ctx context.Context, id int, orgID int, email string, isActive *bool) (*EmailRecipient, error) {
 	// Build dynamic query based on provided fields
 	updates := []string{}
 	args := []interface{}{}
@@ -1116,16 +2234,16 @@ func (s *SQLiteStore) UpdateEmailRecipient(ctx context.Context, id int, userID i
 	}
 
 	if len(updates) == 0 {
-		return s.GetEmailRecipient(ctx, id, userID)
+		return s.GetEmailRecipient(ctx, id, orgID)
 	}
 
 	updates = append(updates, "updated_at = ?")
 	args = append(args, time.Now())
 
 	// Add WHERE clause parameters
-	args = append(args, id, userID)
+	args = append(args, id, orgID)
 
-	query := fmt.Sprintf("UPDATE email_recipients SET %s WHERE id = ? AND user_id = ?",
+	query := fmt.Sprintf("UPDATE email_recipients SET %s WHERE id = ? AND org_id = ?",
 		strings.Join(updates, ", "))
 
 	res, err := s.db.ExecContext(ctx, query, args...)
@@ -1141,14 +2259,14 @@ func (s *SQLiteStore) UpdateEmailRecipient(ctx context.Context, id int, userID i
 		return nil, fmt.Errorf("email recipient with id %d not found or unauthorized", id)
 	}
 
-	return s.GetEmailRecipient(ctx, id, userID)
+	return s.GetEmailRecipient(ctx, id, orgID)
 }
 
 // DeleteEmailRecipient deletes an email recipient
-func (s *SQLiteStore) DeleteEmailRecipient(ctx context.Context, id int, userID int) error {
-	query := `DELETE FROM email_recipients WHERE id = ? AND user_id = ?`
+func (s *SQLiteStore) DeleteEmailRecipient(ctx context.Context, id int, orgID int) error {
+	query := `DELETE FROM email_recipients WHERE id = ? AND org_id = ?`
 
-	res, err := s.db.ExecContext(ctx, query, id, userID)
+	res, err := s.db.ExecContext(ctx, query, id, orgID)
 	if err != nil {
 		return fmt.Errorf("failed to delete email recipient: %w", err)
 	}
@@ -1230,14 +2348,15 @@ func (s *SQLiteStore) UpdateEmailDeliveryState(ctx context.Context, id int, last
 	return nil
 }
 
-// ListTelegramRecipients returns all Telegram recipients for a user
-func (s *SQLiteStore) ListTelegramRecipients(ctx context.Context, userID int) ([]TelegramRecipient, error) {
-	query := `SELECT id, user_id, chat_id, is_active, created_at, last_attempt_at, last_success_at, last_error_at, failure_count, cooldown_until
+// ListTelegramRecipients returns all Telegram recipients for an organization
+func (s *SQLiteStore) ListTelegramRecipients(ctx context.Context, orgID int) ([]TelegramRecipient, error) {
+	query := `SELECT id, org_id, chat_id, is_active, created_at, last_attempt_at, last_success_at, last_error_at, failure_count, cooldown_until,
+              min_attempt_interval_seconds, failure_threshold, failure_window_seconds, cooldown_seconds, created_by
               FROM telegram_recipients
-              WHERE user_id = ?
+              WHERE org_id = ?
               ORDER BY created_at DESC`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.db.QueryContext(ctx, query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list telegram recipients: %w", err)
 	}
@@ -1246,8 +2365,42 @@ func (s *SQLiteStore) ListTelegramRecipients(ctx context.Context, userID int) ([
 	var recipients []TelegramRecipient
 	for rows.Next() {
 		var r TelegramRecipient
-		err := rows.Scan(&r.ID, &r.UserID, &r.ChatID, &r.IsActive, &r.CreatedAt,
-			&r.LastAttemptAt, &r.LastSuccessAt, &r.LastErrorAt, &r.FailureCount, &r.CooldownUntil)
+		err := rows.Scan(&r.ID, &r.OrgID, &r.ChatID, &r.IsActive, &r.CreatedAt,
+			&r.LastAttemptAt, &r.LastSuccessAt, &r.LastErrorAt, &r.FailureCount, &r.CooldownUntil,
+			&r.MinAttemptIntervalSeconds, &r.FailureThreshold, &r.FailureWindowSeconds, &r.CooldownSeconds, &r.CreatedBy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan telegram recipient: %w", err)
+		}
+		recipients = append(recipients, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating telegram recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// ListTelegramRecipientsByChatID returns every recipient registered for a chat, across all organizations
+func (s *SQLiteStore) ListTelegramRecipientsByChatID(ctx context.Context, chatID string) ([]TelegramRecipient, error) {
+	query := `SELECT id, org_id, chat_id, is_active, created_at, last_attempt_at, last_success_at, last_error_at, failure_count, cooldown_until,
+              min_attempt_interval_seconds, failure_threshold, failure_window_seconds, cooldown_seconds, created_by
+              FROM telegram_recipients
+              WHERE chat_id = ?
+              ORDER BY created_at ASC, id ASC`
+
+	rows, err := s.db.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list telegram recipients by chat id: %w", err)
+	}
+	defer rows.Close()
+
+	var recipients []TelegramRecipient
+	for rows.Next() {
+		var r TelegramRecipient
+		err := rows.Scan(&r.ID, &r.OrgID, &r.ChatID, &r.IsActive, &r.CreatedAt,
+			&r.LastAttemptAt, &r.LastSuccessAt, &r.LastErrorAt, &r.FailureCount, &r.CooldownUntil,
+			&r.MinAttemptIntervalSeconds, &r.FailureThreshold, &r.FailureWindowSeconds, &r.CooldownSeconds, &r.CreatedBy)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan telegram recipient: %w", err)
 		}
@@ -1262,14 +2415,16 @@ func (s *SQLiteStore) ListTelegramRecipients(ctx context.Context, userID int) ([
 }
 
 // GetTelegramRecipient returns a specific Telegram recipient
-func (s *SQLiteStore) GetTelegramRecipient(ctx context.Context, id int, userID int) (*TelegramRecipient, error) {
-	query := `SELECT id, user_id, chat_id, is_active, created_at, last_attempt_at, last_success_at, last_error_at, failure_count, cooldown_until
+func (s *SQLiteStore) GetTelegramRecipient(ctx context.Context, id int, orgID int) (*TelegramRecipient, error) {
+	query := `SELECT id, org_id, chat_id, is_active, created_at, last_attempt_at, last_success_at, last_error_at, failure_count, cooldown_until,
+              min_attempt_interval_seconds, failure_threshold, failure_window_seconds, cooldown_seconds, created_by
               FROM telegram_recipients
-              WHERE id = ? AND user_id = ?`
+              WHERE id = ? AND org_id = ?`
 
 	var r TelegramRecipient
-	err := s.db.QueryRowContext(ctx, query, id, userID).Scan(&r.ID, &r.UserID, &r.ChatID, &r.IsActive, &r.CreatedAt,
-		&r.LastAttemptAt, &r.LastSuccessAt, &r.LastErrorAt, &r.FailureCount, &r.CooldownUntil)
+	err := s.db.QueryRowContext(ctx, query, id, orgID).Scan(&r.ID, &r.OrgID, &r.ChatID, &r.IsActive, &r.CreatedAt,
+		&r.LastAttemptAt, &r.LastSuccessAt, &r.LastErrorAt, &r.FailureCount, &r.CooldownUntil,
+		&r.MinAttemptIntervalSeconds, &r.FailureThreshold, &r.FailureWindowSeconds, &r.CooldownSeconds, &r.CreatedBy)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -1281,13 +2436,13 @@ func (s *SQLiteStore) GetTelegramRecipient(ctx context.Context, id int, userID i
 	return &r, nil
 }
 
-// CreateTelegramRecipient creates a new Telegram recipient
-func (s *SQLiteStore) CreateTelegramRecipient(ctx context.Context, userID int, chatID string) (*TelegramRecipient, error) {
-	query := `INSERT INTO telegram_recipients (user_id, chat_id, is_active, created_at, failure_count)
-              VALUES (?, ?, 1, ?, 0)`
+// CreateTelegramRecipient creates a new Telegram recipient linked by the user
+func (s *SQLiteStore) CreateTelegramRecipient(ctx context.Context, orgID int, chatID string, createdBy int) (*TelegramRecipient, error) {
+	query := `INSERT INTO telegram_recipients (org_id, chat_id, is_active, created_at, failure_count, created_by)
+              VALUES (?, ?, 1, ?, 0, ?)`
 
 	now := time.Now()
-	res, err := s.db.ExecContext(ctx, query, userID, chatID, now)
+	res, err := s.db.ExecContext(ctx, query, orgID, chatID, now, createdBy)
 	if err != nil {
 		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
 			return nil, fmt.Errorf("telegram recipient with chat_id %s already exists", chatID)
@@ -1300,11 +2455,11 @@ func (s *SQLiteStore) CreateTelegramRecipient(ctx context.Context, userID int, c
 		return nil, fmt.Errorf("failed to get last insert id: %w", err)
 	}
 
-	return s.GetTelegramRecipient(ctx, int(id), userID)
+	return s.GetTelegramRecipient(ctx, int(id), orgID)
 }
 
 // UpdateTelegramRecipient updates a Telegram recipient
-func (s *SQLiteStore) UpdateTelegramRecipient(ctx context.Context, id int, userID int, chatID string, isActive *bool) (*TelegramRecipient, error) {
+func (s *SQLiteStore) UpdateTelegramRecipient(ctx context.Context, id int, orgID int, chatID string, isActive *bool) (*TelegramRecipient, error) {
 	var updates []string
 	var args []interface{}
 
@@ -1319,13 +2474,13 @@ func (s *SQLiteStore) UpdateTelegramRecipient(ctx context.Context, id int, userI
 	}
 
 	if len(updates) == 0 {
-		return s.GetTelegramRecipient(ctx, id, userID)
+		return s.GetTelegramRecipient(ctx, id, orgID)
 	}
 
 	// Add WHERE clause parameters
-	args = append(args, id, userID)
+	args = append(args, id, orgID)
 
-	query := fmt.Sprintf("UPDATE telegram_recipients SET %s WHERE id = ? AND user_id = ?",
+	query := fmt.Sprintf("UPDATE telegram_recipients SET %s WHERE id = ? AND org_id = ?",
 		strings.Join(updates, ", "))
 
 	res, err := s.db.ExecContext(ctx, query, args...)
@@ -1341,14 +2496,14 @@ func (s *SQLiteStore) UpdateTelegramRecipient(ctx context.Context, id int, userI
 		return nil, fmt.Errorf("telegram recipient with id %d not found or unauthorized", id)
 	}
 
-	return s.GetTelegramRecipient(ctx, id, userID)
+	return s.GetTelegramRecipient(ctx, id, orgID)
 }
 
 // DeleteTelegramRecipient deletes a Telegram recipient
-func (s *SQLiteStore) DeleteTelegramRecipient(ctx context.Context, id int, userID int) error {
-	query := `DELETE FROM telegram_recipients WHERE id = ? AND user_id = ?`
+func (s *SQLiteStore) DeleteTelegramRecipient(ctx context.Context, id int, orgID int) error {
+	query := `DELETE FROM telegram_recipients WHERE id = ? AND org_id = ?`
 
-	res, err := s.db.ExecContext(ctx, query, id, userID)
+	res, err := s.db.ExecContext(ctx, query, id, orgID)
 	if err != nil {
 		return fmt.Errorf("failed to delete telegram recipient: %w", err)
 	}
@@ -1430,6 +2585,51 @@ func (s *SQLiteStore) UpdateTelegramDeliveryState(ctx context.Context, id int, l
 	return nil
 }
 
+// UpdateTelegramDeliverySettings replaces the recipient's rate limiting and circuit breaker overrides
+func (s *SQLiteStore) UpdateTelegramDeliverySettings(ctx context.Context, id int, orgID int, settings DeliverySettings) (*TelegramRecipient, error) {
+	query := `UPDATE telegram_recipients
+              SET min_attempt_interval_seconds = ?, failure_threshold = ?, failure_window_seconds = ?, cooldown_seconds = ?
+              WHERE id = ? AND org_id = ?`
+
+	res, err := s.db.ExecContext(ctx, query, settings.MinAttemptIntervalSeconds, settings.FailureThreshold,
+		settings.FailureWindowSeconds, settings.CooldownSeconds, id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update telegram delivery settings: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify delivery settings update: %w", err)
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("telegram recipient with id %d not found or unauthorized", id)
+	}
+
+	return s.GetTelegramRecipient(ctx, id, orgID)
+}
+
+// ResetTelegramCircuit clears the recipient's failure count, cooldown and rate limit window
+func (s *SQLiteStore) ResetTelegramCircuit(ctx context.Context, id int, orgID int) (*TelegramRecipient, error) {
+	query := `UPDATE telegram_recipients
+              SET failure_count = 0, cooldown_until = NULL, last_attempt_at = NULL
+              WHERE id = ? AND org_id = ?`
+
+	res, err := s.db.ExecContext(ctx, query, id, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset telegram circuit: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify circuit reset: %w", err)
+	}
+	if rows == 0 {
+		return nil, fmt.Errorf("telegram recipient with id %d not found or unauthorized", id)
+	}
+
+	return s.GetTelegramRecipient(ctx, id, orgID)
+}
+
 // Close closes the database connection
 func (s *SQLiteStore) Close() error {
 	return s.db.Close()
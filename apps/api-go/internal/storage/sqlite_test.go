@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -824,3 +826,92 @@ func TestHashSecret(t *testing.T) {
 		t.Error("Different secrets should produce different hashes")
 	}
 }
+
+func TestSQLiteStore_SetUserRole(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+
+	owner, _ := store.UpsertAdmin(ctx, "owner@example.com", "hash")
+	user, _ := store.CreateUser(ctx, "user@example.com", "hash")
+	if owner.Role != RoleOwner || user.Role != RoleOperator {
+		t.Fatalf("Expected owner and operator, got %s and %s", owner.Role, user.Role)
+	}
+
+	// is_admin follows the role
+	if err := store.SetUserRole(ctx, user.ID, RoleAdmin); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	updated, _ := store.GetUserByID(ctx, user.ID)
+	if updated.Role != RoleAdmin || !updated.IsAdmin {
+		t.Errorf("Expected an admin, got %+v", updated)
+	}
+	if err := store.SetUserRole(ctx, user.ID, RoleViewer); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	updated, _ = store.GetUserByID(ctx, user.ID)
+	if updated.Role != RoleViewer || updated.IsAdmin {
+		t.Errorf("Expected a viewer, got %+v", updated)
+	}
+
+	// The last owner stays an owner
+	if err := store.SetUserRole(ctx, owner.ID, RoleAdmin); err == nil || err.Error() != "cannot remove the last owner" {
+		t.Errorf("Expected 'cannot remove the last owner' error, got: %v", err)
+	}
+	store.SetUserRole(ctx, user.ID, RoleOwner)
+	if err := store.SetUserRole(ctx, owner.ID, RoleAdmin); err != nil {
+		t.Errorf("Expected demoting an owner to work with another owner left: %v", err)
+	}
+	if err := store.DeleteUser(ctx, user.ID); err == nil || err.Error() != "cannot delete the last owner" {
+		t.Errorf("Expected 'cannot delete the last owner' error, got: %v", err)
+	}
+
+	if err := store.SetUserRole(ctx, 999, RoleViewer); err != ErrUserNotFound {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+}
+
+func TestSQLiteStore_UserRolesMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to create test store: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// Go back to before roles, with two admins and a regular user
+	user, _ := store.CreateUser(ctx, "user@example.com", "hash")
+	first, _ := store.CreateUser(ctx, "first@example.com", "hash")
+	second, _ := store.CreateUser(ctx, "second@example.com", "hash")
+	for _, stmt := range []string{
+		"UPDATE users SET is_admin = 1 WHERE id IN (" + strconv.Itoa(first.ID) + ", " + strconv.Itoa(second.ID) + ")",
+		"ALTER TABLE users DROP COLUMN role",
+		"DELETE FROM migrations WHERE version = '031_user_roles'",
+	} {
+		if _, err := store.db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("Failed to run %q: %v", stmt, err)
+		}
+	}
+	store.Close()
+
+	store, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Failed to reopen test store: %v", err)
+	}
+	defer store.Close()
+
+	for id, want := range map[int]string{user.ID: RoleOperator, first.ID: RoleOwner, second.ID: RoleAdmin} {
+		migrated, err := store.GetUserByID(ctx, id)
+		if err != nil {
+			t.Fatalf("Failed to get user %d: %v", id, err)
+		}
+		if migrated.Role != want {
+			t.Errorf("Expected user %d to be %s, got %s", id, want, migrated.Role)
+		}
+	}
+}
@@ -37,8 +37,8 @@ func TestSQLiteStore_CreateWebhook(t *testing.T) {
 	if webhook.ID == 0 {
 		t.Error("Expected webhook ID to be set")
 	}
-	if webhook.UserID != user.ID {
-		t.Errorf("Expected user ID %d, got %d", user.ID, webhook.UserID)
+	if webhook.OrgID != user.ID {
+		t.Errorf("Expected org ID %d, got %d", user.ID, webhook.OrgID)
 	}
 	if webhook.URL != url {
 		t.Errorf("Expected URL %s, got %s", url, webhook.URL)
@@ -84,7 +84,7 @@ func TestSQLiteStore_CreateWebhook_UniqueConstraint(t *testing.T) {
 		t.Fatalf("Failed to create first webhook: %v", err)
 	}
 
-	// Try to create duplicate webhook (same user_id, url)
+	// Try to create duplicate webhook (same org_id, url)
 	_, err = store.CreateWebhook(ctx, user.ID, url, secretHash)
 	if err == nil {
 		t.Error("Expected error when creating duplicate webhook")
@@ -182,8 +182,8 @@ func TestSQLiteStore_GetWebhook(t *testing.T) {
 	if fetchedWebhook.ID != webhook.ID {
 		t.Errorf("Expected webhook ID %d, got %d", webhook.ID, fetchedWebhook.ID)
 	}
-	if fetchedWebhook.UserID != user1.ID {
-		t.Errorf("Expected user ID %d, got %d", user1.ID, fetchedWebhook.UserID)
+	if fetchedWebhook.OrgID != user1.ID {
+		t.Errorf("Expected org ID %d, got %d", user1.ID, fetchedWebhook.OrgID)
 	}
 
 	// Try to fetch the webhook with a different user ID
@@ -533,3 +533,104 @@ func TestSQLiteStore_UpdateWebhookDeliveryState(t *testing.T) {
 		t.Errorf("Expected specific error message, got: %v", err)
 	}
 }
+
+func TestSQLiteStore_WebhookDeliverySettings(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "delivery@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	webhook, err := store.CreateWebhook(ctx, user.ID, "https://example.com/webhook", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	if webhook.FailureThreshold != nil || webhook.CooldownSeconds != nil {
+		t.Error("Expected delivery overrides to be unset by default")
+	}
+
+	threshold, cooldown := 5, 120
+	updated, err := store.UpdateWebhookDeliverySettings(ctx, webhook.ID, user.ID, DeliverySettings{
+		FailureThreshold: &threshold,
+		CooldownSeconds:  &cooldown,
+	})
+	if err != nil {
+		t.Fatalf("Failed to update delivery settings: %v", err)
+	}
+	if updated.FailureThreshold == nil || *updated.FailureThreshold != 5 {
+		t.Errorf("Expected failure threshold 5, got %v", updated.FailureThreshold)
+	}
+	if updated.MinAttemptIntervalSeconds != nil {
+		t.Error("Expected min attempt interval to remain unset")
+	}
+
+	// Open the circuit, then reset it
+	if err := store.IncrementWebhookFailure(ctx, webhook.ID, time.Now()); err != nil {
+		t.Fatalf("Failed to increment failure: %v", err)
+	}
+	cooldownUntil := time.Now().Add(time.Hour)
+	if err := store.UpdateWebhookDeliveryState(ctx, webhook.ID, time.Now(), &cooldownUntil); err != nil {
+		t.Fatalf("Failed to set cooldown: %v", err)
+	}
+
+	reset, err := store.ResetWebhookCircuit(ctx, webhook.ID, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to reset circuit: %v", err)
+	}
+	if reset.FailureCount != 0 || reset.CooldownUntil != nil || reset.LastAttemptAt != nil {
+		t.Errorf("Expected circuit state to be cleared, got %+v", reset)
+	}
+	if reset.FailureThreshold == nil || *reset.FailureThreshold != 5 {
+		t.Error("Expected delivery settings to survive a circuit reset")
+	}
+
+	if _, err := store.ResetWebhookCircuit(ctx, webhook.ID, user.ID+1); err == nil {
+		t.Error("Expected error resetting another user's webhook")
+	}
+}
+
+func TestSQLiteStore_RotateWebhookSecret(t *testing.T) {
+	store := setupTestDB(t)
+	defer store.Close()
+
+	ctx := context.Background()
+	user, err := store.CreateUser(ctx, "rotate@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	webhook, err := store.CreateWebhook(ctx, user.ID, "https://example.com/webhook", "oldhash")
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	rotated, err := store.RotateWebhookSecret(ctx, webhook.ID, user.ID, "newhash", expiresAt)
+	if err != nil {
+		t.Fatalf("Failed to rotate secret: %v", err)
+	}
+	if rotated.SecretHash != "newhash" {
+		t.Errorf("Expected new secret hash, got %s", rotated.SecretHash)
+	}
+	if rotated.PreviousSecretHash == nil || *rotated.PreviousSecretHash != "oldhash" {
+		t.Errorf("Expected previous secret hash 'oldhash', got %v", rotated.PreviousSecretHash)
+	}
+	if rotated.PreviousSecretExpiresAt == nil || !rotated.PreviousSecretExpiresAt.Truncate(time.Second).Equal(expiresAt.Truncate(time.Second)) {
+		t.Errorf("Expected previous secret expiry %v, got %v", expiresAt, rotated.PreviousSecretExpiresAt)
+	}
+
+	// Replacing the secret directly ends the rotation
+	replacement := "replacedhash"
+	updated, err := store.UpdateWebhook(ctx, webhook.ID, user.ID, "", &replacement, nil)
+	if err != nil {
+		t.Fatalf("Failed to update webhook: %v", err)
+	}
+	if updated.PreviousSecretHash != nil || updated.PreviousSecretExpiresAt != nil {
+		t.Error("Expected previous secret to be cleared after direct replacement")
+	}
+
+	if _, err := store.RotateWebhookSecret(ctx, webhook.ID, user.ID+1, "x", expiresAt); err == nil {
+		t.Error("Expected error rotating another user's webhook")
+	}
+}
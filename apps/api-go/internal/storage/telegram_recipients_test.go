@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTelegramRecipients_ListByChatID(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, err := store.CreateUser(ctx, "chat@example.com", "hash")
+	if err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if _, err := store.CreateTelegramRecipient(ctx, user.ID, "555", user.ID); err != nil {
+		t.Fatalf("Failed to create recipient: %v", err)
+	}
+	if _, err := store.CreateTelegramRecipient(ctx, user.ID, "777", user.ID); err != nil {
+		t.Fatalf("Failed to create recipient: %v", err)
+	}
+
+	recipients, err := store.ListTelegramRecipientsByChatID(ctx, "555")
+	if err != nil {
+		t.Fatalf("Failed to list recipients: %v", err)
+	}
+	if len(recipients) != 1 || recipients[0].OrgID != user.ID {
+		t.Fatalf("Expected one recipient for chat 555, got %+v", recipients)
+	}
+	if recipients[0].CreatedBy == nil || *recipients[0].CreatedBy != user.ID {
+		t.Errorf("Expected the recipient to be linked by user %d, got %v", user.ID, recipients[0].CreatedBy)
+	}
+
+	recipients, err = store.ListTelegramRecipientsByChatID(ctx, "999")
+	if err != nil {
+		t.Fatalf("Failed to list recipients: %v", err)
+	}
+	if len(recipients) != 0 {
+		t.Errorf("Expected no recipients for unknown chat, got %d", len(recipients))
+	}
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UserTOTP is a user's authenticator app secret. It is pending until the user confirms it with a
+// code, and only then required at login.
+type UserTOTP struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`                    // base32 shared secret
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"` // nil while enrolment is pending
+	LastUsedStep int64      `json:"-"`                    // time step of the last accepted code, so codes cannot be replayed
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// GetUserTOTP returns the user's authenticator app secret, whether enabled or pending
+func (s *SQLiteStore) GetUserTOTP(ctx context.Context, userID int) (*UserTOTP, error) {
+	query := `SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_totp WHERE user_id = ?`
+
+	totp := &UserTOTP{}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&totp.UserID, &totp.Secret, &totp.EnabledAt,
+		&totp.LastUsedStep, &totp.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTOTPNotFound
+		}
+		return nil, fmt.Errorf("failed to get TOTP secret: %w", err)
+	}
+
+	return totp, nil
+}
+
+// SetUserTOTPSecret stores a pending authenticator app secret for the user, replacing any
+// previous one
+func (s *SQLiteStore) SetUserTOTPSecret(ctx context.Context, userID int, secret string) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET secret = excluded.secret, enabled_at = NULL, last_used_step = 0,
+			created_at = excluded.created_at`
+
+	if _, err := s.db.ExecContext(ctx, query, userID, secret, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to set TOTP secret: %w", err)
+	}
+
+	return nil
+}
+
+// EnableUserTOTP enables the user's pending secret, recording the step of the code that confirmed
+// it, and replaces their recovery codes
+func (s *SQLiteStore) EnableUserTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE user_totp SET enabled_at = ?, last_used_step = ? WHERE user_id = ? AND enabled_at IS NULL`,
+		time.Now().UTC(), step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify TOTP enablement: %w", err)
+	}
+	if rows == 0 {
+		return ErrTOTPNotFound
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit TOTP enablement: %w", err)
+	}
+	return nil
+}
+
+// UseTOTPStep records that a code of the given time step was accepted, returning ErrTOTPStepUsed
+// unless it is newer than the last accepted code
+func (s *SQLiteStore) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`,
+		step, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to record TOTP use: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify TOTP use: %w", err)
+	}
+	if rows == 0 {
+		return ErrTOTPStepUsed
+	}
+
+	return nil
+}
+
+// DeleteUserTOTP removes the user's authenticator app secret and recovery codes
+func (s *SQLiteStore) DeleteUserTOTP(ctx context.Context, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete TOTP secret: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify TOTP deletion: %w", err)
+	}
+	if rows == 0 {
+		return ErrTOTPNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit TOTP deletion: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces all of the user's recovery codes
+func (s *SQLiteStore) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+	return nil
+}
+
+// replaceRecoveryCodes replaces the user's recovery codes within a transaction
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO recovery_codes (user_id, code_hash, created_at) VALUES (?, ?, ?)`,
+			userID, hash, now); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks one of the user's unused recovery codes as used, returning
+// ErrRecoveryCodeNotFound if there is no such code
+func (s *SQLiteStore) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE recovery_codes SET used_at = ?
+		WHERE id = (SELECT id FROM recovery_codes WHERE user_id = ? AND code_hash = ? AND used_at IS NULL LIMIT 1)`,
+		time.Now().UTC(), userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to verify recovery code use: %w", err)
+	}
+	if rows == 0 {
+		return ErrRecoveryCodeNotFound
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes returns how many of the user's recovery codes are unused
+func (s *SQLiteStore) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserTOTP(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	user, _ := store.CreateUser(ctx, "alice@example.com", "hash")
+	if _, err := store.GetUserTOTP(ctx, user.ID); err != ErrTOTPNotFound {
+		t.Fatalf("Expected ErrTOTPNotFound, got %v", err)
+	}
+
+	// A pending secret does not enable two-factor authentication
+	if err := store.SetUserTOTPSecret(ctx, user.ID, "SECRET1"); err != nil {
+		t.Fatalf("Failed to set TOTP secret: %v", err)
+	}
+	if got, _ := store.GetUserByID(ctx, user.ID); got.TOTPEnabled {
+		t.Error("Expected a pending secret to leave two-factor authentication off")
+	}
+
+	if err := store.EnableUserTOTP(ctx, user.ID, 100, []string{"code-a", "code-b"}); err != nil {
+		t.Fatalf("Failed to enable TOTP: %v", err)
+	}
+	totp, _ := store.GetUserTOTP(ctx, user.ID)
+	if totp.EnabledAt == nil || totp.LastUsedStep != 100 || totp.Secret != "SECRET1" {
+		t.Errorf("Unexpected TOTP secret %+v", totp)
+	}
+	if got, _ := store.GetUserByEmail(ctx, user.Email); !got.TOTPEnabled {
+		t.Error("Expected two-factor authentication to be on")
+	}
+	if err := store.EnableUserTOTP(ctx, user.ID, 101, nil); err != ErrTOTPNotFound {
+		t.Errorf("Expected enabling twice to fail with ErrTOTPNotFound, got %v", err)
+	}
+
+	// Steps only move forward
+	if err := store.UseTOTPStep(ctx, user.ID, 100); err != ErrTOTPStepUsed {
+		t.Errorf("Expected ErrTOTPStepUsed, got %v", err)
+	}
+	if err := store.UseTOTPStep(ctx, user.ID, 101); err != nil {
+		t.Errorf("Failed to use a newer step: %v", err)
+	}
+
+	// Recovery codes are single use
+	if err := store.UseRecoveryCode(ctx, user.ID, "code-a"); err != nil {
+		t.Fatalf("Failed to use recovery code: %v", err)
+	}
+	if err := store.UseRecoveryCode(ctx, user.ID, "code-a"); err != ErrRecoveryCodeNotFound {
+		t.Errorf("Expected ErrRecoveryCodeNotFound for a used code, got %v", err)
+	}
+	if n, _ := store.CountRecoveryCodes(ctx, user.ID); n != 1 {
+		t.Errorf("Expected 1 recovery code left, got %d", n)
+	}
+	if err := store.ReplaceRecoveryCodes(ctx, user.ID, []string{"code-c", "code-d", "code-e"}); err != nil {
+		t.Fatalf("Failed to replace recovery codes: %v", err)
+	}
+	if err := store.UseRecoveryCode(ctx, user.ID, "code-b"); err != ErrRecoveryCodeNotFound {
+		t.Errorf("Expected replaced codes to be gone, got %v", err)
+	}
+
+	if err := store.DeleteUserTOTP(ctx, user.ID); err != nil {
+		t.Fatalf("Failed to delete TOTP: %v", err)
+	}
+	if n, _ := store.CountRecoveryCodes(ctx, user.ID); n != 0 {
+		t.Errorf("Expected recovery codes to be deleted, got %d", n)
+	}
+	if err := store.DeleteUserTOTP(ctx, user.ID); err != ErrTOTPNotFound {
+		t.Errorf("Expected ErrTOTPNotFound, got %v", err)
+	}
+}
+
+func TestSettings(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	if _, err := store.GetSetting(ctx, "two_factor_policy"); err != ErrSettingNotFound {
+		t.Fatalf("Expected ErrSettingNotFound, got %v", err)
+	}
+	store.SetSetting(ctx, "two_factor_policy", "admins")
+	store.SetSetting(ctx, "two_factor_policy", "all")
+	if value, err := store.GetSetting(ctx, "two_factor_policy"); err != nil || value != "all" {
+		t.Errorf("Expected the latest value, got %q, %v", value, err)
+	}
+}
@@ -0,0 +1,213 @@
+// Package webhookverify verifies signed LunaSentri webhook deliveries.
+//
+// Every delivery carries three headers:
+//
+//	X-LunaSentri-Timestamp:    Unix time (seconds) the request was signed
+//	X-LunaSentri-Event-ID:     delivery ID, stable across retries of the same delivery
+//	X-LunaSentri-Signature-V2: one or more comma-separated "v2=<hex>" signatures
+//
+// Each signature is HMAC-SHA256 over "v2:{timestamp}:{event_id}:{body}" keyed with
+// SHA-256(secret). While a secret is being rotated the delivery is signed with both the
+// new and the old secret, so receivers keep working whichever one they have configured.
+//
+// Receivers should reject requests whose timestamp is outside a small tolerance and
+// remember recently seen signatures, which is what Verifier does.
+package webhookverify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header names set on every webhook delivery
+const (
+	HeaderSignature   = "X-LunaSentri-Signature" // Legacy v1 body-only signature, kept for existing receivers
+	HeaderSignatureV2 = "X-LunaSentri-Signature-V2"
+	HeaderTimestamp   = "X-LunaSentri-Timestamp"
+	HeaderEventID     = "X-LunaSentri-Event-ID"
+)
+
+// DefaultTolerance is the maximum accepted age (or clock skew) of a delivery
+const DefaultTolerance = 5 * time.Minute
+
+// SignaturePrefix prefixes each v2 signature in the signature header
+const SignaturePrefix = "v2="
+
+var (
+	ErrMissingHeaders   = errors.New("webhookverify: missing signature headers")
+	ErrInvalidTimestamp = errors.New("webhookverify: invalid timestamp")
+	ErrTimestampExpired = errors.New("webhookverify: timestamp outside tolerance")
+	ErrNoValidSignature = errors.New("webhookverify: no valid signature")
+	ErrReplayed         = errors.New("webhookverify: delivery already received")
+)
+
+// SigningKey derives the HMAC key from a webhook secret as entered in LunaSentri
+func SigningKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// Sign computes the hex-encoded v2 signature of a delivery
+func Sign(key []byte, timestamp int64, eventID string, body []byte) string {
+	h := hmac.New(sha256.New, key)
+	fmt.Fprintf(h, "v2:%d:%s:", timestamp, eventID)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// FormatSignatures builds the v2 signature header value from one or more signatures
+func FormatSignatures(signatures ...string) string {
+	parts := make([]string, len(signatures))
+	for i, sig := range signatures {
+		parts[i] = SignaturePrefix + sig
+	}
+	return strings.Join(parts, ",")
+}
+
+// ReplayCache remembers deliveries that have already been accepted.
+// Implementations must be safe for concurrent use.
+type ReplayCache interface {
+	// Seen records key until expiresAt and reports whether it was already recorded
+	Seen(key string, expiresAt time.Time) bool
+}
+
+// MemoryReplayCache is an in-process ReplayCache. Receivers running several
+// instances should back ReplayCache with shared storage instead.
+type MemoryReplayCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+// NewMemoryReplayCache creates an empty in-memory replay cache
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{entries: make(map[string]time.Time), now: time.Now}
+}
+
+// Seen implements ReplayCache
+func (c *MemoryReplayCache) Seen(key string, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, exp := range c.entries {
+		if now.After(exp) {
+			delete(c.entries, k)
+		}
+	}
+
+	if _, ok := c.entries[key]; ok {
+		return true
+	}
+	c.entries[key] = expiresAt
+	return false
+}
+
+// Verifier checks signatures, timestamps and replays of incoming deliveries
+type Verifier struct {
+	// Secrets accepted for verification. Configure both the old and the new secret
+	// while rotating on the receiver side.
+	Secrets []string
+	// Tolerance is the maximum accepted age of a delivery; zero means DefaultTolerance
+	Tolerance time.Duration
+	// ReplayCache rejects a delivery seen before; nil disables replay detection
+	ReplayCache ReplayCache
+	// Now overrides the clock, for tests
+	Now func() time.Time
+}
+
+// New creates a Verifier with an in-memory replay cache
+func New(secrets ...string) *Verifier {
+	return &Verifier{Secrets: secrets, ReplayCache: NewMemoryReplayCache()}
+}
+
+// Verify checks the headers and body of a delivery and returns its event ID
+func (v *Verifier) Verify(header http.Header, body []byte) (string, error) {
+	timestampStr := header.Get(HeaderTimestamp)
+	eventID := header.Get(HeaderEventID)
+	signatureHeader := header.Get(HeaderSignatureV2)
+	if timestampStr == "" || eventID == "" || signatureHeader == "" {
+		return "", ErrMissingHeaders
+	}
+
+	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
+	if err != nil {
+		return "", ErrInvalidTimestamp
+	}
+
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+	signedAt := time.Unix(timestamp, 0)
+	if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(tolerance)) {
+		return "", ErrTimestampExpired
+	}
+
+	if !v.matches(signatureHeader, timestamp, eventID, body) {
+		return "", ErrNoValidSignature
+	}
+
+	// Retries of a delivery reuse the event ID but are signed with a fresh timestamp,
+	// so only an identical (event ID, timestamp) pair counts as a replay.
+	if v.ReplayCache != nil && v.ReplayCache.Seen(eventID+":"+timestampStr, signedAt.Add(tolerance)) {
+		return "", ErrReplayed
+	}
+
+	return eventID, nil
+}
+
+// VerifyRequest reads and verifies the request body, leaving it readable for the caller
+func (v *Verifier) VerifyRequest(r *http.Request) (string, []byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("webhookverify: failed to read body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	eventID, err := v.Verify(r.Header, body)
+	if err != nil {
+		return "", nil, err
+	}
+	return eventID, body, nil
+}
+
+// matches reports whether any signature in the header was made with any configured secret
+func (v *Verifier) matches(signatureHeader string, timestamp int64, eventID string, body []byte) bool {
+	var candidates [][]byte
+	for _, part := range strings.Split(signatureHeader, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, SignaturePrefix) {
+			continue
+		}
+		decoded, err := hex.DecodeString(strings.TrimPrefix(part, SignaturePrefix))
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, decoded)
+	}
+
+	for _, secret := range v.Secrets {
+		expected, _ := hex.DecodeString(Sign(SigningKey(secret), timestamp, eventID, body))
+		for _, candidate := range candidates {
+			if hmac.Equal(candidate, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
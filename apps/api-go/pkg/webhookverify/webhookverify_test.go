@@ -0,0 +1,128 @@
+package webhookverify
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedHeader(timestamp int64, eventID string, body []byte, secrets ...string) http.Header {
+	signatures := make([]string, len(secrets))
+	for i, secret := range secrets {
+		signatures[i] = Sign(SigningKey(secret), timestamp, eventID, body)
+	}
+	header := make(http.Header)
+	header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderEventID, eventID)
+	header.Set(HeaderSignatureV2, FormatSignatures(signatures...))
+	return header
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"event":"alert.fired"}`)
+
+	tests := []struct {
+		name    string
+		secrets []string
+		header  http.Header
+		body    []byte
+		wantErr error
+	}{
+		{
+			name:    "valid signature",
+			secrets: []string{"new-secret-123456"},
+			header:  signedHeader(now.Unix(), "evt_1", body, "new-secret-123456"),
+			body:    body,
+		},
+		{
+			name:    "receiver still on old secret during rotation",
+			secrets: []string{"old-secret-123456"},
+			header:  signedHeader(now.Unix(), "evt_1", body, "new-secret-123456", "old-secret-123456"),
+			body:    body,
+		},
+		{
+			name:    "wrong secret",
+			secrets: []string{"other-secret-1234"},
+			header:  signedHeader(now.Unix(), "evt_1", body, "new-secret-123456"),
+			body:    body,
+			wantErr: ErrNoValidSignature,
+		},
+		{
+			name:    "tampered body",
+			secrets: []string{"new-secret-123456"},
+			header:  signedHeader(now.Unix(), "evt_1", body, "new-secret-123456"),
+			body:    []byte(`{"event":"alert.resolved"}`),
+			wantErr: ErrNoValidSignature,
+		},
+		{
+			name:    "stale timestamp",
+			secrets: []string{"new-secret-123456"},
+			header:  signedHeader(now.Add(-10*time.Minute).Unix(), "evt_1", body, "new-secret-123456"),
+			body:    body,
+			wantErr: ErrTimestampExpired,
+		},
+		{
+			name:    "missing headers",
+			secrets: []string{"new-secret-123456"},
+			header:  http.Header{},
+			body:    body,
+			wantErr: ErrMissingHeaders,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New(tt.secrets...)
+			v.Now = func() time.Time { return now }
+
+			eventID, err := v.Verify(tt.header, tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && eventID != "evt_1" {
+				t.Errorf("Expected event ID evt_1, got %q", eventID)
+			}
+		})
+	}
+}
+
+func TestVerifier_RejectsReplay(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	body := []byte(`{"event":"alert.fired"}`)
+	cache := NewMemoryReplayCache()
+	cache.now = func() time.Time { return now }
+	v := &Verifier{Secrets: []string{"new-secret-123456"}, ReplayCache: cache, Now: func() time.Time { return now }}
+
+	header := signedHeader(now.Unix(), "evt_1", body, "new-secret-123456")
+	if _, err := v.Verify(header, body); err != nil {
+		t.Fatalf("Expected first delivery to verify, got %v", err)
+	}
+	if _, err := v.Verify(header, body); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("Expected replay to be rejected, got %v", err)
+	}
+
+	// A retry of the same delivery is re-signed with a new timestamp and is accepted
+	retry := signedHeader(now.Unix()+2, "evt_1", body, "new-secret-123456")
+	if _, err := v.Verify(retry, body); err != nil {
+		t.Errorf("Expected retry to verify, got %v", err)
+	}
+}
+
+func TestVerifier_VerifyRequest(t *testing.T) {
+	body := []byte(`{"event":"machine.offline"}`)
+	req := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(body))
+	req.Header = signedHeader(time.Now().Unix(), "evt_2", body, "new-secret-123456")
+
+	eventID, got, err := New("new-secret-123456").VerifyRequest(req)
+	if err != nil {
+		t.Fatalf("Expected request to verify, got %v", err)
+	}
+	if eventID != "evt_2" || !bytes.Equal(got, body) {
+		t.Errorf("Unexpected result: event=%q body=%s", eventID, got)
+	}
+}
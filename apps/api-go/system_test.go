@@ -137,7 +137,7 @@ func TestSystemInfoHandler(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to seed user: %v", err)
 	}
-	token, err := server.authService.CreateSession(user.ID)
+	session, err := server.authService.CreateSession(context.Background(), user.ID, auth.ClientInfo{})
 	if err != nil {
 		t.Fatalf("Failed to create session: %v", err)
 	}
@@ -146,7 +146,7 @@ func TestSystemInfoHandler(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
-	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token, Path: "/"})
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: session.AccessToken, Path: "/"})
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -197,7 +197,7 @@ func TestSystemInfoHandlerError(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to seed user: %v", err)
 	}
-	token, err := server.authService.CreateSession(user.ID)
+	session, err := server.authService.CreateSession(context.Background(), user.ID, auth.ClientInfo{})
 	if err != nil {
 		t.Fatalf("Failed to create session: %v", err)
 	}
@@ -206,7 +206,7 @@ func TestSystemInfoHandlerError(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create request: %v", err)
 	}
-	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token, Path: "/"})
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: session.AccessToken, Path: "/"})
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -235,7 +235,7 @@ func TestSystemInfoHandlerMethodNotAllowed(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to seed user: %v", err)
 	}
-	token, err := server.authService.CreateSession(user.ID)
+	session, err := server.authService.CreateSession(context.Background(), user.ID, auth.ClientInfo{})
 	if err != nil {
 		t.Fatalf("Failed to create session: %v", err)
 	}
@@ -245,7 +245,7 @@ func TestSystemInfoHandlerMethodNotAllowed(t *testing.T) {
 		t.Fatalf("Failed to create request: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: token, Path: "/"})
+	req.AddCookie(&http.Cookie{Name: auth.CookieName, Value: session.AccessToken, Path: "/"})
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
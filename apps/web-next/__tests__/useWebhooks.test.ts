@@ -132,11 +132,16 @@ describe('useWebhooks', () => {
     it('should dispatch session-expired event on 401', async () => {
         const dispatchEventSpy = jest.spyOn(window, 'dispatchEvent');
 
-        (global.fetch as jest.Mock).mockResolvedValueOnce({
+        const unauthorized = {
             ok: false,
             status: 401,
             statusText: 'Unauthorized',
-        });
+        };
+        // Request, failed session refresh, then the retried request
+        (global.fetch as jest.Mock)
+            .mockResolvedValueOnce(unauthorized)
+            .mockResolvedValueOnce(unauthorized)
+            .mockResolvedValueOnce(unauthorized);
 
         renderHook(() => useWebhooks());
 
@@ -146,6 +151,11 @@ describe('useWebhooks', () => {
             );
         });
 
+        expect(global.fetch).toHaveBeenCalledWith(
+            expect.stringContaining('/auth/refresh'),
+            expect.objectContaining({ method: 'POST' })
+        );
+
         dispatchEventSpy.mockRestore();
     });
 
@@ -0,0 +1,100 @@
+"use client";
+
+import { Suspense, useEffect, useState } from "react";
+import Link from "next/link";
+import { useRouter, useSearchParams } from "next/navigation";
+import { useSession } from "@/lib/useSession";
+import { acceptOrgInvitation, setActiveOrgId } from "@/lib/api";
+
+function AcceptInvitation() {
+  const router = useRouter();
+  const searchParams = useSearchParams();
+  const token = searchParams.get("token");
+  const { status } = useSession();
+  const [error, setError] = useState<string | null>(null);
+  const [accepting, setAccepting] = useState(false);
+
+  // Invitations are accepted by a signed-in user with the invited email
+  useEffect(() => {
+    if (status === "unauthenticated") {
+      router.push("/login");
+    }
+  }, [status, router]);
+
+  const handleAccept = async () => {
+    if (!token) return;
+    try {
+      setAccepting(true);
+      setError(null);
+      const member = await acceptOrgInvitation(token);
+      setActiveOrgId(member.org_id);
+      router.push("/organizations");
+    } catch (err) {
+      setError(
+        err instanceof Error ? err.message : "Failed to accept invitation"
+      );
+    } finally {
+      setAccepting(false);
+    }
+  };
+
+  if (status !== "authenticated") {
+    return (
+      <div className="text-muted-foreground animate-pulse">Loading...</div>
+    );
+  }
+
+  return (
+    <div className="w-full max-w-md bg-card/70 backdrop-blur-xl border border-border/30 rounded-lg p-8">
+      <h1 className="text-2xl font-bold text-primary mb-2">
+        Join Organization
+      </h1>
+      {!token ? (
+        <p className="text-destructive">
+          This invitation link is missing its token.
+        </p>
+      ) : (
+        <>
+          <p className="text-muted-foreground mb-6">
+            You were invited to share an organization&apos;s machines and
+            notification channels.
+          </p>
+
+          {error && (
+            <div className="mb-4 p-3 bg-destructive/10 border border-destructive/30 rounded-lg text-destructive text-sm">
+              {error}
+            </div>
+          )}
+
+          <button
+            onClick={handleAccept}
+            disabled={accepting}
+            className="w-full px-6 py-2 bg-primary hover:bg-primary/90 disabled:bg-muted disabled:cursor-not-allowed text-primary-foreground rounded-lg transition-all"
+          >
+            {accepting ? "Joining..." : "Accept Invitation"}
+          </button>
+        </>
+      )}
+      <Link
+        href="/"
+        className="block mt-4 text-center text-sm text-muted-foreground hover:text-foreground"
+      >
+        Back to dashboard
+      </Link>
+    </div>
+  );
+}
+
+export default function AcceptInvitationPage() {
+  return (
+    <div className="min-h-screen flex items-center justify-center px-4">
+      <Suspense
+        fallback={
+          <div className="text-muted-foreground animate-pulse">Loading...</div>
+        }
+      >
+        <AcceptInvitation />
+      </Suspense>
+    </div>
+  );
+}
@@ -4,21 +4,57 @@ import { useState, FormEvent, useEffect } from "react";
 import { useRouter } from "next/navigation";
 import Link from "next/link";
 import { useSession } from "@/lib/useSession";
+import { getSSOConfig, ssoLoginURL, type SSOConfig } from "@/lib/api";
+
+// Messages for the sso_error parameter single sign-on failures come back with
+const SSO_ERRORS: Record<string, string> = {
+  denied: "Single sign-on was cancelled.",
+  invalid: "Single sign-on expired or was started in another browser. Try again.",
+  not_provisioned: "Your account has not been set up yet. Ask an administrator for access.",
+  failed: "Single sign-on failed. Try again or contact an administrator.",
+};
 
 export default function LoginPage() {
   const router = useRouter();
-  const { status, login } = useSession();
+  const { status, login, completeTwoFactorLogin } = useSession();
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
+  const [challenge, setChallenge] = useState<string | null>(null);
+  const [code, setCode] = useState("");
   const [error, setError] = useState("");
   const [isSubmitting, setIsSubmitting] = useState(false);
+  const [sso, setSSO] = useState<SSOConfig | null>(null);
+  const [returnTo, setReturnTo] = useState("/");
 
   // Redirect if already authenticated
   useEffect(() => {
     if (status === "authenticated") {
-      router.push("/");
+      router.push(returnTo);
+    }
+  }, [status, router, returnTo]);
+
+  // Offer single sign-on, and pick up where a single sign-on redirect left off
+  useEffect(() => {
+    getSSOConfig()
+      .then(setSSO)
+      .catch(() => setSSO(null));
+
+    const params = new URLSearchParams(window.location.search);
+    const ssoError = params.get("sso_error");
+    if (ssoError) {
+      setError(SSO_ERRORS[ssoError] ?? SSO_ERRORS.failed);
+    }
+    const ssoChallenge = params.get("challenge");
+    if (ssoChallenge) {
+      setChallenge(ssoChallenge);
+    }
+    const target = params.get("return_to");
+    if (target?.startsWith("/") && !target.startsWith("//")) {
+      setReturnTo(target);
     }
-  }, [status, router]);
+  }, []);
+
+  const passwordLogin = sso?.password_login ?? true;
 
   async function handleSubmit(e: FormEvent) {
     e.preventDefault();
@@ -26,9 +62,18 @@ export default function LoginPage() {
     setIsSubmitting(true);
 
     try {
-      await login(email, password);
+      if (challenge) {
+        await completeTwoFactorLogin(challenge, code);
+      } else {
+        const pending = await login(email, password);
+        if (pending) {
+          // Ask for the authenticator app code
+          setChallenge(pending.challenge);
+          return;
+        }
+      }
       // Redirect happens via useEffect when status changes to 'authenticated'
-      router.push("/");
+      router.push(returnTo);
     } catch (err) {
       setError(err instanceof Error ? err.message : "Login failed");
     } finally {
@@ -65,46 +110,79 @@ export default function LoginPage() {
         <div className="bg-card/70 backdrop-blur-xl rounded-xl p-8 shadow-2xl border border-border/30">
           <form onSubmit={handleSubmit} className="space-y-6">
             {/* Email Field */}
-            <div>
-              <label
-                htmlFor="email"
-                className="block text-sm font-medium text-card-foreground mb-2"
-              >
-                Email
-              </label>
-              <input
-                id="email"
-                type="email"
-                value={email}
-                onChange={(e) => setEmail(e.target.value)}
-                required
-                disabled={isSubmitting}
-                className="w-full px-4 py-3 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent disabled:opacity-50 disabled:cursor-not-allowed transition-all"
-                placeholder="admin@example.com"
-                autoComplete="email"
-              />
-            </div>
+            {passwordLogin && !challenge && (
+              <div>
+                <label
+                  htmlFor="email"
+                  className="block text-sm font-medium text-card-foreground mb-2"
+                >
+                  Email
+                </label>
+                <input
+                  id="email"
+                  type="email"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                  disabled={isSubmitting || challenge !== null}
+                  className="w-full px-4 py-3 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent disabled:opacity-50 disabled:cursor-not-allowed transition-all"
+                  placeholder="admin@example.com"
+                  autoComplete="email"
+                />
+              </div>
+            )}
 
             {/* Password Field */}
-            <div>
-              <label
-                htmlFor="password"
-                className="block text-sm font-medium text-card-foreground mb-2"
-              >
-                Password
-              </label>
-              <input
-                id="password"
-                type="password"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                required
-                disabled={isSubmitting}
-                className="w-full px-4 py-3 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent disabled:opacity-50 disabled:cursor-not-allowed transition-all"
-                placeholder="••••••••"
-                autoComplete="current-password"
-              />
-            </div>
+            {passwordLogin && !challenge && (
+              <div>
+                <label
+                  htmlFor="password"
+                  className="block text-sm font-medium text-card-foreground mb-2"
+                >
+                  Password
+                </label>
+                <input
+                  id="password"
+                  type="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                  disabled={isSubmitting || challenge !== null}
+                  className="w-full px-4 py-3 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent disabled:opacity-50 disabled:cursor-not-allowed transition-all"
+                  placeholder="••••••••"
+                  autoComplete="current-password"
+                />
+              </div>
+            )}
+
+            {/* Two-Factor Code Field */}
+            {challenge && (
+              <div>
+                <label
+                  htmlFor="code"
+                  className="block text-sm font-medium text-card-foreground mb-2"
+                >
+                  Authentication code
+                </label>
+                <input
+                  id="code"
+                  type="text"
+                  inputMode="numeric"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
+                  required
+                  autoFocus
+                  disabled={isSubmitting}
+                  className="w-full px-4 py-3 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent disabled:opacity-50 disabled:cursor-not-allowed transition-all"
+                  placeholder="123456"
+                  autoComplete="one-time-code"
+                />
+                <p className="mt-2 text-xs text-muted-foreground">
+                  Enter the code from your authenticator app, or one of your
+                  recovery codes.
+                </p>
+              </div>
+            )}
 
             {/* Error Message */}
             {error && (
@@ -114,15 +192,27 @@ export default function LoginPage() {
             )}
 
             {/* Submit Button */}
-            <button
-              type="submit"
-              disabled={isSubmitting}
-              className="w-full bg-primary hover:bg-primary/90 disabled:bg-muted disabled:cursor-not-allowed text-primary-foreground font-medium py-3 px-4 rounded-lg transition-all focus:outline-none focus:ring-2 focus:ring-primary focus:ring-offset-2 focus:ring-offset-background"
-            >
-              {isSubmitting ? "Signing in..." : "Sign in"}
-            </button>
+            {(passwordLogin || challenge) && (
+              <button
+                type="submit"
+                disabled={isSubmitting}
+                className="w-full bg-primary hover:bg-primary/90 disabled:bg-muted disabled:cursor-not-allowed text-primary-foreground font-medium py-3 px-4 rounded-lg transition-all focus:outline-none focus:ring-2 focus:ring-primary focus:ring-offset-2 focus:ring-offset-background"
+              >
+                {isSubmitting ? "Signing in..." : challenge ? "Verify" : "Sign in"}
+              </button>
+            )}
           </form>
 
+          {/* Single Sign-On */}
+          {sso?.enabled && !challenge && (
+            <a
+              href={ssoLoginURL(returnTo)}
+              className="mt-4 block w-full text-center border border-border hover:bg-accent text-foreground font-medium py-3 px-4 rounded-lg transition-all focus:outline-none focus:ring-2 focus:ring-primary focus:ring-offset-2 focus:ring-offset-background"
+            >
+              Sign in with {sso.provider_name ?? "SSO"}
+            </a>
+          )}
+
           {/* Helper Text */}
           <div className="mt-6 pt-6 border-t border-border/30">
             <p className="text-xs text-muted-foreground text-center mb-4">
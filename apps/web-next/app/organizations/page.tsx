@@ -0,0 +1,462 @@
+"use client";
+
+import { useCallback, useEffect, useState } from "react";
+import { useRouter } from "next/navigation";
+import { useSession } from "@/lib/useSession";
+import { useAlertsWithNotifications } from "@/lib/alerts";
+import { Navbar } from "@/components/Navbar";
+import {
+  listOrganizations,
+  createOrganization,
+  deleteOrganization,
+  listOrgMembers,
+  setOrgMemberRole,
+  removeOrgMember,
+  listOrgInvitations,
+  createOrgInvitation,
+  revokeOrgInvitation,
+  getActiveOrgId,
+  setActiveOrgId,
+  type Organization,
+  type OrgMember,
+  type OrgInvitation,
+  type UserRole,
+} from "@/lib/api";
+
+const ROLES: { value: UserRole; label: string }[] = [
+  { value: "owner", label: "Owner" },
+  { value: "admin", label: "Admin" },
+  { value: "operator", label: "Operator" },
+  { value: "viewer", label: "Viewer (read-only)" },
+];
+
+// Admins can only manage operators and viewers; owners can manage everyone
+function canManageRole(actor: UserRole | undefined, role: UserRole): boolean {
+  if (actor === "owner") return true;
+  return actor === "admin" && (role === "operator" || role === "viewer");
+}
+
+export default function OrganizationsPage() {
+  const router = useRouter();
+  const { status, user: currentUser, logout } = useSession();
+  const { events, newAlertsCount } = useAlertsWithNotifications(10);
+  const [orgs, setOrgs] = useState<Organization[]>([]);
+  const [activeOrgId, setActiveOrg] = useState<number | null>(null);
+  const [members, setMembers] = useState<OrgMember[]>([]);
+  const [invitations, setInvitations] = useState<OrgInvitation[]>([]);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState<string | null>(null);
+
+  // Form state
+  const [newOrgName, setNewOrgName] = useState("");
+  const [inviteEmail, setInviteEmail] = useState("");
+  const [inviteRole, setInviteRole] = useState<UserRole>("operator");
+  const [inviteLink, setInviteLink] = useState<string | null>(null);
+
+  // Count unacknowledged events
+  const unacknowledgedCount =
+    events?.filter((e) => !e.acknowledged).length || 0;
+
+  // Requests without a selected organization act in the personal one, which is listed first
+  const activeOrg =
+    orgs.find((org) => org.id === activeOrgId) ?? orgs[0] ?? null;
+  const canManage = canManageRole(activeOrg?.role, "operator");
+
+  // Redirect to login if unauthenticated
+  useEffect(() => {
+    if (status === "unauthenticated") {
+      router.push("/login");
+    }
+  }, [status, router]);
+
+  const fetchOrganization = useCallback(async () => {
+    try {
+      setLoading(true);
+      setError(null);
+      const data = await listOrganizations();
+      setOrgs(data);
+
+      const selected = getActiveOrgId();
+      const org = data.find((o) => o.id === selected) ?? data[0];
+      setActiveOrg(org?.id ?? null);
+      if (!org) return;
+
+      setMembers(await listOrgMembers(org.id));
+      setInvitations(
+        canManageRole(org.role, "operator")
+          ? await listOrgInvitations(org.id)
+          : []
+      );
+    } catch (err) {
+      setError(
+        err instanceof Error ? err.message : "Failed to load organizations"
+      );
+    } finally {
+      setLoading(false);
+    }
+  }, []);
+
+  useEffect(() => {
+    if (status !== "authenticated") return;
+    fetchOrganization();
+    window.addEventListener("organization-changed", fetchOrganization);
+    return () =>
+      window.removeEventListener("organization-changed", fetchOrganization);
+  }, [status, fetchOrganization]);
+
+  const run = async (action: () => Promise<unknown>) => {
+    try {
+      setError(null);
+      await action();
+      await fetchOrganization();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Request failed");
+    }
+  };
+
+  const handleSwitch = (org: Organization) => {
+    setInviteLink(null);
+    setActiveOrgId(org.personal ? null : org.id);
+  };
+
+  const handleCreate = (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!newOrgName.trim()) return;
+    run(async () => {
+      const org = await createOrganization(newOrgName);
+      setNewOrgName("");
+      setActiveOrgId(org.id);
+    });
+  };
+
+  const handleInvite = (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!activeOrg || !inviteEmail) return;
+    run(async () => {
+      const invitation = await createOrgInvitation(
+        activeOrg.id,
+        inviteEmail,
+        inviteRole
+      );
+      setInviteEmail("");
+      setInviteLink(
+        invitation.link ??
+          `${window.location.origin}/invitations/accept?token=${encodeURIComponent(invitation.token)}`
+      );
+    });
+  };
+
+  const handleLeave = (org: Organization) => {
+    if (!currentUser) return;
+    run(async () => {
+      await removeOrgMember(org.id, currentUser.id);
+      if (org.id === getActiveOrgId()) setActiveOrgId(null);
+    });
+  };
+
+  const handleDelete = (org: Organization) => {
+    if (!confirm(`Delete ${org.name} with all of its machines and notification channels?`)) return;
+    run(async () => {
+      await deleteOrganization(org.id);
+      if (org.id === getActiveOrgId()) setActiveOrgId(null);
+    });
+  };
+
+  // Show loading state while checking authentication
+  if (status === "loading") {
+    return (
+      <div className="min-h-screen flex items-center justify-center">
+        <div className="text-muted-foreground animate-pulse">Loading...</div>
+      </div>
+    );
+  }
+
+  // Don't render if not authenticated (will redirect)
+  if (status !== "authenticated") {
+    return null;
+  }
+
+  return (
+    <div className="min-h-screen">
+      <Navbar
+        user={currentUser}
+        unacknowledgedCount={unacknowledgedCount}
+        newAlertsCount={newAlertsCount}
+        onLogout={logout}
+      />
+
+      {/* Main Content */}
+      <div className="max-w-7xl mx-auto px-4 py-8">
+        <div className="mb-8">
+          <h1 className="text-4xl font-bold text-primary mb-2">
+            Organizations
+          </h1>
+          <p className="text-muted-foreground">
+            Share machines and notification channels with your team. Pages
+            show the active organization.
+          </p>
+        </div>
+
+        {error && (
+          <div className="mb-6 p-3 bg-destructive/10 border border-destructive/30 rounded-lg text-destructive text-sm">
+            {error}
+          </div>
+        )}
+
+        {/* Organizations */}
+        <div className="bg-card/70 backdrop-blur-xl border border-border/30 rounded-lg p-6 mb-8">
+          <h2 className="text-2xl font-semibold text-card-foreground mb-4">
+            Your Organizations
+          </h2>
+
+          {loading && orgs.length === 0 ? (
+            <div className="text-muted-foreground">Loading organizations...</div>
+          ) : (
+            <ul className="divide-y divide-border/30 mb-6">
+              {orgs.map((org) => (
+                <li
+                  key={org.id}
+                  className="flex items-center justify-between py-3"
+                >
+                  <div>
+                    <span className="text-card-foreground font-medium">
+                      {org.name}
+                    </span>
+                    <span className="ml-2 text-sm text-muted-foreground">
+                      {org.personal ? "Personal · " : ""}
+                      {ROLES.find((r) => r.value === org.role)?.label ??
+                        org.role}
+                    </span>
+                  </div>
+                  <div className="flex items-center gap-4">
+                    {org.id === activeOrg?.id ? (
+                      <span className="text-sm text-primary">Active</span>
+                    ) : (
+                      <button
+                        onClick={() => handleSwitch(org)}
+                        className="text-sm text-primary hover:text-primary/80 transition-colors"
+                      >
+                        Switch
+                      </button>
+                    )}
+                    {!org.personal && (
+                      <button
+                        onClick={() => handleLeave(org)}
+                        className="text-sm text-muted-foreground hover:text-foreground transition-colors"
+                      >
+                        Leave
+                      </button>
+                    )}
+                    {!org.personal && org.role === "owner" && (
+                      <button
+                        onClick={() => handleDelete(org)}
+                        className="text-sm text-destructive hover:text-destructive/80 transition-colors"
+                      >
+                        Delete
+                      </button>
+                    )}
+                  </div>
+                </li>
+              ))}
+            </ul>
+          )}
+
+          <form onSubmit={handleCreate} className="flex gap-3">
+            <input
+              type="text"
+              value={newOrgName}
+              onChange={(e) => setNewOrgName(e.target.value)}
+              className="flex-1 px-4 py-2 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary"
+              placeholder="New organization name"
+            />
+            <button
+              type="submit"
+              className="px-6 py-2 bg-primary hover:bg-primary/90 text-primary-foreground rounded-lg transition-all"
+            >
+              Create
+            </button>
+          </form>
+        </div>
+
+        {activeOrg && (
+          <>
+            {/* Invite Member */}
+            {canManage && (
+              <div className="bg-card/70 backdrop-blur-xl border border-border/30 rounded-lg p-6 mb-8">
+                <h2 className="text-2xl font-semibold text-card-foreground mb-4">
+                  Invite to {activeOrg.name}
+                </h2>
+
+                {inviteLink && (
+                  <div className="mb-4 p-4 bg-chart-1/10 border border-chart-1/30 rounded-lg">
+                    <p className="text-chart-1 font-semibold mb-2">
+                      Invitation Link
+                    </p>
+                    <p className="text-sm text-chart-1/80 mb-2">
+                      It is emailed when a mail server is configured. The
+                      invitee signs in with the invited email and opens the
+                      link to join.
+                    </p>
+                    <code className="block bg-background/80 px-3 py-2 rounded text-chart-1 text-sm break-all">
+                      {inviteLink}
+                    </code>
+                  </div>
+                )}
+
+                <form onSubmit={handleInvite} className="flex flex-col sm:flex-row gap-3">
+                  <input
+                    type="email"
+                    value={inviteEmail}
+                    onChange={(e) => setInviteEmail(e.target.value)}
+                    className="flex-1 px-4 py-2 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary"
+                    placeholder="teammate@example.com"
+                  />
+                  <select
+                    value={inviteRole}
+                    onChange={(e) => setInviteRole(e.target.value as UserRole)}
+                    className="px-4 py-2 bg-background/50 border border-input rounded-lg text-foreground focus:outline-none focus:ring-2 focus:ring-primary"
+                  >
+                    {ROLES.filter((r) =>
+                      canManageRole(activeOrg.role, r.value)
+                    ).map((r) => (
+                      <option key={r.value} value={r.value}>
+                        {r.label}
+                      </option>
+                    ))}
+                  </select>
+                  <button
+                    type="submit"
+                    className="px-6 py-2 bg-primary hover:bg-primary/90 text-primary-foreground rounded-lg transition-all"
+                  >
+                    Invite
+                  </button>
+                </form>
+
+                {invitations.length > 0 && (
+                  <ul className="mt-6 divide-y divide-border/30">
+                    {invitations.map((invitation) => (
+                      <li
+                        key={invitation.id}
+                        className="flex items-center justify-between py-3"
+                      >
+                        <span className="text-card-foreground">
+                          {invitation.email}
+                          <span className="ml-2 text-sm text-muted-foreground">
+                            {invitation.role} · expires{" "}
+                            {new Date(invitation.expires_at).toLocaleDateString()}
+                          </span>
+                        </span>
+                        <button
+                          onClick={() =>
+                            run(() =>
+                              revokeOrgInvitation(activeOrg.id, invitation.id)
+                            )
+                          }
+                          className="text-sm text-destructive hover:text-destructive/80 transition-colors"
+                        >
+                          Revoke
+                        </button>
+                      </li>
+                    ))}
+                  </ul>
+                )}
+              </div>
+            )}
+
+            {/* Members Table */}
+            <div className="bg-card/70 backdrop-blur-xl border border-border/30 rounded-lg overflow-hidden">
+              <div className="px-6 py-4 border-b border-border/30">
+                <h2 className="text-2xl font-semibold text-card-foreground">
+                  Members of {activeOrg.name}
+                </h2>
+              </div>
+
+              <div className="overflow-x-auto">
+                <table className="w-full">
+                  <thead>
+                    <tr className="border-b border-border/30 text-left">
+                      <th className="px-6 py-3 text-sm font-semibold text-card-foreground">
+                        Email
+                      </th>
+                      <th className="px-6 py-3 text-sm font-semibold text-card-foreground">
+                        Role
+                      </th>
+                      <th className="px-6 py-3 text-sm font-semibold text-card-foreground">
+                        Actions
+                      </th>
+                    </tr>
+                  </thead>
+                  <tbody>
+                    {members.map((member) => {
+                      const manageable =
+                        member.user_id !== currentUser?.id &&
+                        canManageRole(activeOrg.role, member.role);
+                      return (
+                        <tr
+                          key={member.user_id}
+                          className="border-b border-border/30 hover:bg-background/20"
+                        >
+                          <td className="px-6 py-4 text-card-foreground">
+                            {member.email}
+                          </td>
+                          <td className="px-6 py-4">
+                            {manageable ? (
+                              <select
+                                value={member.role}
+                                onChange={(e) =>
+                                  run(() =>
+                                    setOrgMemberRole(
+                                      activeOrg.id,
+                                      member.user_id,
+                                      e.target.value as UserRole
+                                    )
+                                  )
+                                }
+                                className="px-2 py-1 bg-background/50 border border-input rounded text-sm text-foreground focus:outline-none focus:ring-2 focus:ring-primary"
+                              >
+                                {ROLES.filter((r) =>
+                                  canManageRole(activeOrg.role, r.value)
+                                ).map((r) => (
+                                  <option key={r.value} value={r.value}>
+                                    {r.label}
+                                  </option>
+                                ))}
+                              </select>
+                            ) : (
+                              <span className="text-sm text-muted-foreground">
+                                {ROLES.find((r) => r.value === member.role)
+                                  ?.label ?? member.role}
+                              </span>
+                            )}
+                          </td>
+                          <td className="px-6 py-4">
+                            {member.user_id === currentUser?.id ? (
+                              <span className="text-sm text-muted-foreground">
+                                You
+                              </span>
+                            ) : manageable ? (
+                              <button
+                                onClick={() =>
+                                  run(() =>
+                                    removeOrgMember(activeOrg.id, member.user_id)
+                                  )
+                                }
+                                className="text-sm text-destructive hover:text-destructive/80 transition-colors"
+                              >
+                                Remove
+                              </button>
+                            ) : null}
+                          </td>
+                        </tr>
+                      );
+                    })}
+                  </tbody>
+                </table>
+              </div>
+            </div>
+          </>
+        )}
+      </div>
+    </div>
+  );
+}
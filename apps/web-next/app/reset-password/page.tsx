@@ -0,0 +1,182 @@
+"use client";
+
+import { Suspense, useState, FormEvent } from "react";
+import Link from "next/link";
+import { useSearchParams } from "next/navigation";
+import { resetPassword } from "@/lib/api";
+
+function ResetPasswordForm() {
+  const searchParams = useSearchParams();
+  const token = searchParams.get("token");
+  const [password, setPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
+  const [error, setError] = useState("");
+  const [isSubmitting, setIsSubmitting] = useState(false);
+  const [done, setDone] = useState(false);
+
+  function validateForm(): string | null {
+    if (password.length < 8) {
+      return "Password must be at least 8 characters long";
+    }
+    if (password !== confirmPassword) {
+      return "Passwords do not match";
+    }
+    return null;
+  }
+
+  async function handleSubmit(e: FormEvent) {
+    e.preventDefault();
+    if (!token) return;
+    setError("");
+
+    const validationError = validateForm();
+    if (validationError) {
+      setError(validationError);
+      return;
+    }
+
+    setIsSubmitting(true);
+
+    try {
+      await resetPassword(token, password);
+      setDone(true);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Password reset failed");
+    } finally {
+      setIsSubmitting(false);
+    }
+  }
+
+  if (!token) {
+    return (
+      <p className="text-destructive">
+        This password reset link is missing its token.
+      </p>
+    );
+  }
+
+  // Resetting the password signs out every session, so the user signs in again
+  if (done) {
+    return (
+      <div className="space-y-6">
+        <p className="text-card-foreground">
+          Your password has been changed. Sign in with your new password.
+        </p>
+        <Link
+          href="/login"
+          className="block w-full text-center bg-primary hover:bg-primary/90 text-primary-foreground font-medium py-3 px-4 rounded-lg transition-all"
+        >
+          Sign in
+        </Link>
+      </div>
+    );
+  }
+
+  return (
+    <form onSubmit={handleSubmit} className="space-y-6">
+      {/* New Password Field */}
+      <div>
+        <label
+          htmlFor="password"
+          className="block text-sm font-medium text-card-foreground mb-2"
+        >
+          New Password
+        </label>
+        <input
+          id="password"
+          type="password"
+          value={password}
+          onChange={(e) => setPassword(e.target.value)}
+          required
+          disabled={isSubmitting}
+          className="w-full px-4 py-3 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent disabled:opacity-50 disabled:cursor-not-allowed transition-all"
+          placeholder="••••••••"
+          autoComplete="new-password"
+          minLength={8}
+        />
+        <p className="text-xs text-muted-foreground mt-1">
+          Must be at least 8 characters long
+        </p>
+      </div>
+
+      {/* Confirm Password Field */}
+      <div>
+        <label
+          htmlFor="confirmPassword"
+          className="block text-sm font-medium text-card-foreground mb-2"
+        >
+          Confirm Password
+        </label>
+        <input
+          id="confirmPassword"
+          type="password"
+          value={confirmPassword}
+          onChange={(e) => setConfirmPassword(e.target.value)}
+          required
+          disabled={isSubmitting}
+          className="w-full px-4 py-3 bg-background/50 border border-input rounded-lg text-foreground placeholder-muted-foreground focus:outline-none focus:ring-2 focus:ring-primary focus:border-transparent disabled:opacity-50 disabled:cursor-not-allowed transition-all"
+          placeholder="••••••••"
+          autoComplete="new-password"
+        />
+      </div>
+
+      {/* Error Message */}
+      {error && (
+        <div className="bg-destructive/10 border border-destructive/30 rounded-lg p-4">
+          <p className="text-destructive text-sm">{error}</p>
+        </div>
+      )}
+
+      {/* Submit Button */}
+      <button
+        type="submit"
+        disabled={isSubmitting}
+        className="w-full bg-primary hover:bg-primary/90 disabled:bg-muted disabled:cursor-not-allowed text-primary-foreground font-medium py-3 px-4 rounded-lg transition-all focus:outline-none focus:ring-2 focus:ring-primary focus:ring-offset-2 focus:ring-offset-background"
+      >
+        {isSubmitting ? "Changing password..." : "Change password"}
+      </button>
+    </form>
+  );
+}
+
+export default function ResetPasswordPage() {
+  return (
+    <div className="min-h-screen flex items-center justify-center p-4">
+      <div className="w-full max-w-md">
+        {/* Header */}
+        <div className="text-center mb-8">
+          <h1 className="text-4xl font-bold text-primary mb-2">
+            🌙 LunaSentri
+          </h1>
+          <p className="text-muted-foreground">Choose a new password</p>
+        </div>
+
+        {/* Reset Form Card */}
+        <div className="bg-card/70 backdrop-blur-xl rounded-xl p-8 shadow-2xl border border-border/30">
+          <Suspense
+            fallback={
+              <div className="text-muted-foreground animate-pulse">
+                Loading...
+              </div>
+            }
+          >
+            <ResetPasswordForm />
+          </Suspense>
+
+          {/* Login Link */}
+          <div className="mt-6 pt-6 border-t border-border/30">
+            <p className="text-sm text-muted-foreground text-center">
+              Remembered it?{" "}
+              <Link
+                href="/login"
+                className="text-primary hover:text-primary/80 transition-colors"
+              >
+                Sign in
+              </Link>
+            </p>
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+}
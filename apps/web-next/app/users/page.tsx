@@ -9,7 +9,9 @@ import {
   listUsers,
   createUser,
   deleteUser,
+  setUserRole,
   type User,
+  type UserRole,
   type CreateUserResponse,
 } from "@/lib/api";
 import {
@@ -22,6 +24,19 @@ import {
 } from "@/components/ui/dialog";
 import { Button } from "@/components/ui/button";
 
+const ROLES: { value: UserRole; label: string }[] = [
+  { value: "owner", label: "Owner" },
+  { value: "admin", label: "Admin" },
+  { value: "operator", label: "Operator" },
+  { value: "viewer", label: "Viewer (read-only)" },
+];
+
+// Admins can only manage operators and viewers; owners can manage everyone
+function canManageRole(actor: UserRole | undefined, role: UserRole): boolean {
+  if (actor === "owner") return true;
+  return actor === "admin" && (role === "operator" || role === "viewer");
+}
+
 export default function UsersPage() {
   const router = useRouter();
   const { status, user: currentUser, logout } = useSession();
@@ -39,6 +54,7 @@ export default function UsersPage() {
   // Form state
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
+  const [role, setRole] = useState<UserRole>("operator");
   const [formError, setFormError] = useState<string | null>(null);
   const [tempPassword, setTempPassword] = useState<string | null>(null);
 
@@ -92,6 +108,7 @@ export default function UsersPage() {
       const response: CreateUserResponse = await createUser({
         email,
         password: password || undefined,
+        role,
       });
 
       // If a temp password was generated, show it
@@ -102,6 +119,7 @@ export default function UsersPage() {
       // Reset form
       setEmail("");
       setPassword("");
+      setRole("operator");
 
       // Refresh user list
       await fetchUsers();
@@ -114,6 +132,16 @@ export default function UsersPage() {
     }
   };
 
+  const handleRoleChange = async (userId: number, newRole: UserRole) => {
+    try {
+      setError(null);
+      await setUserRole(userId, newRole);
+      await fetchUsers();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to change role");
+    }
+  };
+
   const handleDeleteUser = (userId: number, userEmail: string) => {
     setDeletingUser({ id: userId, email: userEmail });
     setShowDeleteModal(true);
@@ -240,6 +268,30 @@ export default function UsersPage() {
               />
             </div>
 
+            <div>
+              <label
+                htmlFor="role"
+                className="block text-sm font-medium text-card-foreground mb-2"
+              >
+                Role
+              </label>
+              <select
+                id="role"
+                value={role}
+                onChange={(e) => setRole(e.target.value as UserRole)}
+                className="w-full px-4 py-2 bg-background/50 border border-input rounded-lg text-foreground focus:outline-none focus:ring-2 focus:ring-primary"
+                disabled={isCreating}
+              >
+                {ROLES.filter((r) =>
+                  canManageRole(currentUser?.role, r.value)
+                ).map((r) => (
+                  <option key={r.value} value={r.value}>
+                    {r.label}
+                  </option>
+                ))}
+              </select>
+            </div>
+
             {formError && (
               <div className="p-3 bg-destructive/10 border border-destructive/30 rounded-lg text-destructive text-sm">
                 {formError}
@@ -284,6 +336,9 @@ export default function UsersPage() {
                     <th className="px-6 py-3 text-sm font-semibold text-card-foreground">
                       Email
                     </th>
+                    <th className="px-6 py-3 text-sm font-semibold text-card-foreground">
+                      Role
+                    </th>
                     <th className="px-6 py-3 text-sm font-semibold text-card-foreground">
                       Created
                     </th>
@@ -301,6 +356,34 @@ export default function UsersPage() {
                       <td className="px-6 py-4 text-card-foreground">
                         {user.email}
                       </td>
+                      <td className="px-6 py-4">
+                        {currentUser?.id !== user.id &&
+                        canManageRole(currentUser?.role, user.role) ? (
+                          <select
+                            value={user.role}
+                            onChange={(e) =>
+                              handleRoleChange(
+                                user.id,
+                                e.target.value as UserRole
+                              )
+                            }
+                            className="px-2 py-1 bg-background/50 border border-input rounded text-sm text-foreground focus:outline-none focus:ring-2 focus:ring-primary"
+                          >
+                            {ROLES.filter((r) =>
+                              canManageRole(currentUser?.role, r.value)
+                            ).map((r) => (
+                              <option key={r.value} value={r.value}>
+                                {r.label}
+                              </option>
+                            ))}
+                          </select>
+                        ) : (
+                          <span className="text-sm text-muted-foreground">
+                            {ROLES.find((r) => r.value === user.role)?.label ??
+                              user.role}
+                          </span>
+                        )}
+                      </td>
                       <td className="px-6 py-4 text-muted-foreground">
                         {user.created_at
                           ? new Date(user.created_at).toLocaleDateString()
@@ -311,6 +394,10 @@ export default function UsersPage() {
                           <span className="text-sm text-muted-foreground">
                             Current User
                           </span>
+                        ) : !canManageRole(currentUser?.role, user.role) ? (
+                          <span className="text-sm text-muted-foreground">
+                            Owner only
+                          </span>
                         ) : (
                           <button
                             onClick={() =>
@@ -20,12 +20,14 @@ import {
   Server,
   AlertTriangle,
   MessageSquare,
+  Building2,
 } from "lucide-react";
 
 interface NavbarProps {
   user: {
     email: string;
     is_admin: boolean;
+    role?: string;
   } | null;
   unacknowledgedCount: number;
   newAlertsCount: number;
@@ -147,7 +149,11 @@ export function Navbar({
                 <div className="px-2 py-1.5 text-sm">
                   <p className="font-medium">{user?.email}</p>
                   <p className="text-xs text-muted-foreground mt-0.5">
-                    {user?.is_admin ? "Administrator" : "User"}
+                    {user?.role
+                      ? user.role.charAt(0).toUpperCase() + user.role.slice(1)
+                      : user?.is_admin
+                        ? "Administrator"
+                        : "User"}
                   </p>
                 </div>
                 <DropdownMenuSeparator />
@@ -160,6 +166,15 @@ export function Navbar({
                     <span>Settings</span>
                   </Link>
                 </DropdownMenuItem>
+                <DropdownMenuItem asChild>
+                  <Link
+                    href="/organizations"
+                    className="flex items-center gap-2 cursor-pointer"
+                  >
+                    <Building2 className="w-4 h-4" />
+                    <span>Organizations</span>
+                  </Link>
+                </DropdownMenuItem>
                 <DropdownMenuSeparator />
                 <DropdownMenuItem
                   onClick={onLogout}
@@ -259,6 +274,14 @@ export function Navbar({
                 <Settings className="w-4 h-4" />
                 <span>Settings</span>
               </Link>
+              <Link
+                href="/organizations"
+                onClick={() => setMobileMenuOpen(false)}
+                className="flex items-center gap-2 px-4 py-3 rounded-lg text-muted-foreground hover:text-foreground hover:bg-accent/10"
+              >
+                <Building2 className="w-4 h-4" />
+                <span>Organizations</span>
+              </Link>
               <div className="px-4 py-2 text-sm text-muted-foreground">
                 {user?.email}
               </div>
@@ -1,6 +1,7 @@
 'use client';
 
 import { useEffect, useState, useCallback } from 'react';
+import { fetchWithRefresh } from '@/lib/api';
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 
@@ -46,17 +47,10 @@ async function webhookRequest<T>(
     input: RequestInfo | URL,
     init?: RequestInit
 ): Promise<T> {
-    const response = await fetch(input, {
-        ...init,
-        credentials: 'include',
-        headers: {
-            'Content-Type': 'application/json',
-            ...init?.headers,
-        },
-    });
+    const response = await fetchWithRefresh(input, init);
 
     // Handle authentication errors
-    if (response.status === 401 || response.status === 403) {
+    if (response.status === 401) {
         if (typeof window !== 'undefined') {
             window.dispatchEvent(new CustomEvent('session-expired'));
         }
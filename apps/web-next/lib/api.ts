@@ -17,15 +17,121 @@ export interface SystemInfo {
   last_boot_time: number;
 }
 
+export type UserRole = 'owner' | 'admin' | 'operator' | 'viewer';
+
 export interface User {
   id: number;
   email: string;
   is_admin: boolean;
+  role: UserRole;
   created_at?: string;
+  totp_enabled?: boolean;
+}
+
+// Returned by /auth/login when the password was right but a two-factor code is needed
+export interface LoginChallenge {
+  two_factor_required: true;
+  challenge: string;
+  expires_at: string;
+}
+
+// Sign-in methods the login page offers, from /auth/oidc
+export interface SSOConfig {
+  enabled: boolean;
+  provider_name?: string;
+  password_login: boolean;
+}
+
+export type TwoFactorPolicy = 'optional' | 'admins' | 'all';
+
+export interface LoginLockout {
+  key: string;
+  failures: number;
+  last_failure_at: string;
+  locked_until?: string;
+}
+
+export interface AuditEntry {
+  id: number;
+  org_id?: number;
+  actor_type: 'user' | 'machine' | 'anonymous' | 'system';
+  actor_id?: number;
+  actor_email?: string;
+  api_token_id?: number;
+  action: string; // e.g. "machine.delete"
+  target_type: string;
+  target_id?: string;
+  changes?: Record<string, { before?: unknown; after?: unknown }>;
+  ip_address?: string;
+  created_at: string;
+}
+
+export interface AuditFilter {
+  action?: string; // exact, or a prefix when it ends in "."
+  actor_id?: number;
+  org_id?: number;
+  target_type?: string;
+  target_id?: string;
+  from?: string; // RFC 3339
+  to?: string;
+}
+
+export interface AuditPage {
+  entries: AuditEntry[];
+  next_cursor?: string;
+}
+
+export interface TwoFactorStatus {
+  enabled: boolean;
+  pending: boolean;
+  required: boolean;
+  recovery_codes_remaining: number;
+  policy: TwoFactorPolicy;
+}
+
+export interface TwoFactorSetup {
+  secret: string;
+  provisioning_uri: string; // otpauth:// URI to render as a QR code
+}
+
+export interface Session {
+  id: number;
+  device: string;
+  user_agent: string;
+  ip_address: string;
+  created_at: string;
+  last_used_at: string;
+  expires_at: string;
+  current: boolean;
+}
+
+export type APITokenScope = 'read' | 'alerts:write' | 'machines:admin' | 'notifications:write' | 'users:admin';
+
+export interface APIToken {
+  id: number;
+  user_id: number;
+  name: string;
+  token_prefix: string;
+  scopes: APITokenScope[];
+  created_at: string;
+  expires_at?: string;
+  last_used_at?: string;
+  last_used_ip?: string;
+}
+
+export interface CreateAPITokenRequest {
+  name: string;
+  scopes: APITokenScope[];
+  expires_at?: string;
+}
+
+export interface CreateAPITokenResponse extends APIToken {
+  token: string; // only returned once
 }
 
 export interface AlertRule {
   id: number;
+  org_id: number;
   name: string;
   metric: 'cpu_pct' | 'mem_used_pct' | 'disk_used_pct';
   threshold_pct: number;
@@ -52,15 +158,21 @@ export interface CreateAlertRuleRequest {
   trigger_after: number;
 }
 
+// Fields left out of an update keep their current value, including the advanced
+// settings (severity, type, hysteresis, escalation) the dashboard does not edit
+export type UpdateAlertRuleRequest = Partial<CreateAlertRuleRequest>;
+
 export interface CreateUserRequest {
   email: string;
   password?: string;
+  role?: UserRole; // defaults to operator
 }
 
 export interface CreateUserResponse {
   id: number;
   email: string;
   is_admin: boolean;
+  role: UserRole;
   created_at: string;
   temp_password?: string;
 }
@@ -72,22 +184,110 @@ export interface LoginRequest {
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
 
+// Endpoints whose 401 means bad credentials rather than an expired access token
+const NO_REFRESH_PATHS = ['/auth/login', '/auth/register', '/auth/refresh', '/auth/logout', '/auth/forgot-password', '/auth/reset-password'];
+
+let refreshInFlight: Promise<boolean> | null = null;
+
+/**
+ * Exchanges the refresh token cookie for new session cookies.
+ * Concurrent callers share a single refresh request.
+ */
+function refreshSession(): Promise<boolean> {
+  if (!refreshInFlight) {
+    refreshInFlight = fetch(`${API_URL}/auth/refresh`, {
+      method: 'POST',
+      credentials: 'include',
+    })
+      .then((response) => response.ok)
+      .catch(() => false)
+      .finally(() => {
+        refreshInFlight = null;
+      });
+  }
+  return refreshInFlight;
+}
+
+const ACTIVE_ORG_KEY = 'lunasentri.activeOrgId';
+
+// The API answers requests for an organization the user has left with this message
+const NOT_A_MEMBER = 'you are not a member of this organization';
+
+/**
+ * Returns the organization requests act in, or null for the user's personal organization.
+ */
+export function getActiveOrgId(): number | null {
+  if (typeof window === 'undefined') {
+    return null;
+  }
+  const stored = Number(window.localStorage.getItem(ACTIVE_ORG_KEY));
+  return Number.isInteger(stored) && stored > 0 ? stored : null;
+}
+
+/**
+ * Switches the organization requests act in and tells open pages to reload their data.
+ */
+export function setActiveOrgId(orgId: number | null): void {
+  if (typeof window === 'undefined') {
+    return;
+  }
+  if (orgId === null) {
+    window.localStorage.removeItem(ACTIVE_ORG_KEY);
+  } else {
+    window.localStorage.setItem(ACTIVE_ORG_KEY, String(orgId));
+  }
+  window.dispatchEvent(new CustomEvent('organization-changed'));
+}
+
+/**
+ * Fetches with credentials in the active organization, refreshing the session
+ * and retrying once when the access token has expired. When the user is no
+ * longer a member of the active organization it falls back to their personal one.
+ */
+export async function fetchWithRefresh(input: RequestInfo | URL, init?: RequestInit): Promise<Response> {
+  const doFetch = () => {
+    const orgId = getActiveOrgId();
+    return fetch(input, {
+      ...init,
+      credentials: 'include', // Always include cookies for authentication
+      headers: {
+        'Content-Type': 'application/json',
+        ...(orgId !== null ? { 'X-Organization-ID': String(orgId) } : {}),
+        ...init?.headers,
+      },
+    });
+  };
+
+  let response = await doFetch();
+  if (response.status === 403 && getActiveOrgId() !== null) {
+    const message = await response.clone().text();
+    if (message.includes(NOT_A_MEMBER)) {
+      setActiveOrgId(null);
+      response = await doFetch();
+    }
+  }
+
+  const path = new URL(input instanceof Request ? input.url : input.toString()).pathname;
+  if (response.status !== 401 || NO_REFRESH_PATHS.includes(path)) {
+    return response;
+  }
+
+  // Retry even if this refresh failed: another tab may have just rotated the token
+  await refreshSession();
+  return doFetch();
+}
+
 /**
  * Centralized request helper that handles authentication errors.
- * Dispatches a 'session-expired' event on 401/403 responses.
+ * Refreshes an expired session once, then dispatches a 'session-expired'
+ * event on 401 responses. A 403 means the user's role does not allow the
+ * request and is thrown like any other error.
  */
 async function request<T>(input: RequestInfo | URL, init?: RequestInit): Promise<T> {
-  const response = await fetch(input, {
-    ...init,
-    credentials: 'include', // Always include cookies for authentication
-    headers: {
-      'Content-Type': 'application/json',
-      ...init?.headers,
-    },
-  });
+  const response = await fetchWithRefresh(input, init);
 
   // Handle authentication errors
-  if (response.status === 401 || response.status === 403) {
+  if (response.status === 401) {
     // Dispatch session expired event for useSession to handle
     if (typeof window !== 'undefined') {
       window.dispatchEvent(new CustomEvent('session-expired'));
@@ -123,17 +323,10 @@ async function request<T>(input: RequestInfo | URL, init?: RequestInit): Promise
  * Request helper for endpoints that don't return JSON content.
  */
 async function requestVoid(input: RequestInfo | URL, init?: RequestInit): Promise<void> {
-  const response = await fetch(input, {
-    ...init,
-    credentials: 'include', // Always include cookies for authentication
-    headers: {
-      'Content-Type': 'application/json',
-      ...init?.headers,
-    },
-  });
+  const response = await fetchWithRefresh(input, init);
 
   // Handle authentication errors
-  if (response.status === 401 || response.status === 403) {
+  if (response.status === 401) {
     // Dispatch session expired event for useSession to handle
     if (typeof window !== 'undefined') {
       window.dispatchEvent(new CustomEvent('session-expired'));
@@ -198,9 +391,9 @@ export async function fetchSystemInfo(machineId?: number): Promise<SystemInfo> {
   return request<SystemInfo>(url.toString());
 }
 
-export async function login(email: string, password: string): Promise<User> {
+export async function login(email: string, password: string): Promise<User | LoginChallenge> {
   try {
-    return await request<User>(`${API_URL}/auth/login`, {
+    return await request<User | LoginChallenge>(`${API_URL}/auth/login`, {
       method: 'POST',
       body: JSON.stringify({ email, password }),
     });
@@ -213,6 +406,156 @@ export async function login(email: string, password: string): Promise<User> {
   }
 }
 
+/**
+ * Sets a new password with the token from a password reset email.
+ */
+export async function resetPassword(token: string, password: string): Promise<void> {
+  return requestVoid(`${API_URL}/auth/reset-password`, {
+    method: 'POST',
+    body: JSON.stringify({ token, password }),
+  });
+}
+
+/**
+ * Answers a login challenge with an authenticator app or recovery code.
+ */
+export async function completeTwoFactorLogin(challenge: string, code: string): Promise<User> {
+  try {
+    return await request<User>(`${API_URL}/auth/login`, {
+      method: 'POST',
+      body: JSON.stringify({ challenge, code }),
+    });
+  } catch (error) {
+    if (error instanceof Error && error.message === 'Request failed: 401 Unauthorized') {
+      throw new Error('Invalid code, or the login expired');
+    }
+    throw error;
+  }
+}
+
+export async function getSSOConfig(): Promise<SSOConfig> {
+  return request<SSOConfig>(`${API_URL}/auth/oidc`);
+}
+
+/**
+ * URL that starts single sign-on. The browser navigates there rather than fetching it, and comes
+ * back to returnTo once signed in.
+ */
+export function ssoLoginURL(returnTo = '/'): string {
+  const url = new URL(`${API_URL}/auth/oidc/login`);
+  url.searchParams.set('return_to', returnTo);
+  return url.toString();
+}
+
+export function isLoginChallenge(result: User | LoginChallenge): result is LoginChallenge {
+  return 'two_factor_required' in result && result.two_factor_required;
+}
+
+export async function getTwoFactorStatus(): Promise<TwoFactorStatus> {
+  return request<TwoFactorStatus>(`${API_URL}/auth/2fa`);
+}
+
+export async function setupTwoFactor(): Promise<TwoFactorSetup> {
+  return request<TwoFactorSetup>(`${API_URL}/auth/2fa/setup`, { method: 'POST' });
+}
+
+/**
+ * Confirms the authenticator app and returns the recovery codes, which are only shown once.
+ */
+export async function enableTwoFactor(code: string): Promise<string[]> {
+  const response = await request<{ recovery_codes: string[] }>(`${API_URL}/auth/2fa/enable`, {
+    method: 'POST',
+    body: JSON.stringify({ code }),
+  });
+  return response.recovery_codes;
+}
+
+export async function regenerateRecoveryCodes(code: string): Promise<string[]> {
+  const response = await request<{ recovery_codes: string[] }>(`${API_URL}/auth/2fa/recovery-codes`, {
+    method: 'POST',
+    body: JSON.stringify({ code }),
+  });
+  return response.recovery_codes;
+}
+
+export async function disableTwoFactor(password: string, code: string): Promise<void> {
+  return requestVoid(`${API_URL}/auth/2fa`, {
+    method: 'DELETE',
+    body: JSON.stringify({ password, code }),
+  });
+}
+
+export async function setTwoFactorPolicy(policy: TwoFactorPolicy): Promise<void> {
+  return requestVoid(`${API_URL}/auth/2fa/policy`, {
+    method: 'PUT',
+    body: JSON.stringify({ policy }),
+  });
+}
+
+export async function resetUserTwoFactor(userId: number): Promise<void> {
+  return requestVoid(`${API_URL}/auth/users/${userId}/2fa`, {
+    method: 'DELETE',
+  });
+}
+
+export async function unlockUser(userId: number): Promise<void> {
+  return requestVoid(`${API_URL}/auth/users/${userId}/lockout`, {
+    method: 'DELETE',
+  });
+}
+
+export async function listLoginLockouts(): Promise<LoginLockout[]> {
+  return request<LoginLockout[]>(`${API_URL}/auth/lockouts`);
+}
+
+export async function liftLoginLockout(key: string): Promise<void> {
+  return requestVoid(`${API_URL}/auth/lockouts/${encodeURIComponent(key)}`, {
+    method: 'DELETE',
+  });
+}
+
+function auditURL(path: string, filter: AuditFilter): URL {
+  const url = new URL(`${API_URL}${path}`);
+  for (const [name, value] of Object.entries(filter)) {
+    if (value !== undefined && value !== '') {
+      url.searchParams.set(name, String(value));
+    }
+  }
+  return url;
+}
+
+export async function listAuditEntries(filter: AuditFilter = {}, limit?: number, cursor?: string): Promise<AuditPage> {
+  const url = auditURL('/audit', filter);
+  if (limit) {
+    url.searchParams.set('limit', limit.toString());
+  }
+  if (cursor) {
+    url.searchParams.set('cursor', cursor);
+  }
+
+  const response = await fetchWithRefresh(url.toString());
+  if (response.status === 401) {
+    if (typeof window !== 'undefined') {
+      window.dispatchEvent(new CustomEvent('session-expired'));
+    }
+    throw new Error('Session expired');
+  }
+  if (!response.ok) {
+    throw new Error((await response.text()) || `Request failed: ${response.status} ${response.statusText}`);
+  }
+
+  // The next page is announced in a header
+  return {
+    entries: await response.json(),
+    next_cursor: response.headers.get('X-Next-Cursor') ?? undefined,
+  };
+}
+
+// URL downloading every matching entry as a JSON file; open it in the browser, which sends the session cookie
+export function auditExportURL(filter: AuditFilter = {}): string {
+  return auditURL('/audit/export', filter).toString();
+}
+
 export async function logout(): Promise<void> {
   // Don't use request helper for logout - it shouldn't trigger session expiry
   const response = await fetch(`${API_URL}/auth/logout`, {
@@ -253,6 +596,13 @@ export async function deleteUser(id: number): Promise<void> {
   });
 }
 
+export async function setUserRole(id: number, role: UserRole): Promise<User> {
+  return request<User>(`${API_URL}/auth/users/${id}/role`, {
+    method: 'PUT',
+    body: JSON.stringify({ role }),
+  });
+}
+
 export async function changePassword(currentPassword: string, newPassword: string): Promise<void> {
   return requestVoid(`${API_URL}/auth/change-password`, {
     method: 'POST',
@@ -260,6 +610,144 @@ export async function changePassword(currentPassword: string, newPassword: strin
   });
 }
 
+export async function listSessions(): Promise<Session[]> {
+  return request<Session[]>(`${API_URL}/auth/sessions`);
+}
+
+export async function revokeSession(id: number): Promise<void> {
+  return requestVoid(`${API_URL}/auth/sessions/${id}`, {
+    method: 'DELETE',
+  });
+}
+
+export async function listAPITokens(): Promise<APIToken[]> {
+  return request<APIToken[]>(`${API_URL}/auth/tokens`);
+}
+
+export async function createAPIToken(data: CreateAPITokenRequest): Promise<CreateAPITokenResponse> {
+  return request<CreateAPITokenResponse>(`${API_URL}/auth/tokens`, {
+    method: 'POST',
+    body: JSON.stringify(data),
+  });
+}
+
+export async function revokeAPIToken(id: number): Promise<void> {
+  return requestVoid(`${API_URL}/auth/tokens/${id}`, {
+    method: 'DELETE',
+  });
+}
+
+/**
+ * Signs out of every device, or only the other devices when keepCurrent is set.
+ */
+export async function revokeAllSessions(keepCurrent = false): Promise<void> {
+  const url = new URL(`${API_URL}/auth/sessions`);
+  if (keepCurrent) {
+    url.searchParams.set('others', 'true');
+  }
+  return requestVoid(url.toString(), {
+    method: 'DELETE',
+  });
+}
+
+// Organizations API
+
+export interface Organization {
+  id: number;
+  name: string;
+  personal: boolean;
+  created_at: string;
+  role: UserRole; // the current user's role in the organization
+}
+
+export interface OrgMember {
+  org_id: number;
+  user_id: number;
+  email: string;
+  role: UserRole;
+  created_at: string;
+}
+
+export interface OrgInvitation {
+  id: number;
+  org_id: number;
+  email: string;
+  role: UserRole;
+  invited_by?: number;
+  created_at: string;
+  expires_at: string;
+}
+
+export interface CreateOrgInvitationResponse extends OrgInvitation {
+  token: string; // only returned once
+  link?: string;
+}
+
+export async function listOrganizations(): Promise<Organization[]> {
+  return request<Organization[]>(`${API_URL}/orgs`);
+}
+
+export async function createOrganization(name: string): Promise<Organization> {
+  return request<Organization>(`${API_URL}/orgs`, {
+    method: 'POST',
+    body: JSON.stringify({ name }),
+  });
+}
+
+export async function renameOrganization(id: number, name: string): Promise<Organization> {
+  return request<Organization>(`${API_URL}/orgs/${id}`, {
+    method: 'PUT',
+    body: JSON.stringify({ name }),
+  });
+}
+
+export async function deleteOrganization(id: number): Promise<void> {
+  return requestVoid(`${API_URL}/orgs/${id}`, {
+    method: 'DELETE',
+  });
+}
+
+export async function listOrgMembers(orgId: number): Promise<OrgMember[]> {
+  return request<OrgMember[]>(`${API_URL}/orgs/${orgId}/members`);
+}
+
+export async function setOrgMemberRole(orgId: number, userId: number, role: UserRole): Promise<OrgMember> {
+  return request<OrgMember>(`${API_URL}/orgs/${orgId}/members/${userId}/role`, {
+    method: 'PUT',
+    body: JSON.stringify({ role }),
+  });
+}
+
+export async function removeOrgMember(orgId: number, userId: number): Promise<void> {
+  return requestVoid(`${API_URL}/orgs/${orgId}/members/${userId}`, {
+    method: 'DELETE',
+  });
+}
+
+export async function listOrgInvitations(orgId: number): Promise<OrgInvitation[]> {
+  return request<OrgInvitation[]>(`${API_URL}/orgs/${orgId}/invitations`);
+}
+
+export async function createOrgInvitation(orgId: number, email: string, role: UserRole): Promise<CreateOrgInvitationResponse> {
+  return request<CreateOrgInvitationResponse>(`${API_URL}/orgs/${orgId}/invitations`, {
+    method: 'POST',
+    body: JSON.stringify({ email, role }),
+  });
+}
+
+export async function revokeOrgInvitation(orgId: number, id: number): Promise<void> {
+  return requestVoid(`${API_URL}/orgs/${orgId}/invitations/${id}`, {
+    method: 'DELETE',
+  });
+}
+
+export async function acceptOrgInvitation(token: string): Promise<OrgMember> {
+  return request<OrgMember>(`${API_URL}/orgs/invitations/accept`, {
+    method: 'POST',
+    body: JSON.stringify({ token }),
+  });
+}
+
 // Alert Rules API
 
 export async function listAlertRules(): Promise<AlertRule[]> {
@@ -273,7 +761,7 @@ export async function createAlertRule(rule: CreateAlertRuleRequest): Promise<Ale
   });
 }
 
-export async function updateAlertRule(id: number, rule: CreateAlertRuleRequest): Promise<AlertRule> {
+export async function updateAlertRule(id: number, rule: UpdateAlertRuleRequest): Promise<AlertRule> {
   return request<AlertRule>(`${API_URL}/alerts/rules/${id}`, {
     method: 'PUT',
     body: JSON.stringify(rule),
@@ -307,7 +795,7 @@ export async function ackAlertEvent(id: number): Promise<void> {
 
 export interface EmailRecipient {
   id: number;
-  user_id: number;
+  org_id: number;
   email: string;
   is_active: boolean;
   created_at: string;
@@ -423,7 +911,7 @@ export async function testTelegramRecipient(id: number): Promise<TestTelegramRes
 
 export interface Machine {
   id: number;
-  user_id: number;
+  org_id: number;
   name: string;
   hostname: string;
   description: string;
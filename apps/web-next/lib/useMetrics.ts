@@ -1,7 +1,7 @@
 'use client';
 
 import { useEffect, useState, useRef, useCallback } from 'react';
-import { fetchMetrics, type Metrics } from './api';
+import { fetchMetrics, getActiveOrgId, type Metrics } from './api';
 
 export interface MetricSample {
     timestamp: number;
@@ -93,11 +93,16 @@ export function useMetrics(options: UseMetricsOptions = {}): UseMetricsReturn {
     // Determine WebSocket URL with machine_id parameter if provided
     const wsUrl = opts.wsUrl || (() => {
         const apiUrl = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
-        const baseWsUrl = apiUrl.replace(/^http/, 'ws') + '/ws';
+        const url = new URL(apiUrl.replace(/^http/, 'ws') + '/ws');
         if (machineId !== undefined) {
-            return `${baseWsUrl}?machine_id=${machineId}`;
+            url.searchParams.set('machine_id', String(machineId));
         }
-        return baseWsUrl;
+        // WebSockets cannot send headers, so the organization goes in the query
+        const orgId = getActiveOrgId();
+        if (orgId !== null) {
+            url.searchParams.set('org_id', String(orgId));
+        }
+        return url.toString();
     })();
 
     // State
@@ -1,7 +1,7 @@
 'use client';
 
 import { useEffect, useState, useCallback } from 'react';
-import { fetchCurrentUser, login as apiLogin, logout as apiLogout, register as apiRegister, changePassword as apiChangePassword, type User } from './api';
+import { fetchCurrentUser, login as apiLogin, completeTwoFactorLogin as apiCompleteTwoFactorLogin, isLoginChallenge, logout as apiLogout, register as apiRegister, changePassword as apiChangePassword, type LoginChallenge, type User } from './api';
 import { useToast } from '@/components/ui/use-toast';
 
 export type SessionStatus = 'loading' | 'authenticated' | 'unauthenticated';
@@ -9,7 +9,8 @@ export type SessionStatus = 'loading' | 'authenticated' | 'unauthenticated';
 export interface Session {
   user: User | null;
   status: SessionStatus;
-  login: (email: string, password: string) => Promise<void>;
+  login: (email: string, password: string) => Promise<LoginChallenge | null>; // a challenge when a two-factor code is needed
+  completeTwoFactorLogin: (challenge: string, code: string) => Promise<void>;
   register: (email: string, password: string) => Promise<void>;
   changePassword: (currentPassword: string, newPassword: string) => Promise<void>;
   logout: () => Promise<void>;
@@ -75,7 +76,23 @@ export function useSession(): Session {
 
   const login = useCallback(async (email: string, password: string) => {
     try {
-      const loggedInUser = await apiLogin(email, password);
+      const result = await apiLogin(email, password);
+      if (isLoginChallenge(result)) {
+        return result;
+      }
+      setUser(result);
+      setStatus('authenticated');
+      return null;
+    } catch (error) {
+      setUser(null);
+      setStatus('unauthenticated');
+      throw error; // Re-throw so caller can handle error display
+    }
+  }, []);
+
+  const completeTwoFactorLogin = useCallback(async (challenge: string, code: string) => {
+    try {
+      const loggedInUser = await apiCompleteTwoFactorLogin(challenge, code);
       setUser(loggedInUser);
       setStatus('authenticated');
     } catch (error) {
@@ -133,6 +150,7 @@ export function useSession(): Session {
     user,
     status,
     login,
+    completeTwoFactorLogin,
     register,
     changePassword,
     logout,